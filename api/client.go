@@ -110,6 +110,30 @@ type Config struct {
 
 	// SRVLookup enables the client to lookup the host through DNS SRV lookup
 	SRVLookup bool
+
+	// FailoverAddresses are the addresses of other servers of the cluster.
+	// When the current server cannot be reached, the request is sent to the
+	// next of Address and these, and the client keeps using the one that
+	// answered. The client also switches to one of them when a standby
+	// redirects a request to it, so that later requests go there directly.
+	// Only requests whose body is empty or set with SetJSONBody (BodyBytes)
+	// can be sent again, so requests with a Body reader are not failed over.
+	FailoverAddresses []string
+
+	// AutoRenewToken makes the client renew its token in the background
+	// with a LifetimeWatcher, from when the token is set until it is
+	// changed or cleared, or can no longer be renewed. Tokens which are not
+	// renewable are not watched.
+	AutoRenewToken bool
+
+	// TokenRenewIncrement is the increment, in seconds, requested when the
+	// token is renewed in the background.
+	TokenRenewIncrement int
+
+	// TokenRenewalNotify, if set, is called with the result of each
+	// background renewal of the token, and with a nil output and the error,
+	// possibly nil, that renewal stopped with.
+	TokenRenewalNotify func(*RenewOutput, error)
 }
 
 // TLSConfig contains the parameters needed to configure TLS on the HTTP client
@@ -378,8 +402,10 @@ func parseRateLimit(val string) (rate float64, burst int, err error) {
 type Client struct {
 	modifyLock         sync.RWMutex
 	addr               *url.URL
+	addrs              []*url.URL
 	config             *Config
 	token              string
+	tokenRenewStopCh   chan struct{}
 	headers            http.Header
 	wrappingLookupFunc WrappingLookupFunc
 	mfaCreds           []string
@@ -449,6 +475,19 @@ func NewClient(c *Config) (*Client, error) {
 		headers: make(http.Header),
 	}
 
+	// The agent is a single local endpoint, so there is nothing to fail
+	// over to
+	if c.AgentAddress == "" && len(c.FailoverAddresses) > 0 {
+		client.addrs = []*url.URL{u}
+		for _, addr := range c.FailoverAddresses {
+			failoverURL, err := url.Parse(addr)
+			if err != nil {
+				return nil, errwrap.Wrapf("failed to parse failover address: {{err}}", err)
+			}
+			client.addrs = append(client.addrs, failoverURL)
+		}
+	}
+
 	// Add the VaultRequest SSRF protection header
 	client.headers[consts.RequestHeaderName] = []string{"true"}
 
@@ -460,6 +499,12 @@ func NewClient(c *Config) (*Client, error) {
 		client.setNamespace(namespace)
 	}
 
+	// The renewal sends requests with the client, so it is started once the
+	// client is fully set up
+	if client.token != "" {
+		client.startTokenRenewal(c.AutoRenewToken, c.TokenRenewIncrement, c.TokenRenewalNotify)
+	}
+
 	return client, nil
 }
 
@@ -606,6 +651,7 @@ func (c *Client) SetToken(v string) {
 	defer c.modifyLock.Unlock()
 
 	c.token = v
+	c.restartTokenRenewal()
 }
 
 // ClearToken deletes the token if it is set or does nothing otherwise.
@@ -614,6 +660,115 @@ func (c *Client) ClearToken() {
 	defer c.modifyLock.Unlock()
 
 	c.token = ""
+	c.restartTokenRenewal()
+}
+
+// StopTokenRenewal stops the background renewal of the current token, if
+// AutoRenewToken is set. Renewal starts again when a token is next set.
+func (c *Client) StopTokenRenewal() {
+	c.modifyLock.Lock()
+	defer c.modifyLock.Unlock()
+
+	if c.tokenRenewStopCh != nil {
+		close(c.tokenRenewStopCh)
+		c.tokenRenewStopCh = nil
+	}
+}
+
+// restartTokenRenewal stops the renewal of the previous token, and starts
+// renewing the current one if AutoRenewToken is set. The caller must hold
+// the client's modifyLock.
+func (c *Client) restartTokenRenewal() {
+	c.config.modifyLock.RLock()
+	autoRenew := c.config.AutoRenewToken
+	increment := c.config.TokenRenewIncrement
+	notify := c.config.TokenRenewalNotify
+	c.config.modifyLock.RUnlock()
+
+	c.startTokenRenewal(autoRenew, increment, notify)
+}
+
+// startTokenRenewal is restartTokenRenewal for callers which already hold
+// the config's modifyLock.
+func (c *Client) startTokenRenewal(autoRenew bool, increment int, notify func(*RenewOutput, error)) {
+	if c.tokenRenewStopCh != nil {
+		close(c.tokenRenewStopCh)
+		c.tokenRenewStopCh = nil
+	}
+
+	if !autoRenew || c.token == "" {
+		return
+	}
+
+	stopCh := make(chan struct{})
+	c.tokenRenewStopCh = stopCh
+	go c.renewToken(c.token, increment, notify, stopCh)
+}
+
+// renewToken renews the token with a LifetimeWatcher until it can no
+// longer be renewed, or stopCh is closed.
+func (c *Client) renewToken(token string, increment int, notify func(*RenewOutput, error), stopCh chan struct{}) {
+	if notify == nil {
+		notify = func(*RenewOutput, error) {}
+	}
+
+	r := c.NewRequest("GET", "/v1/auth/token/lookup-self")
+	r.ClientToken = token
+	resp, err := c.RawRequest(r)
+	if err != nil {
+		notify(nil, errwrap.Wrapf("failed to look up token: {{err}}", err))
+		return
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		notify(nil, errwrap.Wrapf("failed to look up token: {{err}}", err))
+		return
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		notify(nil, err)
+		return
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		notify(nil, err)
+		return
+	}
+	if !renewable || ttl == 0 {
+		notify(nil, nil)
+		return
+	}
+
+	watcher, err := c.NewLifetimeWatcher(&LifetimeWatcherInput{
+		Secret: &Secret{
+			Auth: &SecretAuth{
+				ClientToken:   token,
+				Renewable:     renewable,
+				LeaseDuration: int(ttl.Seconds()),
+			},
+		},
+		Increment: increment,
+	})
+	if err != nil {
+		notify(nil, err)
+		return
+	}
+	go watcher.Start()
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case err := <-watcher.DoneCh():
+			notify(nil, err)
+			return
+		case renewal := <-watcher.RenewCh():
+			notify(renewal, nil)
+		}
+	}
 }
 
 // Headers gets the current set of headers used for requests. This returns a
@@ -677,13 +832,17 @@ func (c *Client) Clone() (*Client, error) {
 	c.modifyLock.RUnlock()
 
 	newConfig := &Config{
-		Address:    config.Address,
-		HttpClient: config.HttpClient,
-		MaxRetries: config.MaxRetries,
-		Timeout:    config.Timeout,
-		Backoff:    config.Backoff,
-		CheckRetry: config.CheckRetry,
-		Limiter:    config.Limiter,
+		Address:             config.Address,
+		HttpClient:          config.HttpClient,
+		MaxRetries:          config.MaxRetries,
+		Timeout:             config.Timeout,
+		Backoff:             config.Backoff,
+		CheckRetry:          config.CheckRetry,
+		Limiter:             config.Limiter,
+		FailoverAddresses:   config.FailoverAddresses,
+		AutoRenewToken:      config.AutoRenewToken,
+		TokenRenewIncrement: config.TokenRenewIncrement,
+		TokenRenewalNotify:  config.TokenRenewalNotify,
 	}
 	config.modifyLock.RUnlock()
 
@@ -773,6 +932,8 @@ func (c *Client) RawRequest(r *Request) (*Response, error) {
 func (c *Client) RawRequestWithContext(ctx context.Context, r *Request) (*Response, error) {
 	c.modifyLock.RLock()
 	token := c.token
+	addr := c.addr
+	addrs := c.addrs
 
 	c.config.modifyLock.RLock()
 	limiter := c.config.Limiter
@@ -799,6 +960,8 @@ func (c *Client) RawRequestWithContext(ctx context.Context, r *Request) (*Respon
 	}
 
 	redirectCount := 0
+	failoverCount := 0
+	var failoverAddr *url.URL
 START:
 	req, err := r.toRetryableHTTP()
 	if err != nil {
@@ -845,6 +1008,17 @@ START:
 	if resp != nil {
 		result = &Response{Response: resp}
 	}
+	// A Body reader has been consumed by the request and cannot be replayed
+	replayable := r.Body == nil || r.BodyBytes != nil
+	if err != nil && resp == nil && ctx.Err() == nil && replayable && failoverCount < len(addrs)-1 {
+		// The server could not be reached, try the next one
+		// BodyBytes are read anew by each attempt, so they are sent as is
+		if next := nextFailoverAddress(addrs, r); next != nil {
+			failoverCount++
+			failoverAddr = next
+			goto START
+		}
+	}
 	if err != nil {
 		if strings.Contains(err.Error(), "tls: oversized") {
 			err = errwrap.Wrapf(
@@ -883,14 +1057,51 @@ START:
 			return result, err
 		}
 
+		// A standby redirecting to another server of the cluster points
+		// at the active node, so keep using it
+		if resp.StatusCode == 307 {
+			for _, u := range addrs {
+				if u.Scheme == respLoc.Scheme && u.Host == respLoc.Host {
+					failoverAddr = u
+				}
+			}
+		}
+
 		// Retry the request
 		redirectCount++
 		goto START
 	}
 
+	if failoverAddr != nil {
+		c.modifyLock.Lock()
+		if c.addr == addr {
+			c.addr = failoverAddr
+		}
+		c.modifyLock.Unlock()
+	}
+
 	if err := result.Error(); err != nil {
 		return result, err
 	}
 
 	return result, nil
 }
+
+// nextFailoverAddress points the request at the address following the one
+// it was sent to, and returns it. If the request was not sent to one of
+// the addresses, it is left untouched and nil is returned.
+func nextFailoverAddress(addrs []*url.URL, r *Request) *url.URL {
+	for i, u := range addrs {
+		if u.Host != r.Host {
+			continue
+		}
+
+		next := addrs[(i+1)%len(addrs)]
+		r.URL.Scheme = next.Scheme
+		r.URL.Host = next.Host
+		r.URL.Path = path.Join(next.Path, strings.TrimPrefix(r.URL.Path, u.Path))
+		r.Host = next.Host
+		return next
+	}
+	return nil
+}
@@ -2,11 +2,13 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/helper/consts"
 )
@@ -190,6 +192,154 @@ func TestClientRedirect(t *testing.T) {
 	}
 }
 
+func TestClientFailover(t *testing.T) {
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("test"))
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	// Nothing listens on the primary address
+	down, lnDown := testHTTPServer(t, http.HandlerFunc(handler))
+	lnDown.Close()
+
+	up := config.Address
+	config.Address = down.Address
+	config.FailoverAddresses = []string{up}
+	config.MaxRetries = 0
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := client.RawRequest(client.NewRequest("GET", "/"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var buf bytes.Buffer
+	io.Copy(&buf, resp.Body)
+	if buf.String() != "test" {
+		t.Fatalf("Bad: %s", buf.String())
+	}
+
+	// The client keeps using the server which answered
+	if client.Address() != up {
+		t.Fatalf("expected address %s, got %s", up, client.Address())
+	}
+
+	// Bodies set as bytes are sent again to the next server, while body
+	// readers, which cannot be replayed, are not failed over
+	client.SetAddress(down.Address)
+	req := client.NewRequest("PUT", "/")
+	req.BodyBytes = []byte("body")
+	if _, err := client.RawRequest(req); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client.SetAddress(down.Address)
+	req = client.NewRequest("PUT", "/")
+	req.Body = strings.NewReader("body")
+	if _, err := client.RawRequest(req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Requests fail once no server can be reached
+	client.SetAddress(up)
+	ln.Close()
+	if _, err := client.RawRequest(client.NewRequest("GET", "/")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClientRedirectFailover(t *testing.T) {
+	primary := func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("test"))
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(primary))
+	defer ln.Close()
+
+	standby := func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Location", config.Address+req.URL.Path)
+		w.WriteHeader(307)
+	}
+	config2, ln2 := testHTTPServer(t, http.HandlerFunc(standby))
+	defer ln2.Close()
+
+	config2.FailoverAddresses = []string{config.Address}
+	client, err := NewClient(config2)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := client.RawRequest(client.NewRequest("GET", "/")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Later requests go to the active node directly
+	if client.Address() != config.Address {
+		t.Fatalf("expected address %s, got %s", config.Address, client.Address())
+	}
+}
+
+func TestClientAutoRenewToken(t *testing.T) {
+	renewed := make(chan string, 10)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		token := req.Header.Get(consts.AuthHeaderName)
+		switch req.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			renewable := token != "root"
+			w.Write([]byte(fmt.Sprintf(`{"data": {"renewable": %t, "ttl": 3600}}`, renewable)))
+		case "/v1/auth/token/renew-self":
+			renewed <- token
+			w.Write([]byte(`{"auth": {"client_token": "` + token + `", "renewable": true, "lease_duration": 3600}}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}
+	config, ln := testHTTPServer(t, http.HandlerFunc(handler))
+	defer ln.Close()
+
+	done := make(chan error, 10)
+	config.AutoRenewToken = true
+	config.TokenRenewalNotify = func(renewal *RenewOutput, err error) {
+		if renewal == nil {
+			done <- err
+		}
+	}
+
+	client, err := NewClient(config)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer client.StopTokenRenewal()
+
+	client.SetToken("foo")
+	select {
+	case token := <-renewed:
+		if token != "foo" {
+			t.Fatalf("expected foo to be renewed, got %s", token)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("token was not renewed")
+	}
+
+	// Tokens which cannot be renewed are not watched
+	client.SetToken("root")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("renewal did not stop")
+	}
+	select {
+	case token := <-renewed:
+		t.Fatalf("unexpected renewal of %s", token)
+	default:
+	}
+}
+
 func TestClientEnvSettings(t *testing.T) {
 	cwd, _ := os.Getwd()
 	oldCACert := os.Getenv(EnvVaultCACert)
@@ -0,0 +1,308 @@
+// Package kv2 provides typed access to a KV version 2 secrets engine, on top
+// of the generic logical client of the api package.
+//
+//	kv := kv2.New(client, "secret")
+//	version, err := kv.Put("my-app/config", map[string]interface{}{
+//		"password": "hunter2",
+//	})
+//	...
+//	secret, err := kv.Get("my-app/config")
+//	if err == kv2.ErrSecretNotFound {
+//		...
+//	}
+package kv2
+
+import (
+	"errors"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	// ErrSecretNotFound is returned when there is no secret, or no such
+	// version of it, at the path.
+	ErrSecretNotFound = errors.New("secret not found")
+
+	// ErrVersionDeleted is returned when the requested version of the
+	// secret has been deleted. It can be undeleted.
+	ErrVersionDeleted = errors.New("secret version deleted")
+
+	// ErrVersionDestroyed is returned when the requested version of the
+	// secret has been destroyed.
+	ErrVersionDestroyed = errors.New("secret version destroyed")
+)
+
+// KV is a client for a KV version 2 secrets engine mount.
+type KV struct {
+	client    *api.Client
+	mountPath string
+}
+
+// Secret is a version of a secret.
+type Secret struct {
+	// Data is the data of the secret.
+	Data map[string]interface{}
+
+	// VersionMetadata is the metadata of the returned version.
+	VersionMetadata *VersionMetadata
+
+	// Raw is the response the secret was read from.
+	Raw *api.Secret
+}
+
+// VersionMetadata is the metadata of a version of a secret.
+type VersionMetadata struct {
+	Version      int       `mapstructure:"version"`
+	CreatedTime  time.Time `mapstructure:"created_time"`
+	DeletionTime time.Time `mapstructure:"deletion_time"`
+	Destroyed    bool      `mapstructure:"destroyed"`
+}
+
+// Metadata is the metadata of a secret and of all its versions.
+type Metadata struct {
+	CASRequired        bool                     `mapstructure:"cas_required"`
+	CreatedTime        time.Time                `mapstructure:"created_time"`
+	CurrentVersion     int                      `mapstructure:"current_version"`
+	DeleteVersionAfter time.Duration            `mapstructure:"delete_version_after"`
+	MaxVersions        int                      `mapstructure:"max_versions"`
+	OldestVersion      int                      `mapstructure:"oldest_version"`
+	UpdatedTime        time.Time                `mapstructure:"updated_time"`
+	Versions           map[int]*VersionMetadata `mapstructure:"versions"`
+}
+
+// MetadataInput is the settings of a secret. Zero values fall back to the
+// configuration of the mount.
+type MetadataInput struct {
+	CASRequired        bool
+	DeleteVersionAfter time.Duration
+	MaxVersions        int
+}
+
+// New returns a client for the KV version 2 mount at the given path.
+func New(client *api.Client, mountPath string) *KV {
+	return &KV{
+		client:    client,
+		mountPath: strings.Trim(mountPath, "/"),
+	}
+}
+
+// Get returns the latest version of the secret at the path.
+func (kv *KV) Get(secretPath string) (*Secret, error) {
+	return kv.GetVersion(secretPath, 0)
+}
+
+// GetVersion returns the given version of the secret at the path, or the
+// latest version if version is 0.
+func (kv *KV) GetVersion(secretPath string, version int) (*Secret, error) {
+	var params map[string][]string
+	if version > 0 {
+		params = map[string][]string{
+			"version": {strconv.Itoa(version)},
+		}
+	}
+
+	secret, err := kv.client.Logical().ReadWithData(kv.path("data", secretPath), params)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	var metadata VersionMetadata
+	if err := decode(secret.Data["metadata"], &metadata); err != nil {
+		return nil, err
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	switch {
+	case ok:
+	case metadata.Destroyed:
+		return nil, ErrVersionDestroyed
+	case !metadata.DeletionTime.IsZero():
+		return nil, ErrVersionDeleted
+	default:
+		return nil, ErrSecretNotFound
+	}
+
+	return &Secret{
+		Data:            data,
+		VersionMetadata: &metadata,
+		Raw:             secret,
+	}, nil
+}
+
+// Put writes a new version of the secret at the path, and returns its
+// metadata.
+func (kv *KV) Put(secretPath string, data map[string]interface{}) (*VersionMetadata, error) {
+	return kv.put(secretPath, data, nil)
+}
+
+// PutCAS writes a new version of the secret at the path only if cas is its
+// current version, or if cas is 0 and the secret does not exist yet.
+func (kv *KV) PutCAS(secretPath string, data map[string]interface{}, cas int) (*VersionMetadata, error) {
+	return kv.put(secretPath, data, map[string]interface{}{
+		"cas": cas,
+	})
+}
+
+func (kv *KV) put(secretPath string, data, options map[string]interface{}) (*VersionMetadata, error) {
+	body := map[string]interface{}{
+		"data": data,
+	}
+	if options != nil {
+		body["options"] = options
+	}
+
+	secret, err := kv.client.Logical().Write(kv.path("data", secretPath), body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var metadata VersionMetadata
+	if err := decode(secret.Data, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// Delete soft deletes the latest version of the secret at the path.
+func (kv *KV) Delete(secretPath string) error {
+	_, err := kv.client.Logical().Delete(kv.path("data", secretPath))
+	return err
+}
+
+// DeleteVersions soft deletes the given versions of the secret at the path.
+func (kv *KV) DeleteVersions(secretPath string, versions ...int) error {
+	return kv.writeVersions("delete", secretPath, versions)
+}
+
+// Undelete restores the given soft deleted versions of the secret at the
+// path.
+func (kv *KV) Undelete(secretPath string, versions ...int) error {
+	return kv.writeVersions("undelete", secretPath, versions)
+}
+
+// Destroy permanently removes the data of the given versions of the secret
+// at the path.
+func (kv *KV) Destroy(secretPath string, versions ...int) error {
+	return kv.writeVersions("destroy", secretPath, versions)
+}
+
+func (kv *KV) writeVersions(prefix, secretPath string, versions []int) error {
+	if len(versions) == 0 {
+		return errors.New("no versions given")
+	}
+
+	_, err := kv.client.Logical().Write(kv.path(prefix, secretPath), map[string]interface{}{
+		"versions": versions,
+	})
+	return err
+}
+
+// List returns the keys under the path. Keys ending with a slash are
+// folders.
+func (kv *KV) List(secretPath string) ([]string, error) {
+	secret, err := kv.client.Logical().List(kv.path("metadata", secretPath) + "/")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var keys []string
+	if err := decode(secret.Data["keys"], &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetMetadata returns the metadata of the secret at the path.
+func (kv *KV) GetMetadata(secretPath string) (*Metadata, error) {
+	secret, err := kv.client.Logical().Read(kv.path("metadata", secretPath))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrSecretNotFound
+	}
+
+	var metadata Metadata
+	if err := decode(secret.Data, &metadata); err != nil {
+		return nil, err
+	}
+	for version, versionMetadata := range metadata.Versions {
+		versionMetadata.Version = version
+	}
+	return &metadata, nil
+}
+
+// PutMetadata updates the settings of the secret at the path.
+func (kv *KV) PutMetadata(secretPath string, input *MetadataInput) error {
+	if input == nil {
+		input = &MetadataInput{}
+	}
+
+	_, err := kv.client.Logical().Write(kv.path("metadata", secretPath), map[string]interface{}{
+		"cas_required":         input.CASRequired,
+		"delete_version_after": input.DeleteVersionAfter.String(),
+		"max_versions":         input.MaxVersions,
+	})
+	return err
+}
+
+// DeleteMetadata permanently removes the secret at the path, with all its
+// versions and metadata.
+func (kv *KV) DeleteMetadata(secretPath string) error {
+	_, err := kv.client.Logical().Delete(kv.path("metadata", secretPath))
+	return err
+}
+
+// path returns the API path of the secret under the given prefix, such as
+// data or metadata.
+func (kv *KV) path(prefix, secretPath string) string {
+	return path.Join(kv.mountPath, prefix, strings.Trim(secretPath, "/"))
+}
+
+// decode decodes a response into a struct, parsing timestamps and
+// durations.
+func decode(input, output interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			stringToTimeHookFunc,
+			mapstructure.StringToTimeDurationHookFunc(),
+		),
+		WeaklyTypedInput: true,
+		Result:           output,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(input); err != nil {
+		return errwrap.Wrapf("failed to decode response: {{err}}", err)
+	}
+	return nil
+}
+
+// stringToTimeHookFunc parses RFC 3339 timestamps, an empty string being
+// the zero time.
+func stringToTimeHookFunc(f, t reflect.Type, data interface{}) (interface{}, error) {
+	if f.Kind() != reflect.String || t != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+	if data.(string) == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, data.(string))
+}
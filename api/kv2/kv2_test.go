@@ -0,0 +1,157 @@
+package kv2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) (*api.Client, func()) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return client, server.Close
+}
+
+func TestKV_GetVersion(t *testing.T) {
+	client, closer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		metadata := map[string]interface{}{
+			"created_time":  "2018-03-22T02:24:06.945319214Z",
+			"deletion_time": "",
+			"destroyed":     false,
+			"version":       2,
+		}
+		data := map[string]interface{}{"foo": "bar"}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/found":
+			if r.URL.Query().Get("version") != "2" {
+				t.Errorf("bad version: %s", r.URL.RawQuery)
+			}
+		case "/v1/secret/data/deleted":
+			w.WriteHeader(http.StatusNotFound)
+			data = nil
+			metadata["deletion_time"] = "2018-03-22T02:25:06.945319214Z"
+		case "/v1/secret/data/destroyed":
+			w.WriteHeader(http.StatusNotFound)
+			data = nil
+			metadata["destroyed"] = true
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     data,
+				"metadata": metadata,
+			},
+		})
+	})
+	defer closer()
+
+	kv := New(client, "/secret/")
+
+	secret, err := kv.GetVersion("found", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(secret.Data, map[string]interface{}{"foo": "bar"}) {
+		t.Fatalf("bad data: %#v", secret.Data)
+	}
+	created, _ := time.Parse(time.RFC3339Nano, "2018-03-22T02:24:06.945319214Z")
+	expected := &VersionMetadata{
+		Version:     2,
+		CreatedTime: created,
+	}
+	if !reflect.DeepEqual(secret.VersionMetadata, expected) {
+		t.Fatalf("bad metadata: %#v", secret.VersionMetadata)
+	}
+
+	for path, expectedErr := range map[string]error{
+		"deleted":   ErrVersionDeleted,
+		"destroyed": ErrVersionDestroyed,
+		"missing":   ErrSecretNotFound,
+	} {
+		if _, err := kv.Get(path); err != expectedErr {
+			t.Fatalf("%s: expected %v, got %v", path, expectedErr, err)
+		}
+	}
+}
+
+func TestKV_PutCAS(t *testing.T) {
+	client, closer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/foo/bar" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		expected := map[string]interface{}{
+			"data":    map[string]interface{}{"foo": "bar"},
+			"options": map[string]interface{}{"cas": float64(0)},
+		}
+		if !reflect.DeepEqual(body, expected) {
+			t.Errorf("bad body: %#v", body)
+		}
+
+		w.Write([]byte(`{"data": {"created_time": "2018-03-22T02:24:06.945319214Z", "deletion_time": "", "destroyed": false, "version": 1}}`))
+	})
+	defer closer()
+
+	metadata, err := New(client, "secret").PutCAS("foo/bar", map[string]interface{}{"foo": "bar"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Version != 1 {
+		t.Fatalf("bad metadata: %#v", metadata)
+	}
+}
+
+func TestKV_GetMetadata(t *testing.T) {
+	client, closer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {
+			"cas_required": true,
+			"created_time": "2018-03-22T02:24:06.945319214Z",
+			"current_version": 2,
+			"delete_version_after": "3h25m19s",
+			"max_versions": 5,
+			"oldest_version": 1,
+			"updated_time": "2018-03-22T02:36:43.986212308Z",
+			"versions": {
+				"1": {"created_time": "2018-03-22T02:24:06.945319214Z", "deletion_time": "", "destroyed": true},
+				"2": {"created_time": "2018-03-22T02:36:33.954880664Z", "deletion_time": "", "destroyed": false}
+			}
+		}}`))
+	})
+	defer closer()
+
+	metadata, err := New(client, "secret").GetMetadata("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !metadata.CASRequired || metadata.CurrentVersion != 2 || metadata.MaxVersions != 5 {
+		t.Fatalf("bad metadata: %#v", metadata)
+	}
+	if metadata.DeleteVersionAfter != 3*time.Hour+25*time.Minute+19*time.Second {
+		t.Fatalf("bad delete_version_after: %s", metadata.DeleteVersionAfter)
+	}
+	if len(metadata.Versions) != 2 || !metadata.Versions[1].Destroyed || metadata.Versions[2].Version != 2 {
+		t.Fatalf("bad versions: %#v", metadata.Versions)
+	}
+}
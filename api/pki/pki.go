@@ -0,0 +1,278 @@
+// Package pki provides typed access to a PKI secrets engine, on top of the
+// generic logical client of the api package.
+//
+//	p := pki.New(client, "pki")
+//	cert, err := p.Issue("web", &pki.IssueInput{
+//		CommonName: "www.example.com",
+//		TTL:        24 * time.Hour,
+//	})
+package pki
+
+import (
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+)
+
+// ErrCertificateNotFound is returned when there is no certificate with the
+// given serial number.
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// PKI is a client for a PKI secrets engine mount.
+type PKI struct {
+	client    *api.Client
+	mountPath string
+}
+
+// IssueInput is the request for a new certificate. Empty fields fall back
+// to the configuration of the role.
+type IssueInput struct {
+	CommonName        string
+	AltNames          []string
+	IPSANs            []string
+	URISANs           []string
+	OtherSANs         []string
+	TTL               time.Duration
+	ExcludeCNFromSANs bool
+
+	// Format is the encoding of the returned certificates and key: "pem",
+	// "der" or "pem_bundle". Defaults to "pem".
+	Format string
+
+	// PrivateKeyFormat is "der" or "pkcs8". Only used by Issue.
+	PrivateKeyFormat string
+}
+
+// SignInput is a certificate signing request, along with the request for
+// the certificate.
+type SignInput struct {
+	IssueInput
+
+	// CSR is the PEM encoded certificate signing request.
+	CSR string
+}
+
+// Certificate is an issued or signed certificate.
+type Certificate struct {
+	Certificate    string    `mapstructure:"certificate"`
+	IssuingCA      string    `mapstructure:"issuing_ca"`
+	CAChain        []string  `mapstructure:"ca_chain"`
+	PrivateKey     string    `mapstructure:"private_key"`
+	PrivateKeyType string    `mapstructure:"private_key_type"`
+	SerialNumber   string    `mapstructure:"serial_number"`
+	Expiration     time.Time `mapstructure:"expiration"`
+}
+
+// TidyInput selects what to remove from the storage of the mount.
+type TidyInput struct {
+	TidyCertStore      bool
+	TidyRevokedCerts   bool
+	TidyRevocationList bool
+
+	// SafetyBuffer is how long after their expiration certificates are
+	// kept. The server default is used if 0.
+	SafetyBuffer time.Duration
+}
+
+// New returns a client for the PKI mount at the given path.
+func New(client *api.Client, mountPath string) *PKI {
+	return &PKI{
+		client:    client,
+		mountPath: strings.Trim(mountPath, "/"),
+	}
+}
+
+// Issue generates a private key and a certificate for it with the named
+// role.
+func (p *PKI) Issue(role string, input *IssueInput) (*Certificate, error) {
+	if input == nil {
+		return nil, errors.New("missing input")
+	}
+
+	data := input.data()
+	if input.PrivateKeyFormat != "" {
+		data["private_key_format"] = input.PrivateKeyFormat
+	}
+	return p.writeCertificate(p.path("issue", role), data)
+}
+
+// Sign signs a certificate signing request with the named role.
+func (p *PKI) Sign(role string, input *SignInput) (*Certificate, error) {
+	if input == nil {
+		return nil, errors.New("missing input")
+	}
+
+	data := input.IssueInput.data()
+	data["csr"] = input.CSR
+	return p.writeCertificate(p.path("sign", role), data)
+}
+
+// ReadCertificate returns the PEM encoded certificate with the given serial
+// number.
+func (p *PKI) ReadCertificate(serial string) (string, error) {
+	secret, err := p.client.Logical().Read(p.path("cert", serial))
+	if err != nil {
+		return "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return "", ErrCertificateNotFound
+	}
+
+	certificate, _ := secret.Data["certificate"].(string)
+	if certificate == "" {
+		return "", ErrCertificateNotFound
+	}
+	return certificate, nil
+}
+
+// ReadCA returns the PEM encoded CA certificate of the mount.
+func (p *PKI) ReadCA() (string, error) {
+	return p.ReadCertificate("ca")
+}
+
+// ListCertificates returns the serial numbers of the certificates issued
+// by the mount, which have not been tidied.
+func (p *PKI) ListCertificates() ([]string, error) {
+	secret, err := p.client.Logical().List(p.path("certs") + "/")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var serials []string
+	if err := decode(secret.Data["keys"], &serials); err != nil {
+		return nil, err
+	}
+	return serials, nil
+}
+
+// Revoke revokes the certificate with the given serial number, and returns
+// the time it was revoked at.
+func (p *PKI) Revoke(serial string) (time.Time, error) {
+	secret, err := p.client.Logical().Write(p.path("revoke"), map[string]interface{}{
+		"serial_number": serial,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if secret == nil || secret.Data == nil {
+		return time.Time{}, errors.New("data from server response is empty")
+	}
+
+	var output struct {
+		RevocationTime time.Time `mapstructure:"revocation_time"`
+	}
+	if err := decode(secret.Data, &output); err != nil {
+		return time.Time{}, err
+	}
+	return output.RevocationTime, nil
+}
+
+// Tidy starts removing expired certificates from the storage of the mount.
+// It runs in the background on the server.
+func (p *PKI) Tidy(input *TidyInput) error {
+	if input == nil {
+		return errors.New("missing input")
+	}
+
+	data := map[string]interface{}{
+		"tidy_cert_store":      input.TidyCertStore,
+		"tidy_revoked_certs":   input.TidyRevokedCerts,
+		"tidy_revocation_list": input.TidyRevocationList,
+	}
+	if input.SafetyBuffer > 0 {
+		data["safety_buffer"] = input.SafetyBuffer.String()
+	}
+
+	_, err := p.client.Logical().Write(p.path("tidy"), data)
+	return err
+}
+
+func (p *PKI) writeCertificate(certPath string, data map[string]interface{}) (*Certificate, error) {
+	secret, err := p.client.Logical().Write(certPath, data)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var certificate Certificate
+	if err := decode(secret.Data, &certificate); err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+// path returns the API path of the given segments under the mount.
+func (p *PKI) path(segments ...string) string {
+	return path.Join(append([]string{p.mountPath}, segments...)...)
+}
+
+// data returns the request data of the input.
+func (i *IssueInput) data() map[string]interface{} {
+	data := map[string]interface{}{
+		"common_name":          i.CommonName,
+		"exclude_cn_from_sans": i.ExcludeCNFromSANs,
+	}
+	if len(i.AltNames) > 0 {
+		data["alt_names"] = strings.Join(i.AltNames, ",")
+	}
+	if len(i.IPSANs) > 0 {
+		data["ip_sans"] = strings.Join(i.IPSANs, ",")
+	}
+	if len(i.URISANs) > 0 {
+		data["uri_sans"] = strings.Join(i.URISANs, ",")
+	}
+	if len(i.OtherSANs) > 0 {
+		data["other_sans"] = strings.Join(i.OtherSANs, ",")
+	}
+	if i.TTL > 0 {
+		data["ttl"] = i.TTL.String()
+	}
+	if i.Format != "" {
+		data["format"] = i.Format
+	}
+	return data
+}
+
+// decode decodes a response into a struct, parsing Unix timestamps.
+func decode(input, output interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       unixToTimeHookFunc,
+		WeaklyTypedInput: true,
+		Result:           output,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(input); err != nil {
+		return errwrap.Wrapf("failed to decode response: {{err}}", err)
+	}
+	return nil
+}
+
+// unixToTimeHookFunc parses timestamps given in seconds since the Unix
+// epoch.
+func unixToTimeHookFunc(f, t reflect.Type, data interface{}) (interface{}, error) {
+	if t != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+
+	var seconds int64
+	if err := mapstructure.WeakDecode(data, &seconds); err != nil {
+		return nil, err
+	}
+	if seconds == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(seconds, 0), nil
+}
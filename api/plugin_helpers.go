@@ -22,6 +22,10 @@ var (
 	// PluginUnwrapTokenEnv is the ENV name used to pass unwrap tokens to the
 	// plugin.
 	PluginUnwrapTokenEnv = "VAULT_UNWRAP_TOKEN"
+
+	// PluginUnwrapAddrEnv is the ENV name used to pass the address the plugin
+	// unwraps its token at, when it cannot reach the address of the token.
+	PluginUnwrapAddrEnv = "VAULT_PLUGIN_UNWRAP_ADDR"
 )
 
 // PluginAPIClientMeta is a helper that plugins can use to configure TLS connections
@@ -104,6 +108,12 @@ func VaultPluginTLSProvider(apiTLSConfig *TLSConfig) func() (*tls.Config, error)
 			return nil, errwrap.Wrapf("error parsing the vault api_addr: {{err}}", err)
 		}
 
+		// Plugins run in a sandbox without network access are given a local
+		// address to unwrap the token at
+		if unwrapAddr := os.Getenv(PluginUnwrapAddrEnv); unwrapAddr != "" {
+			vaultAddr = unwrapAddr
+		}
+
 		// Unwrap the token
 		clientConf := DefaultConfig()
 		clientConf.Address = vaultAddr
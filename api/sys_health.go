@@ -38,4 +38,7 @@ type HealthResponse struct {
 	ClusterName                string `json:"cluster_name,omitempty"`
 	ClusterID                  string `json:"cluster_id,omitempty"`
 	LastWAL                    uint64 `json:"last_wal,omitempty"`
+	Draining                   bool   `json:"draining"`
+	DrainReason                string `json:"drain_reason,omitempty"`
+	DrainDeadlineUTC           int64  `json:"drain_deadline_utc,omitempty"`
 }
@@ -3,6 +3,8 @@ package api
 import (
 	"context"
 	"errors"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 func (c *Sys) Renew(id string, increment int) (*Secret, error) {
@@ -69,6 +71,61 @@ func (c *Sys) RevokeForce(id string) error {
 	return err
 }
 
+// LookupByTag returns the IDs of the leases carrying all the given tags.
+func (c *Sys) LookupByTag(tags map[string]string) ([]string, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/leases/lookup-by-tag")
+	body := map[string]interface{}{
+		"tags": tags,
+	}
+	if err := r.SetJSONBody(body); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var result struct {
+		Keys []string `mapstructure:"keys"`
+	}
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+	return result.Keys, nil
+}
+
+// RevokeByTag revokes all the leases carrying all the given tags.
+func (c *Sys) RevokeByTag(tags map[string]string, sync bool) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/leases/revoke-by-tag")
+	body := map[string]interface{}{
+		"tags": tags,
+		"sync": sync,
+	}
+	if err := r.SetJSONBody(body); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
 func (c *Sys) RevokeWithOptions(opts *RevokeOptions) error {
 	if opts == nil {
 		return errors.New("nil options provided")
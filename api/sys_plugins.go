@@ -131,21 +131,33 @@ type GetPluginInput struct {
 
 	// Type of the plugin. Required.
 	Type consts.PluginType `json:"type"`
+
+	// Version of the plugin, if it was registered with one.
+	Version string `json:"version,omitempty"`
 }
 
 // GetPluginResponse is the response from the GetPlugin call.
 type GetPluginResponse struct {
-	Args    []string `json:"args"`
-	Builtin bool     `json:"builtin"`
-	Command string   `json:"command"`
-	Name    string   `json:"name"`
-	SHA256  string   `json:"sha256"`
+	Args    []string       `json:"args"`
+	Builtin bool           `json:"builtin"`
+	Command string         `json:"command"`
+	Name    string         `json:"name"`
+	SHA256  string         `json:"sha256"`
+	Runtime *PluginRuntime `json:"runtime,omitempty"`
+
+	// Version is the version the plugin was registered with, if any, and
+	// Versions all the versions the plugin is registered with.
+	Version  string   `json:"version,omitempty"`
+	Versions []string `json:"versions,omitempty"`
 }
 
 // GetPlugin retrieves information about the plugin.
 func (c *Sys) GetPlugin(i *GetPluginInput) (*GetPluginResponse, error) {
 	path := catalogPathByType(i.Type, i.Name)
 	req := c.c.NewRequest(http.MethodGet, path)
+	if i.Version != "" {
+		req.Params.Set("version", i.Version)
+	}
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -181,6 +193,25 @@ type RegisterPluginInput struct {
 
 	// SHA256 is the shasum of the plugin.
 	SHA256 string `json:"sha256,omitempty"`
+
+	// Runtime is the isolated runtime to run the plugin in, if any.
+	Runtime *PluginRuntime `json:"runtime,omitempty"`
+
+	// Version is the semantic version to register the plugin with, if any.
+	Version string `json:"version,omitempty"`
+}
+
+// PluginRuntime is the isolated runtime an external plugin is run in.
+type PluginRuntime struct {
+	// Type is the kind of runtime, "container" or "gvisor".
+	Type string `json:"type"`
+
+	// Image is the container image the plugin binary is run in.
+	Image string `json:"image"`
+
+	// CPUs and MemoryBytes limit the resources of the plugin, if set.
+	CPUs        float64 `json:"cpus,omitempty"`
+	MemoryBytes int64   `json:"memory_bytes,omitempty"`
 }
 
 // RegisterPlugin registers the plugin with the given information.
@@ -208,6 +239,9 @@ type DeregisterPluginInput struct {
 
 	// Type of the plugin. Required.
 	Type consts.PluginType `json:"type"`
+
+	// Version of the plugin to deregister, if only that version should be.
+	Version string `json:"version,omitempty"`
 }
 
 // DeregisterPlugin removes the plugin with the given name from the plugin
@@ -215,6 +249,9 @@ type DeregisterPluginInput struct {
 func (c *Sys) DeregisterPlugin(i *DeregisterPluginInput) error {
 	path := catalogPathByType(i.Type, i.Name)
 	req := c.c.NewRequest(http.MethodDelete, path)
+	if i.Version != "" {
+		req.Params.Set("version", i.Version)
+	}
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -104,6 +104,67 @@ func (c *Sys) DeletePolicy(name string) error {
 	return err
 }
 
+// TestPolicyInput is a hypothetical request to test an ACL policy against.
+type TestPolicyInput struct {
+	// Path and Operation of the request. Operation defaults to "read".
+	Path      string `json:"path"`
+	Operation string `json:"operation,omitempty"`
+
+	// Parameters of the request, checked against the parameter constraints of
+	// the policy.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+
+	// EntityID, EntityName, EntityMetadata and Groups describe the identity
+	// the templating of the policy is evaluated with.
+	EntityID       string            `json:"entity_id,omitempty"`
+	EntityName     string            `json:"entity_name,omitempty"`
+	EntityMetadata map[string]string `json:"entity_metadata,omitempty"`
+	Groups         []string          `json:"groups,omitempty"`
+
+	// RemoteAddress is the IP address the request comes from, checked
+	// against the bound CIDRs of the policy.
+	RemoteAddress string `json:"remote_address,omitempty"`
+}
+
+// TestPolicyOutput is the result of testing an ACL policy.
+type TestPolicyOutput struct {
+	Allowed      bool     `json:"allowed" mapstructure:"allowed"`
+	MatchedPath  string   `json:"matched_path" mapstructure:"matched_path"`
+	Capabilities []string `json:"capabilities" mapstructure:"capabilities"`
+	Templated    bool     `json:"templated" mapstructure:"templated"`
+	MFAMethods   []string `json:"mfa_methods" mapstructure:"mfa_methods"`
+}
+
+// TestPolicy evaluates the named ACL policy against a hypothetical request.
+func (c *Sys) TestPolicy(name string, input *TestPolicyInput) (*TestPolicyOutput, error) {
+	r := c.c.NewRequest("PUT", fmt.Sprintf("/v1/sys/policies/acl/%s/test", name))
+	if err := r.SetJSONBody(input); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result TestPolicyOutput
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
 type getPoliciesResp struct {
 	Rules string `json:"rules"`
 }
@@ -19,6 +19,37 @@ func (c *Sys) Seal() error {
 	return err
 }
 
+// SealMigrate starts the online migration to the new auto-seal on the active
+// node. Its progress is reported by SealStatus.
+func (c *Sys) SealMigrate() error {
+	r := c.c.NewRequest("PUT", "/v1/sys/seal-migrate")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// SealBackendStatus returns the health of each of the seals of the node
+func (c *Sys) SealBackendStatus() (*SealBackendStatusResponse, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/seal-backend-status")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result SealBackendStatusResponse
+	err = resp.DecodeJSON(&result)
+	return &result, err
+}
+
 func (c *Sys) ResetUnsealProcess() (*SealStatusResponse, error) {
 	body := map[string]interface{}{"reset": true}
 
@@ -65,19 +96,49 @@ func sealStatusRequest(c *Sys, r *Request) (*SealStatusResponse, error) {
 }
 
 type SealStatusResponse struct {
-	Type         string `json:"type"`
-	Initialized  bool   `json:"initialized"`
-	Sealed       bool   `json:"sealed"`
-	T            int    `json:"t"`
-	N            int    `json:"n"`
-	Progress     int    `json:"progress"`
-	Nonce        string `json:"nonce"`
-	Version      string `json:"version"`
-	Migration    bool   `json:"migration"`
-	ClusterName  string `json:"cluster_name,omitempty"`
-	ClusterID    string `json:"cluster_id,omitempty"`
-	RecoverySeal bool   `json:"recovery_seal"`
-	StorageType  string `json:"storage_type,omitempty"`
+	Type            string               `json:"type"`
+	Initialized     bool                 `json:"initialized"`
+	Sealed          bool                 `json:"sealed"`
+	T               int                  `json:"t"`
+	N               int                  `json:"n"`
+	Progress        int                  `json:"progress"`
+	Nonce           string               `json:"nonce"`
+	Version         string               `json:"version"`
+	Migration       bool                 `json:"migration"`
+	MigrationStatus *SealMigrationStatus `json:"migration_status,omitempty"`
+	ClusterName     string               `json:"cluster_name,omitempty"`
+	ClusterID       string               `json:"cluster_id,omitempty"`
+	RecoverySeal    bool                 `json:"recovery_seal"`
+	StorageType     string               `json:"storage_type,omitempty"`
+}
+
+// SealMigrationStatus is the progress of an online seal migration, as seen
+// by the node reporting it
+type SealMigrationStatus struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	State          string `json:"state"`
+	Progress       int    `json:"progress"`
+	Total          int    `json:"total"`
+	StartTime      string `json:"start_time"`
+	CompletionTime string `json:"completion_time"`
+	Error          string `json:"error"`
+}
+
+type SealBackendStatusResponse struct {
+	Healthy  bool                 `json:"healthy"`
+	Backends []*SealBackendStatus `json:"backends"`
+}
+
+// SealBackendStatus is the health of one of the seals of a node
+type SealBackendStatus struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Priority        int    `json:"priority"`
+	Healthy         bool   `json:"healthy"`
+	LastHealthyTime string `json:"last_healthy_time"`
+	LastError       string `json:"last_error"`
+	LastErrorTime   string `json:"last_error_time"`
 }
 
 type UnsealOpts struct {
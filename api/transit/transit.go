@@ -0,0 +1,403 @@
+// Package transit provides typed access to a Transit secrets engine, on top
+// of the generic logical client of the api package. Plaintexts, contexts and
+// signed inputs are taken and returned as raw bytes; their base64 encoding
+// is handled here.
+//
+//	t := transit.New(client, "transit")
+//	if err := t.CreateKey("my-key", nil); err != nil {
+//		...
+//	}
+//	encrypted, err := t.Encrypt("my-key", &transit.EncryptInput{
+//		Plaintext: []byte("hello"),
+//	})
+package transit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Key types which can be given when creating a key.
+const (
+	KeyTypeAES128GCM96      = "aes128-gcm96"
+	KeyTypeAES256GCM96      = "aes256-gcm96"
+	KeyTypeChaCha20Poly1305 = "chacha20-poly1305"
+	KeyTypeED25519          = "ed25519"
+	KeyTypeECDSAP256        = "ecdsa-p256"
+	KeyTypeECDSAP384        = "ecdsa-p384"
+	KeyTypeECDSAP521        = "ecdsa-p521"
+	KeyTypeRSA2048          = "rsa-2048"
+	KeyTypeRSA3072          = "rsa-3072"
+	KeyTypeRSA4096          = "rsa-4096"
+)
+
+// ErrKeyNotFound is returned when there is no key of the given name.
+var ErrKeyNotFound = errors.New("key not found")
+
+// Transit is a client for a Transit secrets engine mount.
+type Transit struct {
+	client    *api.Client
+	mountPath string
+}
+
+// CreateKeyInput is the settings of a new key.
+type CreateKeyInput struct {
+	Type                 string `json:"type,omitempty"`
+	Derived              bool   `json:"derived,omitempty"`
+	ConvergentEncryption bool   `json:"convergent_encryption,omitempty"`
+	Exportable           bool   `json:"exportable,omitempty"`
+	AllowPlaintextBackup bool   `json:"allow_plaintext_backup,omitempty"`
+}
+
+// Key is the description of a key.
+type Key struct {
+	Name                 string `mapstructure:"name"`
+	Type                 string `mapstructure:"type"`
+	Derived              bool   `mapstructure:"derived"`
+	DeletionAllowed      bool   `mapstructure:"deletion_allowed"`
+	Exportable           bool   `mapstructure:"exportable"`
+	AllowPlaintextBackup bool   `mapstructure:"allow_plaintext_backup"`
+	LatestVersion        int    `mapstructure:"latest_version"`
+	MinAvailableVersion  int    `mapstructure:"min_available_version"`
+	MinDecryptionVersion int    `mapstructure:"min_decryption_version"`
+	MinEncryptionVersion int    `mapstructure:"min_encryption_version"`
+	SupportsEncryption   bool   `mapstructure:"supports_encryption"`
+	SupportsDecryption   bool   `mapstructure:"supports_decryption"`
+	SupportsDerivation   bool   `mapstructure:"supports_derivation"`
+	SupportsSigning      bool   `mapstructure:"supports_signing"`
+}
+
+// KeyConfigInput is the settings of a key which can be changed. Nil fields
+// are left unchanged.
+type KeyConfigInput struct {
+	MinDecryptionVersion *int  `json:"min_decryption_version,omitempty"`
+	MinEncryptionVersion *int  `json:"min_encryption_version,omitempty"`
+	DeletionAllowed      *bool `json:"deletion_allowed,omitempty"`
+	Exportable           *bool `json:"exportable,omitempty"`
+	AllowPlaintextBackup *bool `json:"allow_plaintext_backup,omitempty"`
+}
+
+// EncryptInput is the plaintext to encrypt.
+type EncryptInput struct {
+	Plaintext []byte
+
+	// Context is the key derivation context, required for derived keys.
+	Context []byte
+
+	// KeyVersion is the version of the key to encrypt with, the latest if 0.
+	KeyVersion int
+}
+
+// EncryptOutput is an encrypted plaintext.
+type EncryptOutput struct {
+	Ciphertext string `mapstructure:"ciphertext"`
+	KeyVersion int    `mapstructure:"key_version"`
+}
+
+// DecryptInput is the ciphertext to decrypt.
+type DecryptInput struct {
+	Ciphertext string
+
+	// Context is the key derivation context, required for derived keys.
+	Context []byte
+}
+
+// SignInput is the data to sign.
+type SignInput struct {
+	Input []byte
+
+	// Context is the key derivation context, required for derived keys.
+	Context []byte
+
+	// KeyVersion is the version of the key to sign with, the latest if 0.
+	KeyVersion int
+
+	// HashAlgorithm is the hash algorithm to use, such as "sha2-256".
+	HashAlgorithm string
+
+	// SignatureAlgorithm is the RSA signature algorithm to use, "pss" or
+	// "pkcs1v15".
+	SignatureAlgorithm string
+
+	// Prehashed is set if the input is already hashed.
+	Prehashed bool
+}
+
+// SignOutput is a signature.
+type SignOutput struct {
+	Signature  string `mapstructure:"signature"`
+	KeyVersion int    `mapstructure:"key_version"`
+}
+
+// VerifyInput is a signature to verify, along with the data it signs.
+type VerifyInput struct {
+	Input     []byte
+	Signature string
+
+	// Context is the key derivation context, required for derived keys.
+	Context []byte
+
+	// HashAlgorithm, SignatureAlgorithm and Prehashed must match the ones
+	// the input was signed with.
+	HashAlgorithm      string
+	SignatureAlgorithm string
+	Prehashed          bool
+}
+
+// New returns a client for the Transit mount at the given path.
+func New(client *api.Client, mountPath string) *Transit {
+	return &Transit{
+		client:    client,
+		mountPath: strings.Trim(mountPath, "/"),
+	}
+}
+
+// CreateKey creates a new key. A nil input creates an aes256-gcm96 key.
+func (t *Transit) CreateKey(name string, input *CreateKeyInput) error {
+	if input == nil {
+		input = &CreateKeyInput{}
+	}
+
+	data, err := toMap(input)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.Logical().Write(t.path("keys", name), data)
+	return err
+}
+
+// ReadKey returns the description of a key.
+func (t *Transit) ReadKey(name string) (*Key, error) {
+	secret, err := t.client.Logical().Read(t.path("keys", name))
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	var key Key
+	if err := decode(secret.Data, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListKeys returns the names of the keys.
+func (t *Transit) ListKeys() ([]string, error) {
+	secret, err := t.client.Logical().List(t.path("keys") + "/")
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, nil
+	}
+
+	var keys []string
+	if err := decode(secret.Data["keys"], &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// UpdateKeyConfig changes the settings of a key.
+func (t *Transit) UpdateKeyConfig(name string, input *KeyConfigInput) error {
+	if input == nil {
+		return nil
+	}
+
+	data, err := toMap(input)
+	if err != nil {
+		return err
+	}
+	_, err = t.client.Logical().Write(t.path("keys", name, "config"), data)
+	return err
+}
+
+// RotateKey adds a new version to a key, which becomes the one used for
+// encryption and signing.
+func (t *Transit) RotateKey(name string) error {
+	_, err := t.client.Logical().Write(t.path("keys", name, "rotate"), nil)
+	return err
+}
+
+// DeleteKey deletes a key. The key must have been configured to allow
+// deletion.
+func (t *Transit) DeleteKey(name string) error {
+	_, err := t.client.Logical().Delete(t.path("keys", name))
+	return err
+}
+
+// Encrypt encrypts a plaintext with the named key.
+func (t *Transit) Encrypt(name string, input *EncryptInput) (*EncryptOutput, error) {
+	if input == nil {
+		return nil, errors.New("missing input")
+	}
+
+	data := map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(input.Plaintext),
+	}
+	if input.Context != nil {
+		data["context"] = base64.StdEncoding.EncodeToString(input.Context)
+	}
+	if input.KeyVersion > 0 {
+		data["key_version"] = input.KeyVersion
+	}
+
+	var output EncryptOutput
+	if err := t.write(t.path("encrypt", name), data, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// Decrypt decrypts a ciphertext with the named key, and returns the
+// plaintext.
+func (t *Transit) Decrypt(name string, input *DecryptInput) ([]byte, error) {
+	if input == nil {
+		return nil, errors.New("missing input")
+	}
+
+	data := map[string]interface{}{
+		"ciphertext": input.Ciphertext,
+	}
+	if input.Context != nil {
+		data["context"] = base64.StdEncoding.EncodeToString(input.Context)
+	}
+
+	var output struct {
+		Plaintext string `mapstructure:"plaintext"`
+	}
+	if err := t.write(t.path("decrypt", name), data, &output); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(output.Plaintext)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to decode plaintext: {{err}}", err)
+	}
+	return plaintext, nil
+}
+
+// Rewrap encrypts a ciphertext again with the latest version of the named
+// key, without revealing the plaintext.
+func (t *Transit) Rewrap(name, ciphertext string, context []byte) (*EncryptOutput, error) {
+	data := map[string]interface{}{
+		"ciphertext": ciphertext,
+	}
+	if context != nil {
+		data["context"] = base64.StdEncoding.EncodeToString(context)
+	}
+
+	var output EncryptOutput
+	if err := t.write(t.path("rewrap", name), data, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// Sign signs the input with the named key.
+func (t *Transit) Sign(name string, input *SignInput) (*SignOutput, error) {
+	if input == nil {
+		return nil, errors.New("missing input")
+	}
+
+	data := map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString(input.Input),
+		"prehashed": input.Prehashed,
+	}
+	if input.Context != nil {
+		data["context"] = base64.StdEncoding.EncodeToString(input.Context)
+	}
+	if input.KeyVersion > 0 {
+		data["key_version"] = input.KeyVersion
+	}
+	if input.HashAlgorithm != "" {
+		data["hash_algorithm"] = input.HashAlgorithm
+	}
+	if input.SignatureAlgorithm != "" {
+		data["signature_algorithm"] = input.SignatureAlgorithm
+	}
+
+	var output SignOutput
+	if err := t.write(t.path("sign", name), data, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// Verify reports whether the signature of the input is valid for the named
+// key.
+func (t *Transit) Verify(name string, input *VerifyInput) (bool, error) {
+	if input == nil {
+		return false, errors.New("missing input")
+	}
+
+	data := map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString(input.Input),
+		"signature": input.Signature,
+		"prehashed": input.Prehashed,
+	}
+	if input.Context != nil {
+		data["context"] = base64.StdEncoding.EncodeToString(input.Context)
+	}
+	if input.HashAlgorithm != "" {
+		data["hash_algorithm"] = input.HashAlgorithm
+	}
+	if input.SignatureAlgorithm != "" {
+		data["signature_algorithm"] = input.SignatureAlgorithm
+	}
+
+	var output struct {
+		Valid bool `mapstructure:"valid"`
+	}
+	if err := t.write(t.path("verify", name), data, &output); err != nil {
+		return false, err
+	}
+	return output.Valid, nil
+}
+
+// write writes the data to the path, and decodes the response into output.
+func (t *Transit) write(p string, data map[string]interface{}, output interface{}) error {
+	secret, err := t.client.Logical().Write(p, data)
+	if err != nil {
+		return err
+	}
+	if secret == nil || secret.Data == nil {
+		return errors.New("data from server response is empty")
+	}
+	return decode(secret.Data, output)
+}
+
+// path returns the API path of the given segments under the mount.
+func (t *Transit) path(segments ...string) string {
+	return path.Join(append([]string{t.mountPath}, segments...)...)
+}
+
+func decode(input, output interface{}) error {
+	if err := mapstructure.WeakDecode(input, output); err != nil {
+		return errwrap.Wrapf("failed to decode response: {{err}}", err)
+	}
+	return nil
+}
+
+// toMap turns an input struct into request data, following its JSON tags.
+func toMap(input interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+package transit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func testClient(t *testing.T, handler http.HandlerFunc) (*api.Client, func()) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return client, server.Close
+}
+
+func TestTransit_EncryptDecrypt(t *testing.T) {
+	client, closer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+
+		// Echo the base64 encoded plaintext back as the ciphertext
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/my-key":
+			if body["context"] != base64.StdEncoding.EncodeToString([]byte("ctx")) {
+				t.Errorf("bad context: %#v", body)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"ciphertext":  "vault:v1:" + body["plaintext"].(string),
+					"key_version": 1,
+				},
+			})
+		case "/v1/transit/decrypt/my-key":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"plaintext": body["ciphertext"].(string)[len("vault:v1:"):],
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closer()
+
+	transit := New(client, "transit")
+
+	encrypted, err := transit.Encrypt("my-key", &EncryptInput{
+		Plaintext: []byte("hello"),
+		Context:   []byte("ctx"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted.KeyVersion != 1 || encrypted.Ciphertext != "vault:v1:aGVsbG8=" {
+		t.Fatalf("bad output: %#v", encrypted)
+	}
+
+	plaintext, err := transit.Decrypt("my-key", &DecryptInput{
+		Ciphertext: encrypted.Ciphertext,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "hello" {
+		t.Fatalf("bad plaintext: %q", plaintext)
+	}
+}
+
+func TestTransit_ReadKey(t *testing.T) {
+	client, closer := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/transit/keys/my-key" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"data": {"name": "my-key", "type": "aes256-gcm96", "latest_version": 3, "supports_encryption": true, "keys": {"1": 1442851412}}}`))
+	})
+	defer closer()
+
+	transit := New(client, "transit")
+
+	key, err := transit.ReadKey("my-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Name != "my-key" || key.Type != KeyTypeAES256GCM96 || key.LatestVersion != 3 || !key.SupportsEncryption {
+		t.Fatalf("bad key: %#v", key)
+	}
+
+	if _, err := transit.ReadKey("missing"); err != ErrKeyNotFound {
+		t.Fatalf("expected %v, got %v", ErrKeyNotFound, err)
+	}
+}
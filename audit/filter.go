@@ -0,0 +1,325 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	glob "github.com/ryanuber/go-glob"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// filterFields are the attributes of an audit entry that filters can test,
+// by the name used in filter expressions.
+var filterFields = map[string]func(context.Context, *logical.LogInput) string{
+	"operation": func(_ context.Context, in *logical.LogInput) string {
+		return string(in.Request.Operation)
+	},
+	"path": func(_ context.Context, in *logical.LogInput) string {
+		return in.Request.Path
+	},
+	"mount_type": func(_ context.Context, in *logical.LogInput) string {
+		return in.Request.MountType
+	},
+	"mount_point": func(_ context.Context, in *logical.LogInput) string {
+		return in.Request.MountPoint
+	},
+	"namespace": func(ctx context.Context, _ *logical.LogInput) string {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return ""
+		}
+		return ns.Path
+	},
+	"remote_address": func(_ context.Context, in *logical.LogInput) string {
+		if in.Request.Connection == nil {
+			return ""
+		}
+		return in.Request.Connection.RemoteAddr
+	},
+	"entity_id": func(_ context.Context, in *logical.LogInput) string {
+		if in.Auth != nil && in.Auth.EntityID != "" {
+			return in.Auth.EntityID
+		}
+		return in.Request.EntityID
+	},
+	"display_name": func(_ context.Context, in *logical.LogInput) string {
+		if in.Auth == nil {
+			return ""
+		}
+		return in.Auth.DisplayName
+	},
+}
+
+// Filter is a predicate on audit entries, which an audit device uses to
+// only log the entries it matches. Filters are written as expressions such
+// as:
+//
+//   mount_type != "kv" or operation == "delete"
+//
+// Comparisons test a field of the entry against a quoted string with the
+// ==, != and matches operators, where matches takes a glob pattern using *
+// as a wildcard. Comparisons are combined with and, or, not and parentheses.
+// Since a request and its response are tested against the same fields,
+// both are either logged or skipped.
+type Filter struct {
+	raw  string
+	root filterNode
+}
+
+// NewFilter parses a filter expression. An empty expression returns a nil
+// filter, which matches every entry.
+func NewFilter(expr string) (*Filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+
+	return &Filter{
+		raw:  expr,
+		root: root,
+	}, nil
+}
+
+// Matches reports whether the entry should be logged. A nil filter matches
+// every entry.
+func (f *Filter) Matches(ctx context.Context, in *logical.LogInput) bool {
+	if f == nil {
+		return true
+	}
+	if in == nil || in.Request == nil {
+		return false
+	}
+	return f.root.eval(ctx, in)
+}
+
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.raw
+}
+
+type filterNode interface {
+	eval(context.Context, *logical.LogInput) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+
+func (n *filterAnd) eval(ctx context.Context, in *logical.LogInput) bool {
+	return n.left.eval(ctx, in) && n.right.eval(ctx, in)
+}
+
+type filterOr struct{ left, right filterNode }
+
+func (n *filterOr) eval(ctx context.Context, in *logical.LogInput) bool {
+	return n.left.eval(ctx, in) || n.right.eval(ctx, in)
+}
+
+type filterNot struct{ node filterNode }
+
+func (n *filterNot) eval(ctx context.Context, in *logical.LogInput) bool {
+	return !n.node.eval(ctx, in)
+}
+
+type filterComparison struct {
+	field func(context.Context, *logical.LogInput) string
+	op    string
+	value string
+}
+
+func (n *filterComparison) eval(ctx context.Context, in *logical.LogInput) bool {
+	actual := n.field(ctx, in)
+	switch n.op {
+	case "==":
+		return actual == n.value
+	case "!=":
+		return actual != n.value
+	default:
+		return glob.Glob(n.value, actual)
+	}
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenIdent filterTokenKind = iota
+	filterTokenString
+	filterTokenOperator
+	filterTokenParen
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		r := rune(expr[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, filterToken{filterTokenParen, string(r)})
+			i++
+		case strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, filterToken{filterTokenOperator, expr[i : i+2]})
+			i += 2
+		case r == '"':
+			end := i + 1
+			for ; end < len(expr) && expr[end] != '"'; end++ {
+				if expr[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			value, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, filterToken{filterTokenString, value})
+			i = end + 1
+		case r == '_' || unicode.IsLetter(r):
+			end := i
+			for ; end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))); end++ {
+			}
+			tokens = append(tokens, filterToken{filterTokenIdent, expr[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser parses filter expressions by recursive descent, with not
+// binding tighter than and, and and binding tighter than or.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == filterTokenIdent && p.tokens[p.pos].text == keyword
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNot{node}, nil
+	}
+
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind == filterTokenParen && t.text == "(" {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if closing.kind != filterTokenParen || closing.text != ")" {
+			return nil, fmt.Errorf("expected \")\", got %q", closing.text)
+		}
+		return node, nil
+	}
+
+	if t.kind != filterTokenIdent {
+		return nil, fmt.Errorf("expected a field name, got %q", t.text)
+	}
+	field, ok := filterFields[t.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", t.text)
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if op.kind != filterTokenOperator && !(op.kind == filterTokenIdent && op.text == "matches") {
+		return nil, fmt.Errorf("expected ==, != or matches after %q, got %q", t.text, op.text)
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if value.kind != filterTokenString {
+		return nil, fmt.Errorf("expected a quoted string after %q, got %q", op.text, value.text)
+	}
+
+	return &filterComparison{
+		field: field,
+		op:    op.text,
+		value: value.text,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	ctx := namespace.RootContext(context.Background())
+	kvRead := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "secret/foo",
+			MountType: "kv",
+		},
+	}
+	kvDelete := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.DeleteOperation,
+			Path:      "secret/foo",
+			MountType: "kv",
+		},
+	}
+	login := &logical.LogInput{
+		Auth: &logical.Auth{DisplayName: "userpass-bob"},
+		Request: &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "auth/userpass/login/bob",
+			MountType:  "userpass",
+			Connection: &logical.Connection{RemoteAddr: "10.0.0.1"},
+		},
+	}
+
+	cases := []struct {
+		expr    string
+		matches []*logical.LogInput
+		skips   []*logical.LogInput
+	}{
+		{
+			expr:    `mount_type != "kv" or operation == "delete"`,
+			matches: []*logical.LogInput{kvDelete, login},
+			skips:   []*logical.LogInput{kvRead},
+		},
+		{
+			expr:    `path matches "auth/*/login/*" and not (remote_address == "10.0.0.2")`,
+			matches: []*logical.LogInput{login},
+			skips:   []*logical.LogInput{kvRead, kvDelete},
+		},
+		{
+			expr:    `display_name == "userpass-bob" or namespace == "ns1/"`,
+			matches: []*logical.LogInput{login},
+			skips:   []*logical.LogInput{kvRead},
+		},
+		{
+			// and binds tighter than or
+			expr:    `operation == "read" or operation == "delete" and mount_type == "userpass"`,
+			matches: []*logical.LogInput{kvRead},
+			skips:   []*logical.LogInput{kvDelete, login},
+		},
+	}
+
+	for _, tc := range cases {
+		f, err := NewFilter(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		for _, in := range tc.matches {
+			if !f.Matches(ctx, in) {
+				t.Errorf("%s: expected %s to match", tc.expr, in.Request.Path)
+			}
+		}
+		for _, in := range tc.skips {
+			if f.Matches(ctx, in) {
+				t.Errorf("%s: expected %s %s to be skipped", tc.expr, in.Request.Operation, in.Request.Path)
+			}
+		}
+	}
+}
+
+func TestFilter_Empty(t *testing.T) {
+	f, err := NewFilter("  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != nil {
+		t.Fatalf("expected nil filter, got %#v", f)
+	}
+	if !f.Matches(context.Background(), &logical.LogInput{Request: &logical.Request{}}) {
+		t.Fatal("expected nil filter to match")
+	}
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		`mount_type`,
+		`mount_type = "kv"`,
+		`mount_type == kv`,
+		`unknown == "kv"`,
+		`(mount_type == "kv"`,
+		`mount_type == "kv" or`,
+		`mount_type == "kv" operation == "read"`,
+		`path == "unterminated`,
+	} {
+		if _, err := NewFilter(expr); err == nil {
+			t.Errorf("expected error parsing %s", expr)
+		}
+	}
+}
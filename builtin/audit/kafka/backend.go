@@ -0,0 +1,438 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/tlsutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	kafka "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+const (
+	// partitionKeyMountPath sends the entries of a mount to the same
+	// partition, so that they are consumed in order.
+	partitionKeyMountPath = "mount_path"
+
+	// partitionKeyEntityID sends the entries of an entity to the same
+	// partition.
+	partitionKeyEntityID = "entity_id"
+
+	// partitionKeyNone spreads the entries over all partitions.
+	partitionKeyNone = "none"
+)
+
+const (
+	// fallbackError fails the audit of entries which cannot be queued, so
+	// that Vault refuses the request unless another device logs it.
+	fallbackError = "error"
+
+	// fallbackDrop discards the entries which cannot be queued.
+	fallbackDrop = "drop"
+
+	// fallbackBlock waits for room in the queue.
+	fallbackBlock = "block"
+)
+
+// errQueueFull is returned when an entry cannot be queued because the
+// brokers are not keeping up or are unavailable.
+var errQueueFull = errors.New("kafka audit queue is full")
+
+// messageWriter writes messages to a Kafka topic. It is implemented by
+// kafka.Writer, and swapped out in tests.
+type messageWriter interface {
+	WriteMessages(context.Context, ...kafka.Message) error
+	Close() error
+}
+
+func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
+	if conf.SaltConfig == nil {
+		return nil, fmt.Errorf("nil salt config")
+	}
+	if conf.SaltView == nil {
+		return nil, fmt.Errorf("nil salt view")
+	}
+
+	address, ok := conf.Config["address"]
+	if !ok || address == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	brokers := strings.Split(address, ",")
+	for i, broker := range brokers {
+		brokers[i] = strings.TrimSpace(broker)
+	}
+
+	topic, ok := conf.Config["topic"]
+	if !ok || topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+
+	partitionKey, ok := conf.Config["partition_key"]
+	if !ok {
+		partitionKey = partitionKeyMountPath
+	}
+	var balancer kafka.Balancer = &kafka.Hash{}
+	switch partitionKey {
+	case partitionKeyMountPath, partitionKeyEntityID:
+	case partitionKeyNone:
+		balancer = &kafka.LeastBytes{}
+	default:
+		return nil, fmt.Errorf("unknown partition_key %q", partitionKey)
+	}
+
+	fallback, ok := conf.Config["fallback"]
+	if !ok {
+		fallback = fallbackError
+	}
+	switch fallback {
+	case fallbackError, fallbackDrop, fallbackBlock:
+	default:
+		return nil, fmt.Errorf("unknown fallback %q", fallback)
+	}
+
+	queueSize := 1024
+	if raw, ok := conf.Config["queue_size"]; ok {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("queue_size must be a positive integer")
+		}
+		queueSize = size
+	}
+
+	writeTimeout, ok := conf.Config["write_timeout"]
+	if !ok {
+		writeTimeout = "2s"
+	}
+	writeDuration, err := parseutil.ParseDurationSecond(writeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	format, ok := conf.Config["format"]
+	if !ok {
+		format = "json"
+	}
+	switch format {
+	case "json", "jsonx":
+	default:
+		return nil, fmt.Errorf("unknown format type %q", format)
+	}
+
+	// Check if hashing of accessor is disabled
+	hmacAccessor := true
+	if hmacAccessorRaw, ok := conf.Config["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(hmacAccessorRaw)
+		if err != nil {
+			return nil, err
+		}
+		hmacAccessor = value
+	}
+
+	// Check if raw logging is enabled
+	logRaw := false
+	if raw, ok := conf.Config["log_raw"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logRaw = b
+	}
+
+	dialer := &kafka.Dialer{
+		Timeout:   writeDuration,
+		DualStack: true,
+	}
+	useTLS := false
+	if raw, ok := conf.Config["tls"]; ok {
+		useTLS, err = parseutil.ParseBool(raw)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing tls parameter: {{err}}", err)
+		}
+	}
+	if useTLS {
+		dialer.TLS, err = tlsutil.SetupTLSConfig(conf.Config, brokers[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if username, ok := conf.Config["sasl_username"]; ok {
+		dialer.SASLMechanism = plain.Mechanism{
+			Username: username,
+			Password: conf.Config["sasl_password"],
+		}
+	}
+
+	b := &Backend{
+		saltConfig: conf.SaltConfig,
+		saltView:   conf.SaltView,
+		formatConfig: audit.FormatterConfig{
+			Raw:          logRaw,
+			HMACAccessor: hmacAccessor,
+		},
+
+		partitionKey:  partitionKey,
+		fallback:      fallback,
+		writeDuration: writeDuration,
+		newWriter: func() messageWriter {
+			return kafka.NewWriter(kafka.WriterConfig{
+				Brokers:      brokers,
+				Topic:        topic,
+				Dialer:       dialer,
+				Balancer:     balancer,
+				BatchTimeout: 10 * time.Millisecond,
+				WriteTimeout: writeDuration,
+			})
+		},
+		queue: make(chan kafka.Message, queueSize),
+	}
+
+	switch format {
+	case "json":
+		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	case "jsonx":
+		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	}
+
+	b.start()
+	return b, nil
+}
+
+// Backend is the audit backend for the Kafka audit transport. Entries are
+// queued, and written to the topic in batches by a background goroutine,
+// so that requests do not wait for the brokers. What happens to entries
+// which do not fit in the queue depends on the fallback.
+type Backend struct {
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+
+	partitionKey  string
+	fallback      string
+	writeDuration time.Duration
+
+	newWriter func() messageWriter
+	queue     chan kafka.Message
+
+	// l guards the lifecycle of the writer goroutine
+	l      sync.Mutex
+	stopCh chan struct{}
+	doneCh chan struct{}
+
+	saltMutex  sync.RWMutex
+	salt       *salt.Salt
+	saltConfig *salt.Config
+	saltView   logical.Storage
+}
+
+var _ audit.Backend = (*Backend)(nil)
+
+func (b *Backend) GetHash(ctx context.Context, data string) (string, error) {
+	salt, err := b.Salt(ctx)
+	if err != nil {
+		return "", err
+	}
+	return audit.HashString(salt, data), nil
+}
+
+func (b *Backend) LogRequest(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+	return b.enqueue(ctx, b.messageKey(in), buf.Bytes())
+}
+
+func (b *Backend) LogResponse(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+	return b.enqueue(ctx, b.messageKey(in), buf.Bytes())
+}
+
+// messageKey returns the key used to pick the partition of an entry.
+func (b *Backend) messageKey(in *logical.LogInput) []byte {
+	var key string
+	switch b.partitionKey {
+	case partitionKeyMountPath:
+		if in.Request != nil {
+			key = in.Request.MountPoint
+		}
+	case partitionKeyEntityID:
+		if in.Auth != nil && in.Auth.EntityID != "" {
+			key = in.Auth.EntityID
+		} else if in.Request != nil {
+			key = in.Request.EntityID
+		}
+	}
+	if key == "" {
+		return nil
+	}
+	return []byte(key)
+}
+
+func (b *Backend) enqueue(ctx context.Context, key, value []byte) error {
+	msg := kafka.Message{
+		Key:   key,
+		Value: value,
+	}
+
+	select {
+	case b.queue <- msg:
+		return nil
+	default:
+	}
+
+	switch b.fallback {
+	case fallbackDrop:
+		metrics.IncrCounter([]string{"audit", "kafka", "dropped"}, 1)
+		return nil
+	case fallbackBlock:
+		select {
+		case b.queue <- msg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		return errQueueFull
+	}
+}
+
+// start runs the goroutine writing the queued entries to the topic.
+func (b *Backend) start() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.stopCh = make(chan struct{})
+	b.doneCh = make(chan struct{})
+	go b.run(b.newWriter(), b.stopCh, b.doneCh)
+}
+
+// stop waits for the writer goroutine to exit. Entries still queued are
+// kept, and written once the goroutine is started again.
+func (b *Backend) stop() {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.stopCh == nil {
+		return
+	}
+	close(b.stopCh)
+	<-b.doneCh
+	b.stopCh, b.doneCh = nil, nil
+}
+
+func (b *Backend) run(w messageWriter, stopCh, doneCh chan struct{}) {
+	defer close(doneCh)
+	defer w.Close()
+
+	var batch []kafka.Message
+	backoff := 100 * time.Millisecond
+	for {
+		if len(batch) == 0 {
+			select {
+			case msg := <-b.queue:
+				batch = append(batch, msg)
+			case <-stopCh:
+				return
+			}
+		}
+		// Gather whatever else is queued into the same batch
+	gather:
+		for len(batch) < cap(b.queue) {
+			select {
+			case msg := <-b.queue:
+				batch = append(batch, msg)
+			default:
+				break gather
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), b.writeDuration)
+		err := w.WriteMessages(ctx, batch...)
+		cancel()
+		if err == nil {
+			metrics.IncrCounter([]string{"audit", "kafka", "written"}, float32(len(batch)))
+			batch = batch[:0]
+			backoff = 100 * time.Millisecond
+			continue
+		}
+
+		// Keep the batch and retry, while new entries fill the queue
+		metrics.IncrCounter([]string{"audit", "kafka", "write_failure"}, 1)
+		select {
+		case <-time.After(backoff):
+		case <-stopCh:
+			// Put the batch back so that it is written after a reload,
+			// as far as it still fits
+			for _, msg := range batch {
+				select {
+				case b.queue <- msg:
+				default:
+				}
+			}
+			return
+		}
+		if backoff < 5*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// Reload reconnects to the brokers.
+func (b *Backend) Reload(_ context.Context) error {
+	b.stop()
+	b.start()
+	return nil
+}
+
+// Close stops writing entries to the topic, as when the device is
+// disabled or Vault is sealed.
+func (b *Backend) Close() error {
+	b.stop()
+	return nil
+}
+
+func (b *Backend) Salt(ctx context.Context) (*salt.Salt, error) {
+	b.saltMutex.RLock()
+	if b.salt != nil {
+		defer b.saltMutex.RUnlock()
+		return b.salt, nil
+	}
+	b.saltMutex.RUnlock()
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(ctx, b.saltView, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *Backend) Invalidate(_ context.Context) {
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	b.salt = nil
+}
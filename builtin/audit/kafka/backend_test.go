@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// testWriter records the messages written, and holds each write until it
+// is released.
+type testWriter struct {
+	sync.Mutex
+	messages []kafka.Message
+	writing  chan struct{}
+	release  chan struct{}
+}
+
+func (w *testWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.writing <- struct{}{}
+	<-w.release
+	w.Lock()
+	defer w.Unlock()
+	w.messages = append(w.messages, msgs...)
+	return nil
+}
+
+func (w *testWriter) Close() error {
+	return nil
+}
+
+func testBackend(t *testing.T, config map[string]string) (*Backend, *testWriter, func()) {
+	t.Helper()
+
+	config["address"] = "127.0.0.1:9092"
+	config["topic"] = "vault-audit"
+	be, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Swap the brokers out for a test writer
+	b := be.(*Backend)
+	b.Close()
+	w := &testWriter{
+		writing: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+	b.newWriter = func() messageWriter { return w }
+	b.start()
+	return b, w, func() {
+		close(w.release)
+		b.Close()
+	}
+}
+
+func testLogInput(mountPoint, entityID string) *logical.LogInput {
+	return &logical.LogInput{
+		Auth: &logical.Auth{EntityID: entityID},
+		Request: &logical.Request{
+			Operation:  logical.ReadOperation,
+			Path:       mountPoint + "foo",
+			MountPoint: mountPoint,
+		},
+	}
+}
+
+func TestAuditKafka_Factory(t *testing.T) {
+	for _, config := range []map[string]string{
+		{"topic": "vault-audit"},
+		{"address": "127.0.0.1:9092"},
+		{"address": "127.0.0.1:9092", "topic": "vault-audit", "partition_key": "accessor"},
+		{"address": "127.0.0.1:9092", "topic": "vault-audit", "fallback": "retry"},
+		{"address": "127.0.0.1:9092", "topic": "vault-audit", "queue_size": "0"},
+		{"address": "127.0.0.1:9092", "topic": "vault-audit", "format": "xml"},
+	} {
+		_, err := Factory(context.Background(), &audit.BackendConfig{
+			SaltConfig: &salt.Config{},
+			SaltView:   &logical.InmemStorage{},
+			Config:     config,
+		})
+		if err == nil {
+			t.Errorf("expected error for config %v", config)
+		}
+	}
+}
+
+func TestAuditKafka_PartitionKey(t *testing.T) {
+	ctx := namespace.RootContext(context.Background())
+	in := testLogInput("secret/", "entity-1")
+
+	cases := map[string]string{
+		"mount_path": "secret/",
+		"entity_id":  "entity-1",
+		"none":       "",
+	}
+	for partitionKey, expected := range cases {
+		b, w, cleanup := testBackend(t, map[string]string{"partition_key": partitionKey})
+		defer cleanup()
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+		<-w.writing
+		w.release <- struct{}{}
+
+		// Wait for the write to be recorded
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			w.Lock()
+			n := len(w.messages)
+			w.Unlock()
+			if n > 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for the message")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		msg := w.messages[0]
+		if string(msg.Key) != expected {
+			t.Fatalf("%s: expected key %q, got %q", partitionKey, expected, msg.Key)
+		}
+		if !strings.Contains(string(msg.Value), `"path":"secret/foo"`) {
+			t.Fatalf("%s: unexpected entry %s", partitionKey, msg.Value)
+		}
+	}
+}
+
+func TestAuditKafka_Fallback(t *testing.T) {
+	ctx := namespace.RootContext(context.Background())
+	in := testLogInput("secret/", "")
+
+	cases := map[string]func(error) bool{
+		"error": func(err error) bool { return err == errQueueFull },
+		"drop":  func(err error) bool { return err == nil },
+		"block": func(err error) bool { return err == context.DeadlineExceeded },
+	}
+	for fallback, check := range cases {
+		b, w, cleanup := testBackend(t, map[string]string{
+			"fallback":   fallback,
+			"queue_size": "1",
+		})
+		defer cleanup()
+
+		// The first entry is held by the writer, the second fills the queue
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+		<-w.writing
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		err := b.LogRequest(ctx, in)
+		cancel()
+		if !check(err) {
+			t.Fatalf("%s: unexpected error %v", fallback, err)
+		}
+	}
+}
@@ -1,11 +1,22 @@
 package awsauth
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
@@ -82,6 +93,137 @@ func GenerateLoginData(creds *credentials.Credentials, headerValue, configuredRe
 	return loginData, nil
 }
 
+// GenerateRolesAnywhereLoginData populates the data to send to the Vault
+// server to authenticate with an X.509 certificate registered with IAM Roles
+// Anywhere. The CreateSession request is signed with the certificate's
+// private key, using the Roles Anywhere signing process.
+func GenerateRolesAnywhereLoginData(cert *x509.Certificate, key crypto.Signer, trustAnchorArn, profileArn, roleArn, headerValue, region string) (map[string]interface{}, error) {
+	var algorithm string
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = "AWS4-X509-RSA-SHA256"
+	case *ecdsa.PrivateKey:
+		algorithm = "AWS4-X509-ECDSA-SHA256"
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"trustAnchorArn": trustAnchorArn,
+		"profileArn":     profileArn,
+		"roleArn":        roleArn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host := fmt.Sprintf("rolesanywhere.%s.amazonaws.com", region)
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	headers := map[string]string{
+		"content-type": "application/json",
+		"host":         host,
+		"x-amz-date":   amzDate,
+		"x-amz-x509":   base64.StdEncoding.EncodeToString(cert.Raw),
+	}
+	signedHeaders := []string{"content-type", "host", "x-amz-date", "x-amz-x509"}
+	if headerValue != "" {
+		headers[strings.ToLower(iamServerIdHeader)] = headerValue
+		signedHeaders = append(signedHeaders, strings.ToLower(iamServerIdHeader))
+	}
+
+	var canonicalHeaders bytes.Buffer
+	for _, name := range signedHeaders {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		rolesAnywhereSessionsPath,
+		"",
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/rolesanywhere/aws4_request", now.Format("20060102"), region)
+	stringToSign := strings.Join([]string{
+		algorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to sign request: {{err}}", err)
+	}
+
+	requestHeaders := map[string][]string{
+		"Authorization": {fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+			algorithm, cert.SerialNumber.String(), scope, strings.Join(signedHeaders, ";"), hex.EncodeToString(signature))},
+		"Content-Type":          {headers["content-type"]},
+		"Host":                  {host},
+		"X-Amz-Date":            {amzDate},
+		rolesAnywhereX509Header: {headers["x-amz-x509"]},
+	}
+	if headerValue != "" {
+		requestHeaders[iamServerIdHeader] = []string{headerValue}
+	}
+	headersJson, err := json.Marshal(requestHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"rolesanywhere_request_url":     base64.StdEncoding.EncodeToString([]byte("https://" + host + rolesAnywhereSessionsPath)),
+		"rolesanywhere_request_headers": base64.StdEncoding.EncodeToString(headersJson),
+		"rolesanywhere_request_body":    base64.StdEncoding.EncodeToString(body),
+	}, nil
+}
+
+// loadRolesAnywhereCredentials reads a PEM encoded certificate and private
+// key from disk.
+func loadRolesAnywhereCredentials(certPath, keyPath string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("no PEM data found in %q", certPath)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("no PEM data found in %q", keyPath)
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return cert, signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err == nil {
+		return cert, key, nil
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key in %q", keyPath)
+	}
+	return cert, key, nil
+}
+
 func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, error) {
 	mount, ok := m["mount"]
 	if !ok {
@@ -98,21 +240,56 @@ func (h *CLIHandler) Auth(c *api.Client, m map[string]string) (*api.Secret, erro
 		headerValue = ""
 	}
 
-	creds, err := RetrieveCreds(m["aws_access_key_id"], m["aws_secret_access_key"], m["aws_security_token"])
-	if err != nil {
-		return nil, err
-	}
-
 	region := m["region"]
 	if region == "" {
 		region = awsutil.DefaultRegion
 	}
-	loginData, err := GenerateLoginData(creds, headerValue, region)
-	if err != nil {
-		return nil, err
-	}
-	if loginData == nil {
-		return nil, fmt.Errorf("got nil response from GenerateLoginData")
+
+	var loginData map[string]interface{}
+	switch m["type"] {
+	case "", iamAuthType:
+		creds, err := RetrieveCreds(m["aws_access_key_id"], m["aws_secret_access_key"], m["aws_security_token"])
+		if err != nil {
+			return nil, err
+		}
+
+		loginData, err = GenerateLoginData(creds, headerValue, region)
+		if err != nil {
+			return nil, err
+		}
+		if loginData == nil {
+			return nil, fmt.Errorf("got nil response from GenerateLoginData")
+		}
+
+	case rolesAnywhereAuthType:
+		cert, key, err := loadRolesAnywhereCredentials(m["cert"], m["key"])
+		if err != nil {
+			return nil, err
+		}
+		loginData, err = GenerateRolesAnywhereLoginData(cert, key, m["trust_anchor_arn"], m["profile_arn"], m["role_arn"], headerValue, region)
+		if err != nil {
+			return nil, err
+		}
+
+	case eksPodIdentityAuthType:
+		tokenFile := m["token_file"]
+		if tokenFile == "" {
+			tokenFile = os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE")
+		}
+		if tokenFile == "" {
+			return nil, fmt.Errorf("'token_file' must be specified when AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE is not set")
+		}
+		token, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		loginData = map[string]interface{}{
+			"eks_pod_identity_token": strings.TrimSpace(string(token)),
+			"eks_cluster_arn":        m["cluster_arn"],
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported type %q", m["type"])
 	}
 	loginData["role"] = role
 	path := fmt.Sprintf("auth/%s/login", mount)
@@ -173,6 +350,15 @@ Usage: vault login -method=aws [CONFIG K=V...]
 
       $ vault login -method=aws aws_access_key_id=... aws_secret_access_key=...
 
+  Authenticate with a certificate registered with IAM Roles Anywhere:
+
+      $ vault login -method=aws type=rolesanywhere cert=cert.pem key=key.pem \
+          trust_anchor_arn=... profile_arn=... role_arn=... region=us-east-1
+
+  Authenticate from a pod using EKS Pod Identity:
+
+      $ vault login -method=aws type=eks_pod_identity cluster_arn=... role=...
+
 Configuration:
 
   aws_access_key_id=<string>
@@ -184,17 +370,47 @@ Configuration:
   aws_security_token=<string>
       Explicit AWS security token for temporary credentials
 
+  cert=<string>
+      Path to the PEM encoded certificate when type is rolesanywhere
+
+  cluster_arn=<string>
+      ARN of the EKS cluster the pod runs in when type is eks_pod_identity
+
   header_value=<string>
       Value for the x-vault-aws-iam-server-id header in requests
 
+  key=<string>
+      Path to the PEM encoded private key when type is rolesanywhere
+
   mount=<string>
       Path where the AWS credential method is mounted. This is usually provided
       via the -path flag in the "vault login" command, but it can be specified
       here as well. If specified here, it takes precedence over the value for
       -path. The default value is "aws".
 
+  profile_arn=<string>
+      ARN of the IAM Roles Anywhere profile when type is rolesanywhere
+
+  region=<string>
+      Region to sign requests for. The default value is "us-east-1".
+
   role=<string>
       Name of the role to request a token against
+
+  role_arn=<string>
+      ARN of the IAM role to create the session for when type is rolesanywhere
+
+  token_file=<string>
+      Path to the EKS Pod Identity token when type is eks_pod_identity. The
+      default value is the AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE environment
+      variable.
+
+  trust_anchor_arn=<string>
+      ARN of the IAM Roles Anywhere trust anchor when type is rolesanywhere
+
+  type=<string>
+      Type of credentials to authenticate with: "iam", "rolesanywhere" or
+      "eks_pod_identity". The default value is "iam".
 `
 
 	return strings.TrimSpace(help)
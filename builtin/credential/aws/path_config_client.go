@@ -48,6 +48,18 @@ func (b *backend) pathConfigClient() *framework.Path {
 				Description: "The region ID for the sts_endpoint, if set.",
 			},
 
+			"rolesanywhere_endpoint": {
+				Type:        framework.TypeString,
+				Default:     "",
+				Description: "URL to override the IAM Roles Anywhere endpoint inferred from the signed request when auth_type is rolesanywhere.",
+			},
+
+			"eks_auth_endpoint": {
+				Type:        framework.TypeString,
+				Default:     "",
+				Description: "URL to override the default generated endpoint for making EKS Auth API calls when auth_type is eks_pod_identity.",
+			},
+
 			"iam_server_id_header_value": {
 				Type:        framework.TypeString,
 				Default:     "",
@@ -134,6 +146,8 @@ func (b *backend) pathConfigClientRead(ctx context.Context, req *logical.Request
 			"iam_endpoint":               clientConfig.IAMEndpoint,
 			"sts_endpoint":               clientConfig.STSEndpoint,
 			"sts_region":                 clientConfig.STSRegion,
+			"rolesanywhere_endpoint":     clientConfig.RolesAnywhereEndpoint,
+			"eks_auth_endpoint":          clientConfig.EKSAuthEndpoint,
 			"iam_server_id_header_value": clientConfig.IAMServerIdHeaderValue,
 			"max_retries":                clientConfig.MaxRetries,
 		},
@@ -246,6 +260,28 @@ func (b *backend) pathConfigClientCreateUpdate(ctx context.Context, req *logical
 		}
 	}
 
+	rolesAnywhereEndpointStr, ok := data.GetOk("rolesanywhere_endpoint")
+	if ok {
+		if configEntry.RolesAnywhereEndpoint != rolesAnywhereEndpointStr.(string) {
+			// NOT setting changedCreds here, since Roles Anywhere requests
+			// are not made with cached clients
+			configEntry.RolesAnywhereEndpoint = rolesAnywhereEndpointStr.(string)
+			changedOtherConfig = true
+		}
+	} else if req.Operation == logical.CreateOperation {
+		configEntry.RolesAnywhereEndpoint = data.Get("rolesanywhere_endpoint").(string)
+	}
+
+	eksAuthEndpointStr, ok := data.GetOk("eks_auth_endpoint")
+	if ok {
+		if configEntry.EKSAuthEndpoint != eksAuthEndpointStr.(string) {
+			configEntry.EKSAuthEndpoint = eksAuthEndpointStr.(string)
+			changedOtherConfig = true
+		}
+	} else if req.Operation == logical.CreateOperation {
+		configEntry.EKSAuthEndpoint = data.Get("eks_auth_endpoint").(string)
+	}
+
 	headerValStr, ok := data.GetOk("iam_server_id_header_value")
 	if ok {
 		if configEntry.IAMServerIdHeaderValue != headerValStr.(string) {
@@ -299,6 +335,8 @@ type clientConfig struct {
 	IAMEndpoint            string `json:"iam_endpoint"`
 	STSEndpoint            string `json:"sts_endpoint"`
 	STSRegion              string `json:"sts_region"`
+	RolesAnywhereEndpoint  string `json:"rolesanywhere_endpoint"`
+	EKSAuthEndpoint        string `json:"eks_auth_endpoint"`
 	IAMServerIdHeaderValue string `json:"iam_server_id_header_value"`
 	MaxRetries             int    `json:"max_retries"`
 }
@@ -315,4 +353,5 @@ has permission to make the following API queries:
 
 * ec2:DescribeInstances
 * iam:GetInstanceProfile (if IAM Role binding is used)
+* eks-auth:AssumeRoleForPodIdentity (if the eks_pod_identity auth type is used)
 `
@@ -29,6 +29,14 @@ var (
 			"inferred_aws_region",
 			"inferred_entity_id",
 			"inferred_entity_type",
+			"trust_anchor_arn",
+			"profile_arn",
+			"subject_arn",
+			"certificate_subject",
+			"eks_cluster_arn",
+			"pod_identity_association_arn",
+			"kubernetes_namespace",
+			"kubernetes_service_account",
 		},
 	}
 
@@ -36,6 +36,8 @@ const (
 	reauthenticationDisabledNonce = "reauthentication-disabled-nonce"
 	iamAuthType                   = "iam"
 	ec2AuthType                   = "ec2"
+	rolesAnywhereAuthType         = "rolesanywhere"
+	eksPodIdentityAuthType        = "eks_pod_identity"
 	ec2EntityType                 = "ec2_instance"
 
 	// Retry configuration
@@ -100,6 +102,35 @@ This must match the request body included in the signature.`,
 sts:GetCallerIdentity HTTP requests headers when auth_type is iam. Can be either 
 a Base64-encoded, JSON-serialized string, or a JSON object of key/value pairs. 
 This must at a minimum include the headers over which AWS has included a  signature.`,
+			},
+			"rolesanywhere_request_url": {
+				Type: framework.TypeString,
+				Description: `Base64-encoded full URL of the IAM Roles Anywhere CreateSession
+request when auth_type is rolesanywhere.`,
+			},
+			"rolesanywhere_request_body": {
+				Type: framework.TypeString,
+				Description: `Base64-encoded body of the IAM Roles Anywhere CreateSession request
+when auth_type is rolesanywhere. This must match the request body included in
+the signature.`,
+			},
+			"rolesanywhere_request_headers": {
+				Type: framework.TypeHeader,
+				Description: `Key/value pairs of headers of the IAM Roles Anywhere CreateSession
+request when auth_type is rolesanywhere. Can be either a Base64-encoded,
+JSON-serialized string, or a JSON object of key/value pairs. This must include
+the X-Amz-X509 certificate header and the headers over which the certificate's
+private key has included a signature.`,
+			},
+			"eks_pod_identity_token": {
+				Type: framework.TypeString,
+				Description: `The EKS Pod Identity service account token of the pod when
+auth_type is eks_pod_identity.`,
+			},
+			"eks_cluster_arn": {
+				Type: framework.TypeString,
+				Description: `ARN of the EKS cluster the pod runs in when auth_type is
+eks_pod_identity.`,
 			},
 			"identity": {
 				Type: framework.TypeString,
@@ -363,9 +394,21 @@ func (b *backend) parseIdentityDocument(ctx context.Context, s logical.Storage,
 func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	anyEc2, allEc2 := hasValuesForEc2Auth(data)
 	anyIam, allIam := hasValuesForIamAuth(data)
+	anyRolesAnywhere, allRolesAnywhere := hasValuesForRolesAnywhereAuth(data)
+	anyEKS, allEKS := hasValuesForEKSPodIdentityAuth(data)
+
+	numTypes := 0
+	for _, hasAny := range []bool{anyEc2, anyIam, anyRolesAnywhere, anyEKS} {
+		if hasAny {
+			numTypes++
+		}
+	}
+
 	switch {
 	case anyEc2 && anyIam:
 		return logical.ErrorResponse("supplied auth values for both ec2 and iam auth types"), nil
+	case numTypes > 1:
+		return logical.ErrorResponse("supplied auth values for more than one auth type"), nil
 	case anyEc2 && !allEc2:
 		return logical.ErrorResponse("supplied some of the auth values for the ec2 auth type but not all"), nil
 	case anyEc2:
@@ -374,6 +417,14 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 		return logical.ErrorResponse("supplied some of the auth values for the iam auth type but not all"), nil
 	case anyIam:
 		return b.pathLoginUpdateIam(ctx, req, data)
+	case anyRolesAnywhere && !allRolesAnywhere:
+		return logical.ErrorResponse("supplied some of the auth values for the rolesanywhere auth type but not all"), nil
+	case anyRolesAnywhere:
+		return b.pathLoginUpdateRolesAnywhere(ctx, req, data)
+	case anyEKS && !allEKS:
+		return logical.ErrorResponse("supplied some of the auth values for the eks_pod_identity auth type but not all"), nil
+	case anyEKS:
+		return b.pathLoginUpdateEKSPodIdentity(ctx, req, data)
 	default:
 		return logical.ErrorResponse("didn't supply required authentication values"), nil
 	}
@@ -952,6 +1003,17 @@ func (b *backend) handleRoleTagLogin(ctx context.Context, s logical.Storage, rol
 
 // pathLoginRenew is used to renew an authenticated token
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// The newer auth types record the auth type internally, as the auth
+	// metadata can be configured to leave it out
+	if authType, ok := req.Auth.InternalData["auth_type"]; ok {
+		switch authType {
+		case rolesAnywhereAuthType:
+			return b.pathLoginRenewRolesAnywhere(ctx, req, data)
+		case eksPodIdentityAuthType:
+			return b.pathLoginRenewEKSPodIdentity(ctx, req, data)
+		}
+	}
+
 	authType, ok := req.Auth.Metadata["auth_type"]
 	if !ok {
 		// backwards compatibility for clients that have leases from before we added auth_type
@@ -1576,20 +1638,8 @@ func submitCallerIdentityRequest(ctx context.Context, maxRetries int, method, en
 		return nil, err
 	}
 	retryableReq = retryableReq.WithContext(ctx)
-	client := cleanhttp.DefaultClient()
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		return http.ErrUseLastResponse
-	}
-	retryingClient := &retryablehttp.Client{
-		HTTPClient:   client,
-		RetryWaitMin: retryWaitMin,
-		RetryWaitMax: retryWaitMax,
-		RetryMax:     maxRetries,
-		CheckRetry:   retryablehttp.DefaultRetryPolicy,
-		Backoff:      retryablehttp.DefaultBackoff,
-	}
 
-	response, err := retryingClient.Do(retryableReq)
+	response, err := newRetryingClient(maxRetries).Do(retryableReq)
 	if err != nil {
 		return nil, errwrap.Wrapf("error making request: {{err}}", err)
 	}
@@ -1611,6 +1661,23 @@ func submitCallerIdentityRequest(ctx context.Context, maxRetries int, method, en
 	return &callerIdentityResponse.GetCallerIdentityResult[0], nil
 }
 
+// newRetryingClient returns a client for replaying login requests to AWS,
+// which does not follow redirects.
+func newRetryingClient(maxRetries int) *retryablehttp.Client {
+	client := cleanhttp.DefaultClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &retryablehttp.Client{
+		HTTPClient:   client,
+		RetryWaitMin: retryWaitMin,
+		RetryWaitMax: retryWaitMax,
+		RetryMax:     maxRetries,
+		CheckRetry:   retryablehttp.DefaultRetryPolicy,
+		Backoff:      retryablehttp.DefaultBackoff,
+	}
+}
+
 type GetCallerIdentityResponse struct {
 	XMLName                 xml.Name                  `xml:"GetCallerIdentityResponse"`
 	GetCallerIdentityResult []GetCallerIdentityResult `xml:"GetCallerIdentityResult"`
@@ -1748,6 +1815,11 @@ the caller can be inferred to be another AWS entity type, with EC2 instances
 the only currently supported entity type, and additional filtering can be
 implemented based on that inferred type.
 
+Workloads outside of EC2 holding an X.509 certificate are authenticated by
+replaying a CreateSession request to IAM Roles Anywhere signed with the
+certificate's private key. Pods on EKS are authenticated by exchanging their
+EKS Pod Identity token with the EKS Auth API.
+
 An EC2 instance is authenticated using the PKCS#7 signature of the instance identity
 document and a client created nonce. This nonce should be unique and should be used by
 the instance for all future logins, unless 'disallow_reauthentication' option on the
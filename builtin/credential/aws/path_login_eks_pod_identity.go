@@ -0,0 +1,314 @@
+package awsauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+	awsClient "github.com/aws/aws-sdk-go/aws/client"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// eksAuthSigningName is the name the EKS Auth API is signed for.
+const eksAuthSigningName = "eks-auth"
+
+// eksCluster is a parsed EKS cluster ARN.
+type eksCluster struct {
+	Region string
+	Name   string
+}
+
+// podIdentityResponse holds the fields of interest of an EKS Auth
+// AssumeRoleForPodIdentity response body.
+type podIdentityResponse struct {
+	Subject struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount string `json:"serviceAccount"`
+	} `json:"subject"`
+	PodIdentityAssociation struct {
+		AssociationArn string `json:"associationArn"`
+	} `json:"podIdentityAssociation"`
+	AssumedRoleUser struct {
+		Arn          string `json:"arn"`
+		AssumeRoleId string `json:"assumeRoleId"`
+	} `json:"assumedRoleUser"`
+}
+
+func (b *backend) pathLoginUpdateEKSPodIdentity(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	token := data.Get("eks_pod_identity_token").(string)
+	if token == "" {
+		return logical.ErrorResponse("missing eks_pod_identity_token"), nil
+	}
+
+	clusterArn := data.Get("eks_cluster_arn").(string)
+	if clusterArn == "" {
+		return logical.ErrorResponse("missing eks_cluster_arn"), nil
+	}
+	cluster, err := parseEKSClusterArn(clusterArn)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing eks_cluster_arn: %v", err)), nil
+	}
+
+	roleName := data.Get("role").(string)
+	if roleName == "" {
+		return logical.ErrorResponse("missing role"), nil
+	}
+
+	roleEntry, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+	}
+
+	// Check for a CIDR match.
+	if len(roleEntry.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
+			return nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, roleEntry.TokenBoundCIDRs) {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	if roleEntry.AuthType != eksPodIdentityAuthType {
+		return logical.ErrorResponse(fmt.Sprintf("auth method eks_pod_identity not allowed for role %s", roleName)), nil
+	}
+
+	// The cluster is checked before calling AWS, as it determines which
+	// endpoint the token is sent to
+	if !strutil.StrListContains(roleEntry.BoundEKSClusterARNs, clusterArn) {
+		return logical.ErrorResponse(fmt.Sprintf("EKS cluster %q is not bound for role %q", clusterArn, roleName)), nil
+	}
+
+	podIdentity, err := b.assumeRoleForPodIdentity(ctx, req.Storage, cluster, token)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error making upstream request: %v", err)), nil
+	}
+
+	entity, err := parseIamArn(podIdentity.AssumedRoleUser.Arn)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing arn %q: %v", podIdentity.AssumedRoleUser.Arn, err)), nil
+	}
+
+	identityConfigEntry, err := identityConfigEntry(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	callerUniqueId := strings.Split(podIdentity.AssumedRoleUser.AssumeRoleId, ":")[0]
+	identityAlias := ""
+	switch identityConfigEntry.IAMAlias {
+	case identityAliasRoleID:
+		identityAlias = roleEntry.RoleID
+	case identityAliasIAMUniqueID:
+		identityAlias = callerUniqueId
+	case identityAliasIAMFullArn:
+		identityAlias = podIdentity.AssumedRoleUser.Arn
+	}
+
+	// If we're just looking up for MFA, return the Alias info
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: identityAlias,
+				},
+			},
+		}, nil
+	}
+
+	associationArn := podIdentity.PodIdentityAssociation.AssociationArn
+	namespace := podIdentity.Subject.Namespace
+	serviceAccount := podIdentity.Subject.ServiceAccount
+	if err := roleEntry.matchesPodIdentity(clusterArn, associationArn, namespace, serviceAccount); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("%s for role %q", err, roleName)), nil
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"role_id": roleEntry.RoleID,
+		},
+		InternalData: map[string]interface{}{
+			"role_name":                    roleName,
+			"role_id":                      roleEntry.RoleID,
+			"auth_type":                    eksPodIdentityAuthType,
+			"eks_cluster_arn":              clusterArn,
+			"pod_identity_association_arn": associationArn,
+			"kubernetes_namespace":         namespace,
+			"kubernetes_service_account":   serviceAccount,
+		},
+		DisplayName: fmt.Sprintf("%s-%s", namespace, serviceAccount),
+		Alias: &logical.Alias{
+			Name: identityAlias,
+		},
+	}
+	roleEntry.PopulateTokenAuth(auth)
+	if err := identityConfigEntry.IAMAuthMetadataHandler.PopulateDesiredMetadata(auth, map[string]string{
+		"client_arn":                   podIdentity.AssumedRoleUser.Arn,
+		"canonical_arn":                entity.canonicalArn(),
+		"client_user_id":               callerUniqueId,
+		"auth_type":                    eksPodIdentityAuthType,
+		"account_id":                   entity.AccountNumber,
+		"eks_cluster_arn":              clusterArn,
+		"pod_identity_association_arn": associationArn,
+		"kubernetes_namespace":         namespace,
+		"kubernetes_service_account":   serviceAccount,
+	}); err != nil {
+		b.Logger().Warn(fmt.Sprintf("unable to set alias metadata due to %s", err))
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenewEKSPodIdentity(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleEntry, err := b.renewRoleEntry(ctx, req, eksPodIdentityAuthType)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterArn, _ := req.Auth.InternalData["eks_cluster_arn"].(string)
+	associationArn, _ := req.Auth.InternalData["pod_identity_association_arn"].(string)
+	namespace, _ := req.Auth.InternalData["kubernetes_namespace"].(string)
+	serviceAccount, _ := req.Auth.InternalData["kubernetes_service_account"].(string)
+	if err := roleEntry.matchesPodIdentity(clusterArn, associationArn, namespace, serviceAccount); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.TTL = roleEntry.TokenTTL
+	resp.Auth.MaxTTL = roleEntry.TokenMaxTTL
+	resp.Auth.Period = roleEntry.TokenPeriod
+	return resp, nil
+}
+
+// matchesPodIdentity checks the identity of an EKS pod against the binds of
+// the role.
+func (r *awsRoleEntry) matchesPodIdentity(clusterArn, associationArn, namespace, serviceAccount string) error {
+	if !strutil.StrListContains(r.BoundEKSClusterARNs, clusterArn) {
+		return fmt.Errorf("EKS cluster %q is not bound", clusterArn)
+	}
+	if len(r.BoundPodIdentityAssociationARNs) > 0 && !matchesArnBind(r.BoundPodIdentityAssociationARNs, associationArn) {
+		return fmt.Errorf("pod identity association %q is not bound", associationArn)
+	}
+	if len(r.BoundKubernetesNamespaces) > 0 && !strutil.StrListContains(r.BoundKubernetesNamespaces, namespace) {
+		return fmt.Errorf("namespace %q is not bound", namespace)
+	}
+	if len(r.BoundKubernetesServiceAccounts) > 0 && !strutil.StrListContains(r.BoundKubernetesServiceAccounts, serviceAccount) {
+		return fmt.Errorf("service account %q is not bound", serviceAccount)
+	}
+	return nil
+}
+
+// assumeRoleForPodIdentity exchanges a pod identity token with the EKS Auth
+// API. Unlike the other auth types the request is signed by Vault, so the
+// configured credentials must be allowed eks-auth:AssumeRoleForPodIdentity.
+func (b *backend) assumeRoleForPodIdentity(ctx context.Context, s logical.Storage, cluster *eksCluster, token string) (*podIdentityResponse, error) {
+	b.configMutex.RLock()
+	defer b.configMutex.RUnlock()
+
+	config, err := b.nonLockedClientConfigEntry(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("https://eks-auth.%s.api.aws", cluster.Region)
+	maxRetries := awsClient.DefaultRetryerMaxNumRetries
+	if config != nil {
+		if config.EKSAuthEndpoint != "" {
+			endpoint = config.EKSAuthEndpoint
+		}
+		if config.MaxRetries >= 0 {
+			maxRetries = config.MaxRetries
+		}
+	}
+
+	awsConfig, err := b.getRawClientConfig(ctx, s, cluster.Region, eksAuthSigningName)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/clusters/%s/assume-role-for-pod-identity", strings.TrimSuffix(endpoint, "/"), cluster.Name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if _, err := v4.NewSigner(awsConfig.Credentials).Sign(request, bytes.NewReader(body), eksAuthSigningName, cluster.Region, time.Now()); err != nil {
+		return nil, errwrap.Wrapf("error signing request: {{err}}", err)
+	}
+
+	retryableReq, err := retryablehttp.FromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	retryableReq = retryableReq.WithContext(ctx)
+
+	response, err := newRetryingClient(maxRetries).Do(retryableReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("error making request: {{err}}", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received error code %d from EKS Auth: %s", response.StatusCode, string(responseBody))
+	}
+
+	var result podIdentityResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("error parsing EKS Auth response")
+	}
+	return &result, nil
+}
+
+// parseEKSClusterArn parses an ARN of the form
+// arn:<partition>:eks:<region>:<account_id>:cluster/<name>
+func parseEKSClusterArn(clusterArn string) (*eksCluster, error) {
+	parsed, err := arn.Parse(clusterArn)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Service != "eks" {
+		return nil, fmt.Errorf("expected service eks but got %q", parsed.Service)
+	}
+	if parsed.Region == "" {
+		return nil, fmt.Errorf("missing region")
+	}
+	name := strings.TrimPrefix(parsed.Resource, "cluster/")
+	if name == parsed.Resource || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("expected resource cluster/<name> but got %q", parsed.Resource)
+	}
+	return &eksCluster{
+		Region: parsed.Region,
+		Name:   name,
+	}, nil
+}
+
+func hasValuesForEKSPodIdentityAuth(data *framework.FieldData) (bool, bool) {
+	_, hasToken := data.GetOk("eks_pod_identity_token")
+	_, hasClusterArn := data.GetOk("eks_cluster_arn")
+	return (hasToken && hasClusterArn), (hasToken || hasClusterArn)
+}
@@ -0,0 +1,367 @@
+package awsauth
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	awsClient "github.com/aws/aws-sdk-go/aws/client"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// rolesAnywhereX509Header carries the base64-encoded DER certificate
+	// whose private key signed a Roles Anywhere request.
+	rolesAnywhereX509Header = "X-Amz-X509"
+
+	rolesAnywhereSessionsPath = "/sessions"
+)
+
+// rolesAnywhereHostRegex matches the hosts of the public IAM Roles Anywhere
+// endpoints. Requests are only replayed to other hosts when an endpoint is
+// configured, so that Vault cannot be used as an arbitrary network proxy.
+var rolesAnywhereHostRegex = regexp.MustCompile(`^rolesanywhere\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// rolesAnywhereSessionRequest holds the fields of interest of a Roles
+// Anywhere CreateSession request body.
+type rolesAnywhereSessionRequest struct {
+	TrustAnchorArn string `json:"trustAnchorArn"`
+	ProfileArn     string `json:"profileArn"`
+	RoleArn        string `json:"roleArn"`
+}
+
+// rolesAnywhereSessionResponse holds the fields of interest of a Roles
+// Anywhere CreateSession response body.
+type rolesAnywhereSessionResponse struct {
+	CredentialSet []struct {
+		AssumedRoleUser struct {
+			Arn           string `json:"arn"`
+			AssumedRoleId string `json:"assumedRoleId"`
+		} `json:"assumedRoleUser"`
+	} `json:"credentialSet"`
+	SubjectArn string `json:"subjectArn"`
+}
+
+func (b *backend) pathLoginUpdateRolesAnywhere(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	rawUrlB64 := data.Get("rolesanywhere_request_url").(string)
+	if rawUrlB64 == "" {
+		return logical.ErrorResponse("missing rolesanywhere_request_url"), nil
+	}
+	rawUrl, err := base64.StdEncoding.DecodeString(rawUrlB64)
+	if err != nil {
+		return logical.ErrorResponse("failed to base64 decode rolesanywhere_request_url"), nil
+	}
+	parsedUrl, err := url.Parse(string(rawUrl))
+	if err != nil {
+		return logical.ErrorResponse("error parsing rolesanywhere_request_url"), nil
+	}
+	if parsedUrl.Path != rolesAnywhereSessionsPath {
+		return logical.ErrorResponse(fmt.Sprintf("rolesanywhere_request_url must be a CreateSession request to %q", rolesAnywhereSessionsPath)), nil
+	}
+
+	bodyB64 := data.Get("rolesanywhere_request_body").(string)
+	if bodyB64 == "" {
+		return logical.ErrorResponse("missing rolesanywhere_request_body"), nil
+	}
+	bodyRaw, err := base64.StdEncoding.DecodeString(bodyB64)
+	if err != nil {
+		return logical.ErrorResponse("failed to base64 decode rolesanywhere_request_body"), nil
+	}
+	var session rolesAnywhereSessionRequest
+	if err := json.Unmarshal(bodyRaw, &session); err != nil {
+		return logical.ErrorResponse("error parsing rolesanywhere_request_body"), nil
+	}
+	if session.TrustAnchorArn == "" {
+		return logical.ErrorResponse("rolesanywhere_request_body is missing trustAnchorArn"), nil
+	}
+
+	headers := data.Get("rolesanywhere_request_headers").(http.Header)
+	if len(headers) == 0 {
+		return logical.ErrorResponse("missing rolesanywhere_request_headers"), nil
+	}
+	cert, err := parseRolesAnywhereCertificate(headers)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	config, err := b.lockedClientConfigEntry(ctx, req.Storage)
+	if err != nil {
+		return logical.ErrorResponse("error getting configuration"), nil
+	}
+
+	endpoint := ""
+	maxRetries := awsClient.DefaultRetryerMaxNumRetries
+	if config != nil {
+		if config.IAMServerIdHeaderValue != "" {
+			err = validateVaultHeaderValue(headers, parsedUrl, config.IAMServerIdHeaderValue)
+			if err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("error validating %s header: %v", iamServerIdHeader, err)), nil
+			}
+		}
+		endpoint = config.RolesAnywhereEndpoint
+		if config.MaxRetries >= 0 {
+			maxRetries = config.MaxRetries
+		}
+	}
+	if endpoint == "" {
+		if !rolesAnywhereHostRegex.MatchString(parsedUrl.Host) {
+			return logical.ErrorResponse(fmt.Sprintf("rolesanywhere_request_url host %q is not an IAM Roles Anywhere endpoint", parsedUrl.Host)), nil
+		}
+		endpoint = "https://" + parsedUrl.Host
+	}
+
+	sessionResp, err := submitRolesAnywhereRequest(ctx, maxRetries, endpoint, parsedUrl, string(bodyRaw), headers)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error making upstream request: %v", err)), nil
+	}
+	assumedRoleUser := sessionResp.CredentialSet[0].AssumedRoleUser
+
+	entity, err := parseIamArn(assumedRoleUser.Arn)
+	if err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("error parsing arn %q: %v", assumedRoleUser.Arn, err)), nil
+	}
+
+	roleName := data.Get("role").(string)
+	if roleName == "" {
+		roleName = entity.FriendlyName
+	}
+
+	roleEntry, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return logical.ErrorResponse(fmt.Sprintf("entry for role %s not found", roleName)), nil
+	}
+
+	// Check for a CIDR match.
+	if len(roleEntry.TokenBoundCIDRs) > 0 {
+		if req.Connection == nil {
+			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
+			return nil, logical.ErrPermissionDenied
+		}
+		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, roleEntry.TokenBoundCIDRs) {
+			return nil, logical.ErrPermissionDenied
+		}
+	}
+
+	if roleEntry.AuthType != rolesAnywhereAuthType {
+		return logical.ErrorResponse(fmt.Sprintf("auth method rolesanywhere not allowed for role %s", roleName)), nil
+	}
+
+	identityConfigEntry, err := identityConfigEntry(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	callerUniqueId := strings.Split(assumedRoleUser.AssumedRoleId, ":")[0]
+	identityAlias := ""
+	switch identityConfigEntry.IAMAlias {
+	case identityAliasRoleID:
+		identityAlias = roleEntry.RoleID
+	case identityAliasIAMUniqueID:
+		identityAlias = callerUniqueId
+	case identityAliasIAMFullArn:
+		identityAlias = assumedRoleUser.Arn
+	}
+
+	// If we're just looking up for MFA, return the Alias info
+	if req.Operation == logical.AliasLookaheadOperation {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				Alias: &logical.Alias{
+					Name: identityAlias,
+				},
+			},
+		}, nil
+	}
+
+	// The trust anchor and profile are those of the request body, which AWS
+	// validated the certificate against when creating the session
+	if err := roleEntry.matchesRolesAnywhereSession(session.TrustAnchorArn, session.ProfileArn); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("%s for role %q", err, roleName)), nil
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"role_id": roleEntry.RoleID,
+		},
+		InternalData: map[string]interface{}{
+			"role_name":        roleName,
+			"role_id":          roleEntry.RoleID,
+			"auth_type":        rolesAnywhereAuthType,
+			"trust_anchor_arn": session.TrustAnchorArn,
+			"profile_arn":      session.ProfileArn,
+		},
+		DisplayName: cert.Subject.CommonName,
+		Alias: &logical.Alias{
+			Name: identityAlias,
+		},
+	}
+	roleEntry.PopulateTokenAuth(auth)
+	if err := identityConfigEntry.IAMAuthMetadataHandler.PopulateDesiredMetadata(auth, map[string]string{
+		"client_arn":          assumedRoleUser.Arn,
+		"canonical_arn":       entity.canonicalArn(),
+		"client_user_id":      callerUniqueId,
+		"auth_type":           rolesAnywhereAuthType,
+		"account_id":          entity.AccountNumber,
+		"trust_anchor_arn":    session.TrustAnchorArn,
+		"profile_arn":         session.ProfileArn,
+		"subject_arn":         sessionResp.SubjectArn,
+		"certificate_subject": cert.Subject.String(),
+	}); err != nil {
+		b.Logger().Warn(fmt.Sprintf("unable to set alias metadata due to %s", err))
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+func (b *backend) pathLoginRenewRolesAnywhere(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	roleEntry, err := b.renewRoleEntry(ctx, req, rolesAnywhereAuthType)
+	if err != nil {
+		return nil, err
+	}
+
+	trustAnchorArn, _ := req.Auth.InternalData["trust_anchor_arn"].(string)
+	profileArn, _ := req.Auth.InternalData["profile_arn"].(string)
+	if err := roleEntry.matchesRolesAnywhereSession(trustAnchorArn, profileArn); err != nil {
+		return nil, err
+	}
+
+	resp := &logical.Response{Auth: req.Auth}
+	resp.Auth.TTL = roleEntry.TokenTTL
+	resp.Auth.MaxTTL = roleEntry.TokenMaxTTL
+	resp.Auth.Period = roleEntry.TokenPeriod
+	return resp, nil
+}
+
+// renewRoleEntry returns the role a token being renewed was issued against,
+// ensuring it still permits the token's auth type.
+func (b *backend) renewRoleEntry(ctx context.Context, req *logical.Request, authType string) (*awsRoleEntry, error) {
+	roleName, _ := req.Auth.InternalData["role_name"].(string)
+	if roleName == "" {
+		return nil, fmt.Errorf("error retrieving role_name during renewal")
+	}
+	roleEntry, err := b.role(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if roleEntry == nil {
+		return nil, fmt.Errorf("role entry not found")
+	}
+	if roleEntry.AuthType != authType {
+		return nil, fmt.Errorf("role %q no longer allows auth_type %q", roleName, authType)
+	}
+	return roleEntry, nil
+}
+
+// matchesRolesAnywhereSession checks a Roles Anywhere session against the
+// binds of the role.
+func (r *awsRoleEntry) matchesRolesAnywhereSession(trustAnchorArn, profileArn string) error {
+	if !matchesArnBind(r.BoundTrustAnchorARNs, trustAnchorArn) {
+		return fmt.Errorf("trust anchor %q is not bound", trustAnchorArn)
+	}
+	if len(r.BoundRolesAnywhereProfileARNs) > 0 && !matchesArnBind(r.BoundRolesAnywhereProfileARNs, profileArn) {
+		return fmt.Errorf("profile %q is not bound", profileArn)
+	}
+	return nil
+}
+
+// matchesArnBind returns whether arn is one of binds, or matches one of the
+// binds ending in a '*' wildcard.
+func matchesArnBind(binds []string, arn string) bool {
+	if arn == "" {
+		return false
+	}
+	for _, bind := range binds {
+		if bind == arn {
+			return true
+		}
+		if strings.HasSuffix(bind, "*") && strutil.GlobbedStringsMatch(bind, arn) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasValuesForRolesAnywhereAuth(data *framework.FieldData) (bool, bool) {
+	_, hasRequestURL := data.GetOk("rolesanywhere_request_url")
+	_, hasRequestBody := data.GetOk("rolesanywhere_request_body")
+	_, hasRequestHeaders := data.GetOk("rolesanywhere_request_headers")
+	return (hasRequestURL && hasRequestBody && hasRequestHeaders),
+		(hasRequestURL || hasRequestBody || hasRequestHeaders)
+}
+
+// parseRolesAnywhereCertificate returns the certificate a Roles Anywhere
+// request was signed with. The certificate is not verified here; AWS
+// verifies it against the trust anchor when the request is replayed.
+func parseRolesAnywhereCertificate(headers http.Header) (*x509.Certificate, error) {
+	var raw string
+	for k, v := range headers {
+		if strings.EqualFold(rolesAnywhereX509Header, k) && len(v) > 0 {
+			raw = v[0]
+			break
+		}
+	}
+	if raw == "" {
+		return nil, fmt.Errorf("missing header %q", rolesAnywhereX509Header)
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to base64 decode header %q", rolesAnywhereX509Header)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("error parsing header %q: {{err}}", rolesAnywhereX509Header), err)
+	}
+	return cert, nil
+}
+
+func submitRolesAnywhereRequest(ctx context.Context, maxRetries int, endpoint string, parsedUrl *url.URL, body string, headers http.Header) (*rolesAnywhereSessionResponse, error) {
+	request := buildHttpRequest(http.MethodPost, endpoint, parsedUrl, body, headers)
+	if request == nil {
+		return nil, fmt.Errorf("error building request")
+	}
+	retryableReq, err := retryablehttp.FromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	retryableReq = retryableReq.WithContext(ctx)
+
+	response, err := newRetryingClient(maxRetries).Do(retryableReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("error making request: {{err}}", err)
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received error code %d from IAM Roles Anywhere: %s", response.StatusCode, string(responseBody))
+	}
+
+	var result rolesAnywhereSessionResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("error parsing IAM Roles Anywhere response")
+	}
+	if len(result.CredentialSet) == 0 {
+		return nil, fmt.Errorf("IAM Roles Anywhere response has no credentials")
+	}
+	return &result, nil
+}
@@ -31,7 +31,8 @@ func (b *backend) pathRole() *framework.Path {
 			"auth_type": {
 				Type: framework.TypeString,
 				Description: `The auth_type permitted to authenticate to this role. Must be one of
-iam or ec2 and cannot be changed after role creation.`,
+iam, ec2, rolesanywhere or eks_pod_identity and cannot be changed after role
+creation.`,
 			},
 			"bound_ami_id": {
 				Type: framework.TypeCommaStringSlice,
@@ -81,6 +82,43 @@ auth_type is ec2 or inferred_entity_type is ec2_instance.`,
 given instance IDs. Can be a list or comma-separated string of EC2 instance
 IDs. This is only applicable when auth_type is ec2 or inferred_entity_type is
 ec2_instance.`,
+			},
+			"bound_trust_anchor_arn": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `ARNs of the IAM Roles Anywhere trust anchors to bind to this role.
+Values ending in '*' are matched as globs. Required, and only applicable, when
+auth_type is rolesanywhere.`,
+			},
+			"bound_rolesanywhere_profile_arn": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `If set, defines a constraint on the IAM Roles Anywhere profile
+the session is created with to match one of the ARNs specified by this
+parameter. Values ending in '*' are matched as globs. This is only applicable
+when auth_type is rolesanywhere.`,
+			},
+			"bound_eks_cluster_arn": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `ARNs of the EKS clusters whose pods may authenticate to this role.
+Required, and only applicable, when auth_type is eks_pod_identity.`,
+			},
+			"bound_pod_identity_association_arn": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `If set, defines a constraint on the EKS Pod Identity association
+of the pod to match one of the ARNs specified by this parameter. Values ending
+in '*' are matched as globs. This is only applicable when auth_type is
+eks_pod_identity.`,
+			},
+			"bound_kubernetes_namespace": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `If set, defines a constraint on the pod to run in one of the
+Kubernetes namespaces specified by this parameter. This is only applicable when
+auth_type is eks_pod_identity.`,
+			},
+			"bound_kubernetes_service_account": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `If set, defines a constraint on the pod to run as one of the
+Kubernetes service accounts specified by this parameter. This is only
+applicable when auth_type is eks_pod_identity.`,
 			},
 			"resolve_aws_unique_ids": {
 				Type:    framework.TypeBool,
@@ -669,6 +707,30 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		roleEntry.BoundSubnetIDs = boundSubnetIDRaw.([]string)
 	}
 
+	if boundTrustAnchorARNRaw, ok := data.GetOk("bound_trust_anchor_arn"); ok {
+		roleEntry.BoundTrustAnchorARNs = boundTrustAnchorARNRaw.([]string)
+	}
+
+	if boundRolesAnywhereProfileARNRaw, ok := data.GetOk("bound_rolesanywhere_profile_arn"); ok {
+		roleEntry.BoundRolesAnywhereProfileARNs = boundRolesAnywhereProfileARNRaw.([]string)
+	}
+
+	if boundEKSClusterARNRaw, ok := data.GetOk("bound_eks_cluster_arn"); ok {
+		roleEntry.BoundEKSClusterARNs = boundEKSClusterARNRaw.([]string)
+	}
+
+	if boundPodIdentityAssociationARNRaw, ok := data.GetOk("bound_pod_identity_association_arn"); ok {
+		roleEntry.BoundPodIdentityAssociationARNs = boundPodIdentityAssociationARNRaw.([]string)
+	}
+
+	if boundKubernetesNamespaceRaw, ok := data.GetOk("bound_kubernetes_namespace"); ok {
+		roleEntry.BoundKubernetesNamespaces = boundKubernetesNamespaceRaw.([]string)
+	}
+
+	if boundKubernetesServiceAccountRaw, ok := data.GetOk("bound_kubernetes_service_account"); ok {
+		roleEntry.BoundKubernetesServiceAccounts = boundKubernetesServiceAccountRaw.([]string)
+	}
+
 	if resolveAWSUniqueIDsRaw, ok := data.GetOk("resolve_aws_unique_ids"); ok {
 		switch {
 		case req.Operation == logical.CreateOperation:
@@ -726,7 +788,7 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		// auth_type should have already been upgraded to have one before we get here
 		if roleEntry.AuthType == "" {
 			switch authTypeRaw.(string) {
-			case ec2AuthType, iamAuthType:
+			case ec2AuthType, iamAuthType, rolesAnywhereAuthType, eksPodIdentityAuthType:
 				roleEntry.AuthType = authTypeRaw.(string)
 			default:
 				return logical.ErrorResponse(fmt.Sprintf("unrecognized auth_type: %v", authTypeRaw.(string))), nil
@@ -828,6 +890,57 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		numBinds++
 	}
 
+	if len(roleEntry.BoundTrustAnchorARNs) > 0 {
+		if roleEntry.AuthType != rolesAnywhereAuthType {
+			return logical.ErrorResponse("specified bound_trust_anchor_arn but not specifying rolesanywhere auth_type"), nil
+		}
+		numBinds++
+	} else if roleEntry.AuthType == rolesAnywhereAuthType {
+		return logical.ErrorResponse("bound_trust_anchor_arn is required when using rolesanywhere auth_type"), nil
+	}
+
+	if len(roleEntry.BoundRolesAnywhereProfileARNs) > 0 {
+		if roleEntry.AuthType != rolesAnywhereAuthType {
+			return logical.ErrorResponse("specified bound_rolesanywhere_profile_arn but not specifying rolesanywhere auth_type"), nil
+		}
+		numBinds++
+	}
+
+	if len(roleEntry.BoundEKSClusterARNs) > 0 {
+		if roleEntry.AuthType != eksPodIdentityAuthType {
+			return logical.ErrorResponse("specified bound_eks_cluster_arn but not specifying eks_pod_identity auth_type"), nil
+		}
+		for _, clusterARN := range roleEntry.BoundEKSClusterARNs {
+			if _, err := parseEKSClusterArn(clusterARN); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid bound_eks_cluster_arn %q: %s", clusterARN, err)), nil
+			}
+		}
+		numBinds++
+	} else if roleEntry.AuthType == eksPodIdentityAuthType {
+		return logical.ErrorResponse("bound_eks_cluster_arn is required when using eks_pod_identity auth_type"), nil
+	}
+
+	if len(roleEntry.BoundPodIdentityAssociationARNs) > 0 {
+		if roleEntry.AuthType != eksPodIdentityAuthType {
+			return logical.ErrorResponse("specified bound_pod_identity_association_arn but not specifying eks_pod_identity auth_type"), nil
+		}
+		numBinds++
+	}
+
+	if len(roleEntry.BoundKubernetesNamespaces) > 0 {
+		if roleEntry.AuthType != eksPodIdentityAuthType {
+			return logical.ErrorResponse("specified bound_kubernetes_namespace but not specifying eks_pod_identity auth_type"), nil
+		}
+		numBinds++
+	}
+
+	if len(roleEntry.BoundKubernetesServiceAccounts) > 0 {
+		if roleEntry.AuthType != eksPodIdentityAuthType {
+			return logical.ErrorResponse("specified bound_kubernetes_service_account but not specifying eks_pod_identity auth_type"), nil
+		}
+		numBinds++
+	}
+
 	if numBinds == 0 {
 		return logical.ErrorResponse("at least one bound parameter should be specified on the role"), nil
 	}
@@ -966,6 +1079,13 @@ type awsRoleEntry struct {
 	HMACKey                     string   `json:"hmac_key"`
 	Version                     int      `json:"version"`
 
+	BoundTrustAnchorARNs            []string `json:"bound_trust_anchor_arn_list"`
+	BoundRolesAnywhereProfileARNs   []string `json:"bound_rolesanywhere_profile_arn_list"`
+	BoundEKSClusterARNs             []string `json:"bound_eks_cluster_arn_list"`
+	BoundPodIdentityAssociationARNs []string `json:"bound_pod_identity_association_arn_list"`
+	BoundKubernetesNamespaces       []string `json:"bound_kubernetes_namespace_list"`
+	BoundKubernetesServiceAccounts  []string `json:"bound_kubernetes_service_account_list"`
+
 	// Deprecated: These are superceded by TokenUtil
 	TTL      time.Duration `json:"ttl"`
 	MaxTTL   time.Duration `json:"max_ttl"`
@@ -986,24 +1106,30 @@ type awsRoleEntry struct {
 
 func (r *awsRoleEntry) ToResponseData() map[string]interface{} {
 	responseData := map[string]interface{}{
-		"auth_type":                      r.AuthType,
-		"bound_ami_id":                   r.BoundAmiIDs,
-		"bound_account_id":               r.BoundAccountIDs,
-		"bound_ec2_instance_id":          r.BoundEc2InstanceIDs,
-		"bound_iam_principal_arn":        r.BoundIamPrincipalARNs,
-		"bound_iam_principal_id":         r.BoundIamPrincipalIDs,
-		"bound_iam_role_arn":             r.BoundIamRoleARNs,
-		"bound_iam_instance_profile_arn": r.BoundIamInstanceProfileARNs,
-		"bound_region":                   r.BoundRegions,
-		"bound_subnet_id":                r.BoundSubnetIDs,
-		"bound_vpc_id":                   r.BoundVpcIDs,
-		"inferred_entity_type":           r.InferredEntityType,
-		"inferred_aws_region":            r.InferredAWSRegion,
-		"resolve_aws_unique_ids":         r.ResolveAWSUniqueIDs,
-		"role_id":                        r.RoleID,
-		"role_tag":                       r.RoleTag,
-		"allow_instance_migration":       r.AllowInstanceMigration,
-		"disallow_reauthentication":      r.DisallowReauthentication,
+		"auth_type":                          r.AuthType,
+		"bound_ami_id":                       r.BoundAmiIDs,
+		"bound_account_id":                   r.BoundAccountIDs,
+		"bound_ec2_instance_id":              r.BoundEc2InstanceIDs,
+		"bound_iam_principal_arn":            r.BoundIamPrincipalARNs,
+		"bound_iam_principal_id":             r.BoundIamPrincipalIDs,
+		"bound_iam_role_arn":                 r.BoundIamRoleARNs,
+		"bound_iam_instance_profile_arn":     r.BoundIamInstanceProfileARNs,
+		"bound_region":                       r.BoundRegions,
+		"bound_subnet_id":                    r.BoundSubnetIDs,
+		"bound_vpc_id":                       r.BoundVpcIDs,
+		"bound_trust_anchor_arn":             r.BoundTrustAnchorARNs,
+		"bound_rolesanywhere_profile_arn":    r.BoundRolesAnywhereProfileARNs,
+		"bound_eks_cluster_arn":              r.BoundEKSClusterARNs,
+		"bound_pod_identity_association_arn": r.BoundPodIdentityAssociationARNs,
+		"bound_kubernetes_namespace":         r.BoundKubernetesNamespaces,
+		"bound_kubernetes_service_account":   r.BoundKubernetesServiceAccounts,
+		"inferred_entity_type":               r.InferredEntityType,
+		"inferred_aws_region":                r.InferredAWSRegion,
+		"resolve_aws_unique_ids":             r.ResolveAWSUniqueIDs,
+		"role_id":                            r.RoleID,
+		"role_tag":                           r.RoleTag,
+		"allow_instance_migration":           r.AllowInstanceMigration,
+		"disallow_reauthentication":          r.DisallowReauthentication,
 	}
 
 	r.PopulateTokenData(responseData)
@@ -1034,6 +1160,12 @@ func (r *awsRoleEntry) ToResponseData() map[string]interface{} {
 	convertNilToEmptySlice(responseData, "bound_region")
 	convertNilToEmptySlice(responseData, "bound_subnet_id")
 	convertNilToEmptySlice(responseData, "bound_vpc_id")
+	convertNilToEmptySlice(responseData, "bound_trust_anchor_arn")
+	convertNilToEmptySlice(responseData, "bound_rolesanywhere_profile_arn")
+	convertNilToEmptySlice(responseData, "bound_eks_cluster_arn")
+	convertNilToEmptySlice(responseData, "bound_pod_identity_association_arn")
+	convertNilToEmptySlice(responseData, "bound_kubernetes_namespace")
+	convertNilToEmptySlice(responseData, "bound_kubernetes_service_account")
 
 	return responseData
 }
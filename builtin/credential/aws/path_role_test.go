@@ -2,6 +2,7 @@ package awsauth
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"reflect"
 	"strings"
@@ -529,6 +530,99 @@ func TestBackend_pathRoleMixedTypes(t *testing.T) {
 
 }
 
+func TestBackend_pathRoleWorkloadTypes(t *testing.T) {
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Setup(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		data  map[string]interface{}
+		valid bool
+	}{
+		{
+			name: "rolesanywhere",
+			data: map[string]interface{}{
+				"auth_type":              rolesAnywhereAuthType,
+				"bound_trust_anchor_arn": "arn:aws:rolesanywhere:us-east-1:123456789012:trust-anchor/*",
+			},
+			valid: true,
+		},
+		{
+			name: "rolesanywhere without trust anchor",
+			data: map[string]interface{}{
+				"auth_type":                       rolesAnywhereAuthType,
+				"bound_rolesanywhere_profile_arn": "arn:aws:rolesanywhere:us-east-1:123456789012:profile/abc",
+			},
+		},
+		{
+			name: "rolesanywhere with iam binds",
+			data: map[string]interface{}{
+				"auth_type":               rolesAnywhereAuthType,
+				"bound_trust_anchor_arn":  "arn:aws:rolesanywhere:us-east-1:123456789012:trust-anchor/abc",
+				"bound_iam_principal_arn": "arn:aws:iam::123456789012:role/MyRole",
+			},
+		},
+		{
+			name: "eks_pod_identity",
+			data: map[string]interface{}{
+				"auth_type":                  eksPodIdentityAuthType,
+				"bound_eks_cluster_arn":      "arn:aws:eks:us-west-2:123456789012:cluster/prod",
+				"bound_kubernetes_namespace": "payments",
+			},
+			valid: true,
+		},
+		{
+			name: "eks_pod_identity with invalid cluster",
+			data: map[string]interface{}{
+				"auth_type":             eksPodIdentityAuthType,
+				"bound_eks_cluster_arn": "arn:aws:eks:us-west-2:123456789012:nodegroup/prod",
+			},
+		},
+		{
+			name: "eks_pod_identity without cluster",
+			data: map[string]interface{}{
+				"auth_type":                        eksPodIdentityAuthType,
+				"bound_kubernetes_service_account": "api",
+			},
+		},
+		{
+			name: "iam with trust anchor",
+			data: map[string]interface{}{
+				"auth_type":              iamAuthType,
+				"bound_trust_anchor_arn": "arn:aws:rolesanywhere:us-east-1:123456789012:trust-anchor/abc",
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      fmt.Sprintf("role/workload-%d", i),
+			Data:      tc.data,
+			Storage:   storage,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if tc.valid && resp != nil && resp.IsError() {
+			t.Fatalf("%s: unexpected error: %#v", tc.name, resp)
+		}
+		if !tc.valid && (resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+}
+
 func TestAwsEc2_RoleCrud(t *testing.T) {
 	var err error
 	var resp *logical.Response
@@ -599,36 +693,42 @@ func TestAwsEc2_RoleCrud(t *testing.T) {
 	}
 
 	expected := map[string]interface{}{
-		"auth_type":                      ec2AuthType,
-		"bound_ami_id":                   []string{"testamiid"},
-		"bound_account_id":               []string{"testaccountid"},
-		"bound_region":                   []string{"testregion"},
-		"bound_ec2_instance_id":          []string{"i-12345678901234567", "i-76543210987654321"},
-		"bound_iam_principal_arn":        []string{},
-		"bound_iam_principal_id":         []string{},
-		"bound_iam_role_arn":             []string{"arn:aws:iam::123456789012:role/MyRole"},
-		"bound_iam_instance_profile_arn": []string{"arn:aws:iam::123456789012:instance-profile/MyInstancePro*"},
-		"bound_subnet_id":                []string{"testsubnetid"},
-		"bound_vpc_id":                   []string{"testvpcid"},
-		"inferred_entity_type":           "",
-		"inferred_aws_region":            "",
-		"resolve_aws_unique_ids":         false,
-		"role_tag":                       "testtag",
-		"allow_instance_migration":       true,
-		"ttl":                            int64(600),
-		"token_ttl":                      int64(600),
-		"max_ttl":                        int64(1200),
-		"token_max_ttl":                  int64(1200),
-		"token_explicit_max_ttl":         int64(0),
-		"policies":                       []string{"testpolicy1", "testpolicy2"},
-		"token_policies":                 []string{"testpolicy1", "testpolicy2"},
-		"disallow_reauthentication":      false,
-		"period":                         int64(60),
-		"token_period":                   int64(60),
-		"token_bound_cidrs":              []string{},
-		"token_no_default_policy":        false,
-		"token_num_uses":                 0,
-		"token_type":                     "default",
+		"auth_type":                          ec2AuthType,
+		"bound_ami_id":                       []string{"testamiid"},
+		"bound_account_id":                   []string{"testaccountid"},
+		"bound_region":                       []string{"testregion"},
+		"bound_ec2_instance_id":              []string{"i-12345678901234567", "i-76543210987654321"},
+		"bound_iam_principal_arn":            []string{},
+		"bound_iam_principal_id":             []string{},
+		"bound_iam_role_arn":                 []string{"arn:aws:iam::123456789012:role/MyRole"},
+		"bound_iam_instance_profile_arn":     []string{"arn:aws:iam::123456789012:instance-profile/MyInstancePro*"},
+		"bound_subnet_id":                    []string{"testsubnetid"},
+		"bound_vpc_id":                       []string{"testvpcid"},
+		"bound_trust_anchor_arn":             []string{},
+		"bound_rolesanywhere_profile_arn":    []string{},
+		"bound_eks_cluster_arn":              []string{},
+		"bound_pod_identity_association_arn": []string{},
+		"bound_kubernetes_namespace":         []string{},
+		"bound_kubernetes_service_account":   []string{},
+		"inferred_entity_type":               "",
+		"inferred_aws_region":                "",
+		"resolve_aws_unique_ids":             false,
+		"role_tag":                           "testtag",
+		"allow_instance_migration":           true,
+		"ttl":                                int64(600),
+		"token_ttl":                          int64(600),
+		"max_ttl":                            int64(1200),
+		"token_max_ttl":                      int64(1200),
+		"token_explicit_max_ttl":             int64(0),
+		"policies":                           []string{"testpolicy1", "testpolicy2"},
+		"token_policies":                     []string{"testpolicy1", "testpolicy2"},
+		"disallow_reauthentication":          false,
+		"period":                             int64(60),
+		"token_period":                       int64(60),
+		"token_bound_cidrs":                  []string{},
+		"token_no_default_policy":            false,
+		"token_num_uses":                     0,
+		"token_type":                         "default",
 	}
 
 	if resp.Data["role_id"] == nil {
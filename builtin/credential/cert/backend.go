@@ -2,9 +2,12 @@ package cert
 
 import (
 	"context"
+	"net/http"
 	"strings"
 	"sync"
 
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -40,6 +43,9 @@ func Backend() *backend {
 
 	b.crlUpdateMutex = &sync.RWMutex{}
 
+	b.revocationClient = cleanhttp.DefaultPooledClient()
+	b.revocationClient.Timeout = revocationRequestTimeout
+
 	return &b
 }
 
@@ -49,6 +55,12 @@ type backend struct {
 
 	crls           map[string]CRLInfo
 	crlUpdateMutex *sync.RWMutex
+
+	// revocationCache holds the OCSP responses and the CRLs fetched from
+	// distribution points, and revocationClient fetches them
+	revocationCache     *lru.Cache
+	revocationCacheLock sync.Mutex
+	revocationClient    *http.Client
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
@@ -57,6 +69,8 @@ func (b *backend) invalidate(_ context.Context, key string) {
 		b.crlUpdateMutex.Lock()
 		defer b.crlUpdateMutex.Unlock()
 		b.crls = nil
+	case key == "config":
+		b.resetRevocationCache()
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -114,6 +115,43 @@ formatted as "oid:value". Expects the extension value to be some type of ASN1 en
 All values much match. Supports globbing on "value".`,
 			},
 
+			"ocsp_enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, the status of client certificates is checked
+with the OCSP responders found in them, or the ones of ocsp_servers_override.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Group: "Revocation",
+				},
+			},
+
+			"ocsp_servers_override": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of OCSP responder URLs to
+query instead of the ones found in client certificates.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Group: "Revocation",
+				},
+			},
+
+			"crl_distribution_points_enabled": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, client certificates are checked against the
+CRLs fetched from the HTTP distribution points found in them.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Group: "Revocation",
+				},
+			},
+
+			"revocation_fail_open": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set, logins are allowed when the status of the
+client certificate cannot be determined by its OCSP responders or CRL distribution
+points, rather than denied.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Group: "Revocation",
+				},
+			},
+
 			"display_name": &framework.FieldSchema{
 				Type: framework.TypeString,
 				Description: `The display name to use for clients using this
@@ -243,6 +281,11 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 		"allowed_uri_sans":             cert.AllowedURISANs,
 		"allowed_organizational_units": cert.AllowedOrganizationalUnits,
 		"required_extensions":          cert.RequiredExtensions,
+
+		"ocsp_enabled":                    cert.OCSPEnabled,
+		"ocsp_servers_override":           cert.OCSPServersOverride,
+		"crl_distribution_points_enabled": cert.CRLDistributionPointsEnabled,
+		"revocation_fail_open":            cert.RevocationFailOpen,
 	}
 	cert.PopulateTokenData(data)
 
@@ -309,6 +352,23 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if requiredExtensionsRaw, ok := d.GetOk("required_extensions"); ok {
 		cert.RequiredExtensions = requiredExtensionsRaw.([]string)
 	}
+	if ocspEnabledRaw, ok := d.GetOk("ocsp_enabled"); ok {
+		cert.OCSPEnabled = ocspEnabledRaw.(bool)
+	}
+	if ocspServersOverrideRaw, ok := d.GetOk("ocsp_servers_override"); ok {
+		cert.OCSPServersOverride = ocspServersOverrideRaw.([]string)
+		for _, server := range cert.OCSPServersOverride {
+			if u, err := url.Parse(server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return logical.ErrorResponse(fmt.Sprintf("invalid OCSP responder URL %q", server)), nil
+			}
+		}
+	}
+	if crlDistributionPointsEnabledRaw, ok := d.GetOk("crl_distribution_points_enabled"); ok {
+		cert.CRLDistributionPointsEnabled = crlDistributionPointsEnabledRaw.(bool)
+	}
+	if revocationFailOpenRaw, ok := d.GetOk("revocation_fail_open"); ok {
+		cert.RevocationFailOpen = revocationFailOpenRaw.(bool)
+	}
 
 	// Get tokenutil fields
 	if err := cert.ParseTokenFields(req, d); err != nil {
@@ -425,6 +485,11 @@ type CertEntry struct {
 	AllowedOrganizationalUnits []string
 	RequiredExtensions         []string
 	BoundCIDRs                 []*sockaddr.SockAddrMarshaler
+
+	OCSPEnabled                  bool
+	OCSPServersOverride          []string
+	CRLDistributionPointsEnabled bool
+	RevocationFailOpen           bool
 }
 
 const pathCertHelpSyn = `
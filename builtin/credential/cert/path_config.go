@@ -17,20 +17,37 @@ func pathConfig(b *backend) *framework.Path {
 				Default:     false,
 				Description: `If set, during renewal, skips the matching of presented client identity with the client identity used during login. Defaults to false.`,
 			},
+			"revocation_cache_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Default:     defaultRevocationCacheSize,
+				Description: `The number of OCSP responses and CRLs fetched from distribution points to cache. Defaults to 100.`,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
 			logical.UpdateOperation: b.pathConfigWrite,
 		},
 	}
 }
 
 func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	disableBinding := data.Get("disable_binding").(bool)
+	cfg, err := b.Config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
 
-	entry, err := logical.StorageEntryJSON("config", config{
-		DisableBinding: disableBinding,
-	})
+	if disableBindingRaw, ok := data.GetOk("disable_binding"); ok {
+		cfg.DisableBinding = disableBindingRaw.(bool)
+	}
+	if revocationCacheSizeRaw, ok := data.GetOk("revocation_cache_size"); ok {
+		cfg.RevocationCacheSize = revocationCacheSizeRaw.(int)
+		if cfg.RevocationCacheSize <= 0 {
+			return logical.ErrorResponse("revocation_cache_size must be greater than zero"), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -38,9 +55,27 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, dat
 	if err := req.Storage.Put(ctx, entry); err != nil {
 		return nil, err
 	}
+
+	// The cache is created again with its new size on its next use
+	b.resetRevocationCache()
+
 	return nil, nil
 }
 
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	cfg, err := b.Config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"disable_binding":       cfg.DisableBinding,
+			"revocation_cache_size": cfg.RevocationCacheSize,
+		},
+	}, nil
+}
+
 // Config returns the configuration for this backend.
 func (b *backend) Config(ctx context.Context, s logical.Storage) (*config, error) {
 	entry, err := s.Get(ctx, "config")
@@ -55,9 +90,13 @@ func (b *backend) Config(ctx context.Context, s logical.Storage) (*config, error
 			return nil, errwrap.Wrapf("error reading configuration: {{err}}", err)
 		}
 	}
+	if result.RevocationCacheSize == 0 {
+		result.RevocationCacheSize = defaultRevocationCacheSize
+	}
 	return &result, nil
 }
 
 type config struct {
-	DisableBinding bool `json:"disable_binding"`
+	DisableBinding      bool `json:"disable_binding"`
+	RevocationCacheSize int  `json:"revocation_cache_size"`
 }
@@ -198,6 +198,10 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 		return nil, nil, err
 	}
 
+	// The last error of the revocation checks of the matching entries, if
+	// any, is returned if none of them passes
+	var revocationErr error
+
 	// If trustedNonCAs is not empty it means that client had registered a non-CA cert
 	// with the backend.
 	if len(trustedNonCAs) != 0 {
@@ -207,6 +211,11 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 			if tCert.SerialNumber.Cmp(clientCert.SerialNumber) == 0 &&
 				bytes.Equal(tCert.AuthorityKeyId, clientCert.AuthorityKeyId) &&
 				b.matchesConstraints(clientCert, trustedNonCA.Certificates, trustedNonCA) {
+				issuer := findIssuer(clientCert, connState.PeerCertificates[1:])
+				if err := b.checkRevocation(ctx, req.Storage, trustedNonCA.Entry, clientCert, issuer); err != nil {
+					revocationErr = err
+					continue
+				}
 				return trustedNonCA, nil, nil
 			}
 		}
@@ -215,11 +224,15 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 	// If no trusted chain was found, client is not authenticated
 	// This check happens after checking for a matching configured non-CA certs
 	if len(trustedChains) == 0 {
+		if revocationErr != nil {
+			return nil, logical.ErrorResponse(revocationErr.Error()), nil
+		}
 		return nil, logical.ErrorResponse("invalid certificate or no client certificate supplied"), nil
 	}
 
 	// Search for a ParsedCert that intersects with the validated chains and any additional constraints
 	matches := make([]*ParsedCert, 0)
+	matchedChains := make([][]*x509.Certificate, 0)
 	for _, trust := range trusted { // For each ParsedCert in the config
 		for _, tCert := range trust.Certificates { // For each certificate in the entry
 			for _, chain := range trustedChains { // For each root chain that we matched
@@ -228,6 +241,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 						b.matchesConstraints(clientCert, chain, trust) { // validate client cert + matched chain against the config
 						// Add the match to the list
 						matches = append(matches, trust)
+						matchedChains = append(matchedChains, chain)
 					}
 				}
 			}
@@ -236,11 +250,44 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 
 	// Fail on no matches
 	if len(matches) == 0 {
+		if revocationErr != nil {
+			return nil, logical.ErrorResponse(revocationErr.Error()), nil
+		}
 		return nil, logical.ErrorResponse("no chain matching all constraints could be found for this login certificate"), nil
 	}
 
-	// Return the first matching entry (for backwards compatibility, we continue to just pick one if multiple match)
-	return matches[0], nil, nil
+	// Return the first matching entry passing its revocation checks (for
+	// backwards compatibility, we continue to just pick one if multiple match)
+	checked := make(map[*ParsedCert]bool, len(matches))
+	for i, match := range matches {
+		if checked[match] {
+			continue
+		}
+		checked[match] = true
+
+		var issuer *x509.Certificate
+		if len(matchedChains[i]) > 1 {
+			issuer = matchedChains[i][1]
+		}
+		if err := b.checkRevocation(ctx, req.Storage, match.Entry, clientCert, issuer); err != nil {
+			revocationErr = err
+			continue
+		}
+		return match, nil, nil
+	}
+
+	return nil, logical.ErrorResponse(revocationErr.Error()), nil
+}
+
+// findIssuer returns the certificate among the candidates which issued the
+// certificate, or nil if there is none.
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	for _, candidate := range candidates {
+		if bytes.Equal(candidate.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
 }
 
 func (b *backend) matchesConstraints(clientCert *x509.Certificate, trustedChain []*x509.Certificate, config *ParsedCert) bool {
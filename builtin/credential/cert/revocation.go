@@ -0,0 +1,343 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// defaultRevocationCacheSize is the number of OCSP responses and CRLs
+	// cached by default
+	defaultRevocationCacheSize = 100
+
+	// maxRevocationCacheTTL bounds how long an OCSP response or a CRL is
+	// cached, including when it does not say when it will next be updated
+	maxRevocationCacheTTL = time.Hour
+
+	// maxRevocationResponseSize bounds the size of the OCSP responses and
+	// CRLs fetched
+	maxRevocationResponseSize = 10 * 1024 * 1024
+
+	// revocationRequestTimeout bounds the time taken to fetch an OCSP
+	// response or a CRL
+	revocationRequestTimeout = 10 * time.Second
+
+	// revocationClockSkew is the clock skew allowed when checking that an
+	// OCSP response or a CRL is current
+	revocationClockSkew = 5 * time.Minute
+)
+
+// revocationCacheEntry is a cached OCSP response or CRL. The serials are the
+// ones revoked by a CRL.
+type revocationCacheEntry struct {
+	revoked bool
+	serials map[string]bool
+	expires time.Time
+}
+
+// getRevocationCache returns the cache of the OCSP responses and CRLs,
+// creating it with the size of the configuration if needed.
+func (b *backend) getRevocationCache(ctx context.Context, s logical.Storage) (*lru.Cache, error) {
+	b.revocationCacheLock.Lock()
+	defer b.revocationCacheLock.Unlock()
+
+	if b.revocationCache != nil {
+		return b.revocationCache, nil
+	}
+
+	config, err := b.Config(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	size := config.RevocationCacheSize
+	if size <= 0 {
+		size = defaultRevocationCacheSize
+	}
+
+	cache, err := lru.New(size)
+	if err != nil {
+		return nil, err
+	}
+	b.revocationCache = cache
+	return cache, nil
+}
+
+// resetRevocationCache drops the cache of the OCSP responses and CRLs, which
+// is created again on its next use.
+func (b *backend) resetRevocationCache() {
+	b.revocationCacheLock.Lock()
+	defer b.revocationCacheLock.Unlock()
+
+	b.revocationCache = nil
+}
+
+func getCachedRevocation(cache *lru.Cache, key string) (*revocationCacheEntry, bool) {
+	raw, ok := cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := raw.(*revocationCacheEntry)
+	if time.Now().After(entry.expires) {
+		cache.Remove(key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// revocationCacheExpiry returns when a response to be next updated at the
+// given time expires from the cache.
+func revocationCacheExpiry(nextUpdate time.Time) time.Time {
+	expires := time.Now().Add(maxRevocationCacheTTL)
+	if !nextUpdate.IsZero() && nextUpdate.Before(expires) {
+		expires = nextUpdate
+	}
+	return expires
+}
+
+// checkRevocation checks the client certificate against the OCSP responders
+// and the CRL distribution points enabled by the entry it matched. An error is
+// returned if the certificate is revoked, or if its status could not be
+// determined by any of them and the entry does not fail open.
+func (b *backend) checkRevocation(ctx context.Context, s logical.Storage, entry *CertEntry, cert, issuer *x509.Certificate) error {
+	if !entry.OCSPEnabled && !entry.CRLDistributionPointsEnabled {
+		return nil
+	}
+
+	cache, err := b.getRevocationCache(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	var checked bool
+	var errs *multierror.Error
+
+	if entry.OCSPEnabled {
+		servers := entry.OCSPServersOverride
+		if len(servers) == 0 {
+			servers = cert.OCSPServer
+		}
+
+		revoked, err := b.checkOCSP(ctx, cache, cert, issuer, servers)
+		switch {
+		case err != nil:
+			errs = multierror.Append(errs, err)
+		case revoked:
+			return errors.New("client certificate is revoked")
+		default:
+			checked = true
+		}
+	}
+
+	if entry.CRLDistributionPointsEnabled {
+		revoked, err := b.checkCRLDistributionPoints(ctx, cache, cert, issuer)
+		switch {
+		case err != nil:
+			errs = multierror.Append(errs, err)
+		case revoked:
+			return errors.New("client certificate is revoked")
+		default:
+			checked = true
+		}
+	}
+
+	if checked {
+		return nil
+	}
+
+	if entry.RevocationFailOpen {
+		b.Logger().Warn("unable to check the revocation status of the client certificate, allowing login", "cert_name", entry.Name, "serial_number", cert.SerialNumber.String(), "error", errs.ErrorOrNil())
+		return nil
+	}
+	return errwrap.Wrapf("unable to check the revocation status of the client certificate: {{err}}", errs.ErrorOrNil())
+}
+
+// checkOCSP queries the OCSP responders in turn for the status of the
+// certificate, until one of them answers whether it is revoked.
+func (b *backend) checkOCSP(ctx context.Context, cache *lru.Cache, cert, issuer *x509.Certificate, servers []string) (bool, error) {
+	if len(servers) == 0 {
+		return false, errors.New("no OCSP responder found for the certificate")
+	}
+	if issuer == nil {
+		return false, errors.New("issuer of the certificate not found for OCSP")
+	}
+
+	issuerHash := sha256.Sum256(issuer.Raw)
+	cacheKey := fmt.Sprintf("ocsp/%s/%s", hex.EncodeToString(issuerHash[:]), cert.SerialNumber.String())
+	if cached, ok := getCachedRevocation(cache, cacheKey); ok {
+		return cached.revoked, nil
+	}
+
+	ocspReq, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return false, errwrap.Wrapf("failed to create OCSP request: {{err}}", err)
+	}
+
+	var errs *multierror.Error
+	for _, server := range servers {
+		ocspResp, err := b.queryOCSP(ctx, server, ocspReq, cert, issuer)
+		if err != nil {
+			errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("OCSP responder %q: {{err}}", server), err))
+			continue
+		}
+
+		switch ocspResp.Status {
+		case ocsp.Good, ocsp.Revoked:
+			revoked := ocspResp.Status == ocsp.Revoked
+			cache.Add(cacheKey, &revocationCacheEntry{
+				revoked: revoked,
+				expires: revocationCacheExpiry(ocspResp.NextUpdate),
+			})
+			return revoked, nil
+
+		default:
+			errs = multierror.Append(errs, fmt.Errorf("OCSP responder %q: unknown certificate status", server))
+		}
+	}
+
+	return false, errs.ErrorOrNil()
+}
+
+// queryOCSP sends the OCSP request to the responder, and returns its
+// response once its signature is verified and it is current.
+func (b *backend) queryOCSP(ctx context.Context, server string, ocspReq []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	httpReq, err := http.NewRequest(http.MethodPost, server, bytes.NewReader(ocspReq))
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	body, err := b.fetchRevocation(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	ocspResp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to parse response: {{err}}", err)
+	}
+
+	now := time.Now()
+	if ocspResp.ThisUpdate.After(now.Add(revocationClockSkew)) {
+		return nil, errors.New("response is not yet valid")
+	}
+	if !ocspResp.NextUpdate.IsZero() && ocspResp.NextUpdate.Before(now.Add(-revocationClockSkew)) {
+		return nil, errors.New("response is expired")
+	}
+
+	return ocspResp, nil
+}
+
+// checkCRLDistributionPoints fetches the CRLs from the HTTP distribution
+// points of the certificate in turn, until one of them is fetched and checked.
+func (b *backend) checkCRLDistributionPoints(ctx context.Context, cache *lru.Cache, cert, issuer *x509.Certificate) (bool, error) {
+	var urls []string
+	for _, dp := range cert.CRLDistributionPoints {
+		u, err := url.Parse(dp)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		urls = append(urls, dp)
+	}
+	if len(urls) == 0 {
+		return false, errors.New("no HTTP CRL distribution point found for the certificate")
+	}
+	if issuer == nil {
+		return false, errors.New("issuer of the certificate not found for CRL verification")
+	}
+
+	var errs *multierror.Error
+	for _, dp := range urls {
+		serials, err := b.fetchCRL(ctx, cache, dp, issuer)
+		if err != nil {
+			errs = multierror.Append(errs, errwrap.Wrapf(fmt.Sprintf("CRL distribution point %q: {{err}}", dp), err))
+			continue
+		}
+		return serials[cert.SerialNumber.String()], nil
+	}
+
+	return false, errs.ErrorOrNil()
+}
+
+// fetchCRL returns the serials revoked by the CRL at the distribution point,
+// once its signature by the issuer is verified and it is current.
+func (b *backend) fetchCRL(ctx context.Context, cache *lru.Cache, dp string, issuer *x509.Certificate) (map[string]bool, error) {
+	issuerHash := sha256.Sum256(issuer.Raw)
+	cacheKey := fmt.Sprintf("crl/%s/%s", hex.EncodeToString(issuerHash[:]), dp)
+	if cached, ok := getCachedRevocation(cache, cacheKey); ok {
+		return cached.serials, nil
+	}
+
+	httpReq, err := http.NewRequest(http.MethodGet, dp, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq = httpReq.WithContext(ctx)
+
+	body, err := b.fetchRevocation(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := x509.ParseCRL(body)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to parse CRL: {{err}}", err)
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return nil, errwrap.Wrapf("failed to verify CRL signature: {{err}}", err)
+	}
+	if crl.HasExpired(time.Now().Add(-revocationClockSkew)) {
+		return nil, errors.New("CRL is expired")
+	}
+
+	serials := make(map[string]bool, len(crl.TBSCertList.RevokedCertificates))
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		serials[revoked.SerialNumber.String()] = true
+	}
+
+	cache.Add(cacheKey, &revocationCacheEntry{
+		serials: serials,
+		expires: revocationCacheExpiry(crl.TBSCertList.NextUpdate),
+	})
+	return serials, nil
+}
+
+// fetchRevocation sends the request for an OCSP response or a CRL and returns
+// the body of the response.
+func (b *backend) fetchRevocation(httpReq *http.Request) ([]byte, error) {
+	httpResp, err := b.revocationClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxRevocationResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxRevocationResponseSize {
+		return nil, errors.New("response is too large")
+	}
+	return body, nil
+}
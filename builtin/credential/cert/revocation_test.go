@@ -0,0 +1,278 @@
+package cert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ocsp"
+)
+
+type revocationTestCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	// ocspStatus is the status returned by the OCSP responder, or -1 if it
+	// fails, and revoked whether the CRL revokes the client certificate
+	ocspStatus   int32
+	ocspRequests int32
+	revoked      int32
+	crlRequests  int32
+
+	ocspServer *httptest.Server
+	crlServer  *httptest.Server
+}
+
+func newRevocationTestCA(t *testing.T) *revocationTestCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Revocation Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := &revocationTestCA{
+		cert: cert,
+		key:  key,
+	}
+
+	ca.ocspServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ca.ocspRequests, 1)
+
+		status := int(atomic.LoadInt32(&ca.ocspStatus))
+		if status < 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+
+	ca.crlServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ca.crlRequests, 1)
+
+		var revoked []pkix.RevokedCertificate
+		if atomic.LoadInt32(&ca.revoked) == 1 {
+			revoked = append(revoked, pkix.RevokedCertificate{
+				SerialNumber:   big.NewInt(2),
+				RevocationTime: time.Now().Add(-time.Minute),
+			})
+		}
+		crl, err := ca.cert.CreateCRL(rand.Reader, ca.key, revoked, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(crl)
+	}))
+
+	return ca
+}
+
+func (ca *revocationTestCA) Close() {
+	ca.ocspServer.Close()
+	ca.crlServer.Close()
+}
+
+func (ca *revocationTestCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: ca.cert.Raw,
+	}))
+}
+
+func (ca *revocationTestCA) issueClientCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		OCSPServer:            []string{ca.ocspServer.URL},
+		CRLDistributionPoints: []string{ca.crlServer.URL},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestBackend_Revocation(t *testing.T) {
+	ca := newRevocationTestCA(t)
+	defer ca.Close()
+	clientCert := ca.issueClientCert(t)
+
+	config := logical.TestBackendConfig()
+	storage := &logical.InmemStorage{}
+	config.StorageView = storage
+	raw, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := raw.(*backend)
+
+	writeCert := func(data map[string]interface{}) {
+		t.Helper()
+		data["certificate"] = ca.pem()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "certs/ca",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v resp: %#v", err, resp)
+		}
+		b.resetRevocationCache()
+	}
+
+	login := func() *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Connection: &logical.Connection{
+				ConnState: &tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{clientCert},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	expectLogin := func(success bool, contains string) {
+		t.Helper()
+		resp := login()
+		switch {
+		case success && (resp == nil || resp.Auth == nil):
+			t.Fatalf("expected login to succeed, got %#v", resp)
+		case !success && (resp == nil || !resp.IsError()):
+			t.Fatalf("expected login to fail, got %#v", resp)
+		case !success && !strings.Contains(resp.Error().Error(), contains):
+			t.Fatalf("expected error to contain %q, got %v", contains, resp.Error())
+		}
+	}
+
+	// OCSP responses are cached
+	writeCert(map[string]interface{}{
+		"ocsp_enabled": true,
+	})
+	atomic.StoreInt32(&ca.ocspStatus, ocsp.Good)
+	expectLogin(true, "")
+	expectLogin(true, "")
+	if n := atomic.LoadInt32(&ca.ocspRequests); n != 1 {
+		t.Fatalf("expected 1 OCSP request, got %d", n)
+	}
+
+	atomic.StoreInt32(&ca.ocspStatus, ocsp.Revoked)
+	b.resetRevocationCache()
+	expectLogin(false, "revoked")
+
+	// Failing responders deny the login unless failing open
+	atomic.StoreInt32(&ca.ocspStatus, -1)
+	b.resetRevocationCache()
+	expectLogin(false, "unable to check the revocation status")
+
+	writeCert(map[string]interface{}{
+		"revocation_fail_open": true,
+	})
+	expectLogin(true, "")
+
+	// The responders can be overridden
+	writeCert(map[string]interface{}{
+		"revocation_fail_open":  false,
+		"ocsp_servers_override": "http://127.0.0.1:0/ocsp," + ca.ocspServer.URL,
+	})
+	atomic.StoreInt32(&ca.ocspStatus, ocsp.Good)
+	expectLogin(true, "")
+
+	// CRL distribution points
+	writeCert(map[string]interface{}{
+		"ocsp_enabled":                    false,
+		"crl_distribution_points_enabled": true,
+	})
+	expectLogin(true, "")
+	expectLogin(true, "")
+	if n := atomic.LoadInt32(&ca.crlRequests); n != 1 {
+		t.Fatalf("expected 1 CRL request, got %d", n)
+	}
+
+	atomic.StoreInt32(&ca.revoked, 1)
+	b.resetRevocationCache()
+	expectLogin(false, "revoked")
+
+	// A revoked certificate is denied even if another check passes
+	writeCert(map[string]interface{}{
+		"ocsp_enabled": true,
+	})
+	expectLogin(false, "revoked")
+}
@@ -48,6 +48,7 @@ func Backend() *backend {
 		),
 
 		AuthRenew:   b.pathLoginRenew,
+		GroupSync:   b.groupSync,
 		BackendType: logical.TypeCredential,
 	}
 
@@ -158,6 +159,29 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		ldapResponse.AddWarning(errString)
 	}
 
+	user, allGroups, canonicalGroups := b.mergeLocalGroups(ctx, req.Storage, cfg, username, ldapGroups)
+
+	// Retrieve policies
+	var policies []string
+	for _, groupName := range canonicalGroups {
+		group, err := b.Group(ctx, req.Storage, groupName)
+		if err == nil && group != nil {
+			policies = append(policies, group.Policies...)
+		}
+	}
+	if user != nil && user.Policies != nil {
+		policies = append(policies, user.Policies...)
+	}
+	// Policies from each group may overlap
+	policies = strutil.RemoveDuplicates(policies, true)
+
+	return policies, ldapResponse, allGroups, nil
+}
+
+// mergeLocalGroups adds the groups set locally for the user to the groups
+// found in LDAP. The canonical group names are lowercased unless names are
+// case sensitive.
+func (b *backend) mergeLocalGroups(ctx context.Context, s logical.Storage, cfg *ldapConfigEntry, username string, ldapGroups []string) (*UserEntry, []string, []string) {
 	var allGroups []string
 	canonicalUsername := username
 	cs := *cfg.CaseSensitiveNames
@@ -165,7 +189,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 		canonicalUsername = strings.ToLower(username)
 	}
 	// Import the custom added groups from ldap backend
-	user, err := b.User(ctx, req.Storage, canonicalUsername)
+	user, err := b.User(ctx, s, canonicalUsername)
 	if err == nil && user != nil && user.Groups != nil {
 		if b.Logger().IsDebug() {
 			b.Logger().Debug("adding local groups", "num_local_groups", len(user.Groups), "local_groups", user.Groups)
@@ -183,22 +207,7 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username stri
 			canonicalGroups[i] = strings.ToLower(v)
 		}
 	}
-
-	// Retrieve policies
-	var policies []string
-	for _, groupName := range canonicalGroups {
-		group, err := b.Group(ctx, req.Storage, groupName)
-		if err == nil && group != nil {
-			policies = append(policies, group.Policies...)
-		}
-	}
-	if user != nil && user.Policies != nil {
-		policies = append(policies, user.Policies...)
-	}
-	// Policies from each group may overlap
-	policies = strutil.RemoveDuplicates(policies, true)
-
-	return policies, ldapResponse, allGroups, nil
+	return user, allGroups, canonicalGroups
 }
 
 const backendHelp = `
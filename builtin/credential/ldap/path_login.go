@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
+	"github.com/hashicorp/vault/sdk/helper/ldaputil"
 	"github.com/hashicorp/vault/sdk/helper/policyutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -163,6 +165,85 @@ func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *f
 	return resp, nil
 }
 
+// groupSync re-resolves the groups of the user named by the alias. The
+// search is made as the BindDN user, since the user's password is not
+// available outside of a login.
+func (b *backend) groupSync(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cfg, err := b.Config(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("auth method not configured")
+	}
+	if cfg.BindDN == "" || cfg.BindPassword == "" {
+		return nil, fmt.Errorf("group sync requires binddn and bindpass to be configured")
+	}
+
+	username := req.Auth.Alias.Name
+
+	ldapClient := ldaputil.Client{
+		Logger: b.Logger(),
+		LDAP:   ldaputil.NewLDAP(),
+	}
+
+	c, err := ldapClient.DialLDAP(cfg.ConfigEntry)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("invalid connection returned from LDAP dial")
+	}
+
+	// Clean connection
+	defer c.Close()
+
+	if err := c.Bind(cfg.BindDN, cfg.BindPassword); err != nil {
+		return nil, errwrap.Wrapf("failed to bind with the BindDN user: {{err}}", err)
+	}
+
+	userBindDN, err := ldapClient.GetUserBindDN(cfg.ConfigEntry, c, username)
+	if err != nil {
+		return nil, errwrap.Wrapf("unable to retrieve user bind DN: {{err}}", err)
+	}
+
+	userDN, err := ldapClient.GetUserDN(cfg.ConfigEntry, c, userBindDN, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AnonymousGroupSearch {
+		c, err = ldapClient.DialLDAP(cfg.ConfigEntry)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to connect to LDAP server: {{err}}", err)
+		}
+		defer c.Close()
+	}
+
+	ldapGroups, err := ldapClient.GetLdapGroups(cfg.ConfigEntry, c, userDN, username)
+	if err != nil {
+		return nil, err
+	}
+
+	_, allGroups, _ := b.mergeLocalGroups(ctx, req.Storage, cfg, username, ldapGroups)
+
+	auth := &logical.Auth{
+		Alias: req.Auth.Alias,
+	}
+	for _, groupName := range allGroups {
+		if groupName == "" {
+			continue
+		}
+		auth.GroupAliases = append(auth.GroupAliases, &logical.Alias{
+			Name: groupName,
+		})
+	}
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
 const pathLoginSyn = `
 Log in with a username and password.
 `
@@ -2,6 +2,7 @@ package userpass
 
 import (
 	"context"
+	"sync"
 
 	"github.com/hashicorp/vault/helper/mfa"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -22,7 +23,7 @@ func Backend() *backend {
 		Help: backendHelp,
 
 		PathsSpecial: &logical.Paths{
-			Root: mfa.MFARootPaths(),
+			Root: append(mfa.MFARootPaths(), "totp_config"),
 
 			Unauthenticated: []string{
 				"login/*",
@@ -34,6 +35,8 @@ func Backend() *backend {
 			pathUsersList(&b),
 			pathUserPolicies(&b),
 			pathUserPassword(&b),
+			pathUserMFA(&b),
+			pathTOTPConfig(&b),
 		},
 			mfa.MFAPaths(b.Backend, pathLogin(&b))...,
 		),
@@ -47,14 +50,21 @@ func Backend() *backend {
 
 type backend struct {
 	*framework.Backend
+
+	// totpLock serializes TOTP checks, so that a passcode cannot be
+	// accepted twice by concurrent logins
+	totpLock sync.Mutex
 }
 
 const backendHelp = `
 The "userpass" credential provider allows authentication using
-a combination of a username and password. No additional factors
-are supported.
+a combination of a username and password, optionally with a TOTP
+passcode as a second factor.
 
 The username/password combination is configured using the "users/"
 endpoints by a user with root access. Authentication is then done
 by supplying the two fields for "login".
+
+A TOTP device is enrolled for a user using the "users/<name>/mfa"
+endpoint, after which logins must include a passcode.
 `
@@ -26,6 +26,11 @@ func pathLogin(b *backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Password for this user.",
 			},
+
+			"totp_passcode": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "TOTP passcode, if the user has enrolled a device. May instead be given in the X-Vault-MFA header as totp:<passcode>.",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -114,6 +119,20 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		}
 	}
 
+	// Check the second factor, if enrolled or required.
+	totpConfig, err := b.totpConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case user.TOTPSecret != "":
+		if resp, err := b.checkTOTP(ctx, req, username, totpPasscode(req, d)); resp != nil || err != nil {
+			return resp, err
+		}
+	case totpConfig.requiresTOTP(user.TokenPolicies):
+		return logical.ErrorResponse("TOTP is required for this user, but no device is enrolled"), nil
+	}
+
 	auth := &logical.Auth{
 		Metadata: map[string]string{
 			"username": username,
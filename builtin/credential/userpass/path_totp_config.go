@@ -0,0 +1,101 @@
+package userpass
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathTOTPConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "totp_config",
+		Fields: map[string]*framework.FieldSchema{
+			"required_policies": &framework.FieldSchema{
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Users with any of these token policies must log in with a TOTP passcode.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathTOTPConfigWrite,
+			logical.ReadOperation:   b.pathTOTPConfigRead,
+		},
+
+		HelpSynopsis:    pathTOTPConfigHelpSyn,
+		HelpDescription: pathTOTPConfigHelpDesc,
+	}
+}
+
+type TOTPConfig struct {
+	RequiredPolicies []string `json:"required_policies"`
+}
+
+// requiresTOTP returns true if a user with the given policies must
+// provide a TOTP passcode, whether or not they have enrolled a device.
+func (c *TOTPConfig) requiresTOTP(policies []string) bool {
+	if c == nil {
+		return false
+	}
+	for _, p := range policies {
+		if strutil.StrListContains(c.RequiredPolicies, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *backend) totpConfig(ctx context.Context, s logical.Storage) (*TOTPConfig, error) {
+	entry, err := s.Get(ctx, "totp_config")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result TOTPConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (b *backend) pathTOTPConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entry, err := logical.StorageEntryJSON("totp_config", TOTPConfig{
+		RequiredPolicies: d.Get("required_policies").([]string),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+func (b *backend) pathTOTPConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.totpConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"required_policies": config.RequiredPolicies,
+		},
+	}, nil
+}
+
+const pathTOTPConfigHelpSyn = `
+Configure which users must log in with a TOTP passcode.
+`
+
+const pathTOTPConfigHelpDesc = `
+Users who have enrolled a TOTP device with the "users/<name>/mfa" endpoint
+must always provide a passcode at login. This endpoint additionally requires
+a passcode from any user whose token policies include one of the listed
+policies; such users cannot log in until they have enrolled a device.
+`
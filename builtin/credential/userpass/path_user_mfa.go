@@ -0,0 +1,204 @@
+package userpass
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// totpPeriod is the number of seconds each TOTP passcode is valid for.
+	totpPeriod = 30
+
+	// totpSkew is the number of periods before and after the current one
+	// for which passcodes are accepted, to allow for clock drift.
+	totpSkew = 1
+
+	// totpMFAMethod is the method name used in the X-Vault-MFA header.
+	totpMFAMethod = "totp"
+)
+
+func pathUserMFA(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "users/" + framework.GenericNameRegex("username") + "/mfa$",
+		Fields: map[string]*framework.FieldSchema{
+			"username": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "Username for this user.",
+			},
+
+			"issuer": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Default:     "Vault",
+				Description: "Issuer shown by the user's authenticator application.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathUserMFAUpdate,
+			logical.ReadOperation:   b.pathUserMFARead,
+			logical.DeleteOperation: b.pathUserMFADelete,
+		},
+
+		HelpSynopsis:    pathUserMFAHelpSyn,
+		HelpDescription: pathUserMFAHelpDesc,
+	}
+}
+
+func (b *backend) pathUserMFAUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get("username").(string))
+
+	userEntry, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if userEntry == nil {
+		return logical.ErrorResponse("username does not exist"), logical.ErrInvalidRequest
+	}
+
+	issuer := d.Get("issuer").(string)
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: username,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userEntry.TOTPSecret = key.Secret()
+	userEntry.TOTPIssuer = issuer
+	userEntry.TOTPLastCounter = 0
+	if err := b.setUser(ctx, req.Storage, username, userEntry); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"url":    key.String(),
+			"secret": key.Secret(),
+		},
+	}, nil
+}
+
+func (b *backend) pathUserMFARead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	userEntry, err := b.user(ctx, req.Storage, strings.ToLower(d.Get("username").(string)))
+	if err != nil {
+		return nil, err
+	}
+	if userEntry == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enrolled": userEntry.TOTPSecret != "",
+			"issuer":   userEntry.TOTPIssuer,
+		},
+	}, nil
+}
+
+func (b *backend) pathUserMFADelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get("username").(string))
+
+	userEntry, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if userEntry == nil || userEntry.TOTPSecret == "" {
+		return nil, nil
+	}
+
+	userEntry.TOTPSecret = ""
+	userEntry.TOTPIssuer = ""
+	userEntry.TOTPLastCounter = 0
+	return nil, b.setUser(ctx, req.Storage, username, userEntry)
+}
+
+// totpPasscode returns the passcode supplied with a login request, either
+// in the totp_passcode field or as "totp:<passcode>" in the X-Vault-MFA
+// header.
+func totpPasscode(req *logical.Request, d *framework.FieldData) string {
+	if passcode := d.Get("totp_passcode").(string); passcode != "" {
+		return passcode
+	}
+	if values := req.MFACreds[totpMFAMethod]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// checkTOTP verifies the passcode for the user's enrolled device, and
+// records it as used. It returns an error response if the passcode is
+// not accepted.
+func (b *backend) checkTOTP(ctx context.Context, req *logical.Request, username, passcode string) (*logical.Response, error) {
+	b.totpLock.Lock()
+	defer b.totpLock.Unlock()
+
+	// Reload the user, in case a concurrent login used a passcode.
+	userEntry, err := b.user(ctx, req.Storage, username)
+	if err != nil {
+		return nil, err
+	}
+	if userEntry == nil {
+		return logical.ErrorResponse("invalid username or password"), nil
+	}
+
+	if err := verifyTOTP(userEntry, passcode, time.Now()); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+	return nil, b.setUser(ctx, req.Storage, username, userEntry)
+}
+
+// verifyTOTP checks the passcode against the user's enrolled device. To
+// prevent a passcode from being replayed, it is only accepted for a time
+// period later than that of the last accepted passcode; the user entry is
+// updated with the new period, and the caller must persist it.
+func verifyTOTP(userEntry *UserEntry, passcode string, now time.Time) error {
+	if passcode == "" {
+		return fmt.Errorf("TOTP passcode required")
+	}
+
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+	current := uint64(now.Unix()) / totpPeriod
+	for counter := current - totpSkew; counter <= current+totpSkew; counter++ {
+		expected, err := totp.GenerateCodeCustom(userEntry.TOTPSecret, time.Unix(int64(counter*totpPeriod), 0), opts)
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(passcode)) != 1 {
+			continue
+		}
+		if counter <= userEntry.TOTPLastCounter {
+			return fmt.Errorf("TOTP passcode has already been used")
+		}
+		userEntry.TOTPLastCounter = counter
+		return nil
+	}
+
+	return fmt.Errorf("invalid TOTP passcode")
+}
+
+const pathUserMFAHelpSyn = `
+Manage the user's TOTP device.
+`
+
+const pathUserMFAHelpDesc = `
+Writing to this endpoint enrolls a new TOTP device for the user, replacing
+any existing one, and returns its secret and an otpauth:// URL to load into an
+authenticator application. Once enrolled, logins must include a passcode,
+either in the "totp_passcode" field, or in the X-Vault-MFA header as
+"totp:<passcode>". Deleting the device removes the requirement, unless
+one is required by the user's policies in "totp_config".
+`
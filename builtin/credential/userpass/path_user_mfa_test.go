@@ -0,0 +1,187 @@
+package userpass
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestBackend_TOTP(t *testing.T) {
+	storage := &logical.InmemStorage{}
+
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+
+	ctx := context.Background()
+
+	b, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Path:      "users/testuser",
+		Operation: logical.CreateOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"password":       "testpassword",
+			"token_policies": []string{"foo"},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v\n", resp, err)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Path:      "users/testuser/mfa",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v\n", resp, err)
+	}
+	secret := resp.Data["secret"].(string)
+	if secret == "" {
+		t.Fatal("expected a TOTP secret")
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Path:      "users/testuser/mfa",
+		Operation: logical.ReadOperation,
+		Storage:   storage,
+	})
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("bad: resp: %#v\nerr: %v\n", resp, err)
+	}
+	if resp.Data["enrolled"] != true || resp.Data["issuer"] != "Vault" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	login := func(data map[string]interface{}, creds logical.MFACreds) *logical.Response {
+		t.Helper()
+		data["password"] = "testpassword"
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Path:      "login/testuser",
+			Operation: logical.UpdateOperation,
+			Storage:   storage,
+			Data:      data,
+			MFACreds:  creds,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// A passcode is required once a device is enrolled
+	if resp := login(map[string]interface{}{}, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected login without a passcode to fail, got %#v", resp)
+	}
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := login(map[string]interface{}{"totp_passcode": "000000x"}, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected login with a bad passcode to fail, got %#v", resp)
+	}
+	if resp := login(map[string]interface{}{"totp_passcode": code}, nil); resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// The same passcode cannot be used twice
+	if resp := login(map[string]interface{}{"totp_passcode": code}, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected replayed passcode to fail, got %#v", resp)
+	}
+
+	// A passcode for the next period is accepted from the MFA header
+	code, err = totp.GenerateCode(secret, time.Now().Add(totpPeriod*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := logical.MFACreds{totpMFAMethod: []string{code}}
+	if resp := login(map[string]interface{}{}, creds); resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Once unenrolled, a passcode is only required by the TOTP config
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Path:      "users/testuser/mfa",
+		Operation: logical.DeleteOperation,
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v\n", resp, err)
+	}
+	if resp := login(map[string]interface{}{}, nil); resp == nil || resp.IsError() || resp.Auth == nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Path:      "totp_config",
+		Operation: logical.UpdateOperation,
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"required_policies": "bar,foo",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v\n", resp, err)
+	}
+	if resp := login(map[string]interface{}{}, nil); resp == nil || !resp.IsError() {
+		t.Fatalf("expected login without an enrolled device to fail, got %#v", resp)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      "Vault",
+		AccountName: "testuser",
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &UserEntry{TOTPSecret: key.Secret()}
+	now := time.Unix(1600000000, 0)
+
+	codeAt := func(offset time.Duration) string {
+		t.Helper()
+		code, err := totp.GenerateCode(key.Secret(), now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	if err := verifyTOTP(user, "", now); err == nil {
+		t.Fatal("expected error for missing passcode")
+	}
+
+	// Outside of the allowed skew
+	if err := verifyTOTP(user, codeAt(-3*totpPeriod*time.Second), now); err == nil {
+		t.Fatal("expected error for expired passcode")
+	}
+
+	// Within the allowed skew
+	if err := verifyTOTP(user, codeAt(-totpPeriod*time.Second), now); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTOTP(user, codeAt(0), now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Earlier periods are rejected once a later passcode has been used
+	if err := verifyTOTP(user, codeAt(-totpPeriod*time.Second), now); err == nil {
+		t.Fatal("expected error for replayed passcode")
+	}
+	if err := verifyTOTP(user, codeAt(0), now); err == nil {
+		t.Fatal("expected error for replayed passcode")
+	}
+	if err := verifyTOTP(user, codeAt(totpPeriod*time.Second), now); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -267,6 +267,15 @@ type UserEntry struct {
 	MaxTTL time.Duration
 
 	BoundCIDRs []*sockaddr.SockAddrMarshaler
+
+	// TOTPSecret is the base32 secret of the user's TOTP device, if
+	// one is enrolled.
+	TOTPSecret string
+	TOTPIssuer string
+
+	// TOTPLastCounter is the time period of the last accepted passcode,
+	// so that passcodes cannot be reused.
+	TOTPLastCounter uint64
 }
 
 const pathUserHelpSyn = `
@@ -255,7 +255,7 @@ func (b *databaseBackend) GetConnectionWithConfig(ctx context.Context, name stri
 		return db, nil
 	}
 
-	dbp, err := dbplugin.PluginFactory(ctx, config.PluginName, b.System(), b.logger)
+	dbp, err := dbplugin.PluginFactoryVersion(ctx, config.PluginName, config.PluginVersion, b.System(), b.logger)
 	if err != nil {
 		return nil, err
 	}
@@ -350,7 +350,7 @@ func (b *databaseBackend) clean(ctx context.Context) {
 const backendHelp = `
 The database backend supports using many different databases
 as secret backends, including but not limited to:
-cassandra, mssql, mysql, postgres
+cassandra, clickhouse, mssql, mysql, postgres
 
 After mounting this backend, configure it using the endpoints within
 the "database/config/" path.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -129,6 +130,79 @@ func TestPlugin_GRPC_Main(t *testing.T) {
 	dbplugin.Serve(plugin, api.VaultPluginTLSProvider(apiClientMeta.GetTLSConfig()))
 }
 
+// pidMockPlugin returns the PID of the plugin process as the password of the
+// users, to tell which process served a connection.
+type pidMockPlugin struct {
+	*mockPlugin
+}
+
+func (m *pidMockPlugin) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConf dbplugin.UsernameConfig, expiration time.Time) (string, string, error) {
+	username, _, err := m.mockPlugin.CreateUser(ctx, statements, usernameConf, expiration)
+	return username, strconv.Itoa(os.Getpid()), err
+}
+
+// This is not an actual test case, it's a helper function that will be executed
+// by the go-plugin client via an exec call.
+func TestPlugin_GRPC_Multiplexed_Main(t *testing.T) {
+	if os.Getenv(pluginutil.PluginUnwrapTokenEnv) == "" {
+		return
+	}
+
+	factory := func() (interface{}, error) {
+		return &pidMockPlugin{
+			mockPlugin: &mockPlugin{
+				users: make(map[string][]string),
+			},
+		}, nil
+	}
+
+	args := []string{"--tls-skip-verify=true"}
+
+	apiClientMeta := &api.PluginAPIClientMeta{}
+	flags := apiClientMeta.FlagSet()
+	flags.Parse(args)
+
+	dbplugin.ServeMultiplex(factory, api.VaultPluginTLSProvider(apiClientMeta.GetTLSConfig()))
+}
+
+func TestPlugin_Multiplexing(t *testing.T) {
+	cluster, sys := getCluster(t)
+	defer cluster.Cleanup()
+
+	vault.TestAddTestPlugin(t, cluster.Cores[0].Core, "test-plugin-multiplexed", consts.PluginTypeDatabase, "TestPlugin_GRPC_Multiplexed_Main", []string{}, "")
+
+	usernameConf := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	// Each connection has its own database in the shared plugin process, so
+	// the same user can be created by both
+	var pids []string
+	for i := 0; i < 2; i++ {
+		db, err := dbplugin.PluginFactory(namespace.RootContext(nil), "test-plugin-multiplexed", sys, log.NewNullLogger())
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		defer db.Close()
+
+		_, err = db.Init(context.Background(), map[string]interface{}{"test": i}, true)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, pid, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConf, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		pids = append(pids, pid)
+	}
+
+	if pids[0] != pids[1] {
+		t.Fatalf("expected connections to share the plugin process, got %v", pids)
+	}
+}
+
 func TestPlugin_Init(t *testing.T) {
 	cluster, sys := getCluster(t)
 	defer cluster.Cleanup()
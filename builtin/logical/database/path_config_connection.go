@@ -24,6 +24,8 @@ var (
 // object.
 type DatabaseConfig struct {
 	PluginName string `json:"plugin_name" structs:"plugin_name" mapstructure:"plugin_name"`
+	// PluginVersion pins the version of the plugin registered in the catalog
+	PluginVersion string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
 	// ConnectionDetails stores the database specific connection settings needed
 	// by each database type.
 	ConnectionDetails map[string]interface{} `json:"connection_details" structs:"connection_details" mapstructure:"connection_details"`
@@ -93,6 +95,13 @@ func pathConfigurePluginConnection(b *databaseBackend) *framework.Path {
 				that plugin type.`,
 			},
 
+			"plugin_version": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The version of the plugin registered in the
+				catalog to run. If empty, the plugin registered without a
+				version is run.`,
+			},
+
 			"verify_connection": &framework.FieldSchema{
 				Type:    framework.TypeBool,
 				Default: true,
@@ -262,6 +271,10 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 			return logical.ErrorResponse(respErrEmptyPluginName), nil
 		}
 
+		if pluginVersionRaw, ok := data.GetOk("plugin_version"); ok {
+			config.PluginVersion = pluginVersionRaw.(string)
+		}
+
 		if allowedRolesRaw, ok := data.GetOk("allowed_roles"); ok {
 			config.AllowedRoles = allowedRolesRaw.([]string)
 		} else if req.Operation == logical.CreateOperation {
@@ -278,12 +291,13 @@ func (b *databaseBackend) connectionWriteHandler() framework.OperationFunc {
 		// ConnectionDetails.
 		delete(data.Raw, "name")
 		delete(data.Raw, "plugin_name")
+		delete(data.Raw, "plugin_version")
 		delete(data.Raw, "allowed_roles")
 		delete(data.Raw, "verify_connection")
 		delete(data.Raw, "root_rotation_statements")
 
 		// Create a database plugin and initialize it.
-		db, err := dbplugin.PluginFactory(ctx, config.PluginName, b.System(), b.logger)
+		db, err := dbplugin.PluginFactoryVersion(ctx, config.PluginName, config.PluginVersion, b.System(), b.logger)
 		if err != nil {
 			return logical.ErrorResponse(fmt.Sprintf("error creating database object: %s", err)), nil
 		}
@@ -367,6 +381,9 @@ accepts:
 	   plugin known to vault. This endpoint will create an instance of that
 	   plugin type.
 
+	* "plugin_version" - The version of the plugin registered in the catalog to
+	   run.
+
 	* "verify_connection" (default: true) - A boolean value denoting if the plugin should verify
 	   it is able to connect to the database using the provided connection
        details.
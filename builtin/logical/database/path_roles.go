@@ -161,7 +161,20 @@ func staticFields() map[string]*framework.FieldSchema {
 			Type: framework.TypeDurationSecond,
 			Description: `Period for automatic
 	credential rotation of the given username. Not valid unless used with
-	"username".`,
+	"username". If "rotation_schedule" is set, this is the minimum time
+	between rotations.`,
+		},
+		"rotation_schedule": {
+			Type: framework.TypeString,
+			Description: `Cron-style schedule, in UTC, of the times at which
+	automatic rotations of the given username may start, such as "0 2 * * SAT".
+	If set, rotations only happen within "rotation_max_skew" of these times.`,
+		},
+		"rotation_max_skew": {
+			Type: framework.TypeDurationSecond,
+			Description: `How long after a scheduled time a rotation may still
+	happen. Rotations that cannot happen in time wait for the next scheduled
+	time. Only valid with "rotation_schedule". Defaults to 1 hour.`,
 		},
 		"rotation_statements": {
 			Type: framework.TypeStringSlice,
@@ -239,7 +252,13 @@ func (b *databaseBackend) pathStaticRoleRead(ctx context.Context, req *logical.R
 		data["rotation_period"] = role.StaticAccount.RotationPeriod.Seconds()
 		if !role.StaticAccount.LastVaultRotation.IsZero() {
 			data["last_vault_rotation"] = role.StaticAccount.LastVaultRotation
+			data["last_rotation"] = role.StaticAccount.LastVaultRotation
 		}
+		if role.StaticAccount.RotationSchedule != "" {
+			data["rotation_schedule"] = role.StaticAccount.RotationSchedule
+			data["rotation_max_skew"] = role.StaticAccount.rotationMaxSkew().Seconds()
+		}
+		data["next_scheduled_rotation"] = role.StaticAccount.NextRotationTime()
 	}
 
 	if len(role.Statements.Rotation) == 0 {
@@ -455,9 +474,31 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	}
 	role.StaticAccount.Username = username
 
-	// If it's a Create operation, both username and rotation_period must be included
+	if rotationScheduleRaw, ok := data.GetOk("rotation_schedule"); ok {
+		rotationSchedule := strings.TrimSpace(rotationScheduleRaw.(string))
+		if rotationSchedule != "" {
+			if _, err := parseRotationSchedule(rotationSchedule); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid rotation_schedule: %s", err)), nil
+			}
+		}
+		role.StaticAccount.RotationSchedule = rotationSchedule
+	}
+
+	if rotationMaxSkewRaw, ok := data.GetOk("rotation_max_skew"); ok {
+		rotationMaxSkewSeconds := rotationMaxSkewRaw.(int)
+		if rotationMaxSkewSeconds < queueTickSeconds {
+			return logical.ErrorResponse(fmt.Sprintf("rotation_max_skew must be %d seconds or more", queueTickSeconds)), nil
+		}
+		role.StaticAccount.RotationMaxSkew = time.Duration(rotationMaxSkewSeconds) * time.Second
+	}
+	if role.StaticAccount.RotationSchedule == "" && role.StaticAccount.RotationMaxSkew != 0 {
+		return logical.ErrorResponse("rotation_max_skew requires rotation_schedule to be set"), nil
+	}
+
+	// If it's a Create operation, both username and rotation_period must be
+	// included, unless the role rotates on a schedule
 	rotationPeriodSecondsRaw, ok := data.GetOk("rotation_period")
-	if !ok && createRole {
+	if !ok && createRole && role.StaticAccount.RotationSchedule == "" {
 		return logical.ErrorResponse("rotation_period is required to create static accounts"), nil
 	}
 	if ok {
@@ -477,9 +518,6 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		role.Statements.Rotation = data.Get("rotation_statements").([]string)
 	}
 
-	// lvr represents the roles' LastVaultRotation
-	lvr := role.StaticAccount.LastVaultRotation
-
 	// Only call setStaticAccount if we're creating the role for the
 	// first time
 	switch req.Operation {
@@ -494,7 +532,9 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 			return nil, err
 		}
 		// guard against RotationTime not being set or zero-value
-		lvr = resp.RotationTime
+		if !resp.RotationTime.IsZero() {
+			role.StaticAccount.LastVaultRotation = resp.RotationTime
+		}
 	case logical.UpdateOperation:
 		// store updated Role
 		entry, err := logical.StorageEntryJSON(databaseStaticRolePath+name, role)
@@ -513,7 +553,7 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	// Add their rotation to the queue
 	if err := b.pushItem(&queue.Item{
 		Key:      name,
-		Priority: lvr.Add(role.StaticAccount.RotationPeriod).Unix(),
+		Priority: role.StaticAccount.NextRotationTime().Unix(),
 	}); err != nil {
 		return nil, err
 	}
@@ -546,15 +586,24 @@ type staticAccount struct {
 	// determine if a password needs to be rotated
 	RotationPeriod time.Duration `json:"rotation_period"`
 
+	// RotationSchedule is an optional cron-style schedule of the times at
+	// which automatic rotations may start
+	RotationSchedule string `json:"rotation_schedule"`
+
+	// RotationMaxSkew is how long after a scheduled time an automatic
+	// rotation may still happen
+	RotationMaxSkew time.Duration `json:"rotation_max_skew"`
+
 	// RevokeUser is a boolean flag to indicate if Vault should revoke the
 	// database user when the role is deleted
 	RevokeUserOnDelete bool `json:"revoke_user_on_delete"`
 }
 
 // NextRotationTime calculates the next rotation by adding the Rotation Period
-// to the last known vault rotation
+// to the last known vault rotation, and moving it to the next scheduled time
+// if the account has a rotation schedule
 func (s *staticAccount) NextRotationTime() time.Time {
-	return s.LastVaultRotation.Add(s.RotationPeriod)
+	return s.nextRotationTimeAfter(time.Time{})
 }
 
 // PasswordTTL calculates the approximate time remaining until the password is
@@ -63,7 +63,7 @@ func (b *databaseBackend) populateQueue(ctx context.Context, s logical.Storage)
 
 		item := queue.Item{
 			Key:      roleName,
-			Priority: role.StaticAccount.LastVaultRotation.Add(role.StaticAccount.RotationPeriod).Unix(),
+			Priority: role.StaticAccount.NextRotationTime().Unix(),
 		}
 
 		// Check if role name is in map
@@ -183,6 +183,20 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 		return false
 	}
 
+	// If the rotation window has passed, for example because Vault was sealed
+	// or earlier attempts failed, wait for the next one. Rotations recovering
+	// from a WAL are completed regardless, since the new password may already
+	// be set in the database.
+	if _, hasWAL := item.Value.(string); !hasWAL && !role.StaticAccount.inRotationWindow(time.Now()) {
+		next := role.StaticAccount.nextRotationTimeAfter(time.Now())
+		b.logger.Warn("missed rotation window for static role, waiting for the next one", "role", item.Key, "next_rotation", next)
+		item.Priority = next.Unix()
+		if err := b.pushItem(item); err != nil {
+			b.logger.Error("unable to push item on to queue", "error", err)
+		}
+		return true
+	}
+
 	input := &setStaticAccountInput{
 		RoleName: item.Key,
 		Role:     role,
@@ -224,13 +238,12 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 		return true
 	}
 
-	lvr := resp.RotationTime
-	if lvr.IsZero() {
-		lvr = time.Now()
+	if resp.RotationTime.IsZero() {
+		role.StaticAccount.LastVaultRotation = time.Now()
 	}
 
 	// Update priority and push updated Item to the queue
-	nextRotation := lvr.Add(role.StaticAccount.RotationPeriod)
+	nextRotation := role.StaticAccount.NextRotationTime()
 	item.Priority = nextRotation.Unix()
 	if err := b.pushItem(item); err != nil {
 		b.logger.Warn("unable to push item on to queue", "error", err)
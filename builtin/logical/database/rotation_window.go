@@ -0,0 +1,232 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultRotationMaxSkew is how long after a scheduled time a rotation may
+	// still happen when rotation_max_skew isn't given.
+	defaultRotationMaxSkew = time.Hour
+
+	// maxScheduleSearchYears bounds the search for the next scheduled time, so
+	// that schedules which can never match (e.g. February 30th) terminate.
+	maxScheduleSearchYears = 5
+)
+
+// rotationSchedule is a parsed cron-style schedule with the standard five
+// fields: minute, hour, day of month, month and day of week. Times are
+// evaluated in UTC.
+type rotationSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// domStar and dowStar record whether the day fields were unrestricted,
+	// since cron matches either day field when both are restricted.
+	domStar bool
+	dowStar bool
+}
+
+type scheduleField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var scheduleFields = []scheduleField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is accepted as Sunday, as in most cron implementations
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// parseRotationSchedule parses a cron-style schedule such as "0 2 * * SAT".
+// Each field may be "*", a value, a range "a-b", a step "*/n" or "a-b/n", or
+// a comma-separated list of those.
+func parseRotationSchedule(schedule string) (*rotationSchedule, error) {
+	fields := strings.Fields(schedule)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("expected %d fields in schedule, got %d", len(scheduleFields), len(fields))
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseScheduleField(field, scheduleFields[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &rotationSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseScheduleField(field string, f scheduleField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", part[i+1:], f.name)
+			}
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			if end, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			var err error
+			if start, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			// A single value with a step runs to the end of the range
+			if step == 1 {
+				end = start
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f scheduleField) value(raw string) (int, error) {
+	if v, ok := f.names[strings.ToLower(raw)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field", raw, f.name)
+	}
+	return v, nil
+}
+
+// next returns the first scheduled time strictly after t, or the zero time if
+// there is none within the next few years.
+func (s *rotationSchedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + maxScheduleSearchYears
+
+	for t.Year() <= limit {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *rotationSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// schedule returns the parsed rotation schedule of the account, or nil if
+// the account rotates purely on its rotation period. The schedule is
+// validated when the role is written, so an invalid one is ignored.
+func (s *staticAccount) schedule() *rotationSchedule {
+	if s.RotationSchedule == "" {
+		return nil
+	}
+	schedule, err := parseRotationSchedule(s.RotationSchedule)
+	if err != nil {
+		return nil
+	}
+	return schedule
+}
+
+// rotationMaxSkew returns how long after a scheduled time the account may
+// still be rotated.
+func (s *staticAccount) rotationMaxSkew() time.Duration {
+	if s.RotationMaxSkew == 0 {
+		return defaultRotationMaxSkew
+	}
+	return s.RotationMaxSkew
+}
+
+// inRotationWindow reports whether t falls within a rotation window, that is
+// no later than the max skew after a scheduled time. Accounts without a
+// schedule can always be rotated.
+func (s *staticAccount) inRotationWindow(t time.Time) bool {
+	schedule := s.schedule()
+	if schedule == nil {
+		return true
+	}
+	windowStart := schedule.next(t.Add(-s.rotationMaxSkew()).Add(-time.Nanosecond))
+	return !windowStart.IsZero() && !windowStart.After(t)
+}
+
+// nextRotationTimeAfter returns the first time after t at which the account
+// may be rotated, honoring both its rotation period and schedule.
+func (s *staticAccount) nextRotationTimeAfter(t time.Time) time.Time {
+	next := s.LastVaultRotation.Add(s.RotationPeriod)
+	if next.Before(t) {
+		next = t
+	}
+
+	schedule := s.schedule()
+	if schedule == nil {
+		return next
+	}
+	scheduled := schedule.next(next.Add(-time.Nanosecond))
+	if scheduled.IsZero() {
+		// The schedule never matches; fall back to the rotation period
+		return next
+	}
+	return scheduled
+}
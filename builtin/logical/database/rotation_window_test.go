@@ -0,0 +1,125 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotationSchedule_Parse(t *testing.T) {
+	for _, schedule := range []string{
+		"* * * * *",
+		"0 2 * * SAT",
+		"*/15 1-4 * * mon-fri",
+		"30 23 1,15 jan-mar,dec *",
+		"0 0 * * 7",
+		"5/10 * * * *",
+	} {
+		if _, err := parseRotationSchedule(schedule); err != nil {
+			t.Fatalf("%q: unexpected error: %v", schedule, err)
+		}
+	}
+
+	for _, schedule := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"foo * * * *",
+	} {
+		if _, err := parseRotationSchedule(schedule); err == nil {
+			t.Fatalf("%q: expected error", schedule)
+		}
+	}
+}
+
+func TestRotationSchedule_Next(t *testing.T) {
+	// A Wednesday
+	base := time.Date(2020, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		schedule string
+		from     time.Time
+		expected time.Time
+	}{
+		{"* * * * *", base, base.Add(time.Minute)},
+		{"* * * * *", base.Add(10 * time.Second), base.Add(time.Minute)},
+		{"0 * * * *", base, time.Date(2020, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 2 * * SAT", base, time.Date(2020, 1, 18, 2, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", base, time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"*/20 9-17 * * mon-fri", time.Date(2020, 1, 17, 17, 50, 0, 0, time.UTC), time.Date(2020, 1, 20, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", base, time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 dec *", base, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", base, time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// When both day fields are restricted, either may match
+		{"0 0 1 * fri", base, time.Date(2020, 1, 17, 0, 0, 0, 0, time.UTC)},
+		// Schedules that never match
+		{"0 0 30 2 *", base, time.Time{}},
+	} {
+		schedule, err := parseRotationSchedule(tc.schedule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := schedule.next(tc.from); !actual.Equal(tc.expected) {
+			t.Fatalf("%q from %s: expected %s, got %s", tc.schedule, tc.from, tc.expected, actual)
+		}
+	}
+}
+
+func TestStaticAccount_RotationWindow(t *testing.T) {
+	lvr := time.Date(2020, 1, 15, 10, 30, 0, 0, time.UTC)
+	account := &staticAccount{
+		LastVaultRotation: lvr,
+		RotationPeriod:    24 * time.Hour,
+		RotationSchedule:  "0 2 * * *",
+		RotationMaxSkew:   30 * time.Minute,
+	}
+
+	// The period elapses after the day's window, so the next rotation waits
+	// for the following one
+	expected := time.Date(2020, 1, 17, 2, 0, 0, 0, time.UTC)
+	if next := account.NextRotationTime(); !next.Equal(expected) {
+		t.Fatalf("expected %s, got %s", expected, next)
+	}
+
+	for _, tc := range []struct {
+		at       time.Time
+		inWindow bool
+	}{
+		{time.Date(2020, 1, 17, 1, 59, 0, 0, time.UTC), false},
+		{time.Date(2020, 1, 17, 2, 0, 0, 0, time.UTC), true},
+		{time.Date(2020, 1, 17, 2, 30, 0, 0, time.UTC), true},
+		{time.Date(2020, 1, 17, 2, 31, 0, 0, time.UTC), false},
+	} {
+		if actual := account.inRotationWindow(tc.at); actual != tc.inWindow {
+			t.Fatalf("%s: expected %t, got %t", tc.at, tc.inWindow, actual)
+		}
+	}
+
+	// A missed window moves the rotation to the next one
+	expected = time.Date(2020, 1, 18, 2, 0, 0, 0, time.UTC)
+	if next := account.nextRotationTimeAfter(time.Date(2020, 1, 17, 3, 0, 0, 0, time.UTC)); !next.Equal(expected) {
+		t.Fatalf("expected %s, got %s", expected, next)
+	}
+
+	// The default max skew applies when none is set
+	account.RotationMaxSkew = 0
+	if !account.inRotationWindow(time.Date(2020, 1, 17, 2, 59, 0, 0, time.UTC)) {
+		t.Fatal("expected to be within the default rotation window")
+	}
+
+	// Without a schedule, accounts rotate on their period and at any time
+	account.RotationSchedule = ""
+	if next := account.NextRotationTime(); !next.Equal(lvr.Add(24 * time.Hour)) {
+		t.Fatalf("bad: %s", next)
+	}
+	if !account.inRotationWindow(time.Date(2020, 1, 17, 3, 0, 0, 0, time.UTC)) {
+		t.Fatal("expected accounts without a schedule to always be in the rotation window")
+	}
+}
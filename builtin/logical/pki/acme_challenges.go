@@ -0,0 +1,122 @@
+package pki
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// acmeValidationTimeout bounds how long validating a challenge may take
+	acmeValidationTimeout = 10 * time.Second
+
+	// acmeMaxHTTP01ResponseSize limits how much of an http-01 response is read
+	acmeMaxHTTP01ResponseSize = 4096
+)
+
+// acmeValidator validates ACME challenges against the systems the
+// identifiers of an order point to.
+type acmeValidator struct {
+	// httpPort is the port http-01 challenges are validated on. It is only
+	// changed by tests.
+	httpPort int
+
+	// lookupTXT, if set, replaces the DNS lookups of dns-01 challenges. It is
+	// only set by tests.
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+func newACMEValidator() *acmeValidator {
+	return &acmeValidator{
+		httpPort: 80,
+	}
+}
+
+// validate checks that the client completed the challenge, returning an
+// error describing why it did not otherwise.
+func (v *acmeValidator) validate(ctx context.Context, config *acmeConfig, authz *acmeAuthorization, challenge *acmeChallenge, keyAuthorization string) error {
+	ctx, cancel := context.WithTimeout(ctx, acmeValidationTimeout)
+	defer cancel()
+
+	switch challenge.Type {
+	case acmeChallengeHTTP01:
+		return v.validateHTTP01(ctx, authz.Identifier.Value, challenge.Token, keyAuthorization)
+	case acmeChallengeDNS01:
+		return v.validateDNS01(ctx, config, authz.Identifier.Value, keyAuthorization)
+	default:
+		return newACMEError(http.StatusBadRequest, acmeErrMalformed, "unsupported challenge type %q", challenge.Type)
+	}
+}
+
+// validateHTTP01 fetches the key authorization the client is expected to
+// serve at a well-known path of the identifier, per RFC 8555 section 8.3.
+func (v *acmeValidator) validateHTTP01(ctx context.Context, host, token, keyAuthorization string) error {
+	url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", net.JoinHostPort(host, strconv.Itoa(v.httpPort)), token)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return newACMEError(http.StatusBadRequest, acmeErrConnection, "error fetching %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return newACMEError(http.StatusBadRequest, acmeErrIncorrectResponse, "%s returned status %d", url, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, acmeMaxHTTP01ResponseSize))
+	if err != nil {
+		return newACMEError(http.StatusBadRequest, acmeErrConnection, "error reading %s: %s", url, err)
+	}
+	// Trailing whitespace is ignored, as recommended by the RFC
+	if strings.TrimRight(string(body), " \t\r\n") != keyAuthorization {
+		return newACMEError(http.StatusBadRequest, acmeErrIncorrectResponse, "%s did not return the expected key authorization", url)
+	}
+	return nil
+}
+
+// validateDNS01 looks for the digest of the key authorization in the TXT
+// records of the identifier, per RFC 8555 section 8.4.
+func (v *acmeValidator) validateDNS01(ctx context.Context, config *acmeConfig, domain, keyAuthorization string) error {
+	name := "_acme-challenge." + domain
+
+	lookupTXT := v.lookupTXT
+	if lookupTXT == nil {
+		resolver := net.DefaultResolver
+		if config.DNSResolver != "" {
+			resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, config.DNSResolver)
+				},
+			}
+		}
+		lookupTXT = resolver.LookupTXT
+	}
+
+	records, err := lookupTXT(ctx, name)
+	if err != nil {
+		return newACMEError(http.StatusBadRequest, acmeErrDNS, "error looking up TXT records of %s: %s", name, err)
+	}
+
+	digest := sha256.Sum256([]byte(keyAuthorization))
+	expected := base64.RawURLEncoding.EncodeToString(digest[:])
+	for _, record := range records {
+		if record == expected {
+			return nil
+		}
+	}
+	return newACMEError(http.StatusBadRequest, acmeErrIncorrectResponse, "no TXT record of %s matches the key authorization", name)
+}
@@ -0,0 +1,227 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	// acmeNonceLifetime is how long an unused nonce remains valid
+	acmeNonceLifetime = 15 * time.Minute
+
+	// acmeNoncePruneInterval is how often expired nonces are removed
+	acmeNoncePruneInterval = time.Minute
+)
+
+// acmeAllowedAlgorithms are the JWS algorithms accepted on ACME requests.
+// Symmetric algorithms are excluded, as required by RFC 8555.
+var acmeAllowedAlgorithms = []string{
+	string(jose.RS256),
+	string(jose.ES256),
+	string(jose.ES384),
+	string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// acmeNonces tracks the nonces handed out to ACME clients. Nonces are kept in
+// memory, so a request must be made to the node that issued its nonce; ACME
+// requests are always handled by the active node for this reason.
+type acmeNonces struct {
+	sync.Mutex
+	nonces    map[string]time.Time
+	lastPrune time.Time
+}
+
+func newACMENonces() *acmeNonces {
+	return &acmeNonces{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+func (n *acmeNonces) new() (string, error) {
+	nonce, err := newACMEToken()
+	if err != nil {
+		return "", err
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	now := time.Now()
+	if now.Sub(n.lastPrune) > acmeNoncePruneInterval {
+		for old, expires := range n.nonces {
+			if now.After(expires) {
+				delete(n.nonces, old)
+			}
+		}
+		n.lastPrune = now
+	}
+
+	n.nonces[nonce] = now.Add(acmeNonceLifetime)
+	return nonce, nil
+}
+
+// redeem consumes the nonce, returning whether it was valid.
+func (n *acmeNonces) redeem(nonce string) bool {
+	n.Lock()
+	defer n.Unlock()
+
+	expires, ok := n.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(n.nonces, nonce)
+	return time.Now().Before(expires)
+}
+
+// acmeRequest is a verified ACME request. Exactly one of account and jwk is
+// set, depending on whether the request was signed by an existing account or
+// with an embedded key.
+type acmeRequest struct {
+	payload []byte
+	url     string
+	account *acmeAccount
+	jwk     *jose.JSONWebKey
+}
+
+func (r *acmeRequest) requireAccount() error {
+	if r.account == nil {
+		return newACMEError(http.StatusBadRequest, acmeErrMalformed, "request must be signed with the kid of an account")
+	}
+	return nil
+}
+
+func (r *acmeRequest) requireJWK() error {
+	if r.jwk == nil {
+		return newACMEError(http.StatusBadRequest, acmeErrMalformed, "request must be signed with an embedded jwk")
+	}
+	return nil
+}
+
+// decodePayload decodes the JSON payload of the request into out. Empty
+// payloads, as sent by POST-as-GET requests, leave out untouched.
+func (r *acmeRequest) decodePayload(out interface{}) error {
+	if len(r.payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.payload, out); err != nil {
+		return newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not decode payload: %s", err)
+	}
+	return nil
+}
+
+// acmeJWSFields are the fields of the flattened JWS JSON serialization every
+// ACME request is made with.
+func acmeJWSFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["protected"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The base64url-encoded protected header of the JWS.`,
+	}
+	fields["payload"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The base64url-encoded payload of the JWS.`,
+	}
+	fields["signature"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The base64url-encoded signature of the JWS.`,
+	}
+	return fields
+}
+
+// verifyACMERequest parses and verifies the JWS the request was made with,
+// checking its nonce, URL and signature.
+func (b *backend) verifyACMERequest(ctx context.Context, ac *acmeContext, req *logical.Request, data *framework.FieldData) (*acmeRequest, error) {
+	jws, err := parseACMEJWS(map[string]string{
+		"protected": data.Get("protected").(string),
+		"payload":   data.Get("payload").(string),
+		"signature": data.Get("signature").(string),
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := jws.Signatures[0].Protected
+
+	if !b.acmeNonces.redeem(header.Nonce) {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadNonce, "invalid or expired nonce")
+	}
+
+	url, _ := header.ExtraHeaders["url"].(string)
+	if url != ac.config.BaseURL+"/"+req.Path {
+		return nil, newACMEError(http.StatusUnauthorized, acmeErrUnauthorized, "url %q in the protected header does not match the request", url)
+	}
+
+	r := &acmeRequest{
+		url: url,
+	}
+	var key *jose.JSONWebKey
+	switch {
+	case header.JSONWebKey != nil && header.KeyID != "":
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "the jwk and kid headers are mutually exclusive")
+
+	case header.JSONWebKey != nil:
+		r.jwk = header.JSONWebKey
+		key = r.jwk
+
+	case strings.HasPrefix(header.KeyID, ac.url("account/")):
+		r.account, err = getACMEAccount(ctx, req.Storage, strings.TrimPrefix(header.KeyID, ac.url("account/")))
+		if err != nil {
+			return nil, err
+		}
+		if r.account == nil {
+			return nil, newACMEError(http.StatusBadRequest, acmeErrAccountDoesNotExist, "account %q does not exist", header.KeyID)
+		}
+		if r.account.Status != acmeStatusValid {
+			return nil, newACMEError(http.StatusUnauthorized, acmeErrUnauthorized, "account is %s", r.account.Status)
+		}
+		key = r.account.Key
+
+	default:
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "request must be signed with a jwk or the kid of an account of this directory")
+	}
+
+	r.payload, err = jws.Verify(key)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not verify JWS: %s", err)
+	}
+	return r, nil
+}
+
+// parseACMEJWS parses a flattened JWS, checking that its algorithm is allowed.
+// The signature itself is not verified.
+func parseACMEJWS(raw interface{}) (*jose.JSONWebSignature, error) {
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	jws, err := jose.ParseSigned(string(encoded))
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not parse JWS: %s", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "JWS must have exactly one signature")
+	}
+	if alg := jws.Signatures[0].Protected.Algorithm; !strutil.StrListContains(acmeAllowedAlgorithms, alg) {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadSignatureAlgorithm, "unsupported JWS algorithm %q", alg)
+	}
+	return jws, nil
+}
+
+// jwkThumbprint returns the base64url-encoded RFC 7638 thumbprint of the key.
+func jwkThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", newACMEError(http.StatusBadRequest, acmeErrBadPublicKey, "could not compute key thumbprint: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
@@ -0,0 +1,207 @@
+package pki
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeAccountPrefix           = "acme/accounts/"
+	acmeAccountThumbprintPrefix = "acme/account-thumbprints/"
+	acmeAccountOrdersPrefix     = "acme/account-orders/"
+	acmeOrderPrefix             = "acme/orders/"
+	acmeAuthorizationPrefix     = "acme/authz/"
+	acmeCertPrefix              = "acme/certs/"
+
+	// acmeOrderLifetime is how long clients have to complete an order
+	acmeOrderLifetime = 24 * time.Hour
+
+	acmeStatusPending     = "pending"
+	acmeStatusReady       = "ready"
+	acmeStatusValid       = "valid"
+	acmeStatusInvalid     = "invalid"
+	acmeStatusDeactivated = "deactivated"
+
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+)
+
+type acmeAccount struct {
+	ID                   string           `json:"id"`
+	Key                  *jose.JSONWebKey `json:"key"`
+	Thumbprint           string           `json:"thumbprint"`
+	Status               string           `json:"status"`
+	Contact              []string         `json:"contact"`
+	TermsOfServiceAgreed bool             `json:"terms_of_service_agreed"`
+	CreatedAt            time.Time        `json:"created_at"`
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeOrder struct {
+	ID               string           `json:"id"`
+	AccountID        string           `json:"account_id"`
+	Role             string           `json:"role"`
+	Status           string           `json:"status"`
+	Expires          time.Time        `json:"expires"`
+	Identifiers      []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs []string         `json:"authorization_ids"`
+	SerialNumber     string           `json:"serial_number"`
+}
+
+type acmeChallenge struct {
+	Type      string    `json:"type"`
+	Token     string    `json:"token"`
+	Status    string    `json:"status"`
+	Validated time.Time `json:"validated"`
+	ErrorType string    `json:"error_type"`
+	Error     string    `json:"error"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Status     string           `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+func getACMEEntry(ctx context.Context, s logical.Storage, key string, out interface{}) (bool, error) {
+	entry, err := s.Get(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func putACMEEntry(ctx context.Context, s logical.Storage, key string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, value)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func getACMEAccount(ctx context.Context, s logical.Storage, id string) (*acmeAccount, error) {
+	var account acmeAccount
+	ok, err := getACMEEntry(ctx, s, acmeAccountPrefix+id, &account)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// getACMEAccountByThumbprint returns the account registered with the key
+// having the given thumbprint, if any.
+func getACMEAccountByThumbprint(ctx context.Context, s logical.Storage, thumbprint string) (*acmeAccount, error) {
+	var id string
+	ok, err := getACMEEntry(ctx, s, acmeAccountThumbprintPrefix+thumbprint, &id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return getACMEAccount(ctx, s, id)
+}
+
+func putACMEAccount(ctx context.Context, s logical.Storage, account *acmeAccount) error {
+	if err := putACMEEntry(ctx, s, acmeAccountPrefix+account.ID, account); err != nil {
+		return err
+	}
+	return putACMEEntry(ctx, s, acmeAccountThumbprintPrefix+account.Thumbprint, account.ID)
+}
+
+func getACMEOrder(ctx context.Context, s logical.Storage, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	ok, err := getACMEEntry(ctx, s, acmeOrderPrefix+id, &order)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func putACMEOrder(ctx context.Context, s logical.Storage, order *acmeOrder) error {
+	if err := putACMEEntry(ctx, s, acmeOrderPrefix+order.ID, order); err != nil {
+		return err
+	}
+	return s.Put(ctx, &logical.StorageEntry{
+		Key: acmeAccountOrdersPrefix + order.AccountID + "/" + order.ID,
+	})
+}
+
+func getACMEAuthorization(ctx context.Context, s logical.Storage, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	ok, err := getACMEEntry(ctx, s, acmeAuthorizationPrefix+id, &authz)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+func putACMEAuthorization(ctx context.Context, s logical.Storage, authz *acmeAuthorization) error {
+	return putACMEEntry(ctx, s, acmeAuthorizationPrefix+authz.ID, authz)
+}
+
+// refreshStatus updates the status of a pending order from its
+// authorizations and expiry, storing it if it changed.
+func (o *acmeOrder) refreshStatus(ctx context.Context, s logical.Storage) error {
+	status := o.Status
+	switch {
+	case status != acmeStatusPending && status != acmeStatusReady:
+	case time.Now().After(o.Expires):
+		status = acmeStatusInvalid
+	case status == acmeStatusPending:
+		ready := true
+		for _, id := range o.AuthorizationIDs {
+			authz, err := getACMEAuthorization(ctx, s, id)
+			if err != nil {
+				return err
+			}
+			if authz == nil || authz.Status == acmeStatusInvalid || authz.Status == acmeStatusDeactivated {
+				status = acmeStatusInvalid
+				break
+			}
+			if authz.Status != acmeStatusValid {
+				ready = false
+			}
+		}
+		if status == acmeStatusPending && ready {
+			status = acmeStatusReady
+		}
+	}
+
+	if status == o.Status {
+		return nil
+	}
+	o.Status = status
+	return putACMEOrder(ctx, s, o)
+}
+
+func newACMEID() (string, error) {
+	return uuid.GenerateUUID()
+}
+
+// newACMEToken returns a random, URL-safe token with 128 bits of entropy, as
+// required of challenge tokens.
+func newACMEToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -33,12 +35,19 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"ca",
 				"crl/pem",
 				"crl",
+				"crl/delta/pem",
+				"crl/delta",
+				"issuer/*",
+				"acme/*",
 			},
 
 			LocalStorage: []string{
 				"revoked/",
 				"crl",
+				"delta-crls/",
+				"delta-revoked/",
 				"certs/",
+				"acme/",
 			},
 
 			Root: []string{
@@ -48,6 +57,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 
 			SealWrapStorage: []string{
 				"config/ca_bundle",
+				"issuers/",
 			},
 		},
 
@@ -63,6 +73,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathConfigCA(&b),
 			pathConfigCRL(&b),
 			pathConfigURLs(&b),
+			pathConfigACME(&b),
 			pathSignVerbatim(&b),
 			pathSign(&b),
 			pathIssue(&b),
@@ -71,6 +82,9 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathFetchCAChain(&b),
 			pathFetchCRL(&b),
 			pathFetchCRLViaCertPath(&b),
+			pathFetchDeltaCRL(&b),
+			pathFetchIssuerCRL(&b),
+			pathListIssuers(&b),
 			pathFetchValid(&b),
 			pathFetchListCerts(&b),
 			pathRevoke(&b),
@@ -81,12 +95,19 @@ func Backend(conf *logical.BackendConfig) *backend {
 			secretCerts(&b),
 		},
 
+		PeriodicFunc: b.periodicFunc,
+
 		BackendType: logical.TypeLogical,
 	}
 
+	b.Backend.Paths = append(b.Backend.Paths, pathACME(&b)...)
+
 	b.crlLifetime = time.Hour * 72
 	b.tidyCASGuard = new(uint32)
 	b.storage = conf.StorageView
+	b.acmeNonces = newACMENonces()
+	b.acmeValidator = newACMEValidator()
+	b.acmeLocks = locksutil.CreateLocks()
 
 	return &b
 }
@@ -98,6 +119,20 @@ type backend struct {
 	crlLifetime       time.Duration
 	revokeStorageLock sync.RWMutex
 	tidyCASGuard      *uint32
+
+	acmeNonces    *acmeNonces
+	acmeValidator *acmeValidator
+	acmeLocks     []*locksutil.LockEntry
+}
+
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	// Performance standbys cannot write the CRLs; the active node rebuilds
+	// them
+	if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil
+	}
+
+	return b.periodicRebuildCRL(ctx, req)
 }
 
 const backendHelp = `
@@ -105,4 +140,7 @@ The PKI backend dynamically generates X509 server and client certificates.
 
 After mounting this backend, configure the CA using the "pem_bundle" endpoint within
 the "config/" path.
+
+Certificates may also be obtained by ACME clients once the ACME server is
+enabled through the "config/acme" endpoint.
 `
@@ -1,11 +1,19 @@
 package pki
 
 import (
+	"context"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
 )
@@ -122,3 +130,286 @@ func TestBackend_CRL_EnableDisable(t *testing.T) {
 	toggle(false)
 	test(6)
 }
+
+func testCRLRequest(t *testing.T, b *backend, storage logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   storage,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("%s: err: %v resp: %#v", path, err, resp)
+	}
+	return resp
+}
+
+// testFetchCRL fetches a raw CRL, checking it is signed by the given CA
+func testFetchCRL(t *testing.T, b *backend, storage logical.Storage, path string, ca *x509.Certificate) *pkix.CertificateList {
+	t.Helper()
+
+	resp := testCRLRequest(t, b, storage, logical.ReadOperation, path, nil)
+	if resp == nil || resp.Data[logical.HTTPStatusCode] != 200 {
+		t.Fatalf("%s: expected a CRL, got %#v", path, resp)
+	}
+	crl, err := x509.ParseCRL(resp.Data[logical.HTTPRawBody].([]byte))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	if err := ca.CheckCRLSignature(crl); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return crl
+}
+
+func testCRLSerials(crl *pkix.CertificateList) map[string]bool {
+	serials := make(map[string]bool)
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		serials[revoked.SerialNumber.String()] = true
+	}
+	return serials
+}
+
+func testCRLNumber(t *testing.T, crl *pkix.CertificateList, id asn1.ObjectIdentifier) int64 {
+	t.Helper()
+
+	for _, ext := range crl.TBSCertList.Extensions {
+		if ext.Id.Equal(id) {
+			var number *big.Int
+			if _, err := asn1.Unmarshal(ext.Value, &number); err != nil {
+				t.Fatal(err)
+			}
+			return number.Int64()
+		}
+	}
+	return 0
+}
+
+func testIssueCert(t *testing.T, b *backend, storage logical.Storage, role string) (string, *x509.Certificate) {
+	t.Helper()
+
+	resp := testCRLRequest(t, b, storage, logical.UpdateOperation, "issue/"+role, map[string]interface{}{
+		"common_name": "test.example.com",
+	})
+	cert, err := testParseCert(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.Data["serial_number"].(string), cert
+}
+
+func testParseCert(pemCert string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(pemCert))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %q", pemCert)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func testCRLSetup(t *testing.T) (*backend, logical.Storage, *x509.Certificate) {
+	t.Helper()
+
+	b, storage := createBackendWithStorage(t)
+	resp := testCRLRequest(t, b, storage, logical.UpdateOperation, "root/generate/internal", map[string]interface{}{
+		"common_name": "myvault.com",
+	})
+	ca, err := testParseCert(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"ttl":              "1h",
+	})
+	return b, storage, ca
+}
+
+func TestBackend_CRL_Delta(t *testing.T) {
+	b, storage, ca := testCRLSetup(t)
+
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"auto_rebuild":           true,
+		"enable_delta":           true,
+		"delta_rebuild_interval": "1ms",
+	})
+
+	serial, cert := testIssueCert(t, b, storage, "example")
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+
+	// The revocation is left to the periodic function
+	full := testFetchCRL(t, b, storage, "crl", ca)
+	if len(full.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("expected an empty CRL, got %d entries", len(full.TBSCertList.RevokedCertificates))
+	}
+	baseNumber := testCRLNumber(t, full, oidExtensionCRLNumber)
+	if baseNumber == 0 {
+		t.Fatal("expected the CRL to be numbered")
+	}
+
+	time.Sleep(time.Millisecond)
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := testFetchCRL(t, b, storage, "crl/delta", ca)
+	if !testCRLSerials(delta)[cert.SerialNumber.String()] || len(delta.TBSCertList.RevokedCertificates) != 1 {
+		t.Fatalf("expected the delta CRL to list the revoked certificate, got %#v", delta.TBSCertList.RevokedCertificates)
+	}
+	if testCRLNumber(t, delta, oidExtensionDeltaCRLIndicator) != baseNumber {
+		t.Fatalf("expected the delta CRL to be relative to CRL %d", baseNumber)
+	}
+	if testCRLNumber(t, delta, oidExtensionCRLNumber) <= baseNumber {
+		t.Fatal("expected the delta CRL number to follow the CRL it is relative to")
+	}
+
+	// The same delta CRL is available through the issuer
+	testFetchCRL(t, b, storage, "issuer/default/crl/delta", ca)
+	testFetchCRL(t, b, storage, "issuer/"+issuerID(ca)+"/crl/delta/pem", ca)
+
+	// Rebuilding the CRL empties the delta CRL
+	testCRLRequest(t, b, storage, logical.ReadOperation, "crl/rotate", nil)
+	full = testFetchCRL(t, b, storage, "crl", ca)
+	if !testCRLSerials(full)[cert.SerialNumber.String()] {
+		t.Fatal("expected the CRL to list the revoked certificate")
+	}
+	delta = testFetchCRL(t, b, storage, "crl/delta", ca)
+	if len(delta.TBSCertList.RevokedCertificates) != 0 {
+		t.Fatalf("expected an empty delta CRL, got %d entries", len(delta.TBSCertList.RevokedCertificates))
+	}
+	if testCRLNumber(t, delta, oidExtensionDeltaCRLIndicator) != testCRLNumber(t, full, oidExtensionCRLNumber) {
+		t.Fatal("expected the delta CRL to be relative to the rebuilt CRL")
+	}
+
+	// Nothing is pending, so the periodic function leaves the delta CRL
+	time.Sleep(time.Millisecond)
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if number := testCRLNumber(t, testFetchCRL(t, b, storage, "crl/delta", ca), oidExtensionCRLNumber); number != testCRLNumber(t, delta, oidExtensionCRLNumber) {
+		t.Fatalf("expected the delta CRL not to be rebuilt, got number %d", number)
+	}
+}
+
+func TestBackend_CRL_AutoRebuild(t *testing.T) {
+	b, storage, ca := testCRLSetup(t)
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "config/crl",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"expiry":       "1h",
+			"auto_rebuild": true,
+		},
+	})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected a grace period longer than the expiry to be rejected, got %#v", resp)
+	}
+
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"expiry":                    "1h",
+		"auto_rebuild":              true,
+		"auto_rebuild_grace_period": "30m",
+	})
+	testCRLRequest(t, b, storage, logical.ReadOperation, "crl/rotate", nil)
+	serial, cert := testIssueCert(t, b, storage, "example")
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": serial,
+	})
+
+	// The CRL is far from expiring
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if len(testFetchCRL(t, b, storage, "crl", ca).TBSCertList.RevokedCertificates) != 0 {
+		t.Fatal("expected the CRL not to be rebuilt")
+	}
+
+	// Bring the CRL within the grace period of its expiry
+	state, err := getCRLState(context.Background(), storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.NextUpdate = time.Now().Add(29 * time.Minute)
+	if err := putCRLState(context.Background(), storage, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.periodicFunc(context.Background(), &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+	if !testCRLSerials(testFetchCRL(t, b, storage, "crl", ca))[cert.SerialNumber.String()] {
+		t.Fatal("expected the CRL to be rebuilt")
+	}
+}
+
+func TestBackend_CRL_ShardByIssuer(t *testing.T) {
+	b, storage, oldCA := testCRLSetup(t)
+	oldSerial, oldCert := testIssueCert(t, b, storage, "example")
+
+	// Replace the CA with one generated elsewhere
+	other, otherStorage := createBackendWithStorage(t)
+	resp := testCRLRequest(t, other, otherStorage, logical.UpdateOperation, "root/generate/exported", map[string]interface{}{
+		"common_name": "new.myvault.com",
+		"key_type":    "ec",
+		"key_bits":    384,
+	})
+	newCA, err := testParseCert(resp.Data["certificate"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"pem_bundle": resp.Data["private_key"].(string) + "\n" + resp.Data["certificate"].(string),
+	})
+	newSerial, newCert := testIssueCert(t, b, storage, "example")
+
+	resp = testCRLRequest(t, b, storage, logical.ListOperation, "issuers/", nil)
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != 2 || keys[0] != issuerID(newCA) || keys[1] != issuerID(oldCA) {
+		t.Fatalf("unexpected issuers %v", keys)
+	}
+
+	for _, serial := range []string{oldSerial, newSerial} {
+		testCRLRequest(t, b, storage, logical.UpdateOperation, "revoke", map[string]interface{}{
+			"serial_number": serial,
+		})
+	}
+
+	// Without sharding, the current CA lists every revoked certificate
+	crl := testFetchCRL(t, b, storage, "crl", newCA)
+	if serials := testCRLSerials(crl); !serials[oldCert.SerialNumber.String()] || !serials[newCert.SerialNumber.String()] {
+		t.Fatalf("expected both certificates on the CRL, got %v", serials)
+	}
+	resp = testCRLRequest(t, b, storage, logical.ReadOperation, "issuer/"+issuerID(oldCA)+"/crl", nil)
+	if resp.Data[logical.HTTPStatusCode] != 204 {
+		t.Fatalf("expected no CRL for the replaced CA, got %#v", resp)
+	}
+
+	testCRLRequest(t, b, storage, logical.UpdateOperation, "config/crl", map[string]interface{}{
+		"shard_by_issuer": true,
+	})
+
+	crl = testFetchCRL(t, b, storage, "crl", newCA)
+	if serials := testCRLSerials(crl); len(serials) != 1 || !serials[newCert.SerialNumber.String()] {
+		t.Fatalf("expected only the certificate of the current CA on its CRL, got %v", serials)
+	}
+	testFetchCRL(t, b, storage, "issuer/default/crl", newCA)
+
+	crl = testFetchCRL(t, b, storage, "issuer/"+certutil.GetHexFormatted(oldCA.SubjectKeyId, ":")+"/crl", oldCA)
+	if serials := testCRLSerials(crl); len(serials) != 1 || !serials[oldCert.SerialNumber.String()] {
+		t.Fatalf("expected only the certificate of the replaced CA on its CRL, got %v", serials)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "issuer/abcdef/crl",
+		Storage:   storage,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("expected no response for an unknown issuer, got %#v, %v", resp, err)
+	}
+}
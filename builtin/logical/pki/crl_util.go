@@ -2,11 +2,17 @@ package pki
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
@@ -16,14 +22,48 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+const (
+	crlPrefix          = "crls/"
+	deltaCRLPrefix     = "delta-crls/"
+	deltaRevokedPrefix = "delta-revoked/"
+	crlStatePath       = "crl-state"
+)
+
+var (
+	oidExtensionAuthorityKeyID    = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber         = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+	oidExtensionReasonCode        = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// authKeyID is the value of the authority key identifier extension
+type authKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
 type revocationInfo struct {
 	CertificateBytes  []byte    `json:"certificate_bytes"`
 	RevocationTime    int64     `json:"revocation_time"`
 	RevocationTimeUTC time.Time `json:"revocation_time_utc"`
+
+	// RevocationReason is the reason code of RFC 5280 section 5.3.1, where 0
+	// is unspecified and is left out of the CRL
+	RevocationReason int `json:"revocation_reason,omitempty"`
 }
 
 // Revokes a cert, and tries to be smart about error recovery
 func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial string, fromLease bool) (*logical.Response, error) {
+	return revokeCertWithReason(ctx, b, req, serial, fromLease, 0)
+}
+
+// revokeCertWithReason revokes a cert as revokeCert does, recording the
+// reason code of the revocation in the CRL entry of the cert.
+func revokeCertWithReason(ctx context.Context, b *backend, req *logical.Request, serial string, fromLease bool, reason int) (*logical.Response, error) {
 	// As this backend is self-contained and this function does not hook into
 	// third parties to manage users or resources, if the mount is tainted,
 	// revocation doesn't matter anyways -- the CRL that would be written will
@@ -107,6 +147,7 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 		revInfo.CertificateBytes = certEntry.Value
 		revInfo.RevocationTime = currTime.Unix()
 		revInfo.RevocationTimeUTC = currTime.UTC()
+		revInfo.RevocationReason = reason
 
 		revEntry, err = logical.StorageEntryJSON("revoked/"+normalizeSerial(serial), revInfo)
 		if err != nil {
@@ -118,14 +159,29 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 			return nil, fmt.Errorf("error saving revoked certificate to new location")
 		}
 
+		// Mark it for the next delta CRL
+		err = req.Storage.Put(ctx, &logical.StorageEntry{
+			Key: deltaRevokedPrefix + normalizeSerial(serial),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error saving revoked certificate for the delta CRL")
+		}
 	}
 
-	crlErr := buildCRL(ctx, b, req, false)
-	switch crlErr.(type) {
-	case errutil.UserError:
-		return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
-	case errutil.InternalError:
-		return nil, errwrap.Wrapf("error encountered during CRL building: {{err}}", crlErr)
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return nil, errwrap.Wrapf("error fetching CRL config information: {{err}}", err)
+	}
+
+	// With auto_rebuild set the CRL is left to the periodic function
+	if crlInfo == nil || !crlInfo.AutoRebuild {
+		crlErr := buildCRL(ctx, b, req, false)
+		switch crlErr.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(fmt.Sprintf("Error during CRL building: %s", crlErr)), nil
+		case errutil.InternalError:
+			return nil, errwrap.Wrapf("error encountered during CRL building: {{err}}", crlErr)
+		}
 	}
 
 	resp := &logical.Response{
@@ -140,33 +196,40 @@ func revokeCert(ctx context.Context, b *backend, req *logical.Request, serial st
 }
 
 // Builds a CRL by going through the list of revoked certificates and building
-// a new CRL with the stored revocation times and serial numbers.
+// a new CRL with the stored revocation times and serial numbers. When sharding
+// by issuer, each issuer gets a CRL of the certificates it issued.
 func buildCRL(ctx context.Context, b *backend, req *logical.Request, forceNew bool) error {
 	crlInfo, err := b.CRL(ctx, req.Storage)
 	if err != nil {
 		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL config information: %s", err)}
 	}
+	if crlInfo == nil {
+		crlInfo = &crlConfig{}
+	}
 
 	crlLifetime := b.crlLifetime
-	var revokedCerts []pkix.RevokedCertificate
-	var revInfo revocationInfo
-	var revokedSerials []string
+	var revokedCerts []*revokedCert
+	var revokedSerials, deltaSerials []string
 
-	if crlInfo != nil {
-		if crlInfo.Expiry != "" {
-			crlDur, err := time.ParseDuration(crlInfo.Expiry)
-			if err != nil {
-				return errutil.InternalError{Err: fmt.Sprintf("error parsing CRL duration of %s", crlInfo.Expiry)}
-			}
-			crlLifetime = crlDur
+	if crlInfo.Expiry != "" {
+		crlDur, err := time.ParseDuration(crlInfo.Expiry)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("error parsing CRL duration of %s", crlInfo.Expiry)}
 		}
+		crlLifetime = crlDur
+	}
 
-		if crlInfo.Disable {
-			if !forceNew {
-				return nil
-			}
-			goto WRITE
+	if crlInfo.Disable {
+		if !forceNew {
+			return nil
 		}
+		goto WRITE
+	}
+
+	// Everything marked for the delta CRL is about to be on the full CRL
+	deltaSerials, err = req.Storage.List(ctx, deltaRevokedPrefix)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching list of revoked certs for the delta CRL: %s", err)}
 	}
 
 	revokedSerials, err = req.Storage.List(ctx, "revoked/")
@@ -175,45 +238,111 @@ func buildCRL(ctx context.Context, b *backend, req *logical.Request, forceNew bo
 	}
 
 	for _, serial := range revokedSerials {
-		revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
+		revokedCert, err := fetchRevokedCert(ctx, req, serial)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("unable to fetch revoked cert with serial %s: %s", serial, err)}
+			return err
 		}
-		if revokedEntry == nil {
+		if revokedCert == nil {
 			return errutil.InternalError{Err: fmt.Sprintf("revoked certificate entry for serial %s is nil", serial)}
 		}
-		if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
-			// TODO: In this case, remove it and continue? How likely is this to
-			// happen? Alternately, could skip it entirely, or could implement a
-			// delete function so that there is a way to remove these
-			return errutil.InternalError{Err: fmt.Sprintf("found revoked serial but actual certificate is empty")}
-		}
+		revokedCerts = append(revokedCerts, revokedCert)
+	}
 
-		err = revokedEntry.DecodeJSON(&revInfo)
+WRITE:
+	issuers, caErr := fetchIssuers(ctx, req)
+	switch caErr.(type) {
+	case errutil.UserError:
+		return errutil.UserError{Err: fmt.Sprintf("could not fetch the CA certificate: %s", caErr)}
+	case errutil.InternalError:
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL state: %s", err)}
+	}
+
+	now := time.Now()
+	state.Number++
+	err = writeCRLs(ctx, req, crlPrefix, issuers, revokedCerts, crlInfo.ShardByIssuer, state.Number, 0, now, now.Add(crlLifetime))
+	if err != nil {
+		return err
+	}
+	state.BaseNumber = state.Number
+	state.NextUpdate = now.Add(crlLifetime)
+
+	// Start the delta CRLs over from the new CRLs, so that clients never hold
+	// a delta CRL relative to an older one
+	if crlInfo.EnableDelta && !crlInfo.Disable {
+		state.Number++
+		err = writeCRLs(ctx, req, deltaCRLPrefix, issuers, nil, crlInfo.ShardByIssuer, state.Number, state.BaseNumber, now, now.Add(crlLifetime))
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("error decoding revocation entry for serial %s: %s", serial, err)}
+			return err
+		}
+		state.LastDeltaBuild = now
+	}
+
+	if err := putCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL state: %s", err)}
+	}
+
+	for _, serial := range deltaSerials {
+		if err := req.Storage.Delete(ctx, deltaRevokedPrefix+serial); err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("error clearing revoked cert %s from the delta CRL: %s", serial, err)}
 		}
+	}
+
+	return nil
+}
 
-		revokedCert, err := x509.ParseCertificate(revInfo.CertificateBytes)
+// buildDeltaCRL builds delta CRLs listing the certificates revoked since the
+// CRLs were last built.
+func buildDeltaCRL(ctx context.Context, b *backend, req *logical.Request) error {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL config information: %s", err)}
+	}
+	if crlInfo == nil || !crlInfo.EnableDelta || crlInfo.Disable {
+		return nil
+	}
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching CRL state: %s", err)}
+	}
+	if state.BaseNumber == 0 {
+		// There is no CRL for the delta to be relative to yet
+		return buildCRL(ctx, b, req, false)
+	}
+
+	crlLifetime := b.crlLifetime
+	if crlInfo.Expiry != "" {
+		crlDur, err := time.ParseDuration(crlInfo.Expiry)
 		if err != nil {
-			return errutil.InternalError{Err: fmt.Sprintf("unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+			return errutil.InternalError{Err: fmt.Sprintf("error parsing CRL duration of %s", crlInfo.Expiry)}
 		}
+		crlLifetime = crlDur
+	}
 
-		// NOTE: We have to change this to UTC time because the CRL standard
-		// mandates it but Go will happily encode the CRL without this.
-		newRevCert := pkix.RevokedCertificate{
-			SerialNumber: revokedCert.SerialNumber,
+	deltaSerials, err := req.Storage.List(ctx, deltaRevokedPrefix)
+	if err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error fetching list of revoked certs for the delta CRL: %s", err)}
+	}
+
+	var revokedCerts []*revokedCert
+	for _, serial := range deltaSerials {
+		revokedCert, err := fetchRevokedCert(ctx, req, serial)
+		if err != nil {
+			return err
 		}
-		if !revInfo.RevocationTimeUTC.IsZero() {
-			newRevCert.RevocationTime = revInfo.RevocationTimeUTC
-		} else {
-			newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
+		// The certificate may have been tidied since it was revoked
+		if revokedCert == nil {
+			continue
 		}
-		revokedCerts = append(revokedCerts, newRevCert)
+		revokedCerts = append(revokedCerts, revokedCert)
 	}
 
-WRITE:
-	signingBundle, caErr := fetchCAInfo(ctx, req)
+	issuers, caErr := fetchIssuers(ctx, req)
 	switch caErr.(type) {
 	case errutil.UserError:
 		return errutil.UserError{Err: fmt.Sprintf("could not fetch the CA certificate: %s", caErr)}
@@ -221,18 +350,303 @@ WRITE:
 		return errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
 	}
 
-	crlBytes, err := signingBundle.Certificate.CreateCRL(rand.Reader, signingBundle.PrivateKey, revokedCerts, time.Now(), time.Now().Add(crlLifetime))
+	now := time.Now()
+	state.Number++
+	err = writeCRLs(ctx, req, deltaCRLPrefix, issuers, revokedCerts, crlInfo.ShardByIssuer, state.Number, state.BaseNumber, now, now.Add(crlLifetime))
+	if err != nil {
+		return err
+	}
+	state.LastDeltaBuild = now
+
+	if err := putCRLState(ctx, req.Storage, state); err != nil {
+		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL state: %s", err)}
+	}
+	return nil
+}
+
+// revokedCert is a revoked certificate as it is listed on a CRL
+type revokedCert struct {
+	cert  *x509.Certificate
+	entry pkix.RevokedCertificate
+}
+
+// fetchRevokedCert returns the revoked certificate with the given serial, or
+// nil if it is not revoked.
+func fetchRevokedCert(ctx context.Context, req *logical.Request, serial string) (*revokedCert, error) {
+	revokedEntry, err := req.Storage.Get(ctx, "revoked/"+serial)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch revoked cert with serial %s: %s", serial, err)}
+	}
+	if revokedEntry == nil {
+		return nil, nil
+	}
+	if revokedEntry.Value == nil || len(revokedEntry.Value) == 0 {
+		// TODO: In this case, remove it and continue? How likely is this to
+		// happen? Alternately, could skip it entirely, or could implement a
+		// delete function so that there is a way to remove these
+		return nil, errutil.InternalError{Err: fmt.Sprintf("found revoked serial but actual certificate is empty")}
+	}
+
+	var revInfo revocationInfo
+	err = revokedEntry.DecodeJSON(&revInfo)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error decoding revocation entry for serial %s: %s", serial, err)}
+	}
+
+	cert, err := x509.ParseCertificate(revInfo.CertificateBytes)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("error creating new CRL: %s", err)}
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse stored revoked certificate with serial %s: %s", serial, err)}
+	}
+
+	// NOTE: We have to change this to UTC time because the CRL standard
+	// mandates it but Go will happily encode the CRL without this.
+	newRevCert := pkix.RevokedCertificate{
+		SerialNumber: cert.SerialNumber,
+	}
+	if !revInfo.RevocationTimeUTC.IsZero() {
+		newRevCert.RevocationTime = revInfo.RevocationTimeUTC
+	} else {
+		newRevCert.RevocationTime = time.Unix(revInfo.RevocationTime, 0).UTC()
+	}
+	if revInfo.RevocationReason != 0 {
+		reasonBytes, err := asn1.Marshal(asn1.Enumerated(revInfo.RevocationReason))
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to encode revocation reason of serial %s: %s", serial, err)}
+		}
+		newRevCert.Extensions = []pkix.Extension{
+			{
+				Id:    oidExtensionReasonCode,
+				Value: reasonBytes,
+			},
+		}
+	}
+
+	return &revokedCert{
+		cert:  cert,
+		entry: newRevCert,
+	}, nil
+}
+
+// writeCRLs signs and stores the CRLs of the issuers under the given prefix.
+// Without sharding, the current CA lists every revoked certificate and the
+// CAs it replaced get no CRL. The CRL of the current CA is also stored at the
+// location predating issuers.
+func writeCRLs(ctx context.Context, req *logical.Request, prefix string, issuers []*caIssuer, revokedCerts []*revokedCert, shardByIssuer bool, number, baseNumber int64, thisUpdate, nextUpdate time.Time) error {
+	shards := make(map[string][]pkix.RevokedCertificate, len(issuers))
+	for _, revoked := range revokedCerts {
+		id := issuers[0].ID
+		if shardByIssuer {
+			// Certificates of issuers no longer known to the mount stay on
+			// the CRL of the current CA
+			if issuer := issuerOf(revoked.cert, issuers); issuer != nil {
+				id = issuer.ID
+			}
+		}
+		shards[id] = append(shards[id], revoked.entry)
+	}
+
+	for _, issuer := range issuers {
+		key := prefix + issuer.ID
+		if !shardByIssuer && !issuer.Default {
+			if err := req.Storage.Delete(ctx, key); err != nil {
+				return errutil.InternalError{Err: fmt.Sprintf("error removing CRL of issuer %s: %s", issuer.ID, err)}
+			}
+			continue
+		}
+
+		crlBytes, err := createCRL(issuer.Bundle, shards[issuer.ID], number, baseNumber, thisUpdate, nextUpdate)
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("error creating new CRL: %s", err)}
+		}
+
+		err = req.Storage.Put(ctx, &logical.StorageEntry{
+			Key:   key,
+			Value: crlBytes,
+		})
+		if err != nil {
+			return errutil.InternalError{Err: fmt.Sprintf("error storing CRL: %s", err)}
+		}
+
+		if issuer.Default && prefix == crlPrefix {
+			err = req.Storage.Put(ctx, &logical.StorageEntry{
+				Key:   "crl",
+				Value: crlBytes,
+			})
+			if err != nil {
+				return errutil.InternalError{Err: fmt.Sprintf("error storing CRL: %s", err)}
+			}
+		}
 	}
 
-	err = req.Storage.Put(ctx, &logical.StorageEntry{
-		Key:   "crl",
-		Value: crlBytes,
+	return nil
+}
+
+// createCRL signs a version 2 CRL. Unlike x509.Certificate.CreateCRL, it
+// sets the CRL number and, for delta CRLs, the number of the CRL they are
+// relative to.
+func createCRL(bundle *certutil.ParsedCertBundle, revokedCerts []pkix.RevokedCertificate, number, baseNumber int64, thisUpdate, nextUpdate time.Time) ([]byte, error) {
+	var sigAlg pkix.AlgorithmIdentifier
+	var hashFunc crypto.Hash
+	switch pub := bundle.PrivateKey.Public().(type) {
+	case *rsa.PublicKey:
+		hashFunc = crypto.SHA256
+		sigAlg.Algorithm = oidSignatureSHA256WithRSA
+		sigAlg.Parameters = asn1.NullRawValue
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P384():
+			hashFunc = crypto.SHA384
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA384
+		case elliptic.P521():
+			hashFunc = crypto.SHA512
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA512
+		default:
+			hashFunc = crypto.SHA256
+			sigAlg.Algorithm = oidSignatureECDSAWithSHA256
+		}
+	default:
+		return nil, fmt.Errorf("unsupported CA key type %T", pub)
+	}
+
+	var issuer pkix.RDNSequence
+	if _, err := asn1.Unmarshal(bundle.Certificate.RawSubject, &issuer); err != nil {
+		return nil, err
+	}
+
+	extensions := make([]pkix.Extension, 0, 3)
+	if len(bundle.Certificate.SubjectKeyId) > 0 {
+		value, err := asn1.Marshal(authKeyID{ID: bundle.Certificate.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityKeyID, Value: value})
+	}
+	value, err := asn1.Marshal(big.NewInt(number))
+	if err != nil {
+		return nil, err
+	}
+	extensions = append(extensions, pkix.Extension{Id: oidExtensionCRLNumber, Value: value})
+	if baseNumber != 0 {
+		value, err := asn1.Marshal(big.NewInt(baseNumber))
+		if err != nil {
+			return nil, err
+		}
+		// Clients not supporting delta CRLs must not mistake them for full
+		// ones, so the extension is critical
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionDeltaCRLIndicator, Critical: true, Value: value})
+	}
+
+	tbsCertList := pkix.TBSCertificateList{
+		Version:             1,
+		Signature:           sigAlg,
+		Issuer:              issuer,
+		ThisUpdate:          thisUpdate.UTC(),
+		NextUpdate:          nextUpdate.UTC(),
+		RevokedCertificates: revokedCerts,
+		Extensions:          extensions,
+	}
+	tbsCertListContents, err := asn1.Marshal(tbsCertList)
+	if err != nil {
+		return nil, err
+	}
+	tbsCertList.Raw = tbsCertListContents
+
+	h := hashFunc.New()
+	h.Write(tbsCertListContents)
+	signature, err := bundle.PrivateKey.Sign(rand.Reader, h.Sum(nil), hashFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbsCertList,
+		SignatureAlgorithm: sigAlg,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: len(signature) * 8},
 	})
+}
+
+// crlState tracks the numbering and freshness of the CRLs of the mount
+type crlState struct {
+	// Number is the last CRL number used. Full and delta CRLs share the
+	// sequence.
+	Number int64 `json:"number"`
+
+	// BaseNumber is the number of the last full CRLs
+	BaseNumber int64 `json:"base_number"`
+
+	// NextUpdate is when the last full CRLs expire
+	NextUpdate time.Time `json:"next_update"`
+
+	// LastDeltaBuild is when the delta CRLs were last built
+	LastDeltaBuild time.Time `json:"last_delta_build"`
+}
+
+func getCRLState(ctx context.Context, s logical.Storage) (*crlState, error) {
+	entry, err := s.Get(ctx, crlStatePath)
 	if err != nil {
-		return errutil.InternalError{Err: fmt.Sprintf("error storing CRL: %s", err)}
+		return nil, err
 	}
 
+	var state crlState
+	if entry != nil {
+		if err := entry.DecodeJSON(&state); err != nil {
+			return nil, err
+		}
+	}
+	return &state, nil
+}
+
+func putCRLState(ctx context.Context, s logical.Storage, state *crlState) error {
+	entry, err := logical.StorageEntryJSON(crlStatePath, state)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// periodicRebuildCRL rebuilds the CRL before it expires when auto_rebuild
+// is set, and the delta CRL once certificates were revoked and
+// delta_rebuild_interval elapsed.
+func (b *backend) periodicRebuildCRL(ctx context.Context, req *logical.Request) error {
+	crlInfo, err := b.CRL(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+	if crlInfo == nil || crlInfo.Disable || (!crlInfo.AutoRebuild && !crlInfo.EnableDelta) {
+		return nil
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	state, err := getCRLState(ctx, req.Storage)
+	if err != nil {
+		return err
+	}
+
+	var crlErr error
+	now := time.Now()
+	switch {
+	case crlInfo.AutoRebuild && now.Add(crlInfo.autoRebuildGracePeriod()).After(state.NextUpdate):
+		crlErr = buildCRL(ctx, b, req, false)
+	case crlInfo.EnableDelta && now.Sub(state.LastDeltaBuild) >= crlInfo.deltaRebuildInterval():
+		pending, err := req.Storage.List(ctx, deltaRevokedPrefix)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		crlErr = buildDeltaCRL(ctx, b, req)
+	}
+
+	switch crlErr.(type) {
+	case errutil.UserError:
+		// Most likely no CA is configured yet, which is not for the periodic
+		// function to report
+		return nil
+	case errutil.InternalError:
+		return errwrap.Wrapf("error encountered during CRL building: {{err}}", crlErr)
+	}
 	return nil
 }
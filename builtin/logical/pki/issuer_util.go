@@ -0,0 +1,158 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	issuerPrefix = "issuers/"
+
+	// defaultIssuerRef refers to the CA currently configured on the mount
+	defaultIssuerRef = "default"
+)
+
+// caIssuer is a CA which issued certificates of this mount, either the
+// current one or one it replaced
+type caIssuer struct {
+	ID      string
+	Default bool
+	Bundle  *certutil.ParsedCertBundle
+}
+
+// issuerID identifies a CA by the hex encoding of its subject key ID,
+// falling back to a digest of its public key if it has none.
+func issuerID(cert *x509.Certificate) string {
+	keyID := cert.SubjectKeyId
+	if len(keyID) == 0 {
+		sum := sha1.Sum(cert.RawSubjectPublicKeyInfo)
+		keyID = sum[:]
+	}
+	return hex.EncodeToString(keyID)
+}
+
+// normalizeIssuerRef accepts issuer IDs in plain, colon- or hyphen-separated
+// hex, as key IDs are commonly displayed
+func normalizeIssuerRef(ref string) string {
+	ref = strings.ToLower(ref)
+	ref = strings.Replace(ref, ":", "", -1)
+	return strings.Replace(ref, "-", "", -1)
+}
+
+// storeIssuer records a CA bundle set on the mount, so that certificates it
+// issued can still be placed on its CRL once it has been replaced.
+func storeIssuer(ctx context.Context, s logical.Storage, cb *certutil.CertBundle, cert *x509.Certificate) error {
+	entry, err := logical.StorageEntryJSON(issuerPrefix+issuerID(cert), cb)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// deleteIssuers removes all the recorded CA bundles of the mount
+func deleteIssuers(ctx context.Context, s logical.Storage) error {
+	ids, err := s.List(ctx, issuerPrefix)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Delete(ctx, issuerPrefix+id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchIssuers returns the current CA of the mount, followed by the CAs it
+// replaced.
+func fetchIssuers(ctx context.Context, req *logical.Request) ([]*caIssuer, error) {
+	caInfo, err := fetchCAInfo(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	current := &caIssuer{
+		ID:      issuerID(caInfo.Certificate),
+		Default: true,
+		Bundle:  &caInfo.ParsedCertBundle,
+	}
+	issuers := []*caIssuer{current}
+
+	ids, err := req.Storage.List(ctx, issuerPrefix)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("unable to list issuers: %v", err)}
+	}
+	for _, id := range ids {
+		if id == current.ID {
+			continue
+		}
+
+		entry, err := req.Storage.Get(ctx, issuerPrefix+id)
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to fetch issuer %s: %v", id, err)}
+		}
+		if entry == nil {
+			continue
+		}
+
+		var bundle certutil.CertBundle
+		if err := entry.DecodeJSON(&bundle); err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to decode issuer %s: %v", id, err)}
+		}
+		parsedBundle, err := bundle.ToParsedCertBundle()
+		if err != nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("unable to parse issuer %s: %v", id, err)}
+		}
+		if parsedBundle.Certificate == nil {
+			return nil, errutil.InternalError{Err: fmt.Sprintf("stored issuer %s has no certificate", id)}
+		}
+
+		issuers = append(issuers, &caIssuer{
+			ID:     id,
+			Bundle: parsedBundle,
+		})
+	}
+
+	return issuers, nil
+}
+
+// findIssuer returns the issuer a reference points to, or nil if there is
+// no such issuer.
+func findIssuer(issuers []*caIssuer, ref string) *caIssuer {
+	if ref == defaultIssuerRef {
+		return issuers[0]
+	}
+	ref = normalizeIssuerRef(ref)
+	for _, issuer := range issuers {
+		if issuer.ID == ref {
+			return issuer
+		}
+	}
+	return nil
+}
+
+// issuerOf returns which of the issuers signed the certificate, or nil if
+// none did.
+func issuerOf(cert *x509.Certificate, issuers []*caIssuer) *caIssuer {
+	for _, issuer := range issuers {
+		ca := issuer.Bundle.Certificate
+		if len(cert.AuthorityKeyId) > 0 && len(ca.SubjectKeyId) > 0 {
+			if bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId) {
+				return issuer
+			}
+			continue
+		}
+		if cert.CheckSignatureFrom(ca) == nil {
+			return issuer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1181 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const (
+	acmeErrorPrefix = "urn:ietf:params:acme:error:"
+
+	acmeErrAccountDoesNotExist   = "accountDoesNotExist"
+	acmeErrAlreadyRevoked        = "alreadyRevoked"
+	acmeErrBadCSR                = "badCSR"
+	acmeErrBadNonce              = "badNonce"
+	acmeErrBadPublicKey          = "badPublicKey"
+	acmeErrBadRevocationReason   = "badRevocationReason"
+	acmeErrBadSignatureAlgorithm = "badSignatureAlgorithm"
+	acmeErrConnection            = "connection"
+	acmeErrDNS                   = "dns"
+	acmeErrIncorrectResponse     = "incorrectResponse"
+	acmeErrInvalidContact        = "invalidContact"
+	acmeErrMalformed             = "malformed"
+	acmeErrOrderNotReady         = "orderNotReady"
+	acmeErrRejectedIdentifier    = "rejectedIdentifier"
+	acmeErrUnauthorized          = "unauthorized"
+	acmeErrUnsupportedContact    = "unsupportedContact"
+	acmeErrUnsupportedIdentifier = "unsupportedIdentifier"
+)
+
+// acmeDirectoryPatterns are the prefixes ACME directories are served under:
+// one using the default role of the mount, and one per allowed role.
+var acmeDirectoryPatterns = []string{
+	"acme/",
+	"acme/roles/" + framework.GenericNameRegex("role") + "/",
+}
+
+// acmeError is an error reported to ACME clients as a problem document, per
+// RFC 8555 section 6.7.
+type acmeError struct {
+	status int
+	typ    string
+	detail string
+}
+
+func newACMEError(status int, typ, format string, args ...interface{}) *acmeError {
+	return &acmeError{
+		status: status,
+		typ:    typ,
+		detail: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *acmeError) Error() string {
+	return e.detail
+}
+
+func (e *acmeError) response() *logical.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"type":   acmeErrorPrefix + e.typ,
+		"detail": e.detail,
+		"status": e.status,
+	})
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  e.status,
+			logical.HTTPContentType: "application/problem+json",
+			logical.HTTPRawBody:     body,
+		},
+	}
+}
+
+// acmeContext holds what ACME operations need to know about the directory a
+// request was made to.
+type acmeContext struct {
+	config   *acmeConfig
+	roleName string
+	role     *roleEntry
+
+	// directoryURL is the absolute URL of the directory, with a trailing
+	// slash
+	directoryURL string
+}
+
+func (ac *acmeContext) url(path string) string {
+	return ac.directoryURL + path
+}
+
+type acmeOperationFunc func(context.Context, *logical.Request, *framework.FieldData, *acmeContext) (*logical.Response, error)
+
+func pathACME(b *backend) []*framework.Path {
+	var paths []*framework.Path
+	for _, prefix := range acmeDirectoryPatterns {
+		paths = append(paths,
+			b.acmePath(prefix+"directory", logical.ReadOperation, b.acmeDirectory),
+			b.acmePath(prefix+"new-nonce", logical.ReadOperation, b.acmeNewNonce),
+			b.acmePath(prefix+"new-account", logical.UpdateOperation, b.acmeNewAccount),
+			b.acmePath(prefix+"account/"+acmeIDRegex("account_id"), logical.UpdateOperation, b.acmeUpdateAccount),
+			b.acmePath(prefix+"account/"+acmeIDRegex("account_id")+"/orders", logical.UpdateOperation, b.acmeListOrders),
+			b.acmePath(prefix+"new-order", logical.UpdateOperation, b.acmeNewOrder),
+			b.acmePath(prefix+"order/"+acmeIDRegex("order_id"), logical.UpdateOperation, b.acmeGetOrder),
+			b.acmePath(prefix+"order/"+acmeIDRegex("order_id")+"/finalize", logical.UpdateOperation, b.acmeFinalizeOrder),
+			b.acmePath(prefix+"order/"+acmeIDRegex("order_id")+"/cert", logical.UpdateOperation, b.acmeGetCertificate),
+			b.acmePath(prefix+"authorization/"+acmeIDRegex("authorization_id"), logical.UpdateOperation, b.acmeGetAuthorization),
+			b.acmePath(prefix+"challenge/"+acmeIDRegex("authorization_id")+"/"+acmeIDRegex("challenge_type"), logical.UpdateOperation, b.acmeRespondChallenge),
+			b.acmePath(prefix+"revoke-cert", logical.UpdateOperation, b.acmeRevokeCert),
+			b.acmePath(prefix+"key-change", logical.UpdateOperation, b.acmeKeyChange),
+		)
+	}
+	return paths
+}
+
+func acmeIDRegex(name string) string {
+	return fmt.Sprintf(`(?P<%s>[\w-]+)`, name)
+}
+
+var acmePathFields = map[string]*framework.FieldSchema{
+	"role": &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The role constraining certificates issued through this directory.`,
+	},
+	"account_id": &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The ID of the ACME account.`,
+	},
+	"order_id": &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The ID of the ACME order.`,
+	},
+	"authorization_id": &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The ID of the ACME authorization.`,
+	},
+	"challenge_type": &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The type of the ACME challenge: http-01 or dns-01.`,
+	},
+}
+
+func (b *backend) acmePath(pattern string, operation logical.Operation, op acmeOperationFunc) *framework.Path {
+	fields := map[string]*framework.FieldSchema{}
+	for name, schema := range acmePathFields {
+		if strings.Contains(pattern, "(?P<"+name+">") {
+			fields[name] = schema
+		}
+	}
+	if operation == logical.UpdateOperation {
+		fields = acmeJWSFields(fields)
+	}
+
+	return &framework.Path{
+		Pattern: pattern,
+		Fields:  fields,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			operation: b.acmeOperation(op),
+		},
+
+		HelpSynopsis:    pathACMEHelpSyn,
+		HelpDescription: pathACMEHelpDesc,
+	}
+}
+
+// acmeOperation wraps an ACME operation, resolving the directory the request
+// was made to, turning ACME errors into problem documents and handing out a
+// fresh nonce with every response.
+func (b *backend) acmeOperation(op acmeOperationFunc) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		// Nonces are kept in memory, so all ACME requests are handled by the
+		// active node
+		if b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+			return nil, logical.ErrReadOnly
+		}
+
+		ac, err := b.acmeContextFor(ctx, req, data)
+		var resp *logical.Response
+		if err == nil {
+			resp, err = op(ctx, req, data, ac)
+		}
+		if acmeErr, ok := err.(*acmeError); ok {
+			resp, err = acmeErr.response(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		nonce, err := b.acmeNonces.new()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Headers == nil {
+			resp.Headers = map[string][]string{}
+		}
+		resp.Headers["Replay-Nonce"] = []string{nonce}
+		if ac != nil {
+			resp.Headers["Link"] = append(resp.Headers["Link"], fmt.Sprintf(`<%s>;rel="index"`, ac.url("directory")))
+		}
+		return resp, nil
+	}
+}
+
+func (b *backend) acmeContextFor(ctx context.Context, req *logical.Request, data *framework.FieldData) (*acmeContext, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil || !config.Enabled {
+		return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "ACME is not enabled on this mount")
+	}
+
+	ac := &acmeContext{
+		config:       config,
+		roleName:     config.DefaultRole,
+		directoryURL: config.BaseURL + "/acme/",
+	}
+	if roleRaw, ok := data.GetOk("role"); ok {
+		ac.roleName = roleRaw.(string)
+		if !config.roleAllowed(ac.roleName) {
+			return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "role %q is not allowed for ACME", ac.roleName)
+		}
+		ac.directoryURL = config.BaseURL + "/acme/roles/" + ac.roleName + "/"
+	} else if ac.roleName == "" {
+		return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "no default role is configured; use the directory of a role instead")
+	}
+
+	ac.role, err = b.getRole(ctx, req.Storage, ac.roleName)
+	if err != nil {
+		return nil, err
+	}
+	if ac.role == nil {
+		return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "unknown role: %s", ac.roleName)
+	}
+	return ac, nil
+}
+
+// acmeResponse returns the JSON encoding of body with the given status.
+func acmeResponse(status int, body interface{}) (*logical.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  status,
+			logical.HTTPContentType: "application/json",
+			logical.HTTPRawBody:     raw,
+		},
+	}, nil
+}
+
+func (b *backend) acmeDirectory(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	return acmeResponse(http.StatusOK, map[string]interface{}{
+		"newNonce":   ac.url("new-nonce"),
+		"newAccount": ac.url("new-account"),
+		"newOrder":   ac.url("new-order"),
+		"revokeCert": ac.url("revoke-cert"),
+		"keyChange":  ac.url("key-change"),
+		"meta": map[string]interface{}{
+			"externalAccountRequired": false,
+		},
+	})
+}
+
+func (b *backend) acmeNewNonce(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	// The nonce itself is added to every response
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:      http.StatusNoContent,
+			logical.HTTPRawCacheControl: "no-store",
+		},
+	}, nil
+}
+
+func (b *backend) acmeNewAccount(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireJWK(); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	thumbprint, err := jwkThumbprint(r.jwk)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.acmeLocks, thumbprint)
+	lock.Lock()
+	defer lock.Unlock()
+
+	account, err := getACMEAccountByThumbprint(ctx, req.Storage, thumbprint)
+	if err != nil {
+		return nil, err
+	}
+	status := http.StatusOK
+	if account == nil {
+		if payload.OnlyReturnExisting {
+			return nil, newACMEError(http.StatusBadRequest, acmeErrAccountDoesNotExist, "no account exists with the given key")
+		}
+		if err := validateACMEContacts(payload.Contact); err != nil {
+			return nil, err
+		}
+
+		id, err := newACMEID()
+		if err != nil {
+			return nil, err
+		}
+		account = &acmeAccount{
+			ID:                   id,
+			Key:                  r.jwk,
+			Thumbprint:           thumbprint,
+			Status:               acmeStatusValid,
+			Contact:              payload.Contact,
+			TermsOfServiceAgreed: payload.TermsOfServiceAgreed,
+			CreatedAt:            time.Now(),
+		}
+		if err := putACMEAccount(ctx, req.Storage, account); err != nil {
+			return nil, err
+		}
+		status = http.StatusCreated
+	}
+
+	resp, err := acmeResponse(status, ac.accountResponse(account))
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers = map[string][]string{
+		"Location": []string{ac.url("account/" + account.ID)},
+	}
+	return resp, nil
+}
+
+func validateACMEContacts(contacts []string) error {
+	for _, contact := range contacts {
+		if !strings.HasPrefix(contact, "mailto:") {
+			return newACMEError(http.StatusBadRequest, acmeErrUnsupportedContact, "contact %q is not a mailto: URL", contact)
+		}
+		address := strings.TrimPrefix(contact, "mailto:")
+		if strings.Count(address, "@") != 1 || strings.ContainsAny(address, ",?") {
+			return newACMEError(http.StatusBadRequest, acmeErrInvalidContact, "contact %q is not a single email address", contact)
+		}
+	}
+	return nil
+}
+
+func (ac *acmeContext) accountResponse(account *acmeAccount) map[string]interface{} {
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+	return map[string]interface{}{
+		"status":               account.Status,
+		"contact":              contact,
+		"termsOfServiceAgreed": account.TermsOfServiceAgreed,
+		"orders":               ac.url("account/" + account.ID + "/orders"),
+	}
+}
+
+// verifyAccountRequest verifies a request signed by an account, checking it
+// is the account the request is for.
+func (b *backend) verifyAccountRequest(ctx context.Context, ac *acmeContext, req *logical.Request, data *framework.FieldData) (*acmeRequest, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireAccount(); err != nil {
+		return nil, err
+	}
+	if r.account.ID != data.Get("account_id").(string) {
+		return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "request was not signed by this account")
+	}
+	return r, nil
+}
+
+func (b *backend) acmeUpdateAccount(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyAccountRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Contact *[]string `json:"contact"`
+		Status  string    `json:"status"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	account := r.account
+	var modified bool
+	if payload.Contact != nil {
+		if err := validateACMEContacts(*payload.Contact); err != nil {
+			return nil, err
+		}
+		account.Contact = *payload.Contact
+		modified = true
+	}
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		account.Status = acmeStatusDeactivated
+		modified = true
+	default:
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "accounts can only be updated to the deactivated status")
+	}
+
+	if modified {
+		if err := putACMEAccount(ctx, req.Storage, account); err != nil {
+			return nil, err
+		}
+	}
+
+	return acmeResponse(http.StatusOK, ac.accountResponse(account))
+}
+
+func (b *backend) acmeListOrders(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyAccountRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := req.Storage.List(ctx, acmeAccountOrdersPrefix+r.account.ID+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []string{}
+	for _, id := range ids {
+		order, err := getACMEOrder(ctx, req.Storage, id)
+		if err != nil {
+			return nil, err
+		}
+		if order == nil {
+			continue
+		}
+		if err := order.refreshStatus(ctx, req.Storage); err != nil {
+			return nil, err
+		}
+		if order.Status == acmeStatusInvalid {
+			continue
+		}
+		orders = append(orders, ac.url("order/"+order.ID))
+	}
+
+	return acmeResponse(http.StatusOK, map[string]interface{}{
+		"orders": orders,
+	})
+}
+
+func (b *backend) acmeNewOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireAccount(); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	if len(payload.Identifiers) == 0 {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "orders must have at least one identifier")
+	}
+	if payload.NotBefore != "" || payload.NotAfter != "" {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "notBefore and notAfter are not supported; the validity of certificates is set by the role")
+	}
+
+	orderID, err := newACMEID()
+	if err != nil {
+		return nil, err
+	}
+	order := &acmeOrder{
+		ID:        orderID,
+		AccountID: r.account.ID,
+		Role:      ac.roleName,
+		Status:    acmeStatusPending,
+		Expires:   time.Now().Add(acmeOrderLifetime),
+	}
+
+	seen := map[acmeIdentifier]bool{}
+	for _, identifier := range payload.Identifiers {
+		identifier, err := ac.validateIdentifier(req, identifier)
+		if err != nil {
+			return nil, err
+		}
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		order.Identifiers = append(order.Identifiers, identifier)
+
+		authz, err := newACMEAuthorization(order, identifier)
+		if err != nil {
+			return nil, err
+		}
+		if err := putACMEAuthorization(ctx, req.Storage, authz); err != nil {
+			return nil, err
+		}
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authz.ID)
+	}
+
+	if err := putACMEOrder(ctx, req.Storage, order); err != nil {
+		return nil, err
+	}
+
+	resp, err := acmeResponse(http.StatusCreated, ac.orderResponse(order))
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers = map[string][]string{
+		"Location": []string{ac.url("order/" + order.ID)},
+	}
+	return resp, nil
+}
+
+// validateIdentifier checks that certificates may be issued for the
+// identifier under the role of the directory, returning it in canonical form.
+func (ac *acmeContext) validateIdentifier(req *logical.Request, identifier acmeIdentifier) (acmeIdentifier, error) {
+	switch identifier.Type {
+	case "dns":
+		identifier.Value = strings.ToLower(identifier.Value)
+		if !hostnameRegex.MatchString(identifier.Value) {
+			return identifier, newACMEError(http.StatusBadRequest, acmeErrRejectedIdentifier, "%q is not a valid DNS name", identifier.Value)
+		}
+		input := &inputBundle{
+			req:  req,
+			role: ac.role,
+		}
+		if badName := validateNames(input, []string{identifier.Value}); badName != "" {
+			return identifier, newACMEError(http.StatusBadRequest, acmeErrRejectedIdentifier, "%s is not allowed by role %s", badName, ac.roleName)
+		}
+
+	case "ip":
+		ip := net.ParseIP(identifier.Value)
+		if ip == nil {
+			return identifier, newACMEError(http.StatusBadRequest, acmeErrRejectedIdentifier, "%q is not a valid IP address", identifier.Value)
+		}
+		if !ac.role.AllowIPSANs {
+			return identifier, newACMEError(http.StatusBadRequest, acmeErrRejectedIdentifier, "IP addresses are not allowed by role %s", ac.roleName)
+		}
+		identifier.Value = ip.String()
+
+	default:
+		return identifier, newACMEError(http.StatusBadRequest, acmeErrUnsupportedIdentifier, "unsupported identifier type %q", identifier.Type)
+	}
+	return identifier, nil
+}
+
+// newACMEAuthorization creates the authorization of an identifier of the
+// order. Wildcard names can only be validated through DNS, and IP addresses
+// only through HTTP.
+func newACMEAuthorization(order *acmeOrder, identifier acmeIdentifier) (*acmeAuthorization, error) {
+	id, err := newACMEID()
+	if err != nil {
+		return nil, err
+	}
+	authz := &acmeAuthorization{
+		ID:         id,
+		AccountID:  order.AccountID,
+		Identifier: identifier,
+		Status:     acmeStatusPending,
+		Expires:    order.Expires,
+	}
+
+	var types []string
+	switch {
+	case identifier.Type == "ip":
+		types = []string{acmeChallengeHTTP01}
+	case strings.HasPrefix(identifier.Value, "*."):
+		authz.Identifier.Value = strings.TrimPrefix(identifier.Value, "*.")
+		authz.Wildcard = true
+		types = []string{acmeChallengeDNS01}
+	default:
+		types = []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+	}
+
+	for _, typ := range types {
+		token, err := newACMEToken()
+		if err != nil {
+			return nil, err
+		}
+		authz.Challenges = append(authz.Challenges, &acmeChallenge{
+			Type:   typ,
+			Token:  token,
+			Status: acmeStatusPending,
+		})
+	}
+	return authz, nil
+}
+
+func (ac *acmeContext) orderResponse(order *acmeOrder) map[string]interface{} {
+	authorizations := make([]string, 0, len(order.AuthorizationIDs))
+	for _, id := range order.AuthorizationIDs {
+		authorizations = append(authorizations, ac.url("authorization/"+id))
+	}
+
+	resp := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.UTC().Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       ac.url("order/" + order.ID + "/finalize"),
+	}
+	if order.Status == acmeStatusValid {
+		resp["certificate"] = ac.url("order/" + order.ID + "/cert")
+	}
+	return resp
+}
+
+// verifyOrderRequest verifies a request for an order, returning the order
+// with its status up to date.
+func (b *backend) verifyOrderRequest(ctx context.Context, ac *acmeContext, req *logical.Request, data *framework.FieldData) (*acmeRequest, *acmeOrder, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.requireAccount(); err != nil {
+		return nil, nil, err
+	}
+
+	order, err := getACMEOrder(ctx, req.Storage, data.Get("order_id").(string))
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil {
+		return nil, nil, newACMEError(http.StatusNotFound, acmeErrMalformed, "order does not exist")
+	}
+	if order.AccountID != r.account.ID {
+		return nil, nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "order belongs to another account")
+	}
+	if err := order.refreshStatus(ctx, req.Storage); err != nil {
+		return nil, nil, err
+	}
+	return r, order, nil
+}
+
+func (b *backend) acmeGetOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	_, order, err := b.verifyOrderRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	return acmeResponse(http.StatusOK, ac.orderResponse(order))
+}
+
+func (b *backend) acmeFinalizeOrder(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.acmeLocks, data.Get("order_id").(string))
+	lock.Lock()
+	defer lock.Unlock()
+
+	r, order, err := b.verifyOrderRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, newACMEError(http.StatusForbidden, acmeErrOrderNotReady, "order is %s", order.Status)
+	}
+
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	der, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadCSR, "could not decode CSR: %s", err)
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadCSR, "could not parse CSR: %s", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadCSR, "invalid CSR signature: %s", err)
+	}
+	if err := checkCSRIdentifiers(csr, order.Identifiers); err != nil {
+		return nil, err
+	}
+
+	role, err := b.getRole(ctx, req.Storage, order.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "role %s of the order no longer exists", order.Role)
+	}
+
+	// The names of the CSR were checked against the order, and are still
+	// subject to the role
+	role.UseCSRCommonName = true
+	role.UseCSRSANs = true
+	role.RequireCN = false
+
+	signingBundle, err := fetchCAInfo(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching CA certificate: %w", err)
+	}
+
+	input := &inputBundle{
+		req:  req,
+		role: role,
+		apiData: &framework.FieldData{
+			Raw: map[string]interface{}{
+				"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+			},
+			Schema: addNonCACommonFields(map[string]*framework.FieldSchema{
+				"csr": &framework.FieldSchema{
+					Type: framework.TypeString,
+				},
+			}),
+		},
+	}
+	parsedBundle, err := signCert(b, input, signingBundle, false, false)
+	if err != nil {
+		if userErr, ok := err.(errutil.UserError); ok {
+			return nil, newACMEError(http.StatusBadRequest, acmeErrBadCSR, "%s", userErr)
+		}
+		return nil, fmt.Errorf("error signing certificate: %w", err)
+	}
+
+	serial := certutil.GetHexFormatted(parsedBundle.Certificate.SerialNumber.Bytes(), ":")
+	err = req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   "certs/" + normalizeSerial(serial),
+		Value: parsedBundle.CertificateBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+	}
+	if err := putACMEEntry(ctx, req.Storage, acmeCertPrefix+normalizeSerial(serial), order.AccountID); err != nil {
+		return nil, err
+	}
+
+	order.Status = acmeStatusValid
+	order.SerialNumber = serial
+	if err := putACMEOrder(ctx, req.Storage, order); err != nil {
+		return nil, err
+	}
+
+	return acmeResponse(http.StatusOK, ac.orderResponse(order))
+}
+
+// checkCSRIdentifiers checks that the CSR requests exactly the identifiers of
+// the order.
+func checkCSRIdentifiers(csr *x509.CertificateRequest, identifiers []acmeIdentifier) error {
+	if len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return newACMEError(http.StatusBadRequest, acmeErrBadCSR, "CSR may only contain DNS names and IP addresses")
+	}
+
+	requested := map[acmeIdentifier]bool{}
+	for _, name := range csr.DNSNames {
+		requested[acmeIdentifier{Type: "dns", Value: strings.ToLower(name)}] = true
+	}
+	for _, ip := range csr.IPAddresses {
+		requested[acmeIdentifier{Type: "ip", Value: ip.String()}] = true
+	}
+	if cn := csr.Subject.CommonName; cn != "" {
+		identifier := acmeIdentifier{Type: "dns", Value: strings.ToLower(cn)}
+		if ip := net.ParseIP(cn); ip != nil {
+			identifier = acmeIdentifier{Type: "ip", Value: ip.String()}
+		}
+		requested[identifier] = true
+	}
+
+	if len(requested) != len(identifiers) {
+		return newACMEError(http.StatusBadRequest, acmeErrBadCSR, "CSR does not request exactly the identifiers of the order")
+	}
+	for _, identifier := range identifiers {
+		if !requested[identifier] {
+			return newACMEError(http.StatusBadRequest, acmeErrBadCSR, "CSR does not request %s %s", identifier.Type, identifier.Value)
+		}
+	}
+	return nil
+}
+
+func (b *backend) acmeGetCertificate(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	_, order, err := b.verifyOrderRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusValid {
+		return nil, newACMEError(http.StatusForbidden, acmeErrOrderNotReady, "order is %s", order.Status)
+	}
+
+	certEntry, err := fetchCertBySerial(ctx, req, "certs/", order.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil {
+		return nil, newACMEError(http.StatusNotFound, acmeErrMalformed, "certificate of the order no longer exists")
+	}
+	signingBundle, err := fetchCAInfo(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching CA certificate: %w", err)
+	}
+
+	var chain bytes.Buffer
+	pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: certEntry.Value})
+	pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: signingBundle.CertificateBytes})
+	for _, ca := range signingBundle.CAChain {
+		if !bytes.Equal(ca.Bytes, signingBundle.CertificateBytes) {
+			pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Bytes})
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: "application/pem-certificate-chain",
+			logical.HTTPRawBody:     chain.Bytes(),
+		},
+	}, nil
+}
+
+// verifyAuthorizationRequest verifies a request for an authorization,
+// returning the authorization with its status up to date.
+func (b *backend) verifyAuthorizationRequest(ctx context.Context, ac *acmeContext, req *logical.Request, data *framework.FieldData) (*acmeRequest, *acmeAuthorization, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.requireAccount(); err != nil {
+		return nil, nil, err
+	}
+
+	authz, err := getACMEAuthorization(ctx, req.Storage, data.Get("authorization_id").(string))
+	if err != nil {
+		return nil, nil, err
+	}
+	if authz == nil {
+		return nil, nil, newACMEError(http.StatusNotFound, acmeErrMalformed, "authorization does not exist")
+	}
+	if authz.AccountID != r.account.ID {
+		return nil, nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "authorization belongs to another account")
+	}
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = acmeStatusInvalid
+		if err := putACMEAuthorization(ctx, req.Storage, authz); err != nil {
+			return nil, nil, err
+		}
+	}
+	return r, authz, nil
+}
+
+func (b *backend) acmeGetAuthorization(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.acmeLocks, data.Get("authorization_id").(string))
+	lock.Lock()
+	defer lock.Unlock()
+
+	r, authz, err := b.verifyAuthorizationRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Status string `json:"status"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		if authz.Status != acmeStatusPending && authz.Status != acmeStatusValid {
+			return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "authorization is %s", authz.Status)
+		}
+		authz.Status = acmeStatusDeactivated
+		if err := putACMEAuthorization(ctx, req.Storage, authz); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "authorizations can only be updated to the deactivated status")
+	}
+
+	return acmeResponse(http.StatusOK, ac.authorizationResponse(authz))
+}
+
+func (ac *acmeContext) authorizationResponse(authz *acmeAuthorization) map[string]interface{} {
+	challenges := make([]map[string]interface{}, 0, len(authz.Challenges))
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, ac.challengeResponse(authz, challenge))
+	}
+
+	resp := map[string]interface{}{
+		"status":     authz.Status,
+		"expires":    authz.Expires.UTC().Format(time.RFC3339),
+		"identifier": authz.Identifier,
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		resp["wildcard"] = true
+	}
+	return resp
+}
+
+func (ac *acmeContext) challengeResponse(authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	resp := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    ac.url("challenge/" + authz.ID + "/" + challenge.Type),
+		"token":  challenge.Token,
+		"status": challenge.Status,
+	}
+	if !challenge.Validated.IsZero() {
+		resp["validated"] = challenge.Validated.UTC().Format(time.RFC3339)
+	}
+	if challenge.Error != "" {
+		resp["error"] = map[string]interface{}{
+			"type":   acmeErrorPrefix + challenge.ErrorType,
+			"detail": challenge.Error,
+		}
+	}
+	return resp
+}
+
+func (b *backend) acmeRespondChallenge(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	lock := locksutil.LockForKey(b.acmeLocks, data.Get("authorization_id").(string))
+	lock.Lock()
+	defer lock.Unlock()
+
+	r, authz, err := b.verifyAuthorizationRequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var challenge *acmeChallenge
+	for _, c := range authz.Challenges {
+		if c.Type == data.Get("challenge_type").(string) {
+			challenge = c
+		}
+	}
+	if challenge == nil {
+		return nil, newACMEError(http.StatusNotFound, acmeErrMalformed, "challenge does not exist")
+	}
+
+	// Challenges are validated synchronously, so clients find them in their
+	// final state when they next poll
+	if authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending {
+		keyAuthorization := challenge.Token + "." + r.account.Thumbprint
+		err := b.acmeValidator.validate(ctx, ac.config, authz, challenge, keyAuthorization)
+		switch err := err.(type) {
+		case nil:
+			challenge.Status = acmeStatusValid
+			challenge.Validated = time.Now()
+			authz.Status = acmeStatusValid
+		case *acmeError:
+			challenge.Status = acmeStatusInvalid
+			challenge.ErrorType = err.typ
+			challenge.Error = err.detail
+			authz.Status = acmeStatusInvalid
+		default:
+			return nil, err
+		}
+		if err := putACMEAuthorization(ctx, req.Storage, authz); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := acmeResponse(http.StatusOK, ac.challengeResponse(authz, challenge))
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers = map[string][]string{
+		"Link": []string{fmt.Sprintf(`<%s>;rel="up"`, ac.url("authorization/"+authz.ID))},
+	}
+	return resp, nil
+}
+
+func (b *backend) acmeRevokeCert(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}
+	if err := r.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+	// Reason codes are those of RFC 5280 section 5.3.1, where 7 is unused
+	if payload.Reason < 0 || payload.Reason > 10 || payload.Reason == 7 {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrBadRevocationReason, "invalid revocation reason %d", payload.Reason)
+	}
+	der, err := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not decode certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not parse certificate: %s", err)
+	}
+
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	certEntry, err := fetchCertBySerial(ctx, req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil || !bytes.Equal(certEntry.Value, der) {
+		return nil, newACMEError(http.StatusNotFound, acmeErrMalformed, "certificate was not issued by this mount")
+	}
+
+	// Revocation is authorized by the account the certificate was issued to,
+	// or by the key of the certificate
+	if r.account != nil {
+		var owner string
+		if _, err := getACMEEntry(ctx, req.Storage, acmeCertPrefix+normalizeSerial(serial), &owner); err != nil {
+			return nil, err
+		}
+		if owner != r.account.ID {
+			return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "certificate was not issued to this account")
+		}
+	} else {
+		certThumbprint, err := jwkThumbprint(&jose.JSONWebKey{Key: cert.PublicKey})
+		if err != nil {
+			return nil, err
+		}
+		requestThumbprint, err := jwkThumbprint(r.jwk)
+		if err != nil {
+			return nil, err
+		}
+		if certThumbprint != requestThumbprint {
+			return nil, newACMEError(http.StatusForbidden, acmeErrUnauthorized, "request was not signed by the key of the certificate")
+		}
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	revEntry, err := fetchCertBySerial(ctx, req, "revoked/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if revEntry != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrAlreadyRevoked, "certificate is already revoked")
+	}
+
+	resp, err := revokeCertWithReason(ctx, b, req, serial, false, payload.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "%s", resp.Error())
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:  http.StatusOK,
+			logical.HTTPContentType: "application/json",
+		},
+	}, nil
+}
+
+func (b *backend) acmeKeyChange(ctx context.Context, req *logical.Request, data *framework.FieldData, ac *acmeContext) (*logical.Response, error) {
+	r, err := b.verifyACMERequest(ctx, ac, req, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.requireAccount(); err != nil {
+		return nil, err
+	}
+
+	// The payload is itself a JWS, signed by the new key
+	inner, err := parseACMEJWS(json.RawMessage(r.payload))
+	if err != nil {
+		return nil, err
+	}
+	header := inner.Signatures[0].Protected
+	switch {
+	case header.JSONWebKey == nil || header.KeyID != "":
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "inner JWS must be signed with an embedded jwk")
+	case header.Nonce != "":
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "inner JWS must not have a nonce")
+	case header.ExtraHeaders["url"] != r.url:
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "url of the inner JWS does not match the request")
+	}
+	innerPayload, err := inner.Verify(header.JSONWebKey)
+	if err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not verify inner JWS: %s", err)
+	}
+
+	var payload struct {
+		Account string           `json:"account"`
+		OldKey  *jose.JSONWebKey `json:"oldKey"`
+	}
+	if err := json.Unmarshal(innerPayload, &payload); err != nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "could not decode inner payload: %s", err)
+	}
+	account := r.account
+	if payload.Account != ac.url("account/"+account.ID) {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "account of the inner payload does not match the request")
+	}
+	if payload.OldKey == nil {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "oldKey is required")
+	}
+	oldThumbprint, err := jwkThumbprint(payload.OldKey)
+	if err != nil {
+		return nil, err
+	}
+	if oldThumbprint != account.Thumbprint {
+		return nil, newACMEError(http.StatusBadRequest, acmeErrMalformed, "oldKey is not the current key of the account")
+	}
+
+	newThumbprint, err := jwkThumbprint(header.JSONWebKey)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := locksutil.LockForKey(b.acmeLocks, newThumbprint)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existing, err := getACMEAccountByThumbprint(ctx, req.Storage, newThumbprint)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		acmeErr := newACMEError(http.StatusConflict, acmeErrMalformed, "the new key is already in use by an account")
+		resp := acmeErr.response()
+		resp.Headers = map[string][]string{
+			"Location": []string{ac.url("account/" + existing.ID)},
+		}
+		return resp, nil
+	}
+
+	if err := req.Storage.Delete(ctx, acmeAccountThumbprintPrefix+account.Thumbprint); err != nil {
+		return nil, err
+	}
+	account.Key = header.JSONWebKey
+	account.Thumbprint = newThumbprint
+	if err := putACMEAccount(ctx, req.Storage, account); err != nil {
+		return nil, err
+	}
+
+	return acmeResponse(http.StatusOK, ac.accountResponse(account))
+}
+
+const pathACMEHelpSyn = `
+ACME (RFC 8555) server endpoints.
+`
+
+const pathACMEHelpDesc = `
+These endpoints implement an ACME server, allowing ACME clients to obtain
+certificates from the CA of this mount after proving control of their
+identifiers with http-01 or dns-01 challenges. They are unauthenticated;
+requests are instead signed by the key of an ACME account.
+
+The directory at acme/directory issues certificates under the default role
+set in config/acme, while the one at acme/roles/<role>/directory issues
+certificates under that role, if allowed.
+`
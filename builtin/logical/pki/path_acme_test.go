@@ -0,0 +1,618 @@
+package pki
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const testACMEBaseURL = "https://vault.example.com/v1/pki"
+
+// testACMEClient is a minimal ACME client signing requests made directly to
+// the backend.
+type testACMEClient struct {
+	t       *testing.T
+	b       *backend
+	storage logical.Storage
+	key     crypto.Signer
+	kid     string
+	nonce   string
+}
+
+func (c *testACMEClient) Nonce() (string, error) {
+	return c.nonce, nil
+}
+
+func (c *testACMEClient) request(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	c.t.Helper()
+
+	resp, err := c.b.HandleRequest(context.Background(), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   c.storage,
+		Data:      data,
+	})
+	if err != nil {
+		c.t.Fatalf("%s: %v", path, err)
+	}
+	if nonces := resp.Headers["Replay-Nonce"]; len(nonces) == 1 {
+		c.nonce = nonces[0]
+	} else {
+		c.t.Fatalf("%s: expected a nonce, got %v", path, nonces)
+	}
+	return resp
+}
+
+func (c *testACMEClient) sign(key crypto.Signer, kid, url string, payload []byte) map[string]interface{} {
+	c.t.Helper()
+
+	opts := (&jose.SignerOptions{
+		NonceSource: c,
+		EmbedJWK:    kid == "",
+	}).WithHeader("url", url)
+	if kid != "" {
+		opts.WithHeader("kid", kid)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, opts)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(jws.FullSerialize()), &data); err != nil {
+		c.t.Fatal(err)
+	}
+	return data
+}
+
+// post makes a request signed by the account of the client, or with its key
+// if it has no account yet. A nil payload makes a POST-as-GET request.
+func (c *testACMEClient) post(path string, payload interface{}) *logical.Response {
+	c.t.Helper()
+
+	var raw []byte
+	if payload != nil {
+		var err error
+		if raw, err = json.Marshal(payload); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return c.request(logical.UpdateOperation, path, c.sign(c.key, c.kid, testACMEBaseURL+"/"+path, raw))
+}
+
+func testACMEBody(t *testing.T, resp *logical.Response, status int) map[string]interface{} {
+	t.Helper()
+
+	body, _ := resp.Data[logical.HTTPRawBody].([]byte)
+	if resp.Data[logical.HTTPStatusCode] != status {
+		t.Fatalf("expected status %d, got %v: %s", status, resp.Data[logical.HTTPStatusCode], body)
+	}
+	var ret map[string]interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &ret); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ret
+}
+
+func testACMEProblem(t *testing.T, resp *logical.Response, status int, typ string) {
+	t.Helper()
+
+	if contentType := resp.Data[logical.HTTPContentType]; contentType != "application/problem+json" {
+		t.Fatalf("expected a problem document, got %v", contentType)
+	}
+	problem := testACMEBody(t, resp, status)
+	if problem["type"] != acmeErrorPrefix+typ {
+		t.Fatalf("expected %s, got %v", typ, problem)
+	}
+}
+
+func setupTestACME(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	b, storage := createBackendWithStorage(t)
+	for _, req := range []*logical.Request{
+		{
+			Path: "root/generate/internal",
+			Data: map[string]interface{}{
+				"common_name": "myvault.com",
+			},
+		},
+		{
+			Path: "roles/example",
+			Data: map[string]interface{}{
+				"allowed_domains":  "example.com",
+				"allow_subdomains": true,
+				"key_type":         "any",
+				"ttl":              "1h",
+			},
+		},
+		{
+			Path: "roles/other",
+			Data: map[string]interface{}{
+				"allowed_domains":  "other.com",
+				"allow_subdomains": true,
+				"key_type":         "any",
+			},
+		},
+		{
+			Path: "config/acme",
+			Data: map[string]interface{}{
+				"enabled":       true,
+				"base_url":      testACMEBaseURL + "/",
+				"default_role":  "example",
+				"allowed_roles": "other",
+			},
+		},
+	} {
+		req.Operation = logical.UpdateOperation
+		req.Storage = storage
+		resp, err := b.HandleRequest(context.Background(), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err: %v resp: %#v", req.Path, err, resp)
+		}
+	}
+	return b, storage
+}
+
+func newTestACMEClient(t *testing.T, b *backend, storage logical.Storage) *testACMEClient {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testACMEClient{
+		t:       t,
+		b:       b,
+		storage: storage,
+		key:     key,
+	}
+	c.request(logical.ReadOperation, "acme/new-nonce", nil)
+	return c
+}
+
+func TestPki_ACMEConfig(t *testing.T) {
+	b, storage := createBackendWithStorage(t)
+
+	for name, data := range map[string]map[string]interface{}{
+		"missing base_url": {"enabled": true},
+		"bad base_url":     {"base_url": "vault.example.com"},
+		"unknown role":     {"base_url": testACMEBaseURL, "default_role": "missing"},
+		"bad dns_resolver": {"base_url": testACMEBaseURL, "dns_resolver": "8.8.8.8"},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "config/acme",
+			Storage:   storage,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error, got err: %v resp: %#v", name, err, resp)
+		}
+	}
+
+	// Requests are rejected while ACME is disabled
+	c := &testACMEClient{t: t, b: b, storage: storage}
+	testACMEProblem(t, c.request(logical.ReadOperation, "acme/directory", nil), http.StatusForbidden, acmeErrUnauthorized)
+}
+
+func TestPki_ACMEDirectory(t *testing.T) {
+	b, storage := setupTestACME(t)
+	c := newTestACMEClient(t, b, storage)
+
+	resp := c.request(logical.ReadOperation, "acme/directory", nil)
+	directory := testACMEBody(t, resp, http.StatusOK)
+	if directory["newOrder"] != testACMEBaseURL+"/acme/new-order" {
+		t.Fatalf("bad: %#v", directory)
+	}
+	if link := resp.Headers["Link"]; len(link) != 1 || link[0] != `<`+testACMEBaseURL+`/acme/directory>;rel="index"` {
+		t.Fatalf("bad link: %v", link)
+	}
+
+	directory = testACMEBody(t, c.request(logical.ReadOperation, "acme/roles/other/directory", nil), http.StatusOK)
+	if directory["newAccount"] != testACMEBaseURL+"/acme/roles/other/new-account" {
+		t.Fatalf("bad: %#v", directory)
+	}
+
+	// Only allowed roles have a directory
+	testACMEProblem(t, c.request(logical.ReadOperation, "acme/roles/example/directory", nil), http.StatusForbidden, acmeErrUnauthorized)
+}
+
+func TestPki_ACMERequestVerification(t *testing.T) {
+	b, storage := setupTestACME(t)
+	c := newTestACMEClient(t, b, storage)
+
+	// Nonces can only be used once
+	nonce := c.nonce
+	testACMEBody(t, c.post("acme/new-account", map[string]interface{}{}), http.StatusCreated)
+	c.nonce = nonce
+	testACMEProblem(t, c.post("acme/new-account", map[string]interface{}{}), http.StatusBadRequest, acmeErrBadNonce)
+
+	// The signed URL must be the one the request is made to
+	data := c.sign(c.key, "", testACMEBaseURL+"/acme/new-order", []byte("{}"))
+	testACMEProblem(t, c.request(logical.UpdateOperation, "acme/new-account", data), http.StatusUnauthorized, acmeErrUnauthorized)
+
+	// Accounts of other directories can't be used
+	c.kid = testACMEBaseURL + "/acme/roles/other/account/foo"
+	testACMEProblem(t, c.post("acme/new-order", map[string]interface{}{}), http.StatusBadRequest, acmeErrMalformed)
+	c.kid = testACMEBaseURL + "/acme/account/foo"
+	testACMEProblem(t, c.post("acme/new-order", map[string]interface{}{}), http.StatusBadRequest, acmeErrAccountDoesNotExist)
+
+	// Tampered requests don't verify
+	c.kid = ""
+	data = c.sign(c.key, "", testACMEBaseURL+"/acme/new-account", []byte("{}"))
+	data["payload"] = base64.RawURLEncoding.EncodeToString([]byte(`{"onlyReturnExisting":true}`))
+	testACMEProblem(t, c.request(logical.UpdateOperation, "acme/new-account", data), http.StatusBadRequest, acmeErrMalformed)
+}
+
+func TestPki_ACMEAccounts(t *testing.T) {
+	b, storage := setupTestACME(t)
+	c := newTestACMEClient(t, b, storage)
+
+	testACMEProblem(t, c.post("acme/new-account", map[string]interface{}{
+		"onlyReturnExisting": true,
+	}), http.StatusBadRequest, acmeErrAccountDoesNotExist)
+	testACMEProblem(t, c.post("acme/new-account", map[string]interface{}{
+		"contact": []string{"tel:+15555555555"},
+	}), http.StatusBadRequest, acmeErrUnsupportedContact)
+
+	resp := c.post("acme/new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	account := testACMEBody(t, resp, http.StatusCreated)
+	if account["status"] != acmeStatusValid {
+		t.Fatalf("bad: %#v", account)
+	}
+	c.kid = resp.Headers["Location"][0]
+	if !strings.HasPrefix(c.kid, testACMEBaseURL+"/acme/account/") {
+		t.Fatalf("bad location: %s", c.kid)
+	}
+	accountPath := strings.TrimPrefix(c.kid, testACMEBaseURL+"/")
+
+	// Registering the same key again returns the existing account
+	existing := &testACMEClient{t: t, b: b, storage: storage, key: c.key, nonce: c.nonce}
+	resp = existing.post("acme/new-account", map[string]interface{}{})
+	testACMEBody(t, resp, http.StatusOK)
+	if resp.Headers["Location"][0] != c.kid {
+		t.Fatalf("expected %s, got %s", c.kid, resp.Headers["Location"][0])
+	}
+	c.nonce = existing.nonce
+
+	account = testACMEBody(t, c.post(accountPath, map[string]interface{}{
+		"contact": []string{"mailto:security@example.com"},
+	}), http.StatusOK)
+	if contact := account["contact"].([]interface{}); len(contact) != 1 || contact[0] != "mailto:security@example.com" {
+		t.Fatalf("bad: %#v", account)
+	}
+
+	// Roll over the account key
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: newKey}, (&jose.SignerOptions{
+		EmbedJWK: true,
+	}).WithHeader("url", testACMEBaseURL+"/acme/key-change"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := signer.Sign(mustJSON(t, map[string]interface{}{
+		"account": c.kid,
+		"oldKey":  jose.JSONWebKey{Key: c.key.Public()},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testACMEBody(t, c.post("acme/key-change", json.RawMessage(inner.FullSerialize())), http.StatusOK)
+
+	// Only the new key is accepted from then on
+	testACMEProblem(t, c.post(accountPath, nil), http.StatusBadRequest, acmeErrMalformed)
+	c.key = newKey
+	testACMEBody(t, c.post(accountPath, nil), http.StatusOK)
+
+	account = testACMEBody(t, c.post(accountPath, map[string]interface{}{
+		"status": acmeStatusDeactivated,
+	}), http.StatusOK)
+	if account["status"] != acmeStatusDeactivated {
+		t.Fatalf("bad: %#v", account)
+	}
+	testACMEProblem(t, c.post(accountPath, nil), http.StatusUnauthorized, acmeErrUnauthorized)
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// testHTTP01Server serves the key authorizations of http-01 challenges.
+type testHTTP01Server struct {
+	sync.Mutex
+	*httptest.Server
+	keyAuthorizations map[string]string
+}
+
+func newTestHTTP01Server() *testHTTP01Server {
+	s := &testHTTP01Server{
+		keyAuthorizations: map[string]string{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		defer s.Unlock()
+
+		keyAuthorization, ok := s.keyAuthorizations[strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(keyAuthorization + "\n"))
+	}))
+	return s
+}
+
+func (s *testHTTP01Server) port(t *testing.T) int {
+	_, port, err := net.SplitHostPort(s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPki_ACMEOrder(t *testing.T) {
+	b, storage := setupTestACME(t)
+
+	httpServer := newTestHTTP01Server()
+	defer httpServer.Close()
+	b.acmeValidator.httpPort = httpServer.port(t)
+
+	txtRecords := map[string][]string{}
+	b.acmeValidator.lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		return txtRecords[name], nil
+	}
+
+	c := newTestACMEClient(t, b, storage)
+	resp := c.post("acme/new-account", map[string]interface{}{})
+	testACMEBody(t, resp, http.StatusCreated)
+	c.kid = resp.Headers["Location"][0]
+
+	thumbprint, err := jwkThumbprint(&jose.JSONWebKey{Key: c.key.Public()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Identifiers must be allowed by the role
+	testACMEProblem(t, c.post("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{{Type: "dns", Value: "www.other.com"}},
+	}), http.StatusBadRequest, acmeErrRejectedIdentifier)
+	testACMEProblem(t, c.post("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{{Type: "email", Value: "admin@example.com"}},
+	}), http.StatusBadRequest, acmeErrUnsupportedIdentifier)
+
+	resp = c.post("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{
+			{Type: "dns", Value: "www.example.com"},
+			{Type: "dns", Value: "*.example.com"},
+			{Type: "ip", Value: "127.0.0.1"},
+		},
+	})
+	order := testACMEBody(t, resp, http.StatusCreated)
+	orderPath := strings.TrimPrefix(resp.Headers["Location"][0], testACMEBaseURL+"/")
+	if order["status"] != acmeStatusPending {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// Finalizing requires the authorizations to be completed
+	testACMEProblem(t, c.post(orderPath+"/finalize", map[string]interface{}{}), http.StatusForbidden, acmeErrOrderNotReady)
+
+	for _, authzURL := range order["authorizations"].([]interface{}) {
+		authz := testACMEBody(t, c.post(strings.TrimPrefix(authzURL.(string), testACMEBaseURL+"/"), nil), http.StatusOK)
+		identifier := authz["identifier"].(map[string]interface{})
+
+		var challengeTypes []string
+		for _, raw := range authz["challenges"].([]interface{}) {
+			challengeTypes = append(challengeTypes, raw.(map[string]interface{})["type"].(string))
+		}
+		challengeType := acmeChallengeDNS01
+		switch {
+		case identifier["type"] == "ip":
+			challengeType = acmeChallengeHTTP01
+			if strings.Join(challengeTypes, ",") != acmeChallengeHTTP01 {
+				t.Fatalf("bad challenges for IP identifier: %v", challengeTypes)
+			}
+		case authz["wildcard"] == true:
+			if strings.Join(challengeTypes, ",") != acmeChallengeDNS01 {
+				t.Fatalf("bad challenges for wildcard identifier: %v", challengeTypes)
+			}
+		}
+
+		var challenge map[string]interface{}
+		for _, raw := range authz["challenges"].([]interface{}) {
+			if raw.(map[string]interface{})["type"] == challengeType {
+				challenge = raw.(map[string]interface{})
+			}
+		}
+		keyAuthorization := challenge["token"].(string) + "." + thumbprint
+		if challengeType == acmeChallengeHTTP01 {
+			httpServer.Lock()
+			httpServer.keyAuthorizations[challenge["token"].(string)] = keyAuthorization
+			httpServer.Unlock()
+		} else {
+			digest := sha256.Sum256([]byte(keyAuthorization))
+			name := "_acme-challenge." + identifier["value"].(string)
+			txtRecords[name] = append(txtRecords[name], base64.RawURLEncoding.EncodeToString(digest[:]))
+		}
+
+		challengeResp := c.post(strings.TrimPrefix(challenge["url"].(string), testACMEBaseURL+"/"), map[string]interface{}{})
+		challenge = testACMEBody(t, challengeResp, http.StatusOK)
+		if challenge["status"] != acmeStatusValid {
+			t.Fatalf("bad: %#v", challenge)
+		}
+	}
+
+	order = testACMEBody(t, c.post(orderPath, nil), http.StatusOK)
+	if order["status"] != acmeStatusReady {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// The CSR must request exactly the identifiers of the order
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testACMEProblem(t, c.post(orderPath+"/finalize", map[string]interface{}{
+		"csr": testACMECSR(t, certKey, "www.example.com"),
+	}), http.StatusBadRequest, acmeErrBadCSR)
+
+	order = testACMEBody(t, c.post(orderPath+"/finalize", map[string]interface{}{
+		"csr": testACMECSR(t, certKey, "www.example.com", "*.example.com", "127.0.0.1"),
+	}), http.StatusOK)
+	if order["status"] != acmeStatusValid {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	resp = c.post(strings.TrimPrefix(order["certificate"].(string), testACMEBaseURL+"/"), nil)
+	if resp.Data[logical.HTTPContentType] != "application/pem-certificate-chain" {
+		t.Fatalf("bad content type: %v", resp.Data[logical.HTTPContentType])
+	}
+	block, rest := pem.Decode(resp.Data[logical.HTTPRawBody].([]byte))
+	if block == nil {
+		t.Fatal("expected a certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(cert.DNSNames, ",") != "*.example.com,www.example.com" || len(cert.IPAddresses) != 1 {
+		t.Fatalf("bad names: %v %v", cert.DNSNames, cert.IPAddresses)
+	}
+	if issuer, _ := pem.Decode(rest); issuer == nil {
+		t.Fatal("expected the issuing CA in the chain")
+	}
+
+	// The certificate can only be revoked by its account or key
+	other := newTestACMEClient(t, b, storage)
+	resp = other.post("acme/new-account", map[string]interface{}{})
+	other.kid = resp.Headers["Location"][0]
+	revocation := map[string]interface{}{
+		"certificate": base64.RawURLEncoding.EncodeToString(cert.Raw),
+		"reason":      4,
+	}
+	testACMEProblem(t, other.post("acme/revoke-cert", revocation), http.StatusForbidden, acmeErrUnauthorized)
+
+	testACMEBody(t, c.post("acme/revoke-cert", revocation), http.StatusOK)
+	testACMEProblem(t, c.post("acme/revoke-cert", revocation), http.StatusBadRequest, acmeErrAlreadyRevoked)
+
+	serial := certutil.GetHexFormatted(cert.SerialNumber.Bytes(), ":")
+	if revoked, err := fetchCertBySerial(context.Background(), &logical.Request{Storage: storage}, "revoked/", serial); err != nil || revoked == nil {
+		t.Fatalf("expected certificate to be revoked, err: %v", err)
+	}
+
+	// The reason is recorded in the CRL entry
+	revoked, err := fetchRevokedCert(context.Background(), &logical.Request{Storage: storage}, normalizeSerial(serial))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reason asn1.Enumerated
+	if len(revoked.entry.Extensions) != 1 || !revoked.entry.Extensions[0].Id.Equal(oidExtensionReasonCode) {
+		t.Fatalf("expected a reason code extension, got %#v", revoked.entry.Extensions)
+	}
+	if _, err := asn1.Unmarshal(revoked.entry.Extensions[0].Value, &reason); err != nil || reason != 4 {
+		t.Fatalf("expected reason 4, got %d, err: %v", reason, err)
+	}
+}
+
+func testACMECSR(t *testing.T, key crypto.Signer, names ...string) string {
+	t.Helper()
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: names[0]},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(der)
+}
+
+func TestPki_ACMEChallengeFailure(t *testing.T) {
+	b, storage := setupTestACME(t)
+	b.acmeValidator.lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		return []string{"wrong"}, nil
+	}
+
+	c := newTestACMEClient(t, b, storage)
+	resp := c.post("acme/new-account", map[string]interface{}{})
+	c.kid = resp.Headers["Location"][0]
+
+	resp = c.post("acme/new-order", map[string]interface{}{
+		"identifiers": []acmeIdentifier{{Type: "dns", Value: "*.example.com"}},
+	})
+	order := testACMEBody(t, resp, http.StatusCreated)
+	orderPath := strings.TrimPrefix(resp.Headers["Location"][0], testACMEBaseURL+"/")
+
+	authzPath := strings.TrimPrefix(order["authorizations"].([]interface{})[0].(string), testACMEBaseURL+"/")
+	authz := testACMEBody(t, c.post(authzPath, nil), http.StatusOK)
+	challenge := authz["challenges"].([]interface{})[0].(map[string]interface{})
+
+	challenge = testACMEBody(t, c.post(strings.TrimPrefix(challenge["url"].(string), testACMEBaseURL+"/"), map[string]interface{}{}), http.StatusOK)
+	if challenge["status"] != acmeStatusInvalid {
+		t.Fatalf("bad: %#v", challenge)
+	}
+	if problem := challenge["error"].(map[string]interface{}); problem["type"] != acmeErrorPrefix+acmeErrIncorrectResponse {
+		t.Fatalf("bad: %#v", problem)
+	}
+
+	// A failed challenge invalidates the authorization and the order
+	order = testACMEBody(t, c.post(orderPath, nil), http.StatusOK)
+	if order["status"] != acmeStatusInvalid {
+		t.Fatalf("bad: %#v", order)
+	}
+
+	// Orders belong to the account that created them
+	other := newTestACMEClient(t, b, storage)
+	resp = other.post("acme/new-account", map[string]interface{}{})
+	other.kid = resp.Headers["Location"][0]
+	testACMEProblem(t, other.post(orderPath, nil), http.StatusForbidden, acmeErrUnauthorized)
+}
@@ -0,0 +1,186 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// acmeConfig holds the configuration of the ACME server
+type acmeConfig struct {
+	Enabled      bool     `json:"enabled"`
+	BaseURL      string   `json:"base_url"`
+	DefaultRole  string   `json:"default_role"`
+	AllowedRoles []string `json:"allowed_roles"`
+	DNSResolver  string   `json:"dns_resolver"`
+}
+
+func pathConfigACME(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `If set to true, enables the ACME server of this mount.`,
+			},
+			"base_url": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The URL this mount is reached at by ACME clients,
+e.g. https://vault.example.com/v1/pki. Used to build
+the URLs returned to clients and to check the URL
+protected by their requests.`,
+			},
+			"default_role": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The role constraining certificates issued through
+the acme/ directory. If unset, only the per-role
+directories can be used.`,
+			},
+			"allowed_roles": &framework.FieldSchema{
+				Type: framework.TypeCommaStringSlice,
+				Description: `Comma-separated list of roles which have a
+directory at acme/roles/<role>/. May be "*" to allow
+all roles.`,
+			},
+			"dns_resolver": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `The host:port of the DNS server used to validate
+dns-01 challenges. Defaults to the system resolver.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathACMEConfigRead,
+			logical.UpdateOperation: b.pathACMEConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigACMEHelpSyn,
+		HelpDescription: pathConfigACMEHelpDesc,
+	}
+}
+
+func getACMEConfig(ctx context.Context, s logical.Storage) (*acmeConfig, error) {
+	entry, err := s.Get(ctx, "config/acme")
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result acmeConfig
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// roleAllowed returns whether the role may be used through its own ACME
+// directory.
+func (c *acmeConfig) roleAllowed(role string) bool {
+	return strutil.StrListContains(c.AllowedRoles, "*") || strutil.StrListContains(c.AllowedRoles, role)
+}
+
+func (b *backend) pathACMEConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":       config.Enabled,
+			"base_url":      config.BaseURL,
+			"default_role":  config.DefaultRole,
+			"allowed_roles": config.AllowedRoles,
+			"dns_resolver":  config.DNSResolver,
+		},
+	}, nil
+}
+
+func (b *backend) pathACMEConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getACMEConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &acmeConfig{}
+	}
+
+	if enabledRaw, ok := data.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+	if baseURLRaw, ok := data.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+	}
+	if defaultRoleRaw, ok := data.GetOk("default_role"); ok {
+		config.DefaultRole = defaultRoleRaw.(string)
+	}
+	if allowedRolesRaw, ok := data.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = allowedRolesRaw.([]string)
+	}
+	if dnsResolverRaw, ok := data.GetOk("dns_resolver"); ok {
+		config.DNSResolver = dnsResolverRaw.(string)
+	}
+
+	if config.BaseURL != "" {
+		u, err := url.Parse(config.BaseURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return logical.ErrorResponse(fmt.Sprintf("invalid base_url %q", config.BaseURL)), nil
+		}
+	}
+	if config.Enabled && config.BaseURL == "" {
+		return logical.ErrorResponse("base_url is required to enable ACME"), nil
+	}
+
+	if config.DefaultRole != "" {
+		role, err := b.getRole(ctx, req.Storage, config.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", config.DefaultRole)), nil
+		}
+	}
+
+	if config.DNSResolver != "" {
+		if _, _, err := net.SplitHostPort(config.DNSResolver); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid dns_resolver %q: %s", config.DNSResolver, err)), nil
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config/acme", config)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathConfigACMEHelpSyn = `
+Configure the ACME server of this mount.
+`
+
+const pathConfigACMEHelpDesc = `
+This endpoint allows enabling the ACME (RFC 8555) server of this mount and
+setting the roles certificates may be issued against. Certificates ordered
+through the acme/ directory are constrained by the default role, while those
+ordered through acme/roles/<role>/ are constrained by that role.
+
+ACME clients rely on the Replay-Nonce, Location and Link response headers,
+which must be allowed on the mount with the allowed_response_headers tune
+parameter.
+`
@@ -76,6 +76,10 @@ func (b *backend) pathCAWrite(ctx context.Context, req *logical.Request, data *f
 	if err != nil {
 		return nil, err
 	}
+	err = storeIssuer(ctx, req.Storage, cb, parsedBundle.Certificate)
+	if err != nil {
+		return nil, err
+	}
 
 	// For ease of later use, also store just the certificate at a known
 	// location, plus a fresh CRL
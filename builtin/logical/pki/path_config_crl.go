@@ -13,8 +13,44 @@ import (
 
 // CRLConfig holds basic CRL configuration information
 type crlConfig struct {
-	Expiry  string `json:"expiry" mapstructure:"expiry"`
-	Disable bool   `json:"disable"`
+	Expiry                 string `json:"expiry" mapstructure:"expiry"`
+	Disable                bool   `json:"disable"`
+	ShardByIssuer          bool   `json:"shard_by_issuer"`
+	AutoRebuild            bool   `json:"auto_rebuild"`
+	AutoRebuildGracePeriod string `json:"auto_rebuild_grace_period"`
+	EnableDelta            bool   `json:"enable_delta"`
+	DeltaRebuildInterval   string `json:"delta_rebuild_interval"`
+}
+
+const (
+	defaultAutoRebuildGracePeriod = 12 * time.Hour
+	defaultDeltaRebuildInterval   = 15 * time.Minute
+)
+
+// autoRebuildGracePeriod returns how long before the CRL expires it is
+// rebuilt when auto_rebuild is set
+func (c *crlConfig) autoRebuildGracePeriod() time.Duration {
+	if c.AutoRebuildGracePeriod == "" {
+		return defaultAutoRebuildGracePeriod
+	}
+	d, err := time.ParseDuration(c.AutoRebuildGracePeriod)
+	if err != nil {
+		return defaultAutoRebuildGracePeriod
+	}
+	return d
+}
+
+// deltaRebuildInterval returns how often the delta CRL is rebuilt when
+// certificates were revoked since it was last built
+func (c *crlConfig) deltaRebuildInterval() time.Duration {
+	if c.DeltaRebuildInterval == "" {
+		return defaultDeltaRebuildInterval
+	}
+	d, err := time.ParseDuration(c.DeltaRebuildInterval)
+	if err != nil {
+		return defaultDeltaRebuildInterval
+	}
+	return d
 }
 
 func pathConfigCRL(b *backend) *framework.Path {
@@ -31,6 +67,37 @@ valid; defaults to 72 hours`,
 				Type:        framework.TypeBool,
 				Description: `If set to true, disables generating the CRL entirely.`,
 			},
+			"shard_by_issuer": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set to true, each CA that issued certificates
+of this mount signs a CRL listing only the certificates
+it issued, available at issuer/<ref>/crl. Otherwise the
+current CA signs a single CRL listing all of them.`,
+			},
+			"auto_rebuild": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set to true, revoking a certificate no longer
+rebuilds the CRL; it is instead rebuilt periodically
+before it expires.`,
+			},
+			"auto_rebuild_grace_period": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How long before the CRL expires it is rebuilt
+when auto_rebuild is set; defaults to 12 hours`,
+				Default: "12h",
+			},
+			"enable_delta": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `If set to true, delta CRLs listing the certificates
+revoked since the CRL was last built are generated and
+available at crl/delta.`,
+			},
+			"delta_rebuild_interval": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `How often the delta CRL is rebuilt when
+certificates were revoked; defaults to 15 minutes`,
+				Default: "15m",
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -71,8 +138,13 @@ func (b *backend) pathCRLRead(ctx context.Context, req *logical.Request, data *f
 
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"expiry":  config.Expiry,
-			"disable": config.Disable,
+			"expiry":                    config.Expiry,
+			"disable":                   config.Disable,
+			"shard_by_issuer":           config.ShardByIssuer,
+			"auto_rebuild":              config.AutoRebuild,
+			"auto_rebuild_grace_period": config.autoRebuildGracePeriod().String(),
+			"enable_delta":              config.EnableDelta,
+			"delta_rebuild_interval":    config.deltaRebuildInterval().String(),
 		},
 	}, nil
 }
@@ -95,11 +167,48 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 		config.Expiry = expiry
 	}
 
-	var oldDisable bool
+	oldConfig := *config
 	if disableRaw, ok := d.GetOk("disable"); ok {
-		oldDisable = config.Disable
 		config.Disable = disableRaw.(bool)
 	}
+	if shardByIssuerRaw, ok := d.GetOk("shard_by_issuer"); ok {
+		config.ShardByIssuer = shardByIssuerRaw.(bool)
+	}
+	if autoRebuildRaw, ok := d.GetOk("auto_rebuild"); ok {
+		config.AutoRebuild = autoRebuildRaw.(bool)
+	}
+	if enableDeltaRaw, ok := d.GetOk("enable_delta"); ok {
+		config.EnableDelta = enableDeltaRaw.(bool)
+	}
+
+	if gracePeriodRaw, ok := d.GetOk("auto_rebuild_grace_period"); ok {
+		gracePeriod := gracePeriodRaw.(string)
+		if _, err := time.ParseDuration(gracePeriod); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("given auto_rebuild_grace_period could not be decoded: %s", err)), nil
+		}
+		config.AutoRebuildGracePeriod = gracePeriod
+	}
+	if intervalRaw, ok := d.GetOk("delta_rebuild_interval"); ok {
+		interval := intervalRaw.(string)
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("given delta_rebuild_interval could not be decoded: %s", err)), nil
+		}
+		if parsed <= 0 {
+			return logical.ErrorResponse("delta_rebuild_interval must be positive"), nil
+		}
+		config.DeltaRebuildInterval = interval
+	}
+
+	if config.AutoRebuild {
+		expiry := b.crlLifetime
+		if config.Expiry != "" {
+			expiry, _ = time.ParseDuration(config.Expiry)
+		}
+		if config.autoRebuildGracePeriod() >= expiry {
+			return logical.ErrorResponse("auto_rebuild_grace_period must be shorter than the CRL expiry"), nil
+		}
+	}
 
 	entry, err := logical.StorageEntryJSON("config/crl", config)
 	if err != nil {
@@ -110,8 +219,12 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 		return nil, err
 	}
 
-	if oldDisable != config.Disable {
-		// It wasn't disabled but now it is, rotate
+	// Rotate if the CRL was enabled or disabled, or if the CRLs it is made of
+	// changed
+	if oldConfig.Disable != config.Disable || oldConfig.ShardByIssuer != config.ShardByIssuer || oldConfig.EnableDelta != config.EnableDelta {
+		b.revokeStorageLock.Lock()
+		defer b.revokeStorageLock.Unlock()
+
 		crlErr := buildCRL(ctx, b, req, true)
 		switch crlErr.(type) {
 		case errutil.UserError:
@@ -125,9 +238,15 @@ func (b *backend) pathCRLWrite(ctx context.Context, req *logical.Request, d *fra
 }
 
 const pathConfigCRLHelpSyn = `
-Configure the CRL expiration.
+Configure the CRL expiration, sharding and rebuilding.
 `
 
 const pathConfigCRLHelpDesc = `
-This endpoint allows configuration of the CRL lifetime.
+This endpoint allows configuration of the CRL lifetime, whether each CA
+signs a CRL of its own, and how the CRL is rebuilt.
+
+On mounts revoking many certificates, auto_rebuild avoids rebuilding the
+whole CRL on every revocation; enabling delta CRLs then lets clients learn
+of revocations made since the CRL was last built without downloading it
+again.
 `
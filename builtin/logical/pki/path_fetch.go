@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -89,6 +90,55 @@ func pathFetchCRLViaCertPath(b *backend) *framework.Path {
 	}
 }
 
+// Returns the delta CRL of the current CA in raw format
+func pathFetchDeltaCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `crl/delta(/pem)?`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerCRLRead,
+		},
+
+		HelpSynopsis:    pathFetchIssuerCRLHelpSyn,
+		HelpDescription: pathFetchIssuerCRLHelpDesc,
+	}
+}
+
+// Returns the CRL or delta CRL signed by a given issuer in raw format
+func pathFetchIssuerCRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `issuer/(?P<issuer_ref>[0-9A-Za-z-:]+)/crl(/delta)?(/pem)?`,
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": &framework.FieldSchema{
+				Type: framework.TypeString,
+				Description: `Issuer ID, in hex, or "default" for the
+current CA`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerCRLRead,
+		},
+
+		HelpSynopsis:    pathFetchIssuerCRLHelpSyn,
+		HelpDescription: pathFetchIssuerCRLHelpDesc,
+	}
+}
+
+// This returns the list of issuer IDs
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathListIssuersList,
+		},
+
+		HelpSynopsis:    pathListIssuersHelpSyn,
+		HelpDescription: pathListIssuersHelpDesc,
+	}
+}
+
 // This returns the list of serial numbers for certs
 func pathFetchListCerts(b *backend) *framework.Path {
 	return &framework.Path{
@@ -112,6 +162,90 @@ func (b *backend) pathFetchCertList(ctx context.Context, req *logical.Request, d
 	return logical.ListResponse(entries), nil
 }
 
+func (b *backend) pathListIssuersList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuers, err := fetchIssuers(ctx, req)
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	case errutil.InternalError:
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(issuers))
+	keyInfo := make(map[string]interface{}, len(issuers))
+	for _, issuer := range issuers {
+		keys = append(keys, issuer.ID)
+		keyInfo[issuer.ID] = map[string]interface{}{
+			"common_name":   issuer.Bundle.Certificate.Subject.CommonName,
+			"serial_number": certutil.GetHexFormatted(issuer.Bundle.Certificate.SerialNumber.Bytes(), ":"),
+			"default":       issuer.Default,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func (b *backend) pathFetchIssuerCRLRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := defaultIssuerRef
+	if refRaw, ok := data.GetOk("issuer_ref"); ok {
+		ref = refRaw.(string)
+	}
+	delta := strings.HasSuffix(strings.TrimSuffix(req.Path, "/pem"), "/delta")
+
+	var crlBytes []byte
+	issuers, err := fetchIssuers(ctx, req)
+	switch err.(type) {
+	case errutil.UserError:
+		// No CA is configured, hence there is no CRL
+	case errutil.InternalError:
+		b.Logger().Warn("possible error, but cannot return in raw response", "error", err)
+	default:
+		issuer := findIssuer(issuers, ref)
+		if issuer == nil {
+			return nil, nil
+		}
+
+		prefix := crlPrefix
+		if delta {
+			prefix = deltaCRLPrefix
+		}
+		entry, err := req.Storage.Get(ctx, prefix+issuer.ID)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil && issuer.Default && !delta {
+			// The CRL may predate the CRLs of issuers
+			entry, err = req.Storage.Get(ctx, "crl")
+			if err != nil {
+				return nil, err
+			}
+		}
+		if entry != nil {
+			crlBytes = entry.Value
+		}
+	}
+
+	if len(crlBytes) > 0 && strings.HasSuffix(req.Path, "/pem") {
+		block := pem.Block{
+			Type:  "X509 CRL",
+			Bytes: crlBytes,
+		}
+		crlBytes = []byte(strings.TrimSpace(string(pem.EncodeToMemory(&block))))
+	}
+
+	statusCode := 200
+	if len(crlBytes) == 0 {
+		statusCode = 204
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/pkix-crl",
+			logical.HTTPRawBody:     crlBytes,
+			logical.HTTPStatusCode:  statusCode,
+		},
+	}, nil
+}
+
 func (b *backend) pathFetchRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (response *logical.Response, retErr error) {
 	var serial, pemType, contentType string
 	var certEntry, revokedEntry *logical.StorageEntry
@@ -274,3 +408,26 @@ Using "ca" or "crl" as the value fetches the appropriate information in DER enco
 
 Using "ca_chain" as the value fetches the certificate authority trust chain in PEM encoding.
 `
+
+const pathFetchIssuerCRLHelpSyn = `
+Fetch the CRL or delta CRL signed by an issuer.
+`
+
+const pathFetchIssuerCRLHelpDesc = `
+This allows the CRL signed by an issuer of this mount to be fetched in DER
+encoding. Add "/pem" to get PEM encoding. The issuer is given by its ID or
+"default" for the current CA, which "crl/delta" also fetches from.
+
+Issuers replaced by the current CA only sign a CRL when shard_by_issuer is set
+in the CRL configuration. Adding "/delta" fetches the delta CRL, generated when
+enable_delta is set.
+`
+
+const pathListIssuersHelpSyn = `
+List the issuers of this mount.
+`
+
+const pathListIssuersHelpDesc = `
+This lists the ID of the current CA of the mount, as well as those of the CAs
+it replaced, which can be used to fetch the CRLs they sign.
+`
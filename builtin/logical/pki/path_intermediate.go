@@ -214,6 +214,10 @@ func (b *backend) pathSetSignedIntermediate(ctx context.Context, req *logical.Re
 	if err != nil {
 		return nil, err
 	}
+	err = storeIssuer(ctx, req.Storage, cb, inputBundle.Certificate)
+	if err != nil {
+		return nil, err
+	}
 
 	entry.Key = "certs/" + normalizeSerial(cb.SerialNumber)
 	entry.Value = inputBundle.CertificateBytes
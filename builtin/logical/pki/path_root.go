@@ -114,6 +114,9 @@ func pathSignSelfIssued(b *backend) *framework.Path {
 }
 
 func (b *backend) pathCADeleteRoot(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	if err := deleteIssuers(ctx, req.Storage); err != nil {
+		return nil, err
+	}
 	return nil, req.Storage.Delete(ctx, "config/ca_bundle")
 }
 
@@ -213,6 +216,10 @@ func (b *backend) pathCAGenerateRoot(ctx context.Context, req *logical.Request,
 	if err != nil {
 		return nil, err
 	}
+	err = storeIssuer(ctx, req.Storage, cb, parsedBundle.Certificate)
+	if err != nil {
+		return nil, err
+	}
 
 	// Also store it as just the certificate identified by serial number, so it
 	// can be revoked
@@ -1090,6 +1090,100 @@ func TestBackend_CustomKeyIDFormat(t *testing.T) {
 	logicaltest.Test(t, testCase)
 }
 
+func TestBackend_IdentityKeyIDFormat(t *testing.T) {
+	config := logical.TestBackendConfig()
+	sysView := logical.TestSystemView()
+	sysView.EntityVal = &logical.Entity{
+		ID:   "entity-id",
+		Name: "tuber",
+		Metadata: map[string]string{
+			"team": "ops",
+		},
+		Aliases: []*logical.Alias{
+			{
+				MountAccessor: "auth_userpass_1234",
+				Name:          "tuber-alias",
+			},
+		},
+	}
+	sysView.GroupsVal = []*logical.Group{
+		{ID: "group-2", Name: "wheel"},
+		{ID: "group-1", Name: "admins"},
+	}
+	config.System = sysView
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	request := func(path string, entityID string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   config.StorageView,
+			EntityID:  entityID,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return resp
+	}
+
+	request("config/ca", "", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	request("roles/identity", "", map[string]interface{}{
+		"key_type":                "ca",
+		"key_id_format":           "{{identity.entity.metadata.team}}-{{role_name}}-{{identity.entity.aliases.auth_userpass_1234.name}}-{{identity.entity.groups.names}}",
+		"allowed_users":           "tuber",
+		"default_user":            "tuber",
+		"allow_user_certificates": true,
+	})
+
+	resp := request("sign/identity", "entity-id", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	if resp == nil || resp.IsError() {
+		t.Fatalf("unexpected response: %#v", resp)
+	}
+	parsedKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.Data["signed_key"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "ops-identity-tuber-alias-admins,wheel"
+	if keyID := parsedKey.(*ssh.Certificate).KeyId; keyID != expected {
+		t.Fatalf("expected key ID %q, got %q", expected, keyID)
+	}
+
+	// Tokens without an entity cannot be signed for
+	resp = request("sign/identity", "", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+
+	// Neither can entities lacking the metadata
+	request("roles/identity", "", map[string]interface{}{
+		"key_type":                "ca",
+		"key_id_format":           "{{identity.entity.metadata.site}}",
+		"allowed_users":           "tuber",
+		"default_user":            "tuber",
+		"allow_user_certificates": true,
+	})
+	resp = request("sign/identity", "entity-id", map[string]interface{}{
+		"public_key": publicKey2,
+	})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error, got %#v", resp)
+	}
+}
+
 func TestBackend_DisallowUserProvidedKeyIDs(t *testing.T) {
 	config := logical.TestBackendConfig()
 
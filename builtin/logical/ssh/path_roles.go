@@ -321,6 +321,10 @@ func pathRoles(b *backend) *framework.Path {
 				The following variables are available for use: '{{token_display_name}}' - The display name of
 				the token used to make the request. '{{role_name}}' - The name of the role signing the request.
 				'{{public_key_hash}}' - A SHA256 checksum of the public key that is being signed.
+				Identity templates such as '{{identity.entity.metadata.team}}' or
+				'{{identity.entity.aliases.<mount accessor>.name}}' are also available, rendered from
+				the entity of the token; '{{identity.entity.groups.names}}' renders the comma-separated
+				names of its groups.
 				`,
 				DisplayAttrs: &framework.DisplayAttributes{
 					Name: "Key ID Format",
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/identitytpl"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -322,15 +324,112 @@ func (b *backend) calculateKeyID(data *framework.FieldData, req *logical.Request
 		keyIDFormat = role.KeyIDFormat
 	}
 
-	keyID := substQuery(keyIDFormat, map[string]string{
+	keyID, err := b.renderKeyIDFormat(req, keyIDFormat, map[string]string{
 		"token_display_name": req.DisplayName,
 		"role_name":          data.Get("role").(string),
 		"public_key_hash":    fmt.Sprintf("%x", sha256.Sum256(pubKey.Marshal())),
 	})
+	if err != nil {
+		return "", errwrap.Wrapf("error rendering key_id_format: {{err}}", err)
+	}
 
 	return keyID, nil
 }
 
+// renderKeyIDFormat substitutes the given variables into a key ID format.
+// Identity directives such as '{{identity.entity.metadata.team}}' are resolved
+// against the entity of the request, so that the key ID cannot be influenced
+// by the client. Other directives are left untouched.
+func (b *backend) renderKeyIDFormat(req *logical.Request, format string, vars map[string]string) (string, error) {
+	var entity *logical.Entity
+	var groups []*logical.Group
+
+	var out strings.Builder
+	rest := format
+	for {
+		start := strings.Index(rest, "{{")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end == -1 {
+			break
+		}
+		end += start + 2
+
+		directive := rest[start:end]
+		name := strings.TrimSpace(directive[2 : len(directive)-2])
+		out.WriteString(rest[:start])
+		rest = rest[end:]
+
+		if value, ok := vars[name]; ok {
+			out.WriteString(value)
+			continue
+		}
+		if !strings.HasPrefix(name, "identity.") {
+			out.WriteString(directive)
+			continue
+		}
+
+		if entity == nil {
+			if req.EntityID == "" {
+				return "", fmt.Errorf("%q requires the token to have an entity", directive)
+			}
+			var err error
+			entity, err = b.System().EntityInfo(req.EntityID)
+			if err != nil {
+				return "", err
+			}
+			if entity == nil {
+				return "", fmt.Errorf("no entity found for %q", directive)
+			}
+			groups, err = b.System().GroupsForEntity(req.EntityID)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		value, err := renderIdentityDirective(name, entity, groups)
+		if err != nil {
+			return "", errwrap.Wrapf(fmt.Sprintf("error rendering %q: {{err}}", directive), err)
+		}
+		out.WriteString(value)
+	}
+	out.WriteString(rest)
+
+	return out.String(), nil
+}
+
+// renderIdentityDirective renders a single identity templating directive.
+// Unlike in policies, the group names and IDs of the entity may be used, and
+// are joined with commas.
+func renderIdentityDirective(name string, entity *logical.Entity, groups []*logical.Group) (string, error) {
+	switch name {
+	case "identity.entity.groups.names", "identity.entity.groups.ids":
+		values := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if name == "identity.entity.groups.names" {
+				values = append(values, group.Name)
+			} else {
+				values = append(values, group.ID)
+			}
+		}
+		if len(values) == 0 {
+			return "", identitytpl.ErrNoGroupsAttachedToToken
+		}
+		sort.Strings(values)
+		return strings.Join(values, ","), nil
+	}
+
+	_, value, err := identitytpl.PopulateString(identitytpl.PopulateStringInput{
+		String: "{{" + name + "}}",
+		Entity: entity,
+		Groups: groups,
+		Mode:   identitytpl.ACLTemplating,
+	})
+	return value, err
+}
+
 func (b *backend) calculateCriticalOptions(data *framework.FieldData, role *sshRole) (map[string]string, error) {
 	unparsedCriticalOptions := data.Get("critical_options").(map[string]interface{})
 	if len(unparsedCriticalOptions) == 0 {
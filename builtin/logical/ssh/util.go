@@ -226,12 +226,3 @@ func convertMapToIntValue(initial map[string]interface{}) (map[string]int, error
 	}
 	return result, nil
 }
-
-// Serve a template processor for custom format inputs
-func substQuery(tpl string, data map[string]string) string {
-	for k, v := range data {
-		tpl = strings.Replace(tpl, fmt.Sprintf("{{%s}}", k), v, -1)
-	}
-
-	return tpl
-}
@@ -0,0 +1,64 @@
+package tokenize
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+	b := Backend()
+	if err := b.Setup(ctx, conf); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func Backend() *backend {
+	var b backend
+	b.Backend = &framework.Backend{
+		Help: strings.TrimSpace(backendHelp),
+
+		PathsSpecial: &logical.Paths{
+			SealWrapStorage: []string{
+				"role/",
+			},
+		},
+
+		Paths: []*framework.Path{
+			pathListTemplates(&b),
+			pathTemplates(&b),
+			pathListRoles(&b),
+			pathRotateRole(&b),
+			pathRoles(&b),
+			pathEncode(&b),
+			pathDecode(&b),
+		},
+
+		Secrets:     []*framework.Secret{},
+		BackendType: logical.TypeLogical,
+	}
+
+	b.roleLocks = locksutil.CreateLocks()
+
+	return &b
+}
+
+type backend struct {
+	*framework.Backend
+
+	// roleLocks serializes the changes to the keys of a role
+	roleLocks []*locksutil.LockEntry
+}
+
+const backendHelp = `
+The tokenize backend encodes values such as credit card and social security
+numbers into tokens of the same format, using the FF3-1 format-preserving
+encryption mode.
+
+Templates describe the format of the values, and roles hold the keys the values
+are encoded with and the templates they can be used with.
+`
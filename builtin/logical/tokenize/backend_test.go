@@ -0,0 +1,240 @@
+package tokenize
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testBackend(t *testing.T) (*backend, logical.Storage) {
+	t.Helper()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(*backend), config.StorageView
+}
+
+func testRequest(t *testing.T, b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: op,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+	return resp
+}
+
+func TestBackend_Templates(t *testing.T) {
+	b, s := testBackend(t)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "templates/bad", map[string]interface{}{
+		"pattern": `\d+`,
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error for a pattern without capture groups")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "templates/bad", map[string]interface{}{
+		"pattern":  `(\w+)`,
+		"alphabet": "aab",
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error for an alphabet with duplicate characters")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "templates/phone", map[string]interface{}{
+		"pattern": `\+1 (\d{3}) (\d{3})-(\d{4})`,
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "templates/phone", nil)
+	if resp.Data["alphabet"] != numericAlphabet {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.ListOperation, "templates/", nil)
+	expected := []string{"builtin/creditcardnumber", "builtin/socialsecuritynumber", "phone"}
+	if !reflect.DeepEqual(resp.Data["keys"], expected) {
+		t.Fatalf("expected %v, got %v", expected, resp.Data["keys"])
+	}
+}
+
+func TestBackend_EncodeDecode(t *testing.T) {
+	b, s := testBackend(t)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "roles/payments", map[string]interface{}{
+		"templates": "builtin/creditcardnumber,builtin/socialsecuritynumber",
+		"mode":      "convergent",
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "encode/payments", map[string]interface{}{
+		"value": "123-45-6789",
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error without a template for a role with two templates")
+	}
+
+	encode := func(role, template, value string) *logical.Response {
+		t.Helper()
+		resp := testRequest(t, b, s, logical.UpdateOperation, "encode/"+role, map[string]interface{}{
+			"value":    value,
+			"template": template,
+		})
+		if resp.IsError() {
+			t.Fatalf("bad: %#v", resp)
+		}
+		return resp
+	}
+
+	// Convergent encoding preserves the format and is deterministic
+	resp = encode("payments", "builtin/socialsecuritynumber", "123-45-6789")
+	token := resp.Data["encoded_value"].(string)
+	if !regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`).MatchString(token) || token == "123-45-6789" {
+		t.Fatalf("unexpected token: %s", token)
+	}
+	if _, ok := resp.Data["tweak"]; ok {
+		t.Fatal("expected no tweak for a convergent role")
+	}
+	resp = encode("payments", "builtin/socialsecuritynumber", "123-45-6789")
+	if resp.Data["encoded_value"] != token {
+		t.Fatalf("expected convergent encoding to return %s, got %s", token, resp.Data["encoded_value"])
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "decode/payments", map[string]interface{}{
+		"value":    token,
+		"template": "builtin/socialsecuritynumber",
+	})
+	if resp.Data["decoded_value"] != "123-45-6789" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Randomized encoding requires the tweak to decode
+	resp = testRequest(t, b, s, logical.UpdateOperation, "roles/cards", map[string]interface{}{
+		"templates": "builtin/creditcardnumber",
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = encode("cards", "", "4111 1111 1111 1111")
+	token = resp.Data["encoded_value"].(string)
+	tweak := resp.Data["tweak"].(string)
+	if !regexp.MustCompile(`^\d{4} \d{4} \d{4} \d{4}$`).MatchString(token) {
+		t.Fatalf("unexpected token: %s", token)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "decode/cards", map[string]interface{}{
+		"value": token,
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error without the tweak for a randomized role")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "decode/cards", map[string]interface{}{
+		"value": token,
+		"tweak": tweak,
+	})
+	if resp.Data["decoded_value"] != "4111 1111 1111 1111" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "encode/cards", map[string]interface{}{
+		"value":    "123-45-6789",
+		"template": "builtin/socialsecuritynumber",
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error for a template not bound to the role")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "encode/cards", map[string]interface{}{
+		"value": "4111-1111",
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error for a value not matching the template")
+	}
+}
+
+func TestBackend_Rotate(t *testing.T) {
+	b, s := testBackend(t)
+
+	resp := testRequest(t, b, s, logical.UpdateOperation, "roles/ssn", map[string]interface{}{
+		"templates": "builtin/socialsecuritynumber",
+		"mode":      "convergent",
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "encode/ssn", map[string]interface{}{
+		"value": "123456789",
+	})
+	oldToken := resp.Data["encoded_value"].(string)
+	if resp.Data["key_version"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	testRequest(t, b, s, logical.UpdateOperation, "roles/ssn/rotate", nil)
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "encode/ssn", map[string]interface{}{
+		"value": "123456789",
+	})
+	if resp.Data["key_version"] != 2 || resp.Data["encoded_value"] == oldToken {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "decode/ssn", map[string]interface{}{
+		"value":       oldToken,
+		"key_version": 1,
+	})
+	if resp.Data["decoded_value"] != "123456789" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "roles/ssn", map[string]interface{}{
+		"mode": "randomized",
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error changing the mode of a role")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "roles/ssn", map[string]interface{}{
+		"min_decryption_version": 2,
+	})
+	if resp != nil {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testRequest(t, b, s, logical.ReadOperation, "roles/ssn", nil)
+	if resp.Data["latest_version"] != 2 || resp.Data["min_decryption_version"] != 2 || len(resp.Data["keys"].(map[string]int64)) != 2 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if _, ok := resp.Data["key"]; ok {
+		t.Fatal("expected the keys not to be returned")
+	}
+
+	resp = testRequest(t, b, s, logical.UpdateOperation, "decode/ssn", map[string]interface{}{
+		"value":       oldToken,
+		"key_version": 1,
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error decoding with a version below the minimum")
+	}
+}
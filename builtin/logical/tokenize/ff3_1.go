@@ -0,0 +1,200 @@
+package tokenize
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// ff31TweakSize is the size in bytes of the tweaks of FF3-1
+	ff31TweakSize = 7
+
+	// ff31Rounds is the number of Feistel rounds of FF3-1
+	ff31Rounds = 8
+
+	// ff31MinDomain is the smallest number of values the numeral strings of
+	// a given length can take to be encrypted
+	ff31MinDomain = 1000000
+
+	// ff31MaxRadix is the largest radix supported by FF3-1
+	ff31MaxRadix = 1 << 16
+)
+
+// ff31 implements the FF3-1 format-preserving encryption mode of NIST SP
+// 800-38G Revision 1, which encrypts strings of numerals in a given radix into
+// strings of numerals of the same length and radix.
+type ff31 struct {
+	block  cipher.Block
+	radix  int
+	minLen int
+	maxLen int
+}
+
+// newFF31 returns a FF3-1 cipher using the AES key and radix. The key must be
+// 16, 24 or 32 bytes long.
+func newFF31(key []byte, radix int) (*ff31, error) {
+	if radix < 2 || radix > ff31MaxRadix {
+		return nil, fmt.Errorf("radix must be between 2 and %d", ff31MaxRadix)
+	}
+
+	// The cipher key is used with its bytes reversed
+	block, err := aes.NewCipher(reverseBytes(key))
+	if err != nil {
+		return nil, err
+	}
+
+	// The numeral strings must take at least a million values, and each of
+	// their halves must fit in the 96 bits of the block left by the tweak
+	bigRadix := big.NewInt(int64(radix))
+	minLen := 2
+	for new(big.Int).Exp(bigRadix, big.NewInt(int64(minLen)), nil).Cmp(big.NewInt(ff31MinDomain)) < 0 {
+		minLen++
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	halfLen := 0
+	for new(big.Int).Exp(bigRadix, big.NewInt(int64(halfLen+1)), nil).Cmp(limit) <= 0 {
+		halfLen++
+	}
+
+	return &ff31{
+		block:  block,
+		radix:  radix,
+		minLen: minLen,
+		maxLen: 2 * halfLen,
+	}, nil
+}
+
+// Encrypt encrypts the numerals with the 7 bytes tweak.
+func (f *ff31) Encrypt(numerals []int, tweak []byte) ([]int, error) {
+	return f.crypt(numerals, tweak, true)
+}
+
+// Decrypt decrypts the numerals with the 7 bytes tweak.
+func (f *ff31) Decrypt(numerals []int, tweak []byte) ([]int, error) {
+	return f.crypt(numerals, tweak, false)
+}
+
+func (f *ff31) crypt(numerals []int, tweak []byte, encrypt bool) ([]int, error) {
+	if len(tweak) != ff31TweakSize {
+		return nil, fmt.Errorf("tweak must be %d bytes long", ff31TweakSize)
+	}
+
+	// The 56 bits tweak is split into two 32 bits halves, the middle byte
+	// being shared between them
+	var tl, tr [4]byte
+	copy(tl[:], tweak[:4])
+	tl[3] &= 0xf0
+	copy(tr[:], tweak[4:])
+	tr[3] = tweak[3] << 4
+
+	return f.feistel(numerals, tl, tr, encrypt)
+}
+
+// feistel runs the Feistel rounds of FF3 with the halves of the tweak.
+func (f *ff31) feistel(numerals []int, tl, tr [4]byte, encrypt bool) ([]int, error) {
+	n := len(numerals)
+	if n < f.minLen || n > f.maxLen {
+		return nil, fmt.Errorf("value must have between %d and %d characters to encode", f.minLen, f.maxLen)
+	}
+	for _, x := range numerals {
+		if x < 0 || x >= f.radix {
+			return nil, errors.New("numeral out of range of the radix")
+		}
+	}
+
+	u := (n + 1) / 2
+	v := n - u
+
+	a := append([]int(nil), numerals[:u]...)
+	b := append([]int(nil), numerals[u:]...)
+
+	bigRadix := big.NewInt(int64(f.radix))
+	modU := new(big.Int).Exp(bigRadix, big.NewInt(int64(u)), nil)
+	modV := new(big.Int).Exp(bigRadix, big.NewInt(int64(v)), nil)
+
+	for r := 0; r < ff31Rounds; r++ {
+		i := r
+		if !encrypt {
+			i = ff31Rounds - 1 - r
+		}
+
+		m, mod, w := u, modU, tr
+		if i%2 == 1 {
+			m, mod, w = v, modV, tl
+		}
+
+		// The round function takes the half which is not being changed
+		in := b
+		if !encrypt {
+			in = a
+		}
+
+		var p [aes.BlockSize]byte
+		copy(p[:4], w[:])
+		p[3] ^= byte(i)
+		num := f.num(in)
+		numBytes := num.Bytes()
+		if len(numBytes) > 12 {
+			return nil, errors.New("numeral string too long for the radix")
+		}
+		copy(p[aes.BlockSize-len(numBytes):], numBytes)
+
+		var s [aes.BlockSize]byte
+		rev := reverseBytes(p[:])
+		f.block.Encrypt(s[:], rev)
+		y := new(big.Int).SetBytes(reverseBytes(s[:]))
+
+		var c *big.Int
+		if encrypt {
+			c = new(big.Int).Add(f.num(a), y)
+		} else {
+			c = new(big.Int).Sub(f.num(b), y)
+		}
+		c.Mod(c, mod)
+
+		if encrypt {
+			a, b = b, f.str(c, m)
+		} else {
+			b, a = a, f.str(c, m)
+		}
+	}
+
+	return append(a, b...), nil
+}
+
+// num returns the number represented by the numerals, the last numeral being
+// the most significant.
+func (f *ff31) num(numerals []int) *big.Int {
+	bigRadix := big.NewInt(int64(f.radix))
+	result := new(big.Int)
+	for i := len(numerals) - 1; i >= 0; i-- {
+		result.Mul(result, bigRadix)
+		result.Add(result, big.NewInt(int64(numerals[i])))
+	}
+	return result
+}
+
+// str returns the m numerals representing the number, the last numeral being
+// the most significant.
+func (f *ff31) str(x *big.Int, m int) []int {
+	bigRadix := big.NewInt(int64(f.radix))
+	x = new(big.Int).Set(x)
+	rem := new(big.Int)
+	result := make([]int, m)
+	for i := 0; i < m; i++ {
+		x.QuoRem(x, bigRadix, rem)
+		result[i] = int(rem.Int64())
+	}
+	return result
+}
+
+func reverseBytes(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i := range in {
+		out[len(in)-1-i] = in[i]
+	}
+	return out
+}
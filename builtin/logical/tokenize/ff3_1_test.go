@@ -0,0 +1,162 @@
+package tokenize
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func digits(s string) []int {
+	out := make([]int, len(s))
+	for i, c := range s {
+		out[i] = int(c - '0')
+	}
+	return out
+}
+
+func digitString(numerals []int) string {
+	out := make([]byte, len(numerals))
+	for i, x := range numerals {
+		out[i] = byte('0' + x)
+	}
+	return string(out)
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	out, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// FF3-1 only differs from FF3 by the split of its tweak, so the Feistel
+// rounds are checked against the FF3 samples of NIST
+func TestFF31_FF3Samples(t *testing.T) {
+	cases := []struct {
+		key        string
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{
+			"EF4359D8D580AA4F7F036D6F04FC6A94",
+			"D8E7920AFA330A73",
+			"890121234567890000",
+			"750918814058654607",
+		},
+		{
+			"EF4359D8D580AA4F7F036D6F04FC6A94",
+			"9A768A92F60E12D8",
+			"890121234567890000",
+			"018989839189395384",
+		},
+	}
+
+	for _, tc := range cases {
+		f, err := newFF31(mustHex(t, tc.key), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tweak := mustHex(t, tc.tweak)
+		var tl, tr [4]byte
+		copy(tl[:], tweak[:4])
+		copy(tr[:], tweak[4:])
+
+		out, err := f.feistel(digits(tc.plaintext), tl, tr, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digitString(out) != tc.ciphertext {
+			t.Fatalf("expected %s, got %s", tc.ciphertext, digitString(out))
+		}
+
+		out, err = f.feistel(out, tl, tr, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if digitString(out) != tc.plaintext {
+			t.Fatalf("expected %s, got %s", tc.plaintext, digitString(out))
+		}
+	}
+}
+
+func TestFF31_Encrypt(t *testing.T) {
+	f, err := newFF31(mustHex(t, "2DE79D232DF5585D68CE47882AE256D6"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tweak := mustHex(t, "CBD09280979564")
+
+	out, err := f.Encrypt(digits("3992520240"), tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digitString(out) != "8901801106" {
+		t.Fatalf("expected 8901801106, got %s", digitString(out))
+	}
+
+	out, err = f.Decrypt(out, tweak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digitString(out) != "3992520240" {
+		t.Fatalf("expected 3992520240, got %s", digitString(out))
+	}
+}
+
+func TestFF31_RoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	tweak := make([]byte, ff31TweakSize)
+	if _, err := rand.Read(tweak); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, radix := range []int{10, 26, 62} {
+		f, err := newFF31(key, radix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for n := f.minLen; n <= f.maxLen; n++ {
+			in := make([]int, n)
+			for i := range in {
+				in[i] = (i * 7) % radix
+			}
+
+			enc, err := f.Encrypt(in, tweak)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(enc) != n {
+				t.Fatalf("expected %d numerals, got %d", n, len(enc))
+			}
+
+			dec, err := f.Decrypt(enc, tweak)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range in {
+				if in[i] != dec[i] {
+					t.Fatalf("radix %d length %d: expected %v, got %v", radix, n, in, dec)
+				}
+			}
+		}
+	}
+
+	f, err := newFF31(key, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.minLen != 6 || f.maxLen != 56 {
+		t.Fatalf("unexpected length bounds: %d %d", f.minLen, f.maxLen)
+	}
+	if _, err := f.Encrypt(digits("12345"), tweak); err == nil {
+		t.Fatal("expected an error for a value shorter than the minimum length")
+	}
+}
@@ -0,0 +1,266 @@
+package tokenize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathEncode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "encode/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"value": {
+				Type:        framework.TypeString,
+				Description: "The value to encode.",
+			},
+
+			"template": {
+				Type:        framework.TypeString,
+				Description: "The name of the template describing the format of the value. Only required if the role has more than one template.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathEncodeWrite,
+		},
+
+		HelpSynopsis:    pathEncodeHelpSyn,
+		HelpDescription: pathEncodeHelpDesc,
+	}
+}
+
+func pathDecode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "decode/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"value": {
+				Type:        framework.TypeString,
+				Description: "The token to decode.",
+			},
+
+			"template": {
+				Type:        framework.TypeString,
+				Description: "The name of the template the token was encoded with. Only required if the role has more than one template.",
+			},
+
+			"tweak": {
+				Type:        framework.TypeString,
+				Description: "The base64 encoded tweak returned when the value was encoded. Only used by roles in the randomized mode, for which it is required.",
+			},
+
+			"key_version": {
+				Type:        framework.TypeInt,
+				Description: "The version of the key the token was encoded with. Defaults to the latest version.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathDecodeWrite,
+		},
+
+		HelpSynopsis:    pathDecodeHelpSyn,
+		HelpDescription: pathDecodeHelpDesc,
+	}
+}
+
+func (b *backend) pathEncodeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	value := data.Get("value").(string)
+
+	if value == "" {
+		return logical.ErrorResponse("the value is required"), nil
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, template, resp, err := b.roleAndTemplate(ctx, req.Storage, name, data.Get("template").(string))
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	version := role.latestVersion()
+	key := role.key(version)
+
+	var tweak []byte
+	switch role.Mode {
+	case modeConvergent:
+		tweak = convergentTweak(key, template.name)
+	default:
+		tweak = make([]byte, ff31TweakSize)
+		if _, err := io.ReadFull(b.GetRandomReader(), tweak); err != nil {
+			return nil, err
+		}
+	}
+
+	cipher, err := newFF31(key.Key, len(template.alphabet))
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := template.transform(value, func(numerals []int) ([]int, error) {
+		return cipher.Encrypt(numerals, tweak)
+	})
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{
+			"encoded_value": encoded,
+			"key_version":   version,
+		},
+	}
+	if role.Mode == modeRandomized {
+		resp.Data["tweak"] = base64.StdEncoding.EncodeToString(tweak)
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathDecodeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	value := data.Get("value").(string)
+
+	if value == "" {
+		return logical.ErrorResponse("the value is required"), nil
+	}
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, template, resp, err := b.roleAndTemplate(ctx, req.Storage, name, data.Get("template").(string))
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	version := role.latestVersion()
+	if versionRaw, ok := data.GetOk("key_version"); ok {
+		version = versionRaw.(int)
+	}
+	key := role.key(version)
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("key version %d is not available for decoding", version)), nil
+	}
+
+	var tweak []byte
+	switch role.Mode {
+	case modeConvergent:
+		tweak = convergentTweak(key, template.name)
+	default:
+		tweakRaw := data.Get("tweak").(string)
+		if tweakRaw == "" {
+			return logical.ErrorResponse("the tweak value is required for roles in the randomized mode"), nil
+		}
+		tweak, err = base64.StdEncoding.DecodeString(tweakRaw)
+		if err != nil || len(tweak) != ff31TweakSize {
+			return logical.ErrorResponse(fmt.Sprintf("the tweak value must be %d base64 encoded bytes", ff31TweakSize)), nil
+		}
+	}
+
+	cipher, err := newFF31(key.Key, len(template.alphabet))
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := template.transform(value, func(numerals []int) ([]int, error) {
+		return cipher.Decrypt(numerals, tweak)
+	})
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"decoded_value": decoded,
+		},
+	}, nil
+}
+
+// roleAndTemplate returns the named role and the template to use with it,
+// which is the only template of the role if none is given. An error response
+// is returned if either cannot be used.
+func (b *backend) roleAndTemplate(ctx context.Context, s logical.Storage, name, templateName string) (*roleEntry, *compiledTemplate, *logical.Response, error) {
+	role, err := b.Role(ctx, s, name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if role == nil {
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	switch {
+	case templateName == "" && len(role.Templates) == 1:
+		templateName = role.Templates[0]
+	case templateName == "":
+		return nil, nil, logical.ErrorResponse("the template value is required for roles with more than one template"), nil
+	case !strutil.StrListContains(role.Templates, templateName):
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("template %s is not allowed for role %s", templateName, name)), nil
+	}
+
+	entry, err := b.Template(ctx, s, templateName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if entry == nil {
+		return nil, nil, logical.ErrorResponse(fmt.Sprintf("unknown template: %s", templateName)), nil
+	}
+
+	template, err := entry.compile()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	template.name = templateName
+
+	return role, template, nil, nil
+}
+
+// convergentTweak derives the tweak values are encoded with in the convergent
+// mode from the key and the template, so that a value is always encoded into
+// the same token with a given template and version of the key.
+func convergentTweak(key *roleKey, templateName string) []byte {
+	mac := hmac.New(sha256.New, key.TweakKey)
+	mac.Write([]byte(templateName))
+	return mac.Sum(nil)[:ff31TweakSize]
+}
+
+const pathEncodeHelpSyn = `
+Encode a value into a token of the same format.
+`
+
+const pathEncodeHelpDesc = `
+This path encodes a value matching a template of the role into a token of the
+same format, with the latest version of the key of the role. The version of
+the key is returned along with the token, and so is the tweak the value was
+encoded with for roles in the randomized mode; both are needed to decode it.
+`
+
+const pathDecodeHelpSyn = `
+Decode a token into the value it was encoded from.
+`
+
+const pathDecodeHelpDesc = `
+This path decodes a token encoded by the role back into its value. The version
+of the key the token was encoded with must be given if the key was rotated
+since, and so must the tweak returned along with the token for roles in the
+randomized mode.
+`
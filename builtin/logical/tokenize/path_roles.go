@@ -0,0 +1,337 @@
+package tokenize
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// modeConvergent encodes a value into the same token every time, so
+	// that tokens can be compared or joined on
+	modeConvergent = "convergent"
+
+	// modeRandomized encodes a value with a random tweak, which must be given
+	// back to decode the token
+	modeRandomized = "randomized"
+
+	keySize = 32
+)
+
+func pathListRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathRoleList,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRoles(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+
+			"templates": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: `The names of the templates the role can encode and decode values with.`,
+			},
+
+			"mode": {
+				Type:        framework.TypeString,
+				Description: `Either "convergent", to encode a value into the same token every time, or "randomized", to encode values with a random tweak which must be given back to decode them. Defaults to "randomized", and cannot be changed once the role is created.`,
+			},
+
+			"min_decryption_version": {
+				Type:        framework.TypeInt,
+				Description: `The minimum version of the key of the role tokens can be decoded with.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathRoleRead,
+			logical.UpdateOperation: b.pathRoleWrite,
+			logical.DeleteOperation: b.pathRoleDelete,
+		},
+
+		HelpSynopsis:    pathRoleHelpSyn,
+		HelpDescription: pathRoleHelpDesc,
+	}
+}
+
+func pathRotateRole(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "roles/" + framework.GenericNameRegex("name") + "/rotate",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the role.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRoleRotate,
+		},
+
+		HelpSynopsis:    pathRotateRoleHelpSyn,
+		HelpDescription: pathRotateRoleHelpDesc,
+	}
+}
+
+type roleEntry struct {
+	Templates            []string   `json:"templates" mapstructure:"templates" structs:"templates"`
+	Mode                 string     `json:"mode" mapstructure:"mode" structs:"mode"`
+	MinDecryptionVersion int        `json:"min_decryption_version" mapstructure:"min_decryption_version" structs:"min_decryption_version"`
+	Keys                 []*roleKey `json:"keys" mapstructure:"keys" structs:"keys"`
+}
+
+// roleKey is a version of the key of a role. The tweak key derives the tweaks
+// of the convergent mode.
+type roleKey struct {
+	Key          []byte    `json:"key"`
+	TweakKey     []byte    `json:"tweak_key"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
+// latestVersion returns the version of the key values are encoded with.
+func (r *roleEntry) latestVersion() int {
+	return len(r.Keys)
+}
+
+// key returns the given version of the key of the role, or nil if it does not
+// exist or is below the minimum decryption version.
+func (r *roleEntry) key(version int) *roleKey {
+	if version < 1 || version < r.MinDecryptionVersion || version > len(r.Keys) {
+		return nil
+	}
+	return r.Keys[version-1]
+}
+
+// rotate adds a new version of the key of the role.
+func (r *roleEntry) rotate(rand io.Reader) error {
+	key := &roleKey{
+		Key:          make([]byte, keySize),
+		TweakKey:     make([]byte, keySize),
+		CreationTime: time.Now(),
+	}
+	if _, err := io.ReadFull(rand, key.Key); err != nil {
+		return errwrap.Wrapf("failed to generate key: {{err}}", err)
+	}
+	if _, err := io.ReadFull(rand, key.TweakKey); err != nil {
+		return errwrap.Wrapf("failed to generate tweak key: {{err}}", err)
+	}
+
+	r.Keys = append(r.Keys, key)
+	return nil
+}
+
+// Role returns the named role. The lock of the role should be held.
+func (b *backend) Role(ctx context.Context, s logical.Storage, n string) (*roleEntry, error) {
+	entry, err := s.Get(ctx, "role/"+n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result roleEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) putRole(ctx context.Context, s logical.Storage, name string, role *roleEntry) error {
+	entry, err := logical.StorageEntryJSON("role/"+name, role)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func (b *backend) pathRoleList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "role/")
+	if err != nil {
+		return nil, err
+	}
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, nil
+	}
+
+	keys := make(map[string]int64, len(role.Keys))
+	for i, key := range role.Keys {
+		keys[strconv.Itoa(i+1)] = key.CreationTime.Unix()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"templates":              role.Templates,
+			"mode":                   role.Mode,
+			"latest_version":         role.latestVersion(),
+			"min_decryption_version": role.MinDecryptionVersion,
+			"keys":                   keys,
+		},
+	}, nil
+}
+
+func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	created := role == nil
+	if created {
+		role = &roleEntry{
+			Mode:                 modeRandomized,
+			MinDecryptionVersion: 1,
+		}
+	}
+
+	if templatesRaw, ok := data.GetOk("templates"); ok {
+		role.Templates = strutil.RemoveDuplicates(templatesRaw.([]string), false)
+	}
+	if len(role.Templates) == 0 {
+		return logical.ErrorResponse("at least one template is required"), nil
+	}
+	for _, templateName := range role.Templates {
+		template, err := b.Template(ctx, req.Storage, templateName)
+		if err != nil {
+			return nil, err
+		}
+		if template == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown template: %s", templateName)), nil
+		}
+	}
+
+	if modeRaw, ok := data.GetOk("mode"); ok {
+		mode := modeRaw.(string)
+		switch {
+		case mode != modeConvergent && mode != modeRandomized:
+			return logical.ErrorResponse(`the mode value must be "convergent" or "randomized"`), nil
+		case !created && mode != role.Mode:
+			return logical.ErrorResponse("the mode of a role cannot be changed"), nil
+		}
+		role.Mode = mode
+	}
+
+	if created {
+		if err := role.rotate(b.GetRandomReader()); err != nil {
+			return nil, err
+		}
+	}
+
+	if minDecryptionVersionRaw, ok := data.GetOk("min_decryption_version"); ok {
+		minDecryptionVersion := minDecryptionVersionRaw.(int)
+		if minDecryptionVersion < 1 || minDecryptionVersion > role.latestVersion() {
+			return logical.ErrorResponse(fmt.Sprintf("the min_decryption_version value must be between 1 and the latest version %d", role.latestVersion())), nil
+		}
+		role.MinDecryptionVersion = minDecryptionVersion
+	}
+
+	if err := b.putRole(ctx, req.Storage, name, role); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := req.Storage.Delete(ctx, "role/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathRoleRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.roleLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	role, err := b.Role(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown role: %s", name)), nil
+	}
+
+	if err := role.rotate(b.GetRandomReader()); err != nil {
+		return nil, err
+	}
+	if err := b.putRole(ctx, req.Storage, name, role); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathRoleHelpSyn = `
+Manage the roles values are encoded with.
+`
+
+const pathRoleHelpDesc = `
+This path lets you manage the roles values are encoded with. A role holds the
+key values are encoded with, the templates it can be used with, and whether it
+encodes values in the convergent or the randomized mode.
+`
+
+const pathRotateRoleHelpSyn = `
+Rotate the key of a role.
+`
+
+const pathRotateRoleHelpDesc = `
+This path rotates the key of a role. After rotation, values are encoded with
+the new version of the key, and tokens encoded with older versions can still
+be decoded by giving their key version, down to the min_decryption_version of
+the role.
+`
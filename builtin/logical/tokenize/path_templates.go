@@ -0,0 +1,277 @@
+package tokenize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const numericAlphabet = "0123456789"
+
+// builtinTemplates are the templates provided by the backend. Their names
+// cannot be taken by the templates written to it.
+var builtinTemplates = map[string]*templateEntry{
+	"builtin/creditcardnumber": {
+		Pattern:  `(\d{4})[- ]?(\d{4})[- ]?(\d{4})[- ]?(\d{4})`,
+		Alphabet: numericAlphabet,
+	},
+	"builtin/socialsecuritynumber": {
+		Pattern:  `(\d{3})[- ]?(\d{2})[- ]?(\d{4})`,
+		Alphabet: numericAlphabet,
+	},
+}
+
+func pathListTemplates(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "templates/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathTemplateList,
+		},
+
+		HelpSynopsis:    pathTemplateHelpSyn,
+		HelpDescription: pathTemplateHelpDesc,
+	}
+}
+
+func pathTemplates(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "templates/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the template.",
+			},
+
+			"pattern": {
+				Type:        framework.TypeString,
+				Description: `A regular expression the values must match in full. The characters of the values matched by its capture groups are encoded, and the others are kept as they are.`,
+			},
+
+			"alphabet": {
+				Type:        framework.TypeString,
+				Default:     numericAlphabet,
+				Description: `The set of characters the encoded characters are taken from. The characters matched by the capture groups of the pattern must belong to it.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathTemplateRead,
+			logical.UpdateOperation: b.pathTemplateWrite,
+			logical.DeleteOperation: b.pathTemplateDelete,
+		},
+
+		HelpSynopsis:    pathTemplateHelpSyn,
+		HelpDescription: pathTemplateHelpDesc,
+	}
+}
+
+type templateEntry struct {
+	Pattern  string `json:"pattern" mapstructure:"pattern" structs:"pattern"`
+	Alphabet string `json:"alphabet" mapstructure:"alphabet" structs:"alphabet"`
+}
+
+// Template returns the named template, including the builtin ones.
+func (b *backend) Template(ctx context.Context, s logical.Storage, n string) (*templateEntry, error) {
+	if template, ok := builtinTemplates[n]; ok {
+		return template, nil
+	}
+
+	entry, err := s.Get(ctx, "template/"+n)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result templateEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathTemplateList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, "template/")
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range builtinTemplates {
+		entries = append(entries, name)
+	}
+	sort.Strings(entries)
+
+	return logical.ListResponse(entries), nil
+}
+
+func (b *backend) pathTemplateRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	template, err := b.Template(ctx, req.Storage, data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"pattern":  template.Pattern,
+			"alphabet": template.Alphabet,
+		},
+	}, nil
+}
+
+func (b *backend) pathTemplateWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	template := &templateEntry{
+		Pattern:  data.Get("pattern").(string),
+		Alphabet: data.Get("alphabet").(string),
+	}
+
+	if template.Pattern == "" {
+		return logical.ErrorResponse("the pattern value is required"), nil
+	}
+	if _, err := template.compile(); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	entry, err := logical.StorageEntryJSON("template/"+name, template)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func (b *backend) pathTemplateDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	err := req.Storage.Delete(ctx, "template/"+data.Get("name").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// compiledTemplate is a template ready to transform values.
+type compiledTemplate struct {
+	name     string
+	re       *regexp.Regexp
+	alphabet []rune
+	index    map[rune]int
+}
+
+// compile checks the template and prepares it to transform values.
+func (t *templateEntry) compile() (*compiledTemplate, error) {
+	re, err := regexp.Compile("^(?:" + t.Pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %s", err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, errors.New("the pattern must have at least one capture group")
+	}
+
+	if !utf8.ValidString(t.Alphabet) {
+		return nil, errors.New("the alphabet must be valid UTF-8")
+	}
+	alphabet := []rune(t.Alphabet)
+	if len(alphabet) < 2 || len(alphabet) > ff31MaxRadix {
+		return nil, fmt.Errorf("the alphabet must have between 2 and %d characters", ff31MaxRadix)
+	}
+	index := make(map[rune]int, len(alphabet))
+	for i, r := range alphabet {
+		if _, ok := index[r]; ok {
+			return nil, fmt.Errorf("the alphabet has the character %q more than once", r)
+		}
+		index[r] = i
+	}
+
+	return &compiledTemplate{
+		re:       re,
+		alphabet: alphabet,
+		index:    index,
+	}, nil
+}
+
+// transform replaces the characters of the value matched by the capture
+// groups of the template. fn is given the positions of the matched characters
+// in the alphabet, and returns the positions of their replacements.
+func (t *compiledTemplate) transform(value string, fn func([]int) ([]int, error)) (string, error) {
+	match := t.re.FindStringSubmatchIndex(value)
+	if match == nil {
+		return "", errors.New("the value does not match the template")
+	}
+
+	// Collect the characters matched by the capture groups, in the order they
+	// appear in the value, skipping the groups nested in others
+	var positions []int
+	taken := make(map[int]bool)
+	for i := 1; i <= t.re.NumSubexp(); i++ {
+		start, end := match[2*i], match[2*i+1]
+		if start < 0 {
+			continue
+		}
+		for pos := range value[start:end] {
+			if taken[start+pos] {
+				continue
+			}
+			taken[start+pos] = true
+			positions = append(positions, start+pos)
+		}
+	}
+	sort.Ints(positions)
+
+	numerals := make([]int, len(positions))
+	for i, pos := range positions {
+		r, _ := utf8.DecodeRuneInString(value[pos:])
+		x, ok := t.index[r]
+		if !ok {
+			return "", fmt.Errorf("the character %q is not in the alphabet of the template", r)
+		}
+		numerals[i] = x
+	}
+
+	out, err := fn(numerals)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	next := 0
+	for pos, r := range value {
+		if next < len(positions) && positions[next] == pos {
+			result.WriteRune(t.alphabet[out[next]])
+			next++
+			continue
+		}
+		result.WriteRune(r)
+	}
+
+	return result.String(), nil
+}
+
+const pathTemplateHelpSyn = `
+Manage the templates describing the format of the values to encode.
+`
+
+const pathTemplateHelpDesc = `
+This path lets you manage the templates describing the format of the values to
+encode. The pattern of a template is a regular expression the values must match
+in full; the characters matched by its capture groups are encoded with the
+characters of its alphabet, and the others are kept as they are.
+
+The templates builtin/creditcardnumber and builtin/socialsecuritynumber are
+always available.
+`
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	cache "github.com/patrickmn/go-cache"
 )
@@ -32,7 +33,10 @@ func Backend() *backend {
 		Paths: []*framework.Path{
 			pathListKeys(&b),
 			pathKeys(&b),
+			pathKeyConfig(&b),
+			pathKeyLockout(&b),
 			pathCode(&b),
+			pathBatchCode(&b),
 		},
 
 		Secrets:     []*framework.Secret{},
@@ -40,6 +44,7 @@ func Backend() *backend {
 	}
 
 	b.usedCodes = cache.New(0, 30*time.Second)
+	b.keyLocks = locksutil.CreateLocks()
 
 	return &b
 }
@@ -48,6 +53,10 @@ type backend struct {
 	*framework.Backend
 
 	usedCodes *cache.Cache
+
+	// keyLocks serializes the validations of the codes of a key, so that
+	// its failed validations are counted accurately
+	keyLocks []*locksutil.LockEntry
 }
 
 const backendHelp = `
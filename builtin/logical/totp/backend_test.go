@@ -797,7 +797,7 @@ func TestBackend_generatedKeyInvalidSkew(t *testing.T) {
 	keyData := map[string]interface{}{
 		"issuer":       "Vault",
 		"account_name": "Test",
-		"skew":         "2",
+		"skew":         "11",
 		"generate":     true,
 	}
 
@@ -1205,3 +1205,186 @@ func testAccStepValidateCode(t *testing.T, name string, code string, valid, expe
 		},
 	}
 }
+
+func TestBackend_keyLockout(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil {
+			t.Fatalf("err: %v resp: %#v", err, resp)
+		}
+		return resp
+	}
+
+	resp := request(logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"key":                    key,
+		"max_failed_validations": 2,
+		"lockout_duration":       "1h",
+	})
+	if resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = request(logical.UpdateOperation, "keys/test/config", map[string]interface{}{
+		"skew": 11,
+	})
+	if !resp.IsError() {
+		t.Fatal("expected an error for a skew over the maximum")
+	}
+
+	resp = request(logical.UpdateOperation, "keys/test/config", map[string]interface{}{
+		"skew": 3,
+	})
+	if resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = request(logical.ReadOperation, "keys/test", nil)
+	if resp.Data["skew"].(uint) != 3 || resp.Data["max_failed_validations"].(int) != 2 || resp.Data["lockout_duration"].(int64) != 3600 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	if resp.Data["locked"].(bool) || resp.Data["failed_validations"].(int) != 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// A code from three periods ago is within the skew of the key
+	oldCode, err := totplib.GenerateCodeCustom(key, time.Now().Add(-90*time.Second), totplib.ValidateOpts{
+		Period:    30,
+		Digits:    otplib.DigitsSix,
+		Algorithm: otplib.AlgorithmSHA1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = request(logical.UpdateOperation, "code/test", map[string]interface{}{
+		"code": oldCode,
+	})
+	if !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code within the skew to be valid: %#v", resp.Data)
+	}
+
+	for _, code := range []string{"000001", "000002"} {
+		resp = request(logical.UpdateOperation, "code/test", map[string]interface{}{
+			"code": code,
+		})
+		if resp.Data["valid"].(bool) {
+			t.Fatalf("expected code %s to be invalid", code)
+		}
+	}
+
+	resp = request(logical.ReadOperation, "keys/test", nil)
+	if !resp.Data["locked"].(bool) || resp.Data["locked_until"].(string) == "" {
+		t.Fatalf("expected key to be locked: %#v", resp.Data)
+	}
+
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+	resp = request(logical.UpdateOperation, "code/test", map[string]interface{}{
+		"code": code,
+	})
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "locked") {
+		t.Fatalf("expected a locked key error: %#v", resp)
+	}
+
+	request(logical.DeleteOperation, "keys/test/lockout", nil)
+
+	resp = request(logical.ReadOperation, "keys/test/lockout", nil)
+	if resp.Data["locked"].(bool) {
+		t.Fatalf("expected key to be unlocked: %#v", resp.Data)
+	}
+
+	resp = request(logical.UpdateOperation, "code/test", map[string]interface{}{
+		"code": code,
+	})
+	if resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid: %#v", resp)
+	}
+}
+
+func TestBackend_batchValidateCode(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := make(map[string]string)
+	for _, name := range []string{"alice", "bob"} {
+		key, _ := createKey()
+		resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Storage:   config.StorageView,
+			Data: map[string]interface{}{
+				"key": key,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v resp: %#v", err, resp)
+		}
+		codes[name], _ = generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+	}
+
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "code",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"batch_input": []interface{}{
+				map[string]interface{}{"name": "alice", "code": codes["alice"]},
+				map[string]interface{}{"name": "bob", "code": "12345678"},
+				map[string]interface{}{"name": "alice", "code": codes["alice"]},
+				map[string]interface{}{"name": "carol", "code": "123456"},
+				map[string]interface{}{"name": "bob", "code": codes["bob"]},
+			},
+		},
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, resp)
+	}
+
+	var results []batchValidateResult
+	if err := mapstructure.Decode(resp.Data["batch_results"], &results); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []batchValidateResult{
+		{Name: "alice", Valid: true},
+		{Name: "bob", Valid: false},
+		{Name: "alice", Error: "code already used; wait until the next time period"},
+		{Name: "carol", Error: "unknown key: carol"},
+		{Name: "bob", Valid: true},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %#v", len(expected), results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("result %d: expected %#v, got %#v", i, expected[i], results[i])
+		}
+	}
+
+	_, err = b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "code",
+		Storage:   config.StorageView,
+		Data:      map[string]interface{}{},
+	})
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected an invalid request error for an empty batch, got %v", err)
+	}
+}
@@ -2,12 +2,15 @@ package totp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
 	otplib "github.com/pquerna/otp"
 	totplib "github.com/pquerna/otp/totp"
 )
@@ -66,56 +69,165 @@ func (b *backend) pathReadCode(ctx context.Context, req *logical.Request, data *
 	}, nil
 }
 
+func pathBatchCode(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "code/?$",
+		Fields: map[string]*framework.FieldSchema{
+			"batch_input": &framework.FieldSchema{
+				Type: framework.TypeSlice,
+				Description: `
+Specifies a list of items to be validated in a single batch. Each item holds
+the name of a key under "name" and the TOTP code to be validated against it
+under "code".`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathBatchValidateCode,
+		},
+
+		HelpSynopsis:    pathBatchCodeHelpSyn,
+		HelpDescription: pathBatchCodeHelpDesc,
+	}
+}
+
+// batchValidateItem is a code to be validated by a batch validation
+type batchValidateItem struct {
+	Name string `json:"name" structs:"name" mapstructure:"name"`
+	Code string `json:"code" structs:"code" mapstructure:"code"`
+}
+
+// batchValidateResult is the result of the validation of a code of a batch
+type batchValidateResult struct {
+	Name  string `json:"name" structs:"name" mapstructure:"name"`
+	Valid bool   `json:"valid" structs:"valid" mapstructure:"valid"`
+
+	// Error, if set, is the reason the code could not be validated
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
 func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 	code := data.Get("code").(string)
 
+	valid, userErr, err := b.validateCode(ctx, req.Storage, name, code)
+	if err != nil {
+		return nil, err
+	}
+	if userErr != nil {
+		return logical.ErrorResponse(userErr.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"valid": valid,
+		},
+	}, nil
+}
+
+func (b *backend) pathBatchValidateCode(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var items []batchValidateItem
+	if err := mapstructure.Decode(data.Get("batch_input"), &items); err != nil {
+		return logical.ErrorResponse(fmt.Sprintf("failed to parse batch input: %s", err)), logical.ErrInvalidRequest
+	}
+	if len(items) == 0 {
+		return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+	}
+
+	results := make([]batchValidateResult, len(items))
+	for i, item := range items {
+		results[i].Name = item.Name
+
+		if item.Name == "" {
+			results[i].Error = "the name value is required"
+			continue
+		}
+
+		valid, userErr, err := b.validateCode(ctx, req.Storage, item.Name, item.Code)
+		if err != nil {
+			return nil, err
+		}
+		if userErr != nil {
+			results[i].Error = userErr.Error()
+			continue
+		}
+		results[i].Valid = valid
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"batch_results": results,
+		},
+	}, nil
+}
+
+// validateCode validates a code against the named key, rejecting the codes
+// already used and enforcing the lockout of the key after too many failed
+// validations. The returned user error, if any, is the reason the request
+// cannot be served.
+func (b *backend) validateCode(ctx context.Context, s logical.Storage, name, code string) (bool, error, error) {
 	// Enforce input value requirements
 	if code == "" {
-		return logical.ErrorResponse("the code value is required"), nil
+		return false, errors.New("the code value is required"), nil
 	}
 
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Get the key's stored values
-	key, err := b.Key(ctx, req.Storage, name)
+	key, err := b.Key(ctx, s, name)
 	if err != nil {
-		return nil, err
+		return false, nil, err
 	}
 	if key == nil {
-		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+		return false, fmt.Errorf("unknown key: %s", name), nil
+	}
+
+	lockout, err := b.lockout(ctx, s, name)
+	if err != nil {
+		return false, nil, err
+	}
+
+	now := time.Now()
+	if lockout.locked(now) {
+		return false, fmt.Errorf("key is locked until %s after too many failed validations", lockout.LockedUntil.Format(time.RFC3339)), nil
 	}
 
 	usedName := fmt.Sprintf("%s_%s", name, code)
 
 	_, ok := b.usedCodes.Get(usedName)
 	if ok {
-		return logical.ErrorResponse("code already used; wait until the next time period"), nil
+		return false, errors.New("code already used; wait until the next time period"), nil
 	}
 
-	valid, err := totplib.ValidateCustom(code, key.Key, time.Now(), totplib.ValidateOpts{
+	valid, err := totplib.ValidateCustom(code, key.Key, now, totplib.ValidateOpts{
 		Period:    key.Period,
 		Skew:      key.Skew,
 		Digits:    key.Digits,
 		Algorithm: key.Algorithm,
 	})
 	if err != nil && err != otplib.ErrValidateInputInvalidLength {
-		return logical.ErrorResponse("an error occurred while validating the code"), err
+		return false, nil, errwrap.Wrapf("an error occurred while validating the code: {{err}}", err)
 	}
 
-	// Take the key skew, add two for behind and in front, and multiple that by
-	// the period to cover the full possibility of the validity of the key
+	// A code is accepted for the periods within the skew behind and in front
+	// of its own, so take twice the key skew, add two for its own period and
+	// the partial one it may be first used in, and multiply that by the
+	// period to cover the full possibility of the validity of the key
 	err = b.usedCodes.Add(usedName, nil, time.Duration(
 		int64(time.Second)*
 			int64(key.Period)*
-			int64((2+key.Skew))))
+			int64((2+2*key.Skew))))
 	if err != nil {
-		return nil, errwrap.Wrapf("error adding code to used cache: {{err}}", err)
+		return false, nil, errwrap.Wrapf("error adding code to used cache: {{err}}", err)
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"valid": valid,
-		},
-	}, nil
+	if err := b.recordValidation(ctx, s, name, key, lockout, valid, now); err != nil {
+		return false, nil, err
+	}
+
+	return valid, nil, nil
 }
 
 const pathCodeHelpSyn = `
@@ -124,4 +236,19 @@ Request time-based one-time use password or validate a password for a certain ke
 const pathCodeHelpDesc = `
 This path generates and validates time-based one-time use passwords for a certain key. 
 
+Once the number of failed validations of a key reaches its
+max_failed_validations value, the validations of the codes of the key are
+rejected for its lockout_duration.
+`
+
+const pathBatchCodeHelpSyn = `
+Validate a batch of time-based one-time use passwords.
+`
+
+const pathBatchCodeHelpDesc = `
+This path validates many time-based one-time use passwords in a single request,
+each against the key it names, for instance when migrating users from another
+service. Each password is validated as if on its own, including the rejection
+of used passwords and the lockout of keys after too many failed validations,
+and the results are returned in the order of the batch input.
 `
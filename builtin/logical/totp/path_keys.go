@@ -10,14 +10,26 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	otplib "github.com/pquerna/otp"
 	totplib "github.com/pquerna/otp/totp"
 )
 
+const (
+	// maxSkew is the largest number of periods a key can accept codes from
+	// behind and in front of the current one
+	maxSkew = 10
+
+	// defaultLockoutDuration is how long a key is locked for once it reaches
+	// its max_failed_validations value
+	defaultLockoutDuration = 15 * time.Minute
+)
+
 func pathListKeys(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/?$",
@@ -94,7 +106,19 @@ func pathKeys(b *backend) *framework.Path {
 			"skew": {
 				Type:        framework.TypeInt,
 				Default:     1,
-				Description: `The number of delay periods that are allowed when validating a TOTP token. This value can either be 0 or 1. Only used if generate is true.`,
+				Description: `The number of delay periods that are allowed when validating a TOTP token. This value can be between 0 and 10.`,
+			},
+
+			"max_failed_validations": {
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: `The number of failed validations after which the key is locked. If this value is 0, the key is never locked.`,
+			},
+
+			"lockout_duration": {
+				Type:        framework.TypeDurationSecond,
+				Default:     int(defaultLockoutDuration.Seconds()),
+				Description: `The length of time the key is locked for once it reaches max_failed_validations.`,
 			},
 
 			"qr_size": {
@@ -138,7 +162,18 @@ func (b *backend) Key(ctx context.Context, s logical.Storage, n string) (*keyEnt
 }
 
 func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "key/"+data.Get("name").(string))
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := req.Storage.Delete(ctx, "key/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = req.Storage.Delete(ctx, "lockout/"+name)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +182,13 @@ func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data
 }
 
 func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	key, err := b.Key(ctx, req.Storage, data.Get("name").(string))
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	key, err := b.Key(ctx, req.Storage, name)
 	if err != nil {
 		return nil, err
 	}
@@ -155,18 +196,31 @@ func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *f
 		return nil, nil
 	}
 
+	lockout, err := b.lockout(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
 	// Translate algorithm back to string
 	algorithm := key.Algorithm.String()
 
 	// Return values of key
+	respData := map[string]interface{}{
+		"issuer":                 key.Issuer,
+		"account_name":           key.AccountName,
+		"period":                 key.Period,
+		"algorithm":              algorithm,
+		"digits":                 key.Digits,
+		"skew":                   key.Skew,
+		"max_failed_validations": key.MaxFailedValidations,
+		"lockout_duration":       int64(key.LockoutDuration.Seconds()),
+	}
+	for k, v := range lockout.lockoutData(time.Now()) {
+		respData[k] = v
+	}
+
 	return &logical.Response{
-		Data: map[string]interface{}{
-			"issuer":       key.Issuer,
-			"account_name": key.AccountName,
-			"period":       key.Period,
-			"algorithm":    algorithm,
-			"digits":       key.Digits,
-		},
+		Data: respData,
 	}, nil
 }
 
@@ -190,6 +244,8 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 	algorithm := data.Get("algorithm").(string)
 	digits := data.Get("digits").(int)
 	skew := data.Get("skew").(int)
+	maxFailedValidations := data.Get("max_failed_validations").(int)
+	lockoutDuration := data.Get("lockout_duration").(int)
 	qrSize := data.Get("qr_size").(int)
 	keySize := data.Get("key_size").(int)
 	inputURL := data.Get("url").(string)
@@ -291,11 +347,8 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		return logical.ErrorResponse("the period value must be greater than zero"), nil
 	}
 
-	switch skew {
-	case 0:
-	case 1:
-	default:
-		return logical.ErrorResponse("the skew value must be 0 or 1"), nil
+	if resp := validateValidationParams(skew, maxFailedValidations, lockoutDuration); resp != nil {
+		return resp, nil
 	}
 
 	// QR size can be zero but it shouldn't be negative
@@ -392,6 +445,9 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		Algorithm:   keyAlgorithm,
 		Digits:      keyDigits,
 		Skew:        uintSkew,
+
+		MaxFailedValidations: maxFailedValidations,
+		LockoutDuration:      time.Duration(lockoutDuration) * time.Second,
 	})
 	if err != nil {
 		return nil, err
@@ -411,8 +467,128 @@ type keyEntry struct {
 	Algorithm   otplib.Algorithm `json:"algorithm" mapstructure:"algorithm" structs:"algorithm"`
 	Digits      otplib.Digits    `json:"digits" mapstructure:"digits" structs:"digits"`
 	Skew        uint             `json:"skew" mapstructure:"skew" structs:"skew"`
+
+	MaxFailedValidations int           `json:"max_failed_validations" mapstructure:"max_failed_validations" structs:"max_failed_validations"`
+	LockoutDuration      time.Duration `json:"lockout_duration" mapstructure:"lockout_duration" structs:"lockout_duration"`
+}
+
+// validateValidationParams checks the parameters of a key controlling the
+// validation of its codes, returning an error response if one is invalid.
+func validateValidationParams(skew, maxFailedValidations, lockoutDuration int) *logical.Response {
+	if skew < 0 || skew > maxSkew {
+		return logical.ErrorResponse(fmt.Sprintf("the skew value must be between 0 and %d", maxSkew))
+	}
+
+	if maxFailedValidations < 0 {
+		return logical.ErrorResponse("the max_failed_validations value must be greater than or equal to zero")
+	}
+
+	if lockoutDuration <= 0 {
+		return logical.ErrorResponse("the lockout_duration value must be greater than zero")
+	}
+
+	return nil
+}
+
+func pathKeyConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameWithAtRegex("name") + "/config",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key.",
+			},
+
+			"skew": {
+				Type:        framework.TypeInt,
+				Description: `The number of delay periods that are allowed when validating a TOTP token. This value can be between 0 and 10.`,
+			},
+
+			"max_failed_validations": {
+				Type:        framework.TypeInt,
+				Description: `The number of failed validations after which the key is locked. If this value is 0, the key is never locked.`,
+			},
+
+			"lockout_duration": {
+				Type:        framework.TypeDurationSecond,
+				Description: `The length of time the key is locked for once it reaches max_failed_validations.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathKeyConfigWrite,
+		},
+
+		HelpSynopsis:    pathKeyConfigHelpSyn,
+		HelpDescription: pathKeyConfigHelpDesc,
+	}
+}
+
+func (b *backend) pathKeyConfigWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	key, err := b.Key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	// Keys created before the lockout was introduced have no lockout duration
+	if key.LockoutDuration == 0 {
+		key.LockoutDuration = defaultLockoutDuration
+	}
+
+	skew := int(key.Skew)
+	if skewRaw, ok := data.GetOk("skew"); ok {
+		skew = skewRaw.(int)
+	}
+
+	maxFailedValidations := key.MaxFailedValidations
+	if maxFailedValidationsRaw, ok := data.GetOk("max_failed_validations"); ok {
+		maxFailedValidations = maxFailedValidationsRaw.(int)
+	}
+
+	lockoutDuration := int(key.LockoutDuration.Seconds())
+	if lockoutDurationRaw, ok := data.GetOk("lockout_duration"); ok {
+		lockoutDuration = lockoutDurationRaw.(int)
+	}
+
+	if resp := validateValidationParams(skew, maxFailedValidations, lockoutDuration); resp != nil {
+		return resp, nil
+	}
+
+	key.Skew = uint(skew)
+	key.MaxFailedValidations = maxFailedValidations
+	key.LockoutDuration = time.Duration(lockoutDuration) * time.Second
+
+	entry, err := logical.StorageEntryJSON("key/"+name, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Storage.Put(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
 }
 
+const pathKeyConfigHelpSyn = `
+Configure the validation of the codes of a key.
+`
+
+const pathKeyConfigHelpDesc = `
+This path updates the number of delay periods allowed when validating the
+codes of a key, and the number of failed validations after which the key is
+locked along with how long it is locked for. Parameters which are not given
+keep their current value.
+`
+
 const pathKeyHelpSyn = `
 Manage the keys that can be created with this backend.
 `
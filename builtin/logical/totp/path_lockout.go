@@ -0,0 +1,152 @@
+package totp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathKeyLockout(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "keys/" + framework.GenericNameWithAtRegex("name") + "/lockout",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathKeyLockoutRead,
+			logical.DeleteOperation: b.pathKeyLockoutDelete,
+		},
+
+		HelpSynopsis:    pathKeyLockoutHelpSyn,
+		HelpDescription: pathKeyLockoutHelpDesc,
+	}
+}
+
+// lockoutEntry tracks the failed validations of the codes of a key
+type lockoutEntry struct {
+	FailedValidations int       `json:"failed_validations"`
+	LockedUntil       time.Time `json:"locked_until"`
+}
+
+func (l *lockoutEntry) locked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
+
+// lockoutData returns the lockout state of a key to be returned in a
+// response.
+func (l *lockoutEntry) lockoutData(now time.Time) map[string]interface{} {
+	data := map[string]interface{}{
+		"failed_validations": l.FailedValidations,
+		"locked":             l.locked(now),
+		"locked_until":       "",
+	}
+	if l.locked(now) {
+		data["locked_until"] = l.LockedUntil.Format(time.RFC3339)
+	}
+	return data
+}
+
+// lockout returns the lockout state of the named key. The lock of the key
+// should be held.
+func (b *backend) lockout(ctx context.Context, s logical.Storage, name string) (*lockoutEntry, error) {
+	entry, err := s.Get(ctx, "lockout/"+name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result lockoutEntry
+	if entry == nil {
+		return &result, nil
+	}
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// recordValidation updates the lockout state of the named key with the
+// result of a validation, locking the key once its failed validations reach
+// its max_failed_validations value. The lock of the key should be held.
+func (b *backend) recordValidation(ctx context.Context, s logical.Storage, name string, key *keyEntry, lockout *lockoutEntry, valid bool, now time.Time) error {
+	switch {
+	case valid && lockout.FailedValidations == 0:
+		return nil
+
+	case valid:
+		return s.Delete(ctx, "lockout/"+name)
+
+	case key.MaxFailedValidations <= 0:
+		return nil
+	}
+
+	lockout.FailedValidations++
+	if lockout.FailedValidations >= key.MaxFailedValidations {
+		b.Logger().Warn("locking key after too many failed validations", "name", name, "failed_validations", lockout.FailedValidations)
+		lockout.FailedValidations = 0
+		lockout.LockedUntil = now.Add(key.LockoutDuration)
+	}
+
+	entry, err := logical.StorageEntryJSON("lockout/"+name, lockout)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func (b *backend) pathKeyLockoutRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	key, err := b.Key(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
+	}
+
+	lockout, err := b.lockout(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: lockout.lockoutData(time.Now()),
+	}, nil
+}
+
+func (b *backend) pathKeyLockoutDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := req.Storage.Delete(ctx, "lockout/"+name); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+const pathKeyLockoutHelpSyn = `
+Read or reset the lockout state of a key.
+`
+
+const pathKeyLockoutHelpDesc = `
+This path returns the number of failed validations of the codes of a key and
+whether the key is locked. Deleting it resets the failed validations and
+unlocks the key.
+`
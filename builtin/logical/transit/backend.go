@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -41,12 +42,21 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
+			b.pathBYOKExportKeys(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
 			b.pathDatakey(),
 			b.pathRandom(),
 			b.pathHash(),
 			b.pathHMAC(),
+			// Streams need to come before Sign/Verify
+			// as the handler is greedy
+			b.pathStreamStart(streamOperationSign),
+			b.pathStreamUpdate(streamOperationSign),
+			b.pathStreamFinish(streamOperationSign),
+			b.pathStreamStart(streamOperationVerify),
+			b.pathStreamUpdate(streamOperationVerify),
+			b.pathStreamFinish(streamOperationVerify),
 			b.pathSign(),
 			b.pathVerify(),
 			b.pathBackup(),
@@ -55,9 +65,10 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathCacheConfig(),
 		},
 
-		Secrets:     []*framework.Secret{},
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		Secrets:      []*framework.Secret{},
+		Invalidate:   b.invalidate,
+		PeriodicFunc: b.periodicFunc,
+		BackendType:  logical.TypeLogical,
 	}
 
 	// determine cacheSize to use. Defaults to 0 which means unlimited
@@ -76,6 +87,7 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 	if err != nil {
 		return nil, err
 	}
+	b.streamLocks = locksutil.CreateLocks()
 
 	return &b, nil
 }
@@ -83,6 +95,9 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 type backend struct {
 	*framework.Backend
 	lm *keysutil.LockManager
+
+	// streamLocks serialize the requests to each sign or verify stream
+	streamLocks []*locksutil.LockEntry
 }
 
 func GetCacheSizeFromStorage(ctx context.Context, s logical.Storage) (int, error) {
@@ -101,6 +116,10 @@ func GetCacheSizeFromStorage(ctx context.Context, s logical.Storage) (int, error
 	return size, nil
 }
 
+func (b *backend) periodicFunc(ctx context.Context, req *logical.Request) error {
+	return b.cleanupExpiredStreams(ctx, req.Storage)
+}
+
 func (b *backend) invalidate(_ context.Context, key string) {
 	if b.Logger().IsDebug() {
 		b.Logger().Debug("invalidating key", "key", key)
@@ -0,0 +1,329 @@
+package transit
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// byokECDHInfo is the HKDF info string used to derive the key-encryption key
+// from an ECDH shared secret.
+const byokECDHInfo = "vault transit byok-export"
+
+func (b *backend) pathBYOKExportKeys() *framework.Path {
+	return &framework.Path{
+		Pattern: "byok-export/" + framework.GenericNameRegex("destination") + "/" + framework.GenericNameRegex("source") + framework.OptionalParamRegex("version"),
+		Fields: map[string]*framework.FieldSchema{
+			"destination": {
+				Type:        framework.TypeString,
+				Description: "Name of the RSA or ECDSA key whose public key the exported key is wrapped with",
+			},
+			"source": {
+				Type:        framework.TypeString,
+				Description: "Name of the key to export",
+			},
+			"version": {
+				Type:        framework.TypeString,
+				Description: "Version of the key to export",
+			},
+			"hash": {
+				Type:    framework.TypeString,
+				Default: "sha2-256",
+				Description: `Hash function used with RSA-OAEP or HKDF when wrapping the key.
+Valid values are sha2-224, sha2-256, sha2-384 and sha2-512.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathPolicyBYOKExportRead,
+		},
+
+		HelpSynopsis:    pathBYOKExportHelpSyn,
+		HelpDescription: pathBYOKExportHelpDesc,
+	}
+}
+
+func (b *backend) pathPolicyBYOKExportRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	dstName := d.Get("destination").(string)
+	srcName := d.Get("source").(string)
+	version := d.Get("version").(string)
+
+	hashStr := d.Get("hash").(string)
+	hashType, ok := keysutil.HashTypeMap[hashStr]
+	if !ok || hashType == keysutil.HashTypeSHA1 {
+		return logical.ErrorResponse(fmt.Sprintf("invalid hash %q", hashStr)), logical.ErrInvalidRequest
+	}
+	hashFunc := keysutil.HashFuncMap[hashType]
+
+	dst, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    dstName,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if dst == nil {
+		return logical.ErrorResponse("destination key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		dst.Lock(false)
+	}
+	dstKey, ok := dst.Keys[strconv.Itoa(dst.LatestVersion)]
+	dstType := dst.Type
+	dst.Unlock()
+	if !ok {
+		return nil, errors.New("destination key has no versions")
+	}
+
+	var wrap func([]byte) (string, error)
+	switch dstType {
+	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+		pub := &dstKey.RSAKey.PublicKey
+		wrap = func(material []byte) (string, error) {
+			return wrapRSAAESKeyWrap(pub, hashFunc, material)
+		}
+
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+		pub := &ecdsa.PublicKey{
+			Curve: ecdsaCurve(dstType),
+			X:     dstKey.EC_X,
+			Y:     dstKey.EC_Y,
+		}
+		wrap = func(material []byte) (string, error) {
+			return wrapECDHAESKeyWrap(pub, hashFunc, material)
+		}
+
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("key type %v cannot be used to wrap keys", dstType)), logical.ErrInvalidRequest
+	}
+
+	src, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    srcName,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if src == nil {
+		return logical.ErrorResponse("source key not found"), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		src.Lock(false)
+	}
+	defer src.Unlock()
+
+	if !src.Exportable && !src.ExportableWrapped {
+		return logical.ErrorResponse("key is not exportable"), logical.ErrInvalidRequest
+	}
+
+	versions := map[string]keysutil.KeyEntry{}
+	switch version {
+	case "":
+		for k, v := range src.Keys {
+			versions[k] = v
+		}
+
+	default:
+		var versionValue int
+		if version == "latest" {
+			versionValue = src.LatestVersion
+		} else {
+			version = strings.TrimPrefix(version, "v")
+			versionValue, err = strconv.Atoi(version)
+			if err != nil {
+				return logical.ErrorResponse("invalid key version"), logical.ErrInvalidRequest
+			}
+		}
+
+		if versionValue < src.MinDecryptionVersion {
+			return logical.ErrorResponse("version for export is below minimum decryption version"), logical.ErrInvalidRequest
+		}
+		key, ok := src.Keys[strconv.Itoa(versionValue)]
+		if !ok {
+			return logical.ErrorResponse("version does not exist or cannot be found"), logical.ErrInvalidRequest
+		}
+		versions[strconv.Itoa(versionValue)] = key
+	}
+
+	retKeys := map[string]string{}
+	for k, v := range versions {
+		material, err := getBYOKKeyMaterial(src, &v)
+		if err != nil {
+			return nil, err
+		}
+		retKeys[k], err = wrap(material)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name": src.Name,
+			"type": src.Type.String(),
+			"keys": retKeys,
+		},
+	}, nil
+}
+
+// getBYOKKeyMaterial returns the key material to wrap: the raw key for
+// symmetric keys, and the PKCS#8 encoding of the private key otherwise.
+func getBYOKKeyMaterial(policy *keysutil.Policy, key *keysutil.KeyEntry) ([]byte, error) {
+	switch policy.Type {
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
+		return key.Key, nil
+
+	case keysutil.KeyType_ED25519:
+		return x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(key.Key))
+
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+		return x509.MarshalPKCS8PrivateKey(&ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: ecdsaCurve(policy.Type),
+				X:     key.EC_X,
+				Y:     key.EC_Y,
+			},
+			D: key.EC_D,
+		})
+
+	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+		return x509.MarshalPKCS8PrivateKey(key.RSAKey)
+	}
+
+	return nil, fmt.Errorf("unknown key type %v", policy.Type)
+}
+
+func ecdsaCurve(keyType keysutil.KeyType) elliptic.Curve {
+	switch keyType {
+	case keysutil.KeyType_ECDSA_P384:
+		return elliptic.P384()
+	case keysutil.KeyType_ECDSA_P521:
+		return elliptic.P521()
+	default:
+		return elliptic.P256()
+	}
+}
+
+// wrapRSAAESKeyWrap wraps the key material as with PKCS#11's
+// CKM_RSA_AES_KEY_WRAP mechanism: an ephemeral AES-256 key is encrypted with
+// RSA-OAEP, and wraps the material with AES-KWP. The result is the
+// concatenation of the two ciphertexts.
+func wrapRSAAESKeyWrap(pub *rsa.PublicKey, hashFunc func() hash.Hash, material []byte) (string, error) {
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, kek); err != nil {
+		return "", err
+	}
+
+	wrappedKEK, err := rsa.EncryptOAEP(hashFunc(), rand.Reader, pub, kek, nil)
+	if err != nil {
+		return "", err
+	}
+	wrappedMaterial, err := wrapKeyKWP(kek, material)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(append(wrappedKEK, wrappedMaterial...)), nil
+}
+
+// wrapECDHAESKeyWrap wraps the key material using ECDH with an ephemeral key
+// on the destination key's curve; the shared secret is expanded with HKDF
+// into an AES-256 key, which wraps the material with AES-KWP. The result is
+// the uncompressed ephemeral public key followed by the wrapped material.
+func wrapECDHAESKeyWrap(pub *ecdsa.PublicKey, hashFunc func() hash.Hash, material []byte) (string, error) {
+	ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	x, _ := pub.Curve.ScalarMult(pub.X, pub.Y, ephemeral.D.Bytes())
+	// The shared secret is the x-coordinate, left-padded to the size of the
+	// field
+	secret := make([]byte, (pub.Curve.Params().BitSize+7)/8)
+	xBytes := x.Bytes()
+	copy(secret[len(secret)-len(xBytes):], xBytes)
+
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(hashFunc, secret, nil, []byte(byokECDHInfo)), kek); err != nil {
+		return "", err
+	}
+	wrappedMaterial, err := wrapKeyKWP(kek, material)
+	if err != nil {
+		return "", err
+	}
+
+	ephemeralPub := elliptic.Marshal(pub.Curve, ephemeral.X, ephemeral.Y)
+	return base64.StdEncoding.EncodeToString(append(ephemeralPub, wrappedMaterial...)), nil
+}
+
+// kwpIV is the alternative initial value prefix of RFC 5649.
+var kwpIV = []byte{0xA6, 0x59, 0x59, 0xA6}
+
+// wrapKeyKWP wraps the plaintext with the kek using AES Key Wrap with
+// Padding, as specified in RFC 5649.
+func wrapKeyKWP(kek, plaintext []byte) ([]byte, error) {
+	if len(plaintext) == 0 {
+		return nil, errors.New("nothing to wrap")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := (len(plaintext) + 7) / 8
+	out := make([]byte, 8*(n+1))
+	copy(out, kwpIV)
+	binary.BigEndian.PutUint32(out[4:8], uint32(len(plaintext)))
+	copy(out[8:], plaintext)
+
+	// A single block is encrypted directly
+	if n == 1 {
+		block.Encrypt(out, out)
+		return out, nil
+	}
+
+	// Otherwise, use the wrapping process of RFC 3394
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, out[:8])
+			copy(buf[8:], out[8*i:8*i+8])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+const pathBYOKExportHelpSyn = `Securely export named encryption or signing key`
+
+const pathBYOKExportHelpDesc = `
+This path is used to export the named keys that are configured as exportable
+or exportable_wrapped, wrapped by the public key of the destination key so
+that their plaintext is never revealed. With an RSA destination key, the key
+is wrapped with RSA-OAEP and AES-KWP, as with PKCS#11's CKM_RSA_AES_KEY_WRAP
+mechanism. With an ECDSA destination key, it is wrapped with AES-KWP under a
+key derived with ECDH from an ephemeral key, which is returned before the
+wrapped key.
+`
@@ -0,0 +1,285 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/hkdf"
+)
+
+func TestTransit_WrapKeyKWP(t *testing.T) {
+	// Test vectors from RFC 5649, section 6
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	for _, tc := range []struct {
+		key      string
+		expected string
+	}{
+		{"c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{"466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		wrapped, err := wrapKeyKWP(kek, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := hex.EncodeToString(wrapped); actual != tc.expected {
+			t.Fatalf("expected %s, got %s", tc.expected, actual)
+		}
+
+		unwrapped := testUnwrapKeyKWP(t, kek, wrapped)
+		if !bytes.Equal(unwrapped, key) {
+			t.Fatalf("expected %x, got %x", key, unwrapped)
+		}
+	}
+}
+
+func TestTransit_BYOKExport(t *testing.T) {
+	for _, dstType := range []string{"rsa-2048", "ecdsa-p256", "ecdsa-p384"} {
+		for _, srcType := range []string{"aes256-gcm96", "chacha20-poly1305", "ed25519", "ecdsa-p256", "rsa-2048"} {
+			dstType, srcType := dstType, srcType
+			t.Run(dstType+"/"+srcType, func(t *testing.T) {
+				testTransit_BYOKExport(t, dstType, srcType)
+			})
+		}
+	}
+}
+
+func testTransit_BYOKExport(t *testing.T, dstType, srcType string) {
+	b, storage := createBackendWithSysView(t)
+	ctx := context.Background()
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s\nresp: %#v\nerr: %v", path, resp, err)
+		}
+		return resp
+	}
+
+	doReq(logical.UpdateOperation, "keys/dst", map[string]interface{}{
+		"type": dstType,
+	})
+	doReq(logical.UpdateOperation, "keys/src", map[string]interface{}{
+		"type":               srcType,
+		"exportable_wrapped": true,
+	})
+	doReq(logical.UpdateOperation, "keys/src/rotate", nil)
+
+	// The key cannot be exported in plaintext
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "export/" + exportTypeForKeyType(srcType) + "/src",
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatalf("expected plaintext export to fail, got %#v", resp)
+	}
+
+	resp = doReq(logical.ReadOperation, "keys/src", nil)
+	if !resp.Data["exportable_wrapped"].(bool) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	resp = doReq(logical.ReadOperation, "byok-export/dst/src", nil)
+	keys := resp.Data["keys"].(map[string]string)
+	if len(keys) != 2 {
+		t.Fatalf("expected both versions, got %#v", keys)
+	}
+
+	resp = doReq(logical.ReadOperation, "byok-export/dst/src/1", nil)
+	if keys := resp.Data["keys"].(map[string]string); len(keys) != 1 || keys["1"] == "" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    "dst",
+	}, b.GetRandomReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstKey := p.Keys["1"]
+
+	src, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: storage,
+		Name:    "src",
+	}, b.GetRandomReader())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for version, wrapped := range keys {
+		raw, err := base64.StdEncoding.DecodeString(wrapped)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var kek, wrappedMaterial []byte
+		switch dstType {
+		case "rsa-2048":
+			size := dstKey.RSAKey.Size()
+			kek, err = rsa.DecryptOAEP(sha256.New(), nil, dstKey.RSAKey, raw[:size], nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wrappedMaterial = raw[size:]
+
+		default:
+			curve := ecdsaCurve(p.Type)
+			pointLen := 1 + 2*((curve.Params().BitSize+7)/8)
+			x, y := elliptic.Unmarshal(curve, raw[:pointLen])
+			if x == nil {
+				t.Fatal("invalid ephemeral public key")
+			}
+			sx, _ := curve.ScalarMult(x, y, dstKey.EC_D.Bytes())
+			secret := make([]byte, (curve.Params().BitSize+7)/8)
+			sxBytes := sx.Bytes()
+			copy(secret[len(secret)-len(sxBytes):], sxBytes)
+			kek = make([]byte, 32)
+			if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(byokECDHInfo)), kek); err != nil {
+				t.Fatal(err)
+			}
+			wrappedMaterial = raw[pointLen:]
+		}
+
+		material := testUnwrapKeyKWP(t, kek, wrappedMaterial)
+		key := src.Keys[version]
+		expected, err := getBYOKKeyMaterial(src, &key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(material, expected) {
+			t.Fatalf("version %s: unwrapped key material does not match", version)
+		}
+
+		switch srcType {
+		case "aes256-gcm96", "chacha20-poly1305":
+			if !bytes.Equal(material, key.Key) {
+				t.Fatalf("version %s: unwrapped key does not match", version)
+			}
+		default:
+			priv, err := x509.ParsePKCS8PrivateKey(material)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if srcType == "ecdsa-p256" && priv.(*ecdsa.PrivateKey).D.Cmp(key.EC_D) != 0 {
+				t.Fatalf("version %s: unwrapped key does not match", version)
+			}
+		}
+	}
+}
+
+func TestTransit_BYOKExport_Errors(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+	ctx := context.Background()
+
+	for name, data := range map[string]map[string]interface{}{
+		"dst":        {"type": "rsa-2048"},
+		"aes":        {"type": "aes256-gcm96"},
+		"private":    {"type": "aes256-gcm96"},
+		"exportable": {"type": "aes256-gcm96", "exportable": true},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+	}
+
+	for path, shouldFail := range map[string]bool{
+		// Exportable keys may also be exported wrapped
+		"byok-export/dst/exportable": false,
+		// Keys must be marked as exportable
+		"byok-export/dst/private": true,
+		// Only asymmetric keys can be used to wrap
+		"byok-export/aes/exportable": true,
+		// The destination key must exist
+		"byok-export/missing/exportable": true,
+		// The source key must exist
+		"byok-export/dst/missing": true,
+		// Only known versions can be exported
+		"byok-export/dst/exportable/2": true,
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.ReadOperation,
+			Path:      path,
+		})
+		failed := err != nil || (resp != nil && resp.IsError())
+		if failed != shouldFail {
+			t.Fatalf("%s: expected failure %t, got resp: %#v\nerr: %v", path, shouldFail, resp, err)
+		}
+		if failed && (err != logical.ErrInvalidRequest || resp == nil || !resp.IsError()) {
+			t.Fatalf("%s: expected an error response for an invalid request, got resp: %#v\nerr: %v", path, resp, err)
+		}
+	}
+}
+
+func exportTypeForKeyType(keyType string) string {
+	switch keyType {
+	case "aes256-gcm96", "chacha20-poly1305":
+		return exportTypeEncryptionKey
+	}
+	return exportTypeSigningKey
+}
+
+// testUnwrapKeyKWP reverses wrapKeyKWP, as specified in RFC 5649.
+func testUnwrapKeyKWP(t *testing.T, kek, wrapped []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	if n == 1 {
+		block.Decrypt(out, out)
+	} else {
+		buf := make([]byte, 16)
+		for j := 5; j >= 0; j-- {
+			for i := n; i >= 1; i-- {
+				step := uint64(n*j + i)
+				binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8])^step)
+				copy(buf[8:], out[8*i:8*i+8])
+				block.Decrypt(buf, buf)
+				copy(out[:8], buf[:8])
+				copy(out[8*i:], buf[8:])
+			}
+		}
+	}
+
+	if !bytes.Equal(out[:4], kwpIV) {
+		t.Fatalf("bad integrity check value: %x", out[:8])
+	}
+	length := int(binary.BigEndian.Uint32(out[4:8]))
+	if length > 8*n || length <= 8*(n-1) {
+		t.Fatalf("bad message length: %d", length)
+	}
+	return out[8 : 8+length]
+}
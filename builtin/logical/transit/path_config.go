@@ -43,6 +43,11 @@ the latest version of the key is allowed.`,
 				Description: `Enables export of the key. Once set, this cannot be disabled.`,
 			},
 
+			"exportable_wrapped": &framework.FieldSchema{
+				Type:        framework.TypeBool,
+				Description: `Enables export of the key when wrapped by another key with the byok-export endpoint. Once set, this cannot be disabled.`,
+			},
+
 			"allow_plaintext_backup": &framework.FieldSchema{
 				Type:        framework.TypeBool,
 				Description: `Enables taking a backup of the named key in plaintext format. Once set, this cannot be disabled.`,
@@ -175,6 +180,16 @@ func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *
 		}
 	}
 
+	exportableWrappedRaw, ok := d.GetOk("exportable_wrapped")
+	if ok {
+		exportableWrapped := exportableWrappedRaw.(bool)
+		// Don't unset the already set value
+		if exportableWrapped && !p.ExportableWrapped {
+			p.ExportableWrapped = exportableWrapped
+			persistNeeded = true
+		}
+	}
+
 	allowPlaintextBackupRaw, ok := d.GetOk("allow_plaintext_backup")
 	if ok {
 		allowPlaintextBackup := allowPlaintextBackupRaw.(bool)
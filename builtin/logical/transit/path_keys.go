@@ -81,6 +81,14 @@ This allows for all the valid keys
 in the key ring to be exported.`,
 			},
 
+			"exportable_wrapped": &framework.FieldSchema{
+				Type: framework.TypeBool,
+				Description: `Enables keys to be exported when
+wrapped by another key with the
+byok-export endpoint, without
+revealing their plaintext.`,
+			},
+
 			"allow_plaintext_backup": &framework.FieldSchema{
 				Type: framework.TypeBool,
 				Description: `Enables taking a backup of the named
@@ -123,6 +131,7 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 	convergent := d.Get("convergent_encryption").(bool)
 	keyType := d.Get("type").(string)
 	exportable := d.Get("exportable").(bool)
+	exportableWrapped := d.Get("exportable_wrapped").(bool)
 	allowPlaintextBackup := d.Get("allow_plaintext_backup").(bool)
 
 	if !derived && convergent {
@@ -136,6 +145,7 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		Derived:              derived,
 		Convergent:           convergent,
 		Exportable:           exportable,
+		ExportableWrapped:    exportableWrapped,
 		AllowPlaintextBackup: allowPlaintextBackup,
 	}
 	switch keyType {
@@ -219,6 +229,7 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 			"min_encryption_version": p.MinEncryptionVersion,
 			"latest_version":         p.LatestVersion,
 			"exportable":             p.Exportable,
+			"exportable_wrapped":     p.ExportableWrapped,
 			"allow_plaintext_backup": p.AllowPlaintextBackup,
 			"supports_encryption":    p.Type.EncryptionSupported(),
 			"supports_decryption":    p.Type.DecryptionSupported(),
@@ -91,7 +91,7 @@ derivation is enabled; currently only available with ed25519 keys.`,
 * sha2-512
 
 Defaults to "sha2-256". Not valid for all key types,
-including ed25519.`,
+including ed25519, unless the input is prehashed.`,
 			},
 
 			"algorithm": {
@@ -114,7 +114,7 @@ to the min_encryption_version configured on the key.`,
 
 			"prehashed": {
 				Type:        framework.TypeBool,
-				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
+				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter. If the key type is 'ed25519', the input must be a sha2-512 digest and an Ed25519ph signature is used.`,
 			},
 
 			"signature_algorithm": {
@@ -196,7 +196,7 @@ Defaults to "sha2-256". Not valid for all key types.`,
 
 			"prehashed": {
 				Type:        framework.TypeBool,
-				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter.`,
+				Description: `Set to 'true' when the input is already hashed. If the key type is 'rsa-2048', 'rsa-3072' or 'rsa-4096', then the algorithm used to hash the input should be indicated by the 'algorithm' parameter. If the key type is 'ed25519', the input must be a sha2-512 digest and an Ed25519ph signature is used.`,
 			},
 
 			"signature_algorithm": {
@@ -323,7 +323,12 @@ func (b *backend) pathSignWrite(ctx context.Context, req *logical.Request, d *fr
 			}
 		}
 
-		sig, err := p.Sign(ver, context, input, hashAlgorithm, sigAlgorithm, marshaling)
+		sig, err := p.SignWithOptions(ver, context, input, &keysutil.SigningOptions{
+			HashAlgorithm: hashAlgorithm,
+			Marshaling:    marshaling,
+			SigAlgorithm:  sigAlgorithm,
+			Prehashed:     prehashed,
+		})
 		if err != nil {
 			if batchInputRaw != nil {
 				response[i].Error = err.Error()
@@ -526,7 +531,12 @@ func (b *backend) pathVerifyWrite(ctx context.Context, req *logical.Request, d *
 			}
 		}
 
-		valid, err := p.VerifySignature(context, input, hashAlgorithm, sigAlgorithm, marshaling, sig)
+		valid, err := p.VerifySignatureWithOptions(context, input, sig, &keysutil.SigningOptions{
+			HashAlgorithm: hashAlgorithm,
+			Marshaling:    marshaling,
+			SigAlgorithm:  sigAlgorithm,
+			Prehashed:     prehashed,
+		})
 		if err != nil {
 			switch err.(type) {
 			case errutil.UserError:
@@ -0,0 +1,536 @@
+package transit
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// streamPrefix is the storage prefix of the in-progress streams.
+	streamPrefix = "stream/"
+
+	// streamTTL is how long a stream may go without an update before it
+	// expires.
+	streamTTL = time.Hour
+
+	streamOperationSign   = "sign"
+	streamOperationVerify = "verify"
+)
+
+// signStream holds the state of a streaming sign or verify operation. The
+// input is hashed as it arrives, and only the hash state is kept between
+// requests, so that the size of the input is not limited by the size of a
+// request body.
+type signStream struct {
+	Operation          string                  `json:"operation"`
+	KeyName            string                  `json:"key_name"`
+	KeyVersion         int                     `json:"key_version"`
+	Context            []byte                  `json:"context"`
+	HashAlgorithm      keysutil.HashType       `json:"hash_algorithm"`
+	SignatureAlgorithm string                  `json:"signature_algorithm"`
+	Marshaling         keysutil.MarshalingType `json:"marshaling"`
+	HashState          []byte                  `json:"hash_state"`
+	Length             int64                   `json:"length"`
+	Expiration         time.Time               `json:"expiration"`
+
+	// ClientToken is the token which started the stream, salted by the
+	// router, as only that token may use the stream
+	ClientToken string `json:"client_token"`
+}
+
+func (s *signStream) hash() (hash.Hash, error) {
+	h := keysutil.HashFuncMap[s.HashAlgorithm]()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(s.HashState); err != nil {
+		return nil, errwrap.Wrapf("failed to restore hash state: {{err}}", err)
+	}
+	return h, nil
+}
+
+func (s *signStream) setHash(h hash.Hash) error {
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return errwrap.Wrapf("failed to save hash state: {{err}}", err)
+	}
+	s.HashState = state
+	return nil
+}
+
+func (b *backend) pathStreamStart(operation string) *framework.Path {
+	p := &framework.Path{
+		Pattern: operation + "/" + framework.GenericNameRegex("name") + "/stream$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+
+			"context": {
+				Type: framework.TypeString,
+				Description: `Base64 encoded context for key derivation. Required if key
+derivation is enabled; currently only available with ed25519 keys.`,
+			},
+
+			"hash_algorithm": {
+				Type: framework.TypeString,
+				Description: `Hash algorithm to use. Valid values are:
+
+* sha1
+* sha2-224
+* sha2-256
+* sha2-384
+* sha2-512
+
+Defaults to "sha2-256", or to "sha2-512" for ed25519 keys, which only support
+sha2-512 and are signed with Ed25519ph.`,
+			},
+
+			"signature_algorithm": {
+				Type: framework.TypeString,
+				Description: `The signature algorithm to use. Currently only applies to RSA key types.
+Options are 'pss' or 'pkcs1v15'. Defaults to 'pss'`,
+			},
+
+			"marshaling_algorithm": {
+				Type:        framework.TypeString,
+				Default:     "asn1",
+				Description: `The method by which to marshal the signature, either 'asn1' or 'jws'. Currently only valid for ECDSA P-256 key types.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamStartWrite(operation),
+		},
+
+		HelpSynopsis:    pathStreamHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+
+	if operation == streamOperationSign {
+		p.Fields["key_version"] = &framework.FieldSchema{
+			Type: framework.TypeInt,
+			Description: `The version of the key to use for signing.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+		}
+	}
+
+	return p
+}
+
+func (b *backend) pathStreamUpdate(operation string) *framework.Path {
+	return &framework.Path{
+		Pattern: operation + "/" + framework.GenericNameRegex("name") + "/stream/" + framework.GenericNameRegex("stream_id") + "$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+
+			"stream_id": {
+				Type:        framework.TypeString,
+				Description: "The ID of the stream",
+			},
+
+			"input": {
+				Type:        framework.TypeString,
+				Description: "The base64-encoded chunk of input data to append to the stream",
+			},
+
+			"offset": {
+				Type: framework.TypeInt,
+				Description: `If set, the number of bytes of input the stream is expected to
+have received before this chunk. The chunk is rejected if it does not match,
+so that lost or repeated chunks are detected.`,
+				Default: -1,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamUpdateWrite(operation),
+			logical.DeleteOperation: b.pathStreamDelete(operation),
+		},
+
+		HelpSynopsis:    pathStreamHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathStreamFinish(operation string) *framework.Path {
+	p := &framework.Path{
+		Pattern: operation + "/" + framework.GenericNameRegex("name") + "/stream/" + framework.GenericNameRegex("stream_id") + "/finish$",
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "The key to use",
+			},
+
+			"stream_id": {
+				Type:        framework.TypeString,
+				Description: "The ID of the stream",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamFinishWrite(operation),
+		},
+
+		HelpSynopsis:    pathStreamHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+
+	if operation == streamOperationVerify {
+		p.Fields["signature"] = &framework.FieldSchema{
+			Type:        framework.TypeString,
+			Description: "The signature, including vault header/key version",
+		}
+	}
+
+	return p
+}
+
+func (b *backend) pathStreamStartWrite(operation string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		marshalingStr := d.Get("marshaling_algorithm").(string)
+		marshaling, ok := keysutil.MarshalingTypeMap[marshalingStr]
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("invalid marshaling type %q", marshalingStr)), logical.ErrInvalidRequest
+		}
+
+		var keyContext []byte
+		if contextRaw := d.Get("context").(string); contextRaw != "" {
+			var err error
+			keyContext, err = base64.StdEncoding.DecodeString(contextRaw)
+			if err != nil {
+				return logical.ErrorResponse("failed to base64-decode context"), logical.ErrInvalidRequest
+			}
+		}
+
+		p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+			Storage: req.Storage,
+			Name:    name,
+		}, b.GetRandomReader())
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+		}
+		if !b.System().CachingDisabled() {
+			p.Lock(false)
+		}
+		defer p.Unlock()
+
+		if !p.Type.SigningSupported() {
+			return logical.ErrorResponse(fmt.Sprintf("key type %v does not support %s", p.Type, operation)), logical.ErrInvalidRequest
+		}
+
+		// Ed25519 keys sign the SHA-512 digest of the stream with Ed25519ph
+		hashAlgorithmStr := d.Get("hash_algorithm").(string)
+		if hashAlgorithmStr == "" {
+			hashAlgorithmStr = "sha2-256"
+			if p.Type == keysutil.KeyType_ED25519 {
+				hashAlgorithmStr = "sha2-512"
+			}
+		}
+		hashAlgorithm, ok := keysutil.HashTypeMap[hashAlgorithmStr]
+		if !ok {
+			return logical.ErrorResponse(fmt.Sprintf("invalid hash algorithm %q", hashAlgorithmStr)), logical.ErrInvalidRequest
+		}
+		if p.Type == keysutil.KeyType_ED25519 && hashAlgorithm != keysutil.HashTypeSHA2512 {
+			return logical.ErrorResponse("ed25519 keys only support the sha2-512 hash algorithm"), logical.ErrInvalidRequest
+		}
+
+		var ver int
+		if operation == streamOperationSign {
+			ver = d.Get("key_version").(int)
+			switch {
+			case ver == 0:
+				ver = p.LatestVersion
+			case ver < 0 || ver > p.LatestVersion:
+				return logical.ErrorResponse("invalid key version"), logical.ErrInvalidRequest
+			case p.MinEncryptionVersion > 0 && ver < p.MinEncryptionVersion:
+				return logical.ErrorResponse("requested version for signing is less than the minimum encryption key version"), logical.ErrInvalidRequest
+			}
+		}
+
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		stream := &signStream{
+			Operation:          operation,
+			KeyName:            name,
+			KeyVersion:         ver,
+			Context:            keyContext,
+			HashAlgorithm:      hashAlgorithm,
+			SignatureAlgorithm: d.Get("signature_algorithm").(string),
+			Marshaling:         marshaling,
+			Expiration:         time.Now().Add(streamTTL),
+			ClientToken:        req.ClientToken,
+		}
+		if err := stream.setHash(keysutil.HashFuncMap[hashAlgorithm]()); err != nil {
+			return nil, err
+		}
+		if err := b.putStream(ctx, req.Storage, id, stream); err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"stream_id":  id,
+				"expiration": stream.Expiration,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathStreamUpdateWrite(operation string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		id := d.Get("stream_id").(string)
+
+		input, err := base64.StdEncoding.DecodeString(d.Get("input").(string))
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("unable to decode input as base64: %s", err)), logical.ErrInvalidRequest
+		}
+
+		lock := locksutil.LockForKey(b.streamLocks, id)
+		lock.Lock()
+		defer lock.Unlock()
+
+		stream, err := b.getStream(ctx, req, operation, d.Get("name").(string), id)
+		if err != nil {
+			return streamErrorResponse(err)
+		}
+
+		if offset := int64(d.Get("offset").(int)); offset >= 0 && offset != stream.Length {
+			return logical.ErrorResponse(fmt.Sprintf("stream has received %d bytes, not %d", stream.Length, offset)), logical.ErrInvalidRequest
+		}
+
+		h, err := stream.hash()
+		if err != nil {
+			return nil, err
+		}
+		h.Write(input)
+		if err := stream.setHash(h); err != nil {
+			return nil, err
+		}
+		stream.Length += int64(len(input))
+		stream.Expiration = time.Now().Add(streamTTL)
+
+		if err := b.putStream(ctx, req.Storage, id, stream); err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"stream_id":  id,
+				"length":     stream.Length,
+				"expiration": stream.Expiration,
+			},
+		}, nil
+	}
+}
+
+func (b *backend) pathStreamDelete(operation string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		id := d.Get("stream_id").(string)
+
+		lock := locksutil.LockForKey(b.streamLocks, id)
+		lock.Lock()
+		defer lock.Unlock()
+
+		if _, err := b.getStream(ctx, req, operation, d.Get("name").(string), id); err != nil {
+			if _, ok := err.(errutil.UserError); ok {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return nil, req.Storage.Delete(ctx, streamPrefix+id)
+	}
+}
+
+func (b *backend) pathStreamFinishWrite(operation string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		id := d.Get("stream_id").(string)
+		name := d.Get("name").(string)
+
+		var sig string
+		if operation == streamOperationVerify {
+			sig = d.Get("signature").(string)
+			if sig == "" {
+				return logical.ErrorResponse("missing signature"), logical.ErrInvalidRequest
+			}
+		}
+
+		lock := locksutil.LockForKey(b.streamLocks, id)
+		lock.Lock()
+		defer lock.Unlock()
+
+		stream, err := b.getStream(ctx, req, operation, name, id)
+		if err != nil {
+			return streamErrorResponse(err)
+		}
+		h, err := stream.hash()
+		if err != nil {
+			return nil, err
+		}
+
+		p, _, err := b.lm.GetPolicy(ctx, keysutil.PolicyRequest{
+			Storage: req.Storage,
+			Name:    name,
+		}, b.GetRandomReader())
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+		}
+		if !b.System().CachingDisabled() {
+			p.Lock(false)
+		}
+		defer p.Unlock()
+
+		// The minimum version may have been raised since the stream started
+		if operation == streamOperationSign && p.MinEncryptionVersion > 0 && stream.KeyVersion < p.MinEncryptionVersion {
+			return logical.ErrorResponse("requested version for signing is less than the minimum encryption key version"), logical.ErrInvalidRequest
+		}
+
+		options := &keysutil.SigningOptions{
+			HashAlgorithm: stream.HashAlgorithm,
+			Marshaling:    stream.Marshaling,
+			SigAlgorithm:  stream.SignatureAlgorithm,
+			Prehashed:     true,
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"length": stream.Length,
+			},
+		}
+		switch operation {
+		case streamOperationSign:
+			result, err := p.SignWithOptions(stream.KeyVersion, stream.Context, h.Sum(nil), options)
+			if err != nil {
+				return streamErrorResponse(err)
+			}
+			resp.Data["signature"] = result.Signature
+			resp.Data["key_version"] = stream.KeyVersion
+			if len(result.PublicKey) > 0 {
+				resp.Data["public_key"] = result.PublicKey
+			}
+
+		case streamOperationVerify:
+			valid, err := p.VerifySignatureWithOptions(stream.Context, h.Sum(nil), sig, options)
+			if err != nil {
+				return streamErrorResponse(err)
+			}
+			resp.Data["valid"] = valid
+		}
+
+		if err := req.Storage.Delete(ctx, streamPrefix+id); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+}
+
+// getStream loads an unexpired stream, checking that it was started for
+// the given operation and key by the token of the request. If it cannot be
+// used, a UserError is returned.
+func (b *backend) getStream(ctx context.Context, req *logical.Request, operation, name, id string) (*signStream, error) {
+	entry, err := req.Storage.Get(ctx, streamPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, errutil.UserError{Err: "stream not found"}
+	}
+
+	var stream signStream
+	if err := entry.DecodeJSON(&stream); err != nil {
+		return nil, err
+	}
+	if stream.Operation != operation || stream.KeyName != name {
+		return nil, errutil.UserError{Err: "stream not found"}
+	}
+	if subtle.ConstantTimeCompare([]byte(stream.ClientToken), []byte(req.ClientToken)) != 1 {
+		return nil, errutil.UserError{Err: "stream not found"}
+	}
+	if time.Now().After(stream.Expiration) {
+		return nil, errutil.UserError{Err: "stream has expired"}
+	}
+	return &stream, nil
+}
+
+func (b *backend) putStream(ctx context.Context, s logical.Storage, id string, stream *signStream) error {
+	entry, err := logical.StorageEntryJSON(streamPrefix+id, stream)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// cleanupExpiredStreams removes the streams which have not been updated
+// within their TTL.
+func (b *backend) cleanupExpiredStreams(ctx context.Context, s logical.Storage) error {
+	ids, err := s.List(ctx, streamPrefix)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		lock := locksutil.LockForKey(b.streamLocks, id)
+		lock.Lock()
+		entry, err := s.Get(ctx, streamPrefix+id)
+		if err == nil && entry != nil {
+			var stream signStream
+			if err = entry.DecodeJSON(&stream); err == nil && now.After(stream.Expiration) {
+				err = s.Delete(ctx, streamPrefix+id)
+			}
+		}
+		lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func streamErrorResponse(err error) (*logical.Response, error) {
+	switch err.(type) {
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	default:
+		return nil, err
+	}
+}
+
+const pathStreamHelpSyn = `Sign or verify input data sent over several requests`
+
+const pathStreamHelpDesc = `
+Writing to "sign/<name>/stream" or "verify/<name>/stream" starts a stream and
+returns its ID. Each chunk of input is then written to the "input" field of
+"<operation>/<name>/stream/<stream_id>", in order, and the input is hashed as
+it arrives. Writing to "<operation>/<name>/stream/<stream_id>/finish" signs the
+digest of the whole input, or verifies the given signature against it, and
+closes the stream. Ed25519 keys produce Ed25519ph signatures for streamed
+input. Deleting a stream abandons it; streams that are not updated for an hour
+expire.
+`
@@ -0,0 +1,237 @@
+package transit
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestTransit_SignVerifyStream(t *testing.T) {
+	for _, keyType := range []string{"ecdsa-p256", "ed25519", "rsa-2048"} {
+		keyType := keyType
+		t.Run(keyType, func(t *testing.T) {
+			testTransit_SignVerifyStream(t, keyType)
+		})
+	}
+}
+
+func testTransit_SignVerifyStream(t *testing.T, keyType string) {
+	b, storage := createBackendWithSysView(t)
+	ctx := context.Background()
+
+	doReq := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:     storage,
+			Operation:   op,
+			Path:        path,
+			Data:        data,
+			ClientToken: "token",
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: path: %s\nresp: %#v\nerr: %v", path, resp, err)
+		}
+		return resp
+	}
+	doErrReqWithToken := func(token, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:     storage,
+			Operation:   logical.UpdateOperation,
+			Path:        path,
+			Data:        data,
+			ClientToken: token,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error for path %s, got %#v", path, resp)
+		}
+	}
+	doErrReq := func(path string, data map[string]interface{}) {
+		t.Helper()
+		doErrReqWithToken("token", path, data)
+	}
+
+	doReq(logical.UpdateOperation, "keys/foo", map[string]interface{}{
+		"type": keyType,
+	})
+
+	chunks := []string{"the quick brown fox ", "jumps over ", "the lazy dog"}
+	message := strings.Join(chunks, "")
+
+	stream := func(op string) string {
+		t.Helper()
+		resp := doReq(logical.UpdateOperation, op+"/foo/stream", nil)
+		id := resp.Data["stream_id"].(string)
+
+		var offset int
+		for _, chunk := range chunks {
+			resp = doReq(logical.UpdateOperation, op+"/foo/stream/"+id, map[string]interface{}{
+				"input":  base64.StdEncoding.EncodeToString([]byte(chunk)),
+				"offset": offset,
+			})
+			offset += len(chunk)
+			if resp.Data["length"].(int64) != int64(offset) {
+				t.Fatalf("bad: %#v", resp.Data)
+			}
+		}
+		return id
+	}
+
+	id := stream("sign")
+
+	// A chunk at the wrong offset is rejected
+	doErrReq("sign/foo/stream/"+id, map[string]interface{}{
+		"input":  base64.StdEncoding.EncodeToString([]byte("x")),
+		"offset": 1,
+	})
+
+	// The stream can only be used by the token which started it
+	doErrReqWithToken("other-token", "sign/foo/stream/"+id, map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString([]byte("x")),
+	})
+	doErrReqWithToken("other-token", "sign/foo/stream/"+id+"/finish", nil)
+
+	// The stream can only be used with the key and operation it was started for
+	doErrReq("verify/foo/stream/"+id+"/finish", map[string]interface{}{
+		"signature": "vault:v1:AAAA",
+	})
+
+	resp := doReq(logical.UpdateOperation, "sign/foo/stream/"+id+"/finish", nil)
+	sig := resp.Data["signature"].(string)
+	if resp.Data["length"].(int64) != int64(len(message)) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Once finished, the stream is gone
+	doErrReq("sign/foo/stream/"+id+"/finish", nil)
+
+	// The signature can be verified in a single request as well
+	verifyData := map[string]interface{}{
+		"input":     base64.StdEncoding.EncodeToString([]byte(message)),
+		"signature": sig,
+	}
+	if keyType == "ed25519" {
+		digest := sha512.Sum512([]byte(message))
+		verifyData["input"] = base64.StdEncoding.EncodeToString(digest[:])
+		verifyData["prehashed"] = true
+		verifyData["hash_algorithm"] = "sha2-512"
+
+		// An Ed25519ph signature is not an Ed25519 signature of the digest,
+		// the scheme itself is checked against the test vectors of keysutil
+		keyResp := doReq(logical.ReadOperation, "keys/foo", nil)
+		pubRaw := keyResp.Data["keys"].(map[string]map[string]interface{})["1"]["public_key"].(string)
+		pub, err := base64.StdEncoding.DecodeString(pubRaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "vault:v1:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ed25519.Verify(pub, digest[:], sigBytes) {
+			t.Fatal("expected an Ed25519ph signature rather than an Ed25519 one")
+		}
+	}
+	resp = doReq(logical.UpdateOperation, "verify/foo", verifyData)
+	if !resp.Data["valid"].(bool) {
+		t.Fatalf("expected valid signature: %#v", resp.Data)
+	}
+
+	id = stream("verify")
+	resp = doReq(logical.UpdateOperation, "verify/foo/stream/"+id+"/finish", map[string]interface{}{
+		"signature": sig,
+	})
+	if !resp.Data["valid"].(bool) {
+		t.Fatalf("expected valid signature: %#v", resp.Data)
+	}
+
+	// A signature of other input does not verify
+	resp = doReq(logical.UpdateOperation, "sign/foo", map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString([]byte("other")),
+	})
+	id = stream("verify")
+	resp = doReq(logical.UpdateOperation, "verify/foo/stream/"+id+"/finish", map[string]interface{}{
+		"signature": resp.Data["signature"],
+	})
+	if resp.Data["valid"].(bool) {
+		t.Fatalf("expected invalid signature: %#v", resp.Data)
+	}
+
+	// Raising the minimum version after a stream started fails its signature
+	id = stream("sign")
+	doReq(logical.UpdateOperation, "keys/foo/rotate", nil)
+	doReq(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"min_encryption_version": 2,
+	})
+	doErrReq("sign/foo/stream/"+id+"/finish", nil)
+}
+
+func TestTransit_SignStream_Expiration(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+	ctx := context.Background()
+
+	_, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"type": "ecdsa-p256",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Storage:   storage,
+			Operation: logical.UpdateOperation,
+			Path:      "sign/foo/stream",
+		})
+		if err != nil || resp.IsError() {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+		ids = append(ids, resp.Data["stream_id"].(string))
+	}
+
+	// Expire the first stream
+	stream, err := b.getStream(ctx, &logical.Request{Storage: storage}, streamOperationSign, "foo", ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Expiration = time.Now().Add(-time.Second)
+	if err := b.putStream(ctx, storage, ids[0], stream); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "sign/foo/stream/" + ids[0],
+		Data: map[string]interface{}{
+			"input": base64.StdEncoding.EncodeToString([]byte("foo")),
+		},
+	})
+	if err != logical.ErrInvalidRequest || !strings.Contains(resp.Error().Error(), "expired") {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	if err := b.periodicFunc(ctx, &logical.Request{Storage: storage}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := storage.List(ctx, streamPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != ids[1] {
+		t.Fatalf("expected only unexpired stream to remain, got %v", keys)
+	}
+}
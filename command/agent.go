@@ -163,6 +163,17 @@ func (c *AgentCommand) AutocompleteFlags() complete.Flags {
 }
 
 func (c *AgentCommand) Run(args []string) int {
+	// When started by the Windows service control manager, Agent reports its
+	// status to it and is stopped through it
+	if agentRunningAsService() {
+		return runAgentService(c.ShutdownCh, func() int {
+			return c.run(args)
+		})
+	}
+	return c.run(args)
+}
+
+func (c *AgentCommand) run(args []string) int {
 	f := c.Flags()
 
 	if err := f.Parse(args); err != nil {
@@ -437,12 +448,18 @@ func (c *AgentCommand) Run(args []string) int {
 
 		// Create the lease cache proxier and set its underlying proxier to
 		// the API proxier.
-		leaseCache, err := cache.NewLeaseCache(&cache.LeaseCacheConfig{
+		leaseCacheConfig := &cache.LeaseCacheConfig{
 			Client:      client,
 			BaseContext: ctx,
 			Proxier:     apiProxy,
 			Logger:      cacheLogger.Named("leasecache"),
-		})
+		}
+		if kv := config.Cache.KV; kv != nil {
+			leaseCacheConfig.KVPaths = kv.Paths
+			leaseCacheConfig.KVTTL = kv.TTL
+			leaseCacheConfig.KVMaxEntries = kv.MaxEntries
+		}
+		leaseCache, err := cache.NewLeaseCache(leaseCacheConfig)
 		if err != nil {
 			c.UI.Error(fmt.Sprintf("Error creating lease cache: %v", err))
 			return 1
@@ -530,6 +547,7 @@ func (c *AgentCommand) Run(args []string) int {
 	// signal.Notify(c.signalCh)
 
 	var ssDoneCh, ahDoneCh, tsDoneCh chan struct{}
+	var childExitCh chan int
 	// Start auto-auth and sink servers
 	if method != nil {
 		enableTokenCh := len(config.Templates) > 0
@@ -556,8 +574,10 @@ func (c *AgentCommand) Run(args []string) int {
 			VaultConf:     config.Vault,
 			Namespace:     namespace,
 			ExitAfterAuth: exitAfterAuth,
+			Exec:          config.Exec,
 		})
 		tsDoneCh = ts.DoneCh
+		childExitCh = ts.ChildExitCh
 
 		go ah.Run(ctx, method)
 		go ss.Run(ctx, ah.OutputCh, sinks)
@@ -600,6 +620,19 @@ func (c *AgentCommand) Run(args []string) int {
 		if tsDoneCh != nil {
 			<-tsDoneCh
 		}
+	case code := <-childExitCh:
+		// The exit code of the child process is propagated, so that Agent
+		// can be used as the entrypoint of a container
+		c.UI.Output(fmt.Sprintf("==> Child process exited with code %d, shutting down", code))
+		cancelFunc()
+		if ahDoneCh != nil {
+			<-ahDoneCh
+		}
+		if ssDoneCh != nil {
+			<-ssDoneCh
+		}
+		<-tsDoneCh
+		return code
 	case <-c.ShutdownCh:
 		c.UI.Output("==> Vault agent shutdown triggered")
 		cancelFunc()
@@ -3,6 +3,7 @@ package cache
 import (
 	"bufio"
 	"bytes"
+	"container/list"
 	"context"
 	"encoding/hex"
 	"encoding/json"
@@ -78,6 +79,16 @@ type LeaseCache struct {
 	// idLocks is used during cache lookup to ensure that identical requests made
 	// in parallel won't trigger multiple renewal goroutines.
 	idLocks []*locksutil.LockEntry
+
+	// kvPaths are the path prefixes under which static secrets are cached
+	// for kvTTL. At most kvMaxEntries are cached at a time; kvEntries holds
+	// their indexes, oldest first, so that the oldest are evicted first.
+	kvPaths      []string
+	kvTTL        time.Duration
+	kvMaxEntries int
+	kvLock       sync.Mutex
+	kvEntries    *list.List
+	kvElements   map[string]*list.Element
 }
 
 // LeaseCacheConfig is the configuration for initializing a new
@@ -87,6 +98,13 @@ type LeaseCacheConfig struct {
 	BaseContext context.Context
 	Proxier     Proxier
 	Logger      hclog.Logger
+
+	// KVPaths enables caching the reads of static secrets, such as those of
+	// KV secrets engines, under the given path prefixes, for KVTTL. At most
+	// KVMaxEntries are cached at a time.
+	KVPaths      []string
+	KVTTL        time.Duration
+	KVMaxEntries int
 }
 
 // NewLeaseCache creates a new instance of a LeaseCache.
@@ -112,13 +130,18 @@ func NewLeaseCache(conf *LeaseCacheConfig) (*LeaseCache, error) {
 	baseCtxInfo := cachememdb.NewContextInfo(conf.BaseContext)
 
 	return &LeaseCache{
-		client:      conf.Client,
-		proxier:     conf.Proxier,
-		logger:      conf.Logger,
-		db:          db,
-		baseCtxInfo: baseCtxInfo,
-		l:           &sync.RWMutex{},
-		idLocks:     locksutil.CreateLocks(),
+		client:       conf.Client,
+		proxier:      conf.Proxier,
+		logger:       conf.Logger,
+		db:           db,
+		baseCtxInfo:  baseCtxInfo,
+		l:            &sync.RWMutex{},
+		idLocks:      locksutil.CreateLocks(),
+		kvPaths:      conf.KVPaths,
+		kvTTL:        conf.KVTTL,
+		kvMaxEntries: conf.KVMaxEntries,
+		kvEntries:    list.New(),
+		kvElements:   make(map[string]*list.Element),
 	}, nil
 }
 
@@ -214,6 +237,14 @@ func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 		return resp, err
 	}
 
+	// Writes to static secrets evict their cached reads
+	if req.Request.Method != http.MethodGet && resp.Response.StatusCode < 300 && c.kvCacheablePath(req) {
+		if err := c.evictKVPath(req); err != nil {
+			c.logger.Error("failed to evict cached static secret", "error", err)
+			return nil, err
+		}
+	}
+
 	// If this is a non-2xx or if the returned response does not contain JSON payload,
 	// we skip caching
 	if resp.Response.StatusCode >= 300 || resp.Response.Header.Get("Content-Type") != "application/json" {
@@ -258,6 +289,15 @@ func (c *LeaseCache) Send(ctx context.Context, req *SendRequest) (*SendResponse,
 		return resp, nil
 	}
 
+	// Static secrets carry no lease to renew; they are cached for a fixed
+	// TTL instead, if enabled for their path.
+	if req.Request.Method == http.MethodGet && secret.LeaseID == "" && secret.Auth == nil && secret.WrapInfo == nil && c.kvCacheablePath(req) {
+		if err := c.cacheKVResponse(index, req, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
 	// Short-circuit if the secret is not renewable
 	tokenRenewable, err := secret.TokenIsRenewable()
 	if err != nil {
@@ -435,6 +475,136 @@ func (c *LeaseCache) startRenewing(ctx context.Context, index *cachememdb.Index,
 	}
 }
 
+// kvCacheablePath returns whether the request is to a path under which static
+// secrets are cached.
+func (c *LeaseCache) kvCacheablePath(req *SendRequest) bool {
+	path := strings.TrimPrefix(req.Request.URL.Path, "/v1/")
+	for _, prefix := range c.kvPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKVResponse stores the response to a read of a static secret, which is
+// evicted once the TTL elapses, the cache is cleared, or the oldest entries
+// are evicted to make room for new ones.
+func (c *LeaseCache) cacheKVResponse(index *cachememdb.Index, req *SendRequest, resp *SendResponse) error {
+	c.logger.Debug("processing static secret response", "method", req.Request.Method, "path", req.Request.URL.Path)
+
+	var respBytes bytes.Buffer
+	if err := resp.Response.Write(&respBytes); err != nil {
+		c.logger.Error("failed to serialize response", "error", err)
+		return err
+	}
+
+	// Reset the response body for upper layers to read
+	if resp.Response.Body != nil {
+		resp.Response.Body.Close()
+	}
+	resp.Response.Body = ioutil.NopCloser(bytes.NewReader(resp.ResponseBody))
+
+	index.Response = respBytes.Bytes()
+
+	// Tie the entry to the token that read it, if the agent manages that
+	// token, so that it is evicted once the token is revoked
+	var parentCtx context.Context
+	entry, err := c.db.Get(cachememdb.IndexNameToken, req.Token)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		parentCtx = entry.RenewCtxInfo.Ctx
+	}
+	ctxInfo := c.createCtxInfo(parentCtx)
+	index.RenewCtxInfo = &cachememdb.ContextInfo{
+		Ctx:        context.WithValue(ctxInfo.Ctx, contextIndexID, index.ID),
+		CancelFunc: ctxInfo.CancelFunc,
+		DoneCh:     ctxInfo.DoneCh,
+	}
+
+	c.kvLock.Lock()
+	defer c.kvLock.Unlock()
+
+	for c.kvEntries.Len() >= c.kvMaxEntries {
+		oldest := c.kvEntries.Remove(c.kvEntries.Front()).(*cachememdb.Index)
+		delete(c.kvElements, oldest.ID)
+		c.logger.Debug("static secret cache full; evicting oldest entry", "path", oldest.RequestPath)
+		oldest.RenewCtxInfo.CancelFunc()
+		if err := c.db.Evict(cachememdb.IndexNameID, oldest.ID); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Debug("storing static secret into the cache", "method", req.Request.Method, "path", req.Request.URL.Path, "ttl", c.kvTTL)
+	if err := c.db.Set(index); err != nil {
+		c.logger.Error("failed to cache the proxied response", "error", err)
+		return err
+	}
+	if elem, ok := c.kvElements[index.ID]; ok {
+		c.kvEntries.Remove(elem)
+	}
+	c.kvElements[index.ID] = c.kvEntries.PushBack(index)
+
+	go c.expireKVEntry(index)
+
+	return nil
+}
+
+// expireKVEntry evicts a cached static secret once its TTL elapses, or once
+// its context is cancelled.
+func (c *LeaseCache) expireKVEntry(index *cachememdb.Index) {
+	timer := time.NewTimer(c.kvTTL)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-index.RenewCtxInfo.Ctx.Done():
+	case <-index.RenewCtxInfo.DoneCh:
+	}
+
+	c.kvLock.Lock()
+	if elem, ok := c.kvElements[index.ID]; ok && elem.Value == index {
+		c.kvEntries.Remove(elem)
+		delete(c.kvElements, index.ID)
+	}
+	c.kvLock.Unlock()
+
+	// Only evict this entry, not a newer one cached for the same request
+	current, err := c.db.Get(cachememdb.IndexNameID, index.ID)
+	if err != nil || current != index {
+		return
+	}
+	c.logger.Debug("evicting static secret from cache", "path", index.RequestPath)
+	if err := c.db.Evict(cachememdb.IndexNameID, index.ID); err != nil {
+		c.logger.Error("failed to evict index", "id", index.ID, "error", err)
+	}
+}
+
+// evictKVPath evicts the cached reads of the static secrets at or below the
+// path of the request. They are evicted right away rather than once their
+// expiry goroutine notices, so that reads following the write are not served
+// stale data.
+func (c *LeaseCache) evictKVPath(req *SendRequest) error {
+	namespace := req.Request.Header.Get(consts.NamespaceHeaderName)
+	if namespace == "" {
+		namespace = "root/"
+	}
+
+	indexes, err := c.db.GetByPrefix(cachememdb.IndexNameRequestPath, namespace, req.Request.URL.Path)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		index.RenewCtxInfo.CancelFunc()
+		if err := c.db.Evict(cachememdb.IndexNameID, index.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // computeIndexID results in a value that uniquely identifies a request
 // received by the agent. It does so by SHA256 hashing the serialized request
 // object containing the request path, query parameters and body parameters.
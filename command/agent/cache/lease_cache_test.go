@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/command/agent/cache/cachememdb"
 
@@ -289,6 +290,133 @@ func TestLeaseCache_SendNonCacheableNonTokenLease(t *testing.T) {
 	}
 }
 
+func testNewKVLeaseCache(t *testing.T, responses []*SendResponse, ttl time.Duration, maxEntries int) *LeaseCache {
+	t.Helper()
+
+	client, err := api.NewClient(api.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lc, err := NewLeaseCache(&LeaseCacheConfig{
+		Client:       client,
+		BaseContext:  context.Background(),
+		Proxier:      newMockProxier(responses),
+		Logger:       logging.NewVaultLogger(hclog.Trace).Named("cache.leasecache"),
+		KVPaths:      []string{"secret/"},
+		KVTTL:        ttl,
+		KVMaxEntries: maxEntries,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lc
+}
+
+func testSendKV(t *testing.T, lc *LeaseCache, method, path string) *SendResponse {
+	t.Helper()
+
+	resp, err := lc.Send(context.Background(), &SendRequest{
+		Token:   "testtoken",
+		Request: httptest.NewRequest(method, "http://example.com/v1/"+path, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestLeaseCache_SendKV(t *testing.T) {
+	responses := []*SendResponse{
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "foo"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "bar"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "bar"}}`),
+		newTestSendResponse(http.StatusNoContent, ""),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "baz"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "qux"}}`),
+	}
+	lc := testNewKVLeaseCache(t, responses, time.Hour, 10)
+
+	// Reads of static secrets under the configured paths are cached
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected first read to be proxied")
+	}
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta == nil || !resp.CacheMeta.Hit {
+		t.Fatal("expected second read to be served from the cache")
+	}
+	if lc.proxier.(*mockProxier).ResponseIndex() != 1 {
+		t.Fatalf("expected 1 proxied request, got %d", lc.proxier.(*mockProxier).ResponseIndex())
+	}
+
+	// Reads outside the configured paths are not
+	testSendKV(t, lc, "GET", "other/foo")
+	if resp := testSendKV(t, lc, "GET", "other/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected read outside the cached paths to be proxied")
+	}
+
+	// Writes evict the cached reads of the path
+	testSendKV(t, lc, "POST", "secret/foo")
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected read following a write to be proxied")
+	}
+
+	// Clearing by request path prefix evicts the cached reads below it
+	if err := lc.handleCacheClear(context.Background(), &cacheClearInput{Type: "request_path", RequestPath: "/v1/secret/"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected read following a cache clear to be proxied")
+	}
+}
+
+func TestLeaseCache_SendKV_TTL(t *testing.T) {
+	responses := []*SendResponse{
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "foo"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "bar"}}`),
+	}
+	lc := testNewKVLeaseCache(t, responses, 100*time.Millisecond, 10)
+
+	testSendKV(t, lc, "GET", "secret/foo")
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta == nil || !resp.CacheMeta.Hit {
+		t.Fatal("expected read to be served from the cache")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected read following the expiry to be proxied")
+	}
+	if lc.proxier.(*mockProxier).ResponseIndex() != 2 {
+		t.Fatalf("expected 2 proxied requests, got %d", lc.proxier.(*mockProxier).ResponseIndex())
+	}
+}
+
+func TestLeaseCache_SendKV_MaxEntries(t *testing.T) {
+	responses := []*SendResponse{
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "foo"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "bar"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "baz"}}`),
+		newTestSendResponse(http.StatusOK, `{"data": {"value": "foo"}}`),
+	}
+	lc := testNewKVLeaseCache(t, responses, time.Hour, 2)
+
+	testSendKV(t, lc, "GET", "secret/foo")
+	testSendKV(t, lc, "GET", "secret/bar")
+	testSendKV(t, lc, "GET", "secret/baz")
+
+	// The oldest entry was evicted to make room for the newest
+	if resp := testSendKV(t, lc, "GET", "secret/baz"); resp.CacheMeta == nil || !resp.CacheMeta.Hit {
+		t.Fatal("expected newest entry to be served from the cache")
+	}
+	if resp := testSendKV(t, lc, "GET", "secret/bar"); resp.CacheMeta == nil || !resp.CacheMeta.Hit {
+		t.Fatal("expected second entry to be served from the cache")
+	}
+	if resp := testSendKV(t, lc, "GET", "secret/foo"); resp.CacheMeta != nil && resp.CacheMeta.Hit {
+		t.Fatal("expected oldest entry to be evicted")
+	}
+}
+
 func TestLeaseCache_HandleCacheClear(t *testing.T) {
 	lc := testNewLeaseCache(t, nil)
 
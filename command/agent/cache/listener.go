@@ -52,6 +52,12 @@ func StartListener(lnConfig *configutil.Listener) (net.Listener, *tls.Config, er
 			return nil, nil, err
 		}
 
+	case "pipe":
+		ln, err = listenerutil.NamedPipeListener(addr, lnConfig.PipeSecurityDescriptor)
+		if err != nil {
+			return nil, nil, err
+		}
+
 	default:
 		return nil, nil, fmt.Errorf("invalid listener type: %q", lnConfig.Type)
 	}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/signals"
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
@@ -28,6 +29,7 @@ type Config struct {
 	Cache         *Cache                     `hcl:"cache"`
 	Vault         *Vault                     `hcl:"vault"`
 	Templates     []*ctconfig.TemplateConfig `hcl:"templates"`
+	Exec          *Exec                      `hcl:"exec"`
 }
 
 // Vault contains configuration for connnecting to Vault servers
@@ -47,8 +49,55 @@ type Cache struct {
 	UseAutoAuthTokenRaw interface{} `hcl:"use_auto_auth_token"`
 	UseAutoAuthToken    bool        `hcl:"-"`
 	ForceAutoAuthToken  bool        `hcl:"-"`
+	KV                  *KVCache    `hcl:"-"`
 }
 
+// KVCache contains the configuration for caching reads of static secrets,
+// such as those of KV secrets engines, which carry no lease
+type KVCache struct {
+	Paths      []string      `hcl:"paths"`
+	TTLRaw     interface{}   `hcl:"ttl"`
+	TTL        time.Duration `hcl:"-"`
+	MaxEntries int           `hcl:"max_entries"`
+}
+
+const (
+	// DefaultKVCacheTTL is how long static secrets are cached unless
+	// configured otherwise
+	DefaultKVCacheTTL = 5 * time.Minute
+
+	// DefaultKVCacheMaxEntries is how many static secrets are cached unless
+	// configured otherwise
+	DefaultKVCacheMaxEntries = 1000
+)
+
+// Exec contains the configuration of the child process supervised by Agent
+// once its templates are rendered
+type Exec struct {
+	Command                []string      `hcl:"command"`
+	RestartOnSecretChanges string        `hcl:"restart_on_secret_changes"`
+	ReloadSignalRaw        string        `hcl:"reload_signal"`
+	ReloadSignal           os.Signal     `hcl:"-"`
+	KillSignalRaw          string        `hcl:"kill_signal"`
+	KillSignal             os.Signal     `hcl:"-"`
+	KillTimeoutRaw         interface{}   `hcl:"kill_timeout"`
+	KillTimeout            time.Duration `hcl:"-"`
+}
+
+const (
+	// ExecRestartAlways restarts, or signals, the child process whenever a
+	// template renders new contents
+	ExecRestartAlways = "always"
+
+	// ExecRestartNever leaves the child process running when templates
+	// render new contents
+	ExecRestartNever = "never"
+
+	// DefaultExecKillTimeout is how long the child process is given to exit
+	// after being sent the kill signal, before being killed forcibly
+	DefaultExecKillTimeout = 30 * time.Second
+)
+
 // AutoAuth is the configured authentication method and sinks
 type AutoAuth struct {
 	Method *Method `hcl:"-"`
@@ -140,6 +189,19 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, errwrap.Wrapf("error parsing 'template': {{err}}", err)
 	}
 
+	if err := parseExec(result, list); err != nil {
+		return nil, errwrap.Wrapf("error parsing 'exec': {{err}}", err)
+	}
+
+	if result.Exec != nil {
+		if result.AutoAuth == nil || len(result.Templates) == 0 {
+			return nil, fmt.Errorf("exec requires auto_auth and at least one template")
+		}
+		if result.ExitAfterAuth {
+			return nil, fmt.Errorf("exec cannot be used with exit_after_auth")
+		}
+	}
+
 	if result.Cache != nil {
 		if len(result.Listeners) < 1 {
 			return nil, fmt.Errorf("at least one listener required when cache enabled")
@@ -244,6 +306,121 @@ func parseCache(result *Config, list *ast.ObjectList) error {
 	}
 
 	result.Cache = &c
+
+	subs, ok := item.Val.(*ast.ObjectType)
+	if !ok {
+		return fmt.Errorf("could not parse %q as an object", name)
+	}
+	if err := parseKVCache(result, subs.List); err != nil {
+		return errwrap.Wrapf("error parsing 'kv': {{err}}", err)
+	}
+
+	return nil
+}
+
+func parseKVCache(result *Config, list *ast.ObjectList) error {
+	name := "kv"
+
+	kvList := list.Filter(name)
+	if len(kvList.Items) == 0 {
+		return nil
+	}
+
+	if len(kvList.Items) > 1 {
+		return fmt.Errorf("at most one %q block is allowed", name)
+	}
+
+	item := kvList.Items[0]
+
+	var kv KVCache
+	err := hcl.DecodeObject(&kv, item.Val)
+	if err != nil {
+		return err
+	}
+
+	if len(kv.Paths) == 0 {
+		return errors.New("at least one path must be specified")
+	}
+	for i, path := range kv.Paths {
+		kv.Paths[i] = strings.TrimPrefix(path, "/")
+	}
+
+	kv.TTL = DefaultKVCacheTTL
+	if kv.TTLRaw != nil {
+		if kv.TTL, err = parseutil.ParseDurationSecond(kv.TTLRaw); err != nil {
+			return err
+		}
+		kv.TTLRaw = nil
+	}
+	if kv.TTL <= 0 {
+		return errors.New("ttl must be positive")
+	}
+
+	switch {
+	case kv.MaxEntries == 0:
+		kv.MaxEntries = DefaultKVCacheMaxEntries
+	case kv.MaxEntries < 0:
+		return errors.New("max_entries must be positive")
+	}
+
+	result.Cache.KV = &kv
+	return nil
+}
+
+func parseExec(result *Config, list *ast.ObjectList) error {
+	name := "exec"
+
+	execList := list.Filter(name)
+	if len(execList.Items) == 0 {
+		return nil
+	}
+
+	if len(execList.Items) > 1 {
+		return fmt.Errorf("at most one %q block is allowed", name)
+	}
+
+	item := execList.Items[0]
+
+	var e Exec
+	err := hcl.DecodeObject(&e, item.Val)
+	if err != nil {
+		return err
+	}
+
+	if len(e.Command) == 0 || e.Command[0] == "" {
+		return errors.New("exec.command must be specified")
+	}
+
+	switch e.RestartOnSecretChanges {
+	case "":
+		e.RestartOnSecretChanges = ExecRestartAlways
+	case ExecRestartAlways, ExecRestartNever:
+	default:
+		return fmt.Errorf("value of 'restart_on_secret_changes' can be either %q or %q, %q is an invalid option", ExecRestartAlways, ExecRestartNever, e.RestartOnSecretChanges)
+	}
+
+	if e.ReloadSignalRaw != "" {
+		if e.ReloadSignal, err = signals.Parse(e.ReloadSignalRaw); err != nil {
+			return errwrap.Wrapf("error parsing 'reload_signal': {{err}}", err)
+		}
+	}
+
+	if e.KillSignalRaw == "" {
+		e.KillSignalRaw = "SIGTERM"
+	}
+	if e.KillSignal, err = signals.Parse(e.KillSignalRaw); err != nil {
+		return errwrap.Wrapf("error parsing 'kill_signal': {{err}}", err)
+	}
+
+	e.KillTimeout = DefaultExecKillTimeout
+	if e.KillTimeoutRaw != nil {
+		if e.KillTimeout, err = parseutil.ParseDurationSecond(e.KillTimeoutRaw); err != nil {
+			return errwrap.Wrapf("error parsing 'kill_timeout': {{err}}", err)
+		}
+		e.KillTimeoutRaw = nil
+	}
+
+	result.Exec = &e
 	return nil
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -226,6 +227,72 @@ func TestLoadConfigFile_AgentCache_NoAutoAuth(t *testing.T) {
 	}
 }
 
+func TestLoadConfigFile_AgentCache_PipeListener(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-cache-pipe-listener.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		Cache: &Cache{},
+		SharedConfig: &configutil.SharedConfig{
+			PidFile: "./pidfile",
+			Listeners: []*configutil.Listener{
+				{
+					Type:                   "pipe",
+					Address:                `\\.\pipe\vault-agent`,
+					TLSDisable:             true,
+					PipeSecurityDescriptor: "D:P(A;;GA;;;BA)(A;;GA;;;SY)",
+				},
+			},
+		},
+	}
+
+	config.Listeners[0].RawConfig = nil
+	if diff := deep.Equal(config, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_AgentCache_KV(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-cache-kv.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Config{
+		Cache: &Cache{
+			KV: &KVCache{
+				Paths:      []string{"secret/", "kv/data/app/"},
+				TTL:        10 * time.Minute,
+				MaxEntries: 50,
+			},
+		},
+		SharedConfig: &configutil.SharedConfig{
+			PidFile: "./pidfile",
+			Listeners: []*configutil.Listener{
+				{
+					Type:       "tcp",
+					Address:    "127.0.0.1:8300",
+					TLSDisable: true,
+				},
+			},
+		},
+	}
+
+	config.Listeners[0].RawConfig = nil
+	if diff := deep.Equal(config, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_Bad_AgentCache_KV_NoPaths(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-cache-kv-no-paths.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when cache.kv has no paths")
+	}
+}
+
 func TestLoadConfigFile_Bad_AgentCache_InconsisentAutoAuth(t *testing.T) {
 	_, err := LoadConfig("./test-fixtures/bad-config-cache-inconsistent-auto_auth.hcl")
 	if err == nil {
@@ -641,3 +708,38 @@ func TestLoadConfigFile_Template_NoSinks(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigFile_Exec(t *testing.T) {
+	config, err := LoadConfig("./test-fixtures/config-exec.hcl")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &Exec{
+		Command:                []string{"/usr/bin/app", "-config", "/path/on/disk/where/template/will/render.txt"},
+		RestartOnSecretChanges: ExecRestartAlways,
+		ReloadSignalRaw:        "SIGHUP",
+		ReloadSignal:           syscall.SIGHUP,
+		KillSignalRaw:          "SIGTERM",
+		KillSignal:             syscall.SIGTERM,
+		KillTimeout:            10 * time.Second,
+	}
+
+	if diff := deep.Equal(config.Exec, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadConfigFile_Bad_Exec_NoTemplates(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-exec-no-templates.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when exec is configured without templates")
+	}
+}
+
+func TestLoadConfigFile_Bad_Exec_Restart(t *testing.T) {
+	_, err := LoadConfig("./test-fixtures/bad-config-exec-restart.hcl")
+	if err == nil {
+		t.Fatal("LoadConfig should return an error when restart_on_secret_changes is invalid")
+	}
+}
@@ -0,0 +1,12 @@
+pid_file = "./pidfile"
+
+cache {
+    kv {
+        ttl = "10m"
+    }
+}
+
+listener "tcp" {
+    address = "127.0.0.1:8300"
+    tls_disable = true
+}
@@ -0,0 +1,14 @@
+pid_file = "./pidfile"
+
+cache {
+    kv {
+        paths = ["/secret/", "kv/data/app/"]
+        ttl = "10m"
+        max_entries = 50
+    }
+}
+
+listener "tcp" {
+    address = "127.0.0.1:8300"
+    tls_disable = true
+}
@@ -0,0 +1,10 @@
+pid_file = "./pidfile"
+
+cache {
+}
+
+listener "pipe" {
+    address = "\\\\.\\pipe\\vault-agent"
+    tls_disable = true
+    pipe_security_descriptor = "D:P(A;;GA;;;BA)(A;;GA;;;SY)"
+}
@@ -0,0 +1,36 @@
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type      = "aws"
+    namespace = "/my-namespace"
+
+    config = {
+      role = "foobar"
+    }
+  }
+
+  sink {
+    type = "file"
+
+    config = {
+      path = "/tmp/file-foo"
+    }
+
+    aad     = "foobar"
+    dh_type = "curve25519"
+    dh_path = "/tmp/file-foo-dhpath"
+  }
+}
+
+template {
+  source      = "/path/on/disk/to/template.ctmpl"
+  destination = "/path/on/disk/where/template/will/render.txt"
+}
+
+exec {
+  command                   = ["/usr/bin/app", "-config", "/path/on/disk/where/template/will/render.txt"]
+  restart_on_secret_changes = "always"
+  reload_signal             = "SIGHUP"
+  kill_timeout              = "10s"
+}
@@ -8,8 +8,11 @@ package template
 import (
 	"context"
 	"io"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/hashicorp/consul-template/child"
 	ctconfig "github.com/hashicorp/consul-template/config"
 	ctlogging "github.com/hashicorp/consul-template/logging"
 	"github.com/hashicorp/consul-template/manager"
@@ -36,6 +39,10 @@ type ServerConfig struct {
 	// the same io.Writer that Vault Agent itself is using.
 	LogLevel  hclog.Level
 	LogWriter io.Writer
+
+	// Exec is the configuration of the child process to supervise once all
+	// templates are rendered, if any
+	Exec *config.Exec
 }
 
 // Server manages the Consul Template Runner which renders templates
@@ -55,6 +62,19 @@ type Server struct {
 	// from the runner in the event we're using exit after auth.
 	lookupMap map[string][]*ctconfig.TemplateConfig
 
+	// child is the process supervised according to the exec configuration.
+	// It is started once all templates are rendered, and restarted or
+	// signalled when they render new contents.
+	child *child.Child
+
+	// lastRendered holds when each template last rendered new contents, as
+	// seen by the child
+	lastRendered map[string]time.Time
+
+	// ChildExitCh receives the exit code of the child process, if it exits.
+	// The server stops once it does.
+	ChildExitCh chan int
+
 	DoneCh        chan struct{}
 	logger        hclog.Logger
 	exitAfterAuth bool
@@ -64,6 +84,8 @@ type Server struct {
 func NewServer(conf *ServerConfig) *Server {
 	ts := Server{
 		DoneCh:        make(chan struct{}),
+		ChildExitCh:   make(chan int, 1),
+		lastRendered:  make(map[string]time.Time),
 		logger:        conf.Logger,
 		config:        conf,
 		exitAfterAuth: conf.ExitAfterAuth,
@@ -130,6 +152,13 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 		select {
 		case <-ctx.Done():
 			ts.runner.Stop()
+			ts.stopChild()
+			return
+
+		case code := <-ts.childExitCh():
+			ts.logger.Info("child process exited", "exit_code", code)
+			ts.runner.Stop()
+			ts.ChildExitCh <- code
 			return
 
 		case token := <-incoming:
@@ -187,8 +216,80 @@ func (ts *Server) Run(ctx context.Context, incoming chan string, templates []*ct
 				ts.runner.Stop()
 				return
 			}
+
+			if doneRendering && ts.config.Exec != nil {
+				if err := ts.superviseChild(events); err != nil {
+					ts.logger.Error("template server failed to run child process", "error", err)
+					ts.runner.Stop()
+					ts.stopChild()
+					ts.ChildExitCh <- child.ExitCodeError
+					return
+				}
+			}
+		}
+	}
+}
+
+// superviseChild starts the child process once all templates are rendered,
+// and then restarts or signals it when any template renders new contents.
+func (ts *Server) superviseChild(events map[string]*manager.RenderEvent) error {
+	changed := false
+	for id, event := range events {
+		if event.LastDidRender.After(ts.lastRendered[id]) {
+			ts.lastRendered[id] = event.LastDidRender
+			changed = true
 		}
 	}
+
+	execConf := ts.config.Exec
+	if ts.child == nil {
+		c, err := child.New(&child.NewInput{
+			Stdin:        os.Stdin,
+			Stdout:       os.Stdout,
+			Stderr:       os.Stderr,
+			Command:      execConf.Command[0],
+			Args:         execConf.Command[1:],
+			Env:          os.Environ(),
+			ReloadSignal: execConf.ReloadSignal,
+			KillSignal:   execConf.KillSignal,
+			KillTimeout:  execConf.KillTimeout,
+		})
+		if err != nil {
+			return err
+		}
+		ts.logger.Info("starting child process", "command", c.Command())
+		if err := c.Start(); err != nil {
+			return err
+		}
+		ts.child = c
+		return nil
+	}
+
+	if !changed || execConf.RestartOnSecretChanges == config.ExecRestartNever {
+		return nil
+	}
+	if execConf.ReloadSignal != nil {
+		ts.logger.Info("templates rendered new contents, signalling child process", "signal", execConf.ReloadSignal.String())
+	} else {
+		ts.logger.Info("templates rendered new contents, restarting child process")
+	}
+	return ts.child.Reload()
+}
+
+// childExitCh returns the channel receiving the exit code of the current
+// child process, or nil if there is none. The channel changes when the child
+// process is restarted.
+func (ts *Server) childExitCh() <-chan int {
+	if ts.child == nil {
+		return nil
+	}
+	return ts.child.ExitCh()
+}
+
+func (ts *Server) stopChild() {
+	if ts.child != nil {
+		ts.child.Stop()
+	}
 }
 
 // newRunnerConfig returns a consul-template runner configuration, setting the
@@ -8,7 +8,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	ctconfig "github.com/hashicorp/consul-template/config"
 	"github.com/hashicorp/go-hclog"
@@ -162,6 +166,69 @@ func TestServerRun(t *testing.T) {
 	}
 }
 
+func TestServerRun_Exec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires a POSIX shell")
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handleRequest))
+	defer ts.Close()
+	tmpDir, err := ioutil.TempDir("", "agent-tests")
+	defer os.RemoveAll(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstFile := filepath.Join(tmpDir, "render_01")
+	outFile := filepath.Join(tmpDir, "child_out")
+	templatesToRender := []*ctconfig.TemplateConfig{
+		&ctconfig.TemplateConfig{
+			Contents:    pointerutil.StringPtr(templateContents),
+			Destination: pointerutil.StringPtr(dstFile),
+		},
+	}
+
+	// The child process only starts once the template is rendered, and its
+	// exit code is passed on
+	sc := ServerConfig{
+		Logger: logging.NewVaultLogger(hclog.Trace),
+		VaultConf: &config.Vault{
+			Address: ts.URL,
+		},
+		LogLevel:  hclog.Trace,
+		LogWriter: hclog.DefaultOutput,
+		Exec: &config.Exec{
+			Command:                []string{"sh", "-c", fmt.Sprintf("cp %s %s; exit 3", dstFile, outFile)},
+			RestartOnSecretChanges: config.ExecRestartAlways,
+			KillSignal:             os.Interrupt,
+			KillTimeout:            config.DefaultExecKillTimeout,
+		},
+	}
+
+	server := NewServer(&sc)
+	templateTokenCh := make(chan string, 1)
+	go server.Run(context.Background(), templateTokenCh, templatesToRender)
+	templateTokenCh <- "test"
+
+	select {
+	case code := <-server.ChildExitCh:
+		if code != 3 {
+			t.Fatalf("expected exit code 3, got %d", code)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the child process to exit")
+	}
+	<-server.DoneCh
+
+	content, err := ioutil.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "appuser") {
+		t.Fatalf("child process ran before the template was rendered: %q", content)
+	}
+}
+
 func handleRequest(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, jsonResponse)
 }
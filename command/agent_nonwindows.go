@@ -0,0 +1,13 @@
+// +build !windows
+
+package command
+
+// agentRunningAsService is always false outside of Windows.
+func agentRunningAsService() bool {
+	return false
+}
+
+// runAgentService is only used on Windows.
+func runAgentService(shutdownCh chan struct{}, run func() int) int {
+	return run()
+}
@@ -0,0 +1,75 @@
+// +build windows
+
+package command
+
+import (
+	"golang.org/x/sys/windows/svc"
+)
+
+// agentServiceName is the name Agent runs under as a Windows service. It is
+// only informational, as the service control manager starts a single service
+// per process.
+const agentServiceName = "vault-agent"
+
+// agentRunningAsService returns whether Agent was started by the Windows
+// service control manager rather than from a console.
+func agentRunningAsService() bool {
+	interactive, err := svc.IsAnInteractiveSession()
+	return err == nil && !interactive
+}
+
+// runAgentService runs Agent as a Windows service, triggering its shutdown
+// when the service control manager stops the service, and returns its exit
+// code. If the process turns out not to be connected to the service control
+// manager, Agent is run as if started from a console.
+func runAgentService(shutdownCh chan struct{}, run func() int) int {
+	s := &agentService{
+		shutdownCh: shutdownCh,
+		run:        run,
+	}
+	if err := svc.Run(agentServiceName, s); err != nil && !s.started {
+		return run()
+	}
+	return s.exitCode
+}
+
+// agentService implements svc.Handler
+type agentService struct {
+	shutdownCh chan struct{}
+	run        func() int
+
+	started  bool
+	exitCode int
+}
+
+func (s *agentService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	s.started = true
+	status <- svc.Status{State: svc.StartPending}
+
+	doneCh := make(chan int, 1)
+	go func() {
+		doneCh <- s.run()
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case code := <-doneCh:
+			s.exitCode = code
+			// A non-zero exit code is reported as a service-specific error
+			return code != 0, uint32(code)
+
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				go func() {
+					s.shutdownCh <- struct{}{}
+				}()
+			}
+		}
+	}
+}
@@ -34,7 +34,9 @@ type AuthEnableCommand struct {
 	flagSealWrap                  bool
 	flagExternalEntropyAccess     bool
 	flagTokenType                 string
+	flagGroupSyncInterval         time.Duration
 	flagVersion                   int
+	flagPluginVersion             string
 }
 
 func (c *AuthEnableCommand) Synopsis() string {
@@ -190,6 +192,25 @@ func (c *AuthEnableCommand) Flags() *FlagSets {
 		Usage:  "Sets a forced token type for the mount.",
 	})
 
+	f.DurationVar(&DurationVar{
+		Name:       flagNameGroupSyncInterval,
+		Target:     &c.flagGroupSyncInterval,
+		Completion: complete.PredictAnything,
+		Usage: "How often the external group memberships of the entities with " +
+			"aliases on this auth method are re-resolved, so that removals from " +
+			"a group take effect before tokens are renewed. Not supported by all " +
+			"auth methods. Set to 0 to disable.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       flagNamePluginVersion,
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version of the plugin of the catalog to run, which has " +
+			"to be registered with the version. By default, the plugin " +
+			"registered without a version is run.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "version",
 		Target:  &c.flagVersion,
@@ -296,6 +317,14 @@ func (c *AuthEnableCommand) Run(args []string) int {
 		if fl.Name == flagNameTokenType {
 			authOpts.Config.TokenType = c.flagTokenType
 		}
+
+		if fl.Name == flagNameGroupSyncInterval {
+			authOpts.Config.GroupSyncInterval = c.flagGroupSyncInterval.String()
+		}
+
+		if fl.Name == flagNamePluginVersion {
+			authOpts.Config.PluginVersion = c.flagPluginVersion
+		}
 	})
 
 	if err := client.Sys().EnableAuthWithOptions(authPath, authOpts); err != nil {
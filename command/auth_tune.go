@@ -22,11 +22,13 @@ type AuthTuneCommand struct {
 	flagAuditNonHMACResponseKeys []string
 	flagDefaultLeaseTTL          time.Duration
 	flagDescription              string
+	flagGroupSyncInterval        time.Duration
 	flagListingVisibility        string
 	flagMaxLeaseTTL              time.Duration
 	flagOptions                  map[string]string
 	flagTokenType                string
 	flagVersion                  int
+	flagPluginVersion            string
 }
 
 func (c *AuthTuneCommand) Synopsis() string {
@@ -119,6 +121,25 @@ func (c *AuthTuneCommand) Flags() *FlagSets {
 		Usage:  "Sets a forced token type for the mount.",
 	})
 
+	f.DurationVar(&DurationVar{
+		Name:       flagNameGroupSyncInterval,
+		Target:     &c.flagGroupSyncInterval,
+		Completion: complete.PredictAnything,
+		Usage: "How often the external group memberships of the entities with " +
+			"aliases on this auth method are re-resolved, so that removals from " +
+			"a group take effect before tokens are renewed. Not supported by all " +
+			"auth methods. Set to 0 to disable.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       flagNamePluginVersion,
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version of the plugin of the catalog to run, which has " +
+			"to be registered with the version. The plugin of the mount is " +
+			"reloaded with the version.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "version",
 		Target:  &c.flagVersion,
@@ -195,6 +216,14 @@ func (c *AuthTuneCommand) Run(args []string) int {
 		if fl.Name == flagNameTokenType {
 			mountConfigInput.TokenType = c.flagTokenType
 		}
+
+		if fl.Name == flagNameGroupSyncInterval {
+			mountConfigInput.GroupSyncInterval = c.flagGroupSyncInterval.String()
+		}
+
+		if fl.Name == flagNamePluginVersion {
+			mountConfigInput.PluginVersion = c.flagPluginVersion
+		}
 	})
 
 	// Append /auth (since that's where auths live) and a trailing slash to
@@ -196,7 +196,6 @@ type FlagSetBit uint
 const (
 	FlagSetNone FlagSetBit = 1 << iota
 	FlagSetHTTP
-	FlagSetOutputField
 	FlagSetOutputFormat
 )
 
@@ -358,33 +357,31 @@ func (c *BaseCommand) flagSet(bit FlagSetBit) *FlagSets {
 
 		}
 
-		if bit&(FlagSetOutputField|FlagSetOutputFormat) != 0 {
+		if bit&FlagSetOutputFormat != 0 {
 			f := set.NewFlagSet("Output Options")
 
-			if bit&FlagSetOutputField != 0 {
-				f.StringVar(&StringVar{
-					Name:       "field",
-					Target:     &c.flagField,
-					Default:    "",
-					Completion: complete.PredictAnything,
-					Usage: "Print only the field with the given name. Specifying " +
-						"this option will take precedence over other formatting " +
-						"directives. The result will not have a trailing newline " +
-						"making it ideal for piping to other processes.",
-				})
-			}
+			f.StringVar(&StringVar{
+				Name:       "field",
+				Target:     &c.flagField,
+				Default:    "",
+				Completion: complete.PredictAnything,
+				Usage: "Print only the field with the given name, or the value " +
+					"at the given JSONPath-style expression such as \".data.keys[0]\", " +
+					"evaluated against the JSON output. Specifying this option will " +
+					"take precedence over other formatting directives. The result " +
+					"will not have a trailing newline making it ideal for piping to " +
+					"other processes.",
+			})
 
-			if bit&FlagSetOutputFormat != 0 {
-				f.StringVar(&StringVar{
-					Name:       "format",
-					Target:     &c.flagFormat,
-					Default:    "table",
-					EnvVar:     EnvVaultFormat,
-					Completion: complete.PredictSet("table", "json", "yaml"),
-					Usage: "Print the output in the given format. Valid formats " +
-						"are \"table\", \"json\", or \"yaml\".",
-				})
-			}
+			f.StringVar(&StringVar{
+				Name:       "format",
+				Target:     &c.flagFormat,
+				Default:    "table",
+				EnvVar:     EnvVaultFormat,
+				Completion: complete.PredictSet("table", "json", "yaml"),
+				Usage: "Print the output in the given format. Valid formats " +
+					"are \"table\", \"json\", or \"yaml\".",
+			})
 		}
 
 		c.flags = set
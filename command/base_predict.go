@@ -89,6 +89,7 @@ func (b *BaseCommand) PredictVaultAvailableMounts() complete.Predictor {
 		"plugin",
 		"rabbitmq",
 		"ssh",
+		"tokenize",
 		"totp",
 		"transit",
 	)
@@ -352,6 +352,7 @@ func TestPredict_Plugins(t *testing.T) {
 				"centrify",
 				"cert",
 				"cf",
+				"clickhouse-database-plugin",
 				"consul",
 				"elasticsearch-database-plugin",
 				"gcp",
@@ -389,6 +390,7 @@ func TestPredict_Plugins(t *testing.T) {
 				"radius",
 				"redshift-database-plugin",
 				"ssh",
+				"tokenize",
 				"totp",
 				"transform",
 				"transit",
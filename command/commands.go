@@ -20,6 +20,7 @@ import (
 	_ "github.com/hashicorp/vault/helper/builtinplugins"
 
 	auditFile "github.com/hashicorp/vault/builtin/audit/file"
+	auditKafka "github.com/hashicorp/vault/builtin/audit/kafka"
 	auditSocket "github.com/hashicorp/vault/builtin/audit/socket"
 	auditSyslog "github.com/hashicorp/vault/builtin/audit/syslog"
 
@@ -55,6 +56,7 @@ import (
 	physMSSQL "github.com/hashicorp/vault/physical/mssql"
 	physMySQL "github.com/hashicorp/vault/physical/mysql"
 	physOCI "github.com/hashicorp/vault/physical/oci"
+	physPlugin "github.com/hashicorp/vault/physical/plugin"
 	physPostgreSQL "github.com/hashicorp/vault/physical/postgresql"
 	physRaft "github.com/hashicorp/vault/physical/raft"
 	physS3 "github.com/hashicorp/vault/physical/s3"
@@ -110,11 +112,16 @@ const (
 	flagNameAllowedResponseHeaders = "allowed-response-headers"
 	// flagNameTokenType is the flag name used to force a specific token type
 	flagNameTokenType = "token-type"
+	// flagNameGroupSyncInterval is the flag name used to set how often the external group memberships of an auth mount are synced
+	flagNameGroupSyncInterval = "group-sync-interval"
+	// flagNamePluginVersion is the flag name used to pin the version of the plugin of a mount
+	flagNamePluginVersion = "plugin-version"
 )
 
 var (
 	auditBackends = map[string]audit.Factory{
 		"file":   auditFile.Factory,
+		"kafka":  auditKafka.Factory,
 		"socket": auditSocket.Factory,
 		"syslog": auditSyslog.Factory,
 	}
@@ -153,6 +160,7 @@ var (
 		"mssql":                  physMSSQL.NewMSSQLBackend,
 		"mysql":                  physMySQL.NewMySQLBackend,
 		"oci":                    physOCI.NewBackend,
+		"plugin":                 physPlugin.NewPluginBackend,
 		"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
 		"s3":                     physS3.NewS3Backend,
 		"spanner":                physSpanner.NewBackend,
@@ -274,6 +282,16 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"identity": func() (cli.Command, error) {
+			return &IdentityCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"identity dedupe": func() (cli.Command, error) {
+			return &IdentityDedupeCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"lease": func() (cli.Command, error) {
 			return &LeaseCommand{
 				BaseCommand: getBaseCommand(),
@@ -330,11 +348,22 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator diagnose": func() (cli.Command, error) {
+			return &OperatorDiagnoseCommand{
+				BaseCommand:      getBaseCommand(),
+				PhysicalBackends: physicalBackends,
+			}, nil
+		},
 		"operator generate-root": func() (cli.Command, error) {
 			return &OperatorGenerateRootCommand{
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator import": func() (cli.Command, error) {
+			return &OperatorImportCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"operator init": func() (cli.Command, error) {
 			return &OperatorInitCommand{
 				BaseCommand: getBaseCommand(),
@@ -402,6 +431,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator seal-migrate": func() (cli.Command, error) {
+			return &OperatorSealMigrateCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"operator step-down": func() (cli.Command, error) {
 			return &OperatorStepDownCommand{
 				BaseCommand: getBaseCommand(),
@@ -472,6 +506,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"policy test": func() (cli.Command, error) {
+			return &PolicyTestCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"policy write": func() (cli.Command, error) {
 			return &PolicyWriteCommand{
 				BaseCommand: getBaseCommand(),
@@ -617,6 +656,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"kv diff": func() (cli.Command, error) {
+			return &KVDiffCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"kv get": func() (cli.Command, error) {
 			return &KVGetCommand{
 				BaseCommand: getBaseCommand(),
@@ -43,6 +43,15 @@ func OutputData(ui cli.Ui, data interface{}) int {
 }
 
 func outputWithFormat(ui cli.Ui, secret *api.Secret, data interface{}) int {
+	// Commands which don't print the requested field themselves leave it to
+	// be picked from their output here
+	if field := Field(ui); field != "" {
+		if secret != nil {
+			return PrintRawField(ui, secret, field)
+		}
+		return PrintRawField(ui, data, field)
+	}
+
 	format := Format(ui)
 	formatter, ok := Formatters[format]
 	if !ok {
@@ -83,6 +92,16 @@ func Format(ui cli.Ui) string {
 	return format
 }
 
+// Field returns the -field given on the command line, if any.
+func Field(ui cli.Ui) string {
+	switch ui.(type) {
+	case *VaultUI:
+		return ui.(*VaultUI).field
+	}
+
+	return ""
+}
+
 // An output formatter for json output of an object
 type JsonFormatter struct{}
 
@@ -334,6 +353,15 @@ func OutputSealStatus(ui cli.Ui, client *api.Client, status *api.SealStatusRespo
 		out = append(out, fmt.Sprintf("Seal Migration in Progress | %t", status.Migration))
 	}
 
+	if ms := status.MigrationStatus; ms != nil {
+		out = append(out, fmt.Sprintf("Seal Migration | %s -> %s", ms.From, ms.To))
+		out = append(out, fmt.Sprintf("Seal Migration State | %s", ms.State))
+		out = append(out, fmt.Sprintf("Seal Migration Progress | %d/%d", ms.Progress, ms.Total))
+		if ms.Error != "" {
+			out = append(out, fmt.Sprintf("Seal Migration Error | %s", ms.Error))
+		}
+	}
+
 	out = append(out, fmt.Sprintf("Version | %s", status.Version))
 
 	if status.ClusterName != "" && status.ClusterID != "" {
@@ -2,7 +2,9 @@ package command
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -135,3 +137,40 @@ func Test_Format_Parsing(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldPathValue(t *testing.T) {
+	secret := &api.Secret{
+		LeaseDuration: 60,
+		Data: map[string]interface{}{
+			"keys": []string{"foo", "bar"},
+			"a.b":  "c",
+		},
+	}
+
+	cases := []struct {
+		path string
+		val  interface{}
+		err  bool
+	}{
+		{".data.keys[0]", "foo", false},
+		{".data.keys[-1]", "bar", false},
+		{`$.data["a.b"]`, "c", false},
+		{".data['a.b']", "c", false},
+		{".lease_duration", json.Number("60"), false},
+		{".data.keys[2]", nil, true},
+		{".data.missing", nil, true},
+		{".data.keys.foo", nil, true},
+		{".data..keys", nil, true},
+		{".data.keys[x]", nil, true},
+	}
+
+	for _, tc := range cases {
+		val, err := fieldPathValue(secret, tc.path)
+		if (err != nil) != tc.err {
+			t.Fatalf("%s: unexpected error: %v", tc.path, err)
+		}
+		if !reflect.DeepEqual(val, tc.val) {
+			t.Fatalf("%s: expected %#v, got %#v", tc.path, tc.val, val)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*IdentityCommand)(nil)
+
+type IdentityCommand struct {
+	*BaseCommand
+}
+
+func (c *IdentityCommand) Synopsis() string {
+	return "Interact with identity entities"
+}
+
+func (c *IdentityCommand) Help() string {
+	helpText := `
+Usage: vault identity <subcommand> [options] [args]
+
+  This command groups subcommands for interacting with Vault's identity store.
+  These subcommands operate on the entities of the namespace that the
+  currently logged in token belongs to.
+
+  Preview the merge of entities sharing alias names across mounts:
+
+      $ vault identity dedupe -dry-run
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,228 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*IdentityDedupeCommand)(nil)
+var _ cli.CommandAutocomplete = (*IdentityDedupeCommand)(nil)
+
+type IdentityDedupeCommand struct {
+	*BaseCommand
+
+	flagDryRun           bool
+	flagMountAccessors   []string
+	flagCaseInsensitive  bool
+	flagTarget           string
+	flagMetadataConflict string
+	flagPolicies         string
+	flagForce            bool
+}
+
+func (c *IdentityDedupeCommand) Synopsis() string {
+	return "Merge entities sharing alias names across mounts"
+}
+
+func (c *IdentityDedupeCommand) Help() string {
+	helpText := `
+Usage: vault identity dedupe [options]
+
+  Finds entities holding aliases of the same name on different auth mounts,
+  and merges each set of them into a single entity. Entities are grouped
+  transitively, so that an entity sharing one alias name with a second
+  entity, and another with a third, is merged with both.
+
+  Preview the entities that would be merged, and the conflicts between them:
+
+      $ vault identity dedupe -dry-run
+
+  Merge duplicates from two mounts, ignoring the case of alias names:
+
+      $ vault identity dedupe -mount-accessor=auth_ldap_1234 \
+          -mount-accessor=auth_oidc_5678 -case-insensitive
+
+  Leave entities with conflicting metadata unmerged:
+
+      $ vault identity dedupe -metadata-conflict=skip
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *IdentityDedupeCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	f := set.NewFlagSet("Command Options")
+
+	f.BoolVar(&BoolVar{
+		Name:    "dry-run",
+		Target:  &c.flagDryRun,
+		Default: false,
+		Usage:   "Report the duplicate entities without merging them.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "mount-accessor",
+		Target: &c.flagMountAccessors,
+		Usage: "Accessor of a mount whose aliases are compared. This can be " +
+			"specified multiple times. If not given, the aliases of all " +
+			"mounts are compared.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "case-insensitive",
+		Target:  &c.flagCaseInsensitive,
+		Default: false,
+		Usage:   "Consider alias names differing only in case the same.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "target",
+		Target:     &c.flagTarget,
+		Default:    "oldest",
+		Completion: complete.PredictSet("oldest", "most_aliases"),
+		Usage: "How the entity the duplicates are merged into is chosen. " +
+			"Either \"oldest\" or \"most_aliases\".",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "metadata-conflict",
+		Target:     &c.flagMetadataConflict,
+		Default:    "keep_target",
+		Completion: complete.PredictSet("keep_target", "overwrite", "skip"),
+		Usage: "How metadata keys with different values are resolved. " +
+			"\"keep_target\" keeps the value of the entity merged into, " +
+			"\"overwrite\" takes the value of the newest merged entity, and " +
+			"\"skip\" leaves the duplicates unmerged.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "policies",
+		Target:     &c.flagPolicies,
+		Default:    "union",
+		Completion: complete.PredictSet("union", "keep_target"),
+		Usage: "How the policies of the duplicates are merged. \"union\" " +
+			"gives the entity merged into the policies of all duplicates, and " +
+			"\"keep_target\" keeps only its own.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "force",
+		Aliases: []string{"f"},
+		Target:  &c.flagForce,
+		Default: false,
+		Usage: "Merge duplicates with conflicting MFA secrets, keeping the " +
+			"secrets of the entity merged into.",
+	})
+
+	return set
+}
+
+func (c *IdentityDedupeCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *IdentityDedupeCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *IdentityDedupeCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	secret, err := client.Logical().Write("identity/entity/deduplicate", map[string]interface{}{
+		"dry_run":           c.flagDryRun,
+		"mount_accessors":   c.flagMountAccessors,
+		"case_insensitive":  c.flagCaseInsensitive,
+		"target":            c.flagTarget,
+		"metadata_conflict": c.flagMetadataConflict,
+		"policies":          c.flagPolicies,
+		"force":             c.flagForce,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error deduplicating entities: %s", err))
+		return 2
+	}
+	if secret == nil || secret.Data == nil {
+		c.UI.Error("No data returned from deduplication")
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	groups, _ := secret.Data["groups"].([]interface{})
+	if len(groups) == 0 {
+		c.UI.Output("No duplicate entities found.")
+		return 0
+	}
+
+	var conflicts []string
+	columns := []string{"To Entity | From Entities | Alias Names | Status"}
+	for _, raw := range groups {
+		group, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		status := "merged"
+		switch {
+		case group["skipped"] == true:
+			status = "skipped"
+		case c.flagDryRun:
+			status = "pending"
+		}
+		columns = append(columns, fmt.Sprintf("%v | %s | %s | %s",
+			group["to_entity_id"],
+			joinInterfaceStrings(group["from_entity_ids"]),
+			joinInterfaceStrings(group["alias_names"]),
+			status,
+		))
+
+		if raw, ok := group["conflicts"].([]interface{}); ok {
+			for _, conflict := range raw {
+				conflicts = append(conflicts, fmt.Sprintf("%v", conflict))
+			}
+		}
+	}
+	c.UI.Output(tableOutput(columns, nil))
+
+	if len(conflicts) > 0 {
+		c.UI.Output("\nConflicts:\n")
+		for _, conflict := range conflicts {
+			c.UI.Output("  - " + conflict)
+		}
+	}
+	return 0
+}
+
+// joinInterfaceStrings joins a decoded JSON list of strings with commas.
+func joinInterfaceStrings(raw interface{}) string {
+	list, _ := raw.([]interface{})
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		values = append(values, fmt.Sprintf("%v", v))
+	}
+	return strings.Join(values, ",")
+}
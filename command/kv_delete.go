@@ -16,6 +16,7 @@ type KVDeleteCommand struct {
 	*BaseCommand
 
 	flagVersions []string
+	flagRecurse  kvRecurseFlags
 }
 
 func (c *KVDeleteCommand) Synopsis() string {
@@ -38,6 +39,11 @@ Usage: vault kv delete [options] PATH
 
       $ vault kv delete -versions=3 secret/foo
 
+  To delete the latest version of every key under "apps/", listing them first:
+
+      $ vault kv delete -recurse -dry-run -mount=secret apps/
+      $ vault kv delete -recurse -mount=secret apps/
+
   To delete all versions and metadata, see the "vault kv metadata" subcommand.
 
   Additional flags and more advanced use cases are detailed below.
@@ -48,7 +54,7 @@ Usage: vault kv delete [options] PATH
 }
 
 func (c *KVDeleteCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 	// Common Options
 	f := set.NewFlagSet("Common Options")
 
@@ -59,6 +65,8 @@ func (c *KVDeleteCommand) Flags() *FlagSets {
 		Usage:   `Specifies the version numbers to delete.`,
 	})
 
+	c.flagRecurse.addFlags(f, "Deletes")
+
 	return set
 }
 
@@ -88,13 +96,22 @@ func (c *KVDeleteCommand) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.flagRecurse.validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 2
 	}
 
-	path := sanitizePath(args[0])
+	path := c.flagRecurse.path(args[0])
+	if c.flagRecurse.recurse {
+		return c.flagRecurse.run(c.UI, client, path, "delete", c.flagVersions)
+	}
+
 	mountPath, v2, err := isKVv2(path, client)
 	if err != nil {
 		c.UI.Error(err.Error())
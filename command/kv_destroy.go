@@ -15,6 +15,7 @@ type KVDestroyCommand struct {
 	*BaseCommand
 
 	flagVersions []string
+	flagRecurse  kvRecurseFlags
 }
 
 func (c *KVDestroyCommand) Synopsis() string {
@@ -32,6 +33,11 @@ Usage: vault kv destroy [options] KEY
 
       $ vault kv destroy -versions=3 secret/foo
 
+  To destroy the latest version of every key under "apps/", listing them first:
+
+      $ vault kv destroy -recurse -dry-run -mount=secret apps/
+      $ vault kv destroy -recurse -mount=secret apps/
+
   Additional flags and more advanced use cases are detailed below.
 
 ` + c.Flags().Help()
@@ -48,9 +54,12 @@ func (c *KVDestroyCommand) Flags() *FlagSets {
 		Name:    "versions",
 		Target:  &c.flagVersions,
 		Default: nil,
-		Usage:   `Specifies the version numbers to destroy.`,
+		Usage: `Specifies the version numbers to destroy. With "-recurse", ` +
+			`defaults to the latest version of each key.`,
 	})
 
+	c.flagRecurse.addFlags(f, "Destroys")
+
 	return set
 }
 
@@ -80,12 +89,17 @@ func (c *KVDestroyCommand) Run(args []string) int {
 		return 1
 	}
 
-	if len(c.flagVersions) == 0 {
+	if err := c.flagRecurse.validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.flagVersions) == 0 && !c.flagRecurse.recurse {
 		c.UI.Error("No versions provided, use the \"-versions\" flag to specify the version to destroy.")
 		return 1
 	}
 	var err error
-	path := sanitizePath(args[0])
+	path := c.flagRecurse.path(args[0])
 
 	client, err := c.Client()
 	if err != nil {
@@ -93,6 +107,10 @@ func (c *KVDestroyCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.flagRecurse.recurse {
+		return c.flagRecurse.run(c.UI, client, path, "destroy", c.flagVersions)
+	}
+
 	mountPath, v2, err := isKVv2(path, client)
 	if err != nil {
 		c.UI.Error(err.Error())
@@ -0,0 +1,215 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/mitchellh/mapstructure"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*KVDiffCommand)(nil)
+var _ cli.CommandAutocomplete = (*KVDiffCommand)(nil)
+
+type KVDiffCommand struct {
+	*BaseCommand
+
+	flagVersions []string
+}
+
+// kvFieldDiff describes a change to a single field between two versions of
+// a secret.
+type kvFieldDiff struct {
+	Field  string      `json:"field"`
+	Change string      `json:"change"`
+	Old    interface{} `json:"old,omitempty"`
+	New    interface{} `json:"new,omitempty"`
+}
+
+// kvDiff is the structured output of the diff command.
+type kvDiff struct {
+	Path        string         `json:"path"`
+	FromVersion int            `json:"from_version"`
+	ToVersion   int            `json:"to_version"`
+	Changes     []*kvFieldDiff `json:"changes"`
+}
+
+func (c *KVDiffCommand) Synopsis() string {
+	return "Compares two versions of a secret in the KV store"
+}
+
+func (c *KVDiffCommand) Help() string {
+	helpText := `
+Usage: vault kv diff [options] KEY
+
+  Compares two versions of the data at the given key name and reports the
+  fields that were added, removed, or changed between them. This command only
+  works with the versioned key-value store.
+
+  To compare version 3 of key foo with version 5:
+
+      $ vault kv diff -versions=3,5 secret/foo
+
+  If only one version is given, it is compared with the current version:
+
+      $ vault kv diff -versions=3 secret/foo
+
+  Additional flags and more advanced use cases are detailed below.
+
+` + c.Flags().Help()
+	return strings.TrimSpace(helpText)
+}
+
+func (c *KVDiffCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:    "versions",
+		Target:  &c.flagVersions,
+		Default: nil,
+		Usage: `Specifies the two version numbers to compare. If only one is ` +
+			`given, it is compared with the current version.`,
+	})
+
+	return set
+}
+
+func (c *KVDiffCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *KVDiffCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *KVDiffCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	versions := kvParseVersionsFlags(c.flagVersions)
+	if len(versions) < 1 || len(versions) > 2 {
+		c.UI.Error("One or two versions must be specified with -versions")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	path := sanitizePath(args[0])
+	mountPath, v2, err := isKVv2(path, client)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+	if !v2 {
+		c.UI.Error("Diff not supported on KV Version 1")
+		return 1
+	}
+	for _, version := range versions {
+		if _, err := strconv.Atoi(version); err != nil {
+			c.UI.Error(fmt.Sprintf("Invalid version %q", version))
+			return 1
+		}
+	}
+
+	path = addPrefixToVKVPath(path, mountPath, "diff")
+	secret, err := client.Logical().ReadWithData(path, map[string][]string{
+		"versions": []string{strings.Join(versions, ",")},
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error comparing versions of %s: %s", args[0], err))
+		return 2
+	}
+	if secret == nil {
+		c.UI.Error(fmt.Sprintf("No value found at %s", args[0]))
+		return 2
+	}
+
+	diff, err := parseKVDiff(secret)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error parsing diff of %s: %s", args[0], err))
+		return 2
+	}
+
+	switch Format(c.UI) {
+	case "table":
+		if len(diff.Changes) == 0 {
+			c.UI.Output(fmt.Sprintf("No changes between versions %d and %d", diff.FromVersion, diff.ToVersion))
+			return 0
+		}
+
+		out := []string{"Field | Change | Version " + strconv.Itoa(diff.FromVersion) + " | Version " + strconv.Itoa(diff.ToVersion)}
+		for _, d := range diff.Changes {
+			out = append(out, fmt.Sprintf("%s | %s | %s | %s",
+				d.Field, d.Change, kvDiffValue(d.Old), kvDiffValue(d.New)))
+		}
+		c.UI.Output(tableOutput(out, nil))
+		return 0
+	default:
+		return OutputData(c.UI, diff)
+	}
+}
+
+// parseKVDiff returns the diff returned by the diff endpoint of a KV
+// version 2 mount.
+func parseKVDiff(secret *api.Secret) (*kvDiff, error) {
+	diff := &kvDiff{
+		Changes: []*kvFieldDiff{},
+	}
+	diff.Path, _ = secret.Data["path"].(string)
+
+	var err error
+	if diff.FromVersion, err = parseKVVersion(secret.Data["from_version"]); err != nil {
+		return nil, err
+	}
+	if diff.ToVersion, err = parseKVVersion(secret.Data["to_version"]); err != nil {
+		return nil, err
+	}
+	if err := mapstructure.WeakDecode(secret.Data["changes"], &diff.Changes); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+func parseKVVersion(raw interface{}) (int, error) {
+	switch v := raw.(type) {
+	case fmt.Stringer:
+		return strconv.Atoi(v.String())
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("unexpected version type %T", raw)
+	}
+}
+
+func kvDiffValue(v interface{}) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%v", v)
+}
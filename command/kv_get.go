@@ -43,7 +43,7 @@ Usage: vault kv get [options] KEY
 }
 
 func (c *KVGetCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	// Common Options
 	f := set.NewFlagSet("Common Options")
@@ -123,7 +123,7 @@ func (c *KVGetCommand) Run(args []string) int {
 	}
 
 	if c.flagField != "" {
-		if v2 {
+		if v2 && !isFieldPath(c.flagField) {
 			// This is a v2, pass in the data field
 			if data, ok := secret.Data["data"]; ok && data != nil {
 				// If they requested a literal "data" see if they meant actual
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/mitchellh/cli"
 )
 
 func kvReadRequest(client *api.Client, path string, params map[string]string) (*api.Secret, error) {
@@ -150,3 +152,142 @@ func kvParseVersionsFlags(versions []string) []string {
 
 	return versionsOut
 }
+
+// kvRecurseFlags are the flags of the KV commands which can act on every
+// secret under a path, rather than on a single one.
+type kvRecurseFlags struct {
+	mount   string
+	recurse bool
+	dryRun  bool
+}
+
+func (k *kvRecurseFlags) addFlags(f *FlagSet, action string) {
+	f.StringVar(&StringVar{
+		Name:    "mount",
+		Target:  &k.mount,
+		Default: "",
+		Usage: "Specifies the path of the KV mount. When given, the path " +
+			"argument is relative to the mount.",
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "recurse",
+		Target:  &k.recurse,
+		Default: false,
+		Usage: fmt.Sprintf("%s every secret under the path, which is "+
+			"treated as a folder. The tree is walked by the server, and each "+
+			"secret is checked against the caller's policies. Only supported "+
+			"on KV version 2.", action),
+	})
+
+	f.BoolVar(&BoolVar{
+		Name:    "dry-run",
+		Target:  &k.dryRun,
+		Default: false,
+		Usage:   `Lists the secrets that "-recurse" would act on, without changing them.`,
+	})
+}
+
+// path returns the full path for the path argument, which is relative to
+// the mount if one was given.
+func (k *kvRecurseFlags) path(arg string) string {
+	if k.mount == "" {
+		return sanitizePath(arg)
+	}
+	return sanitizePath(path.Join(sanitizePath(k.mount), arg))
+}
+
+// validate checks that the flags are consistent.
+func (k *kvRecurseFlags) validate() error {
+	if k.dryRun && !k.recurse {
+		return errors.New(`"-dry-run" requires "-recurse"`)
+	}
+	return nil
+}
+
+// run performs the operation on every secret under the path using the
+// recursive delete endpoint of the KV version 2 mount, and outputs the
+// result. It returns the exit code of the command.
+func (k *kvRecurseFlags) run(ui cli.Ui, client *api.Client, p, operation string, versions []string) int {
+	mountPath, v2, err := isKVv2(p, client)
+	if err != nil {
+		ui.Error(err.Error())
+		return 2
+	}
+	if !v2 {
+		ui.Error("Recursive operations not supported on KV Version 1")
+		return 1
+	}
+
+	endpoint := path.Join(mountPath, "batch-delete")
+	data := map[string]interface{}{
+		"path":      strings.TrimPrefix(p, sanitizePath(mountPath)),
+		"operation": operation,
+		"dry_run":   k.dryRun,
+	}
+	if len(versions) > 0 {
+		data["versions"] = kvParseVersionsFlags(versions)
+	}
+
+	secret, err := client.Logical().Write(endpoint, data)
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error writing data to %s: %s", endpoint, err))
+		if secret != nil {
+			OutputSecret(ui, secret)
+		}
+		return 2
+	}
+	if secret == nil {
+		ui.Error(fmt.Sprintf("No response from %s", endpoint))
+		return 2
+	}
+
+	items, _ := secret.Data["items"].([]interface{})
+	failed := false
+	out := []string{"Path | Status | Errors"}
+	if k.dryRun {
+		out = []string{"Path"}
+	}
+	for _, raw := range items {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		itemPath := path.Join(mountPath, fmt.Sprintf("%v", item["path"]))
+		if k.dryRun {
+			out = append(out, itemPath)
+			continue
+		}
+
+		status, _ := strconv.Atoi(fmt.Sprintf("%v", item["status"]))
+		if status >= 400 {
+			failed = true
+		}
+		var errs []string
+		if raw, ok := item["errors"].([]interface{}); ok {
+			for _, e := range raw {
+				errs = append(errs, strings.Join(strings.Fields(fmt.Sprintf("%v", e)), " "))
+			}
+		}
+		out = append(out, fmt.Sprintf("%s | %d | %s", itemPath, status, strings.Join(errs, ", ")))
+	}
+
+	code := 0
+	if failed {
+		code = 2
+	}
+
+	if Format(ui) != "table" {
+		if ret := OutputSecret(ui, secret); ret != 0 {
+			return ret
+		}
+		return code
+	}
+
+	if len(items) == 0 {
+		ui.Warn(fmt.Sprintf("No secrets found under %s", p))
+		return code
+	}
+	ui.Output(tableOutput(out, nil))
+	return code
+}
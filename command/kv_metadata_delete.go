@@ -13,6 +13,8 @@ var _ cli.CommandAutocomplete = (*KVMetadataDeleteCommand)(nil)
 
 type KVMetadataDeleteCommand struct {
 	*BaseCommand
+
+	flagRecurse kvRecurseFlags
 }
 
 func (c *KVMetadataDeleteCommand) Synopsis() string {
@@ -27,6 +29,11 @@ Usage: vault kv metadata delete [options] PATH
 
       $ vault kv metadata delete secret/foo
 
+  To delete every key under "apps/", listing them first:
+
+      $ vault kv metadata delete -recurse -dry-run -mount=secret apps/
+      $ vault kv metadata delete -recurse -mount=secret apps/
+
   Additional flags and more advanced use cases are detailed below.
 
 ` + c.Flags().Help()
@@ -35,7 +42,14 @@ Usage: vault kv metadata delete [options] PATH
 }
 
 func (c *KVMetadataDeleteCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	// Common Options
+	f := set.NewFlagSet("Common Options")
+
+	c.flagRecurse.addFlags(f, "Deletes all versions and metadata of")
+
+	return set
 }
 
 func (c *KVMetadataDeleteCommand) AutocompleteArgs() complete.Predictor {
@@ -64,13 +78,22 @@ func (c *KVMetadataDeleteCommand) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.flagRecurse.validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
 	client, err := c.Client()
 	if err != nil {
 		c.UI.Error(err.Error())
 		return 2
 	}
 
-	path := sanitizePath(args[0])
+	path := c.flagRecurse.path(args[0])
+	if c.flagRecurse.recurse {
+		return c.flagRecurse.run(c.UI, client, path, "delete-metadata", nil)
+	}
+
 	mountPath, v2, err := isKVv2(path, client)
 	if err != nil {
 		c.UI.Error(err.Error())
@@ -50,7 +50,7 @@ Usage: vault kv patch [options] KEY [DATA]
 }
 
 func (c *KVPatchCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	return set
 }
@@ -534,3 +534,128 @@ func TestKVMetadataGetCommand(t *testing.T) {
 		assertNoTabs(t, cmd)
 	})
 }
+
+func testKVDiffCommand(tb testing.TB) (*cli.MockUi, *KVDiffCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &KVDiffCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestKVDiffCommand(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  []string
+		code int
+	}{
+		{
+			"not_enough_args",
+			[]string{},
+			[]string{"Not enough arguments"},
+			1,
+		},
+		{
+			"too_many_args",
+			[]string{"-versions", "1,2", "foo", "bar"},
+			[]string{"Too many arguments"},
+			1,
+		},
+		{
+			"no_versions",
+			[]string{"kv/diff/foo"},
+			[]string{"versions must be specified"},
+			1,
+		},
+		{
+			"invalid_version",
+			[]string{"-versions", "one", "kv/diff/foo"},
+			[]string{"Invalid version"},
+			1,
+		},
+		{
+			"v1",
+			[]string{"-versions", "1,2", "secret/diff/foo"},
+			[]string{"not supported on KV Version 1"},
+			1,
+		},
+		{
+			"not_found",
+			[]string{"-versions", "1,2", "kv/nope/not/once/never"},
+			[]string{"No value found"},
+			2,
+		},
+		{
+			"versions",
+			[]string{"-versions", "1,2", "kv/diff/foo"},
+			[]string{"Version 1", "Version 2", "foo", "changed", "baz", "removed", "zip", "added"},
+			0,
+		},
+		{
+			"current",
+			[]string{"-versions", "2", "kv/diff/foo"},
+			[]string{"No changes between versions 2 and 2"},
+			0,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+		if err := client.Sys().Mount("kv/", &api.MountInput{
+			Type: "kv-v2",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Give time for the upgrade code to run/finish
+		time.Sleep(time.Second)
+
+		for _, data := range []map[string]interface{}{
+			{"foo": "bar", "baz": "qux"},
+			{"foo": "bar2", "zip": "zap"},
+		} {
+			if _, err := client.Logical().Write("kv/data/diff/foo", map[string]interface{}{
+				"data": data,
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				ui, cmd := testKVDiffCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				for _, out := range tc.out {
+					if !strings.Contains(combined, out) {
+						t.Errorf("expected %q to contain %q", combined, out)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testKVDiffCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
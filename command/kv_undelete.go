@@ -15,6 +15,7 @@ type KVUndeleteCommand struct {
 	*BaseCommand
 
 	flagVersions []string
+	flagRecurse  kvRecurseFlags
 }
 
 func (c *KVUndeleteCommand) Synopsis() string {
@@ -32,6 +33,10 @@ Usage: vault kv undelete [options] KEY
   
       $ vault kv undelete -versions=3 secret/foo
 
+  To undelete the latest version of every key under "apps/":
+
+      $ vault kv undelete -recurse -mount=secret apps/
+
   Additional flags and more advanced use cases are detailed below.
 
 ` + c.Flags().Help()
@@ -48,9 +53,12 @@ func (c *KVUndeleteCommand) Flags() *FlagSets {
 		Name:    "versions",
 		Target:  &c.flagVersions,
 		Default: nil,
-		Usage:   `Specifies the version numbers to undelete.`,
+		Usage: `Specifies the version numbers to undelete. With "-recurse", ` +
+			`defaults to the latest version of each key.`,
 	})
 
+	c.flagRecurse.addFlags(f, "Undeletes")
+
 	return set
 }
 
@@ -80,7 +88,12 @@ func (c *KVUndeleteCommand) Run(args []string) int {
 		return 1
 	}
 
-	if len(c.flagVersions) == 0 {
+	if err := c.flagRecurse.validate(); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.flagVersions) == 0 && !c.flagRecurse.recurse {
 		c.UI.Error("No versions provided, use the \"-versions\" flag to specify the version to undelete.")
 		return 1
 	}
@@ -91,7 +104,11 @@ func (c *KVUndeleteCommand) Run(args []string) int {
 		return 2
 	}
 
-	path := sanitizePath(args[0])
+	path := c.flagRecurse.path(args[0])
+	if c.flagRecurse.recurse {
+		return c.flagRecurse.run(c.UI, client, path, "undelete", c.flagVersions)
+	}
+
 	mountPath, v2, err := isKVv2(path, client)
 	if err != nil {
 		c.UI.Error(err.Error())
@@ -18,6 +18,7 @@ type LeaseRevokeCommand struct {
 	flagForce  bool
 	flagPrefix bool
 	flagSync   bool
+	flagTags   map[string]string
 }
 
 func (c *LeaseRevokeCommand) Synopsis() string {
@@ -29,7 +30,8 @@ func (c *LeaseRevokeCommand) Help() string {
 Usage: vault lease revoke [options] ID
 
   Revokes secrets by their lease ID. This command can revoke a single secret
-  or multiple secrets based on a path-matched prefix.
+  or multiple secrets based on a path-matched prefix or on the tags of their
+  leases.
 
   The default behavior when not using -force is to revoke asynchronously; Vault
   will queue the revocation and keep trying if it fails (including across
@@ -45,6 +47,10 @@ Usage: vault lease revoke [options] ID
 
       $ vault lease revoke -prefix aws/creds/deploy
 
+  Revoke all leases tagged for an application:
+
+      $ vault lease revoke -tag=app=checkout
+
   Force delete leases from Vault even if secret engine revocation fails:
 
       $ vault lease revoke -force -prefix consul/creds
@@ -80,6 +86,15 @@ func (c *LeaseRevokeCommand) Flags() *FlagSets {
 			"revoke multiple leases simultaneously.",
 	})
 
+	f.StringMapVar(&StringMapVar{
+		Name:       "tag",
+		Target:     &c.flagTags,
+		Completion: complete.PredictAnything,
+		Usage: "Key-value pair provided as key=value which the leases to revoke " +
+			"are tagged with, instead of a lease ID. Leases carrying all the " +
+			"given tags are revoked. This can be specified multiple times.",
+	})
+
 	f.BoolVar(&BoolVar{
 		Name:    "sync",
 		Target:  &c.flagSync,
@@ -108,6 +123,10 @@ func (c *LeaseRevokeCommand) Run(args []string) int {
 	}
 
 	args = f.Args()
+	if len(c.flagTags) > 0 {
+		return c.runTags(args)
+	}
+
 	switch {
 	case len(args) < 1:
 		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
@@ -174,3 +193,35 @@ func (c *LeaseRevokeCommand) Run(args []string) int {
 	c.UI.Output("All revocation operations queued successfully!")
 	return 0
 }
+
+// runTags revokes the leases carrying the tags of the -tag flag.
+func (c *LeaseRevokeCommand) runTags(args []string) int {
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0 with -tag, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagForce || c.flagPrefix {
+		c.UI.Error("Specifying -tag is not supported with -force or -prefix")
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	if err := client.Sys().RevokeByTag(c.flagTags, c.flagSync); err != nil {
+		c.UI.Error(fmt.Sprintf("Error revoking leases by tag: %s", err))
+		return 2
+	}
+
+	if c.flagSync {
+		c.UI.Output("Success! Revoked any leases with the given tags")
+		return 0
+	}
+
+	c.UI.Output("All revocation operations queued successfully!")
+	return 0
+}
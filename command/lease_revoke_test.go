@@ -114,6 +114,57 @@ func TestLeaseRevokeCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("tag", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		if err := client.Sys().Mount("secret-leased", &api.MountInput{
+			Type: "generic-leased",
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		data := map[string]interface{}{
+			"key":   "value",
+			"lease": "1m",
+			"lease_tags": map[string]interface{}{
+				"app": "checkout",
+			},
+		}
+		if _, err := client.Logical().Write("secret-leased/revoke/tag", data); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Logical().Read("secret-leased/revoke/tag"); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testLeaseRevokeCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-sync", "-tag=app=checkout",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Success! Revoked any leases with the given tags"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		leaseIDs, err := client.Sys().LookupByTag(map[string]string{"app": "checkout"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(leaseIDs) != 0 {
+			t.Errorf("expected no leases, got %v", leaseIDs)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -79,7 +79,7 @@ Usage: vault login [options] [AUTH K=V...]
 }
 
 func (c *LoginCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("Command Options")
 
@@ -21,12 +21,13 @@ import (
 type VaultUI struct {
 	cli.Ui
 	format string
+	field  string
 }
 
 // setupEnv parses args and may replace them and sets some env vars to known
 // values based on format options
-func setupEnv(args []string) (retArgs []string, format string, outputCurlString bool) {
-	var nextArgFormat bool
+func setupEnv(args []string) (retArgs []string, format string, field string, outputCurlString bool) {
+	var nextArgFormat, nextArgField bool
 
 	for _, arg := range args {
 		if nextArgFormat {
@@ -34,6 +35,11 @@ func setupEnv(args []string) (retArgs []string, format string, outputCurlString
 			format = arg
 			continue
 		}
+		if nextArgField {
+			nextArgField = false
+			field = arg
+			continue
+		}
 
 		if arg == "--" {
 			break
@@ -60,6 +66,18 @@ func setupEnv(args []string) (retArgs []string, format string, outputCurlString
 		if arg == "-format" || arg == "--format" {
 			nextArgFormat = true
 		}
+
+		// The field is also needed by commands which leave printing it to
+		// the formatter
+		if strings.HasPrefix(arg, "--field=") {
+			field = strings.TrimPrefix(arg, "--field=")
+		}
+		if strings.HasPrefix(arg, "-field=") {
+			field = strings.TrimPrefix(arg, "-field=")
+		}
+		if arg == "-field" || arg == "--field" {
+			nextArgField = true
+		}
 	}
 
 	envVaultFormat := os.Getenv(EnvVaultFormat)
@@ -73,7 +91,7 @@ func setupEnv(args []string) (retArgs []string, format string, outputCurlString
 		format = "table"
 	}
 
-	return args, format, outputCurlString
+	return args, format, field, outputCurlString
 }
 
 type RunOptions struct {
@@ -95,9 +113,9 @@ func RunCustom(args []string, runOpts *RunOptions) int {
 		runOpts = &RunOptions{}
 	}
 
-	var format string
+	var format, field string
 	var outputCurlString bool
-	args, format, outputCurlString = setupEnv(args)
+	args, format, field, outputCurlString = setupEnv(args)
 
 	// Don't use color if disabled
 	useColor := true
@@ -141,6 +159,7 @@ func RunCustom(args []string, runOpts *RunOptions) int {
 			},
 		},
 		format: format,
+		field:  field,
 	}
 
 	serverCmdUi := &VaultUI{
@@ -0,0 +1,539 @@
+package command
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/internalshared/configutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorDiagnoseCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorDiagnoseCommand)(nil)
+
+const (
+	diagnosePass = "pass"
+	diagnoseWarn = "warn"
+	diagnoseFail = "fail"
+	diagnoseSkip = "skip"
+
+	// diagnoseCertExpiryWarning is how long before expiry TLS certificates
+	// are reported.
+	diagnoseCertExpiryWarning = 30 * 24 * time.Hour
+
+	// diagnoseMaxClockSkew is the clock skew above which a warning is
+	// reported.
+	diagnoseMaxClockSkew = 5 * time.Second
+)
+
+type OperatorDiagnoseCommand struct {
+	*BaseCommand
+
+	PhysicalBackends map[string]physical.Factory
+
+	flagConfigs        []string
+	flagClockReference string
+	flagTimeout        time.Duration
+
+	results []*diagnoseResult
+}
+
+// diagnoseResult is the outcome of a single check.
+type diagnoseResult struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Details string `json:"details"`
+}
+
+func (c *OperatorDiagnoseCommand) Synopsis() string {
+	return "Checks a server configuration and its environment"
+}
+
+func (c *OperatorDiagnoseCommand) Help() string {
+	helpText := `
+Usage: vault operator diagnose [options]
+
+  Checks a Vault server configuration and the environment it runs in before
+  starting the server. This parses the configuration, and checks that the
+  storage backend and seals can be reached, that the TLS certificates are
+  valid, that the listener addresses can be bound, that sensitive files have
+  safe permissions and that the clock is not skewed.
+
+  This does not start a server, nor does it write to storage. Listeners can't
+  be bound while a server is running with the same configuration.
+
+  Check a configuration file:
+
+      $ vault operator diagnose -config=/etc/vault/config.hcl
+
+  Check the clock against a specific server:
+
+      $ vault operator diagnose -config=/etc/vault/config.hcl \
+          -clock-reference=https://vault.example.com:8200
+
+  The command exits with 2 if any check fails, and 0 otherwise.
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorDiagnoseCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:   "config",
+		Target: &c.flagConfigs,
+		Completion: complete.PredictOr(
+			complete.PredictFiles("*.hcl"),
+			complete.PredictFiles("*.json"),
+			complete.PredictDirs("*"),
+		),
+		Usage: "Path to a configuration file or directory of configuration " +
+			"files. This flag can be specified multiple times to load multiple " +
+			"configurations. If the path is a directory, all files which end in " +
+			".hcl or .json are loaded.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "clock-reference",
+		Target:     &c.flagClockReference,
+		Completion: complete.PredictAnything,
+		Usage: "URL of a server whose Date header the local clock is compared " +
+			"to. Defaults to the api_addr of the configuration.",
+	})
+
+	f.DurationVar(&DurationVar{
+		Name:       "timeout",
+		Target:     &c.flagTimeout,
+		Completion: complete.PredictAnything,
+		Default:    10 * time.Second,
+		Usage:      "Time to wait for each remote check.",
+	})
+
+	return set
+}
+
+func (c *OperatorDiagnoseCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorDiagnoseCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorDiagnoseCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.flagConfigs) == 0 {
+		c.UI.Error("Must specify at least one config path using -config")
+		return 1
+	}
+
+	c.diagnose()
+
+	if Format(c.UI) != "table" {
+		OutputData(c.UI, c.results)
+	} else {
+		out := []string{"Check | Status | Details"}
+		for _, result := range c.results {
+			out = append(out, fmt.Sprintf("%s | %s | %s", result.Check, result.Status, result.Details))
+		}
+		c.UI.Output(tableOutput(out, nil))
+	}
+
+	for _, result := range c.results {
+		if result.Status == diagnoseFail {
+			return 2
+		}
+	}
+	return 0
+}
+
+func (c *OperatorDiagnoseCommand) report(check, status, format string, args ...interface{}) {
+	c.results = append(c.results, &diagnoseResult{
+		Check:   check,
+		Status:  status,
+		Details: fmt.Sprintf(format, args...),
+	})
+}
+
+// diagnose runs all the checks, recording their results. Checks which need
+// the configuration are skipped if it can't be loaded.
+func (c *OperatorDiagnoseCommand) diagnose() {
+	c.results = nil
+
+	var config *server.Config
+	for _, path := range c.flagConfigs {
+		current, err := server.LoadConfig(path)
+		if err != nil {
+			c.report("config", diagnoseFail, "error loading %s: %s", path, err)
+			return
+		}
+
+		if config == nil {
+			config = current
+		} else {
+			config = config.Merge(current)
+		}
+	}
+	c.report("config", diagnosePass, "loaded %s", strings.Join(c.flagConfigs, ", "))
+
+	c.diagnosePermissions(config)
+
+	if config.Storage == nil {
+		c.report("storage", diagnoseFail, "a storage backend must be specified")
+	} else {
+		c.diagnoseStorage("storage", config.Storage)
+	}
+	if config.HAStorage != nil {
+		c.diagnoseStorage("ha_storage", config.HAStorage)
+	}
+
+	c.diagnoseSeals(config)
+
+	for _, ln := range config.Listeners {
+		c.diagnoseListener(ln)
+	}
+
+	c.diagnoseClock(config)
+}
+
+// diagnosePermissions reports configuration, TLS and plugin files which can
+// be modified, or keys which can be read, by other users.
+func (c *OperatorDiagnoseCommand) diagnosePermissions(config *server.Config) {
+	if runtime.GOOS == "windows" {
+		c.report("permissions", diagnoseSkip, "file modes are not checked on Windows")
+		return
+	}
+
+	checked, issues := 0, 0
+	check := func(path string, secret bool) {
+		if path == "" {
+			return
+		}
+		checked++
+
+		info, err := os.Stat(path)
+		if err != nil {
+			issues++
+			c.report("permissions", diagnoseFail, "%s", err)
+			return
+		}
+		mode := info.Mode().Perm()
+		switch {
+		case mode&0002 != 0:
+			issues++
+			c.report("permissions", diagnoseWarn, "%s is writable by all users (%s)", path, mode)
+		case secret && mode&0077 != 0:
+			issues++
+			c.report("permissions", diagnoseWarn, "%s is accessible by other users (%s)", path, mode)
+		}
+	}
+
+	for _, path := range c.flagConfigs {
+		check(path, false)
+	}
+	for _, ln := range config.Listeners {
+		check(ln.TLSCertFile, false)
+		check(ln.TLSKeyFile, true)
+		check(ln.TLSClientCAFile, false)
+	}
+	check(config.PluginDirectory, false)
+
+	if issues == 0 {
+		c.report("permissions", diagnosePass, "checked %d files", checked)
+	}
+}
+
+// diagnoseStorage checks that the storage backend can be reached by reading
+// the seal configuration. File based backends only have their directory
+// checked, as they can't be opened while a server is using them.
+func (c *OperatorDiagnoseCommand) diagnoseStorage(check string, storage *server.Storage) {
+	factory, ok := c.PhysicalBackends[storage.Type]
+	if !ok {
+		c.report(check, diagnoseFail, "unknown storage type %q", storage.Type)
+		return
+	}
+
+	switch storage.Type {
+	case "inmem", "inmem_ha", "inmem_transactional", "inmem_transactional_ha":
+		c.report(check, diagnoseWarn, "%s storage does not persist data across restarts", storage.Type)
+		return
+
+	case "file", "file_transactional", "raft":
+		path := storage.Config["path"]
+		if path == "" {
+			c.report(check, diagnoseFail, "%s storage requires a path", storage.Type)
+			return
+		}
+		if err := diagnoseWritableDir(path); err != nil {
+			c.report(check, diagnoseFail, "%s storage path is not usable: %s", storage.Type, err)
+			return
+		}
+		c.report(check, diagnosePass, "%s storage path %s is writable", storage.Type, path)
+		return
+	}
+
+	backend, err := factory(storage.Config, log.NewNullLogger())
+	if err != nil {
+		c.report(check, diagnoseFail, "error initializing %s storage: %s", storage.Type, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.flagTimeout)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := backend.Get(ctx, "core/seal-config")
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			c.report(check, diagnoseFail, "error reading from %s storage: %s", storage.Type, err)
+			return
+		}
+		c.report(check, diagnosePass, "%s storage is reachable", storage.Type)
+	case <-ctx.Done():
+		c.report(check, diagnoseFail, "timed out reading from %s storage", storage.Type)
+	}
+}
+
+// diagnoseWritableDir returns an error if files can't be created in the
+// directory.
+func diagnoseWritableDir(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	f, err := ioutil.TempFile(path, ".vault-diagnose")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// diagnoseSeals checks that auto seals can be reached by encrypting and
+// decrypting a test value.
+func (c *OperatorDiagnoseCommand) diagnoseSeals(config *server.Config) {
+	seals := config.Seals
+	if len(seals) == 0 {
+		seals = []*configutil.KMS{{Type: wrapping.Shamir}}
+	}
+
+	for _, configSeal := range seals {
+		sealType := configSeal.Type
+		if !configSeal.Disabled && os.Getenv("VAULT_SEAL_TYPE") != "" {
+			sealType = os.Getenv("VAULT_SEAL_TYPE")
+		}
+		check := "seal." + sealType
+
+		if sealType == wrapping.Shamir {
+			c.report(check, diagnosePass, "unseal keys are required to unseal the server")
+			continue
+		}
+
+		sealConfig := *configSeal
+		sealConfig.Type = sealType
+		wrapper, err := configutil.ConfigureWrapper(&sealConfig, nil, nil, log.NewNullLogger())
+		if err != nil {
+			if errwrap.ContainsType(err, new(logical.KeyNotFoundError)) {
+				c.report(check, diagnoseWarn, "seal key not found: %s", err)
+			} else {
+				c.report(check, diagnoseFail, "error configuring seal: %s", err)
+			}
+			continue
+		}
+		if wrapper == nil {
+			continue
+		}
+
+		if err := c.diagnoseWrapper(wrapper); err != nil {
+			c.report(check, diagnoseFail, "%s", err)
+			continue
+		}
+		if configSeal.Disabled {
+			c.report(check, diagnosePass, "seal is reachable, and disabled for migration")
+		} else {
+			c.report(check, diagnosePass, "seal is reachable")
+		}
+	}
+}
+
+func (c *OperatorDiagnoseCommand) diagnoseWrapper(wrapper wrapping.Wrapper) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.flagTimeout)
+	defer cancel()
+	defer wrapper.Finalize(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() {
+		plaintext := []byte("vault operator diagnose")
+		blob, err := wrapper.Encrypt(ctx, plaintext, nil)
+		if err != nil {
+			errCh <- errwrap.Wrapf("error encrypting with seal: {{err}}", err)
+			return
+		}
+		decrypted, err := wrapper.Decrypt(ctx, blob, nil)
+		if err != nil {
+			errCh <- errwrap.Wrapf("error decrypting with seal: {{err}}", err)
+			return
+		}
+		if string(decrypted) != string(plaintext) {
+			errCh <- fmt.Errorf("seal decrypted an unexpected value")
+			return
+		}
+		errCh <- nil
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out reaching seal")
+	}
+}
+
+// diagnoseListener checks the TLS configuration of the listener, and that
+// its addresses can be bound.
+func (c *OperatorDiagnoseCommand) diagnoseListener(ln *configutil.Listener) {
+	check := fmt.Sprintf("listener.%s.%s", ln.Type, ln.Address)
+	if ln.Type != "tcp" {
+		c.report(check, diagnoseSkip, "only tcp listeners are checked")
+		return
+	}
+
+	if ln.TLSDisable {
+		c.report(check+".tls", diagnoseWarn, "TLS is disabled")
+	} else {
+		c.diagnoseTLS(check+".tls", ln)
+	}
+
+	for _, addr := range []string{ln.Address, ln.ClusterAddress} {
+		if addr == "" {
+			continue
+		}
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			c.report(check, diagnoseFail, "error binding %s: %s", addr, err)
+			continue
+		}
+		l.Close()
+		c.report(check, diagnosePass, "%s can be bound", addr)
+	}
+}
+
+func (c *OperatorDiagnoseCommand) diagnoseTLS(check string, ln *configutil.Listener) {
+	cert, err := tls.LoadX509KeyPair(ln.TLSCertFile, ln.TLSKeyFile)
+	if err != nil {
+		c.report(check, diagnoseFail, "error loading certificate: %s", err)
+		return
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		c.report(check, diagnoseFail, "error parsing certificate: %s", err)
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(leaf.NotBefore):
+		c.report(check, diagnoseFail, "certificate is not valid before %s", leaf.NotBefore.Format(time.RFC3339))
+	case now.After(leaf.NotAfter):
+		c.report(check, diagnoseFail, "certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	case leaf.NotAfter.Sub(now) < diagnoseCertExpiryWarning:
+		c.report(check, diagnoseWarn, "certificate expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	default:
+		c.report(check, diagnosePass, "certificate is valid until %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if ln.TLSClientCAFile != "" {
+		pem, err := ioutil.ReadFile(ln.TLSClientCAFile)
+		if err != nil {
+			c.report(check, diagnoseFail, "error reading client CA file: %s", err)
+			return
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			c.report(check, diagnoseFail, "no certificates found in client CA file %s", ln.TLSClientCAFile)
+		}
+	}
+}
+
+// diagnoseClock compares the local clock to the Date header returned by the
+// reference server.
+func (c *OperatorDiagnoseCommand) diagnoseClock(config *server.Config) {
+	reference := c.flagClockReference
+	if reference == "" {
+		reference = config.APIAddr
+	}
+	if reference == "" {
+		c.report("clock", diagnoseSkip, "no api_addr configured, use -clock-reference to check clock skew")
+		return
+	}
+
+	client := cleanhttp.DefaultClient()
+	client.Timeout = c.flagTimeout
+	// Only the Date header is read, and nothing is sent, so the server
+	// certificate is not verified: it may be issued by a CA this host
+	// doesn't trust yet.
+	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: true,
+	}
+
+	start := time.Now()
+	resp, err := client.Head(reference)
+	if err != nil {
+		c.report("clock", diagnoseWarn, "error reaching %s: %s", reference, err)
+		return
+	}
+	resp.Body.Close()
+	rtt := time.Since(start)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		c.report("clock", diagnoseWarn, "%s did not return a valid Date header", reference)
+		return
+	}
+
+	// The Date header has a resolution of a second
+	skew := date.Sub(start.Add(rtt / 2)).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > diagnoseMaxClockSkew {
+		c.report("clock", diagnoseWarn, "local clock is %s off from %s", skew, reference)
+		return
+	}
+	c.report("clock", diagnosePass, "local clock is within %s of %s", diagnoseMaxClockSkew, reference)
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorDiagnoseCommand(tb testing.TB) (*cli.MockUi, *OperatorDiagnoseCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorDiagnoseCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+		PhysicalBackends: physicalBackends,
+	}
+}
+
+func TestOperatorDiagnoseCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "vault-diagnose")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeConfig := func(name, storage string) string {
+		path := filepath.Join(dir, name)
+		config := fmt.Sprintf(`
+storage %q {
+  path = %q
+}
+
+listener "tcp" {
+  address     = "127.0.0.1:0"
+  tls_disable = true
+}
+`, storage, dir)
+		if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"no_config",
+			nil,
+			"Must specify at least one config path",
+			1,
+		},
+		{
+			"missing_config",
+			[]string{"-config", filepath.Join(dir, "missing.hcl")},
+			"error loading",
+			2,
+		},
+		{
+			"unknown_storage",
+			[]string{"-config", writeConfig("unknown.hcl", "nope")},
+			"unknown storage type",
+			2,
+		},
+		{
+			"file_storage",
+			[]string{"-config", writeConfig("file.hcl", "file")},
+			"file storage path " + dir + " is writable",
+			0,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ui, cmd := testOperatorDiagnoseCommand(t)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testOperatorDiagnoseCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -0,0 +1,186 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorImportCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorImportCommand)(nil)
+
+type OperatorImportCommand struct {
+	*BaseCommand
+
+	flagDryRun    bool
+	flagBatchSize int
+
+	testStdin io.Reader // for tests
+}
+
+func (c *OperatorImportCommand) Synopsis() string {
+	return "Imports secrets from a manifest into KV secrets engines"
+}
+
+func (c *OperatorImportCommand) Help() string {
+	helpText := `
+Usage: vault operator import [options] PATH
+
+  Imports the secrets listed in a JSON manifest from the local file PATH or
+  stdin into KV secrets engines. If PATH is "-", the manifest is read from
+  stdin. The manifest lists the path and data of each secret:
+
+      {
+        "secrets": [
+          { "path": "secret/db", "data": { "password": "..." } }
+        ]
+      }
+
+  Secrets are written in batches; if writing a secret fails, the other
+  secrets of its batch are restored to their previous state. The result of
+  each secret is reported.
+
+  Check a manifest without writing any secret:
+
+      $ vault operator import -dry-run secrets.json
+
+  Import secrets from stdin, 500 at a time:
+
+      $ cat secrets.json | vault operator import -batch-size=500 -
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorImportCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.BoolVar(&BoolVar{
+		Name:    "dry-run",
+		Target:  &c.flagDryRun,
+		Default: false,
+		Usage: "Check that each secret of the manifest can be written, without " +
+			"writing any.",
+	})
+
+	f.IntVar(&IntVar{
+		Name:    "batch-size",
+		Target:  &c.flagBatchSize,
+		Default: 0,
+		Usage: "Number of secrets written together. Overrides the batch size of " +
+			"the manifest; defaults to 100.",
+	})
+
+	return set
+}
+
+func (c *OperatorImportCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*.json")
+}
+
+func (c *OperatorImportCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorImportCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	switch {
+	case len(args) < 1:
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected 1, got %d)", len(args)))
+		return 1
+	case len(args) > 1:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	path := strings.TrimSpace(args[0])
+
+	var reader io.Reader
+	if path == "-" {
+		reader = os.Stdin
+		if c.testStdin != nil {
+			reader = c.testStdin
+		}
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error opening manifest: %s", err))
+			return 2
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var manifest map[string]interface{}
+	if err := jsonutil.DecodeJSONFromReader(reader, &manifest); err != nil {
+		c.UI.Error(fmt.Sprintf("Error parsing manifest: %s", err))
+		return 2
+	}
+	if c.flagDryRun {
+		manifest["dry_run"] = true
+	}
+	if c.flagBatchSize > 0 {
+		manifest["batch_size"] = c.flagBatchSize
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	secret, err := client.Logical().Write("sys/import", manifest)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error importing secrets: %s", err))
+		return 2
+	}
+	if secret == nil {
+		c.UI.Error("No response from the import")
+		return 2
+	}
+
+	var failed int64
+	if n, ok := secret.Data["failed"].(json.Number); ok {
+		failed, _ = n.Int64()
+	}
+	if Format(c.UI) != "table" {
+		if code := OutputSecret(c.UI, secret); code != 0 {
+			return code
+		}
+	} else {
+		items, _ := secret.Data["items"].([]interface{})
+		out := []string{"Path | Status | Errors"}
+		for _, itemRaw := range items {
+			item := itemRaw.(map[string]interface{})
+			var errs []string
+			if errsRaw, ok := item["errors"].([]interface{}); ok {
+				for _, e := range errsRaw {
+					errs = append(errs, fmt.Sprintf("%v", e))
+				}
+			}
+			out = append(out, fmt.Sprintf("%s | %s | %s", item["path"], item["status"], strings.Join(errs, "; ")))
+		}
+		c.UI.Output(tableOutput(out, nil))
+	}
+
+	if failed > 0 {
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorImportCommand(tb testing.TB) (*cli.MockUi, *OperatorImportCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorImportCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestOperatorImportCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			"Not enough arguments",
+			1,
+		},
+		{
+			"too_many_args",
+			[]string{"foo", "bar"},
+			"Too many arguments",
+			1,
+		},
+		{
+			"missing_file",
+			[]string{"/nope/not/real"},
+			"Error opening manifest",
+			2,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				client, closer := testVaultServer(t)
+				defer closer()
+
+				ui, cmd := testOperatorImportCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("integration", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		manifest := `{
+  "secrets": [
+    { "path": "secret/a", "data": { "value": "a" } },
+    { "path": "secret/b", "data": { "value": "b" } }
+  ]
+}`
+
+		ui, cmd := testOperatorImportCommand(t)
+		cmd.client = client
+		cmd.testStdin = strings.NewReader(manifest)
+
+		code := cmd.Run([]string{"-dry-run", "-"})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+		if secret, err := client.Logical().Read("secret/a"); err != nil || secret != nil {
+			t.Fatalf("expected no secret after a dry run, got %v, %v", secret, err)
+		}
+
+		ui, cmd = testOperatorImportCommand(t)
+		cmd.client = client
+		cmd.testStdin = strings.NewReader(manifest)
+
+		code = cmd.Run([]string{"-batch-size=1", "-"})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+		combined := ui.OutputWriter.String()
+		if !strings.Contains(combined, "secret/b") || !strings.Contains(combined, "written") {
+			t.Errorf("expected %q to list the written secrets", combined)
+		}
+
+		secret, err := client.Logical().Read("secret/b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil || secret.Data["value"] != "b" {
+			t.Fatalf("bad secret: %#v", secret)
+		}
+	})
+
+	t.Run("failed_items", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		ui, cmd := testOperatorImportCommand(t)
+		cmd.client = client
+		cmd.testStdin = strings.NewReader(`{"secrets": [{"path": "nope/a", "data": {"value": "a"}}]}`)
+
+		code := cmd.Run([]string{"-"})
+		if exp := 2; code != exp {
+			t.Fatalf("expected %d to be %d", code, exp)
+		}
+		combined := ui.OutputWriter.String()
+		if !strings.Contains(combined, "no KV secrets engine") {
+			t.Errorf("expected %q to contain the item error", combined)
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testOperatorImportCommand(t)
+		cmd.client = client
+		cmd.testStdin = strings.NewReader(`{"secrets": []}`)
+
+		code := cmd.Run([]string{"-"})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error importing secrets: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testOperatorImportCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -0,0 +1,83 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*OperatorSealMigrateCommand)(nil)
+var _ cli.CommandAutocomplete = (*OperatorSealMigrateCommand)(nil)
+
+type OperatorSealMigrateCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorSealMigrateCommand) Synopsis() string {
+	return "Migrates to a new auto-seal without downtime"
+}
+
+func (c *OperatorSealMigrateCommand) Help() string {
+	helpText := `
+Usage: vault operator seal-migrate [options]
+
+  Starts the online migration from one auto-seal to another. The servers must
+  be configured with the new seal, the old seal marked as disabled and
+  "online_seal_migration" enabled; they then unseal using the old seal until
+  the migration completes.
+
+  The active node rewraps the recovery key and the stored barrier keys under
+  the new seal in the background, while the standbys keep serving requests.
+  Track the progress of the migration with "vault status".
+
+  Start the seal migration:
+
+      $ vault operator seal-migrate
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorSealMigrateCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetHTTP)
+}
+
+func (c *OperatorSealMigrateCommand) AutocompleteArgs() complete.Predictor {
+	return nil
+}
+
+func (c *OperatorSealMigrateCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorSealMigrateCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	if err := client.Sys().SealMigrate(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error starting seal migration: %s", err))
+		return 2
+	}
+
+	c.UI.Output("Success! Started seal migration. Track its progress with \"vault status\".")
+	return 0
+}
@@ -15,6 +15,8 @@ var _ cli.CommandAutocomplete = (*PluginDeregisterCommand)(nil)
 
 type PluginDeregisterCommand struct {
 	*BaseCommand
+
+	flagVersion string
 }
 
 func (c *PluginDeregisterCommand) Synopsis() string {
@@ -33,13 +35,29 @@ Usage: vault plugin deregister [options] TYPE NAME
 
       $ vault plugin deregister auth my-custom-plugin
 
+  Deregister version 1.2.0 of the plugin named my-custom-plugin:
+
+      $ vault plugin deregister -version=1.2.0 auth my-custom-plugin
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
 }
 
 func (c *PluginDeregisterCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP)
+	set := c.flagSet(FlagSetHTTP)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Version of the plugin to deregister. The other versions of " +
+			"the plugin stay registered.",
+	})
+
+	return set
 }
 
 func (c *PluginDeregisterCommand) AutocompleteArgs() complete.Predictor {
@@ -91,8 +109,9 @@ func (c *PluginDeregisterCommand) Run(args []string) int {
 	pluginName := strings.TrimSpace(pluginNameRaw)
 
 	if err := client.Sys().DeregisterPlugin(&api.DeregisterPluginInput{
-		Name: pluginName,
-		Type: pluginType,
+		Name:    pluginName,
+		Type:    pluginType,
+		Version: c.flagVersion,
 	}); err != nil {
 		c.UI.Error(fmt.Sprintf("Error deregistering plugin named %s: %s", pluginName, err))
 		return 2
@@ -15,6 +15,8 @@ var _ cli.CommandAutocomplete = (*PluginInfoCommand)(nil)
 
 type PluginInfoCommand struct {
 	*BaseCommand
+
+	flagVersion string
 }
 
 func (c *PluginInfoCommand) Synopsis() string {
@@ -33,13 +35,28 @@ Usage: vault plugin info [options] TYPE NAME
 
       $ vault plugin info database mysql-database-plugin
 
+  Get info about version 1.2.0 of a plugin:
+
+      $ vault plugin info -version=1.2.0 secret my-custom-plugin
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
 }
 
 func (c *PluginInfoCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage:      "Version of the plugin to read, if it is registered with versions.",
+	})
+
+	return set
 }
 
 func (c *PluginInfoCommand) AutocompleteArgs() complete.Predictor {
@@ -91,8 +108,9 @@ func (c *PluginInfoCommand) Run(args []string) int {
 	pluginName := strings.TrimSpace(pluginNameRaw)
 
 	resp, err := client.Sys().GetPlugin(&api.GetPluginInput{
-		Name: pluginName,
-		Type: pluginType,
+		Name:    pluginName,
+		Type:    pluginType,
+		Version: c.flagVersion,
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error reading plugin named %s: %s", pluginName, err))
@@ -111,6 +129,18 @@ func (c *PluginInfoCommand) Run(args []string) int {
 		"name":    resp.Name,
 		"sha256":  resp.SHA256,
 	}
+	if resp.Version != "" {
+		data["version"] = resp.Version
+	}
+	if len(resp.Versions) > 0 {
+		data["versions"] = resp.Versions
+	}
+	if resp.Runtime != nil {
+		data["runtime"] = resp.Runtime.Type
+		data["runtime_image"] = resp.Runtime.Image
+		data["runtime_cpus"] = resp.Runtime.CPUs
+		data["runtime_memory_bytes"] = resp.Runtime.MemoryBytes
+	}
 
 	if c.flagField != "" {
 		return PrintRawField(c.UI, data, c.flagField)
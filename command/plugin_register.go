@@ -16,9 +16,14 @@ var _ cli.CommandAutocomplete = (*PluginRegisterCommand)(nil)
 type PluginRegisterCommand struct {
 	*BaseCommand
 
-	flagArgs    []string
-	flagCommand string
-	flagSHA256  string
+	flagArgs               []string
+	flagCommand            string
+	flagSHA256             string
+	flagRuntime            string
+	flagRuntimeImage       string
+	flagRuntimeCPUs        float64
+	flagRuntimeMemoryBytes int64
+	flagVersion            string
 }
 
 func (c *PluginRegisterCommand) Synopsis() string {
@@ -44,6 +49,23 @@ Usage: vault plugin register [options] TYPE NAME
           -args=--with-glibc,--with-cgo \
           auth my-custom-plugin
 
+  Register a plugin run in a gVisor sandbox limited to half a CPU:
+
+      $ vault plugin register \
+          -sha256=d3f0a8b... \
+          -runtime=gvisor \
+          -runtime-image=gcr.io/distroless/static \
+          -runtime-cpus=0.5 \
+          secret my-custom-plugin
+
+  Register version 1.2.0 of a plugin, alongside its other versions:
+
+      $ vault plugin register \
+          -sha256=d3f0a8b... \
+          -command=my-custom-plugin-1.2.0 \
+          -version=1.2.0 \
+          secret my-custom-plugin
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -77,6 +99,45 @@ func (c *PluginRegisterCommand) Flags() *FlagSets {
 		Usage:      "SHA256 of the plugin binary. This is required for all plugins.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "runtime",
+		Target:     &c.flagRuntime,
+		Completion: complete.PredictSet("container", "gvisor"),
+		Usage: "Isolated runtime to run the plugin in, \"container\" or " +
+			"\"gvisor\". The plugin has no network access. By default, the " +
+			"plugin is run as a process of the host.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "runtime-image",
+		Target:     &c.flagRuntimeImage,
+		Completion: complete.PredictAnything,
+		Usage:      "Container image to run the plugin binary in. This is required with -runtime.",
+	})
+
+	f.Float64Var(&Float64Var{
+		Name:       "runtime-cpus",
+		Target:     &c.flagRuntimeCPUs,
+		Completion: complete.PredictAnything,
+		Usage:      "Number of CPUs the plugin can use in its runtime, such as 0.5.",
+	})
+
+	f.Int64Var(&Int64Var{
+		Name:       "runtime-memory-bytes",
+		Target:     &c.flagRuntimeMemoryBytes,
+		Completion: complete.PredictAnything,
+		Usage:      "Memory in bytes the plugin can use in its runtime.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "version",
+		Target:     &c.flagVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version to register the plugin with. Mounts can pin " +
+			"a registered version with their plugin_version setting. This " +
+			"requires the type of the plugin.",
+	})
+
 	return set
 }
 
@@ -136,12 +197,24 @@ func (c *PluginRegisterCommand) Run(args []string) int {
 		command = pluginName
 	}
 
+	var runtime *api.PluginRuntime
+	if c.flagRuntime != "" {
+		runtime = &api.PluginRuntime{
+			Type:        c.flagRuntime,
+			Image:       c.flagRuntimeImage,
+			CPUs:        c.flagRuntimeCPUs,
+			MemoryBytes: c.flagRuntimeMemoryBytes,
+		}
+	}
+
 	if err := client.Sys().RegisterPlugin(&api.RegisterPluginInput{
 		Name:    pluginName,
 		Type:    pluginType,
 		Args:    c.flagArgs,
 		Command: command,
 		SHA256:  c.flagSHA256,
+		Runtime: runtime,
+		Version: c.flagVersion,
 	}); err != nil {
 		c.UI.Error(fmt.Sprintf("Error registering plugin %s: %s", pluginName, err))
 		return 2
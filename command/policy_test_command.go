@@ -0,0 +1,203 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var _ cli.Command = (*PolicyTestCommand)(nil)
+var _ cli.CommandAutocomplete = (*PolicyTestCommand)(nil)
+
+type PolicyTestCommand struct {
+	*BaseCommand
+
+	flagOperation      string
+	flagEntityID       string
+	flagEntityName     string
+	flagEntityMetadata map[string]string
+	flagGroups         []string
+	flagRemoteAddress  string
+
+	testStdin io.Reader // for tests
+}
+
+func (c *PolicyTestCommand) Synopsis() string {
+	return "Tests a policy against a hypothetical request"
+}
+
+func (c *PolicyTestCommand) Help() string {
+	helpText := `
+Usage: vault policy test [options] NAME PATH [K=V...]
+
+  Evaluates the Vault ACL policy named NAME against a request to PATH, and
+  prints whether the policy allows the request along with the rule of the
+  policy matching it. The command exits with 0 if the request is allowed, and
+  with 2 if it is denied, so it can be used to check policies in CI pipelines.
+
+  Parameters of the request are given as K=V pairs, which are checked against
+  the parameter constraints of the policy.
+
+  Test whether "my-policy" allows reading "secret/data/foo":
+
+      $ vault policy test my-policy secret/data/foo
+
+  Test whether "my-policy" allows creating a role with a given TTL:
+
+      $ vault policy test -operation=create my-policy auth/approle/role/my-role ttl=1h
+
+  Test a templated policy for an entity of the "engineering" group:
+
+      $ vault policy test \
+          -entity-name=alice \
+          -entity-metadata=team=payments \
+          -groups=engineering \
+          my-templated-policy secret/data/payments/alice
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PolicyTestCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "operation",
+		Target:     &c.flagOperation,
+		Default:    "read",
+		Completion: complete.PredictSet("create", "read", "update", "delete", "list"),
+		Usage: "Operation of the request, \"create\", \"read\", \"update\", " +
+			"\"delete\" or \"list\".",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "entity-id",
+		Target:     &c.flagEntityID,
+		Completion: complete.PredictAnything,
+		Usage: "ID of the entity to evaluate the templating of the policy " +
+			"with. If the entity exists, its name, metadata and groups are " +
+			"used too.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "entity-name",
+		Target:     &c.flagEntityName,
+		Completion: complete.PredictAnything,
+		Usage:      "Name of the entity to evaluate the templating of the policy with.",
+	})
+
+	f.StringMapVar(&StringMapVar{
+		Name:       "entity-metadata",
+		Target:     &c.flagEntityMetadata,
+		Completion: complete.PredictAnything,
+		Usage: "Key-value pair provided as key=value for the metadata of the " +
+			"entity to evaluate the templating of the policy with. This can " +
+			"be specified multiple times.",
+	})
+
+	f.StringSliceVar(&StringSliceVar{
+		Name:       "groups",
+		Target:     &c.flagGroups,
+		Completion: complete.PredictAnything,
+		Usage: "Names of the groups of the entity to evaluate the templating " +
+			"of the policy with. Separate multiple groups with a comma.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "remote-address",
+		Target:     &c.flagRemoteAddress,
+		Completion: complete.PredictAnything,
+		Usage: "IP address the request comes from, checked against the " +
+			"bound CIDRs of the policy.",
+	})
+
+	return set
+}
+
+func (c *PolicyTestCommand) AutocompleteArgs() complete.Predictor {
+	return c.PredictVaultPolicies()
+}
+
+func (c *PolicyTestCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *PolicyTestCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 2 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 2, got %d)", len(args)))
+		return 1
+	}
+
+	// Pull our fake stdin if needed
+	stdin := (io.Reader)(os.Stdin)
+	if c.testStdin != nil {
+		stdin = c.testStdin
+	}
+
+	parameters, err := parseArgsData(stdin, args[2:])
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to parse K=V data: %s", err))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	name := strings.ToLower(strings.TrimSpace(args[0]))
+	path := sanitizePath(args[1])
+	result, err := client.Sys().TestPolicy(name, &api.TestPolicyInput{
+		Path:           path,
+		Operation:      c.flagOperation,
+		Parameters:     parameters,
+		EntityID:       c.flagEntityID,
+		EntityName:     c.flagEntityName,
+		EntityMetadata: c.flagEntityMetadata,
+		Groups:         c.flagGroups,
+		RemoteAddress:  c.flagRemoteAddress,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error testing policy named %s: %s", name, err))
+		return 2
+	}
+
+	data := map[string]interface{}{
+		"allowed":   result.Allowed,
+		"templated": result.Templated,
+	}
+	if result.MatchedPath != "" {
+		data["matched_path"] = result.MatchedPath
+	}
+	if len(result.Capabilities) > 0 {
+		data["capabilities"] = result.Capabilities
+	}
+	if len(result.MFAMethods) > 0 {
+		data["mfa_methods"] = result.MFAMethods
+	}
+
+	if code := OutputData(c.UI, data); code != 0 {
+		return code
+	}
+	if !result.Allowed {
+		return 2
+	}
+	return 0
+}
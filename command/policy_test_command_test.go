@@ -0,0 +1,165 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func testPolicyTestCommand(tb testing.TB) (*cli.MockUi, *PolicyTestCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &PolicyTestCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestPolicyTestCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	policy := `
+path "secret/foo" {
+	capabilities = ["read"]
+}
+
+path "secret/internal" {
+	capabilities = ["read"]
+	bound_cidrs = ["10.0.0.0/8"]
+}
+
+path "secret/{{identity.entity.name}}/*" {
+	capabilities = ["create", "update"]
+	allowed_parameters = {
+		"ttl" = ["1h"]
+	}
+}
+`
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			[]string{"my-policy"},
+			"Not enough arguments",
+			1,
+		},
+		{
+			"no_policy_exists",
+			[]string{"not-a-real-policy", "secret/foo"},
+			"does not exist",
+			2,
+		},
+		{
+			"allowed",
+			[]string{"my-policy", "secret/foo"},
+			"secret/foo",
+			0,
+		},
+		{
+			"denied_operation",
+			[]string{"-operation=delete", "my-policy", "secret/foo"},
+			"false",
+			2,
+		},
+		{
+			"bound_cidrs_allowed",
+			[]string{"-remote-address=10.1.2.3", "my-policy", "secret/internal"},
+			"secret/internal",
+			0,
+		},
+		{
+			"bound_cidrs_denied",
+			[]string{"-remote-address=192.168.1.1", "my-policy", "secret/internal"},
+			"false",
+			2,
+		},
+		{
+			"templated_allowed",
+			[]string{"-operation=create", "-entity-name=alice", "my-policy", "secret/alice/bar", "ttl=1h"},
+			"secret/alice/*",
+			0,
+		},
+		{
+			"templated_denied_parameter",
+			[]string{"-operation=create", "-entity-name=alice", "my-policy", "secret/alice/bar", "ttl=2h"},
+			"false",
+			2,
+		},
+		{
+			"templated_other_entity",
+			[]string{"-operation=create", "-entity-name=bob", "my-policy", "secret/alice/bar"},
+			"false",
+			2,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				client, closer := testVaultServer(t)
+				defer closer()
+
+				if err := client.Sys().PutPolicy("my-policy", policy); err != nil {
+					t.Fatal(err)
+				}
+
+				ui, cmd := testPolicyTestCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testPolicyTestCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"my-policy", "secret/foo",
+		})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error testing policy named my-policy: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testPolicyTestCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
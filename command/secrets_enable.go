@@ -35,6 +35,7 @@ type SecretsEnableCommand struct {
 	flagSealWrap                  bool
 	flagExternalEntropyAccess     bool
 	flagVersion                   int
+	flagPluginVersion             string
 }
 
 func (c *SecretsEnableCommand) Synopsis() string {
@@ -200,6 +201,15 @@ func (c *SecretsEnableCommand) Flags() *FlagSets {
 		Usage:   "Enable secrets engine to access Vault's external entropy source.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       flagNamePluginVersion,
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version of the plugin of the catalog to run, which has " +
+			"to be registered with the version. By default, the plugin " +
+			"registered without a version is run.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "version",
 		Target:  &c.flagVersion,
@@ -305,6 +315,10 @@ func (c *SecretsEnableCommand) Run(args []string) int {
 		if fl.Name == flagNameAllowedResponseHeaders {
 			mountInput.Config.AllowedResponseHeaders = c.flagAllowedResponseHeaders
 		}
+
+		if fl.Name == flagNamePluginVersion {
+			mountInput.Config.PluginVersion = c.flagPluginVersion
+		}
 	})
 
 	if err := client.Sys().Mount(mountPath, mountInput); err != nil {
@@ -26,6 +26,7 @@ type SecretsTuneCommand struct {
 	flagMaxLeaseTTL              time.Duration
 	flagOptions                  map[string]string
 	flagVersion                  int
+	flagPluginVersion            string
 }
 
 func (c *SecretsTuneCommand) Synopsis() string {
@@ -112,6 +113,15 @@ func (c *SecretsTuneCommand) Flags() *FlagSets {
 			"This can be specified multiple times.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       flagNamePluginVersion,
+		Target:     &c.flagPluginVersion,
+		Completion: complete.PredictAnything,
+		Usage: "Semantic version of the plugin of the catalog to run, which has " +
+			"to be registered with the version. The plugin of the mount is " +
+			"reloaded with the version.",
+	})
+
 	f.IntVar(&IntVar{
 		Name:    "version",
 		Target:  &c.flagVersion,
@@ -187,6 +197,10 @@ func (c *SecretsTuneCommand) Run(args []string) int {
 		if fl.Name == flagNameListingVisibility {
 			mountConfigInput.ListingVisibility = c.flagListingVisibility
 		}
+
+		if fl.Name == flagNamePluginVersion {
+			mountConfigInput.PluginVersion = c.flagPluginVersion
+		}
 	})
 
 	if err := client.Sys().TuneMount(mountPath, mountConfigInput); err != nil {
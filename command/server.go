@@ -26,9 +26,11 @@ import (
 	wrapping "github.com/hashicorp/go-kms-wrapping"
 	aeadwrapper "github.com/hashicorp/go-kms-wrapping/wrappers/aead"
 	"github.com/hashicorp/go-multierror"
+	gplugin "github.com/hashicorp/go-plugin"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/helper/builtinplugins"
+	"github.com/hashicorp/vault/helper/loggers"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	vaulthttp "github.com/hashicorp/vault/http"
@@ -85,6 +87,7 @@ type ServerCommand struct {
 	logOutput   io.Writer
 	gatedWriter *gatedwriter.Writer
 	logger      log.Logger
+	logRouter   *loggers.Router
 
 	cleanupGuard sync.Once
 
@@ -633,7 +636,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 			ReadHeaderTimeout: 10 * time.Second,
 			ReadTimeout:       30 * time.Second,
 			IdleTimeout:       5 * time.Minute,
-			ErrorLog:          c.logger.StandardLogger(nil),
+			ErrorLog:          loggers.StandardLogger(c.logger),
 		}
 
 		go server.Serve(ln.Listener)
@@ -662,9 +665,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 		c.UI.Output("==> Vault server started! Log data will stream in below:\n")
 	}
 
-	c.logger.(hclog.OutputResettable).ResetOutputWithFlush(&hclog.LoggerOptions{
-		Output: c.logOutput,
-	}, c.gatedWriter)
+	c.releaseLogGate()
 
 	for {
 		select {
@@ -721,15 +722,15 @@ func (c *ServerCommand) adjustLogLevel(config *server.Config, logLevelWasNotSet
 		logLevelString = configLogLevel
 		switch configLogLevel {
 		case "trace":
-			c.logger.SetLevel(log.Trace)
+			c.setLogLevel(log.Trace)
 		case "debug":
-			c.logger.SetLevel(log.Debug)
+			c.setLogLevel(log.Debug)
 		case "notice", "info", "":
-			c.logger.SetLevel(log.Info)
+			c.setLogLevel(log.Info)
 		case "warn", "warning":
-			c.logger.SetLevel(log.Warn)
+			c.setLogLevel(log.Warn)
 		case "err", "error":
-			c.logger.SetLevel(log.Error)
+			c.setLogLevel(log.Error)
 		default:
 			return "", fmt.Errorf("unknown log level: %s", config.LogLevel)
 		}
@@ -737,6 +738,29 @@ func (c *ServerCommand) adjustLogLevel(config *server.Config, logLevelWasNotSet
 	return logLevelString, nil
 }
 
+// setLogLevel sets the level of the server logs, through the log router if
+// the server has one.
+func (c *ServerCommand) setLogLevel(level log.Level) {
+	if c.logRouter != nil {
+		c.logRouter.SetConfiguredLevel(level)
+		return
+	}
+	c.logger.SetLevel(level)
+}
+
+// releaseLogGate stops buffering the log messages and writes them, along with
+// the buffered ones, to the log output.
+func (c *ServerCommand) releaseLogGate() {
+	opts := &hclog.LoggerOptions{
+		Output: c.logOutput,
+	}
+	if c.logRouter != nil {
+		c.logRouter.ResetOutputWithFlush(opts, c.gatedWriter)
+		return
+	}
+	c.logger.(hclog.OutputResettable).ResetOutputWithFlush(opts, c.gatedWriter)
+}
+
 func (c *ServerCommand) processLogLevelAndFormat(config *server.Config) (log.Level, string, bool, logging.LogFormat, error) {
 	// Create a logger. We wrap it in a gated writer so that it doesn't
 	// start logging too early.
@@ -798,6 +822,9 @@ func (c *ServerCommand) Run(args []string) int {
 		return 1
 	}
 
+	// Stop the storage plugin, if any, on exit
+	defer gplugin.CleanupClients()
+
 	if c.flagRecovery {
 		return c.runRecoveryMode()
 	}
@@ -877,22 +904,33 @@ func (c *ServerCommand) Run(args []string) int {
 
 	config.LogFormat = logFormat.String()
 
+	var logOpts *log.LoggerOptions
 	if c.flagDevThreeNode || c.flagDevFourCluster {
-		c.logger = log.NewInterceptLogger(&log.LoggerOptions{
+		logOpts = &log.LoggerOptions{
 			Mutex:  &sync.Mutex{},
 			Output: c.gatedWriter,
 			Level:  log.Trace,
-		})
+		}
 	} else {
-		c.logger = log.NewInterceptLogger(&log.LoggerOptions{
+		logOpts = &log.LoggerOptions{
 			Output: c.gatedWriter,
 			Level:  level,
 			// Note that if logFormat is either unspecified or standard, then
 			// the resulting logger's format will be standard.
 			JSONFormat: logFormat == logging.JSONFormat,
-		})
+		}
 	}
 
+	// The messages are written by the log router at the level of the
+	// subsystem they come from, which can be changed at runtime through
+	// sys/loggers, so the logger itself discards them.
+	interceptLogger := log.NewInterceptLogger(&log.LoggerOptions{
+		Output: ioutil.Discard,
+		Level:  logOpts.Level,
+	})
+	c.logRouter = loggers.NewRouter(interceptLogger, logOpts)
+	c.logger = interceptLogger
+
 	allLoggers := []log.Logger{c.logger}
 
 	logLevelStr, err := c.adjustLogLevel(config, logLevelWasNotSet)
@@ -947,11 +985,17 @@ func (c *ServerCommand) Run(args []string) int {
 		DisplayName: "Vault",
 		UserAgent:   useragent.String(),
 		ClusterName: config.ClusterName,
+		Logger:      c.logger.Named("telemetry"),
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error initializing telemetry: %s", err))
 		return 1
 	}
+	defer func() {
+		if err := metricSink.Stop(); err != nil {
+			c.logger.Warn("failed to push the last metrics", "error", err)
+		}
+	}()
 	metricsHelper := metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
 	// Initialize the backend
@@ -1031,6 +1075,7 @@ func (c *ServerCommand) Run(args []string) int {
 
 	var barrierSeal vault.Seal
 	var unwrapSeal vault.Seal
+	var multiSeals []*vaultseal.MultiWrapperSeal
 
 	var sealConfigError error
 	var wrapper wrapping.Wrapper
@@ -1087,6 +1132,20 @@ func (c *ServerCommand) Run(args []string) int {
 			} else {
 				barrierSeal = seal
 				barrierWrapper = wrapper
+				if wrapper != nil {
+					multiSeal := &vaultseal.MultiWrapperSeal{
+						Name:     configSeal.Name,
+						Priority: configSeal.Priority,
+						Wrapper:  wrapper,
+					}
+					if multiSeal.Name == "" {
+						multiSeal.Name = configSeal.Type
+					}
+					if multiSeal.Priority == 0 {
+						multiSeal.Priority = len(multiSeals) + 1
+					}
+					multiSeals = append(multiSeals, multiSeal)
+				}
 			}
 
 			// Ensure that the seal finalizer is called, even if using verify-only
@@ -1100,6 +1159,20 @@ func (c *ServerCommand) Run(args []string) int {
 		}
 	}
 
+	// With several seals enabled, values are wrapped by all of them; the
+	// seals themselves are finalized above
+	if len(multiSeals) > 1 {
+		multiWrapper, err := vaultseal.NewMultiWrapper(c.logger.ResetNamed("seal.multi"), multiSeals)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error configuring seals: %s", err))
+			return 1
+		}
+		barrierSeal = vault.NewAutoSeal(&vaultseal.Access{
+			Wrapper: multiWrapper,
+		})
+		barrierWrapper = multiWrapper
+	}
+
 	if barrierSeal == nil {
 		c.UI.Error(fmt.Sprintf("Could not create barrier seal! Most likely proper Seal configuration information was not set, but no error was generated."))
 		return 1
@@ -1131,13 +1204,17 @@ func (c *ServerCommand) Run(args []string) int {
 		DefaultLeaseTTL:           config.DefaultLeaseTTL,
 		ClusterName:               config.ClusterName,
 		CacheSize:                 config.CacheSize,
+		CacheMaxBytes:             config.CacheMaxBytes,
 		PluginDirectory:           config.PluginDirectory,
 		EnableUI:                  config.EnableUI,
 		EnableRaw:                 config.EnableRawEndpoint,
 		DisableSealWrap:           config.DisableSealWrap,
 		DisablePerformanceStandby: config.DisablePerformanceStandby,
 		DisableIndexing:           config.DisableIndexing,
+		OnlineSealMigration:       config.OnlineSealMigration,
+		DrainGracePeriod:          config.DrainGracePeriod,
 		AllLoggers:                allLoggers,
+		LogRouter:                 c.logRouter,
 		BuiltinRegistry:           builtinplugins.Registry,
 		DisableKeyEncodingChecks:  config.DisablePrintableCheck,
 		MetricsHelper:             metricsHelper,
@@ -1707,7 +1784,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 			ReadHeaderTimeout: 10 * time.Second,
 			ReadTimeout:       30 * time.Second,
 			IdleTimeout:       5 * time.Minute,
-			ErrorLog:          c.logger.StandardLogger(nil),
+			ErrorLog:          loggers.StandardLogger(c.logger),
 		}
 
 		// override server defaults with config values for read/write/idle timeouts if configured
@@ -1767,9 +1844,7 @@ CLUSTER_SYNTHESIS_COMPLETE:
 	}
 
 	// Release the log gate.
-	c.logger.(hclog.OutputResettable).ResetOutputWithFlush(&hclog.LoggerOptions{
-		Output: c.logOutput,
-	}, c.gatedWriter)
+	c.releaseLogGate()
 
 	// Write out the PID to the file now that server has successfully started
 	if err := c.storePidFile(config.PidFile); err != nil {
@@ -2175,9 +2250,7 @@ func (c *ServerCommand) enableThreeNodeDevCluster(base *vault.CoreConfig, info m
 	}
 
 	// Release the log gate.
-	c.logger.(hclog.OutputResettable).ResetOutputWithFlush(&hclog.LoggerOptions{
-		Output: c.logOutput,
-	}, c.gatedWriter)
+	c.releaseLogGate()
 
 	// Wait for shutdown
 	shutdownTriggered := false
@@ -2412,9 +2485,7 @@ func (c *ServerCommand) storageMigrationActive(backend physical.Backend) bool {
 			c.UI.Warn("\nWARNING! Unable to read storage migration status.")
 
 			// unexpected state, so stop buffering log messages
-			c.logger.(hclog.OutputResettable).ResetOutputWithFlush(&hclog.LoggerOptions{
-				Output: c.logOutput,
-			}, c.gatedWriter)
+			c.releaseLogGate()
 		}
 		c.logger.Warn("storage migration check error", "error", err.Error())
 
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
@@ -32,6 +33,7 @@ type Config struct {
 	ServiceRegistration *ServiceRegistration `hcl:"-"`
 
 	CacheSize                int         `hcl:"cache_size"`
+	CacheMaxBytes            int         `hcl:"cache_max_bytes"`
 	DisableCache             bool        `hcl:"-"`
 	DisableCacheRaw          interface{} `hcl:"disable_cache"`
 	DisablePrintableCheck    bool        `hcl:"-"`
@@ -65,6 +67,12 @@ type Config struct {
 
 	DisableIndexing    bool        `hcl:"-"`
 	DisableIndexingRaw interface{} `hcl:"disable_indexing"`
+
+	OnlineSealMigration    bool        `hcl:"-"`
+	OnlineSealMigrationRaw interface{} `hcl:"online_seal_migration"`
+
+	DrainGracePeriod    time.Duration `hcl:"-"`
+	DrainGracePeriodRaw interface{}   `hcl:"drain_grace_period"`
 }
 
 // DevConfig is a Config that is used for dev mode of Vault.
@@ -162,6 +170,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.CacheSize = c2.CacheSize
 	}
 
+	result.CacheMaxBytes = c.CacheMaxBytes
+	if c2.CacheMaxBytes != 0 {
+		result.CacheMaxBytes = c2.CacheMaxBytes
+	}
+
 	// merging these booleans via an OR operation
 	result.DisableCache = c.DisableCache
 	if c2.DisableCache {
@@ -237,6 +250,16 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.DisableIndexing = c2.DisableIndexing
 	}
 
+	result.OnlineSealMigration = c.OnlineSealMigration
+	if c2.OnlineSealMigration {
+		result.OnlineSealMigration = c2.OnlineSealMigration
+	}
+
+	result.DrainGracePeriod = c.DrainGracePeriod
+	if c2.DrainGracePeriod > result.DrainGracePeriod {
+		result.DrainGracePeriod = c2.DrainGracePeriod
+	}
+
 	// Use values from top-level configuration for storage if set
 	if storage := result.Storage; storage != nil {
 		if result.APIAddr != "" {
@@ -284,13 +307,35 @@ func CheckConfig(c *Config, e error) (*Config, error) {
 		return c, e
 	}
 
-	if len(c.Seals) == 2 {
-		switch {
-		case c.Seals[0].Disabled && c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but both are disabled")
-		case !c.Seals[0].Disabled && !c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but neither is disabled")
+	if len(c.Seals) == 2 && c.Seals[0].Disabled && c.Seals[1].Disabled {
+		return nil, errors.New("seals: two seals provided but both are disabled")
+	}
+
+	// Several seals may be enabled at once, in which case values are wrapped
+	// by all of them
+	var disabled int
+	var shamir bool
+	names := make(map[string]bool, len(c.Seals))
+	for _, s := range c.Seals {
+		if s.Disabled {
+			disabled++
+			continue
+		}
+		name := s.Name
+		if name == "" {
+			name = s.Type
 		}
+		if names[name] {
+			return nil, fmt.Errorf("seals: seal name %q is used more than once; set distinct names", name)
+		}
+		names[name] = true
+		shamir = shamir || s.Type == wrapping.Shamir
+	}
+	switch {
+	case disabled > 1:
+		return nil, errors.New("seals: only one seal may be disabled")
+	case shamir && len(names) > 1:
+		return nil, errors.New("seals: shamir cannot be enabled along with other seals")
 	}
 
 	return c, nil
@@ -390,6 +435,19 @@ func ParseConfig(d string) (*Config, error) {
 		}
 	}
 
+	if result.OnlineSealMigrationRaw != nil {
+		if result.OnlineSealMigration, err = parseutil.ParseBool(result.OnlineSealMigrationRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.DrainGracePeriodRaw != nil {
+		if result.DrainGracePeriod, err = parseutil.ParseDurationSecond(result.DrainGracePeriodRaw); err != nil {
+			return nil, err
+		}
+		result.DrainGracePeriodRaw = nil
+	}
+
 	list, ok := obj.Node.(*ast.ObjectList)
 	if !ok {
 		return nil, fmt.Errorf("error parsing: file doesn't contain a root object")
@@ -702,6 +760,7 @@ func (c *Config) Sanitized() map[string]interface{} {
 	sharedResult := c.SharedConfig.Sanitized()
 	result := map[string]interface{}{
 		"cache_size":              c.CacheSize,
+		"cache_max_bytes":         c.CacheMaxBytes,
 		"disable_cache":           c.DisableCache,
 		"disable_printable_check": c.DisablePrintableCheck,
 
@@ -725,6 +784,10 @@ func (c *Config) Sanitized() map[string]interface{} {
 		"disable_sealwrap": c.DisableSealWrap,
 
 		"disable_indexing": c.DisableIndexing,
+
+		"online_seal_migration": c.OnlineSealMigration,
+
+		"drain_grace_period": c.DrainGracePeriod,
 	}
 	for k, v := range sharedResult {
 		result[k] = v
@@ -49,3 +49,7 @@ func TestParseEntropy(t *testing.T) {
 func TestConfigRaftRetryJoin(t *testing.T) {
 	testConfigRaftRetryJoin(t)
 }
+
+func TestConfigMultipleSeals(t *testing.T) {
+	testConfigMultipleSeals(t)
+}
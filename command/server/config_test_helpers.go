@@ -578,6 +578,7 @@ func testConfig_Sanitized(t *testing.T) {
 	expected := map[string]interface{}{
 		"api_addr":                     "top_level_api_addr",
 		"cache_size":                   0,
+		"cache_max_bytes":              0,
 		"cluster_addr":                 "top_level_cluster_addr",
 		"cluster_cipher_suites":        "",
 		"cluster_name":                 "testcluster",
@@ -590,6 +591,7 @@ func testConfig_Sanitized(t *testing.T) {
 		"disable_performance_standby":  false,
 		"disable_printable_check":      false,
 		"disable_sealwrap":             true,
+		"drain_grace_period":           0 * time.Second,
 		"raw_storage_endpoint":         true,
 		"enable_ui":                    true,
 		"ha_storage": map[string]interface{}{
@@ -605,14 +607,17 @@ func testConfig_Sanitized(t *testing.T) {
 				"type": "tcp",
 			},
 		},
-		"log_format":       "",
-		"log_level":        "",
-		"max_lease_ttl":    10 * time.Hour,
-		"pid_file":         "./pidfile",
-		"plugin_directory": "",
+		"log_format":            "",
+		"log_level":             "",
+		"max_lease_ttl":         10 * time.Hour,
+		"online_seal_migration": false,
+		"pid_file":              "./pidfile",
+		"plugin_directory":      "",
 		"seals": []interface{}{
 			map[string]interface{}{
 				"disabled": false,
+				"name":     "",
+				"priority": 0,
 				"type":     "awskms",
 			},
 		},
@@ -711,3 +716,67 @@ listener "tcp" {
 		t.Fatal(diff)
 	}
 }
+
+func testConfigMultipleSeals(t *testing.T) {
+	config, err := CheckConfig(ParseConfig(`
+seal "awskms" {
+	name     = "aws-primary"
+	priority = 1
+	kms_key_id = "primary"
+}
+
+seal "awskms" {
+	name     = "aws-secondary"
+	priority = 2
+	kms_key_id = "secondary"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*configutil.KMS{
+		{
+			Type:     "awskms",
+			Name:     "aws-primary",
+			Priority: 1,
+			Config:   map[string]string{"kms_key_id": "primary"},
+		},
+		{
+			Type:     "awskms",
+			Name:     "aws-secondary",
+			Priority: 2,
+			Config:   map[string]string{"kms_key_id": "secondary"},
+		},
+	}
+	if diff := deep.Equal(config.Seals, expected); diff != nil {
+		t.Fatal(diff)
+	}
+
+	bad := map[string]string{
+		"duplicate names": `
+seal "awskms" {}
+seal "awskms" {}`,
+		"shamir and auto-seal": `
+seal "shamir" {}
+seal "awskms" {}`,
+		"two disabled seals": `
+seal "awskms" {
+	name     = "a"
+	disabled = true
+}
+seal "awskms" {
+	name     = "b"
+	disabled = true
+}
+seal "transit" {}`,
+		"priority below 1": `
+seal "awskms" {
+	priority = 0
+}`,
+	}
+	for name, conf := range bad {
+		if _, err := CheckConfig(ParseConfig(conf)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
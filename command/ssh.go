@@ -82,7 +82,7 @@ Usage: vault ssh [options] username@ip [ssh options]
 }
 
 func (c *SSHCommand) Flags() *FlagSets {
-	set := c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 
 	f := set.NewFlagSet("SSH Options")
 
@@ -46,7 +46,7 @@ Usage: vault unwrap [options] [TOKEN]
 }
 
 func (c *UnwrapCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP | FlagSetOutputField | FlagSetOutputFormat)
+	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
 }
 
 func (c *UnwrapCommand) AutocompleteArgs() complete.Predictor {
@@ -1,9 +1,13 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -90,11 +94,20 @@ func RawField(secret *api.Secret, field string) interface{} {
 // PrintRawField prints raw field from the secret.
 func PrintRawField(ui cli.Ui, data interface{}, field string) int {
 	var val interface{}
-	switch data.(type) {
-	case *api.Secret:
-		val = RawField(data.(*api.Secret), field)
-	case map[string]interface{}:
-		val = data.(map[string]interface{})[field]
+	if isFieldPath(field) {
+		var err error
+		val, err = fieldPathValue(data, field)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Field %q not present in secret: %s", field, err))
+			return 1
+		}
+	} else {
+		switch data.(type) {
+		case *api.Secret:
+			val = RawField(data.(*api.Secret), field)
+		case map[string]interface{}:
+			val = data.(map[string]interface{})[field]
+		}
 	}
 
 	if val == nil {
@@ -104,6 +117,19 @@ func PrintRawField(ui cli.Ui, data interface{}, field string) int {
 
 	format := Format(ui)
 	if format == "" || format == "table" {
+		// Values picked by path are printed as JSON unless they are scalars,
+		// so that they can be parsed by scripts
+		switch val.(type) {
+		case map[string]interface{}, []interface{}:
+			if isFieldPath(field) {
+				b, err := json.Marshal(val)
+				if err != nil {
+					ui.Error(fmt.Sprintf("Error formatting output: %s", err))
+					return 1
+				}
+				return PrintRaw(ui, string(b))
+			}
+		}
 		return PrintRaw(ui, fmt.Sprintf("%v", val))
 	}
 
@@ -123,6 +149,118 @@ func PrintRawField(ui cli.Ui, data interface{}, field string) int {
 	return PrintRaw(ui, string(b))
 }
 
+// isFieldPath returns whether the field is a JSONPath-style expression rather
+// than the name of a field.
+func isFieldPath(field string) bool {
+	return strings.HasPrefix(field, ".") || strings.HasPrefix(field, "$") || strings.HasPrefix(field, "[")
+}
+
+// fieldPathValue returns the value at the given JSONPath-style expression in
+// the JSON representation of data. Expressions are made of ".key",
+// "[\"key\"]" and "[index]" segments, such as ".data.keys[0]"; negative
+// indexes count from the end of lists.
+func fieldPathValue(data interface{}, path string) (interface{}, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			rest = rest[end:]
+			if key == "" {
+				if rest != "" && rest[0] == '.' {
+					return nil, fmt.Errorf("empty key in path")
+				}
+				continue
+			}
+			if val, err = fieldPathKey(val, key); err != nil {
+				return nil, err
+			}
+
+		case '[':
+			if len(rest) > 1 && (rest[1] == '"' || rest[1] == '\'') {
+				end := strings.IndexByte(rest[2:], rest[1])
+				if end == -1 || !strings.HasPrefix(rest[2+end+1:], "]") {
+					return nil, fmt.Errorf("unterminated key in path")
+				}
+				key := rest[2 : 2+end]
+				rest = rest[2+end+2:]
+				if val, err = fieldPathKey(val, key); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated index in path")
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in path", rest[1:end])
+			}
+			rest = rest[end+1:]
+			list, ok := val.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index into %s", fieldPathType(val))
+			}
+			i := index
+			if i < 0 {
+				i += len(list)
+			}
+			if i < 0 || i >= len(list) {
+				return nil, fmt.Errorf("index %d out of range", index)
+			}
+			val = list[i]
+
+		default:
+			return nil, fmt.Errorf("unexpected %q in path", rest[0])
+		}
+	}
+
+	return val, nil
+}
+
+func fieldPathKey(val interface{}, key string) (interface{}, error) {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot look up key %q in %s", key, fieldPathType(val))
+	}
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+	return v, nil
+}
+
+func fieldPathType(val interface{}) string {
+	switch val.(type) {
+	case nil:
+		return "null"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return "a value"
+	}
+}
+
 // PrintRaw prints a raw value to the terminal. If the process is being "piped"
 // to something else, the "raw" value is printed without a newline character.
 // Otherwise the value is printed as normal.
@@ -22,6 +22,7 @@ import (
 	credRadius "github.com/hashicorp/vault/builtin/credential/radius"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	dbCass "github.com/hashicorp/vault/plugins/database/cassandra"
+	dbClickhouse "github.com/hashicorp/vault/plugins/database/clickhouse"
 	dbHana "github.com/hashicorp/vault/plugins/database/hana"
 	dbInflux "github.com/hashicorp/vault/plugins/database/influxdb"
 	dbMongo "github.com/hashicorp/vault/plugins/database/mongodb"
@@ -52,6 +53,7 @@ import (
 	logicalPostgres "github.com/hashicorp/vault/builtin/logical/postgresql"
 	logicalRabbit "github.com/hashicorp/vault/builtin/logical/rabbitmq"
 	logicalSsh "github.com/hashicorp/vault/builtin/logical/ssh"
+	logicalTokenize "github.com/hashicorp/vault/builtin/logical/tokenize"
 	logicalTotp "github.com/hashicorp/vault/builtin/logical/totp"
 	logicalTransit "github.com/hashicorp/vault/builtin/logical/transit"
 )
@@ -102,6 +104,7 @@ func newRegistry() *registry {
 			"redshift-database-plugin":      dbRedshift.New(true),
 			"mssql-database-plugin":         dbMssql.New,
 			"cassandra-database-plugin":     dbCass.New,
+			"clickhouse-database-plugin":    dbClickhouse.New,
 			"mongodb-database-plugin":       dbMongo.New,
 			"mongodbatlas-database-plugin":  dbMongoAtlas.New,
 			"hana-database-plugin":          dbHana.New,
@@ -128,6 +131,7 @@ func newRegistry() *registry {
 			"postgresql":   logicalPostgres.Factory, // Deprecated
 			"rabbitmq":     logicalRabbit.Factory,
 			"ssh":          logicalSsh.Factory,
+			"tokenize":     logicalTokenize.Factory,
 			"totp":         logicalTotp.Factory,
 			"transit":      logicalTransit.Factory,
 		},
@@ -0,0 +1,194 @@
+package fairshare
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Job is a unit of work run by the workers of a JobManager.
+type Job func()
+
+// QueueStats describes the jobs of a queue of a JobManager.
+type QueueStats struct {
+	// Pending is the number of jobs waiting for a worker
+	Pending int
+
+	// InFlight is the number of jobs being run by workers
+	InFlight int
+}
+
+// JobManager runs jobs with a bounded pool of workers. Jobs are added to
+// queues, which the workers take turns at, so that a queue with a large
+// backlog does not starve the others. The number of jobs of a queue run at
+// the same time can be limited, leaving workers to the other queues.
+type JobManager struct {
+	numWorkers       int
+	queueConcurrency int
+
+	l    sync.Mutex
+	cond *sync.Cond
+
+	// queues holds the pending jobs of each queue, and order the IDs of the
+	// queues with pending jobs, in the order the workers take turns at them
+	queues   map[string]*list.List
+	order    []string
+	next     int
+	inFlight map[string]int
+
+	started bool
+	stopped bool
+}
+
+// NewJobManager returns a JobManager running jobs with numWorkers workers,
+// and at most queueConcurrency jobs of a queue at the same time. If
+// queueConcurrency is not positive, the jobs of a queue can use all the
+// workers.
+func NewJobManager(numWorkers, queueConcurrency int) *JobManager {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if queueConcurrency < 1 || queueConcurrency > numWorkers {
+		queueConcurrency = numWorkers
+	}
+
+	m := &JobManager{
+		numWorkers:       numWorkers,
+		queueConcurrency: queueConcurrency,
+		queues:           make(map[string]*list.List),
+		inFlight:         make(map[string]int),
+	}
+	m.cond = sync.NewCond(&m.l)
+	return m
+}
+
+// Start starts the workers of the manager.
+func (m *JobManager) Start() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.started || m.stopped {
+		return
+	}
+	m.started = true
+
+	for i := 0; i < m.numWorkers; i++ {
+		go m.worker()
+	}
+}
+
+// Stop stops the workers of the manager once they finish the job they are
+// running, if any, and drops the pending jobs. It does not wait for the jobs
+// in flight, which should watch for cancellation on their own.
+func (m *JobManager) Stop() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.stopped = true
+	m.queues = make(map[string]*list.List)
+	m.order = nil
+	m.cond.Broadcast()
+}
+
+// AddJob adds a job to the queue with the given ID. Jobs added after the
+// manager is stopped are dropped.
+func (m *JobManager) AddJob(queueID string, job Job) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.stopped {
+		return
+	}
+
+	queue, ok := m.queues[queueID]
+	if !ok {
+		queue = list.New()
+		m.queues[queueID] = queue
+		m.order = append(m.order, queueID)
+	}
+	queue.PushBack(job)
+
+	m.cond.Signal()
+}
+
+// Stats returns the jobs pending or in flight of each queue.
+func (m *JobManager) Stats() map[string]QueueStats {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	stats := make(map[string]QueueStats, len(m.queues)+len(m.inFlight))
+	for queueID, queue := range m.queues {
+		stats[queueID] = QueueStats{
+			Pending: queue.Len(),
+		}
+	}
+	for queueID, inFlight := range m.inFlight {
+		s := stats[queueID]
+		s.InFlight = inFlight
+		stats[queueID] = s
+	}
+	return stats
+}
+
+func (m *JobManager) worker() {
+	for {
+		queueID, job, ok := m.nextJob()
+		if !ok {
+			return
+		}
+
+		job()
+
+		m.jobDone(queueID)
+	}
+}
+
+// nextJob waits for a job a worker can run, taking turns at the queues whose
+// jobs in flight are below the concurrency limit. It returns false once the
+// manager is stopped.
+func (m *JobManager) nextJob() (string, Job, bool) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for {
+		if m.stopped {
+			return "", nil, false
+		}
+
+		for i := 0; i < len(m.order); i++ {
+			idx := (m.next + i) % len(m.order)
+			queueID := m.order[idx]
+			if m.inFlight[queueID] >= m.queueConcurrency {
+				continue
+			}
+
+			queue := m.queues[queueID]
+			job := queue.Remove(queue.Front()).(Job)
+			m.inFlight[queueID]++
+
+			// The next worker starts at the following queue; if this queue
+			// is now empty, the following queue takes its index
+			m.next = idx + 1
+			if queue.Len() == 0 {
+				delete(m.queues, queueID)
+				m.order = append(m.order[:idx], m.order[idx+1:]...)
+				m.next = idx
+			}
+			return queueID, job, true
+		}
+
+		m.cond.Wait()
+	}
+}
+
+func (m *JobManager) jobDone(queueID string) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.inFlight[queueID]--
+	if m.inFlight[queueID] <= 0 {
+		delete(m.inFlight, queueID)
+	}
+
+	// A job of the queue may now be under the concurrency limit
+	m.cond.Signal()
+}
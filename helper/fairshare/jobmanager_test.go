@@ -0,0 +1,147 @@
+package fairshare
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJobManager_RunsJobs(t *testing.T) {
+	m := NewJobManager(4, 0)
+	m.Start()
+	defer m.Stop()
+
+	var wg sync.WaitGroup
+	var count int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		queueID := "a"
+		if i%2 == 0 {
+			queueID = "b"
+		}
+		m.AddJob(queueID, func() {
+			defer wg.Done()
+			atomic.AddInt32(&count, 1)
+		})
+	}
+	wg.Wait()
+
+	if count != 100 {
+		t.Fatalf("expected 100 jobs to run, got %d", count)
+	}
+	if stats := m.Stats(); len(stats) != 0 {
+		t.Fatalf("expected no pending jobs, got %#v", stats)
+	}
+}
+
+func TestJobManager_QueueConcurrency(t *testing.T) {
+	m := NewJobManager(8, 2)
+	m.Start()
+	defer m.Stop()
+
+	var wg sync.WaitGroup
+	var inFlight, maxInFlight int32
+	block := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		m.AddJob("a", func() {
+			defer wg.Done()
+			n := atomic.AddInt32(&inFlight, 1)
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+					break
+				}
+			}
+			<-block
+			atomic.AddInt32(&inFlight, -1)
+		})
+	}
+
+	// The other queue is served by the remaining workers while the jobs of
+	// the first queue are blocked
+	done := make(chan struct{})
+	m.AddJob("b", func() {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job of the second queue was not run")
+	}
+
+	stats := m.Stats()["a"]
+	if stats.InFlight != 2 || stats.Pending != 8 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+
+	close(block)
+	wg.Wait()
+
+	if maxInFlight != 2 {
+		t.Fatalf("expected at most 2 jobs in flight, got %d", maxInFlight)
+	}
+}
+
+func TestJobManager_FairScheduling(t *testing.T) {
+	m := NewJobManager(1, 0)
+
+	var l sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	add := func(queueID string) {
+		wg.Add(1)
+		m.AddJob(queueID, func() {
+			defer wg.Done()
+			l.Lock()
+			order = append(order, queueID)
+			l.Unlock()
+		})
+	}
+	for i := 0; i < 3; i++ {
+		add("a")
+	}
+	add("b")
+	add("c")
+
+	m.Start()
+	defer m.Stop()
+	wg.Wait()
+
+	expected := []string{"a", "b", "c", "a", "a"}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected jobs to run in order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestJobManager_Stop(t *testing.T) {
+	m := NewJobManager(1, 0)
+	m.Start()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	m.AddJob("a", func() {
+		close(started)
+		<-block
+	})
+	<-started
+
+	var ran int32
+	m.AddJob("a", func() {
+		atomic.StoreInt32(&ran, 1)
+	})
+	m.Stop()
+	close(block)
+
+	m.AddJob("a", func() {
+		atomic.StoreInt32(&ran, 1)
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Fatal("expected the pending jobs to be dropped")
+	}
+}
@@ -0,0 +1,13 @@
+package hostutil
+
+// FDLimits holds the limits on the number of file descriptors of the process,
+// and the number it has open.
+type FDLimits struct {
+	// Soft is the limit enforced on the process.
+	Soft uint64 `json:"soft"`
+	// Hard is the ceiling the soft limit can be raised to.
+	Hard uint64 `json:"hard"`
+	// Open is the number of file descriptors open, or -1 if it could not be
+	// determined.
+	Open int `json:"open"`
+}
@@ -0,0 +1,34 @@
+// +build !windows
+
+package hostutil
+
+import (
+	"os"
+	"syscall"
+)
+
+// CollectFDLimits returns the limits on the number of file descriptors of the
+// process, and the number it has open.
+func CollectFDLimits() (*FDLimits, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return nil, &HostInfoError{"fd_limits", err}
+	}
+
+	limits := &FDLimits{
+		Soft: uint64(rlimit.Cur),
+		Hard: uint64(rlimit.Max),
+		Open: -1,
+	}
+
+	if dir, err := os.Open("/dev/fd"); err == nil {
+		names, err := dir.Readdirnames(-1)
+		dir.Close()
+		if err == nil {
+			// The descriptor used to list the directory is listed too
+			limits.Open = len(names) - 1
+		}
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,9 @@
+// +build windows
+
+package hostutil
+
+import "errors"
+
+func CollectFDLimits() (*FDLimits, error) {
+	return nil, &HostInfoError{"fd_limits", errors.New("file descriptor limits not supported on this platform")}
+}
@@ -0,0 +1,12 @@
+package hostutil
+
+// NUMANode holds the CPUs and the memory of a NUMA node of the host.
+type NUMANode struct {
+	ID int `json:"id"`
+	// CPUs is the list of the CPUs of the node, such as "0-3,8-11".
+	CPUs string `json:"cpus"`
+	// MemoryTotal and MemoryFree are the total and free memory of the node in
+	// number of bytes.
+	MemoryTotal uint64 `json:"memory_total"`
+	MemoryFree  uint64 `json:"memory_free"`
+}
@@ -0,0 +1,84 @@
+package hostutil
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const numaNodesPath = "/sys/devices/system/node"
+
+// CollectNUMANodes returns the NUMA nodes of the host, as described in sysfs.
+func CollectNUMANodes() ([]*NUMANode, error) {
+	dirs, err := filepath.Glob(filepath.Join(numaNodesPath, "node[0-9]*"))
+	if err != nil {
+		return nil, &HostInfoError{"numa_nodes", err}
+	}
+
+	var nodes []*NUMANode
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		node := &NUMANode{ID: id}
+
+		cpus, err := ioutil.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, &HostInfoError{"numa_nodes", err}
+		}
+		node.CPUs = strings.TrimSpace(string(cpus))
+
+		f, err := os.Open(filepath.Join(dir, "meminfo"))
+		if err != nil {
+			return nil, &HostInfoError{"numa_nodes", err}
+		}
+		node.MemoryTotal, node.MemoryFree, err = parseNUMAMeminfo(f)
+		f.Close()
+		if err != nil {
+			return nil, &HostInfoError{"numa_nodes", err}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes, nil
+}
+
+// parseNUMAMeminfo returns the total and free memory in bytes from the
+// meminfo file of a node, whose lines look like:
+//
+//	Node 0 MemTotal:       16333044 kB
+func parseNUMAMeminfo(r io.Reader) (total, free uint64, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 4 && fields[4] == "kB" {
+			value *= 1024
+		}
+
+		switch fields[2] {
+		case "MemTotal:":
+			total = value
+		case "MemFree:":
+			free = value
+		}
+	}
+	return total, free, scanner.Err()
+}
@@ -0,0 +1,24 @@
+package hostutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNUMAMeminfo(t *testing.T) {
+	meminfo := `Node 0 MemTotal:       16333044 kB
+Node 0 MemFree:         1032896 kB
+Node 0 MemUsed:        15300148 kB
+Node 0 HugePages_Total:     0
+`
+	total, free, err := parseNUMAMeminfo(strings.NewReader(meminfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 16333044*1024 {
+		t.Fatalf("bad total: %d", total)
+	}
+	if free != 1032896*1024 {
+		t.Fatalf("bad free: %d", free)
+	}
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package hostutil
+
+import "errors"
+
+func CollectNUMANodes() ([]*NUMANode, error) {
+	return nil, &HostInfoError{"numa_nodes", errors.New("NUMA topology not supported on this platform")}
+}
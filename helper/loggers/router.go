@@ -0,0 +1,197 @@
+package loggers
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+var _ hclog.SinkAdapter = (*Router)(nil)
+var _ hclog.OutputResettable = (*Router)(nil)
+
+// Router writes the messages of an intercept logger at a level which can be
+// set for each subsystem of the server at runtime. A subsystem is a sequence
+// of the dot-separated segments of the names of the loggers, so that "raft"
+// is the subsystem of both the "core.raft" and "storage.raft" loggers.
+//
+// The loggers named after a logger share its level, so they cannot be given
+// levels of their own. Instead, the intercept logger should write to
+// ioutil.Discard, and the router registered as its sink writes the messages
+// at the level of the subsystem they come from. The level of the intercept
+// logger is kept at the most verbose of the levels, so that the IsDebug and
+// IsTrace checks of the loggers pass whenever one of them may be written.
+type Router struct {
+	logger hclog.InterceptLogger
+	sink   hclog.SinkAdapter
+
+	lock       sync.RWMutex
+	configured hclog.Level
+	level      hclog.Level
+	subsystems map[string]hclog.Level
+}
+
+// NewRouter returns a router writing the messages of the logger with the
+// given options, whose level is the level configured for the server.
+func NewRouter(logger hclog.InterceptLogger, opts *hclog.LoggerOptions) *Router {
+	sinkOpts := *opts
+	sinkOpts.Level = hclog.Trace
+
+	r := &Router{
+		logger:     logger,
+		sink:       hclog.NewSinkAdapter(&sinkOpts),
+		configured: opts.Level,
+		level:      opts.Level,
+		subsystems: make(map[string]hclog.Level),
+	}
+	if r.level == hclog.NoLevel {
+		r.configured = hclog.DefaultLevel
+		r.level = hclog.DefaultLevel
+	}
+
+	r.updateLoggerLevel()
+	logger.RegisterSink(r)
+	return r
+}
+
+// Accept writes the message if its level is at least the one of the subsystem
+// of the logger it comes from.
+func (r *Router) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	if level < r.LevelFor(name) {
+		return
+	}
+	r.sink.Accept(name, level, msg, args...)
+}
+
+// ResetOutput resets the output the messages are written to.
+func (r *Router) ResetOutput(opts *hclog.LoggerOptions) error {
+	if or, ok := r.sink.(hclog.OutputResettable); ok {
+		return or.ResetOutput(opts)
+	}
+	return nil
+}
+
+// ResetOutputWithFlush resets the output the messages are written to, after
+// flushing the one they were written to.
+func (r *Router) ResetOutputWithFlush(opts *hclog.LoggerOptions, flushable hclog.Flushable) error {
+	if or, ok := r.sink.(hclog.OutputResettable); ok {
+		return or.ResetOutputWithFlush(opts, flushable)
+	}
+	return nil
+}
+
+// SetConfiguredLevel sets the level configured for the server, which is also
+// the level of the subsystems without one of their own.
+func (r *Router) SetConfiguredLevel(level hclog.Level) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.configured = level
+	r.level = level
+	r.updateLoggerLevel()
+}
+
+// SetLevel sets the level of the subsystems without one of their own, until
+// the router is reset.
+func (r *Router) SetLevel(level hclog.Level) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.level = level
+	r.updateLoggerLevel()
+}
+
+// SetSubsystemLevel sets the level of the subsystem, until it is reset.
+func (r *Router) SetSubsystemLevel(subsystem string, level hclog.Level) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.subsystems[subsystem] = level
+	r.updateLoggerLevel()
+}
+
+// ResetSubsystem removes the level of the subsystem, which goes back to the
+// level of the subsystems it is part of, if any, or of the server.
+func (r *Router) ResetSubsystem(subsystem string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.subsystems, subsystem)
+	r.updateLoggerLevel()
+}
+
+// Reset removes the levels of all the subsystems and restores the level
+// configured for the server.
+func (r *Router) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.level = r.configured
+	r.subsystems = make(map[string]hclog.Level)
+	r.updateLoggerLevel()
+}
+
+// Levels returns the level of the server and the levels set for subsystems.
+func (r *Router) Levels() (hclog.Level, map[string]hclog.Level) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	subsystems := make(map[string]hclog.Level, len(r.subsystems))
+	for subsystem, level := range r.subsystems {
+		subsystems[subsystem] = level
+	}
+	return r.level, subsystems
+}
+
+// LevelFor returns the level messages of the named logger are written at,
+// which is the level of the most specific subsystem it belongs to, or the
+// level of the server.
+func (r *Router) LevelFor(name string) hclog.Level {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	level := r.level
+	var matched string
+	for subsystem, subsystemLevel := range r.subsystems {
+		if len(subsystem) < len(matched) || (len(subsystem) == len(matched) && subsystem > matched) {
+			continue
+		}
+		if inSubsystem(name, subsystem) {
+			matched = subsystem
+			level = subsystemLevel
+		}
+	}
+	return level
+}
+
+// updateLoggerLevel sets the level of the logger to the most verbose of the
+// levels. The lock should be held.
+func (r *Router) updateLoggerLevel() {
+	level := r.level
+	for _, subsystemLevel := range r.subsystems {
+		if subsystemLevel < level {
+			level = subsystemLevel
+		}
+	}
+	r.logger.SetLevel(level)
+}
+
+// inSubsystem returns whether the dot-separated segments of the subsystem are
+// found in the name of the logger.
+func inSubsystem(name, subsystem string) bool {
+	return name == subsystem ||
+		strings.HasPrefix(name, subsystem+".") ||
+		strings.HasSuffix(name, "."+subsystem) ||
+		strings.Contains(name, "."+subsystem+".")
+}
+
+// StandardLogger returns a standard library logger writing to the logger. The
+// messages are written through the sinks of intercept loggers, so that they
+// reach the router of their levels.
+func StandardLogger(logger hclog.Logger) *log.Logger {
+	if intercept, ok := logger.(hclog.InterceptLogger); ok {
+		return intercept.StandardLoggerIntercept(nil)
+	}
+	return logger.StandardLogger(nil)
+}
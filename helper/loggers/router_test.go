@@ -0,0 +1,149 @@
+package loggers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+type lockedBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
+
+func TestRouter(t *testing.T) {
+	logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{
+		Output: ioutil.Discard,
+	})
+	var out lockedBuffer
+	router := NewRouter(logger, &hclog.LoggerOptions{
+		Output: &out,
+		Level:  hclog.Info,
+	})
+
+	core := logger.Named("core")
+	coreRaft := core.Named("raft")
+	storageRaft := logger.Named("storage").Named("raft")
+	expiration := logger.Named("expiration")
+
+	core.Debug("core debug")
+	core.Info("core info")
+	if s := out.String(); strings.Contains(s, "core debug") || !strings.Contains(s, "core info") {
+		t.Fatalf("unexpected output: %s", s)
+	}
+	if logger.IsDebug() {
+		t.Fatal("expected the logger to be at the info level")
+	}
+
+	router.SetSubsystemLevel("raft", hclog.Debug)
+	router.SetSubsystemLevel("core.raft", hclog.Trace)
+	router.SetSubsystemLevel("expiration", hclog.Error)
+	if !expiration.IsTrace() {
+		t.Fatal("expected the loggers to be at the trace level")
+	}
+
+	coreRaft.Trace("core raft trace")
+	storageRaft.Trace("storage raft trace")
+	storageRaft.Debug("storage raft debug")
+	expiration.Warn("expiration warn")
+	core.Debug("core debug again")
+	s := out.String()
+	for _, expected := range []string{"core raft trace", "storage raft debug"} {
+		if !strings.Contains(s, expected) {
+			t.Fatalf("expected %q in output: %s", expected, s)
+		}
+	}
+	for _, unexpected := range []string{"storage raft trace", "expiration warn", "core debug again"} {
+		if strings.Contains(s, unexpected) {
+			t.Fatalf("unexpected %q in output: %s", unexpected, s)
+		}
+	}
+
+	level, subsystems := router.Levels()
+	if level != hclog.Info || len(subsystems) != 3 || subsystems["core.raft"] != hclog.Trace {
+		t.Fatalf("unexpected levels: %s %v", level, subsystems)
+	}
+
+	router.ResetSubsystem("core.raft")
+	if level := router.LevelFor("core.raft"); level != hclog.Debug {
+		t.Fatalf("expected the debug level of raft, got %s", level)
+	}
+	if logger.IsTrace() || !logger.IsDebug() {
+		t.Fatal("expected the logger to be at the debug level")
+	}
+
+	router.SetLevel(hclog.Warn)
+	if level := router.LevelFor("core"); level != hclog.Warn {
+		t.Fatalf("expected the warn level, got %s", level)
+	}
+
+	router.Reset()
+	level, subsystems = router.Levels()
+	if level != hclog.Info || len(subsystems) != 0 {
+		t.Fatalf("unexpected levels: %s %v", level, subsystems)
+	}
+	if logger.IsDebug() {
+		t.Fatal("expected the logger to be back at the info level")
+	}
+
+	router.SetConfiguredLevel(hclog.Error)
+	router.SetLevel(hclog.Debug)
+	router.Reset()
+	if level, _ := router.Levels(); level != hclog.Error {
+		t.Fatalf("expected the configured error level, got %s", level)
+	}
+}
+
+func TestInSubsystem(t *testing.T) {
+	cases := []struct {
+		name      string
+		subsystem string
+		expected  bool
+	}{
+		{"expiration", "expiration", true},
+		{"core.raft", "raft", true},
+		{"core.raft.autopilot", "raft", true},
+		{"core.raft.autopilot", "core.raft", true},
+		{"storage.raft", "core.raft", false},
+		{"core.raftish", "raft", false},
+		{"secrets.kv.kv_1234", "kv", true},
+		{"core", "core.raft", false},
+	}
+	for _, tc := range cases {
+		if actual := inSubsystem(tc.name, tc.subsystem); actual != tc.expected {
+			t.Fatalf("%q in %q: expected %t, got %t", tc.name, tc.subsystem, tc.expected, actual)
+		}
+	}
+}
+
+func TestStandardLogger(t *testing.T) {
+	logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{
+		Output: ioutil.Discard,
+	})
+	var out lockedBuffer
+	NewRouter(logger, &hclog.LoggerOptions{
+		Output: &out,
+		Level:  hclog.Info,
+	})
+
+	StandardLogger(logger.Named("http")).Print("[ERR] listener failed")
+	if s := out.String(); !strings.Contains(s, "listener failed") {
+		t.Fatalf("expected the message in the output: %s", s)
+	}
+}
@@ -0,0 +1,46 @@
+package metricsutil
+
+import (
+	"sort"
+)
+
+// otherLabelValue is reported in place of label values that have been
+// aggregated or suppressed to limit cardinality.
+const otherLabelValue = "other"
+
+// limitCardinality keeps the max values with the largest value, and
+// reports how many were dropped. If aggregate is set, the dropped values
+// are summed into one additional value, whose labels are the union of the
+// dropped label names, each with the value "other".
+//
+// The order of the values slice may be changed.
+func limitCardinality(values []GaugeLabelValues, max int, aggregate bool) ([]GaugeLabelValues, int) {
+	if len(values) <= max {
+		return values, 0
+	}
+
+	sort.Slice(values, func(a, b int) bool {
+		return values[a].Value > values[b].Value
+	})
+	kept, excess := values[:max], values[max:]
+	if !aggregate {
+		return kept, len(excess)
+	}
+
+	other := GaugeLabelValues{}
+	seen := make(map[string]bool)
+	for _, v := range excess {
+		other.Value += v.Value
+		for _, l := range v.Labels {
+			if !seen[l.Name] {
+				seen[l.Name] = true
+				other.Labels = append(other.Labels, Label{l.Name, otherLabelValue})
+			}
+		}
+	}
+
+	// Copy rather than append, so the caller's slice is not overwritten.
+	limited := make([]GaugeLabelValues, 0, max+1)
+	limited = append(limited, kept...)
+	return append(limited, other), len(excess)
+}
@@ -0,0 +1,63 @@
+package metricsutil
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLimitCardinality_UnderLimit(t *testing.T) {
+	values := makeLabels(10)
+	limited, dropped := limitCardinality(values, 10, true)
+	if dropped != 0 {
+		t.Errorf("Dropped %v values, expected 0.", dropped)
+	}
+	if !reflect.DeepEqual(limited, makeLabels(10)) {
+		t.Errorf("Values changed: %v", limited)
+	}
+}
+
+func TestLimitCardinality_Truncate(t *testing.T) {
+	values := makeLabels(100)
+	rand.Shuffle(len(values), func(i, j int) {
+		values[i], values[j] = values[j], values[i]
+	})
+
+	limited, dropped := limitCardinality(values, 40, false)
+	if dropped != 60 {
+		t.Errorf("Dropped %v values, expected 60.", dropped)
+	}
+	if len(limited) != 40 {
+		t.Fatalf("Kept %v values, expected 40.", len(limited))
+	}
+	for _, v := range limited {
+		if v.Value <= 60 {
+			t.Errorf("Value %v with labels %v should not have been kept.", v.Value, v.Labels)
+		}
+	}
+}
+
+func TestLimitCardinality_Aggregate(t *testing.T) {
+	values := makeLabels(100)
+	rand.Shuffle(len(values), func(i, j int) {
+		values[i], values[j] = values[j], values[i]
+	})
+
+	limited, dropped := limitCardinality(values, 40, true)
+	if dropped != 60 {
+		t.Errorf("Dropped %v values, expected 60.", dropped)
+	}
+	if len(limited) != 41 {
+		t.Fatalf("Kept %v values, expected 41.", len(limited))
+	}
+
+	other := limited[40]
+	expectedLabels := []Label{{"test", "other"}, {"which", "other"}}
+	if !reflect.DeepEqual(other.Labels, expectedLabels) {
+		t.Errorf("Other labels are %v, expected %v.", other.Labels, expectedLabels)
+	}
+	// Sum of 1..60
+	if other.Value != 1830 {
+		t.Errorf("Other value is %v, expected %v.", other.Value, 1830)
+	}
+}
@@ -2,8 +2,9 @@ package metricsutil
 
 import (
 	"context"
+	"errors"
 	"math/rand"
-	"sort"
+	"sync"
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
@@ -45,6 +46,10 @@ const collectionBound = 0.02
 // will be doubled.
 const collectionTarget = 0.01
 
+// defaultDrainTimeout is how long an in-flight collection may continue
+// after StopAndDrain is called, before it is abandoned.
+const defaultDrainTimeout = 10 * time.Second
+
 // A GaugeCollectionProcess is responsible for one particular gauge metric.
 // It handles a delay on initial startup; limiting the cardinality; and
 // exponential backoff on the requested interval.
@@ -52,6 +57,11 @@ type GaugeCollectionProcess struct {
 	stop    chan struct{}
 	stopped chan struct{}
 
+	// closed (before stop) if an in-flight collection should be
+	// allowed to finish, up to drainTimeout
+	drain        chan struct{}
+	drainTimeout time.Duration
+
 	// gauge name
 	key []string
 	// labels to use when reporting
@@ -71,6 +81,35 @@ type GaugeCollectionProcess struct {
 
 	// time source
 	clock clock
+
+	// expensive processes share the sink's scheduler; otherwise nil
+	scheduler  *gaugeCollectionScheduler
+	priority   int
+	maxRuntime time.Duration
+
+	// results of the most recent collection, and currentInterval,
+	// are protected by statusLock so they can be reported
+	statusLock   sync.RWMutex
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    error
+	errorCount   int
+}
+
+// GaugeCollectionStatus describes the state of a running
+// GaugeCollectionProcess.
+type GaugeCollectionStatus struct {
+	Key              []string
+	Labels           []Label
+	OriginalInterval time.Duration
+	CurrentInterval  time.Duration
+	// LastRun is the zero time if no collection has completed.
+	LastRun      time.Time
+	LastDuration time.Duration
+	LastError    error
+	ErrorCount   int
+	Expensive    bool
+	Priority     int
 }
 
 // NewGaugeCollectionProcess creates a new collection process for the callback
@@ -84,12 +123,32 @@ func (m *ClusterMetricSink) NewGaugeCollectionProcess(
 	collector GaugeCollector,
 	logger log.Logger,
 ) (*GaugeCollectionProcess, error) {
-	return m.newGaugeCollectionProcessWithClock(
+	return m.newGaugeCollectionProcess(
 		key,
 		id,
 		collector,
 		logger,
 		defaultClock{},
+		GaugeCollectionOptions{},
+	)
+}
+
+// NewGaugeCollectionProcessWithOptions is like NewGaugeCollectionProcess,
+// but allows the process to be scheduled as an expensive collector.
+func (m *ClusterMetricSink) NewGaugeCollectionProcessWithOptions(
+	key []string,
+	id []Label,
+	collector GaugeCollector,
+	logger log.Logger,
+	opts GaugeCollectionOptions,
+) (*GaugeCollectionProcess, error) {
+	return m.newGaugeCollectionProcess(
+		key,
+		id,
+		collector,
+		logger,
+		defaultClock{},
+		opts,
 	)
 }
 
@@ -101,9 +160,32 @@ func (m *ClusterMetricSink) newGaugeCollectionProcessWithClock(
 	logger log.Logger,
 	clock clock,
 ) (*GaugeCollectionProcess, error) {
+	return m.newGaugeCollectionProcess(
+		key,
+		id,
+		collector,
+		logger,
+		clock,
+		GaugeCollectionOptions{},
+	)
+}
+
+func (m *ClusterMetricSink) newGaugeCollectionProcess(
+	key []string,
+	id []Label,
+	collector GaugeCollector,
+	logger log.Logger,
+	clock clock,
+	opts GaugeCollectionOptions,
+) (*GaugeCollectionProcess, error) {
+	if opts.MaxRuntime < 0 {
+		return nil, errors.New("negative maximum runtime")
+	}
 	process := &GaugeCollectionProcess{
 		stop:             make(chan struct{}, 1),
 		stopped:          make(chan struct{}, 1),
+		drain:            make(chan struct{}, 1),
+		drainTimeout:     defaultDrainTimeout,
 		key:              key,
 		labels:           id,
 		collector:        collector,
@@ -112,6 +194,11 @@ func (m *ClusterMetricSink) newGaugeCollectionProcessWithClock(
 		currentInterval:  m.GaugeInterval,
 		logger:           logger,
 		clock:            clock,
+		priority:         opts.Priority,
+		maxRuntime:       opts.MaxRuntime,
+	}
+	if opts.Expensive {
+		process.scheduler = m.gaugeScheduler()
 	}
 	return process, nil
 }
@@ -120,11 +207,17 @@ func (m *ClusterMetricSink) newGaugeCollectionProcessWithClock(
 // so that collection processes do not all run at the time time.
 // If we knew all the procsses in advance, we could just schedule them
 // evenly, but a new one could be added per secret engine.
+// Expensive processes are instead spread out by the scheduler.
 func (p *GaugeCollectionProcess) delayStart() bool {
-	randomDelay := time.Duration(rand.Intn(int(p.currentInterval)))
+	var delay time.Duration
+	if p.scheduler != nil {
+		delay = p.scheduler.startOffset(p.currentInterval)
+	} else {
+		delay = time.Duration(rand.Intn(int(p.currentInterval)))
+	}
 	// A Timer might be better, but then we'd have to simulate
 	// one of those too?
-	delayTick := p.clock.NewTicker(randomDelay)
+	delayTick := p.clock.NewTicker(delay)
 	defer delayTick.Stop()
 
 	select {
@@ -145,15 +238,101 @@ func (p *GaugeCollectionProcess) resetTicker() {
 	p.ticker = p.clock.NewTicker(p.currentInterval)
 }
 
+// isDraining returns true if StopAndDrain has been called.
+func (p *GaugeCollectionProcess) isDraining() bool {
+	select {
+	case <-p.drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchForStop returns a channel that is closed when the in-flight
+// collection should be abandoned: immediately on Stop, or after the
+// drain timeout on StopAndDrain. The cancel function is also invoked
+// at that time. The returned function must be called once the
+// collection is complete, to release the watcher.
+func (p *GaugeCollectionProcess) watchForStop(cancel context.CancelFunc) (<-chan struct{}, func()) {
+	abort := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-p.stop:
+		}
+
+		if p.isDraining() {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			drainTick := p.clock.NewTicker(p.drainTimeout)
+			defer drainTick.Stop()
+
+			select {
+			case <-done:
+				return
+			case <-drainTick.C:
+				p.logger.Warn("gauge collection did not drain before timeout", "timeout", p.drainTimeout, "id", p.labels)
+			}
+		}
+		close(abort)
+		cancel()
+	}()
+
+	return abort, func() { close(done) }
+}
+
+// abandoned returns true if the results of the in-flight collection
+// should be discarded rather than sent to the sink.
+func (p *GaugeCollectionProcess) abandoned(abort <-chan struct{}) bool {
+	select {
+	case <-abort:
+		return true
+	default:
+	}
+
+	// The watcher may not have noticed the stop yet.
+	select {
+	case <-p.stop:
+		return !p.isDraining()
+	default:
+		return false
+	}
+}
+
 // collectAndFilterGauges executes the callback function,
 // limits the cardinality, and streams the results to the metrics sink.
 func (p *GaugeCollectionProcess) collectAndFilterGauges() {
+	// Expensive collectors wait their turn.
+	if p.scheduler != nil {
+		waitStart := p.clock.Now()
+		if !p.scheduler.acquire(p.priority, p.stop) {
+			return
+		}
+		defer p.scheduler.release()
+		p.sink.AddDurationWithLabels([]string{"metrics", "collection", "wait"},
+			p.clock.Now().Sub(waitStart),
+			p.labels)
+	}
+
 	// Run for only an allotted amount of time.
 	timeout := time.Duration(collectionBound * float64(p.currentInterval))
+	if p.maxRuntime > 0 {
+		timeout = p.maxRuntime
+	}
 	ctx, cancel := context.WithTimeout(context.Background(),
 		timeout)
 	defer cancel()
 
+	abort, release := p.watchForStop(cancel)
+	defer release()
+
 	p.sink.AddDurationWithLabels([]string{"metrics", "collection", "interval"},
 		p.currentInterval,
 		p.labels)
@@ -168,6 +347,19 @@ func (p *GaugeCollectionProcess) collectAndFilterGauges() {
 		duration,
 		p.labels)
 
+	if p.abandoned(abort) {
+		p.logger.Debug("gauge collection abandoned due to stop", "id", p.labels)
+		return
+	}
+
+	p.statusLock.Lock()
+	p.lastRun = end
+	p.lastDuration = duration
+	p.lastError = err
+	if err != nil {
+		p.errorCount++
+	}
+
 	// If over threshold, back off by doubling the measurement interval.
 	// Currently a restart is the only way to bring it back down.
 	threshold := time.Duration(collectionTarget * float64(p.currentInterval))
@@ -175,7 +367,11 @@ func (p *GaugeCollectionProcess) collectAndFilterGauges() {
 		p.logger.Warn("gauge collection time exceeded target", "target", threshold, "actual", duration, "id", p.labels)
 		p.currentInterval *= 2
 		p.resetTicker()
+		p.sink.SetGaugeWithLabels([]string{"metrics", "collection", "backoff"},
+			float32(p.currentInterval)/float32(p.originalInterval),
+			p.labels)
 	}
+	p.statusLock.Unlock()
 
 	if err != nil {
 		p.logger.Error("error collecting gauge", "id", p.labels, "error", err)
@@ -185,20 +381,22 @@ func (p *GaugeCollectionProcess) collectAndFilterGauges() {
 		return
 	}
 
-	// Filter to top N.
+	// Filter to top N, optionally summing the rest into an "other" value.
 	// This does not guarantee total cardinality is <= N, but it does slow things down
 	// a little if the cardinality *is* too high and the gauge needs to be disabled.
-	if len(values) > p.sink.MaxGaugeCardinality {
-		sort.Slice(values, func(a, b int) bool {
-			return values[a].Value > values[b].Value
-		})
-		values = values[:p.sink.MaxGaugeCardinality]
-	}
+	values, dropped := limitCardinality(values,
+		p.sink.MaxGaugeCardinality,
+		p.sink.AggregateGaugeOverflow)
+	p.sink.SetGaugeWithLabels([]string{"metrics", "collection", "dropped"},
+		float32(dropped),
+		p.labels)
 
-	p.streamGaugesToSink(values)
+	p.streamGaugesToSink(values, abort)
 }
 
-func (p *GaugeCollectionProcess) streamGaugesToSink(values []GaugeLabelValues) {
+// streamGaugesToSink sends the values to the sink in batches, giving up
+// early if abort is closed.
+func (p *GaugeCollectionProcess) streamGaugesToSink(values []GaugeLabelValues, abort <-chan struct{}) {
 	// Dumping 500 metrics in one big chunk is somewhat unfriendly to UDP-based
 	// transport, and to the rest of the metrics trying to get through.
 	// Let's smooth things out over the course of a second.
@@ -209,10 +407,11 @@ func (p *GaugeCollectionProcess) streamGaugesToSink(values []GaugeLabelValues) {
 	for i, lv := range values {
 		if i > 0 && i%batchSize == 0 {
 			select {
-			case <-p.stop:
-				// because the channel is closed,
-				// the main loop will successfully
+			case <-abort:
+				// abort is only closed after p.stop,
+				// so the main loop will successfully
 				// read from p.stop too, and exit.
+				sendTick.Stop()
 				return
 			case <-sendTick.C:
 				break
@@ -224,10 +423,33 @@ func (p *GaugeCollectionProcess) streamGaugesToSink(values []GaugeLabelValues) {
 	sendTick.Stop()
 }
 
+// Status returns a snapshot of the state of the collection process.
+func (p *GaugeCollectionProcess) Status() GaugeCollectionStatus {
+	p.statusLock.RLock()
+	defer p.statusLock.RUnlock()
+
+	return GaugeCollectionStatus{
+		Key:              p.key,
+		Labels:           p.labels,
+		OriginalInterval: p.originalInterval,
+		CurrentInterval:  p.currentInterval,
+		LastRun:          p.lastRun,
+		LastDuration:     p.lastDuration,
+		LastError:        p.lastError,
+		ErrorCount:       p.errorCount,
+		Expensive:        p.scheduler != nil,
+		Priority:         p.priority,
+	}
+}
+
 // Run should be called as a goroutine.
 func (p *GaugeCollectionProcess) Run() {
 	defer close(p.stopped)
 
+	// While running, the process is listed by the sink.
+	p.sink.registerCollectionProcess(p)
+	defer p.sink.unregisterCollectionProcess(p)
+
 	// Wait a random amount of time
 	stopReceived := p.delayStart()
 	if stopReceived {
@@ -251,7 +473,17 @@ func (p *GaugeCollectionProcess) Run() {
 	}
 }
 
-// Stop the collection process
+// Stop the collection process. Any collection in progress
+// is abandoned without sending its results.
 func (p *GaugeCollectionProcess) Stop() {
 	close(p.stop)
 }
+
+// StopAndDrain stops the collection process, but allows a collection
+// in progress to complete and send its results to the sink first.
+// If the collection does not finish within the drain timeout, it is
+// abandoned as with Stop.
+func (p *GaugeCollectionProcess) StopAndDrain() {
+	close(p.drain)
+	close(p.stop)
+}
@@ -386,7 +386,7 @@ func TestGauge_InterruptedStreaming(t *testing.T) {
 	values := makeLabels(75)
 	done := make(chan struct{})
 	go func() {
-		p.streamGaugesToSink(values)
+		p.streamGaugesToSink(values, p.stop)
 		close(done)
 	}()
 
@@ -483,14 +483,28 @@ func TestGauge_MaximumMeasurements(t *testing.T) {
 		t.Skip("Detected interval crossing.")
 	}
 
-	if len(intervals[0].Gauges) != sink.MaxGaugeCardinality {
+	// One extra gauge reports the number dropped.
+	droppedKey := "metrics.collection.dropped;gauge=test;cluster=test"
+	droppedGauge, ok := intervals[0].Gauges[droppedKey]
+	if !ok {
+		t.Fatalf("Key %v not found in map %v", droppedKey, intervals[0].Gauges)
+	}
+	if droppedGauge.Value != float32(excessGauges) {
+		t.Errorf("Dropped gauge value is %v, expected %v.",
+			droppedGauge.Value, excessGauges)
+	}
+
+	if len(intervals[0].Gauges) != sink.MaxGaugeCardinality+1 {
 		t.Errorf("Found %v gauges, expected %v.",
 			len(intervals[0].Gauges),
-			sink.MaxGaugeCardinality)
+			sink.MaxGaugeCardinality+1)
 	}
 
 	minVal := float32(excessGauges)
-	for _, v := range intervals[0].Gauges {
+	for k, v := range intervals[0].Gauges {
+		if k == droppedKey {
+			continue
+		}
 		if v.Value < minVal {
 			t.Errorf("Gauge %v with value %v should not have been included.", v.Labels, v.Value)
 			break
@@ -549,3 +563,253 @@ func TestGauge_MeasurementError(t *testing.T) {
 			len(intervals[0].Gauges), 0)
 	}
 }
+
+// helper function to create a GaugeCollector that blocks until
+// released or cancelled, then returns the values regardless.
+func (c *SimulatedCollector) makeBlockingFunctionForValues(
+	values []GaugeLabelValues,
+	release <-chan struct{},
+) GaugeCollector {
+	return func(ctx context.Context) ([]GaugeLabelValues, error) {
+		atomic.AddUint32(&c.numCalls, 1)
+		c.callBarrier <- c.numCalls
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return values, nil
+	}
+}
+
+// startCollection runs the process through its initial delay and
+// triggers the first collection.
+func startCollection(t *testing.T, s *SimulatedTime, c *SimulatedCollector, p *GaugeCollectionProcess) {
+	t.Helper()
+	go p.Run()
+
+	delayTicker := s.waitForTicker(t)
+	delayTicker.sender <- time.Now()
+
+	intervalTicker := s.waitForTicker(t)
+	intervalTicker.sender <- time.Now()
+	c.waitForCall(t)
+}
+
+func TestGauge_StoppedDuringCollection(t *testing.T) {
+	s := startSimulatedTime()
+	c := newSimulatedCollector()
+	inmemSink := metrics.NewInmemSink(
+		1000000*time.Hour,
+		2000000*time.Hour)
+	sink := NewClusterMetricSink("test", inmemSink)
+	sink.MaxGaugeCardinality = 500
+	sink.GaugeInterval = 2 * time.Hour
+
+	release := make(chan struct{})
+	p, err := sink.newGaugeCollectionProcessWithClock(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		c.makeBlockingFunctionForValues(makeLabels(10), release),
+		log.Default(),
+		s,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	startCollection(t, s, c, p)
+	p.Stop()
+	close(release)
+	waitForStopped(t, p)
+
+	intervals := inmemSink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	if len(intervals[0].Gauges) != 0 {
+		t.Errorf("Found %v gauges, expected %v.",
+			len(intervals[0].Gauges), 0)
+	}
+}
+
+func TestGauge_DrainedDuringCollection(t *testing.T) {
+	s := startSimulatedTime()
+	c := newSimulatedCollector()
+	inmemSink := metrics.NewInmemSink(
+		1000000*time.Hour,
+		2000000*time.Hour)
+	sink := NewClusterMetricSink("test", inmemSink)
+	sink.MaxGaugeCardinality = 500
+	sink.GaugeInterval = 2 * time.Hour
+
+	release := make(chan struct{})
+	values := makeLabels(10)
+	p, err := sink.newGaugeCollectionProcessWithClock(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		c.makeBlockingFunctionForValues(values, release),
+		log.Default(),
+		s,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	startCollection(t, s, c, p)
+	p.StopAndDrain()
+
+	// The drain timeout starts once the stop is noticed.
+	drainTicker := s.waitForTicker(t)
+	if drainTicker.duration != p.drainTimeout {
+		t.Errorf("Drain ticker duration is %v, expected %v",
+			drainTicker.duration, p.drainTimeout)
+	}
+
+	select {
+	case <-p.stopped:
+		t.Fatal("Process stopped before collection completed.")
+	default:
+	}
+
+	close(release)
+	waitForStopped(t, p)
+
+	intervals := inmemSink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	// Include the gauge for the number dropped.
+	if len(intervals[0].Gauges) != len(values)+1 {
+		t.Errorf("Found %v gauges, expected %v.",
+			len(intervals[0].Gauges), len(values)+1)
+	}
+}
+
+func TestGauge_DrainTimeout(t *testing.T) {
+	s := startSimulatedTime()
+	c := newSimulatedCollector()
+	inmemSink := metrics.NewInmemSink(
+		1000000*time.Hour,
+		2000000*time.Hour)
+	sink := NewClusterMetricSink("test", inmemSink)
+	sink.MaxGaugeCardinality = 500
+	sink.GaugeInterval = 2 * time.Hour
+
+	// Never released; the collection must be cancelled.
+	release := make(chan struct{})
+	p, err := sink.newGaugeCollectionProcessWithClock(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		c.makeBlockingFunctionForValues(makeLabels(10), release),
+		log.Default(),
+		s,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	startCollection(t, s, c, p)
+	p.StopAndDrain()
+
+	drainTicker := s.waitForTicker(t)
+	drainTicker.sender <- time.Now()
+	waitForStopped(t, p)
+
+	intervals := inmemSink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	if len(intervals[0].Gauges) != 0 {
+		t.Errorf("Found %v gauges, expected %v.",
+			len(intervals[0].Gauges), 0)
+	}
+}
+
+func TestGauge_Status(t *testing.T) {
+	s := startSimulatedTime()
+	s.allowTickers(100)
+	c := newSimulatedCollector()
+	sink := BlackholeSink()
+	sink.GaugeInterval = 2 * time.Hour
+
+	threshold := time.Duration(int(sink.GaugeInterval) / 100)
+	f := func(ctx context.Context) ([]GaugeLabelValues, error) {
+		atomic.AddUint32(&c.numCalls, 1)
+		s.now = s.now.Add(threshold).Add(time.Second)
+		c.callBarrier <- c.numCalls
+		return nil, errors.New("test error")
+	}
+
+	p, err := sink.newGaugeCollectionProcessWithClock(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		f,
+		log.Default(),
+		s,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	status := p.Status()
+	if !status.LastRun.IsZero() || status.ErrorCount != 0 {
+		t.Errorf("Unexpected initial status %+v", status)
+	}
+
+	p.collectAndFilterGauges()
+	<-c.callBarrier
+
+	status = p.Status()
+	if !status.LastRun.Equal(s.now) {
+		t.Errorf("Last run is %v, expected %v.", status.LastRun, s.now)
+	}
+	if status.LastDuration != threshold+time.Second {
+		t.Errorf("Last duration is %v, expected %v.", status.LastDuration, threshold+time.Second)
+	}
+	if status.LastError == nil || status.ErrorCount != 1 {
+		t.Errorf("Error not recorded in status %+v", status)
+	}
+	if status.CurrentInterval != 2*status.OriginalInterval {
+		t.Errorf("Backoff not recorded in status %+v", status)
+	}
+}
+
+func TestGauge_StatusListedWhileRunning(t *testing.T) {
+	s := startSimulatedTime()
+	c := newSimulatedCollector()
+	sink := BlackholeSink()
+	sink.GaugeInterval = 2 * time.Hour
+
+	p, err := sink.newGaugeCollectionProcessWithClock(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		c.EmptyCollectionFunction,
+		log.Default(),
+		s,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	if len(sink.GaugeCollectionStatus()) != 0 {
+		t.Fatal("Process listed before running.")
+	}
+
+	go p.Run()
+	s.waitForTicker(t)
+
+	statuses := sink.GaugeCollectionStatus()
+	if len(statuses) != 1 || !reflect.DeepEqual(statuses[0].Key, p.key) {
+		t.Errorf("Expected running process to be listed, got %+v", statuses)
+	}
+
+	p.Stop()
+	waitForStopped(t, p)
+
+	if len(sink.GaugeCollectionStatus()) != 0 {
+		t.Error("Process listed after stopping.")
+	}
+}
@@ -0,0 +1,134 @@
+package metricsutil
+
+import (
+	"sync"
+	"time"
+)
+
+// GaugeCollectionOptions control how a GaugeCollectionProcess is
+// scheduled relative to the other processes sharing a sink.
+type GaugeCollectionOptions struct {
+	// Expensive collectors are run one at a time, so that processes
+	// which walk large parts of storage do not pile up CPU and I/O
+	// work. They also start at deterministic, evenly spread offsets
+	// instead of a random delay.
+	Expensive bool
+
+	// Priority decides which expensive collector runs first when
+	// several are waiting; higher values run earlier. Collectors
+	// with equal priority run in the order they became ready.
+	Priority int
+
+	// MaxRuntime is the budget for a single collection, after which
+	// its context is cancelled. If zero, the budget is a fraction of
+	// the current interval. Time spent waiting for other expensive
+	// collectors does not count against it.
+	MaxRuntime time.Duration
+}
+
+// gaugeCollectionScheduler serializes the expensive collection
+// processes of a sink.
+type gaugeCollectionScheduler struct {
+	l       sync.Mutex
+	busy    bool
+	waiting []*gaugeCollectionWaiter
+	seq     uint64
+
+	// number of start offsets handed out so far
+	started uint64
+}
+
+type gaugeCollectionWaiter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// startOffset returns the initial delay for the next expensive process
+// to start. Offsets follow the base-2 van der Corput sequence (1/2, 1/4,
+// 3/4, 1/8, ...) of the interval, so however many processes are
+// started, they remain roughly evenly spread, without needing to know
+// them all in advance.
+func (s *gaugeCollectionScheduler) startOffset(interval time.Duration) time.Duration {
+	s.l.Lock()
+	s.started++
+	n := s.started
+	s.l.Unlock()
+
+	fraction := 0.0
+	for base := 0.5; n > 0; base /= 2 {
+		if n&1 == 1 {
+			fraction += base
+		}
+		n >>= 1
+	}
+	return time.Duration(fraction * float64(interval))
+}
+
+// acquire blocks until the caller may run an expensive collection,
+// returning true, or until stop is closed, returning false. A true
+// result must be followed by a call to release.
+func (s *gaugeCollectionScheduler) acquire(priority int, stop <-chan struct{}) bool {
+	s.l.Lock()
+	if !s.busy {
+		s.busy = true
+		s.l.Unlock()
+		return true
+	}
+	s.seq++
+	w := &gaugeCollectionWaiter{
+		priority: priority,
+		seq:      s.seq,
+		ready:    make(chan struct{}),
+	}
+	s.waiting = append(s.waiting, w)
+	s.l.Unlock()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-stop:
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	select {
+	case <-w.ready:
+		// Granted concurrently with the stop; pass it on.
+		s.grantNextLocked()
+	default:
+		for i, other := range s.waiting {
+			if other == w {
+				s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	return false
+}
+
+// release allows the next waiting collection, if any, to run.
+func (s *gaugeCollectionScheduler) release() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.grantNextLocked()
+}
+
+func (s *gaugeCollectionScheduler) grantNextLocked() {
+	if len(s.waiting) == 0 {
+		s.busy = false
+		return
+	}
+
+	next := 0
+	for i, w := range s.waiting {
+		best := s.waiting[next]
+		if w.priority > best.priority || (w.priority == best.priority && w.seq < best.seq) {
+			next = i
+		}
+	}
+	w := s.waiting[next]
+	s.waiting = append(s.waiting[:next], s.waiting[next+1:]...)
+	close(w.ready)
+}
@@ -0,0 +1,241 @@
+package metricsutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+func TestGaugeScheduler_StartOffsets(t *testing.T) {
+	s := &gaugeCollectionScheduler{}
+	interval := 80 * time.Minute
+
+	expected := []time.Duration{
+		40 * time.Minute,
+		20 * time.Minute,
+		60 * time.Minute,
+		10 * time.Minute,
+		50 * time.Minute,
+	}
+	for i, e := range expected {
+		if offset := s.startOffset(interval); offset != e {
+			t.Errorf("Offset %v is %v, expected %v.", i, offset, e)
+		}
+	}
+}
+
+// waitForWaiters blocks until n collections are queued.
+func waitForWaiters(t *testing.T, s *gaugeCollectionScheduler, n int) {
+	t.Helper()
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		s.l.Lock()
+		waiting := len(s.waiting)
+		s.l.Unlock()
+		if waiting == n {
+			return
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("Timeout waiting for %v waiters, found %v.", n, waiting)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestGaugeScheduler_Priority(t *testing.T) {
+	s := &gaugeCollectionScheduler{}
+	stop := make(chan struct{})
+
+	if !s.acquire(0, stop) {
+		t.Fatal("Failed to acquire idle scheduler.")
+	}
+
+	order := make(chan string, 3)
+	queue := func(name string, priority int) {
+		go func() {
+			if s.acquire(priority, stop) {
+				order <- name
+			}
+		}()
+	}
+	queue("low", 1)
+	waitForWaiters(t, s, 1)
+	queue("high-first", 5)
+	waitForWaiters(t, s, 2)
+	queue("high-second", 5)
+	waitForWaiters(t, s, 3)
+
+	for _, expected := range []string{"high-first", "high-second", "low"} {
+		s.release()
+		select {
+		case name := <-order:
+			if name != expected {
+				t.Errorf("%v ran, expected %v.", name, expected)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("Timeout waiting for %v to run.", expected)
+		}
+	}
+
+	s.release()
+	if s.busy {
+		t.Error("Scheduler still busy after final release.")
+	}
+}
+
+func TestGaugeScheduler_StopWhileWaiting(t *testing.T) {
+	s := &gaugeCollectionScheduler{}
+	s.acquire(0, nil)
+
+	stop := make(chan struct{})
+	result := make(chan bool)
+	go func() {
+		result <- s.acquire(0, stop)
+	}()
+	waitForWaiters(t, s, 1)
+	close(stop)
+
+	if <-result {
+		t.Error("Acquired scheduler after stop.")
+	}
+	waitForWaiters(t, s, 0)
+
+	s.release()
+	if s.busy {
+		t.Error("Scheduler still busy after release.")
+	}
+}
+
+func TestGauge_ExpensiveSerialized(t *testing.T) {
+	s := startSimulatedTime()
+	s.allowTickers(10)
+	sink := BlackholeSink()
+	sink.MaxGaugeCardinality = 500
+	sink.GaugeInterval = 2 * time.Hour
+
+	opts := GaugeCollectionOptions{Expensive: true}
+	c1 := newSimulatedCollector()
+	release1 := make(chan struct{})
+	p1, err := sink.newGaugeCollectionProcess(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "first"}},
+		c1.makeBlockingFunctionForValues(makeLabels(10), release1),
+		log.Default(),
+		s,
+		opts,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+	c2 := newSimulatedCollector()
+	release2 := make(chan struct{})
+	p2, err := sink.newGaugeCollectionProcess(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "second"}},
+		c2.makeBlockingFunctionForValues(makeLabels(10), release2),
+		log.Default(),
+		s,
+		opts,
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	go p1.Run()
+	delayTicker := s.waitForTicker(t)
+	if delayTicker.duration != time.Hour {
+		t.Errorf("First start delay is %v, expected %v.", delayTicker.duration, time.Hour)
+	}
+	delayTicker.sender <- time.Now()
+	s.waitForTicker(t).sender <- time.Now()
+	c1.waitForCall(t)
+
+	go p2.Run()
+	delayTicker = s.waitForTicker(t)
+	if delayTicker.duration != 30*time.Minute {
+		t.Errorf("Second start delay is %v, expected %v.", delayTicker.duration, 30*time.Minute)
+	}
+	delayTicker.sender <- time.Now()
+	s.waitForTicker(t).sender <- time.Now()
+
+	// The second collection must wait for the first.
+	select {
+	case <-c2.callBarrier:
+		t.Fatal("Second collection ran concurrently with the first.")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release1)
+	c2.waitForCall(t)
+
+	status := p2.Status()
+	if !status.Expensive {
+		t.Error("Process not reported as expensive.")
+	}
+
+	p1.Stop()
+	p2.Stop()
+	close(release2)
+	waitForStopped(t, p1)
+	waitForStopped(t, p2)
+}
+
+func TestGauge_MaxRuntime(t *testing.T) {
+	s := startSimulatedTime()
+	sink := BlackholeSink()
+	sink.GaugeInterval = 2 * time.Hour
+
+	deadlines := make(chan time.Duration, 1)
+	collector := func(ctx context.Context) ([]GaugeLabelValues, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Error("Collection context has no deadline.")
+		}
+		deadlines <- time.Until(deadline)
+		return []GaugeLabelValues{}, nil
+	}
+
+	p, err := sink.newGaugeCollectionProcess(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		collector,
+		log.Default(),
+		s,
+		GaugeCollectionOptions{MaxRuntime: time.Hour},
+	)
+	if err != nil {
+		t.Fatalf("Error creating collection process: %v", err)
+	}
+
+	go p.Run()
+	s.waitForTicker(t).sender <- time.Now()
+	s.waitForTicker(t).sender <- time.Now()
+
+	// The default budget would be 2% of the interval.
+	remaining := <-deadlines
+	if remaining > time.Hour || remaining < 59*time.Minute {
+		t.Errorf("Collection budget is %v, expected %v.", remaining, time.Hour)
+	}
+
+	p.Stop()
+	waitForStopped(t, p)
+}
+
+func TestGauge_NegativeMaxRuntime(t *testing.T) {
+	sink := BlackholeSink()
+	sink.GaugeInterval = 2 * time.Hour
+
+	_, err := sink.NewGaugeCollectionProcessWithOptions(
+		[]string{"example", "count"},
+		[]Label{{"gauge", "test"}},
+		newSimulatedCollector().EmptyCollectionFunction,
+		log.Default(),
+		GaugeCollectionOptions{MaxRuntime: -time.Second},
+	)
+	if err == nil {
+		t.Error("Expected error for negative maximum runtime.")
+	}
+}
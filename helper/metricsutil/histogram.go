@@ -0,0 +1,153 @@
+package metricsutil
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultHistogramBuckets are the upper bounds, in milliseconds, of the
+// buckets used by histograms without a configured set of buckets.
+var DefaultHistogramBuckets = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// histogramNameSuffix distinguishes a histogram from the summary that
+// the go-metrics Prometheus sink reports for samples of the same key.
+const histogramNameSuffix = "_histogram"
+
+// HistogramSink records samples in Prometheus histograms, which, unlike
+// the summaries produced from go-metrics samples, can be aggregated
+// across scrapes and instances to compute accurate quantiles.
+//
+// It implements prometheus.Collector as an unchecked collector, since
+// the set of histograms is not known in advance.
+type HistogramSink struct {
+	prefix  string
+	buckets map[string][]float64
+
+	l          sync.Mutex
+	histograms map[string]prometheus.Histogram
+}
+
+var _ prometheus.Collector = &HistogramSink{}
+
+// NewHistogramSink creates a sink whose metric names begin with prefix.
+// Buckets may be configured per metric, keyed by the metric key joined
+// with "." and without the prefix, e.g. "core.handle_request".
+func NewHistogramSink(prefix string, buckets map[string][]float64) *HistogramSink {
+	return &HistogramSink{
+		prefix:     prefix,
+		buckets:    buckets,
+		histograms: make(map[string]prometheus.Histogram),
+	}
+}
+
+// ValidateHistogramBuckets checks that a list of bucket boundaries is
+// non-empty and strictly increasing.
+func ValidateHistogramBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		return errors.New("at least one bucket must be provided")
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		return errors.New("buckets must be in increasing order")
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] == buckets[i-1] {
+			return errors.New("buckets must not be repeated")
+		}
+	}
+	return nil
+}
+
+// Observe records val in the histogram for the given key and labels.
+func (s *HistogramSink) Observe(key []string, val float32, labels []Label) {
+	s.histogram(key, labels).Observe(float64(val))
+}
+
+func (s *HistogramSink) histogram(key []string, labels []Label) prometheus.Histogram {
+	var id strings.Builder
+	id.WriteString(strings.Join(key, "."))
+	for _, l := range labels {
+		id.WriteString(";")
+		id.WriteString(l.Name)
+		id.WriteString("=")
+		id.WriteString(l.Value)
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	h, ok := s.histograms[id.String()]
+	if ok {
+		return h
+	}
+
+	buckets, ok := s.buckets[strings.Join(key, ".")]
+	if !ok {
+		buckets = DefaultHistogramBuckets
+	}
+
+	constLabels := make(prometheus.Labels, len(labels))
+	for _, l := range labels {
+		constLabels[l.Name] = l.Value
+	}
+
+	name := strings.Join(append([]string{s.prefix}, key...), "_")
+	h = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:        prometheusName(name) + histogramNameSuffix,
+		Help:        strings.Join(key, "."),
+		ConstLabels: constLabels,
+		Buckets:     buckets,
+	})
+	s.histograms[id.String()] = h
+	return h
+}
+
+// prometheusName replaces characters that are not valid in a Prometheus
+// metric name, in the same way as the go-metrics Prometheus sink.
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// Describe sends no descriptors, which makes this an unchecked collector.
+func (s *HistogramSink) Describe(chan<- *prometheus.Desc) {
+}
+
+// Collect sends the current state of every histogram.
+func (s *HistogramSink) Collect(ch chan<- prometheus.Metric) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for _, h := range s.histograms {
+		ch <- h
+	}
+}
+
+// ObserveHistogramWithLabels records a value in a histogram, if
+// histograms are enabled. Unlike AddSampleWithLabels, it is not sent
+// to the go-metrics sink.
+func (m *ClusterMetricSink) ObserveHistogramWithLabels(key []string, val float32, labels []Label) {
+	if m.Histograms == nil {
+		return
+	}
+	m.Histograms.Observe(key, val,
+		append(labels, Label{"cluster", m.ClusterName.Load().(string)}))
+}
+
+// MeasureSinceHistogramWithLabels records the time elapsed since start,
+// in milliseconds, in a histogram, if histograms are enabled.
+func (m *ClusterMetricSink) MeasureSinceHistogramWithLabels(key []string, start time.Time, labels []Label) {
+	elapsed := time.Now().Sub(start)
+	val := float32(elapsed) / float32(time.Millisecond)
+	m.ObserveHistogramWithLabels(key, val, labels)
+}
@@ -0,0 +1,93 @@
+package metricsutil
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func gatherHistograms(t *testing.T, s *HistogramSink) []*dto.MetricFamily {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(s); err != nil {
+		t.Fatal(err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families
+}
+
+func TestHistogramSink_Buckets(t *testing.T) {
+	s := NewHistogramSink("vault", map[string][]float64{
+		"core.handle_request": {1, 10, 100},
+	})
+	sink := BlackholeSink()
+	sink.ClusterName.Store("test-cluster")
+	sink.Histograms = s
+
+	for _, v := range []float32{0.5, 5, 50, 500} {
+		sink.ObserveHistogramWithLabels([]string{"core", "handle_request"}, v, nil)
+	}
+	sink.ObserveHistogramWithLabels([]string{"core", "handle_login_request"}, 3, nil)
+
+	families := gatherHistograms(t, s)
+	if len(families) != 2 {
+		t.Fatalf("Found %v metric families, expected 2.", len(families))
+	}
+
+	// Sorted by name
+	login, req := families[0], families[1]
+	if req.GetName() != "vault_core_handle_request_histogram" {
+		t.Fatalf("Bad name %v", req.GetName())
+	}
+
+	h := req.GetMetric()[0].GetHistogram()
+	if h.GetSampleCount() != 4 || h.GetSampleSum() != 555.5 {
+		t.Errorf("Bad count %v or sum %v", h.GetSampleCount(), h.GetSampleSum())
+	}
+	expectedCounts := []uint64{1, 2, 3}
+	if len(h.GetBucket()) != len(expectedCounts) {
+		t.Fatalf("Found %v buckets, expected %v.", len(h.GetBucket()), len(expectedCounts))
+	}
+	for i, b := range h.GetBucket() {
+		if b.GetCumulativeCount() != expectedCounts[i] {
+			t.Errorf("Bucket %v has count %v, expected %v.", b.GetUpperBound(), b.GetCumulativeCount(), expectedCounts[i])
+		}
+	}
+
+	labels := req.GetMetric()[0].GetLabel()
+	if len(labels) != 1 || labels[0].GetName() != "cluster" || labels[0].GetValue() != "test-cluster" {
+		t.Errorf("Bad labels %v", labels)
+	}
+
+	// Not configured, so uses the default buckets
+	if len(login.GetMetric()[0].GetHistogram().GetBucket()) != len(DefaultHistogramBuckets) {
+		t.Errorf("Expected default buckets for %v", login.GetName())
+	}
+}
+
+func TestHistogramSink_Disabled(t *testing.T) {
+	sink := BlackholeSink()
+	// Must not panic
+	sink.ObserveHistogramWithLabels([]string{"core", "handle_request"}, 1, nil)
+}
+
+func TestValidateHistogramBuckets(t *testing.T) {
+	for _, tc := range []struct {
+		buckets []float64
+		valid   bool
+	}{
+		{[]float64{1, 2, 3}, true},
+		{[]float64{}, false},
+		{[]float64{3, 2}, false},
+		{[]float64{1, 1}, false},
+	} {
+		err := ValidateHistogramBuckets(tc.buckets)
+		if (err == nil) != tc.valid {
+			t.Errorf("Buckets %v: got error %v, expected valid=%v", tc.buckets, err, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,577 @@
+package metricsutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	// OTLPDefaultExportInterval is how often metrics are pushed to the
+	// collector if no interval is configured.
+	OTLPDefaultExportInterval = 10 * time.Second
+
+	otlpScopeName = "github.com/hashicorp/vault"
+
+	// Values from the OTLP AggregationTemporality enum
+	otlpTemporalityDelta = 1
+)
+
+// OTLPSinkConfig configures an OTLPSink.
+type OTLPSinkConfig struct {
+	// Endpoint is the full URL of the collector's OTLP/HTTP metrics
+	// receiver, typically ending in /v1/metrics.
+	Endpoint string
+
+	// Headers are added to every export request, e.g. for authentication.
+	Headers map[string]string
+
+	// ExportInterval is the time between pushes to the collector.
+	ExportInterval time.Duration
+
+	// MaxGaugeCardinality limits the number of label combinations kept for
+	// each gauge, since gauges are reported until the sink stops. Values of
+	// further combinations are dropped. If zero, there is no limit.
+	MaxGaugeCardinality int
+
+	// ServiceName, ClusterName and NodeID are reported as resource
+	// attributes on every export.
+	ServiceName string
+	ClusterName string
+	NodeID      string
+
+	// HTTPClient is used to contact the collector; if nil, a client
+	// with a short timeout is created.
+	HTTPClient *http.Client
+
+	Logger log.Logger
+}
+
+// OTLPSink is a go-metrics sink that aggregates metrics in memory and
+// periodically pushes them to an OpenTelemetry collector using the
+// OTLP/HTTP protocol with JSON encoding. Neither gRPC nor the protobuf
+// encoding of OTLP/HTTP are supported.
+//
+// Gauges report their last value; counters are reported as monotonic
+// sums with delta temporality; samples are reported as summaries with
+// min and max as the 0 and 1 quantiles. Counters and samples which could
+// not be pushed are kept and pushed along with the next ones.
+type OTLPSink struct {
+	config OTLPSinkConfig
+	client *http.Client
+	logger log.Logger
+
+	// flushLock serializes flushes, so that metrics which failed to be
+	// pushed are merged back before the next flush picks them up
+	flushLock sync.Mutex
+
+	l         sync.Mutex
+	gauges    map[string]*otlpPoint
+	counters  map[string]*otlpPoint
+	samples   map[string]*otlpPoint
+	lastFlush time.Time
+
+	// gaugeSeries counts the label combinations of each gauge, and
+	// droppedGauges the values dropped over the cardinality limit since
+	// the last flush
+	gaugeSeries   map[string]int
+	droppedGauges map[string]int
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	started   bool
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+var _ metrics.MetricSink = &OTLPSink{}
+
+// otlpPoint is the aggregated value for a single name/label combination
+// within one export interval.
+type otlpPoint struct {
+	name   string
+	labels []Label
+	value  float64
+	count  uint64
+	sum    float64
+	min    float64
+	max    float64
+}
+
+// NewOTLPSink creates a new sink. The Start method must be called to
+// begin exporting.
+func NewOTLPSink(config OTLPSinkConfig) (*OTLPSink, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("OTLP endpoint must be provided")
+	}
+	if config.ExportInterval <= 0 {
+		config.ExportInterval = OTLPDefaultExportInterval
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Timeout: config.ExportInterval,
+		}
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
+	return &OTLPSink{
+		config:    config,
+		client:    client,
+		logger:    logger,
+		gauges:    make(map[string]*otlpPoint),
+		counters:  make(map[string]*otlpPoint),
+		samples:   make(map[string]*otlpPoint),
+		lastFlush: time.Now(),
+
+		gaugeSeries:   make(map[string]int),
+		droppedGauges: make(map[string]int),
+
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// Start begins periodically exporting to the collector in a
+// background goroutine.
+func (s *OTLPSink) Start() {
+	s.startOnce.Do(func() {
+		s.l.Lock()
+		s.started = true
+		s.l.Unlock()
+		go s.run()
+	})
+}
+
+func (s *OTLPSink) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.config.ExportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(context.Background()); err != nil {
+				s.logger.Warn("failed to export metrics to OTLP collector", "error", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop ends the background export and pushes any remaining metrics. Only
+// the first call has an effect.
+func (s *OTLPSink) Stop() error {
+	var err error
+	s.stopOnce.Do(func() {
+		s.l.Lock()
+		started := s.started
+		s.l.Unlock()
+
+		close(s.stop)
+		if started {
+			<-s.stopped
+		}
+		err = s.Flush(context.Background())
+	})
+	return err
+}
+
+func (s *OTLPSink) SetGauge(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) SetGaugeWithLabels(key []string, val float32, labels []Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	p := s.gaugePoint(key, labels)
+	if p == nil {
+		return
+	}
+	p.value = float64(val)
+}
+
+func (s *OTLPSink) EmitKey(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) IncrCounter(key []string, val float32) {
+	s.IncrCounterWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) IncrCounterWithLabels(key []string, val float32, labels []Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	p := s.point(s.counters, key, labels)
+	p.value += float64(val)
+}
+
+func (s *OTLPSink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+func (s *OTLPSink) AddSampleWithLabels(key []string, val float32, labels []Label) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	p := s.point(s.samples, key, labels)
+	v := float64(val)
+	if p.count == 0 || v < p.min {
+		p.min = v
+	}
+	if p.count == 0 || v > p.max {
+		p.max = v
+	}
+	p.count++
+	p.sum += v
+}
+
+// gaugePoint is point for gauges, which returns nil once the gauge has
+// as many label combinations as allowed. The lock must be held.
+func (s *OTLPSink) gaugePoint(key []string, labels []Label) *otlpPoint {
+	name, id := otlpPointID(key, labels)
+	if p, ok := s.gauges[id]; ok {
+		return p
+	}
+
+	if s.config.MaxGaugeCardinality > 0 && s.gaugeSeries[name] >= s.config.MaxGaugeCardinality {
+		s.droppedGauges[name]++
+		return nil
+	}
+	s.gaugeSeries[name]++
+	return s.point(s.gauges, key, labels)
+}
+
+// point looks up or creates the entry for the given key and labels.
+// The lock must be held.
+func (s *OTLPSink) point(m map[string]*otlpPoint, key []string, labels []Label) *otlpPoint {
+	name, id := otlpPointID(key, labels)
+
+	p, ok := m[id]
+	if !ok {
+		p = &otlpPoint{
+			name:   name,
+			labels: append([]Label(nil), labels...),
+		}
+		m[id] = p
+	}
+	return p
+}
+
+// otlpPointID returns the name of the metric of the given key, and the ID
+// of its entry for the given labels.
+func otlpPointID(key []string, labels []Label) (string, string) {
+	name := strings.Join(key, ".")
+
+	var id strings.Builder
+	id.WriteString(name)
+	for _, l := range labels {
+		id.WriteString(";")
+		id.WriteString(l.Name)
+		id.WriteString("=")
+		id.WriteString(l.Value)
+	}
+	return name, id.String()
+}
+
+// merge adds the counters and samples of a failed export back into the
+// ones collected since. The lock must be held.
+func (s *OTLPSink) merge(counters, samples map[string]*otlpPoint) {
+	for id, old := range counters {
+		if p, ok := s.counters[id]; ok {
+			p.value += old.value
+			continue
+		}
+		s.counters[id] = old
+	}
+
+	for id, old := range samples {
+		p, ok := s.samples[id]
+		if !ok {
+			s.samples[id] = old
+			continue
+		}
+		if old.min < p.min {
+			p.min = old.min
+		}
+		if old.max > p.max {
+			p.max = old.max
+		}
+		p.count += old.count
+		p.sum += old.sum
+	}
+}
+
+// Flush immediately exports all metrics collected since the last
+// successful flush. Gauges retain their values across flushes; counters
+// and samples are reset once the collector has accepted them.
+func (s *OTLPSink) Flush(ctx context.Context) error {
+	s.flushLock.Lock()
+	defer s.flushLock.Unlock()
+
+	s.l.Lock()
+	now := time.Now()
+	body, err := json.Marshal(s.buildRequest(s.lastFlush, now))
+	counters, samples := s.counters, s.samples
+	s.counters = make(map[string]*otlpPoint)
+	s.samples = make(map[string]*otlpPoint)
+	dropped := s.droppedGauges
+	s.droppedGauges = make(map[string]int)
+	s.l.Unlock()
+
+	for name, count := range dropped {
+		s.logger.Warn("dropped values of gauge over its cardinality limit", "name", name, "count", count, "limit", s.config.MaxGaugeCardinality)
+	}
+
+	if err == nil {
+		err = s.export(ctx, body)
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	if err != nil {
+		s.merge(counters, samples)
+		return err
+	}
+	s.lastFlush = now
+	return nil
+}
+
+// export pushes an encoded export request to the collector.
+func (s *OTLPSink) export(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("OTLP collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The types below are the subset of the OTLP metrics protobuf schema
+// needed by this sink, using its canonical JSON mapping. 64-bit
+// integers are encoded as strings.
+
+type otlpExportRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpMetric struct {
+	Name    string       `json:"name"`
+	Gauge   *otlpGauge   `json:"gauge,omitempty"`
+	Sum     *otlpSum     `json:"sum,omitempty"`
+	Summary *otlpSummary `json:"summary,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryDataPoint `json:"dataPoints"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+type otlpSummaryDataPoint struct {
+	Attributes        []otlpKeyValue      `json:"attributes,omitempty"`
+	StartTimeUnixNano string              `json:"startTimeUnixNano"`
+	TimeUnixNano      string              `json:"timeUnixNano"`
+	Count             string              `json:"count"`
+	Sum               float64             `json:"sum"`
+	QuantileValues    []otlpQuantileValue `json:"quantileValues"`
+}
+
+type otlpQuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+func otlpAttributes(labels []Label) []otlpKeyValue {
+	if len(labels) == 0 {
+		return nil
+	}
+	attrs := make([]otlpKeyValue, len(labels))
+	for i, l := range labels {
+		attrs[i] = otlpKeyValue{Key: l.Name, Value: otlpAnyValue{StringValue: l.Value}}
+	}
+	return attrs
+}
+
+func otlpTimestamp(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// buildRequest converts the aggregated metrics into an export request,
+// grouping data points by metric name. The lock must be held.
+func (s *OTLPSink) buildRequest(start, end time.Time) *otlpExportRequest {
+	startTS := otlpTimestamp(start)
+	endTS := otlpTimestamp(end)
+
+	byName := make(map[string]*otlpMetric)
+	metricFor := func(name string) *otlpMetric {
+		m, ok := byName[name]
+		if !ok {
+			m = &otlpMetric{Name: name}
+			byName[name] = m
+		}
+		return m
+	}
+
+	for _, p := range s.gauges {
+		m := metricFor(p.name)
+		if m.Gauge == nil {
+			m.Gauge = &otlpGauge{}
+		}
+		m.Gauge.DataPoints = append(m.Gauge.DataPoints, otlpNumberDataPoint{
+			Attributes:   otlpAttributes(p.labels),
+			TimeUnixNano: endTS,
+			AsDouble:     p.value,
+		})
+	}
+
+	for _, p := range s.counters {
+		m := metricFor(p.name)
+		if m.Sum == nil {
+			m.Sum = &otlpSum{
+				AggregationTemporality: otlpTemporalityDelta,
+				IsMonotonic:            true,
+			}
+		}
+		m.Sum.DataPoints = append(m.Sum.DataPoints, otlpNumberDataPoint{
+			Attributes:        otlpAttributes(p.labels),
+			StartTimeUnixNano: startTS,
+			TimeUnixNano:      endTS,
+			AsDouble:          p.value,
+		})
+	}
+
+	for _, p := range s.samples {
+		m := metricFor(p.name)
+		if m.Summary == nil {
+			m.Summary = &otlpSummary{}
+		}
+		m.Summary.DataPoints = append(m.Summary.DataPoints, otlpSummaryDataPoint{
+			Attributes:        otlpAttributes(p.labels),
+			StartTimeUnixNano: startTS,
+			TimeUnixNano:      endTS,
+			Count:             strconv.FormatUint(p.count, 10),
+			Sum:               p.sum,
+			QuantileValues: []otlpQuantileValue{
+				{Quantile: 0, Value: p.min},
+				{Quantile: 1, Value: p.max},
+			},
+		})
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ms := make([]otlpMetric, 0, len(names))
+	for _, name := range names {
+		ms = append(ms, *byName[name])
+	}
+
+	resource := []Label{
+		{Name: "service.name", Value: s.config.ServiceName},
+	}
+	if s.config.ClusterName != "" {
+		resource = append(resource, Label{Name: "vault.cluster.name", Value: s.config.ClusterName})
+	}
+	if s.config.NodeID != "" {
+		resource = append(resource, Label{Name: "service.instance.id", Value: s.config.NodeID})
+	}
+
+	return &otlpExportRequest{
+		ResourceMetrics: []otlpResourceMetrics{
+			{
+				Resource: otlpResource{
+					Attributes: otlpAttributes(resource),
+				},
+				ScopeMetrics: []otlpScopeMetrics{
+					{
+						Scope:   otlpScope{Name: otlpScopeName},
+						Metrics: ms,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,260 @@
+package metricsutil
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPSink_Flush(t *testing.T) {
+	bodies := make(chan []byte, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad content type %q", r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get("X-Test") != "yes" {
+			t.Errorf("missing configured header")
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{
+		Endpoint:    srv.URL,
+		Headers:     map[string]string{"X-Test": "yes"},
+		ServiceName: "vault",
+		ClusterName: "test-cluster",
+		NodeID:      "node1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := []Label{{Name: "namespace", Value: "root"}}
+	s.SetGaugeWithLabels([]string{"vault", "token", "count"}, 5, labels)
+	s.IncrCounter([]string{"vault", "core", "handle_request"}, 1)
+	s.IncrCounter([]string{"vault", "core", "handle_request"}, 2)
+	s.AddSample([]string{"vault", "core", "check_token"}, 4)
+	s.AddSample([]string{"vault", "core", "check_token"}, 2)
+
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpExportRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.ResourceMetrics) != 1 {
+		t.Fatalf("expected one resource, got %d", len(req.ResourceMetrics))
+	}
+	rm := req.ResourceMetrics[0]
+
+	attrs := make(map[string]string)
+	for _, kv := range rm.Resource.Attributes {
+		attrs[kv.Key] = kv.Value.StringValue
+	}
+	if attrs["service.name"] != "vault" || attrs["vault.cluster.name"] != "test-cluster" || attrs["service.instance.id"] != "node1" {
+		t.Errorf("bad resource attributes: %v", attrs)
+	}
+
+	ms := rm.ScopeMetrics[0].Metrics
+	if len(ms) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(ms))
+	}
+	// sorted by name
+	summary, counter, gauge := ms[0], ms[1], ms[2]
+
+	if summary.Summary == nil || summary.Summary.DataPoints[0].Count != "2" ||
+		summary.Summary.DataPoints[0].Sum != 6 ||
+		summary.Summary.DataPoints[0].QuantileValues[0].Value != 2 ||
+		summary.Summary.DataPoints[0].QuantileValues[1].Value != 4 {
+		t.Errorf("bad summary: %#v", summary)
+	}
+	if counter.Sum == nil || !counter.Sum.IsMonotonic || counter.Sum.DataPoints[0].AsDouble != 3 {
+		t.Errorf("bad counter: %#v", counter)
+	}
+	if gauge.Gauge == nil || gauge.Gauge.DataPoints[0].AsDouble != 5 ||
+		gauge.Gauge.DataPoints[0].Attributes[0].Key != "namespace" {
+		t.Errorf("bad gauge: %#v", gauge)
+	}
+
+	// Gauges persist across flushes, but counters and samples are reset.
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	req = otlpExportRequest{}
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	ms = req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 1 || ms[0].Gauge == nil {
+		t.Errorf("expected only the gauge after second flush, got %#v", ms)
+	}
+}
+
+func TestOTLPSink_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Flush(context.Background()); err == nil {
+		t.Fatal("expected error from bad status")
+	}
+}
+
+func TestOTLPSink_NoEndpoint(t *testing.T) {
+	if _, err := NewOTLPSink(OTLPSinkConfig{}); err == nil {
+		t.Fatal("expected error with no endpoint")
+	}
+}
+
+func TestOTLPSink_FailedExport(t *testing.T) {
+	var fail bool
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Counters and samples which failed to be pushed are pushed with the
+	// next ones
+	fail = true
+	s.IncrCounter([]string{"vault", "core", "handle_request"}, 1)
+	s.AddSample([]string{"vault", "core", "check_token"}, 4)
+	if err := s.Flush(context.Background()); err == nil {
+		t.Fatal("expected error from bad status")
+	}
+
+	fail = false
+	s.IncrCounter([]string{"vault", "core", "handle_request"}, 2)
+	s.AddSample([]string{"vault", "core", "check_token"}, 2)
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpExportRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	ms := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 metrics, got %#v", ms)
+	}
+	summary, counter := ms[0], ms[1]
+	if summary.Summary == nil || summary.Summary.DataPoints[0].Count != "2" ||
+		summary.Summary.DataPoints[0].Sum != 6 ||
+		summary.Summary.DataPoints[0].QuantileValues[0].Value != 2 ||
+		summary.Summary.DataPoints[0].QuantileValues[1].Value != 4 {
+		t.Errorf("bad summary: %#v", summary)
+	}
+	if counter.Sum == nil || counter.Sum.DataPoints[0].AsDouble != 3 {
+		t.Errorf("bad counter: %#v", counter)
+	}
+}
+
+func TestOTLPSink_Stop(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	s.IncrCounter([]string{"vault", "core", "handle_request"}, 1)
+
+	// The remaining metrics are pushed once, however many times the sink
+	// is stopped
+	wrapper := &ClusterMetricSink{Sink: s, OTLP: s}
+	if err := wrapper.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapper.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpExportRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	ms := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 1 || ms[0].Sum == nil {
+		t.Fatalf("expected the counter, got %#v", ms)
+	}
+	select {
+	case <-bodies:
+		t.Fatal("unexpected second export")
+	default:
+	}
+}
+
+func TestOTLPSink_GaugeCardinality(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	s, err := NewOTLPSink(OTLPSinkConfig{
+		Endpoint:            srv.URL,
+		MaxGaugeCardinality: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ns := range []string{"a", "b", "c", "a"} {
+		s.SetGaugeWithLabels([]string{"vault", "token", "count"}, 1, []Label{{Name: "namespace", Value: ns}})
+	}
+	s.SetGauge([]string{"vault", "expire", "num_leases"}, 1)
+
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var req otlpExportRequest
+	if err := json.Unmarshal(<-bodies, &req); err != nil {
+		t.Fatal(err)
+	}
+	ms := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 2 || len(ms[0].Gauge.DataPoints) != 1 || len(ms[1].Gauge.DataPoints) != 2 {
+		t.Fatalf("bad gauges: %#v", ms)
+	}
+}
@@ -1,12 +1,16 @@
 package metricsutil
 
 import (
+	"context"
+	"sort"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	metrics "github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 )
 
 // ClusterMetricSink serves as a shim around go-metrics
@@ -26,13 +30,50 @@ type ClusterMetricSink struct {
 	MaxGaugeCardinality int
 	GaugeInterval       time.Duration
 
+	// AggregateGaugeOverflow sums the gauge values beyond
+	// MaxGaugeCardinality into a single series with "other" labels,
+	// instead of dropping them.
+	AggregateGaugeOverflow bool
+
+	// NamespaceLabels enables attaching a "namespace" label derived from
+	// the request context in the *Context variants of the reporting
+	// methods.
+	NamespaceLabels bool
+
+	// NamespaceAllowlist, if non-empty, limits the namespace label values
+	// reported via the *Context methods; other namespaces are reported
+	// as "other". Entries use the same form as NamespaceLabel.
+	NamespaceAllowlist []string
+
 	// Sink is the go-metrics instance to send to.
 	Sink metrics.MetricSink
+
+	// Histograms, if set, receives values sent to the
+	// *HistogramWithLabels methods.
+	Histograms *HistogramSink
+
+	// OTLP, if set, is the OpenTelemetry sink among those of Sink, which
+	// pushes its remaining metrics when stopped.
+	OTLP *OTLPSink
+
+	// Running gauge collection processes
+	collectionLock      sync.Mutex
+	collectionProcesses map[*GaugeCollectionProcess]struct{}
+	collectionScheduler *gaugeCollectionScheduler
 }
 
 // Convenience alias
 type Label = metrics.Label
 
+// Stop stops the sinks which push metrics, once they have pushed the metrics
+// they hold.
+func (m *ClusterMetricSink) Stop() error {
+	if m.OTLP == nil {
+		return nil
+	}
+	return m.OTLP.Stop()
+}
+
 func (m *ClusterMetricSink) SetGaugeWithLabels(key []string, val float32, labels []Label) {
 	m.Sink.SetGaugeWithLabels(key, val,
 		append(labels, Label{"cluster", m.ClusterName.Load().(string)}))
@@ -43,6 +84,58 @@ func (m *ClusterMetricSink) IncrCounterWithLabels(key []string, val float32, lab
 		append(labels, Label{"cluster", m.ClusterName.Load().(string)}))
 }
 
+// SetGaugeWithLabelsContext is like SetGaugeWithLabels, but attaches the
+// namespace of the request context if namespace labels are enabled.
+func (m *ClusterMetricSink) SetGaugeWithLabelsContext(ctx context.Context, key []string, val float32, labels []Label) {
+	m.SetGaugeWithLabels(key, val, m.namespaceLabels(ctx, labels))
+}
+
+// IncrCounterWithLabelsContext is like IncrCounterWithLabels, but attaches
+// the namespace of the request context if namespace labels are enabled.
+func (m *ClusterMetricSink) IncrCounterWithLabelsContext(ctx context.Context, key []string, val float32, labels []Label) {
+	m.IncrCounterWithLabels(key, val, m.namespaceLabels(ctx, labels))
+}
+
+// namespaceLabels adds a namespace label from the context, if enabled and
+// not already present, and applies the allowlist to the namespace label.
+// The labels passed in are not modified.
+func (m *ClusterMetricSink) namespaceLabels(ctx context.Context, labels []Label) []Label {
+	for i, l := range labels {
+		if l.Name != "namespace" {
+			continue
+		}
+		if m.namespaceAllowed(l.Value) {
+			return labels
+		}
+		filtered := make([]Label, len(labels))
+		copy(filtered, labels)
+		filtered[i].Value = otherLabelValue
+		return filtered
+	}
+
+	if !m.NamespaceLabels {
+		return labels
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return labels
+	}
+
+	nsLabel := NamespaceLabel(ns)
+	if !m.namespaceAllowed(nsLabel.Value) {
+		nsLabel.Value = otherLabelValue
+	}
+
+	withNamespace := make([]Label, len(labels), len(labels)+1)
+	copy(withNamespace, labels)
+	return append(withNamespace, nsLabel)
+}
+
+func (m *ClusterMetricSink) namespaceAllowed(value string) bool {
+	return len(m.NamespaceAllowlist) == 0 || strutil.StrListContains(m.NamespaceAllowlist, value)
+}
+
 func (m *ClusterMetricSink) AddSampleWithLabels(key []string, val float32, labels []Label) {
 	m.Sink.AddSampleWithLabels(key, val,
 		append(labels, Label{"cluster", m.ClusterName.Load().(string)}))
@@ -90,6 +183,51 @@ func (m *ClusterMetricSink) SetDefaultClusterName(clusterName string) {
 	}
 }
 
+// gaugeScheduler returns the scheduler shared by the sink's expensive
+// collection processes.
+func (m *ClusterMetricSink) gaugeScheduler() *gaugeCollectionScheduler {
+	m.collectionLock.Lock()
+	defer m.collectionLock.Unlock()
+
+	if m.collectionScheduler == nil {
+		m.collectionScheduler = &gaugeCollectionScheduler{}
+	}
+	return m.collectionScheduler
+}
+
+func (m *ClusterMetricSink) registerCollectionProcess(p *GaugeCollectionProcess) {
+	m.collectionLock.Lock()
+	defer m.collectionLock.Unlock()
+
+	if m.collectionProcesses == nil {
+		m.collectionProcesses = make(map[*GaugeCollectionProcess]struct{})
+	}
+	m.collectionProcesses[p] = struct{}{}
+}
+
+func (m *ClusterMetricSink) unregisterCollectionProcess(p *GaugeCollectionProcess) {
+	m.collectionLock.Lock()
+	defer m.collectionLock.Unlock()
+
+	delete(m.collectionProcesses, p)
+}
+
+// GaugeCollectionStatus returns the status of every running
+// gauge collection process, sorted by key.
+func (m *ClusterMetricSink) GaugeCollectionStatus() []GaugeCollectionStatus {
+	m.collectionLock.Lock()
+	statuses := make([]GaugeCollectionStatus, 0, len(m.collectionProcesses))
+	for p := range m.collectionProcesses {
+		statuses = append(statuses, p.Status())
+	}
+	m.collectionLock.Unlock()
+
+	sort.Slice(statuses, func(a, b int) bool {
+		return strings.Join(statuses[a].Key, ".") < strings.Join(statuses[b].Key, ".")
+	})
+	return statuses
+}
+
 // NamespaceLabel creates a metrics label for the given
 // Namespace: root is "root"; others are path with the
 // final '/' removed.
@@ -1,10 +1,12 @@
 package metricsutil
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/namespace"
 )
 
 func isLabelPresent(toFind Label, ls []Label) bool {
@@ -109,3 +111,65 @@ func TestClusterLabelPresent(t *testing.T) {
 	}
 
 }
+
+func TestNamespaceLabelsFromContext(t *testing.T) {
+	inmemSink := metrics.NewInmemSink(
+		1000000*time.Hour,
+		2000000*time.Hour)
+	clusterSink := NewClusterMetricSink("test-cluster", defaultMetrics(inmemSink))
+
+	rootCtx := namespace.RootContext(context.Background())
+	childCtx := namespace.ContextWithNamespace(context.Background(), &namespace.Namespace{
+		ID:   "abc",
+		Path: "team1/",
+	})
+	key := []string{"aaa", "bbb"}
+	labels := []Label{{"dim1", "val1"}}
+
+	// Disabled: no label added
+	clusterSink.IncrCounterWithLabelsContext(rootCtx, key, 1.0, labels)
+
+	clusterSink.NamespaceLabels = true
+	clusterSink.IncrCounterWithLabelsContext(childCtx, key, 1.0, labels)
+	clusterSink.SetGaugeWithLabelsContext(rootCtx, key, 1.0, labels)
+
+	// Not on allowlist, whether from context or explicit
+	clusterSink.NamespaceAllowlist = []string{"root"}
+	clusterSink.IncrCounterWithLabelsContext(childCtx, key, 1.0, labels)
+	clusterSink.IncrCounterWithLabelsContext(rootCtx, key, 1.0,
+		[]Label{{"dim1", "val1"}, {"namespace", "team2"}})
+
+	if len(labels) != 1 {
+		t.Errorf("Labels argument was modified: %v", labels)
+	}
+
+	intervals := inmemSink.Data()
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	expectedCounters := map[string]float64{
+		"aaa.bbb;dim1=val1;cluster=test-cluster":                 1.0,
+		"aaa.bbb;dim1=val1;namespace=team1;cluster=test-cluster": 1.0,
+		"aaa.bbb;dim1=val1;namespace=other;cluster=test-cluster": 2.0,
+	}
+	if len(intervals[0].Counters) != len(expectedCounters) {
+		t.Errorf("Found %v counters, expected %v: %v",
+			len(intervals[0].Counters), len(expectedCounters), intervals[0].Counters)
+	}
+	for k, v := range expectedCounters {
+		c, ok := intervals[0].Counters[k]
+		if !ok {
+			t.Errorf("Key %v not found in map %v", k, intervals[0].Counters)
+			continue
+		}
+		if c.Sum != v {
+			t.Errorf("Counter %v value %v does not match %v", k, c.Sum, v)
+		}
+	}
+
+	gaugeKey := "aaa.bbb;dim1=val1;namespace=root;cluster=test-cluster"
+	if _, ok := intervals[0].Gauges[gaugeKey]; !ok {
+		t.Errorf("Key %v not found in map %v", gaugeKey, intervals[0].Gauges)
+	}
+}
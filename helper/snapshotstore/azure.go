@@ -0,0 +1,133 @@
+package snapshotstore
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest/azure"
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+)
+
+// azureBlockSize is the size of the blocks snapshots are uploaded in
+const azureBlockSize = 4 * 1024 * 1024
+
+// AzureConfig configures a store of snapshots in an Azure Blob Storage
+// container
+type AzureConfig struct {
+	ContainerName string
+	AccountName   string
+	AccountKey    string
+
+	// Environment is the name of the Azure cloud, AzurePublicCloud if unset
+	Environment string
+}
+
+// AzureStore stores snapshots as block blobs of an Azure Blob Storage
+// container
+type AzureStore struct {
+	container *storage.Container
+	prefix    string
+}
+
+var _ Store = (*AzureStore)(nil)
+
+// NewAzureStore returns a store of snapshots in the container, under the
+// given path prefix
+func NewAzureStore(config *AzureConfig, pathPrefix string) (*AzureStore, error) {
+	switch {
+	case config.ContainerName == "":
+		return nil, errors.New("azure_container_name is required")
+	case config.AccountName == "":
+		return nil, errors.New("azure_account_name is required")
+	case config.AccountKey == "":
+		return nil, errors.New("azure_account_key is required")
+	}
+
+	environmentName := config.Environment
+	if environmentName == "" {
+		environmentName = "AzurePublicCloud"
+	}
+	environment, err := azure.EnvironmentFromName(environmentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up Azure environment descriptor for name %q: %w", environmentName, err)
+	}
+
+	client, err := storage.NewBasicClientOnSovereignCloud(config.AccountName, config.AccountKey, environment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+	client.HTTPClient = cleanhttp.DefaultPooledClient()
+
+	blobClient := client.GetBlobService()
+	return &AzureStore{
+		container: blobClient.GetContainerReference(config.ContainerName),
+		prefix:    pathPrefix,
+	}, nil
+}
+
+// Put uploads the snapshot block by block, then commits the blocks
+func (s *AzureStore) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	blob := s.container.GetBlobReference(s.prefix + name)
+
+	var blocks []storage.Block
+	chunk := make([]byte, azureBlockSize)
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", i)))
+			if err := blob.PutBlock(id, chunk[:n], nil); err != nil {
+				return err
+			}
+			blocks = append(blocks, storage.Block{
+				ID:     id,
+				Status: storage.BlockStatusLatest,
+			})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return blob.PutBlockList(blocks, nil)
+}
+
+func (s *AzureStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	params := storage.ListBlobsParameters{
+		Prefix: s.prefix + prefix,
+	}
+	for {
+		resp, err := s.container.ListBlobs(params)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range resp.Blobs {
+			name := strings.TrimPrefix(blob.Name, s.prefix)
+			if !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if resp.NextMarker == "" {
+			break
+		}
+		params.Marker = resp.NextMarker
+	}
+	return names, nil
+}
+
+func (s *AzureStore) Delete(ctx context.Context, name string) error {
+	_, err := s.container.GetBlobReference(s.prefix + name).DeleteIfExists(nil)
+	return err
+}
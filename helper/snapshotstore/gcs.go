@@ -0,0 +1,101 @@
+package snapshotstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/hashicorp/vault/sdk/helper/useragent"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// GCSConfig configures a store of snapshots in a Google Cloud Storage bucket.
+// Without a service account key, the application default credentials are
+// used.
+type GCSConfig struct {
+	Bucket            string
+	ServiceAccountKey string
+}
+
+// GCSStore stores snapshots as objects of a Google Cloud Storage bucket
+type GCSStore struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+var _ Store = (*GCSStore)(nil)
+
+// NewGCSStore returns a store of snapshots in the bucket, under the given
+// path prefix
+func NewGCSStore(ctx context.Context, config *GCSConfig, pathPrefix string) (*GCSStore, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("google_gcs_bucket is required")
+	}
+
+	opts := []option.ClientOption{option.WithUserAgent(useragent.String())}
+	if config.ServiceAccountKey != "" {
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.ServiceAccountKey)))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GCSStore{
+		client: client,
+		bucket: config.Bucket,
+		prefix: pathPrefix,
+	}, nil
+}
+
+func (s *GCSStore) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := s.client.Bucket(s.bucket).Object(s.prefix + name).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		// Cancelling the context aborts the upload
+		cancel()
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	it := s.client.Bucket(s.bucket).Objects(ctx, &storage.Query{
+		Prefix: s.prefix + prefix,
+	})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(attrs.Name, s.prefix)
+		if !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (s *GCSStore) Delete(ctx context.Context, name string) error {
+	err := s.client.Bucket(s.bucket).Object(s.prefix + name).Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	return err
+}
+
+// Close releases the client of the store
+func (s *GCSStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,115 @@
+package snapshotstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrLocalMaxSpace is returned when storing a snapshot would make local
+// snapshots use more space than allowed
+var ErrLocalMaxSpace = errors.New("storing the snapshot would exceed the maximum space allowed for snapshots")
+
+// LocalStore stores snapshots in a directory of the local filesystem
+type LocalStore struct {
+	dir      string
+	maxSpace int64
+}
+
+var _ Store = (*LocalStore)(nil)
+
+// NewLocalStore returns a store of snapshots in the given directory, which is
+// created if missing. If maxSpace is positive, the snapshots in the directory
+// may not use more than maxSpace bytes in total.
+func NewLocalStore(dir string, maxSpace int64) (*LocalStore, error) {
+	if dir == "" {
+		return nil, errors.New("path_prefix is required for local storage")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return &LocalStore{
+		dir:      dir,
+		maxSpace: maxSpace,
+	}, nil
+}
+
+// Put writes the snapshot to a temporary file, renamed once complete
+func (s *LocalStore) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	if s.maxSpace > 0 {
+		used, err := s.usedSpace()
+		if err != nil {
+			return err
+		}
+		if used+size > s.maxSpace {
+			return ErrLocalMaxSpace
+		}
+	}
+
+	f, err := ioutil.TempFile(s.dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(s.dir, name))
+}
+
+// List returns the names of the snapshots in the directory
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasPrefix(info.Name(), prefix) {
+			continue
+		}
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, name string) error {
+	err := os.Remove(filepath.Join(s.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// usedSpace returns the size of the snapshots in the directory
+func (s *LocalStore) usedSpace() (int64, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, info := range infos {
+		if !info.IsDir() {
+			used += info.Size()
+		}
+	}
+	return used, nil
+}
@@ -0,0 +1,79 @@
+package snapshotstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s, err := NewLocalStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"vault-2", "vault-1", "other-1"} {
+		if err := s.Put(ctx, name, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := s.List(ctx, "vault-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"vault-1", "vault-2"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, names)
+	}
+
+	if err := s.Delete(ctx, "vault-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "vault-1"); err != nil {
+		t.Fatalf("deleting a missing snapshot should succeed: %v", err)
+	}
+	names, err = s.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"other-1", "vault-2"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: expected %v, got %v", expected, names)
+	}
+}
+
+func TestLocalStore_MaxSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshotstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	s, err := NewLocalStore(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "vault-1", strings.NewReader("123456"), 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "vault-2", strings.NewReader("123456"), 6); err != ErrLocalMaxSpace {
+		t.Fatalf("expected %v, got %v", ErrLocalMaxSpace, err)
+	}
+	if err := s.Delete(ctx, "vault-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "vault-2", strings.NewReader("123456"), 6); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+package snapshotstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/sdk/helper/awsutil"
+)
+
+// S3Config configures a store of snapshots in an AWS S3 bucket. Credentials
+// not given are looked up in the usual places of the AWS SDK.
+type S3Config struct {
+	Bucket          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+	DisableTLS      bool
+	ForcePathStyle  bool
+}
+
+// S3Store stores snapshots as objects of an AWS S3 bucket
+type S3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+var _ Store = (*S3Store)(nil)
+
+// NewS3Store returns a store of snapshots in the bucket, under the given
+// path prefix
+func NewS3Store(config *S3Config, pathPrefix string) (*S3Store, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("aws_s3_bucket is required")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey: config.AccessKeyID,
+		SecretKey: config.SecretAccessKey,
+	}
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials:      creds,
+		HTTPClient:       cleanhttp.DefaultPooledClient(),
+		Endpoint:         aws.String(config.Endpoint),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(config.ForcePathStyle),
+		DisableSSL:       aws.Bool(config.DisableTLS),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{
+		client: s3.New(sess),
+		bucket: config.Bucket,
+		prefix: pathPrefix,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(s.prefix + name),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+	})
+	return err
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix + prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(object.Key), s.prefix)
+			if !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	return err
+}
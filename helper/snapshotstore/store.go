@@ -0,0 +1,23 @@
+// Package snapshotstore stores the snapshots taken automatically of the raft
+// storage, on the local filesystem or in cloud object storage.
+package snapshotstore
+
+import (
+	"context"
+	"io"
+)
+
+// Store is where snapshots are uploaded to. Snapshots are named by their
+// caller; names must not contain path separators.
+type Store interface {
+	// Put stores a snapshot of the given size under the given name. The
+	// snapshot may be read again from the start if the upload is retried.
+	Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error
+
+	// List returns the names of the stored snapshots starting with the given
+	// prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Delete removes the stored snapshot with the given name
+	Delete(ctx context.Context, name string) error
+}
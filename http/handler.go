@@ -8,12 +8,14 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"mime"
 	"net"
 	"net/http"
 	"net/textproto"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -125,15 +127,21 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 		mux.Handle("/v1/sys/config/state/", handleLogicalNoForward(core))
 		mux.Handle("/v1/sys/host-info", handleLogicalNoForward(core))
 		mux.Handle("/v1/sys/pprof/", handleLogicalNoForward(core))
+		mux.Handle("/v1/sys/loggers", handleLogicalNoForward(core))
+		mux.Handle("/v1/sys/loggers/", handleLogicalNoForward(core))
 
 		mux.Handle("/v1/sys/init", handleSysInit(core))
 		mux.Handle("/v1/sys/seal-status", handleSysSealStatus(core))
+		mux.Handle("/v1/sys/seal-backend-status", handleSysSealBackendStatus(core))
 		mux.Handle("/v1/sys/seal", handleSysSeal(core))
 		mux.Handle("/v1/sys/step-down", handleRequestForwarding(core, handleSysStepDown(core)))
 		mux.Handle("/v1/sys/unseal", handleSysUnseal(core))
 		mux.Handle("/v1/sys/leader", handleSysLeader(core))
 		mux.Handle("/v1/sys/health", handleSysHealth(core))
 		mux.Handle("/v1/sys/monitor", handleLogicalNoForward(core))
+		mux.Handle("/v1/sys/monitor/profiles/", handleMonitorProfiles(core))
+		mux.Handle("/v1/sys/metrics/stream", handleMetricsStream(core))
+		mux.Handle("/v1/sys/events/subscribe/", handleEventsSubscribe(core))
 		mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core,
 			handleAuditNonLogical(core, handleSysGenerateRootAttempt(core, vault.GenerateStandardRootTokenStrategy))))
 		mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core,
@@ -275,8 +283,10 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 		// Start with the request context
 		ctx := r.Context()
 		var cancelFunc context.CancelFunc
-		// Add our timeout, but not for the monitor endpoint, as it's streaming
-		if strings.HasSuffix(r.URL.Path, "sys/monitor") {
+		// Add our timeout, but not for the monitor, metrics stream and
+		// events endpoints, as they're streaming
+		if strings.HasSuffix(r.URL.Path, "sys/monitor") || strings.HasSuffix(r.URL.Path, "sys/metrics/stream") ||
+			strings.HasPrefix(r.URL.Path, "/v1/sys/events/subscribe/") {
 			ctx, cancelFunc = context.WithCancel(ctx)
 		} else {
 			ctx, cancelFunc = context.WithTimeout(ctx, maxRequestDuration)
@@ -660,6 +670,13 @@ func parseFormRequest(r *http.Request) (map[string]interface{}, error) {
 // falling back on the older behavior of redirecting the client
 func handleRequestForwarding(core *vault.Core, handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// If the node is stepping down or sealing, hold the request until
+		// it can be served or forwarded again
+		if ok, retryAfter := core.WaitForDrain(r.Context()); !ok {
+			respondDraining(w, retryAfter)
+			return
+		}
+
 		// If we are a performance standby we can handle the request.
 		if core.PerfStandby() {
 			ns, err := namespace.FromContext(r.Context())
@@ -869,6 +886,17 @@ func respondStandby(core *vault.Core, w http.ResponseWriter, reqURL *url.URL) {
 	w.WriteHeader(307)
 }
 
+// respondDraining is used to fail a request which was held while the node
+// was draining, hinting the client at when to retry
+func respondDraining(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	respondError(w, http.StatusServiceUnavailable, errors.New("node is stepping down or sealing, retry later"))
+}
+
 // getTokenFromReq parse headers of the incoming request to extract token if
 // present it accepts Authorization Bearer (RFC6750) and X-Vault-Token header.
 // Returns true if the token was sourced from a Bearer header.
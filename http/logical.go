@@ -57,6 +57,15 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 	case "DELETE":
 		op = logical.DeleteOperation
 		data = parseQuery(r.URL.Query())
+	case "HEAD":
+		// HEAD is only served for the ACME nonce endpoint of PKI mounts, as
+		// reading other paths may have side effects, such as issuing
+		// credentials, whose response would be thrown away
+		if !strings.HasSuffix(path, "/acme/new-nonce") {
+			return nil, nil, http.StatusMethodNotAllowed, nil
+		}
+		op = logical.ReadOperation
+		data = parseQuery(r.URL.Query())
 	case "GET":
 		op = logical.ReadOperation
 		queryVals := r.URL.Query()
@@ -81,7 +90,7 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		}
 
 		switch {
-		case strings.HasPrefix(path, "sys/pprof/"):
+		case strings.HasPrefix(path, "sys/pprof/"), strings.HasPrefix(path, "sys/monitor/profiles/"):
 			passHTTPReq = true
 			responseWriter = w
 		case path == "sys/storage/raft/snapshot":
@@ -387,12 +396,54 @@ func handleLogicalInternal(core *vault.Core, injectDataIntoTopLevel bool, noForw
 			}
 		}
 
+		// Batches, recursive deletes and diffs of versions on a KV version 2
+		// mount, and imports of secrets to KV mounts, are split into a
+		// request per item here, rather than being handled by a backend.
+		// Batches are served whatever their operation, so that unsupported
+		// ones are rejected rather than passed on to the backend.
+		var batchMount, batchDeleteMount, diffMount string
+		if strings.HasSuffix(req.Path, "/batch") {
+			batchMount = core.KVv2BatchMount(r.Context(), req.Path)
+		}
+		switch req.Operation {
+		case logical.UpdateOperation:
+			switch {
+			case strings.HasSuffix(req.Path, "/batch-delete"):
+				batchDeleteMount = core.KVv2BatchDeleteMount(r.Context(), req.Path)
+			}
+		case logical.ReadOperation:
+			if strings.Contains(req.Path, "/diff/") {
+				diffMount = core.KVv2DiffMount(r.Context(), req.Path)
+			}
+		}
+
 		// Make the internal request. We attach the connection info
 		// as well in case this is an authentication request that requires
 		// it. Vault core handles stripping this if we need to. This also
 		// handles all error cases; if we hit respondLogical, the request is a
 		// success.
-		resp, ok, needsForward := request(core, w, r, req)
+		var resp *logical.Response
+		var ok, needsForward bool
+		switch {
+		case batchMount != "":
+			resp, ok, needsForward = requestEnvelope(core, w, r, req, func(w http.ResponseWriter, r *http.Request) (*logical.Response, bool, bool) {
+				return requestKVBatch(core, w, r, req, batchMount)
+			})
+		case batchDeleteMount != "":
+			resp, ok, needsForward = requestEnvelope(core, w, r, req, func(w http.ResponseWriter, r *http.Request) (*logical.Response, bool, bool) {
+				return requestKVBatchDelete(core, w, r, req, batchDeleteMount)
+			})
+		case diffMount != "":
+			resp, ok, needsForward = requestEnvelope(core, w, r, req, func(w http.ResponseWriter, r *http.Request) (*logical.Response, bool, bool) {
+				return requestKVDiff(core, w, r, req, diffMount)
+			})
+		case req.Operation == logical.UpdateOperation && req.Path == "sys/import":
+			resp, ok, needsForward = requestEnvelope(core, w, r, req, func(w http.ResponseWriter, r *http.Request) (*logical.Response, bool, bool) {
+				return requestImport(core, w, r, req)
+			})
+		default:
+			resp, ok, needsForward = request(core, w, r, req)
+		}
 		switch {
 		case needsForward && noForward:
 			respondError(w, http.StatusBadRequest, vault.ErrCannotForwardLocalOnly)
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// maxKVBatchItems is the maximum number of secrets that can be read or
+// written in a single batch request.
+const maxKVBatchItems = 128
+
+const (
+	// kvBatchOpRead reads a version of the secret, the latest one if no
+	// version is given.
+	kvBatchOpRead = "read"
+
+	// kvBatchOpWrite writes a new version of the secret.
+	kvBatchOpWrite = "write"
+)
+
+type kvBatchItem struct {
+	Operation string                 `mapstructure:"operation"`
+	Path      string                 `mapstructure:"path"`
+	Version   int                    `mapstructure:"version"`
+	Data      map[string]interface{} `mapstructure:"data"`
+	CAS       *int                   `mapstructure:"cas"`
+}
+
+// requestKVBatch reads or writes each of the requested secrets of the KV
+// version 2 mount with a separate request on behalf of the caller, so that
+// each one is subject to the caller's ACL and is audited individually. The
+// status of each item is reported alongside its data; the batch itself only
+// fails if it is malformed. The return values are the same as for request.
+func requestKVBatch(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request, mountPath string) (*logical.Response, bool, bool) {
+	if req.Operation != logical.UpdateOperation && req.Operation != logical.CreateOperation {
+		respondError(w, http.StatusMethodNotAllowed, fmt.Errorf("batch requests must be made with POST or PUT"))
+		return nil, false, false
+	}
+	if req.WrapInfo != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("response wrapping is not supported for batch requests"))
+		return nil, false, false
+	}
+
+	var items []kvBatchItem
+	if err := mapstructure.WeakDecode(req.Data["items"], &items); err != nil {
+		respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to parse items: {{err}}", err))
+		return nil, false, false
+	}
+	switch {
+	case len(items) == 0:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("no items given"))
+		return nil, false, false
+	case len(items) > maxKVBatchItems:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("at most %d items may be given in a batch", maxKVBatchItems))
+		return nil, false, false
+	}
+
+	results := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		itemPath := strings.TrimPrefix(item.Path, "/")
+		result := map[string]interface{}{
+			"path": itemPath,
+		}
+		results = append(results, result)
+
+		fail := func(err string) {
+			result["status"] = http.StatusBadRequest
+			result["errors"] = []string{err}
+		}
+
+		var itemReq *logical.Request
+		var err error
+		switch {
+		case itemPath == "":
+			fail("missing path")
+			continue
+		case item.Operation == "" || item.Operation == kvBatchOpRead:
+			itemReq, err = kvBatchItemRequest(req, mountPath, itemPath, item.Version)
+		case item.Operation != kvBatchOpWrite:
+			fail(fmt.Sprintf("unsupported operation %q, must be %q or %q", item.Operation, kvBatchOpRead, kvBatchOpWrite))
+			continue
+		case len(item.Data) == 0:
+			fail("missing data")
+			continue
+		case item.Version != 0:
+			fail("version cannot be given when writing, use cas instead")
+			continue
+		default:
+			itemReq, err = kvBatchWriteRequest(req, mountPath, itemPath, item.Data, item.CAS)
+		}
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return nil, false, false
+		}
+
+		resp, err := core.HandleRequest(r.Context(), itemReq)
+		switch {
+		case errwrap.Contains(err, consts.ErrStandby.Error()):
+			respondStandby(core, w, r.URL)
+			return nil, false, false
+		case err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()):
+			return nil, false, true
+		}
+
+		status, err := logical.RespondErrorCommon(itemReq, resp, err)
+		if status == 0 {
+			status = http.StatusOK
+		}
+		result["status"] = status
+		switch {
+		case err != nil:
+			result["errors"] = []string{err.Error()}
+		case itemReq.Operation != logical.ReadOperation:
+			// Writes return the metadata of the version written
+			if resp != nil {
+				result["metadata"] = resp.Data
+			}
+		case status == http.StatusOK:
+			result["data"] = resp.Data["data"]
+			result["metadata"] = resp.Data["metadata"]
+		case resp != nil && resp.Data["metadata"] != nil:
+			// Deleted and destroyed versions still report their metadata
+			result["metadata"] = resp.Data["metadata"]
+		}
+		if resp != nil && len(resp.Warnings) > 0 {
+			result["warnings"] = resp.Warnings
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"items": results,
+		},
+	}, true, false
+}
+
+// kvBatchItemRequest builds the read request for a single batch item,
+// carrying over the caller's token and connection.
+func kvBatchItemRequest(req *logical.Request, mountPath, itemPath string, version int) (*logical.Request, error) {
+	var data map[string]interface{}
+	if version > 0 {
+		data = map[string]interface{}{
+			"version": strconv.Itoa(version),
+		}
+	}
+	return kvBatchSubRequest(req, logical.ReadOperation, mountPath+"data/"+itemPath, data)
+}
+
+// kvBatchWriteRequest builds the write request for a single batch item,
+// carrying over the caller's token and connection.
+func kvBatchWriteRequest(req *logical.Request, mountPath, itemPath string, data map[string]interface{}, cas *int) (*logical.Request, error) {
+	payload := map[string]interface{}{
+		"data": data,
+	}
+	if cas != nil {
+		payload["options"] = map[string]interface{}{
+			"cas": *cas,
+		}
+	}
+	return kvBatchSubRequest(req, logical.UpdateOperation, mountPath+"data/"+itemPath, payload)
+}
+
+// kvBatchSubRequest builds a request made on behalf of the caller of a
+// batch endpoint, carrying over their token and connection.
+func kvBatchSubRequest(req *logical.Request, op logical.Operation, path string, data map[string]interface{}) (*logical.Request, error) {
+	requestID, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err)
+	}
+
+	return &logical.Request{
+		ID:                  requestID,
+		Operation:           op,
+		Path:                path,
+		Data:                data,
+		Connection:          req.Connection,
+		Headers:             req.Headers,
+		ClientToken:         req.ClientToken,
+		ClientTokenAccessor: req.ClientTokenAccessor,
+		ClientTokenSource:   req.ClientTokenSource,
+		PolicyOverride:      req.PolicyOverride,
+		MFACreds:            req.MFACreds,
+	}, nil
+}
@@ -0,0 +1,283 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// maxKVBatchDeleteKeys is the maximum number of secrets that a single
+// recursive delete may touch.
+const maxKVBatchDeleteKeys = 1024
+
+const (
+	// kvBatchDeleteOpDelete soft deletes versions of each secret, the
+	// latest one if no versions are given.
+	kvBatchDeleteOpDelete = "delete"
+
+	// kvBatchDeleteOpUndelete restores soft deleted versions of each
+	// secret, the latest one if no versions are given.
+	kvBatchDeleteOpUndelete = "undelete"
+
+	// kvBatchDeleteOpDestroy permanently removes versions of each secret,
+	// the latest one if no versions are given.
+	kvBatchDeleteOpDestroy = "destroy"
+
+	// kvBatchDeleteOpDeleteMetadata removes each secret with its metadata
+	// and all of its versions.
+	kvBatchDeleteOpDeleteMetadata = "delete-metadata"
+)
+
+// errKVBatchAbort is returned while walking a recursive delete when the
+// request has to be answered by another node.
+var errKVBatchAbort = errors.New("batch aborted")
+
+type kvBatchDeleteInput struct {
+	Path      string `mapstructure:"path"`
+	Operation string `mapstructure:"operation"`
+	Versions  []int  `mapstructure:"versions"`
+	DryRun    bool   `mapstructure:"dry_run"`
+}
+
+// kvBatchDelete is the state of a single recursive delete.
+type kvBatchDelete struct {
+	core      *vault.Core
+	r         *http.Request
+	req       *logical.Request
+	mountPath string
+
+	paths   []string
+	results []map[string]interface{}
+
+	standby bool
+	forward bool
+}
+
+// requestKVBatchDelete lists the secrets under a path of the KV version 2
+// mount, and deletes, undeletes or destroys each of them with a separate
+// request on behalf of the caller, so that both the listing and each
+// operation are subject to the caller's ACL and are audited individually.
+// A dry run only reports the secrets that would be affected. The return
+// values are the same as for request.
+func requestKVBatchDelete(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request, mountPath string) (*logical.Response, bool, bool) {
+	if req.WrapInfo != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("response wrapping is not supported for batch requests"))
+		return nil, false, false
+	}
+
+	var input kvBatchDeleteInput
+	if err := mapstructure.WeakDecode(req.Data, &input); err != nil {
+		respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to parse request: {{err}}", err))
+		return nil, false, false
+	}
+
+	switch input.Operation {
+	case kvBatchDeleteOpDelete, kvBatchDeleteOpUndelete, kvBatchDeleteOpDestroy:
+	case kvBatchDeleteOpDeleteMetadata:
+		if len(input.Versions) > 0 {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("versions cannot be given for %q", input.Operation))
+			return nil, false, false
+		}
+	case "":
+		respondError(w, http.StatusBadRequest, fmt.Errorf("missing operation"))
+		return nil, false, false
+	default:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("unknown operation %q", input.Operation))
+		return nil, false, false
+	}
+
+	// The path is always treated as a folder, so that "apps" and "apps/"
+	// both refer to the secrets under apps/ and not to a secret named apps
+	prefix := strings.Trim(input.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	d := &kvBatchDelete{
+		core:      core,
+		r:         r,
+		req:       req,
+		mountPath: mountPath,
+	}
+
+	err := d.walk(prefix)
+	switch {
+	case d.standby:
+		respondStandby(core, w, r.URL)
+		return nil, false, false
+	case d.forward:
+		return nil, false, true
+	case err != nil:
+		respondError(w, http.StatusBadRequest, err)
+		return nil, false, false
+	}
+
+	if !input.DryRun {
+		for _, path := range d.paths {
+			if err := d.apply(path, input.Operation, input.Versions); err != nil {
+				switch {
+				case d.standby:
+					respondStandby(core, w, r.URL)
+					return nil, false, false
+				case d.forward:
+					return nil, false, true
+				default:
+					respondError(w, http.StatusInternalServerError, err)
+					return nil, false, false
+				}
+			}
+		}
+	} else {
+		for _, path := range d.paths {
+			d.results = append(d.results, map[string]interface{}{
+				"path": path,
+			})
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run":   input.DryRun,
+			"operation": input.Operation,
+			"items":     d.results,
+		},
+	}, true, false
+}
+
+// walk collects the paths of the secrets under the given folder. Folders
+// which cannot be listed are reported as failed items rather than failing
+// the whole request.
+func (d *kvBatchDelete) walk(prefix string) error {
+	resp, status, err := d.handle(logical.ListOperation, "metadata/"+prefix, nil)
+	switch {
+	case err == errKVBatchAbort:
+		return err
+	case status == http.StatusNotFound:
+		return nil
+	case err != nil:
+		d.results = append(d.results, map[string]interface{}{
+			"path":   prefix,
+			"status": status,
+			"errors": []string{err.Error()},
+		})
+		return nil
+	}
+
+	var keys []string
+	if resp != nil {
+		if err := mapstructure.WeakDecode(resp.Data["keys"], &keys); err != nil {
+			return errwrap.Wrapf("failed to parse keys: {{err}}", err)
+		}
+	}
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			if err := d.walk(prefix + key); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(d.paths) == maxKVBatchDeleteKeys {
+			return fmt.Errorf("more than %d secrets found, use a more specific path", maxKVBatchDeleteKeys)
+		}
+		d.paths = append(d.paths, prefix+key)
+	}
+
+	return nil
+}
+
+// apply runs the operation on a single secret, and records its result.
+func (d *kvBatchDelete) apply(path, operation string, versions []int) error {
+	result := map[string]interface{}{
+		"path": path,
+	}
+	d.results = append(d.results, result)
+
+	// Undelete and destroy act on the latest version unless told otherwise
+	if len(versions) == 0 && (operation == kvBatchDeleteOpUndelete || operation == kvBatchDeleteOpDestroy) {
+		resp, status, err := d.handle(logical.ReadOperation, "metadata/"+path, nil)
+		switch {
+		case err == errKVBatchAbort:
+			return err
+		case err != nil:
+			result["status"] = status
+			result["errors"] = []string{err.Error()}
+			return nil
+		}
+
+		var current int
+		if err := mapstructure.WeakDecode(resp.Data["current_version"], &current); err != nil {
+			return errwrap.Wrapf("failed to parse current version: {{err}}", err)
+		}
+		versions = []int{current}
+	}
+
+	var op logical.Operation
+	var subPath string
+	var data map[string]interface{}
+	switch {
+	case operation == kvBatchDeleteOpDeleteMetadata:
+		op, subPath = logical.DeleteOperation, "metadata/"+path
+	case operation == kvBatchDeleteOpDelete && len(versions) == 0:
+		op, subPath = logical.DeleteOperation, "data/"+path
+	default:
+		op, subPath = logical.UpdateOperation, operation+"/"+path
+		data = map[string]interface{}{
+			"versions": versions,
+		}
+	}
+
+	resp, status, err := d.handle(op, subPath, data)
+	if err == errKVBatchAbort {
+		return err
+	}
+	result["status"] = status
+	if err != nil {
+		result["errors"] = []string{err.Error()}
+	}
+	if resp != nil && len(resp.Warnings) > 0 {
+		result["warnings"] = resp.Warnings
+	}
+	return nil
+}
+
+// handle makes a request relative to the mount on behalf of the caller,
+// and returns its response along with the HTTP status it would have been
+// answered with. If the request has to be answered by another node,
+// errKVBatchAbort is returned and the node is recorded.
+func (d *kvBatchDelete) handle(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, int, error) {
+	subReq, err := kvBatchSubRequest(d.req, op, d.mountPath+path, data)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	resp, err := d.core.HandleRequest(d.r.Context(), subReq)
+	switch {
+	case errwrap.Contains(err, consts.ErrStandby.Error()):
+		d.standby = true
+		return nil, 0, errKVBatchAbort
+	case err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()):
+		d.forward = true
+		return nil, 0, errKVBatchAbort
+	}
+
+	status, err := logical.RespondErrorCommon(subReq, resp, err)
+	if status == 0 {
+		status = http.StatusOK
+		if resp == nil {
+			status = http.StatusNoContent
+		}
+	}
+	if err == nil && status >= http.StatusBadRequest {
+		err = errors.New(http.StatusText(status))
+	}
+	return resp, status, err
+}
@@ -0,0 +1,531 @@
+package http
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestLogical_KVBatch(t *testing.T) {
+	var noop *vault.NoopAudit
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				noop = &vault.NoopAudit{
+					Config: config,
+				}
+				return noop, nil
+			},
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the upgrade code to run/finish
+	time.Sleep(time.Second)
+
+	for _, w := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"kv/data/a", map[string]interface{}{"foo": "v1"}},
+		{"kv/data/a", map[string]interface{}{"foo": "v2"}},
+		{"kv/data/b", map[string]interface{}{"bar": "baz"}},
+	} {
+		if _, err := client.Logical().Write(w.path, map[string]interface{}{"data": w.data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batch := func(client *api.Client, items ...map[string]interface{}) []map[string]interface{} {
+		t.Helper()
+		secret, err := client.Logical().Write("kv/batch", map[string]interface{}{
+			"items": items,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil {
+			t.Fatal("nil secret")
+		}
+
+		raw := secret.Data["items"].([]interface{})
+		results := make([]map[string]interface{}, len(raw))
+		for i, r := range raw {
+			results[i] = r.(map[string]interface{})
+		}
+		return results
+	}
+
+	audited := len(noop.Req)
+	results := batch(client,
+		map[string]interface{}{"path": "a"},
+		map[string]interface{}{"path": "a", "version": 1},
+		map[string]interface{}{"path": "b"},
+		map[string]interface{}{"path": "c"},
+	)
+	if len(results) != 4 {
+		t.Fatalf("bad: %#v", results)
+	}
+	for i, expected := range []struct {
+		status string
+		value  interface{}
+	}{
+		{"200", "v2"},
+		{"200", "v1"},
+		{"200", nil},
+		{"404", nil},
+	} {
+		if status := results[i]["status"].(interface{ String() string }).String(); status != expected.status {
+			t.Fatalf("item %d: expected status %s, got %s", i, expected.status, status)
+		}
+		if expected.value == nil {
+			continue
+		}
+		if v := results[i]["data"].(map[string]interface{})["foo"]; v != expected.value {
+			t.Fatalf("item %d: expected %v, got %v", i, expected.value, v)
+		}
+	}
+	if v := results[2]["data"].(map[string]interface{})["bar"]; v != "baz" {
+		t.Fatalf("bad: %#v", results[2])
+	}
+
+	// The batch itself is audited, along with each item
+	var paths []string
+	for _, req := range noop.Req[audited:] {
+		paths = append(paths, req.Path)
+	}
+	if len(paths) != 5 || paths[0] != "kv/batch" {
+		t.Fatalf("bad audited requests: %v", paths)
+	}
+
+	// Secrets are written with the write operation, and other operations
+	// are rejected per item
+	results = batch(client,
+		map[string]interface{}{"operation": "write", "path": "b", "data": map[string]interface{}{"bar": "qux"}},
+		map[string]interface{}{"operation": "write", "path": "c", "data": map[string]interface{}{"baz": "zip"}, "cas": 0},
+		map[string]interface{}{"operation": "write", "path": "c", "data": map[string]interface{}{"baz": "zap"}, "cas": 0},
+		map[string]interface{}{"operation": "write", "path": "d"},
+		map[string]interface{}{"operation": "delete", "path": "a"},
+		map[string]interface{}{"path": "c"},
+	)
+	for i, expected := range []string{"200", "200", "400", "400", "400", "200"} {
+		if status := results[i]["status"].(interface{ String() string }).String(); status != expected {
+			t.Fatalf("item %d: expected status %s, got %#v", i, expected, results[i])
+		}
+	}
+	if v := results[0]["metadata"].(map[string]interface{})["version"].(interface{ String() string }).String(); v != "2" {
+		t.Fatalf("bad: %#v", results[0])
+	}
+	if !strings.Contains(results[4]["errors"].([]interface{})[0].(string), `unsupported operation "delete"`) {
+		t.Fatalf("bad: %#v", results[4])
+	}
+	if v := results[5]["data"].(map[string]interface{})["baz"]; v != "zip" {
+		t.Fatalf("bad: %#v", results[5])
+	}
+
+	// Batches are only made with updates
+	if _, err := client.Logical().Read("kv/batch"); err == nil || !strings.Contains(err.Error(), "Code: 405") {
+		t.Fatalf("expected method not allowed, got: %v", err)
+	}
+
+	// The batch and each item are checked against the caller's policies
+	policy := `
+path "kv/batch" { capabilities = ["update"] }
+path "kv/data/a" { capabilities = ["read"] }
+`
+	if err := client.Sys().PutPolicy("batch", policy); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"batch"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited.SetToken(secret.Auth.ClientToken)
+
+	results = batch(limited,
+		map[string]interface{}{"path": "a"},
+		map[string]interface{}{"path": "b"},
+	)
+	if status := results[0]["status"].(interface{ String() string }).String(); status != "200" {
+		t.Fatalf("bad: %#v", results[0])
+	}
+	if status := results[1]["status"].(interface{ String() string }).String(); status != "403" {
+		t.Fatalf("bad: %#v", results[1])
+	}
+	if results[1]["data"] != nil {
+		t.Fatalf("expected no data for denied item: %#v", results[1])
+	}
+
+	if err := client.Sys().PutPolicy("batch", `path "kv/data/a" { capabilities = ["read"] }`); err != nil {
+		t.Fatal(err)
+	}
+	_, err = limited.Logical().Write("kv/batch", map[string]interface{}{
+		"items": []map[string]interface{}{{"path": "a"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Malformed batches are rejected outright
+	if _, err := client.Logical().Write("kv/batch", map[string]interface{}{}); err == nil {
+		t.Fatal("expected error for empty batch")
+	}
+}
+
+func TestLogical_KVBatchDelete(t *testing.T) {
+	var noop *vault.NoopAudit
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				noop = &vault.NoopAudit{
+					Config: config,
+				}
+				return noop, nil
+			},
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the upgrade code to run/finish
+	time.Sleep(time.Second)
+
+	for _, path := range []string{"apps/a", "apps/b/c", "apps/b/d", "other"} {
+		data := map[string]interface{}{
+			"data": map[string]interface{}{"foo": "bar"},
+		}
+		if _, err := client.Logical().Write("kv/data/"+path, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	batchDelete := func(client *api.Client, data map[string]interface{}) map[string]map[string]interface{} {
+		t.Helper()
+		secret, err := client.Logical().Write("kv/batch-delete", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil {
+			t.Fatal("nil secret")
+		}
+
+		results := make(map[string]map[string]interface{})
+		for _, r := range secret.Data["items"].([]interface{}) {
+			result := r.(map[string]interface{})
+			results[result["path"].(string)] = result
+		}
+		return results
+	}
+	status := func(result map[string]interface{}) string {
+		if result["status"] == nil {
+			return ""
+		}
+		return result["status"].(interface{ String() string }).String()
+	}
+	deleted := func(path string) bool {
+		t.Helper()
+		secret, err := client.Logical().Read("kv/metadata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions := secret.Data["versions"].(map[string]interface{})
+		latest := versions[secret.Data["current_version"].(interface{ String() string }).String()]
+		return latest.(map[string]interface{})["deletion_time"] != ""
+	}
+
+	// A dry run lists every secret under the path without touching them,
+	// and is audited as a whole
+	audited := len(noop.Req)
+	results := batchDelete(client, map[string]interface{}{
+		"path":      "apps",
+		"operation": "delete",
+		"dry_run":   true,
+	})
+	if len(results) != 3 {
+		t.Fatalf("bad: %#v", results)
+	}
+	if len(noop.Req) <= audited || noop.Req[audited].Path != "kv/batch-delete" {
+		t.Fatalf("expected the batch delete to be audited first, got %d requests", len(noop.Req)-audited)
+	}
+	for _, path := range []string{"apps/a", "apps/b/c", "apps/b/d"} {
+		if results[path] == nil || status(results[path]) != "" {
+			t.Fatalf("bad: %#v", results)
+		}
+		if deleted(path) {
+			t.Fatalf("%s deleted by dry run", path)
+		}
+	}
+
+	results = batchDelete(client, map[string]interface{}{
+		"path":      "apps/",
+		"operation": "delete",
+	})
+	for _, path := range []string{"apps/a", "apps/b/c", "apps/b/d"} {
+		if status(results[path]) != "204" {
+			t.Fatalf("bad: %#v", results)
+		}
+		if !deleted(path) {
+			t.Fatalf("%s not deleted", path)
+		}
+	}
+	if deleted("other") {
+		t.Fatal("secret outside of the path deleted")
+	}
+
+	results = batchDelete(client, map[string]interface{}{
+		"path":      "apps/b",
+		"operation": "undelete",
+	})
+	if len(results) != 2 {
+		t.Fatalf("bad: %#v", results)
+	}
+	if deleted("apps/b/c") || deleted("apps/b/d") || !deleted("apps/a") {
+		t.Fatal("expected apps/b to be undeleted")
+	}
+
+	// The request and each listing and operation are checked against the
+	// caller's policies
+	policy := `
+path "kv/batch-delete" { capabilities = ["update"] }
+path "kv/metadata/*" { capabilities = ["list"] }
+path "kv/metadata/apps/b/*" { capabilities = ["list", "delete"] }
+`
+	if err := client.Sys().PutPolicy("batch-delete", policy); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"batch-delete"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited.SetToken(secret.Auth.ClientToken)
+
+	results = batchDelete(limited, map[string]interface{}{
+		"path":      "apps",
+		"operation": "delete-metadata",
+	})
+	if status(results["apps/a"]) != "403" || status(results["apps/b/c"]) != "204" || status(results["apps/b/d"]) != "204" {
+		t.Fatalf("bad: %#v", results)
+	}
+	if secret, err := client.Logical().Read("kv/metadata/apps/b/c"); err != nil || secret != nil {
+		t.Fatalf("expected apps/b/c to be removed: %v %#v", err, secret)
+	}
+
+	policy = `
+path "kv/data/*" { capabilities = ["create", "read", "update", "delete"] }
+path "kv/metadata/*" { capabilities = ["list", "delete"] }
+`
+	if err := client.Sys().PutPolicy("batch-delete", policy); err != nil {
+		t.Fatal(err)
+	}
+	_, err = limited.Logical().Write("kv/batch-delete", map[string]interface{}{
+		"path":      "",
+		"operation": "delete",
+	})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	if deleted("other") {
+		t.Fatal("secret deleted by a denied request")
+	}
+
+	// Malformed requests are rejected outright
+	if _, err := client.Logical().Write("kv/batch-delete", map[string]interface{}{"path": "apps"}); err == nil {
+		t.Fatal("expected error for missing operation")
+	}
+	if _, err := client.Logical().Write("kv/batch-delete", map[string]interface{}{"path": "apps", "operation": "delete-metadata", "versions": "1"}); err == nil {
+		t.Fatal("expected error for versions with delete-metadata")
+	}
+}
+
+func TestLogical_KVBatchMFA(t *testing.T) {
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the upgrade code to run/finish
+	time.Sleep(time.Second)
+
+	for _, foo := range []string{"v1", "v2"} {
+		if _, err := client.Logical().Write("kv/data/a", map[string]interface{}{
+			"data": map[string]interface{}{"foo": foo},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := `
+path "kv/batch" { capabilities = ["update"] }
+path "kv/diff/*" { capabilities = ["read"] }
+path "kv/data/*" {
+	capabilities = ["read"]
+	mfa_methods = ["my_totp"]
+}
+`
+	if err := client.Sys().PutPolicy("mfa", policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("auth/token/roles/mfa", map[string]interface{}{
+		"allowed_policies":       "mfa",
+		"allowed_entity_aliases": "alice",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Write("auth/token/create/mfa", map[string]interface{}{
+		"policies":     "mfa",
+		"entity_alias": "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Logical().Write("identity/mfa/method/totp/admin-generate", map[string]interface{}{
+		"entity_id":   secret.Auth.EntityID,
+		"method_name": "my_totp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := otp.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient.SetToken(secret.Auth.ClientToken)
+
+	batch := func() []interface{} {
+		t.Helper()
+		secret, err := userClient.Logical().Write("kv/batch", map[string]interface{}{
+			"items": []map[string]interface{}{{"path": "a"}, {"path": "a", "version": 1}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Data["items"].([]interface{})
+	}
+	status := func(result interface{}) string {
+		return result.(map[string]interface{})["status"].(interface{ String() string }).String()
+	}
+
+	// Without credentials, each item is denied
+	for i, result := range batch() {
+		if status(result) != "403" {
+			t.Fatalf("item %d: bad: %#v", i, result)
+		}
+	}
+
+	// The credentials are passed on to each item, which share the passcode
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient.SetMFACreds([]string{"my_totp:" + code})
+	for i, result := range batch() {
+		if status(result) != "200" {
+			t.Fatalf("item %d: bad: %#v", i, result)
+		}
+	}
+
+	// The passcode cannot be used again by another request
+	for i, result := range batch() {
+		if status(result) != "403" {
+			t.Fatalf("item %d: bad: %#v", i, result)
+		}
+	}
+
+	code, err = totp.GenerateCode(key.Secret(), time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient.SetMFACreds([]string{"my_totp:" + code})
+	if _, err := userClient.Logical().ReadWithData("kv/diff/a", map[string][]string{
+		"versions": []string{"1,2"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// envelopeHandler serves an envelope request, writing its errors to w. The
+// requests made for it must use the context of r. The return values are the
+// same as for request.
+type envelopeHandler func(w http.ResponseWriter, r *http.Request) (*logical.Response, bool, bool)
+
+// requestEnvelope serves a request made of requests of its own on behalf of
+// the caller, such as the batch endpoint of KV version 2 mounts. The request
+// is checked against the caller's ACL and audited before handle serves it,
+// and its response is audited once served. The return values are the same
+// as for request.
+func requestEnvelope(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request, handle envelopeHandler) (*logical.Response, bool, bool) {
+	auth, resp, err := core.CheckEnvelopeRequest(r.Context(), req)
+	switch {
+	case errwrap.Contains(err, consts.ErrStandby.Error()):
+		respondStandby(core, w, r.URL)
+		return nil, false, false
+	case err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()):
+		return nil, false, true
+	case resp != nil || err != nil:
+		respondErrorCommon(w, req, resp, err)
+		return nil, false, false
+	}
+
+	sw := &statusResponseWriter{ResponseWriter: w}
+	resp, ok, needsForward := handle(sw, r.WithContext(vault.ContextWithEnvelope(r.Context(), req)))
+	if needsForward {
+		return nil, false, true
+	}
+
+	// Errors have already been written out, so only their status is known
+	var respErr error
+	if !ok {
+		respErr = logical.CodedError(sw.status, http.StatusText(sw.status))
+	}
+	if err := core.AuditEnvelopeResponse(r.Context(), req, auth, resp, respErr); err != nil && ok {
+		respondError(w, http.StatusInternalServerError, err)
+		return nil, false, false
+	}
+
+	return resp, ok, false
+}
+
+// statusResponseWriter records the status written to a response.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// kvDiffVersion is a version of a secret read for a diff.
+type kvDiffVersion struct {
+	version int
+	data    map[string]interface{}
+}
+
+// requestKVDiff reads two versions of a secret of the KV version 2 mount with
+// a separate request on behalf of the caller, so that each read is subject to
+// the caller's ACL and is audited individually, and returns the fields that
+// were added, removed or changed between them. If a single version is given,
+// it is compared with the current version. The return values are the same as
+// for request.
+func requestKVDiff(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request, mountPath string) (*logical.Response, bool, bool) {
+	if req.WrapInfo != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("response wrapping is not supported for diff requests"))
+		return nil, false, false
+	}
+
+	secretPath := strings.TrimPrefix(req.Path, mountPath+"diff/")
+	if secretPath == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("missing path"))
+		return nil, false, false
+	}
+
+	versionsRaw, err := parseutil.ParseCommaStringSlice(req.Data["versions"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to parse versions: {{err}}", err))
+		return nil, false, false
+	}
+	if len(versionsRaw) < 1 || len(versionsRaw) > 2 {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("one or two versions must be given"))
+		return nil, false, false
+	}
+
+	// A version of zero reads the current version of the secret
+	var versions [2]int
+	for i, raw := range versionsRaw {
+		version, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || version < 1 {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid version %q", raw))
+			return nil, false, false
+		}
+		versions[i] = version
+	}
+
+	var read [2]*kvDiffVersion
+	for i, version := range versions {
+		itemReq, err := kvBatchItemRequest(req, mountPath, secretPath, version)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, err)
+			return nil, false, false
+		}
+
+		resp, err := core.HandleRequest(r.Context(), itemReq)
+		switch {
+		case errwrap.Contains(err, consts.ErrStandby.Error()):
+			respondStandby(core, w, r.URL)
+			return nil, false, false
+		case err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()):
+			return nil, false, true
+		}
+
+		status, err := logical.RespondErrorCommon(itemReq, resp, err)
+		switch {
+		case err != nil:
+			respondError(w, status, err)
+			return nil, false, false
+		case resp == nil:
+			respondError(w, http.StatusNotFound, nil)
+			return nil, false, false
+		}
+
+		read[i], err = kvDiffParseVersion(resp, secretPath, version)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err)
+			return nil, false, false
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"path":         secretPath,
+			"from_version": read[0].version,
+			"to_version":   read[1].version,
+			"changes":      kvDiffData(read[0].data, read[1].data),
+		},
+	}, true, false
+}
+
+// kvDiffParseVersion returns the version of a secret read for a diff. The
+// version is zero if the current version was read.
+func kvDiffParseVersion(resp *logical.Response, secretPath string, version int) (*kvDiffVersion, error) {
+	// Deleted and destroyed versions are returned as raw 404 responses,
+	// without data
+	data, ok := resp.Data["data"].(map[string]interface{})
+	if !ok {
+		if version == 0 {
+			return nil, fmt.Errorf("the current version of %q has been deleted or destroyed", secretPath)
+		}
+		return nil, fmt.Errorf("version %d of %q has been deleted or destroyed", version, secretPath)
+	}
+
+	if metadata, ok := resp.Data["metadata"].(map[string]interface{}); ok {
+		current, err := parseutil.ParseInt(metadata["version"])
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to parse version: {{err}}", err)
+		}
+		version = int(current)
+	}
+
+	return &kvDiffVersion{
+		version: version,
+		data:    data,
+	}, nil
+}
+
+// kvDiffData returns the field-level differences between two versions of
+// a secret's data, sorted by field name.
+func kvDiffData(from, to map[string]interface{}) []map[string]interface{} {
+	changes := []map[string]interface{}{}
+	for k, oldVal := range from {
+		newVal, ok := to[k]
+		switch {
+		case !ok:
+			changes = append(changes, map[string]interface{}{"field": k, "change": "removed", "old": oldVal})
+		case !reflect.DeepEqual(oldVal, newVal):
+			changes = append(changes, map[string]interface{}{"field": k, "change": "changed", "old": oldVal, "new": newVal})
+		}
+	}
+	for k, newVal := range to {
+		if _, ok := from[k]; !ok {
+			changes = append(changes, map[string]interface{}{"field": k, "change": "added", "new": newVal})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i]["field"].(string) < changes[j]["field"].(string)
+	})
+	return changes
+}
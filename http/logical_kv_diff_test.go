@@ -0,0 +1,179 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestLogical_KVDiff(t *testing.T) {
+	var noop *vault.NoopAudit
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				noop = &vault.NoopAudit{
+					Config: config,
+				}
+				return noop, nil
+			},
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the upgrade code to run/finish
+	time.Sleep(time.Second)
+
+	for _, data := range []map[string]interface{}{
+		{"foo": "bar", "baz": "qux"},
+		{"foo": "bar2", "zip": "zap"},
+		{"foo": "bar2", "zip": "zap"},
+	} {
+		if _, err := client.Logical().Write("kv/data/a/b", map[string]interface{}{"data": data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diff := func(client *api.Client, path string, versions string) (*api.Secret, error) {
+		t.Helper()
+		return client.Logical().ReadWithData(path, map[string][]string{
+			"versions": []string{versions},
+		})
+	}
+
+	audited := len(noop.Req)
+	secret, err := diff(client, "kv/diff/a/b", "1,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil {
+		t.Fatal("nil secret")
+	}
+	if secret.Data["path"] != "a/b" || secret.Data["from_version"] != json.Number("1") || secret.Data["to_version"] != json.Number("2") {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+	expected := []interface{}{
+		map[string]interface{}{"field": "baz", "change": "removed", "old": "qux"},
+		map[string]interface{}{"field": "foo", "change": "changed", "old": "bar", "new": "bar2"},
+		map[string]interface{}{"field": "zip", "change": "added", "new": "zap"},
+	}
+	if !reflect.DeepEqual(secret.Data["changes"], expected) {
+		t.Fatalf("bad: %#v", secret.Data["changes"])
+	}
+
+	// The diff itself is audited, along with each version read
+	var paths []string
+	for _, req := range noop.Req[audited:] {
+		paths = append(paths, req.Path)
+	}
+	if len(paths) != 3 || paths[0] != "kv/diff/a/b" {
+		t.Fatalf("bad audited requests: %v", paths)
+	}
+
+	// A single version is compared with the current version
+	secret, err = diff(client, "kv/diff/a/b", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["to_version"] != json.Number("3") || len(secret.Data["changes"].([]interface{})) != 0 {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// Missing secrets and versions are not found
+	for _, path := range []string{"kv/diff/a/c", "kv/diff/a/b"} {
+		secret, err = diff(client, path, "1,4")
+		if err != nil || secret != nil {
+			t.Fatalf("expected no secret at %s: %v %#v", path, err, secret)
+		}
+	}
+
+	// Deleted versions cannot be compared
+	if _, err := client.Logical().Write("kv/delete/a/b", map[string]interface{}{
+		"versions": []int{1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = diff(client, "kv/diff/a/b", "1,2"); err == nil || !strings.Contains(err.Error(), "version 1 of \"a/b\" has been deleted or destroyed") {
+		t.Fatalf("expected deleted version error, got: %v", err)
+	}
+
+	for _, versions := range []string{"", "1,2,3", "one", "0"} {
+		if _, err = diff(client, "kv/diff/a/b", versions); err == nil || !strings.Contains(err.Error(), "Code: 400") {
+			t.Fatalf("expected bad request for versions %q, got: %v", versions, err)
+		}
+	}
+
+	// The diff and each version read are checked against the caller's
+	// policies
+	err = client.Sys().PutPolicy("diff", `
+path "kv/diff/a/*" {
+	capabilities = ["read"]
+}
+path "kv/data/a/b" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"diff"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited.SetToken(tokenSecret.Auth.ClientToken)
+
+	if _, err := diff(limited, "kv/diff/a/b", "2,3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := diff(limited, "kv/diff/a/c", "1"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	err = client.Sys().PutPolicy("diff", `
+path "kv/data/*" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := diff(limited, "kv/diff/a/b", "2,3"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+}
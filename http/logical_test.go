@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/go-test/deep"
+	"github.com/hashicorp/go-cleanhttp"
 	log "github.com/hashicorp/go-hclog"
 
 	"github.com/hashicorp/vault/audit"
@@ -83,6 +84,37 @@ func TestLogical_noExist(t *testing.T) {
 	testResponseStatus(t, resp, 404)
 }
 
+func TestLogical_Head(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	head := func(path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("HEAD", addr+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(consts.AuthHeaderName, token)
+		resp, err := cleanhttp.DefaultClient().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Reads may have side effects, so HEAD is only served for ACME nonces
+	testResponseStatus(t, head("/v1/secret/foo"), 405)
+	testResponseStatus(t, head("/v1/pki/acme/new-nonce"), 404)
+}
+
 func TestLogical_StandbyRedirect(t *testing.T) {
 	ln1, addr1 := TestListener(t)
 	defer ln1.Close()
@@ -440,6 +472,51 @@ func TestLogical_Audit_invalidWrappingToken(t *testing.T) {
 	}
 }
 
+func TestLogical_Audit_rateLimitQuota(t *testing.T) {
+	// Create a noop audit backend
+	var noop *vault.NoopAudit
+	c, _, root := vault.TestCoreUnsealedWithConfig(t, &vault.CoreConfig{
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				noop = &vault.NoopAudit{
+					Config: config,
+				}
+				return noop, nil
+			},
+		},
+	})
+	ln, addr := TestServer(t, c)
+	defer ln.Close()
+
+	resp := testHttpPost(t, root, addr+"/v1/sys/audit/noop", map[string]interface{}{
+		"type": "noop",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPost(t, root, addr+"/v1/sys/quotas/rate-limit/secret", map[string]interface{}{
+		"path":     "secret/",
+		"rate":     1,
+		"interval": "1h",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, root, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 404)
+	resp = testHttpGet(t, root, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 429)
+
+	// Both requests are audited, including the one over the quota
+	var audited int
+	for _, req := range noop.Req {
+		if req.Path == "secret/foo" {
+			audited++
+		}
+	}
+	if audited != 2 {
+		t.Fatalf("expected 2 audited requests, got %d", audited)
+	}
+}
+
 func TestLogical_ShouldParseForm(t *testing.T) {
 	const formCT = "application/x-www-form-urlencoded"
 
@@ -195,3 +195,86 @@ func TestPlugin_GetParams(t *testing.T) {
 		t.Fatal(secret.Data)
 	}
 }
+
+func TestPlugin_Versions(t *testing.T) {
+	logger := log.New(&log.LoggerOptions{
+		Mutex: &sync.Mutex{},
+	})
+	cluster, core := getPluginClusterAndCore(t, logger)
+	defer cluster.Cleanup()
+
+	client := core.Client
+
+	// Register the mock plugin binary as a version of the plugin too
+	plugin, err := client.Sys().GetPlugin(&api.GetPluginInput{
+		Name: "mock-plugin",
+		Type: consts.PluginTypeSecrets,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Sys().RegisterPlugin(&api.RegisterPluginInput{
+		Name:    "mock-plugin",
+		Type:    consts.PluginTypeSecrets,
+		Command: plugin.Command,
+		Args:    plugin.Args,
+		SHA256:  plugin.SHA256,
+		Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err = client.Sys().GetPlugin(&api.GetPluginInput{
+		Name:    "mock-plugin",
+		Type:    consts.PluginTypeSecrets,
+		Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plugin.Version != "1.0.0" || !reflect.DeepEqual(plugin.Versions, []string{"1.0.0"}) {
+		t.Fatalf("bad: %#v", plugin)
+	}
+
+	// Mount the version and use it
+	err = client.Sys().Mount("mock-versioned", &api.MountInput{
+		Type: "mock-plugin",
+		Config: api.MountConfigInput{
+			PluginVersion: "1.0.0",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("mock-versioned/kv/foo", map[string]interface{}{
+		"value": "bar",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := client.Sys().MountConfig("mock-versioned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.PluginVersion != "1.0.0" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// Versions which are not registered cannot be mounted or tuned to
+	err = client.Sys().Mount("mock-missing", &api.MountInput{
+		Type: "mock-plugin",
+		Config: api.MountConfigInput{
+			PluginVersion: "2.0.0",
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error mounting a missing version")
+	}
+	err = client.Sys().TuneMount("mock-versioned", api.MountConfigInput{
+		PluginVersion: "2.0.0",
+	})
+	if err == nil {
+		t.Fatal("expected error tuning to a missing version")
+	}
+}
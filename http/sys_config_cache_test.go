@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysConfigCache(t *testing.T) {
+	core, keys, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	read := func() map[string]interface{} {
+		t.Helper()
+
+		resp := testHttpGet(t, token, addr+"/v1/sys/config/cache")
+		var actual map[string]interface{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+		return actual["data"].(map[string]interface{})
+	}
+
+	data := read()
+	if data["max_entries"] != json.Number("131072") || data["max_bytes"] != json.Number("268435456") {
+		t.Fatalf("bad: %#v", data)
+	}
+	if len(data["pinned_prefixes"].([]interface{})) != len(physical.DefaultCachePinnedPrefixes) {
+		t.Fatalf("bad: %#v", data)
+	}
+	if data["pinned_entries"] == json.Number("0") || data["hits"] == json.Number("0") {
+		t.Fatalf("expected mount tables and policies to be cached: %#v", data)
+	}
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/config/cache", map[string]interface{}{
+		"max_entries":     100,
+		"pinned_prefixes": "sys/policy/",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/config/cache", map[string]interface{}{
+		"max_bytes": 65536,
+	})
+	testResponseStatus(t, resp, 204)
+
+	data = read()
+	if data["max_entries"] != json.Number("100") || data["max_bytes"] != json.Number("65536") {
+		t.Fatalf("bad: %#v", data)
+	}
+	if prefixes := data["pinned_prefixes"].([]interface{}); len(prefixes) != 1 || prefixes[0] != "sys/policy/" {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/config/cache", map[string]interface{}{
+		"max_entries": -1,
+	})
+	testResponseStatus(t, resp, 400)
+
+	// The overrides persist across a seal
+	if err := core.Seal(token); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := vault.TestCoreUnseal(core, vault.TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data = read()
+	if data["max_entries"] != json.Number("100") {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	resp = testHttpDelete(t, token, addr+"/v1/sys/config/cache")
+	testResponseStatus(t, resp, 204)
+
+	data = read()
+	if data["max_entries"] != json.Number("131072") || len(data["pinned_prefixes"].([]interface{})) != len(physical.DefaultCachePinnedPrefixes) {
+		t.Fatalf("bad: %#v", data)
+	}
+}
@@ -25,6 +25,7 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 
 	configResp := map[string]interface{}{
 		"api_addr":                     "",
+		"cache_max_bytes":              json.Number("0"),
 		"cache_size":                   json.Number("0"),
 		"cluster_addr":                 "",
 		"cluster_cipher_suites":        "",
@@ -38,11 +39,13 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 		"disable_performance_standby":  false,
 		"disable_printable_check":      false,
 		"disable_sealwrap":             false,
+		"drain_grace_period":           json.Number("0"),
 		"raw_storage_endpoint":         false,
 		"enable_ui":                    false,
 		"log_format":                   "",
 		"log_level":                    "",
 		"max_lease_ttl":                json.Number("0"),
+		"online_seal_migration":        false,
 		"pid_file":                     "",
 		"plugin_directory":             "",
 	}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/vault/vault"
+)
+
+// eventsWriteTimeout bounds how long sending a single event to a subscriber
+// may take.
+const eventsWriteTimeout = 10 * time.Second
+
+var eventsUpgrader = websocket.Upgrader{}
+
+// eventsAck is the message subscribers send to acknowledge events
+type eventsAck struct {
+	Ack uint64 `json:"ack"`
+}
+
+// handleEventsSubscribe performs the logical request to
+// sys/events/subscribe/<topic>, so that the usual authentication and ACL
+// checks apply to the topic, then upgrades the connection to a WebSocket and
+// sends the events of the subscriber until the client disconnects.
+//
+// When the client closes the connection normally, the subscriber is removed;
+// otherwise it is kept for a while for the client to resume it, with the
+// subscriber ID returned in the X-Vault-Subscriber-Id header of the upgrade
+// response, and receive again the events it did not acknowledge.
+//
+// Events are only delivered by the node they are published on, so
+// subscriptions are served by the active node and standbys redirect to it.
+func handleEventsSubscribe(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		req, _, status, err := buildLogicalRequest(core, w, r)
+		if err != nil || status != 0 {
+			respondError(w, status, err)
+			return
+		}
+
+		resp, ok, needsForward := request(core, w, r, req)
+		switch {
+		case needsForward:
+			// Events are per node and a WebSocket cannot be forwarded, so
+			// performance standbys redirect to the active node like standbys
+			respondStandby(core, w, r.URL)
+			return
+		case !ok:
+			return
+		}
+
+		id := resp.Data["subscriber_id"].(string)
+		sub := core.Events().Subscriber(id)
+		if sub == nil {
+			respondError(w, http.StatusBadRequest, errors.New("subscriber expired"))
+			return
+		}
+		if err := sub.Attach(); err != nil {
+			respondError(w, http.StatusConflict, err)
+			return
+		}
+
+		header := http.Header{}
+		header.Set("X-Vault-Subscriber-Id", id)
+
+		// On failure, Upgrade has already replied to the client.
+		conn, err := eventsUpgrader.Upgrade(w, r, header)
+		if err != nil {
+			sub.Detach()
+			return
+		}
+		defer conn.Close()
+
+		// The client acknowledges events by sending their ID; reading is
+		// also needed to process control messages and notice when it goes
+		// away.
+		closed := make(chan struct{})
+		normalClosure := false
+		go func() {
+			defer close(closed)
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					normalClosure = websocket.IsCloseError(err, websocket.CloseNormalClosure)
+					return
+				}
+				var ack eventsAck
+				if err := json.Unmarshal(msg, &ack); err != nil {
+					continue
+				}
+				sub.Ack(ack.Ack)
+			}
+		}()
+
+		for {
+			events, subClosed, subErr := sub.Next()
+			for _, event := range events {
+				conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+				if err := conn.WriteJSON(event); err != nil {
+					sub.Detach()
+					return
+				}
+			}
+			if subClosed {
+				code, text := websocket.CloseNormalClosure, ""
+				if subErr != nil {
+					code, text = websocket.ClosePolicyViolation, subErr.Error()
+				}
+				msg := websocket.FormatCloseMessage(code, text)
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(eventsWriteTimeout))
+				return
+			}
+
+			select {
+			case <-closed:
+				if normalClosure {
+					core.Events().Unsubscribe(id)
+				} else {
+					sub.Detach()
+				}
+				return
+			case <-r.Context().Done():
+				sub.Detach()
+				return
+			case <-sub.Notify():
+			}
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysEventsSubscribe(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	wsAddr := "ws" + strings.TrimPrefix(addr, "http") + "/v1/sys/events/subscribe/kv/write"
+
+	// Authentication is required
+	_, resp, err := websocket.DefaultDialer.Dial(wsAddr, nil)
+	if err == nil {
+		t.Fatal("expected error without token")
+	}
+	testResponseStatus(t, resp, 400)
+
+	// Tokens need read capability on the topic
+	resp = testHttpPut(t, token, addr+"/v1/sys/policy/events-seal", map[string]interface{}{
+		"policy": `path "sys/events/subscribe/seal/*" { capabilities = ["read"] }`,
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPut(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"events-seal"},
+	})
+	var tokenResp map[string]interface{}
+	testResponseBody(t, resp, &tokenResp)
+	limitedToken := tokenResp["auth"].(map[string]interface{})["client_token"].(string)
+
+	limitedHeader := http.Header{}
+	limitedHeader.Set("X-Vault-Token", limitedToken)
+	_, resp, err = websocket.DefaultDialer.Dial(wsAddr, limitedHeader)
+	if err == nil {
+		t.Fatal("expected error for a denied topic")
+	}
+	testResponseStatus(t, resp, 403)
+
+	header := http.Header{}
+	header.Set("X-Vault-Token", token)
+	conn, resp, err := websocket.DefaultDialer.Dial(wsAddr, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriberID := resp.Header.Get("X-Vault-Subscriber-Id")
+	if subscriberID == "" {
+		t.Fatal("expected subscriber ID")
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/secret/foo", map[string]interface{}{
+		"data": "bar",
+	})
+	testResponseStatus(t, resp, 204)
+
+	readEvent := func(conn *websocket.Conn) *vault.Event {
+		t.Helper()
+		var event vault.Event
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatal(err)
+		}
+		if event.Topic != vault.EventTopicKVWrite || event.Data["mount"] != "secret/" || event.Data["path"] != "foo" {
+			t.Fatalf("bad event: %#v", event)
+		}
+		return &event
+	}
+	event := readEvent(conn)
+
+	// Without an acknowledgement, the event is sent again when resuming
+	conn.UnderlyingConn().Close()
+	waitDetached := func() {
+		t.Helper()
+		for i := 0; i < 50; i++ {
+			if err := core.Events().Subscriber(subscriberID).Attach(); err == nil {
+				core.Events().Subscriber(subscriberID).Detach()
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatal("subscriber still attached")
+	}
+	waitDetached()
+
+	conn, _, err = websocket.DefaultDialer.Dial(wsAddr+"?subscriber_id="+subscriberID, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if resent := readEvent(conn); resent.ID != event.ID {
+		t.Fatalf("expected event %d to be sent again, got %d", event.ID, resent.ID)
+	}
+
+	// Subscribers are bound to their topic
+	_, resp, err = websocket.DefaultDialer.Dial(strings.TrimSuffix(wsAddr, "write")+"delete?subscriber_id="+subscriberID, header)
+	if err == nil {
+		t.Fatal("expected error resuming for another topic")
+	}
+	testResponseStatus(t, resp, 400)
+
+	if err := conn.WriteJSON(map[string]interface{}{"ack": event.ID}); err != nil {
+		t.Fatal(err)
+	}
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteMessage(websocket.CloseMessage, msg); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50 && core.Events().Subscriber(subscriberID) != nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if core.Events().Subscriber(subscriberID) != nil {
+		t.Fatal("expected subscriber to be removed after a normal closure")
+	}
+}
@@ -133,12 +133,20 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		perfStandbyCode = code
 	}
 
+	// Draining is only reported with its own code if asked for, as the node
+	// holds requests rather than failing them while it drains
+	drainingCode, drainingCodeFound, ok := fetchStatusCode(r, "drainingcode")
+	if !ok {
+		return http.StatusBadRequest, nil, nil
+	}
+
 	ctx := context.Background()
 
 	// Check system status
 	sealed := core.Sealed()
 	standby, _ := core.Standby()
 	perfStandby := core.PerfStandby()
+	drain := core.DrainStatus()
 	var replicationState consts.ReplicationState
 	if standby {
 		replicationState = core.ActiveNodeReplicationState()
@@ -156,6 +164,8 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 	switch {
 	case !init:
 		code = uninitCode
+	case drain.Draining && drainingCodeFound:
+		code = drainingCode
 	case sealed:
 		code = sealedCode
 	case replicationState.HasState(consts.ReplicationDRSecondary):
@@ -196,6 +206,12 @@ func getSysHealth(core *vault.Core, r *http.Request) (int, *HealthResponse, erro
 		Version:                    version.GetVersion().VersionNumber(),
 		ClusterName:                clusterName,
 		ClusterID:                  clusterID,
+		Draining:                   drain.Draining,
+	}
+
+	if drain.Draining {
+		body.DrainReason = drain.Reason
+		body.DrainDeadlineUTC = drain.Deadline.UTC().Unix()
 	}
 
 	if init && !sealed && !standby {
@@ -217,4 +233,7 @@ type HealthResponse struct {
 	ClusterName                string `json:"cluster_name,omitempty"`
 	ClusterID                  string `json:"cluster_id,omitempty"`
 	LastWAL                    uint64 `json:"last_wal,omitempty"`
+	Draining                   bool   `json:"draining"`
+	DrainReason                string `json:"drain_reason,omitempty"`
+	DrainDeadlineUTC           int64  `json:"drain_deadline_utc,omitempty"`
 }
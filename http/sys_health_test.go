@@ -30,6 +30,7 @@ func TestSysHealth_get(t *testing.T) {
 		"sealed":                       true,
 		"standby":                      true,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 501)
 	testResponseBody(t, resp, &actual)
@@ -63,6 +64,7 @@ func TestSysHealth_get(t *testing.T) {
 		"sealed":                       true,
 		"standby":                      true,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 503)
 	testResponseBody(t, resp, &actual)
@@ -100,6 +102,7 @@ func TestSysHealth_get(t *testing.T) {
 		"sealed":                       false,
 		"standby":                      false,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 200)
 	testResponseBody(t, resp, &actual)
@@ -143,6 +146,7 @@ func TestSysHealth_customcodes(t *testing.T) {
 		"sealed":                       true,
 		"standby":                      true,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 581)
 	testResponseBody(t, resp, &actual)
@@ -177,6 +181,7 @@ func TestSysHealth_customcodes(t *testing.T) {
 		"sealed":                       true,
 		"standby":                      true,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 523)
 	testResponseBody(t, resp, &actual)
@@ -215,6 +220,7 @@ func TestSysHealth_customcodes(t *testing.T) {
 		"sealed":                       false,
 		"standby":                      false,
 		"performance_standby":          false,
+		"draining":                     false,
 	}
 	testResponseStatus(t, resp, 202)
 	testResponseBody(t, resp, &actual)
@@ -0,0 +1,361 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	// defaultImportBatchSize is the number of secrets written together
+	// unless the manifest asks otherwise.
+	defaultImportBatchSize = 100
+
+	// maxImportBatchSize is the largest batch a manifest may ask for.
+	maxImportBatchSize = 1000
+
+	// maxImportItems is the maximum number of secrets that can be imported
+	// in a single request.
+	maxImportItems = 10000
+
+	importStatusValid      = "valid"
+	importStatusWritten    = "written"
+	importStatusFailed     = "failed"
+	importStatusSkipped    = "skipped"
+	importStatusRolledBack = "rolled_back"
+)
+
+type importItem struct {
+	Path string                 `mapstructure:"path"`
+	Data map[string]interface{} `mapstructure:"data"`
+}
+
+// importTarget is an item of the manifest resolved against its KV mount,
+// along with the state of the secret before the import.
+type importTarget struct {
+	result     map[string]interface{}
+	version    int
+	mountPath  string
+	secretPath string
+	dataPath   string
+	data       map[string]interface{}
+	existing   map[string]interface{}
+	ok         bool
+
+	// written is the version written by the import on KV version 2 mounts
+	written int64
+}
+
+// requestImport writes the secrets of an import manifest to their KV mounts,
+// with a separate request per secret on behalf of the caller, so that each
+// one is subject to the caller's ACL and is audited individually. The import
+// itself is checked and audited as an envelope request by requestEnvelope.
+//
+// Secrets are written in batches. All the secrets of a batch are checked
+// before any is written, and if writing one fails, those of the batch
+// already written are restored to their previous state. In dry-run mode,
+// only the checks are made. The status of each item is reported; the import
+// itself only fails if the manifest is malformed. The return values are the
+// same as for request.
+func requestImport(core *vault.Core, w http.ResponseWriter, r *http.Request, req *logical.Request) (*logical.Response, bool, bool) {
+	if req.WrapInfo != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("response wrapping is not supported for imports"))
+		return nil, false, false
+	}
+
+	var manifest struct {
+		Secrets   []importItem `mapstructure:"secrets"`
+		BatchSize int          `mapstructure:"batch_size"`
+		DryRun    bool         `mapstructure:"dry_run"`
+	}
+	if err := mapstructure.WeakDecode(req.Data, &manifest); err != nil {
+		respondError(w, http.StatusBadRequest, errwrap.Wrapf("failed to parse manifest: {{err}}", err))
+		return nil, false, false
+	}
+	switch {
+	case len(manifest.Secrets) == 0:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("no secrets given"))
+		return nil, false, false
+	case len(manifest.Secrets) > maxImportItems:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("at most %d secrets may be imported at once", maxImportItems))
+		return nil, false, false
+	case manifest.BatchSize < 0 || manifest.BatchSize > maxImportBatchSize:
+		respondError(w, http.StatusBadRequest, fmt.Errorf("batch_size must be between 1 and %d", maxImportBatchSize))
+		return nil, false, false
+	case manifest.BatchSize == 0:
+		manifest.BatchSize = defaultImportBatchSize
+	}
+
+	im := &importer{
+		core: core,
+		r:    r,
+		req:  req,
+	}
+
+	results := make([]map[string]interface{}, 0, len(manifest.Secrets))
+	var written, failed int
+	for start := 0; start < len(manifest.Secrets); start += manifest.BatchSize {
+		end := start + manifest.BatchSize
+		if end > len(manifest.Secrets) {
+			end = len(manifest.Secrets)
+		}
+
+		targets := make([]*importTarget, 0, end-start)
+		batchOK := true
+		for _, item := range manifest.Secrets[start:end] {
+			target, err := im.check(item)
+			if err != nil {
+				return im.abort(w, err)
+			}
+			targets = append(targets, target)
+			results = append(results, target.result)
+			batchOK = batchOK && target.ok
+		}
+
+		switch {
+		case manifest.DryRun:
+			for _, target := range targets {
+				if target.ok {
+					target.result["status"] = importStatusValid
+				}
+			}
+		case !batchOK:
+			for _, target := range targets {
+				if target.ok {
+					target.result["status"] = importStatusSkipped
+				}
+			}
+		default:
+			n, err := im.write(targets)
+			if err != nil {
+				return im.abort(w, err)
+			}
+			written += n
+		}
+
+		for _, target := range targets {
+			if target.result["status"] == importStatusFailed {
+				failed++
+			}
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"dry_run": manifest.DryRun,
+			"written": written,
+			"failed":  failed,
+			"items":   results,
+		},
+	}, true, false
+}
+
+type importer struct {
+	core *vault.Core
+	r    *http.Request
+	req  *logical.Request
+}
+
+// errImportStandby and errImportForward abort an import left to another node
+var (
+	errImportStandby = fmt.Errorf("node is a standby")
+	errImportForward = fmt.Errorf("request needs forwarding")
+)
+
+func (im *importer) abort(w http.ResponseWriter, err error) (*logical.Response, bool, bool) {
+	switch err {
+	case errImportStandby:
+		respondStandby(im.core, w, im.r.URL)
+		return nil, false, false
+	case errImportForward:
+		return nil, false, true
+	}
+	respondError(w, http.StatusInternalServerError, err)
+	return nil, false, false
+}
+
+// check resolves an item of the manifest and records the current state of
+// its secret, so that it can be restored. The returned error is only set if
+// the import must be aborted; otherwise failures are reported in the result
+// of the item.
+func (im *importer) check(item importItem) (*importTarget, error) {
+	itemPath := strings.TrimPrefix(item.Path, "/")
+	target := &importTarget{
+		result: map[string]interface{}{
+			"path": itemPath,
+		},
+		data: item.Data,
+	}
+	fail := func(err string) (*importTarget, error) {
+		target.result["status"] = importStatusFailed
+		target.result["errors"] = []string{err}
+		return target, nil
+	}
+
+	switch {
+	case itemPath == "":
+		return fail("missing path")
+	case strings.HasSuffix(itemPath, "/"):
+		return fail("path must not end in '/'")
+	case len(item.Data) == 0:
+		return fail("missing data")
+	}
+
+	mountPath, version := im.core.KVMount(im.r.Context(), itemPath)
+	if mountPath == "" {
+		return fail("no KV secrets engine is mounted at the path")
+	}
+	target.version = version
+	target.mountPath = mountPath
+	target.secretPath = strings.TrimPrefix(itemPath, mountPath)
+	target.dataPath = itemPath
+	if version == 2 {
+		target.dataPath = mountPath + "data/" + target.secretPath
+	}
+
+	status, resp, err := im.request(logical.ReadOperation, target.dataPath, nil)
+	switch {
+	case err == errImportStandby || err == errImportForward:
+		return nil, err
+	case status == http.StatusNotFound:
+	case err != nil:
+		return fail(err.Error())
+	case version == 2:
+		target.existing, _ = resp.Data["data"].(map[string]interface{})
+	default:
+		target.existing = resp.Data
+	}
+
+	capabilities, err := im.core.Capabilities(im.r.Context(), im.req.ClientToken, target.dataPath)
+	if err != nil {
+		return nil, err
+	}
+	required := "create"
+	if target.existing != nil {
+		required = "update"
+	}
+	if !strutil.StrListContains(capabilities, required) && !strutil.StrListContains(capabilities, vault.RootCapability) {
+		return fail(fmt.Sprintf("%s capability is required on %s", required, target.dataPath))
+	}
+
+	target.ok = true
+	return target, nil
+}
+
+// write writes the secrets of a batch, restoring those already written if
+// writing one fails, and returns how many were written.
+func (im *importer) write(targets []*importTarget) (int, error) {
+	for i, target := range targets {
+		_, resp, err := im.request(logical.UpdateOperation, target.dataPath, target.payload(target.data))
+		switch {
+		case err == errImportStandby || err == errImportForward:
+			return 0, err
+		case err == nil:
+			if target.version == 2 && resp != nil {
+				target.written, _ = parseutil.ParseInt(resp.Data["version"])
+			}
+			target.result["status"] = importStatusWritten
+			continue
+		}
+
+		target.result["status"] = importStatusFailed
+		target.result["errors"] = []string{err.Error()}
+		for _, skipped := range targets[i+1:] {
+			skipped.result["status"] = importStatusSkipped
+		}
+		for j := i - 1; j >= 0; j-- {
+			if err := im.rollback(targets[j]); err != nil {
+				targets[j].result["status"] = importStatusFailed
+				targets[j].result["errors"] = []string{fmt.Sprintf("failed to roll back: %s", err)}
+				continue
+			}
+			targets[j].result["status"] = importStatusRolledBack
+		}
+		return 0, nil
+	}
+	return len(targets), nil
+}
+
+// rollback restores the secret of a target to its state before the import.
+// On KV version 2 mounts, this writes a new version with the previous data,
+// if any, and destroys the version written by the import, so that the
+// imported data cannot be undeleted.
+func (im *importer) rollback(target *importTarget) error {
+	if target.existing != nil {
+		if _, _, err := im.request(logical.UpdateOperation, target.dataPath, target.payload(target.existing)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case target.version != 2:
+		if target.existing == nil {
+			_, _, err := im.request(logical.DeleteOperation, target.dataPath, nil)
+			return err
+		}
+		return nil
+	case target.written == 0:
+		return fmt.Errorf("the version written to %s is unknown", target.dataPath)
+	}
+
+	_, _, err := im.request(logical.UpdateOperation, target.mountPath+"destroy/"+target.secretPath, map[string]interface{}{
+		"versions": []int64{target.written},
+	})
+	return err
+}
+
+func (target *importTarget) payload(data map[string]interface{}) map[string]interface{} {
+	if target.version == 2 {
+		return map[string]interface{}{
+			"data": data,
+		}
+	}
+	return data
+}
+
+// request makes a request on behalf of the caller, carrying over its token
+// and connection, and returns its status and error as they would be
+// reported to the caller.
+func (im *importer) request(op logical.Operation, path string, data map[string]interface{}) (int, *logical.Response, error) {
+	requestID, err := uuid.GenerateUUID()
+	if err != nil {
+		return 0, nil, errwrap.Wrapf("failed to generate identifier for the request: {{err}}", err)
+	}
+
+	itemReq := &logical.Request{
+		ID:                  requestID,
+		Operation:           op,
+		Path:                path,
+		Data:                data,
+		Connection:          im.req.Connection,
+		Headers:             im.req.Headers,
+		ClientToken:         im.req.ClientToken,
+		ClientTokenAccessor: im.req.ClientTokenAccessor,
+		ClientTokenSource:   im.req.ClientTokenSource,
+		PolicyOverride:      im.req.PolicyOverride,
+		MFACreds:            im.req.MFACreds,
+	}
+
+	resp, err := im.core.HandleRequest(im.r.Context(), itemReq)
+	switch {
+	case errwrap.Contains(err, consts.ErrStandby.Error()):
+		return 0, nil, errImportStandby
+	case err != nil && errwrap.Contains(err, logical.ErrPerfStandbyPleaseForward.Error()):
+		return 0, nil, errImportForward
+	}
+
+	status, err := logical.RespondErrorCommon(itemReq, resp, err)
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return status, resp, err
+}
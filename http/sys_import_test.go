@@ -0,0 +1,223 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	kv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysImport(t *testing.T) {
+	var noop *vault.NoopAudit
+	coreConfig := &vault.CoreConfig{
+		LogicalBackends: map[string]logical.Factory{
+			"kv": kv.Factory,
+		},
+		AuditBackends: map[string]audit.Factory{
+			"noop": func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+				noop = &vault.NoopAudit{
+					Config: config,
+				}
+				return noop, nil
+			},
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+	client := cluster.Cores[0].Client
+
+	err := client.Sys().Mount("kv", &api.MountInput{
+		Type:    "kv",
+		Options: map[string]string{"version": "2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Sys().EnableAuditWithOptions("noop", &api.EnableAuditOptions{Type: "noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give time for the upgrade code to run/finish
+	time.Sleep(time.Second)
+
+	if _, err := client.Logical().Write("secret/existing", map[string]interface{}{"value": "old"}); err != nil {
+		t.Fatal(err)
+	}
+
+	runImport := func(client *api.Client, manifest map[string]interface{}) (map[string]interface{}, []string) {
+		t.Helper()
+		secret, err := client.Logical().Write("sys/import", manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var statuses []string
+		for _, item := range secret.Data["items"].([]interface{}) {
+			statuses = append(statuses, item.(map[string]interface{})["status"].(string))
+		}
+		return secret.Data, statuses
+	}
+	readValue := func(path string) interface{} {
+		t.Helper()
+		secret, err := client.Logical().Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret == nil {
+			return nil
+		}
+		if data, ok := secret.Data["data"].(map[string]interface{}); ok {
+			return data["value"]
+		}
+		return secret.Data["value"]
+	}
+
+	// Nothing is written in dry-run mode. The import itself is audited.
+	audited := len(noop.Req)
+	_, statuses := runImport(client, map[string]interface{}{
+		"dry_run": true,
+		"secrets": []map[string]interface{}{
+			{"path": "secret/a", "data": map[string]interface{}{"value": "a"}},
+			{"path": "sys/a", "data": map[string]interface{}{"value": "a"}},
+			{"path": "secret/b"},
+		},
+	})
+	if expected := []string{"valid", "failed", "failed"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+	if v := readValue("secret/a"); v != nil {
+		t.Fatalf("expected no secret, got %v", v)
+	}
+	if path := noop.Req[audited].Path; path != "sys/import" {
+		t.Fatalf("expected the import to be audited first, got %s", path)
+	}
+
+	// Secrets are written to KV version 1 and 2 mounts, batch by batch; a
+	// batch with an invalid item is skipped
+	data, statuses := runImport(client, map[string]interface{}{
+		"batch_size": 2,
+		"secrets": []map[string]interface{}{
+			{"path": "secret/a", "data": map[string]interface{}{"value": "a"}},
+			{"path": "kv/b", "data": map[string]interface{}{"value": "b"}},
+			{"path": "secret/c", "data": map[string]interface{}{"value": "c"}},
+			{"path": "secret/d/", "data": map[string]interface{}{"value": "d"}},
+		},
+	})
+	if expected := []string{"written", "written", "skipped", "failed"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+	if data["written"] != json.Number("2") || readValue("secret/a") != "a" || readValue("kv/data/b") != "b" || readValue("secret/c") != nil {
+		t.Fatalf("bad import: %#v", data)
+	}
+
+	// Each secret is written on behalf of the caller, who must be allowed
+	// to import. A write denied after the checks rolls back the batch.
+	if err := client.Sys().PutPolicy("importer", `
+path "sys/import" {
+	capabilities = ["update"]
+}
+path "secret/*" {
+	capabilities = ["create", "read", "update", "delete"]
+	allowed_parameters = {
+		"value" = []
+	}
+}
+path "kv/data/*" {
+	capabilities = ["read"]
+}
+path "kv/data/c" {
+	capabilities = ["create", "read"]
+}
+path "kv/destroy/*" {
+	capabilities = ["update"]
+}`); err != nil {
+		t.Fatal(err)
+	}
+	tokenSecret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"importer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer.SetToken(tokenSecret.Auth.ClientToken)
+
+	_, statuses = runImport(importer, map[string]interface{}{
+		"secrets": []map[string]interface{}{
+			{"path": "kv/b", "data": map[string]interface{}{"value": "denied"}},
+		},
+	})
+	if expected := []string{"failed"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+
+	data, statuses = runImport(importer, map[string]interface{}{
+		"secrets": []map[string]interface{}{
+			{"path": "secret/existing", "data": map[string]interface{}{"value": "new"}},
+			{"path": "secret/e", "data": map[string]interface{}{"value": "e"}},
+			{"path": "secret/f", "data": map[string]interface{}{"other": "f"}},
+			{"path": "secret/g", "data": map[string]interface{}{"value": "g"}},
+		},
+	})
+	if expected := []string{"rolled_back", "rolled_back", "failed", "skipped"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v: %#v", expected, statuses, data)
+	}
+	if v := readValue("secret/existing"); v != "old" {
+		t.Fatalf("expected secret to be restored, got %v", v)
+	}
+	if v := readValue("secret/e"); v != nil {
+		t.Fatalf("expected secret to be removed, got %v", v)
+	}
+
+	// On KV version 2 mounts, the versions written are destroyed
+	_, statuses = runImport(importer, map[string]interface{}{
+		"secrets": []map[string]interface{}{
+			{"path": "kv/c", "data": map[string]interface{}{"value": "c"}},
+			{"path": "secret/f", "data": map[string]interface{}{"other": "f"}},
+		},
+	})
+	if expected := []string{"rolled_back", "failed"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected %v, got %v", expected, statuses)
+	}
+	metadata, err := client.Logical().Read("kv/metadata/c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := metadata.Data["versions"].(map[string]interface{})["1"].(map[string]interface{})
+	if version["destroyed"] != true {
+		t.Fatalf("expected the imported version to be destroyed: %#v", version)
+	}
+
+	if err := client.Sys().PutPolicy("importer", `path "secret/*" { capabilities = ["create", "read", "update"] }`); err != nil {
+		t.Fatal(err)
+	}
+	_, err = importer.Logical().Write("sys/import", map[string]interface{}{
+		"secrets": []map[string]interface{}{
+			{"path": "secret/h", "data": map[string]interface{}{"value": "h"}},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Malformed manifests are rejected
+	if _, err := client.Logical().Write("sys/import", map[string]interface{}{}); err == nil {
+		t.Fatal("expected error without secrets")
+	}
+}
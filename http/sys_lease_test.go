@@ -1,6 +1,7 @@
 package http
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
@@ -71,3 +72,113 @@ func TestSysRevokePrefix(t *testing.T) {
 	resp := testHttpPut(t, token, addr+"/v1/sys/revoke-prefix/secret/foo/1234", nil)
 	testResponseStatus(t, resp, 204)
 }
+
+func TestSysLeasesByTag(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	secrets := map[string]map[string]interface{}{
+		"foo": {"app": "checkout"},
+		"bar": {"app": "checkout", "env": "prod"},
+		"baz": {"app": "billing"},
+	}
+	leaseIDs := make(map[string]string)
+	for name, tags := range secrets {
+		resp := testHttpPut(t, token, addr+"/v1/secret/"+name, map[string]interface{}{
+			"data":       "bar",
+			"lease":      "1h",
+			"lease_tags": tags,
+		})
+		testResponseStatus(t, resp, 204)
+
+		resp = testHttpGet(t, token, addr+"/v1/secret/"+name)
+		var result struct {
+			LeaseID string `json:"lease_id"`
+		}
+		if err := jsonutil.DecodeJSONFromReader(resp.Body, &result); err != nil {
+			t.Fatal(err)
+		}
+		leaseIDs[name] = result.LeaseID
+	}
+
+	lookup := func(tags map[string]interface{}) []interface{} {
+		t.Helper()
+
+		resp := testHttpPut(t, token, addr+"/v1/sys/leases/lookup-by-tag", map[string]interface{}{
+			"tags": tags,
+		})
+		var actual map[string]interface{}
+		testResponseStatus(t, resp, 200)
+		testResponseBody(t, resp, &actual)
+		keys, _ := actual["data"].(map[string]interface{})["keys"].([]interface{})
+		return keys
+	}
+
+	keys := lookup(map[string]interface{}{"app": "checkout"})
+	if len(keys) != 2 {
+		t.Fatalf("bad: %#v", keys)
+	}
+	keys = lookup(map[string]interface{}{"app": "checkout", "env": "prod"})
+	if len(keys) != 1 || keys[0] != leaseIDs["bar"] {
+		t.Fatalf("bad: %#v", keys)
+	}
+	if keys = lookup(map[string]interface{}{"app": "unknown"}); len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/leases/lookup", map[string]interface{}{
+		"lease_id": leaseIDs["bar"],
+	})
+	var actual map[string]interface{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if tags := actual["data"].(map[string]interface{})["tags"]; !reflect.DeepEqual(tags, secrets["bar"]) {
+		t.Fatalf("bad: %#v", tags)
+	}
+
+	// Renewing a lease keeps its tags, even if the backend no longer returns
+	// them
+	resp = testHttpPut(t, token, addr+"/v1/secret/bar", map[string]interface{}{
+		"data":  "bar",
+		"lease": "1h",
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPut(t, token, addr+"/v1/sys/leases/renew", map[string]interface{}{
+		"lease_id": leaseIDs["bar"],
+	})
+	testResponseStatus(t, resp, 200)
+
+	keys = lookup(map[string]interface{}{"app": "checkout", "env": "prod"})
+	if len(keys) != 1 || keys[0] != leaseIDs["bar"] {
+		t.Fatalf("bad: %#v", keys)
+	}
+	resp = testHttpPut(t, token, addr+"/v1/sys/leases/lookup", map[string]interface{}{
+		"lease_id": leaseIDs["bar"],
+	})
+	actual = map[string]interface{}{}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if tags := actual["data"].(map[string]interface{})["tags"]; !reflect.DeepEqual(tags, secrets["bar"]) {
+		t.Fatalf("bad: %#v", tags)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/leases/revoke-by-tag", map[string]interface{}{
+		"tags": []string{"app=checkout"},
+	})
+	testResponseStatus(t, resp, 204)
+
+	if keys = lookup(map[string]interface{}{"app": "checkout"}); len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+	for name, status := range map[string]int{"foo": 400, "bar": 400, "baz": 200} {
+		resp = testHttpPut(t, token, addr+"/v1/sys/leases/lookup", map[string]interface{}{
+			"lease_id": leaseIDs[name],
+		})
+		testResponseStatus(t, resp, status)
+	}
+
+	resp = testHttpPut(t, token, addr+"/v1/sys/leases/revoke-by-tag", nil)
+	testResponseStatus(t, resp, 400)
+}
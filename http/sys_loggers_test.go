@@ -0,0 +1,119 @@
+package http
+
+import (
+	"io/ioutil"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/loggers"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysLoggers(t *testing.T) {
+	logger := log.NewInterceptLogger(&log.LoggerOptions{
+		Output: ioutil.Discard,
+	})
+	router := loggers.NewRouter(logger, &log.LoggerOptions{
+		Output: ioutil.Discard,
+		Level:  log.Info,
+	})
+
+	core, _, token := vault.TestCoreUnsealedWithConfig(t, &vault.CoreConfig{
+		Logger:    logger,
+		LogRouter: router,
+	})
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	expectLevels := func(level string, subsystems map[string]interface{}) {
+		t.Helper()
+		resp := testHttpGet(t, token, addr+"/v1/sys/loggers")
+		testResponseStatus(t, resp, 200)
+
+		var actual map[string]interface{}
+		testResponseBody(t, resp, &actual)
+		data := actual["data"].(map[string]interface{})
+		if data["level"] != level {
+			t.Fatalf("expected level %q, got %v", level, data["level"])
+		}
+		if got := data["loggers"].(map[string]interface{}); len(got) != len(subsystems) {
+			t.Fatalf("expected loggers %v, got %v", subsystems, got)
+		}
+		for subsystem, subsystemLevel := range subsystems {
+			if got := data["loggers"].(map[string]interface{})[subsystem]; got != subsystemLevel {
+				t.Fatalf("expected level %q for %q, got %v", subsystemLevel, subsystem, got)
+			}
+		}
+	}
+	expectLevels("info", nil)
+
+	resp := testHttpPost(t, token, addr+"/v1/sys/loggers/raft", map[string]interface{}{
+		"level": "debug",
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPost(t, token, addr+"/v1/sys/loggers/expiration", map[string]interface{}{
+		"level": "trace",
+	})
+	testResponseStatus(t, resp, 204)
+	expectLevels("info", map[string]interface{}{
+		"raft":       "debug",
+		"expiration": "trace",
+	})
+	if level := router.LevelFor("core.raft.autopilot"); level != log.Debug {
+		t.Fatalf("expected debug for the raft autopilot, got %s", level)
+	}
+	if !logger.Named("expiration").IsTrace() {
+		t.Fatal("expected the loggers to be at the trace level")
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/loggers/storage.raft")
+	testResponseStatus(t, resp, 200)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if level := actual["data"].(map[string]interface{})["level"]; level != "debug" {
+		t.Fatalf("expected debug for storage.raft, got %v", level)
+	}
+
+	resp = testHttpPost(t, token, addr+"/v1/sys/loggers/raft", map[string]interface{}{
+		"level": "verbose",
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpDelete(t, token, addr+"/v1/sys/loggers/expiration")
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPost(t, token, addr+"/v1/sys/loggers", map[string]interface{}{
+		"level": "warn",
+	})
+	testResponseStatus(t, resp, 204)
+	expectLevels("warn", map[string]interface{}{
+		"raft": "debug",
+	})
+	if logger.IsTrace() {
+		t.Fatal("expected the loggers to no longer be at the trace level")
+	}
+
+	resp = testHttpDelete(t, token, addr+"/v1/sys/loggers")
+	testResponseStatus(t, resp, 204)
+	expectLevels("info", nil)
+
+	// The endpoints require a root token
+	resp = testHttpPost(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"default"},
+	})
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	nonRootToken := actual["auth"].(map[string]interface{})["client_token"].(string)
+	resp = testHttpGet(t, nonRootToken, addr+"/v1/sys/loggers")
+	testResponseStatus(t, resp, 403)
+}
+
+func TestSysLoggers_NoRouter(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpGet(t, token, addr+"/v1/sys/loggers")
+	testResponseStatus(t, resp, 400)
+}
@@ -3,7 +3,9 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
@@ -47,3 +49,90 @@ func handleMetricsUnauthenticated(core *vault.Core) http.Handler {
 		}
 	})
 }
+
+// metricsStreamWriteTimeout bounds how long sending a single snapshot
+// to a stream client may take.
+const metricsStreamWriteTimeout = 10 * time.Second
+
+var metricsStreamUpgrader = websocket.Upgrader{}
+
+// handleMetricsStream performs the logical request to sys/metrics/stream,
+// so that the usual authentication and ACL checks apply, then upgrades the
+// connection to a WebSocket and sends a snapshot of the in-memory metrics
+// at the requested interval until the client disconnects.
+func handleMetricsStream(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		req, _, status, err := buildLogicalRequest(core, w, r)
+		if err != nil || status != 0 {
+			respondError(w, status, err)
+			return
+		}
+
+		resp, ok, needsForward := request(core, w, r, req)
+		switch {
+		case needsForward:
+			respondError(w, http.StatusBadRequest, vault.ErrCannotForwardLocalOnly)
+			return
+		case !ok:
+			return
+		}
+		interval := time.Duration(resp.Data["interval"].(int)) * time.Second
+
+		// On failure, Upgrade has already replied to the client.
+		conn, err := metricsStreamUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Nothing is expected from the client, but reading is needed to
+		// process control messages and notice when it goes away.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := sendMetricsSnapshot(core, conn); err != nil {
+				return
+			}
+
+			select {
+			case <-closed:
+				return
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func sendMetricsSnapshot(core *vault.Core, conn *websocket.Conn) error {
+	resp := core.MetricsHelper().ResponseForFormat("")
+	body, ok := resp.Data[logical.HTTPRawBody].([]byte)
+	if !ok || resp.Data[logical.HTTPStatusCode].(int) != http.StatusOK {
+		err := fmt.Errorf("error fetching metrics: %v", resp.Data[logical.HTTPRawBody])
+		msg := websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "error fetching metrics")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(metricsStreamWriteTimeout))
+		return err
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(metricsStreamWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, body)
+}
@@ -1,10 +1,14 @@
 package http
 
 import (
+	"encoding/json"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/gorilla/websocket"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/vault"
@@ -56,3 +60,61 @@ func TestSysMetricsUnauthenticated(t *testing.T) {
 	resp = testHttpGet(t, "", addr+"/v1/sys/metrics?format=prometheus")
 	testResponseStatus(t, resp, 200)
 }
+
+func TestSysMetricsStream(t *testing.T) {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	conf := &vault.CoreConfig{
+		BuiltinRegistry: vault.NewMockBuiltinRegistry(),
+		MetricsHelper:   metricsutil.NewMetricsHelper(inm, false),
+	}
+	core, _, token := vault.TestCoreUnsealedWithConfig(t, conf)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	wsAddr := "ws" + strings.TrimPrefix(addr, "http") + "/v1/sys/metrics/stream"
+
+	// Authentication is required
+	_, resp, err := websocket.DefaultDialer.Dial(wsAddr, nil)
+	if err == nil {
+		t.Fatal("expected error without token")
+	}
+	testResponseStatus(t, resp, 400)
+
+	// Interval is validated
+	header := http.Header{}
+	header.Set("X-Vault-Token", token)
+	_, resp, err = websocket.DefaultDialer.Dial(wsAddr+"?interval=0", header)
+	if err == nil {
+		t.Fatal("expected error with bad interval")
+	}
+	testResponseStatus(t, resp, 400)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsAddr+"?interval=1", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	inm.SetGauge([]string{"test", "gauge"}, 42)
+
+	// The first snapshot is sent immediately; the second includes the gauge.
+	for i := 0; i < 2; i++ {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgType != websocket.TextMessage {
+			t.Fatalf("bad message type %d", msgType)
+		}
+
+		var summary metrics.MetricsSummary
+		if err := json.Unmarshal(msg, &summary); err != nil {
+			t.Fatalf("bad snapshot %q: %v", msg, err)
+		}
+		if i == 1 && len(summary.Gauges) == 0 {
+			t.Fatalf("expected gauge in snapshot %q", msg)
+		}
+	}
+}
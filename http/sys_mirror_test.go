@@ -0,0 +1,149 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault"
+)
+
+func testMirrorWaitFor(t *testing.T, client *api.Client, path string, check func(*api.Secret) bool) {
+	t.Helper()
+
+	var secret *api.Secret
+	var err error
+	for i := 0; i < 100; i++ {
+		secret, err = client.Logical().Read(path)
+		if err == nil && check(secret) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q: %#v, %v", path, secret, err)
+}
+
+func TestSysMirror(t *testing.T) {
+	source := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	source.Start()
+	defer source.Cleanup()
+
+	target := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	target.Start()
+	defer target.Cleanup()
+
+	vault.TestWaitActive(t, source.Cores[0].Core)
+	vault.TestWaitActive(t, target.Cores[0].Core)
+	sourceClient := source.Cores[0].Client
+	targetClient := target.Cores[0].Client
+
+	for _, client := range []*api.Client{sourceClient, targetClient} {
+		if err := client.Sys().Mount("mirrored", &api.MountInput{Type: "kv"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Data written before the mirror is created is sent with the first
+	// snapshot
+	if _, err := sourceClient.Logical().Write("mirrored/before", map[string]interface{}{"value": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := targetClient.Logical().Write("mirrored/stale", map[string]interface{}{"value": "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := targetClient.Logical().Write("sys/mirror/targets/dr", map[string]interface{}{
+		"mount": "mirrored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	activationToken := secret.Data["activation_token"].(string)
+
+	// A target cannot be created twice, nor a mount be mirrored twice
+	if _, err := targetClient.Logical().Write("sys/mirror/targets/dr", map[string]interface{}{"mount": "mirrored"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := targetClient.Logical().Write("sys/mirror/targets/other", map[string]interface{}{"mount": "mirrored"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Only KV mounts can be mirrored
+	if _, err := sourceClient.Logical().Write("sys/mirror/sources/dr", map[string]interface{}{
+		"mount":            "cubbyhole",
+		"activation_token": activationToken,
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, err := sourceClient.Logical().Write("sys/mirror/sources/dr", map[string]interface{}{
+		"mount":            "mirrored",
+		"activation_token": activationToken,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	hasValue := func(value string) func(*api.Secret) bool {
+		return func(secret *api.Secret) bool {
+			return secret != nil && secret.Data["value"] == value
+		}
+	}
+	testMirrorWaitFor(t, targetClient, "mirrored/before", hasValue("1"))
+	testMirrorWaitFor(t, targetClient, "mirrored/stale", func(secret *api.Secret) bool {
+		return secret == nil
+	})
+
+	// The target mount is read-only
+	if _, err := targetClient.Logical().Write("mirrored/foo", map[string]interface{}{"value": "1"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Changes are streamed as they are made
+	for i := 0; i < 10; i++ {
+		if _, err := sourceClient.Logical().Write(fmt.Sprintf("mirrored/key%d", i), map[string]interface{}{"value": fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sourceClient.Logical().Delete("mirrored/before"); err != nil {
+		t.Fatal(err)
+	}
+	testMirrorWaitFor(t, targetClient, "mirrored/key9", hasValue("9"))
+	testMirrorWaitFor(t, targetClient, "mirrored/before", func(secret *api.Secret) bool {
+		return secret == nil
+	})
+
+	testMirrorWaitFor(t, sourceClient, "sys/mirror/sources/dr", func(secret *api.Secret) bool {
+		return secret != nil && secret.Data["connected"] == true && secret.Data["acked_index"] == secret.Data["last_index"]
+	})
+	secret, err = targetClient.Logical().Read("sys/mirror/targets/dr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["mount"] != "mirrored/" || secret.Data["conflicts"] != json.Number("0") {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	// Deleting the target makes the mount writable again
+	if _, err := targetClient.Logical().Delete("sys/mirror/targets/dr"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := targetClient.Logical().Write("mirrored/foo", map[string]interface{}{"value": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sourceClient.Logical().Delete("sys/mirror/sources/dr"); err != nil {
+		t.Fatal(err)
+	}
+	secret, err = sourceClient.Logical().List("sys/mirror/sources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+}
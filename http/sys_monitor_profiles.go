@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+// handleMonitorProfiles handles the requests for the profiles of the server.
+// They are served by the active node, to which standby nodes forward them,
+// unless the local parameter asks a standby node for its own profile. As a
+// standby node cannot check the token of the request, it has the active node
+// authorize the request before serving it.
+func handleMonitorProfiles(core *vault.Core) http.Handler {
+	forwarded := handleRequestForwarding(core, handleLogicalNoForward(core))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		local, _ := strconv.ParseBool(r.URL.Query().Get("local"))
+		if isStandby, _ := core.Standby(); !local || !isStandby {
+			forwarded.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+		default:
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		authReq := r.Clone(r.Context())
+		query := authReq.URL.Query()
+		query.Set("authorize_only", "true")
+		authReq.URL.RawQuery = query.Encode()
+
+		statusCode, header, retBytes, err := core.ForwardRequest(authReq)
+		if err != nil {
+			core.Logger().Error("failed to authorize profile request with the active node", "error", err)
+			respondError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		if statusCode != http.StatusOK {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statusCode)
+			w.Write(retBytes)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/v1/sys/monitor/profiles/")
+		resp, err := vault.MonitorProfile(r.Context(), name, w, r)
+		switch {
+		case err != nil:
+			respondError(w, http.StatusInternalServerError, err)
+		case resp == nil:
+			// The profile was written to the response writer
+		case resp.IsError():
+			respondError(w, http.StatusBadRequest, resp.Error())
+		default:
+			respondOk(w, logical.LogicalResponseToHTTPResponse(resp))
+		}
+	})
+}
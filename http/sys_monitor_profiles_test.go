@@ -0,0 +1,114 @@
+package http
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysMonitorProfiles(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	cores := cluster.Cores
+
+	vault.TestWaitActive(t, cores[0].Core)
+	client := cores[0].Client
+
+	secret, err := client.Logical().Read("sys/monitor/profiles/runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["num_goroutine"] == nil || secret.Data["memory"] == nil {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	secret, err = client.Logical().Read("sys/monitor/profiles/host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil || secret.Data["host"] == nil || secret.Data["fd_limits"] == nil {
+		t.Fatalf("bad: %#v", secret)
+	}
+
+	resp, err := client.RawRequest(client.NewRequest("GET", "/v1/sys/monitor/profiles/heap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) == 0 {
+		t.Fatal("expected a heap profile")
+	}
+
+	// The read capability does not give access to the profiles
+	err = client.Sys().PutPolicy("read-sys", `path "sys/*" { capabilities = ["read"] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Sys().PutPolicy("profile", `path "sys/monitor/profiles/*" { capabilities = ["profile"] }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken := func(policy string) string {
+		t.Helper()
+		secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{policy},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret.Auth.ClientToken
+	}
+	readToken := newToken("read-sys")
+	profileToken := newToken("profile")
+
+	standbyClient, err := cores[1].Client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*api.Client{client, standbyClient} {
+		for _, local := range []bool{false, true} {
+			if local && c == client {
+				continue
+			}
+			request := func(token string) (*api.Secret, error) {
+				c.SetToken(token)
+				r := c.NewRequest("GET", "/v1/sys/monitor/profiles/runtime")
+				if local {
+					r.Params.Set("local", "true")
+				}
+				resp, err := c.RawRequest(r)
+				if resp != nil {
+					defer resp.Body.Close()
+				}
+				if err != nil {
+					return nil, err
+				}
+				return api.ParseSecret(resp.Body)
+			}
+
+			_, err := request(readToken)
+			if err == nil || !strings.Contains(err.Error(), "Code: 403") {
+				t.Fatalf("expected permission denied, got %v", err)
+			}
+
+			secret, err := request(profileToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if secret == nil || secret.Data["num_goroutine"] == nil {
+				t.Fatalf("bad: %#v", secret)
+			}
+		}
+	}
+}
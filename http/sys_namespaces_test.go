@@ -0,0 +1,165 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/vault"
+)
+
+func testHttpNamespaceRequest(t *testing.T, method, token, addr, ns string, body interface{}) *http.Response {
+	t.Helper()
+
+	var bodyReader io.Reader
+	if body != nil {
+		enc, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodyReader = bytes.NewReader(enc)
+	}
+
+	req, err := http.NewRequest(method, addr, bodyReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(consts.AuthHeaderName, token)
+	req.Header.Set(consts.NamespaceHeaderName, ns)
+
+	resp, err := cleanhttp.DefaultClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestSysNamespaces(t *testing.T) {
+	core, keys, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/namespaces/team1", nil)
+	testResponseStatus(t, resp, 200)
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	nsID := actual["data"].(map[string]interface{})["id"].(string)
+	if nsID == "" || actual["data"].(map[string]interface{})["path"] != "team1/" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Creating the namespace again returns the existing one
+	resp = testHttpPut(t, token, addr+"/v1/sys/namespaces/team1", nil)
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["id"] != nsID {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/namespaces?list=true")
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if keys := actual["data"].(map[string]interface{})["keys"]; !reflect.DeepEqual(keys, []interface{}{"team1/"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Mount a secrets engine in the namespace through the header, and use it
+	// through the path
+	resp = testHttpNamespaceRequest(t, "POST", token, addr+"/v1/sys/mounts/kv", "team1", map[string]interface{}{
+		"type": "kv",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPut(t, token, addr+"/v1/team1/kv/foo", map[string]interface{}{
+		"bar": "baz",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpNamespaceRequest(t, "GET", token, addr+"/v1/kv/foo", "team1", nil)
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["bar"] != "baz" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// The mount is not visible from the root namespace
+	resp = testHttpGet(t, token, addr+"/v1/kv/foo")
+	testResponseStatus(t, resp, 404)
+
+	// Policies are scoped to the namespace
+	resp = testHttpPut(t, token, addr+"/v1/team1/sys/policies/acl/reader", map[string]interface{}{
+		"policy": `path "kv/*" { capabilities = ["read"] }`,
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpGet(t, token, addr+"/v1/sys/policies/acl/reader")
+	testResponseStatus(t, resp, 404)
+	resp = testHttpGet(t, token, addr+"/v1/team1/sys/policies/acl?list=true")
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if keys := actual["data"].(map[string]interface{})["keys"]; !reflect.DeepEqual(keys, []interface{}{"default", "reader"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	// Tokens of the namespace get the policies of the namespace
+	resp = testHttpPost(t, token, addr+"/v1/team1/auth/token/create", map[string]interface{}{
+		"policies": []string{"reader"},
+	})
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	nsToken := actual["auth"].(map[string]interface{})["client_token"].(string)
+
+	resp = testHttpNamespaceRequest(t, "GET", nsToken, addr+"/v1/kv/foo", "team1", nil)
+	testResponseStatus(t, resp, 200)
+	resp = testHttpNamespaceRequest(t, "GET", nsToken, addr+"/v1/auth/token/lookup-self", "team1", nil)
+	testResponseStatus(t, resp, 200)
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if actual["data"].(map[string]interface{})["namespace_path"] != "team1/" {
+		t.Fatalf("bad: %#v", actual)
+	}
+	resp = testHttpGet(t, nsToken, addr+"/v1/secret/foo")
+	testResponseStatus(t, resp, 403)
+
+	// The namespace and its data are loaded again after unsealing
+	if err := core.Seal(token); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, err := vault.TestCoreUnseal(core, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp = testHttpNamespaceRequest(t, "GET", nsToken, addr+"/v1/kv/foo", "team1", nil)
+	testResponseStatus(t, resp, 200)
+
+	// Server wide endpoints are only available in the root namespace
+	resp = testHttpPut(t, token, addr+"/v1/team1/sys/seal", nil)
+	testResponseStatus(t, resp, 403)
+
+	// A mount cannot shadow the namespace
+	resp = testHttpPost(t, token, addr+"/v1/sys/mounts/team1", map[string]interface{}{
+		"type": "kv",
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpDelete(t, token, addr+"/v1/sys/namespaces/team1")
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/namespaces/team1")
+	testResponseStatus(t, resp, 404)
+	resp = testHttpGet(t, token, addr+"/v1/team1/kv/foo")
+	testResponseStatus(t, resp, 404)
+	resp = testHttpGet(t, nsToken, addr+"/v1/auth/token/lookup-self")
+	testResponseStatus(t, resp, 403)
+}
@@ -2,10 +2,17 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/vault"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
 )
 
 func TestSysPolicies(t *testing.T) {
@@ -154,3 +161,476 @@ func TestSysDeletePolicy(t *testing.T) {
 		t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual, expected)
 	}
 }
+
+func TestSysTestPolicy(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo", map[string]interface{}{
+		"policy": `
+path "secret/foo" {
+	capabilities = ["read"]
+}
+
+path "secret/+/bar" {
+	capabilities = ["deny"]
+}
+
+path "secret/{{identity.entity.metadata.team}}/*" {
+	capabilities = ["create", "update"]
+	allowed_parameters = {
+		"ttl" = ["1h"]
+	}
+}
+
+path "secret/groups/{{identity.groups.names.ops.id}}" {
+	capabilities = ["read"]
+}
+`,
+	})
+	testResponseStatus(t, resp, 204)
+
+	cases := map[string]struct {
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		"exact": {
+			input: map[string]interface{}{"path": "secret/foo"},
+			expected: map[string]interface{}{
+				"allowed":      true,
+				"templated":    true,
+				"matched_path": "secret/foo",
+				"capabilities": []interface{}{"read"},
+			},
+		},
+		"denied operation": {
+			input: map[string]interface{}{"path": "secret/foo", "operation": "delete"},
+			expected: map[string]interface{}{
+				"allowed":      false,
+				"templated":    true,
+				"matched_path": "secret/foo",
+				"capabilities": []interface{}{"read"},
+			},
+		},
+		"no match": {
+			input: map[string]interface{}{"path": "secret/baz"},
+			expected: map[string]interface{}{
+				"allowed":   false,
+				"templated": true,
+			},
+		},
+		"segment wildcard": {
+			input: map[string]interface{}{"path": "secret/payments/bar", "operation": "update"},
+			expected: map[string]interface{}{
+				"allowed":      false,
+				"templated":    true,
+				"matched_path": "secret/+/bar",
+				"capabilities": []interface{}{"deny"},
+			},
+		},
+		"templated metadata": {
+			input: map[string]interface{}{
+				"path":            "secret/payments/key",
+				"operation":       "create",
+				"entity_metadata": map[string]interface{}{"team": "payments"},
+				"parameters":      map[string]interface{}{"ttl": "1h"},
+			},
+			expected: map[string]interface{}{
+				"allowed":      true,
+				"templated":    true,
+				"matched_path": "secret/payments/*",
+				"capabilities": []interface{}{"update", "create"},
+			},
+		},
+		"templated denied parameter": {
+			input: map[string]interface{}{
+				"path":            "secret/payments/key",
+				"operation":       "create",
+				"entity_metadata": map[string]interface{}{"team": "payments"},
+				"parameters":      map[string]interface{}{"ttl": "2h"},
+			},
+			expected: map[string]interface{}{
+				"allowed":      false,
+				"templated":    true,
+				"matched_path": "secret/payments/*",
+				"capabilities": []interface{}{"update", "create"},
+			},
+		},
+		"templated group": {
+			input: map[string]interface{}{
+				"path":   "secret/groups/ops",
+				"groups": "ops",
+			},
+			expected: map[string]interface{}{
+				"allowed":      true,
+				"templated":    true,
+				"matched_path": "secret/groups/ops",
+				"capabilities": []interface{}{"read"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo/test", tc.input)
+			testResponseStatus(t, resp, 200)
+
+			var actual map[string]interface{}
+			testResponseBody(t, resp, &actual)
+			if !reflect.DeepEqual(actual["data"], tc.expected) {
+				t.Fatalf("bad: got\n%#v\nexpected\n%#v\n", actual["data"], tc.expected)
+			}
+		})
+	}
+
+	// Missing policies and unsupported operations are rejected
+	resp = testHttpPut(t, token, addr+"/v1/sys/policies/acl/bar/test", map[string]interface{}{
+		"path": "secret/foo",
+	})
+	testResponseStatus(t, resp, 400)
+	resp = testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo/test", map[string]interface{}{
+		"path":      "secret/foo",
+		"operation": "sudo",
+	})
+	testResponseStatus(t, resp, 400)
+}
+
+func TestSysTestPolicy_Conditions(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// A window on a day other than today
+	closedDay := strings.ToLower(time.Now().UTC().AddDate(0, 0, 3).Weekday().String())
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo", map[string]interface{}{
+		"policy": fmt.Sprintf(`
+path "secret/cidr" {
+	capabilities = ["read"]
+	bound_cidrs = ["10.0.0.0/8", "192.168.1.1"]
+}
+
+path "secret/open" {
+	capabilities = ["read"]
+	time_window "always" {
+		start = "00:00"
+		end = "24:00"
+	}
+}
+
+path "secret/closed" {
+	capabilities = ["read"]
+	time_window "someday" {
+		days = [%q]
+	}
+}
+
+path "secret/params" {
+	capabilities = ["update"]
+	parameter_constraint "ttl" {
+		min = 60
+		max = 3600
+	}
+	parameter_constraint "name" {
+		pattern = "[a-z]+-[0-9]+"
+	}
+}
+
+path "secret/mfa" {
+	capabilities = ["read"]
+	mfa_methods = ["my_totp"]
+}
+`, closedDay),
+	})
+	testResponseStatus(t, resp, 204)
+
+	cases := map[string]struct {
+		input   map[string]interface{}
+		allowed bool
+	}{
+		"cidr in block": {
+			input:   map[string]interface{}{"path": "secret/cidr", "remote_address": "10.1.2.3"},
+			allowed: true,
+		},
+		"cidr single address": {
+			input:   map[string]interface{}{"path": "secret/cidr", "remote_address": "192.168.1.1"},
+			allowed: true,
+		},
+		"cidr outside": {
+			input:   map[string]interface{}{"path": "secret/cidr", "remote_address": "192.168.1.2"},
+			allowed: false,
+		},
+		"cidr no address": {
+			input:   map[string]interface{}{"path": "secret/cidr"},
+			allowed: false,
+		},
+		"open window": {
+			input:   map[string]interface{}{"path": "secret/open"},
+			allowed: true,
+		},
+		"closed window": {
+			input:   map[string]interface{}{"path": "secret/closed"},
+			allowed: false,
+		},
+		"params within constraints": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"ttl": 600, "name": "web-01", "other": "x"},
+			},
+			allowed: true,
+		},
+		"param string number": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"ttl": "3600"},
+			},
+			allowed: true,
+		},
+		"param above max": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"ttl": 3601},
+			},
+			allowed: false,
+		},
+		"param below min": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"ttl": 59},
+			},
+			allowed: false,
+		},
+		"param not a number": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"ttl": "1h"},
+			},
+			allowed: false,
+		},
+		"param pattern mismatch": {
+			input: map[string]interface{}{
+				"path":       "secret/params",
+				"operation":  "update",
+				"parameters": map[string]interface{}{"name": "web-01-extra"},
+			},
+			allowed: false,
+		},
+		"mfa methods": {
+			input:   map[string]interface{}{"path": "secret/mfa"},
+			allowed: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo/test", tc.input)
+			testResponseStatus(t, resp, 200)
+
+			var actual map[string]interface{}
+			testResponseBody(t, resp, &actual)
+			data := actual["data"].(map[string]interface{})
+			if data["allowed"] != tc.allowed {
+				t.Fatalf("bad: expected allowed to be %t, got %#v", tc.allowed, data)
+			}
+		})
+	}
+
+	// The MFA methods of the matching rule are returned
+	resp = testHttpPut(t, token, addr+"/v1/sys/policies/acl/foo/test", map[string]interface{}{
+		"path": "secret/mfa",
+	})
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	if methods := actual["data"].(map[string]interface{})["mfa_methods"]; !reflect.DeepEqual(methods, []interface{}{"my_totp"}) {
+		t.Fatalf("bad: mfa_methods: %#v", methods)
+	}
+
+	// Invalid conditions are rejected when writing the policy
+	resp = testHttpPut(t, token, addr+"/v1/sys/policies/acl/bar", map[string]interface{}{
+		"policy": `
+path "secret/foo" {
+	capabilities = ["read"]
+	time_window "bad" {
+		start = "25:00"
+	}
+}
+`,
+	})
+	testResponseStatus(t, resp, 400)
+}
+
+func TestPolicy_BoundCIDRs(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/policies/acl/local", map[string]interface{}{
+		"policy": `
+path "sys/policies/acl/local" {
+	capabilities = ["read"]
+	bound_cidrs = ["127.0.0.0/8"]
+}
+
+path "sys/policies/acl/remote" {
+	capabilities = ["read"]
+	bound_cidrs = ["10.0.0.0/8"]
+}
+`,
+	})
+	testResponseStatus(t, resp, 204)
+	resp = testHttpPut(t, token, addr+"/v1/sys/policies/acl/remote", map[string]interface{}{
+		"policy": `path "secret/*" { capabilities = ["read"] }`,
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpPost(t, token, addr+"/v1/auth/token/create", map[string]interface{}{
+		"policies": []string{"local"},
+	})
+	testResponseStatus(t, resp, 200)
+	var secret map[string]interface{}
+	testResponseBody(t, resp, &secret)
+	clientToken := secret["auth"].(map[string]interface{})["client_token"].(string)
+
+	resp = testHttpGet(t, clientToken, addr+"/v1/sys/policies/acl/local")
+	testResponseStatus(t, resp, 200)
+
+	resp = testHttpGet(t, clientToken, addr+"/v1/sys/policies/acl/remote")
+	testResponseStatus(t, resp, 403)
+}
+
+func TestPolicy_MFAMethods(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	config := api.DefaultConfig()
+	config.Address = addr
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+
+	if err := client.Sys().PutPolicy("mfa", `
+path "sys/policies/acl/mfa" {
+	capabilities = ["read"]
+	mfa_methods = ["my_totp"]
+}
+`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens of the role are tied to an entity through the alias
+	if _, err := client.Logical().Write("auth/token/roles/mfa", map[string]interface{}{
+		"allowed_policies":       "mfa",
+		"allowed_entity_aliases": "alice",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Write("auth/token/create/mfa", map[string]interface{}{
+		"policies":     "mfa",
+		"entity_alias": "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entityID := secret.Auth.EntityID
+	if entityID == "" {
+		t.Fatal("expected an entity ID")
+	}
+
+	userClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient.SetToken(secret.Auth.ClientToken)
+
+	// The entity is not enrolled in the method yet
+	userClient.SetMFACreds([]string{"my_totp:123456"})
+	if _, err := userClient.Sys().GetPolicy("mfa"); err == nil || !strings.Contains(err.Error(), "not enrolled") {
+		t.Fatalf("expected an enrollment error, got %v", err)
+	}
+
+	resp, err := client.Logical().Write("identity/mfa/method/totp/admin-generate", map[string]interface{}{
+		"entity_id":   entityID,
+		"method_name": "my_totp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["barcode"] == "" {
+		t.Fatal("expected a barcode")
+	}
+	key, err := otp.NewKeyFromURL(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enrolling twice is rejected
+	if _, err := client.Logical().Write("identity/mfa/method/totp/admin-generate", map[string]interface{}{
+		"entity_id":   entityID,
+		"method_name": "my_totp",
+	}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Requests without a passcode or with a wrong one are denied
+	userClient.SetMFACreds(nil)
+	if _, err := userClient.Sys().GetPolicy("mfa"); err == nil || !strings.Contains(err.Error(), "requires a passcode") {
+		t.Fatalf("expected a missing passcode error, got %v", err)
+	}
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongCode := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+	userClient.SetMFACreds([]string{"my_totp:" + wrongCode})
+	if _, err := userClient.Sys().GetPolicy("mfa"); err == nil || !strings.Contains(err.Error(), "invalid passcode") {
+		t.Fatalf("expected an invalid passcode error, got %v", err)
+	}
+
+	// A valid passcode is accepted once
+	userClient.SetMFACreds([]string{"my_totp:" + code})
+	if _, err := userClient.Sys().GetPolicy("mfa"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userClient.Sys().GetPolicy("mfa"); err == nil || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("expected a used passcode error, got %v", err)
+	}
+
+	// Once removed from the method, the entity cannot satisfy it anymore
+	if _, err := client.Logical().Write("identity/mfa/method/totp/admin-destroy", map[string]interface{}{
+		"entity_id":   entityID,
+		"method_name": "my_totp",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	code, err = totp.GenerateCode(key.Secret(), time.Now().Add(30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient.SetMFACreds([]string{"my_totp:" + code})
+	if _, err := userClient.Sys().GetPolicy("mfa"); err == nil || !strings.Contains(err.Error(), "not enrolled") {
+		t.Fatalf("expected an enrollment error, got %v", err)
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
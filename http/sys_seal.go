@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/version"
 	"github.com/hashicorp/vault/vault"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
 )
 
 func handleSysSeal(core *vault.Core) http.Handler {
@@ -175,6 +176,28 @@ func handleSysSealStatus(core *vault.Core) http.Handler {
 	})
 }
 
+func handleSysSealBackendStatus(core *vault.Core) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			respondError(w, http.StatusMethodNotAllowed, nil)
+			return
+		}
+
+		backends := core.SealBackendStatus(r.Context())
+		healthy := len(backends) > 0
+		for _, backend := range backends {
+			if !backend.Healthy {
+				healthy = false
+			}
+		}
+
+		respondOk(w, &SealBackendStatusResponse{
+			Healthy:  healthy,
+			Backends: backends,
+		})
+	})
+}
+
 func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 
@@ -222,36 +245,43 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 	progress, nonce := core.SecretProgress()
 
 	respondOk(w, &SealStatusResponse{
-		Type:         sealConfig.Type,
-		Initialized:  true,
-		Sealed:       sealed,
-		T:            sealConfig.SecretThreshold,
-		N:            sealConfig.SecretShares,
-		Progress:     progress,
-		Nonce:        nonce,
-		Version:      version.GetVersion().VersionNumber(),
-		Migration:    core.IsInSealMigration(),
-		ClusterName:  clusterName,
-		ClusterID:    clusterID,
-		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
-		StorageType:  core.StorageType(),
+		Type:            sealConfig.Type,
+		Initialized:     true,
+		Sealed:          sealed,
+		T:               sealConfig.SecretThreshold,
+		N:               sealConfig.SecretShares,
+		Progress:        progress,
+		Nonce:           nonce,
+		Version:         version.GetVersion().VersionNumber(),
+		Migration:       core.IsInSealMigration(),
+		MigrationStatus: core.SealMigrationStatus(),
+		ClusterName:     clusterName,
+		ClusterID:       clusterID,
+		RecoverySeal:    core.SealAccess().RecoveryKeySupported(),
+		StorageType:     core.StorageType(),
 	})
 }
 
 type SealStatusResponse struct {
-	Type         string `json:"type"`
-	Initialized  bool   `json:"initialized"`
-	Sealed       bool   `json:"sealed"`
-	T            int    `json:"t"`
-	N            int    `json:"n"`
-	Progress     int    `json:"progress"`
-	Nonce        string `json:"nonce"`
-	Version      string `json:"version"`
-	Migration    bool   `json:"migration"`
-	ClusterName  string `json:"cluster_name,omitempty"`
-	ClusterID    string `json:"cluster_id,omitempty"`
-	RecoverySeal bool   `json:"recovery_seal"`
-	StorageType  string `json:"storage_type,omitempty"`
+	Type            string                     `json:"type"`
+	Initialized     bool                       `json:"initialized"`
+	Sealed          bool                       `json:"sealed"`
+	T               int                        `json:"t"`
+	N               int                        `json:"n"`
+	Progress        int                        `json:"progress"`
+	Nonce           string                     `json:"nonce"`
+	Version         string                     `json:"version"`
+	Migration       bool                       `json:"migration"`
+	MigrationStatus *vault.SealMigrationStatus `json:"migration_status,omitempty"`
+	ClusterName     string                     `json:"cluster_name,omitempty"`
+	ClusterID       string                     `json:"cluster_id,omitempty"`
+	RecoverySeal    bool                       `json:"recovery_seal"`
+	StorageType     string                     `json:"storage_type,omitempty"`
+}
+
+type SealBackendStatusResponse struct {
+	Healthy  bool                           `json:"healthy"`
+	Backends []*vaultseal.SealBackendStatus `json:"backends"`
 }
 
 // Note: because we didn't provide explicit tagging in the past we can't do it
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 	"github.com/hashicorp/vault/helper/namespace"
@@ -58,6 +59,35 @@ func TestSysSealStatus(t *testing.T) {
 	}
 }
 
+func TestSysSealBackendStatus(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+
+	resp, err := http.Get(addr + "/v1/sys/seal-backend-status")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var actual map[string]interface{}
+	expected := map[string]interface{}{
+		"healthy": true,
+		"backends": []interface{}{
+			map[string]interface{}{
+				"name":     "shamir",
+				"type":     "shamir",
+				"priority": json.Number("1"),
+				"healthy":  true,
+			},
+		},
+	}
+	testResponseStatus(t, resp, 200)
+	testResponseBody(t, resp, &actual)
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
 func TestSysSealStatus_uninit(t *testing.T) {
 	core := vault.TestCore(t)
 	ln, addr := TestServer(t, core)
@@ -388,3 +418,56 @@ func TestSysStepDown(t *testing.T) {
 	resp := testHttpPut(t, token, addr+"/v1/sys/step-down", nil)
 	testResponseStatus(t, resp, 204)
 }
+
+func TestSysSeal_drain(t *testing.T) {
+	core, keys, token := vault.TestCoreUnsealedWithConfig(t, &vault.CoreConfig{
+		DrainGracePeriod: 2 * time.Second,
+	})
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	resp := testHttpPut(t, token, addr+"/v1/sys/seal", nil)
+	testResponseStatus(t, resp, 204)
+
+	resp, err := http.Get(addr + "/v1/sys/health?drainingcode=299")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var health map[string]interface{}
+	testResponseStatus(t, resp, 299)
+	testResponseBody(t, resp, &health)
+	if health["draining"] != true || health["drain_reason"] != vault.DrainReasonSeal {
+		t.Fatalf("bad: %#v", health)
+	}
+
+	// Requests are held until the node is unsealed again
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		for _, key := range keys {
+			if _, err := vault.TestCoreUnseal(core, key); err != nil {
+				t.Errorf("unseal err: %s", err)
+			}
+		}
+	}()
+	resp = testHttpGet(t, token, addr+"/v1/sys/mounts")
+	testResponseStatus(t, resp, 200)
+
+	// Requests fail with a retry hint once the grace period runs out
+	resp = testHttpPut(t, token, addr+"/v1/sys/seal", nil)
+	testResponseStatus(t, resp, 204)
+
+	start := time.Now()
+	resp = testHttpGet(t, token, addr+"/v1/sys/mounts")
+	testResponseStatus(t, resp, 503)
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("request was not held: %s", elapsed)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "2" {
+		t.Fatalf("bad Retry-After: %q", retryAfter)
+	}
+
+	if status := core.DrainStatus(); status.Draining {
+		t.Fatalf("still draining: %#v", status)
+	}
+}
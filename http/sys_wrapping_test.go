@@ -352,3 +352,122 @@ func TestHTTP_Wrapping(t *testing.T) {
 		t.Fatalf("secret data did not match expected: %#v", secret.Data)
 	}
 }
+
+func TestHTTP_Wrapping_Restrictions(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{}, &vault.TestClusterOptions{
+		HandlerFunc: Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	core := cluster.Cores[0].Core
+	vault.TestWaitActive(t, core)
+
+	client := cluster.Cores[0].Client
+	client.SetToken(cluster.RootToken)
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		if path == "sys/wrapping/wrap" {
+			return "5m"
+		}
+		return api.DefaultWrappingLookupFunc(operation, path)
+	})
+
+	_, err := client.Logical().Write("auth/token/roles/test", map[string]interface{}{
+		"allowed_entity_aliases": "alice,bob",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create clients for two tokens with different entities
+	entityClient := func(alias string) (*api.Client, string) {
+		secret, err := client.Logical().Write("auth/token/create/test", map[string]interface{}{
+			"entity_alias": alias,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		entityClient, err := client.Clone()
+		if err != nil {
+			t.Fatal(err)
+		}
+		entityClient.SetToken(secret.Auth.ClientToken)
+		return entityClient, secret.Auth.EntityID
+	}
+	alice, aliceEntityID := entityClient("alice")
+	bob, _ := entityClient("bob")
+
+	secret, err := client.Logical().Write("sys/wrapping/wrap", map[string]interface{}{
+		"foo":                     "bar",
+		"wrap_num_uses":           2,
+		"wrap_allowed_entity_ids": aliceEntityID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapToken := secret.WrapInfo.Token
+
+	secret, err = client.Logical().Write("sys/wrapping/lookup", map[string]interface{}{
+		"token": wrapToken,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["num_uses"].(json.Number).String() != "2" || secret.Data["remaining_uses"].(json.Number).String() != "2" {
+		t.Fatalf("bad lookup: %#v", secret.Data)
+	}
+
+	// Neither other entities nor the token itself can unwrap it, and doing
+	// so does not use it up
+	if _, err := bob.Logical().Unwrap(wrapToken); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := client.Logical().Unwrap(wrapToken); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := bob.Logical().Write("sys/wrapping/rewrap", map[string]interface{}{
+		"token": wrapToken,
+	}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	for i := 0; i < 2; i++ {
+		secret, err = alice.Logical().Unwrap(wrapToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(secret.Data, map[string]interface{}{"foo": "bar"}) {
+			t.Fatalf("secret data did not match expected: %#v", secret.Data)
+		}
+	}
+	if _, err := alice.Logical().Unwrap(wrapToken); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Server-side limits
+	_, err = client.Logical().Write("sys/config/wrapping", map[string]interface{}{
+		"max_payload_size":           20,
+		"max_num_uses":               1,
+		"require_unwrap_constraints": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []map[string]interface{}{
+		{"foo": "bar"},
+		{"foo": "bar", "wrap_allowed_entity_ids": "unknown"},
+		{"foo": "bar", "wrap_allowed_entity_ids": aliceEntityID, "wrap_num_uses": 2},
+		{"foo": "a payload too large", "wrap_allowed_entity_ids": aliceEntityID},
+	} {
+		if _, err := client.Logical().Write("sys/wrapping/wrap", data); err == nil {
+			t.Fatalf("expected error wrapping %#v", data)
+		}
+	}
+	_, err = client.Logical().Write("sys/wrapping/wrap", map[string]interface{}{
+		"foo":                     "bar",
+		"wrap_allowed_entity_ids": aliceEntityID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,14 +2,31 @@ package http
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/vault"
 )
 
 var (
 	adjustRequest = func(c *vault.Core, r *http.Request) (*http.Request, int) {
-		return r.WithContext(namespace.ContextWithNamespace(r.Context(), namespace.RootNamespace)), 0
+		// A namespace given in the header is folded into the path, so that
+		// both ways of addressing a namespace are handled the same. The
+		// header is removed so it is not applied again if the request is
+		// forwarded.
+		if header := r.Header.Get(consts.NamespaceHeaderName); header != "" {
+			r.Header.Del(consts.NamespaceHeaderName)
+			if nsPath := namespace.Canonicalize(strings.Trim(header, "/")); nsPath != "" {
+				r.URL.Path = "/v1/" + nsPath + strings.TrimPrefix(r.URL.Path, "/v1/")
+				if r.URL.RawPath != "" {
+					r.URL.RawPath = "/v1/" + nsPath + strings.TrimPrefix(r.URL.RawPath, "/v1/")
+				}
+			}
+		}
+
+		ns := c.NamespaceByPath(strings.TrimPrefix(r.URL.Path, "/v1/"))
+		return r.WithContext(namespace.ContextWithNamespace(r.Context(), ns)), 0
 	}
 
 	genericWrapping = func(core *vault.Core, in http.Handler, props *vault.HandlerProperties) http.Handler {
@@ -179,6 +179,8 @@ func (c *SharedConfig) Sanitized() map[string]interface{} {
 			cleanSeal := map[string]interface{}{
 				"type":     s.Type,
 				"disabled": s.Disabled,
+				"name":     s.Name,
+				"priority": s.Priority,
 			}
 			sanitizedSeals = append(sanitizedSeals, cleanSeal)
 		}
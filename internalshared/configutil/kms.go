@@ -50,6 +50,11 @@ type KMS struct {
 
 	Disabled bool
 	Config   map[string]string
+
+	// Name and Priority identify and order the seals when several are
+	// enabled; Priority is 0 when not configured
+	Name     string
+	Priority int
 }
 
 func (k *KMS) GoString() string {
@@ -97,6 +102,25 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			delete(m, "disabled")
 		}
 
+		var name string
+		if v, ok := m["name"]; ok {
+			if name, ok = v.(string); !ok {
+				return multierror.Prefix(fmt.Errorf("unable to parse 'name' in kms type %q: value could not be parsed as string", key), fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			delete(m, "name")
+		}
+
+		var priority int64
+		if v, ok := m["priority"]; ok {
+			if priority, err = parseutil.ParseInt(v); err != nil {
+				return multierror.Prefix(fmt.Errorf("unable to parse 'priority' in kms type %q: %w", key, err), fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			if priority < 1 {
+				return multierror.Prefix(fmt.Errorf("'priority' in kms type %q must be at least 1", key), fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			delete(m, "priority")
+		}
+
 		strMap := make(map[string]string, len(m))
 		for k, v := range m {
 			if vs, ok := v.(string); ok {
@@ -110,6 +134,8 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			Type:     strings.ToLower(key),
 			Purpose:  purpose,
 			Disabled: disabled,
+			Name:     name,
+			Priority: int(priority),
 		}
 		if len(strMap) > 0 {
 			seal.Config = strMap
@@ -79,6 +79,8 @@ type Listener struct {
 	SocketUser  string `hcl:"socket_user"`
 	SocketGroup string `hcl:"socket_group"`
 
+	PipeSecurityDescriptor string `hcl:"pipe_security_descriptor"`
+
 	Telemetry ListenerTelemetry `hcl:"telemetry"`
 
 	// RandomPort is used only for some testing purposes
@@ -123,7 +125,7 @@ func ParseListeners(result *SharedConfig, list *ast.ObjectList) error {
 
 			l.Type = strings.ToLower(l.Type)
 			switch l.Type {
-			case "tcp", "unix":
+			case "tcp", "unix", "pipe":
 			default:
 				return multierror.Prefix(fmt.Errorf("unsupported listener type %q", l.Type), fmt.Sprintf("listeners.%d:", i))
 			}
@@ -14,12 +14,14 @@ import (
 	stackdriver "github.com/google/go-metrics-stackdriver"
 	stackdrivervault "github.com/google/go-metrics-stackdriver/vault"
 	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/mitchellh/cli"
+	promclient "github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/api/option"
 )
 
@@ -40,7 +42,15 @@ type Telemetry struct {
 	UsageGaugePeriod    time.Duration
 	UsageGaugePeriodRaw interface{} `hcl:"usage_gauge_period"`
 
-	MaximumGaugeCardinality int `hcl:"maximum_gauge_cardinality"`
+	MaximumGaugeCardinality int  `hcl:"maximum_gauge_cardinality"`
+	AggregateGaugeOverflow  bool `hcl:"aggregate_gauge_overflow"`
+
+	// NamespaceLabels attaches a namespace label, derived from the request,
+	// to request-related metrics. NamespaceLabelAllowlist limits the
+	// namespaces that are reported individually; others are reported
+	// as "other".
+	NamespaceLabels         bool     `hcl:"namespace_labels"`
+	NamespaceLabelAllowlist []string `hcl:"namespace_label_allowlist"`
 
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
@@ -127,6 +137,10 @@ type Telemetry struct {
 	// Default: 24h
 	PrometheusRetentionTime    time.Duration `hcl:"-"`
 	PrometheusRetentionTimeRaw interface{}   `hcl:"prometheus_retention_time"`
+	// HistogramBuckets sets the upper bounds of the histogram buckets,
+	// in milliseconds, for each metric reported as a Prometheus histogram,
+	// keyed by metric name such as "core.handle_request".
+	HistogramBuckets map[string][]float64 `hcl:"histogram_buckets"`
 
 	// Stackdriver:
 	// StackdriverProjectID is the project to publish stackdriver metrics to.
@@ -137,6 +151,21 @@ type Telemetry struct {
 	StackdriverNamespace string `hcl:"stackdriver_namespace"`
 	// StackdriverDebugLogs will write additional stackdriver related debug logs to stderr.
 	StackdriverDebugLogs bool `hcl:"stackdriver_debug_logs"`
+
+	// OpenTelemetry:
+	// OTLPEndpoint is the URL of an OTLP/HTTP metrics receiver, such as
+	// "http://localhost:4318/v1/metrics". If provided, metrics will be
+	// pushed to that collector.
+	OTLPEndpoint string `hcl:"otlp_endpoint"`
+	// OTLPHeaders are additional HTTP headers sent with each export.
+	OTLPHeaders map[string]string `hcl:"otlp_headers"`
+	// OTLPExportInterval is the time between exports.
+	// Default: 10s
+	OTLPExportInterval    time.Duration `hcl:"-"`
+	OTLPExportIntervalRaw interface{}   `hcl:"otlp_export_interval"`
+	// OTLPNodeID is reported as the service.instance.id resource attribute.
+	// Default: hostname
+	OTLPNodeID string `hcl:"otlp_node_id"`
 }
 
 func (t *Telemetry) GoString() string {
@@ -174,6 +203,12 @@ func parseTelemetry(result *SharedConfig, list *ast.ObjectList) error {
 		result.Telemetry.PrometheusRetentionTime = PrometheusDefaultRetentionTime
 	}
 
+	for name, buckets := range result.Telemetry.HistogramBuckets {
+		if err := metricsutil.ValidateHistogramBuckets(buckets); err != nil {
+			return fmt.Errorf("telemetry: invalid histogram_buckets for %q: %w", name, err)
+		}
+	}
+
 	if result.Telemetry.UsageGaugePeriodRaw != nil {
 		if result.Telemetry.UsageGaugePeriodRaw == "none" {
 			result.Telemetry.UsageGaugePeriod = 0
@@ -188,6 +223,14 @@ func parseTelemetry(result *SharedConfig, list *ast.ObjectList) error {
 		result.Telemetry.UsageGaugePeriod = UsageGaugeDefaultPeriod
 	}
 
+	if result.Telemetry.OTLPExportIntervalRaw != nil {
+		var err error
+		if result.Telemetry.OTLPExportInterval, err = parseutil.ParseDurationSecond(result.Telemetry.OTLPExportIntervalRaw); err != nil {
+			return err
+		}
+		result.Telemetry.OTLPExportIntervalRaw = nil
+	}
+
 	if result.Telemetry.MaximumGaugeCardinality == 0 {
 		result.Telemetry.MaximumGaugeCardinality = MaximumGaugeCardinalityDefault
 	}
@@ -202,6 +245,7 @@ type SetupTelemetryOpts struct {
 	DisplayName string
 	UserAgent   string
 	ClusterName string
+	Logger      log.Logger
 }
 
 // SetupTelemetry is used to setup the telemetry sub-systems and returns the
@@ -233,6 +277,8 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 	// Configure the statsite sink
 	var fanout metrics.FanoutSink
 	var prometheusEnabled bool
+	var histograms *metricsutil.HistogramSink
+	var otlp *metricsutil.OTLPSink
 
 	// Configure the Prometheus sink
 	if opts.Config.PrometheusRetentionTime != 0 {
@@ -246,6 +292,12 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 			return nil, nil, false, err
 		}
 		fanout = append(fanout, sink)
+
+		// Request latencies are also reported as histograms.
+		histograms = metricsutil.NewHistogramSink(opts.ServiceName, opts.Config.HistogramBuckets)
+		if err := promclient.Register(histograms); err != nil {
+			return nil, nil, false, err
+		}
 	}
 
 	if opts.Config.StatsiteAddr != "" {
@@ -334,6 +386,32 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 		fanout = append(fanout, sink)
 	}
 
+	// Configure the OpenTelemetry sink
+	if opts.Config.OTLPEndpoint != "" {
+		nodeID := opts.Config.OTLPNodeID
+		if nodeID == "" {
+			nodeID = metricsConf.HostName
+		}
+
+		sink, err := metricsutil.NewOTLPSink(metricsutil.OTLPSinkConfig{
+			Endpoint:       opts.Config.OTLPEndpoint,
+			Headers:        opts.Config.OTLPHeaders,
+			ExportInterval: opts.Config.OTLPExportInterval,
+			ServiceName:    opts.ServiceName,
+			ClusterName:    opts.ClusterName,
+			NodeID:         nodeID,
+			Logger:         opts.Logger,
+
+			MaxGaugeCardinality: opts.Config.MaximumGaugeCardinality,
+		})
+		if err != nil {
+			return nil, nil, false, errwrap.Wrapf("failed to start OTLP sink: {{err}}", err)
+		}
+		sink.Start()
+		otlp = sink
+		fanout = append(fanout, sink)
+	}
+
 	// Initialize the global sink
 	if len(fanout) > 1 {
 		// Hostname enabled will create poor quality metrics name for prometheus
@@ -355,6 +433,11 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 	wrapper := metricsutil.NewClusterMetricSink(opts.ClusterName, globalMetrics)
 	wrapper.MaxGaugeCardinality = opts.Config.MaximumGaugeCardinality
 	wrapper.GaugeInterval = opts.Config.UsageGaugePeriod
+	wrapper.Histograms = histograms
+	wrapper.OTLP = otlp
+	wrapper.AggregateGaugeOverflow = opts.Config.AggregateGaugeOverflow
+	wrapper.NamespaceLabels = opts.Config.NamespaceLabels
+	wrapper.NamespaceAllowlist = opts.Config.NamespaceLabelAllowlist
 
 	return inm, wrapper, prometheusEnabled, nil
 }
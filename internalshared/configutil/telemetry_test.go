@@ -0,0 +1,36 @@
+package configutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTelemetry_HistogramBuckets(t *testing.T) {
+	config, err := ParseConfig(`
+telemetry {
+  prometheus_retention_time = "30s"
+  histogram_buckets {
+    "core.handle_request" = [1, 10, 100]
+  }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]float64{
+		"core.handle_request": {1, 10, 100},
+	}
+	if !reflect.DeepEqual(config.Telemetry.HistogramBuckets, expected) {
+		t.Fatalf("got %#v, expected %#v", config.Telemetry.HistogramBuckets, expected)
+	}
+
+	_, err = ParseConfig(`
+telemetry {
+  histogram_buckets {
+    "core.handle_request" = [10, 1]
+  }
+}`)
+	if err == nil {
+		t.Fatal("expected error for unsorted buckets")
+	}
+}
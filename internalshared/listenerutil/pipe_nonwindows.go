@@ -0,0 +1,13 @@
+// +build !windows
+
+package listenerutil
+
+import (
+	"errors"
+	"net"
+)
+
+// NamedPipeListener is only supported on Windows.
+func NamedPipeListener(path, securityDescriptor string) (net.Listener, error) {
+	return nil, errors.New("named pipe listeners are only supported on Windows")
+}
@@ -0,0 +1,19 @@
+// +build windows
+
+package listenerutil
+
+import (
+	"net"
+
+	winio "github.com/Microsoft/go-winio"
+)
+
+// NamedPipeListener listens on the Windows named pipe at the given path, e.g.
+// \\.\pipe\vault-agent. The security descriptor, in SDDL format, restricts
+// which users may connect; if empty, the default descriptor of the named
+// pipe applies.
+func NamedPipeListener(path, securityDescriptor string) (net.Listener, error) {
+	return winio.ListenPipe(path, &winio.PipeConfig{
+		SecurityDescriptor: securityDescriptor,
+	})
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/storageplugin"
+)
+
+const (
+	// pluginConfigPrefix prefixes the keys configuring the plugin itself,
+	// which are not passed to it
+	pluginConfigPrefix = "plugin_"
+)
+
+// NewPluginBackend runs the storage backend plugin at plugin_path, and passes
+// it the rest of the configuration of the storage stanza.
+func NewPluginBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	path := conf["plugin_path"]
+	if path == "" {
+		return nil, errors.New("'plugin_path' must be set")
+	}
+	if !filepath.IsAbs(path) {
+		return nil, errors.New("'plugin_path' must be an absolute path")
+	}
+
+	var checksum []byte
+	if sum := conf["plugin_sha256"]; sum != "" {
+		var err error
+		checksum, err = hex.DecodeString(sum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode 'plugin_sha256': %w", err)
+		}
+	}
+
+	var args []string
+	if argsRaw := conf["plugin_args"]; argsRaw != "" {
+		args = strings.Fields(argsRaw)
+	}
+
+	pluginConf := make(map[string]string, len(conf))
+	for k, v := range conf {
+		if !strings.HasPrefix(k, pluginConfigPrefix) {
+			pluginConf[k] = v
+		}
+	}
+
+	b, err := storageplugin.NewBackend(context.Background(), exec.Command(path, args...), checksum, pluginConf, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start storage plugin: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
+	"github.com/hashicorp/vault/sdk/physical/storageplugin"
+)
+
+func TestNewPluginBackend_Config(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+	for name, conf := range map[string]map[string]string{
+		"no path":       {},
+		"relative path": {"plugin_path": "bin/vault-storage"},
+		"bad sha256":    {"plugin_path": "/bin/vault-storage", "plugin_sha256": "xyz"},
+	} {
+		if _, err := NewPluginBackend(conf, logger); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestNewPluginBackend(t *testing.T) {
+	os.Setenv(helperProcessEnv, "1")
+	defer os.Unsetenv(helperProcessEnv)
+
+	logger := logging.NewVaultLogger(log.Debug)
+	b, err := NewPluginBackend(map[string]string{
+		"plugin_path": os.Args[0],
+		"plugin_args": "-test.run=TestPluginHelperProcess",
+		"path":        "unused",
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.(*storageplugin.Backend).Close()
+
+	physical.ExerciseBackend(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+}
+
+// TestPluginHelperProcess serves an in-memory storage plugin when run by
+// TestNewPluginBackend
+func TestPluginHelperProcess(t *testing.T) {
+	if os.Getenv(helperProcessEnv) != "1" {
+		return
+	}
+
+	factory := func(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+		if _, ok := conf["plugin_path"]; ok {
+			return nil, errors.New("the configuration of the plugin itself should not be passed to it")
+		}
+		if conf["path"] != "unused" {
+			return nil, errors.New("the configuration of the backend should be passed to it")
+		}
+		return inmem.NewInmemHA(conf, logger)
+	}
+	if err := storageplugin.Serve(&storageplugin.ServeOpts{Factory: factory}); err != nil {
+		t.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+const helperProcessEnv = "VAULT_TEST_STORAGE_PLUGIN_HELPER"
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/physical"
 
 	log "github.com/hashicorp/go-hclog"
@@ -27,7 +28,8 @@ const (
 	// database clock.
 	PostgreSQLLockTTLSeconds = 15
 
-	// The amount of time to wait between the lock renewals
+	// The amount of time to wait between the lock renewals with the default
+	// TTL; renewals happen every third of the configured ha_lock_ttl.
 	PostgreSQLLockRenewInterval = 5 * time.Second
 
 	// PostgreSQLLockRetryInterval is the amount of time to wait
@@ -62,6 +64,7 @@ type PostgreSQLBackend struct {
 	haDeleteLockExec         string
 
 	haEnabled  bool
+	haLockTTL  int
 	logger     log.Logger
 	permitPool *physical.PermitPool
 }
@@ -73,7 +76,8 @@ type PostgreSQLLock struct {
 	identity   string
 	lock       sync.Mutex
 
-	renewTicker *time.Ticker
+	// renewStopCh stops the renewals of the lock once it is released
+	renewStopCh chan struct{}
 
 	// ttlSeconds is how long a lock is valid for
 	ttlSeconds int
@@ -115,6 +119,18 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		maxParInt = physical.DefaultParallelOperations
 	}
 
+	haLockTTL := PostgreSQLLockTTLSeconds
+	if haLockTTLStr, ok := conf["ha_lock_ttl"]; ok {
+		ttl, err := parseutil.ParseDurationSecond(haLockTTLStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing ha_lock_ttl parameter: {{err}}", err)
+		}
+		if ttl < 3*time.Second {
+			return nil, fmt.Errorf("ha_lock_ttl must be at least 3s")
+		}
+		haLockTTL = int(ttl.Seconds())
+	}
+
 	maxIdleConnsStr, maxIdleConnsIsSet := conf["max_idle_connections"]
 	var maxIdleConns int
 	if maxIdleConnsIsSet {
@@ -193,6 +209,7 @@ func NewPostgreSQLBackend(conf map[string]string, logger log.Logger) (physical.B
 		logger:     logger,
 		permitPool: physical.NewPermitPool(maxParInt),
 		haEnabled:  conf["ha_enabled"] == "true",
+		haLockTTL:  haLockTTL,
 	}
 
 	return m, nil
@@ -331,8 +348,8 @@ func (p *PostgreSQLBackend) LockWith(key, value string) (physical.Lock, error) {
 		key:           key,
 		value:         value,
 		identity:      identity,
-		ttlSeconds:    PostgreSQLLockTTLSeconds,
-		renewInterval: PostgreSQLLockRenewInterval,
+		ttlSeconds:    p.haLockTTL,
+		renewInterval: time.Duration(p.haLockTTL) * time.Second / 3,
 		retryInterval: PostgreSQLLockRetryInterval,
 	}, nil
 }
@@ -352,7 +369,7 @@ func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 
 	var (
 		success = make(chan struct{})
-		errors  = make(chan error)
+		errors  = make(chan error, 1)
 		leader  = make(chan struct{})
 	)
 	// try to acquire the lock asynchronously
@@ -361,8 +378,8 @@ func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 	select {
 	case <-success:
 		// after acquiring it successfully, we must renew the lock periodically
-		l.renewTicker = time.NewTicker(l.renewInterval)
-		go l.periodicallyRenewLock(leader)
+		l.renewStopCh = make(chan struct{})
+		go l.periodicallyRenewLock(leader, l.renewStopCh, time.Now())
 	case err := <-errors:
 		return nil, err
 	case <-stopCh:
@@ -375,14 +392,17 @@ func (l *PostgreSQLLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
 // Unlock releases the lock by deleting the lock record from the
 // PostgreSQL table.
 func (l *PostgreSQLLock) Unlock() error {
+	l.lock.Lock()
+	if l.renewStopCh != nil {
+		close(l.renewStopCh)
+		l.renewStopCh = nil
+	}
+	l.lock.Unlock()
+
 	pg := l.backend
 	pg.permitPool.Acquire()
 	defer pg.permitPool.Release()
 
-	if l.renewTicker != nil {
-		l.renewTicker.Stop()
-	}
-
 	// Delete lock owned by me
 	_, err := pg.client.Exec(pg.haDeleteLockExec, l.identity, l.key)
 	return err
@@ -435,13 +455,39 @@ func (l *PostgreSQLLock) tryToLock(stop <-chan struct{}, success chan struct{},
 	}
 }
 
-func (l *PostgreSQLLock) periodicallyRenewLock(done chan struct{}) {
-	for range l.renewTicker.C {
-		gotlock, err := l.writeItem()
-		if err != nil || !gotlock {
-			close(done)
-			l.renewTicker.Stop()
+// periodicallyRenewLock renews the lock every `renewInterval` until stopCh is
+// closed. The done channel is closed once another instance has taken the lock,
+// or once renewals kept failing until the lock may have expired, so that
+// another instance can steal it without two of them considering themselves
+// leader.
+func (l *PostgreSQLLock) periodicallyRenewLock(done, stopCh chan struct{}, renewed time.Time) {
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	ttl := time.Duration(l.ttlSeconds) * time.Second
+	for {
+		select {
+		case <-stopCh:
 			return
+		case <-ticker.C:
+			// The lock expires ttl after the start of the last successful
+			// renewal at the latest, according to the clock of the database
+			attempt := time.Now()
+			gotlock, err := l.writeItem()
+			switch {
+			case err == nil && gotlock:
+				renewed = attempt
+			case err == nil:
+				l.backend.logger.Warn("lost HA lock to another instance", "key", l.key)
+				close(done)
+				return
+			case time.Since(renewed)+l.renewInterval >= ttl:
+				l.backend.logger.Error("failed to renew HA lock before its expiry", "key", l.key, "error", err)
+				close(done)
+				return
+			default:
+				l.backend.logger.Warn("failed to renew HA lock, retrying", "key", l.key, "error", err)
+			}
 		}
 	}
 }
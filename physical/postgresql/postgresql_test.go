@@ -3,6 +3,7 @@ package postgresql
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -113,6 +114,27 @@ func TestPostgreSQLBackendMaxIdleConnectionsParameter(t *testing.T) {
 	}
 }
 
+func TestPostgreSQLBackendHALockTTLParameter(t *testing.T) {
+	// The message of the error parsing the duration depends on the version
+	// of Go, so only its prefix is checked
+	for value, expectedErrPrefix := range map[string]string{
+		"bad param": "failed parsing ha_lock_ttl parameter: ",
+		"1s":        "ha_lock_ttl must be at least 3s",
+	} {
+		_, err := NewPostgreSQLBackend(map[string]string{
+			"connection_url": "some connection url",
+			"ha_lock_ttl":    value,
+		}, logging.NewVaultLogger(log.Debug))
+		if err == nil {
+			t.Errorf("Expected invalid ha_lock_ttl %q to return error", value)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), expectedErrPrefix) {
+			t.Errorf("Expected prefix: \"%s\" but found \"%s\"", expectedErrPrefix, err.Error())
+		}
+	}
+}
+
 func TestConnectionURL(t *testing.T) {
 	type input struct {
 		envar string
@@ -805,6 +805,23 @@ func (b *RaftBackend) AddPeer(ctx context.Context, peerID, clusterAddr string) e
 	return future.Error()
 }
 
+// AddNonVoter adds a new server to the raft cluster without a vote. The
+// server receives the raft log but does not count towards quorum until it is
+// promoted with AddPeer.
+func (b *RaftBackend) AddNonVoter(ctx context.Context, peerID, clusterAddr string) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return errors.New("raft storage is not initialized")
+	}
+
+	b.logger.Debug("adding raft non-voter", "node_id", peerID, "cluster_addr", clusterAddr)
+
+	future := b.raft.AddNonvoter(raft.ServerID(peerID), raft.ServerAddress(clusterAddr), 0, 0)
+	return future.Error()
+}
+
 // Peers returns all the servers present in the raft cluster
 func (b *RaftBackend) Peers(ctx context.Context) ([]Peer, error) {
 	b.l.RLock()
@@ -837,19 +854,7 @@ func (b *RaftBackend) Snapshot(out *logical.HTTPResponseWriter, access *seal.Acc
 	b.l.RLock()
 	defer b.l.RUnlock()
 
-	if b.raft == nil {
-		return errors.New("raft storage backend is sealed")
-	}
-
-	// If we have access to the seal create a sealer object
-	var s snapshot.Sealer
-	if access != nil {
-		s = &sealer{
-			access: access,
-		}
-	}
-
-	snap, err := snapshot.NewWithSealer(b.logger.Named("snapshot"), b.raft, s)
+	snap, err := b.newSnapshot(access)
 	if err != nil {
 		return err
 	}
@@ -871,6 +876,39 @@ func (b *RaftBackend) Snapshot(out *logical.HTTPResponseWriter, access *seal.Acc
 	return nil
 }
 
+// WriteSnapshot writes a snapshot archive to the provided writer, as Snapshot
+// does for HTTP responses.
+func (b *RaftBackend) WriteSnapshot(out io.Writer, access *seal.Access) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	snap, err := b.newSnapshot(access)
+	if err != nil {
+		return err
+	}
+	defer snap.Close()
+
+	_, err = io.Copy(out, snap)
+	return err
+}
+
+// newSnapshot must be called with the lock held
+func (b *RaftBackend) newSnapshot(access *seal.Access) (*snapshot.Snapshot, error) {
+	if b.raft == nil {
+		return nil, errors.New("raft storage backend is sealed")
+	}
+
+	// If we have access to the seal create a sealer object
+	var s snapshot.Sealer
+	if access != nil {
+		s = &sealer{
+			access: access,
+		}
+	}
+
+	return snapshot.NewWithSealer(b.logger.Named("snapshot"), b.raft, s)
+}
+
 // WriteSnapshotToTemp reads a snapshot archive off the provided reader,
 // extracts the data and writes the snapshot to a temporary file. The seal
 // access is used to decrypt the SHASUM file in the archive to ensure this
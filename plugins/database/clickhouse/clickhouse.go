@@ -0,0 +1,253 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"github.com/hashicorp/vault/sdk/database/helper/credsutil"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+)
+
+const (
+	defaultClickhouseRevocationStmts = `
+		DROP USER IF EXISTS '{{name}}';
+	`
+
+	defaultClickhouseRotateCredentialsSQL = `
+		ALTER USER '{{username}}' IDENTIFIED WITH sha256_password BY '{{password}}';
+	`
+
+	clickhouseTypeName = "clickhouse"
+)
+
+var _ dbplugin.Database = (*Clickhouse)(nil)
+
+// Clickhouse is an implementation of Database interface
+type Clickhouse struct {
+	*clickhouseConnectionProducer
+	credsutil.CredentialsProducer
+}
+
+// New implements builtinplugins.BuiltinFactory
+func New() (interface{}, error) {
+	db := new()
+	// Wrap the plugin with middleware to sanitize errors
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.SecretValues)
+
+	return dbType, nil
+}
+
+func new() *Clickhouse {
+	connProducer := &clickhouseConnectionProducer{}
+
+	credsProducer := &credsutil.SQLCredentialsProducer{
+		DisplayNameLen: 15,
+		RoleNameLen:    15,
+		UsernameLen:    100,
+		Separator:      "-",
+	}
+
+	return &Clickhouse{
+		clickhouseConnectionProducer: connProducer,
+		CredentialsProducer:          credsProducer,
+	}
+}
+
+// Run runs the RPC server for the plugin, which instantiates a Clickhouse object
+// for each connection
+func Run(apiTLSConfig *api.TLSConfig) error {
+	dbplugin.ServeMultiplex(New, api.VaultPluginTLSProvider(apiTLSConfig))
+
+	return nil
+}
+
+// Type returns the TypeName for this backend
+func (c *Clickhouse) Type() (string, error) {
+	return clickhouseTypeName, nil
+}
+
+func (c *Clickhouse) getConnection(ctx context.Context) (*clickhouseClient, error) {
+	cli, err := c.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.(*clickhouseClient), nil
+}
+
+// CreateUser generates the username/password on ClickHouse as instructed by
+// the creation statements provided. Since ClickHouse does not support
+// transactions, the rollback statements are run if any of them fail.
+func (c *Clickhouse) CreateUser(ctx context.Context, statements dbplugin.Statements, usernameConfig dbplugin.UsernameConfig, expiration time.Time) (username string, password string, err error) {
+	statements = dbutil.StatementCompatibilityHelper(statements)
+
+	if len(statements.Creation) == 0 {
+		return "", "", dbutil.ErrEmptyCreationStatement
+	}
+
+	username, err = c.GenerateUsername(usernameConfig)
+	if err != nil {
+		return "", "", err
+	}
+
+	password, err = c.GeneratePassword()
+	if err != nil {
+		return "", "", err
+	}
+
+	expirationStr, err := c.GenerateExpiration(expiration)
+	if err != nil {
+		return "", "", err
+	}
+
+	queryMap := map[string]string{
+		"name":       username,
+		"username":   username,
+		"password":   password,
+		"expiration": expirationStr,
+	}
+
+	if err := c.executeStatementsWithMap(ctx, statements.Creation, queryMap); err != nil {
+		rollbackStmts := statements.Rollback
+		if len(rollbackStmts) == 0 {
+			rollbackStmts = []string{defaultClickhouseRevocationStmts}
+		}
+
+		// Attempt the rollback, but return the original error
+		c.executeStatementsWithMap(ctx, rollbackStmts, queryMap)
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// RenewUser is not supported on ClickHouse, so this is a no-op.
+func (c *Clickhouse) RenewUser(ctx context.Context, statements dbplugin.Statements, username string, expiration time.Time) error {
+	// NOOP
+	return nil
+}
+
+// RevokeUser attempts to drop the specified user.
+func (c *Clickhouse) RevokeUser(ctx context.Context, statements dbplugin.Statements, username string) error {
+	statements = dbutil.StatementCompatibilityHelper(statements)
+
+	revocationStmts := statements.Revocation
+	// Use a default SQL statement for revocation if one cannot be fetched from the role
+	if len(revocationStmts) == 0 {
+		revocationStmts = []string{defaultClickhouseRevocationStmts}
+	}
+
+	return c.executeStatementsWithMap(ctx, revocationStmts, map[string]string{
+		"name":     username,
+		"username": username,
+	})
+}
+
+// RotateRootCredentials changes the password of the user Vault connects to
+// ClickHouse with.
+func (c *Clickhouse) RotateRootCredentials(ctx context.Context, statements []string) (map[string]interface{}, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.Username) == 0 || len(c.Password) == 0 {
+		return nil, errors.New("username and password are required to rotate")
+	}
+
+	rotateStatements := statements
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultClickhouseRotateCredentialsSQL}
+	}
+
+	cli, err := c.getConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := c.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	err = executeStatements(ctx, cli, rotateStatements, map[string]string{
+		"name":     c.Username,
+		"username": c.Username,
+		"password": password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Connect with the new password from now on
+	c.client.client.CloseIdleConnections()
+	c.client = nil
+	c.Password = password
+
+	c.RawConfig["password"] = password
+	return c.RawConfig, nil
+}
+
+// SetCredentials uses provided information to set the password to a user in the
+// database. Unlike CreateUser, this method requires a username be provided and
+// uses the name given, instead of generating a name. This is used for setting
+// the password of static accounts, as well as rolling back passwords in the
+// database in the event an updated database fails to save in Vault's storage.
+func (c *Clickhouse) SetCredentials(ctx context.Context, statements dbplugin.Statements, staticUser dbplugin.StaticUserConfig) (username, password string, err error) {
+	rotateStatements := statements.Rotation
+	if len(rotateStatements) == 0 {
+		rotateStatements = []string{defaultClickhouseRotateCredentialsSQL}
+	}
+
+	username = staticUser.Username
+	password = staticUser.Password
+	if username == "" || password == "" {
+		return "", "", errors.New("must provide both username and password")
+	}
+
+	queryMap := map[string]string{
+		"name":     username,
+		"username": username,
+		"password": password,
+	}
+
+	if err := c.executeStatementsWithMap(ctx, rotateStatements, queryMap); err != nil {
+		return "", "", err
+	}
+	return username, password, nil
+}
+
+// executeStatementsWithMap loops through the given templated SQL statements
+// and applies the map to them, interpolating values into the templates before
+// executing them in order.
+func (c *Clickhouse) executeStatementsWithMap(ctx context.Context, statements []string, queryMap map[string]string) error {
+	// Grab the lock
+	c.Lock()
+	defer c.Unlock()
+
+	// Get the connection
+	cli, err := c.getConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	return executeStatements(ctx, cli, statements, queryMap)
+}
+
+func executeStatements(ctx context.Context, cli *clickhouseClient, statements []string, queryMap map[string]string) error {
+	for _, stmt := range statements {
+		for _, query := range strutil.ParseArbitraryStringSlice(stmt, ";") {
+			query = strings.TrimSpace(query)
+			if len(query) == 0 {
+				continue
+			}
+
+			if err := cli.Exec(ctx, dbutil.QueryHelper(query, queryMap)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package clickhouse
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/database/dbplugin"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+)
+
+var _ dbplugin.Database = (*Clickhouse)(nil)
+
+const testClickhouseRole = `
+CREATE USER '{{name}}' IDENTIFIED WITH sha256_password BY '{{password}}';
+GRANT SELECT ON *.* TO '{{name}}';
+`
+
+// fakeClickhouse mimics the ClickHouse HTTP interface, recording the
+// statements it is sent. Statements containing "FAIL" are rejected.
+type fakeClickhouse struct {
+	sync.Mutex
+	*httptest.Server
+
+	username   string
+	password   string
+	statements []string
+}
+
+func newFakeClickhouse() *fakeClickhouse {
+	f := &fakeClickhouse{
+		username: "default",
+		password: "secret",
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.Lock()
+		defer f.Unlock()
+
+		username, password, ok := r.BasicAuth()
+		if !ok || username != f.username || password != f.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Code: 516. DB::Exception: default: Authentication failed"))
+			return
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		query := string(body)
+		if strings.Contains(query, "FAIL") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Code: 62. DB::Exception: Syntax error"))
+			return
+		}
+		f.statements = append(f.statements, query)
+
+		// Follow root credential rotations
+		if strings.HasPrefix(query, "ALTER USER '"+f.username+"'") {
+			f.password = strings.TrimSuffix(strings.SplitN(query, " BY '", 2)[1], "'")
+		}
+	}))
+	return f
+}
+
+func (f *fakeClickhouse) reset() []string {
+	f.Lock()
+	defer f.Unlock()
+
+	statements := f.statements
+	f.statements = nil
+	return statements
+}
+
+func testClickhouse(t *testing.T, f *fakeClickhouse) *Clickhouse {
+	t.Helper()
+
+	db := new()
+	_, err := db.Init(context.Background(), map[string]interface{}{
+		"connection_url": f.URL,
+		"username":       f.username,
+		"password":       f.password,
+	}, true)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.reset()
+	return db
+}
+
+func TestClickhouse_Initialize(t *testing.T) {
+	f := newFakeClickhouse()
+	defer f.Close()
+
+	db := testClickhouse(t, f)
+	if !db.Initialized {
+		t.Fatal("Database should be initialized")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for name, conf := range map[string]map[string]interface{}{
+		"bad password": {
+			"connection_url": f.URL,
+			"username":       "default",
+			"password":       "wrong",
+		},
+		"missing url": {
+			"username": "default",
+			"password": "secret",
+		},
+		"bad scheme": {
+			"connection_url": "tcp://localhost:9000",
+			"username":       "default",
+			"password":       "secret",
+		},
+	} {
+		if _, err := new().Init(context.Background(), conf, true); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestClickhouse_CreateUser(t *testing.T) {
+	f := newFakeClickhouse()
+	defer f.Close()
+
+	db := testClickhouse(t, f)
+	usernameConfig := dbplugin.UsernameConfig{
+		DisplayName: "test",
+		RoleName:    "test",
+	}
+
+	// Test with no configured Creation Statement
+	_, _, err := db.CreateUser(context.Background(), dbplugin.Statements{}, usernameConfig, time.Now().Add(time.Minute))
+	if err != dbutil.ErrEmptyCreationStatement {
+		t.Fatalf("expected empty creation statement error, got %v", err)
+	}
+
+	statements := dbplugin.Statements{
+		Creation: []string{testClickhouseRole},
+	}
+	username, password, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.HasPrefix(username, "v-test-test-") || password == "" {
+		t.Fatalf("bad credentials: %q %q", username, password)
+	}
+
+	expected := []string{
+		"CREATE USER '" + username + "' IDENTIFIED WITH sha256_password BY '" + password + "'",
+		"GRANT SELECT ON *.* TO '" + username + "'",
+	}
+	if actual := f.reset(); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	// A failed creation is rolled back
+	statements.Creation = []string{testClickhouseRole + "FAIL;"}
+	if _, _, err := db.CreateUser(context.Background(), statements, usernameConfig, time.Now().Add(time.Minute)); err == nil {
+		t.Fatal("expected error")
+	}
+	actual := f.reset()
+	if len(actual) != 3 || !strings.HasPrefix(actual[2], "DROP USER IF EXISTS 'v-test-test-") {
+		t.Fatalf("expected rollback, got %q", actual)
+	}
+}
+
+func TestClickhouse_RevokeUser(t *testing.T) {
+	f := newFakeClickhouse()
+	defer f.Close()
+
+	db := testClickhouse(t, f)
+
+	// Test default revoke statements
+	if err := db.RevokeUser(context.Background(), dbplugin.Statements{}, "v-test"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := f.reset(); len(actual) != 1 || actual[0] != "DROP USER IF EXISTS 'v-test'" {
+		t.Fatalf("bad: %q", actual)
+	}
+
+	// Test custom revoke statements
+	statements := dbplugin.Statements{
+		Revocation: []string{"REVOKE ALL ON *.* FROM '{{name}}'; DROP USER '{{name}}'"},
+	}
+	if err := db.RevokeUser(context.Background(), statements, "v-test"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if actual := f.reset(); len(actual) != 2 || actual[1] != "DROP USER 'v-test'" {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestClickhouse_SetCredentials(t *testing.T) {
+	f := newFakeClickhouse()
+	defer f.Close()
+
+	db := testClickhouse(t, f)
+
+	// Test with no username or password
+	if _, _, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	username, password, err := db.SetCredentials(context.Background(), dbplugin.Statements{}, dbplugin.StaticUserConfig{
+		Username: "static",
+		Password: "new-password",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if username != "static" || password != "new-password" {
+		t.Fatalf("bad credentials: %q %q", username, password)
+	}
+	expected := "ALTER USER 'static' IDENTIFIED WITH sha256_password BY 'new-password'"
+	if actual := f.reset(); len(actual) != 1 || actual[0] != expected {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestClickhouse_RotateRootCredentials(t *testing.T) {
+	f := newFakeClickhouse()
+	defer f.Close()
+
+	db := testClickhouse(t, f)
+
+	newConf, err := db.RotateRootCredentials(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if newConf["password"] == "secret" {
+		t.Fatal("password was not changed")
+	}
+	if f.password != newConf["password"] {
+		t.Fatalf("expected server password %q, got %q", newConf["password"], f.password)
+	}
+
+	// The new password is used from then on
+	if err := db.RevokeUser(context.Background(), dbplugin.Statements{}, "v-test"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
@@ -0,0 +1,235 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/tlsutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+// maxErrorBodySize limits how much of an error response from ClickHouse is
+// included in the returned error.
+const maxErrorBodySize = 4096
+
+// clickhouseConnectionProducer implements ConnectionProducer and provides an
+// interface for ClickHouse servers to make connections over their HTTP
+// interface.
+type clickhouseConnectionProducer struct {
+	ConnectionURL     string      `json:"connection_url"  mapstructure:"connection_url"  structs:"connection_url"`
+	Username          string      `json:"username"        mapstructure:"username"        structs:"username"`
+	Password          string      `json:"password"        mapstructure:"password"        structs:"password"`
+	ConnectTimeoutRaw interface{} `json:"connect_timeout" mapstructure:"connect_timeout" structs:"connect_timeout"`
+
+	TLSCertificateKeyData []byte `json:"tls_certificate_key" mapstructure:"tls_certificate_key" structs:"-"`
+	TLSCAData             []byte `json:"tls_ca"              mapstructure:"tls_ca"              structs:"-"`
+	InsecureTLS           bool   `json:"insecure_tls"        mapstructure:"insecure_tls"        structs:"insecure_tls"`
+	TLSMinVersion         string `json:"tls_min_version"     mapstructure:"tls_min_version"     structs:"tls_min_version"`
+
+	RawConfig      map[string]interface{}
+	connectTimeout time.Duration
+	Initialized    bool
+	client         *clickhouseClient
+	sync.Mutex
+}
+
+func (c *clickhouseConnectionProducer) Initialize(ctx context.Context, conf map[string]interface{}, verifyConnection bool) error {
+	_, err := c.Init(ctx, conf, verifyConnection)
+	return err
+}
+
+func (c *clickhouseConnectionProducer) Init(ctx context.Context, conf map[string]interface{}, verifyConnection bool) (map[string]interface{}, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.RawConfig = conf
+
+	err := mapstructure.WeakDecode(conf, &c)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(c.ConnectionURL) == 0:
+		return nil, fmt.Errorf("connection_url cannot be empty")
+	case len(c.Username) == 0:
+		return nil, fmt.Errorf("username cannot be empty")
+	case len(c.Password) == 0:
+		return nil, fmt.Errorf("password cannot be empty")
+	}
+
+	u, err := url.Parse(c.ConnectionURL)
+	if err != nil {
+		return nil, errwrap.Wrapf("invalid connection_url: {{err}}", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("connection_url must be an http or https URL")
+	}
+
+	if c.ConnectTimeoutRaw == nil {
+		c.ConnectTimeoutRaw = "5s"
+	}
+	c.connectTimeout, err = parseutil.ParseDurationSecond(c.ConnectTimeoutRaw)
+	if err != nil {
+		return nil, errwrap.Wrapf("invalid connect_timeout: {{err}}", err)
+	}
+
+	// The client is recreated with the new configuration on next use
+	c.client = nil
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	c.Initialized = true
+
+	if verifyConnection {
+		if _, err := c.Connection(ctx); err != nil {
+			return nil, errwrap.Wrapf("error verifying connection: {{err}}", err)
+		}
+
+		if err := c.client.Exec(ctx, "SELECT 1"); err != nil {
+			return nil, errwrap.Wrapf("error verifying connection: {{err}}", err)
+		}
+	}
+
+	return c.RawConfig, nil
+}
+
+func (c *clickhouseConnectionProducer) Connection(_ context.Context) (interface{}, error) {
+	if !c.Initialized {
+		return nil, connutil.ErrNotInitialized
+	}
+
+	// If we already have a client, return it
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	tlsConfig, err := c.getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	c.client = &clickhouseClient{
+		url:      c.ConnectionURL,
+		username: c.Username,
+		password: c.Password,
+		client: &http.Client{
+			Timeout: c.connectTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+
+	return c.client, nil
+}
+
+func (c *clickhouseConnectionProducer) SecretValues() map[string]interface{} {
+	return map[string]interface{}{
+		c.Password: "[password]",
+	}
+}
+
+// Close attempts to close the connection
+func (c *clickhouseConnectionProducer) Close() error {
+	// Grab the write lock
+	c.Lock()
+	defer c.Unlock()
+
+	if c.client != nil {
+		c.client.client.CloseIdleConnections()
+	}
+
+	c.client = nil
+
+	return nil
+}
+
+func (c *clickhouseConnectionProducer) getTLSConfig() (*tls.Config, error) {
+	if len(c.TLSCAData) == 0 &&
+		len(c.TLSCertificateKeyData) == 0 &&
+		!c.InsecureTLS &&
+		c.TLSMinVersion == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureTLS,
+	}
+
+	if len(c.TLSCAData) > 0 {
+		rootCertPool := x509.NewCertPool()
+		ok := rootCertPool.AppendCertsFromPEM(c.TLSCAData)
+		if !ok {
+			return nil, fmt.Errorf("failed to append CA to client options")
+		}
+		tlsConfig.RootCAs = rootCertPool
+	}
+
+	if len(c.TLSCertificateKeyData) > 0 {
+		certificate, err := tls.X509KeyPair(c.TLSCertificateKeyData, c.TLSCertificateKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load tls_certificate_key_data: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if c.TLSMinVersion != "" {
+		var ok bool
+		tlsConfig.MinVersion, ok = tlsutil.TLSLookup[c.TLSMinVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid 'tls_min_version' in config")
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// clickhouseClient executes statements against ClickHouse's HTTP interface,
+// which runs a single statement per request.
+type clickhouseClient struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// Exec runs the given statement, returning the error reported by ClickHouse
+// if it fails.
+func (c *clickhouseClient) Exec(ctx context.Context, query string) error {
+	req, err := http.NewRequest(http.MethodPost, c.url, strings.NewReader(query))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return fmt.Errorf("clickhouse returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Drain the body so the connection can be reused
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
@@ -86,12 +86,8 @@ func runCommon(legacy bool, apiTLSConfig *api.TLSConfig) error {
 	} else {
 		f = New(MetadataLen, MetadataLen, UsernameLen)
 	}
-	dbType, err := f()
-	if err != nil {
-		return err
-	}
 
-	dbplugin.Serve(dbType.(dbplugin.Database), api.VaultPluginTLSProvider(apiTLSConfig))
+	dbplugin.ServeMultiplex(f, api.VaultPluginTLSProvider(apiTLSConfig))
 
 	return nil
 }
@@ -74,14 +74,10 @@ func new() *PostgreSQL {
 	return db
 }
 
-// Run instantiates a PostgreSQL object, and runs the RPC server for the plugin
+// Run runs the RPC server for the plugin, which instantiates a PostgreSQL object
+// for each connection
 func Run(apiTLSConfig *api.TLSConfig) error {
-	dbType, err := New()
-	if err != nil {
-		return err
-	}
-
-	dbplugin.Serve(dbType.(dbplugin.Database), api.VaultPluginTLSProvider(apiTLSConfig))
+	dbplugin.ServeMultiplex(New, api.VaultPluginTLSProvider(apiTLSConfig))
 
 	return nil
 }
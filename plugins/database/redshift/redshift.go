@@ -68,14 +68,10 @@ func newRedshift(lowercaseUsername bool) *RedShift {
 	return db
 }
 
-// Run instantiates a RedShift object, and runs the RPC server for the plugin
+// Run runs the RPC server for the plugin, which instantiates a RedShift object
+// for each connection
 func Run(apiTLSConfig *api.TLSConfig) error {
-	dbType, err := New(true)()
-	if err != nil {
-		return err
-	}
-
-	dbplugin.Serve(dbType.(dbplugin.Database), api.VaultPluginTLSProvider(apiTLSConfig))
+	dbplugin.ServeMultiplex(New(true), api.VaultPluginTLSProvider(apiTLSConfig))
 
 	return nil
 }
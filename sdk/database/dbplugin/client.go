@@ -11,19 +11,20 @@ import (
 )
 
 // DatabasePluginClient embeds a databasePluginRPCClient and wraps it's Close
-// method to also call Kill() on the plugin.Client.
+// method to also release the plugin process.
 type DatabasePluginClient struct {
-	client *plugin.Client
+	client pluginutil.PluginClient
 	sync.Mutex
 
 	Database
 }
 
 // This wraps the Close call and ensures we both close the database connection
-// and kill the plugin.
+// and release the plugin, which is killed unless it is shared with other
+// connections.
 func (dc *DatabasePluginClient) Close() error {
 	err := dc.Database.Close()
-	dc.client.Kill()
+	dc.client.Close()
 
 	return err
 }
@@ -46,28 +47,51 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 		4: plugin.PluginSet{
 			"database": new(GRPCDatabasePlugin),
 		},
+		// Version 5 serves several connections in a single process
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"database": new(GRPCDatabasePlugin),
+		},
 	}
 
-	var client *plugin.Client
-	var err error
-	if isMetadataMode {
-		client, err = pluginRunner.RunMetadataMode(ctx, sys, pluginSets, handshakeConfig, []string{}, logger)
-	} else {
-		client, err = pluginRunner.Run(ctx, sys, pluginSets, handshakeConfig, []string{}, logger)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	// Connect via RPC
-	rpcClient, err := client.Client()
-	if err != nil {
-		return nil, err
+	var client pluginutil.PluginClient
+	clientRunner, multiplexed := sys.(pluginutil.PluginClientRunner)
+	switch {
+	case isMetadataMode:
+		// Metadata mode processes are short-lived and never shared
+		rawClient, err := pluginRunner.RunMetadataMode(ctx, sys, pluginSets, handshakeConfig, []string{}, logger)
+		if err != nil {
+			return nil, err
+		}
+		client, err = pluginutil.NewDedicatedPluginClient(rawClient)
+		if err != nil {
+			return nil, err
+		}
+	case multiplexed:
+		var err error
+		client, err = clientRunner.NewPluginClient(ctx, pluginRunner, pluginutil.PluginClientConfig{
+			PluginSets:          pluginSets,
+			HandshakeConfig:     handshakeConfig,
+			Logger:              logger,
+			MultiplexingVersion: multiplexingProtocolVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		rawClient, err := pluginRunner.Run(ctx, sys, pluginSets, handshakeConfig, []string{}, logger)
+		if err != nil {
+			return nil, err
+		}
+		client, err = pluginutil.NewDedicatedPluginClient(rawClient)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Request the plugin
-	raw, err := rpcClient.Dispense("database")
+	raw, err := client.Dispense("database")
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
@@ -76,8 +100,13 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 	var db Database
 	switch raw.(type) {
 	case *gRPCClient:
-		db = raw.(*gRPCClient)
+		grpcClient := raw.(*gRPCClient)
+		if id := client.MultiplexingID(); id != "" {
+			grpcClient.setMultiplexingID(id)
+		}
+		db = grpcClient
 	default:
+		client.Close()
 		return nil, errors.New("unsupported client type")
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc"
@@ -23,10 +24,56 @@ var (
 
 type gRPCServer struct {
 	impl Database
+
+	// factory creates the Database of each client of a plugin process shared
+	// by several connections, identified by the multiplexing ID of the
+	// requests. It is nil if the process serves impl only.
+	factory       func() (interface{}, error)
+	instances     map[string]Database
+	instancesLock sync.Mutex
+}
+
+// getDatabase returns the Database of the client the request is made for.
+func (s *gRPCServer) getDatabase(ctx context.Context) (Database, error) {
+	if s.factory == nil {
+		return s.impl, nil
+	}
+
+	id := pluginutil.GetMultiplexIDFromContext(ctx)
+
+	s.instancesLock.Lock()
+	defer s.instancesLock.Unlock()
+
+	if db, ok := s.instances[id]; ok {
+		return db, nil
+	}
+
+	dbRaw, err := s.factory()
+	if err != nil {
+		return nil, err
+	}
+	db, ok := dbRaw.(Database)
+	if !ok {
+		return nil, errors.New("unsupported database type")
+	}
+	db = &DatabaseErrorSanitizerMiddleware{
+		next: db,
+	}
+
+	if s.instances == nil {
+		s.instances = make(map[string]Database)
+	}
+	s.instances[id] = db
+	return db, nil
 }
 
-func (s *gRPCServer) Type(context.Context, *Empty) (*TypeResponse, error) {
-	t, err := s.impl.Type()
+func (s *gRPCServer) Type(ctx context.Context, _ *Empty) (*TypeResponse, error) {
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := impl.Type()
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +89,12 @@ func (s *gRPCServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*C
 		return nil, err
 	}
 
-	u, p, err := s.impl.CreateUser(ctx, *req.Statements, *req.UsernameConfig, e)
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, p, err := impl.CreateUser(ctx, *req.Statements, *req.UsernameConfig, e)
 
 	return &CreateUserResponse{
 		Username: u,
@@ -55,18 +107,33 @@ func (s *gRPCServer) RenewUser(ctx context.Context, req *RenewUserRequest) (*Emp
 	if err != nil {
 		return nil, err
 	}
-	err = s.impl.RenewUser(ctx, *req.Statements, req.Username, e)
+
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = impl.RenewUser(ctx, *req.Statements, req.Username, e)
 	return &Empty{}, err
 }
 
 func (s *gRPCServer) RevokeUser(ctx context.Context, req *RevokeUserRequest) (*Empty, error) {
-	err := s.impl.RevokeUser(ctx, *req.Statements, req.Username)
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = impl.RevokeUser(ctx, *req.Statements, req.Username)
 	return &Empty{}, err
 }
 
 func (s *gRPCServer) RotateRootCredentials(ctx context.Context, req *RotateRootCredentialsRequest) (*RotateRootCredentialsResponse, error) {
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	resp, err := s.impl.RotateRootCredentials(ctx, req.Statements)
+	resp, err := impl.RotateRootCredentials(ctx, req.Statements)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +163,12 @@ func (s *gRPCServer) Init(ctx context.Context, req *InitRequest) (*InitResponse,
 		return nil, err
 	}
 
-	resp, err := s.impl.Init(ctx, config, req.VerifyConnection)
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := impl.Init(ctx, config, req.VerifyConnection)
 	if err != nil {
 		return nil, err
 	}
@@ -111,13 +183,31 @@ func (s *gRPCServer) Init(ctx context.Context, req *InitRequest) (*InitResponse,
 	}, err
 }
 
-func (s *gRPCServer) Close(_ context.Context, _ *Empty) (*Empty, error) {
-	s.impl.Close()
+func (s *gRPCServer) Close(ctx context.Context, _ *Empty) (*Empty, error) {
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	impl.Close()
+
+	// The Database of a shared process is created again if its client is
+	// used after being closed
+	if s.factory != nil {
+		s.instancesLock.Lock()
+		delete(s.instances, pluginutil.GetMultiplexIDFromContext(ctx))
+		s.instancesLock.Unlock()
+	}
 	return &Empty{}, nil
 }
 
 func (s *gRPCServer) GenerateCredentials(ctx context.Context, _ *Empty) (*GenerateCredentialsResponse, error) {
-	p, err := s.impl.GenerateCredentials(ctx)
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := impl.GenerateCredentials(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -128,8 +218,12 @@ func (s *gRPCServer) GenerateCredentials(ctx context.Context, _ *Empty) (*Genera
 }
 
 func (s *gRPCServer) SetCredentials(ctx context.Context, req *SetCredentialsRequest) (*SetCredentialsResponse, error) {
+	impl, err := s.getDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	username, password, err := s.impl.SetCredentials(ctx, *req.Statements, *req.StaticUserConfig)
+	username, password, err := impl.SetCredentials(ctx, *req.Statements, *req.StaticUserConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +243,12 @@ type gRPCClient struct {
 	doneCtx context.Context
 }
 
+// setMultiplexingID makes the client send its requests to the Database of the
+// given ID in a shared plugin process.
+func (c *gRPCClient) setMultiplexingID(id string) {
+	c.client = NewDatabaseClient(pluginutil.NewMultiplexedClientConn(c.clientConn, id))
+}
+
 func (c *gRPCClient) Type() (string, error) {
 	resp, err := c.client.Type(c.doneCtx, &Empty{})
 	if err != nil {
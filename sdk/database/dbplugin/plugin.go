@@ -77,8 +77,25 @@ type Database interface {
 // PluginFactory is used to build plugin database types. It wraps the database
 // object in a logging and metrics middleware.
 func PluginFactory(ctx context.Context, pluginName string, sys pluginutil.LookRunnerUtil, logger log.Logger) (Database, error) {
+	return PluginFactoryVersion(ctx, pluginName, "", sys, logger)
+}
+
+// PluginFactoryVersion is used to build plugin database types of the given
+// version of the plugin catalog. It requires sys to implement
+// pluginutil.VersionedLooker if a version is given.
+func PluginFactoryVersion(ctx context.Context, pluginName string, pluginVersion string, sys pluginutil.LookRunnerUtil, logger log.Logger) (Database, error) {
 	// Look for plugin in the plugin catalog
-	pluginRunner, err := sys.LookupPlugin(ctx, pluginName, consts.PluginTypeDatabase)
+	var pluginRunner *pluginutil.PluginRunner
+	var err error
+	if pluginVersion != "" {
+		versionedLooker, ok := sys.(pluginutil.VersionedLooker)
+		if !ok {
+			return nil, fmt.Errorf("plugin versions are not supported by the system view")
+		}
+		pluginRunner, err = versionedLooker.LookupPluginVersion(ctx, pluginName, consts.PluginTypeDatabase, pluginVersion)
+	} else {
+		pluginRunner, err = sys.LookupPlugin(ctx, pluginName, consts.PluginTypeDatabase)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -141,6 +158,11 @@ func PluginFactory(ctx context.Context, pluginName string, sys pluginutil.LookRu
 	return db, nil
 }
 
+// multiplexingProtocolVersion is the lowest protocol version of the plugins
+// serving several connections in a single process, identified by the
+// multiplexing ID of the requests.
+const multiplexingProtocolVersion = 5
+
 // handshakeConfigs are used to just do a basic handshake between
 // a plugin and host. If the handshake fails, a user friendly error is shown.
 // This prevents users from executing bad plugins or executing a plugin
@@ -159,11 +181,20 @@ var _ plugin.GRPCPlugin = &GRPCDatabasePlugin{}
 type GRPCDatabasePlugin struct {
 	Impl Database
 
+	// Factory creates a Database for each client of a plugin process shared
+	// by several connections. It is used instead of Impl if set.
+	Factory func() (interface{}, error)
+
 	// Embeding this will disable the netRPC protocol
 	plugin.NetRPCUnsupportedPlugin
 }
 
 func (d GRPCDatabasePlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	if d.Factory != nil {
+		RegisterDatabaseServer(s, &gRPCServer{factory: d.Factory})
+		return nil
+	}
+
 	impl := &DatabaseErrorSanitizerMiddleware{
 		next: d.Impl,
 	}
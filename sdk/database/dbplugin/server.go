@@ -15,6 +15,14 @@ func Serve(db Database, tlsProvider func() (*tls.Config, error)) {
 	plugin.Serve(ServeConfig(db, tlsProvider))
 }
 
+// ServeMultiplex is called from within a plugin and starts a RPC server
+// serving a Database created by the factory for each connection using the
+// plugin, so that the connections of all the database mounts share a single
+// plugin process.
+func ServeMultiplex(factory func() (interface{}, error), tlsProvider func() (*tls.Config, error)) {
+	plugin.Serve(ServeConfigMultiplex(factory, tlsProvider))
+}
+
 func ServeConfig(db Database, tlsProvider func() (*tls.Config, error)) *plugin.ServeConfig {
 	err := pluginutil.OptionallyEnableMlock()
 	if err != nil {
@@ -49,3 +57,40 @@ func ServeConfig(db Database, tlsProvider func() (*tls.Config, error)) *plugin.S
 
 	return conf
 }
+
+func ServeConfigMultiplex(factory func() (interface{}, error), tlsProvider func() (*tls.Config, error)) *plugin.ServeConfig {
+	err := pluginutil.OptionallyEnableMlock()
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	// Older versions are served too, for clients which do not support
+	// multiplexing: they use a single Database.
+	pluginSets := map[int]plugin.PluginSet{
+		3: plugin.PluginSet{
+			"database": &GRPCDatabasePlugin{
+				Factory: factory,
+			},
+		},
+		4: plugin.PluginSet{
+			"database": &GRPCDatabasePlugin{
+				Factory: factory,
+			},
+		},
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"database": &GRPCDatabasePlugin{
+				Factory: factory,
+			},
+		},
+	}
+
+	conf := &plugin.ServeConfig{
+		HandshakeConfig:  handshakeConfig,
+		VersionedPlugins: pluginSets,
+		TLSProvider:      tlsProvider,
+		GRPCServer:       plugin.DefaultGRPCServer,
+	}
+
+	return conf
+}
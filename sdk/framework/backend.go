@@ -88,6 +88,12 @@ type Backend struct {
 	// See the built-in AuthRenew helpers in lease.go for common callbacks.
 	AuthRenew OperationFunc
 
+	// GroupSync is the callback to call when the external groups of an
+	// alias are re-resolved outside of a login. The alias is set in the
+	// request's Auth, and the groups are returned as the GroupAliases of
+	// the response's Auth. By default, group sync isn't supported.
+	GroupSync OperationFunc
+
 	// Type is the logical.BackendType for the backend implementation
 	BackendType logical.BackendType
 
@@ -189,6 +195,8 @@ func (b *Backend) HandleRequest(ctx context.Context, req *logical.Request) (*log
 		return b.handleRevokeRenew(ctx, req)
 	case logical.RollbackOperation:
 		return b.handleRollback(ctx, req)
+	case logical.GroupSyncOperation:
+		return b.handleGroupSync(ctx, req)
 	}
 
 	// If the path is empty and it is a help operation, handle that.
@@ -494,6 +502,17 @@ func (b *Backend) handleAuthRenew(ctx context.Context, req *logical.Request) (*l
 	return b.AuthRenew(ctx, req, nil)
 }
 
+func (b *Backend) handleGroupSync(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	if b.GroupSync == nil {
+		return nil, logical.ErrUnsupportedOperation
+	}
+	if req.Auth == nil || req.Auth.Alias == nil {
+		return nil, fmt.Errorf("request has no alias")
+	}
+
+	return b.GroupSync(ctx, req, nil)
+}
+
 func (b *Backend) handleWALRollback(ctx context.Context, req *logical.Request) (*logical.Response, error) {
 	if b.WALRollback == nil {
 		return nil, logical.ErrUnsupportedOperation
@@ -360,6 +360,30 @@ func TestBackendHandleRequest_renewAuthCallback(t *testing.T) {
 		t.Fatalf("bad: %#v", v)
 	}
 }
+func TestBackendHandleRequest_groupSync(t *testing.T) {
+	b := &Backend{}
+
+	_, err := b.HandleRequest(context.Background(), logical.GroupSyncRequest("/foo", &logical.Alias{Name: "foo"}))
+	if err != logical.ErrUnsupportedOperation {
+		t.Fatalf("err: %v", err)
+	}
+
+	b.GroupSync = func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
+		return &logical.Response{
+			Auth: &logical.Auth{
+				GroupAliases: []*logical.Alias{{Name: req.Auth.Alias.Name + "-group"}},
+			},
+		}, nil
+	}
+	resp, err := b.HandleRequest(context.Background(), logical.GroupSyncRequest("/foo", &logical.Alias{Name: "foo"}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resp.Auth.GroupAliases) != 1 || resp.Auth.GroupAliases[0].Name != "foo-group" {
+		t.Fatalf("bad: %#v", resp.Auth.GroupAliases)
+	}
+}
+
 func TestBackendHandleRequest_renew(t *testing.T) {
 	called := new(uint32)
 	callback := func(context.Context, *logical.Request, *FieldData) (*logical.Response, error) {
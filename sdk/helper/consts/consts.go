@@ -32,4 +32,8 @@ const (
 	// ReplicationResolverALPN is the negotiated protocol used for
 	// resolving replicaiton addresses
 	ReplicationResolverALPN = "replication_resolver_v1"
+
+	// MirrorALPN is the negotiated protocol used for streaming the changes
+	// of a mirrored mount to another cluster.
+	MirrorALPN = "mirror_v1"
 )
@@ -0,0 +1,110 @@
+package keysutil
+
+import (
+	"bytes"
+	"crypto/sha512"
+
+	"github.com/hashicorp/vault/sdk/helper/keysutil/internal/edwards25519"
+	"golang.org/x/crypto/ed25519"
+)
+
+// ed25519phDom is dom2(1, "") of RFC 8032, which prefixes the inputs of the
+// hashes of Ed25519ph so that its signatures cannot be mistaken for Ed25519
+// ones. No context is used.
+var ed25519phDom = append([]byte("SigEd25519 no Ed25519 collisions"), 1, 0)
+
+// signEd25519ph returns the Ed25519ph signature of the SHA-512 digest of a
+// message, as defined in section 5.1.6 of RFC 8032. It is the Ed25519 signing
+// of golang.org/x/crypto/ed25519 with the dom2 prefix, which that package does
+// not support.
+func signEd25519ph(privateKey ed25519.PrivateKey, digest []byte) []byte {
+	h := sha512.New()
+	h.Write(privateKey[:32])
+
+	var digest1, messageDigest, hramDigest [64]byte
+	var expandedSecretKey [32]byte
+	h.Sum(digest1[:0])
+	copy(expandedSecretKey[:], digest1[:])
+	expandedSecretKey[0] &= 248
+	expandedSecretKey[31] &= 63
+	expandedSecretKey[31] |= 64
+
+	h.Reset()
+	h.Write(ed25519phDom)
+	h.Write(digest1[32:])
+	h.Write(digest)
+	h.Sum(messageDigest[:0])
+
+	var messageDigestReduced [32]byte
+	edwards25519.ScReduce(&messageDigestReduced, &messageDigest)
+	var R edwards25519.ExtendedGroupElement
+	edwards25519.GeScalarMultBase(&R, &messageDigestReduced)
+
+	var encodedR [32]byte
+	R.ToBytes(&encodedR)
+
+	h.Reset()
+	h.Write(ed25519phDom)
+	h.Write(encodedR[:])
+	h.Write(privateKey[32:])
+	h.Write(digest)
+	h.Sum(hramDigest[:0])
+	var hramDigestReduced [32]byte
+	edwards25519.ScReduce(&hramDigestReduced, &hramDigest)
+
+	var s [32]byte
+	edwards25519.ScMulAdd(&s, &hramDigestReduced, &expandedSecretKey, &messageDigestReduced)
+
+	signature := make([]byte, ed25519.SignatureSize)
+	copy(signature[:], encodedR[:])
+	copy(signature[32:], s[:])
+
+	return signature
+}
+
+// verifyEd25519ph reports whether sig is a valid Ed25519ph signature of the
+// SHA-512 digest of a message, as defined in section 5.1.7 of RFC 8032.
+func verifyEd25519ph(publicKey ed25519.PublicKey, digest, sig []byte) bool {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	if len(sig) != ed25519.SignatureSize || sig[63]&224 != 0 {
+		return false
+	}
+
+	var A edwards25519.ExtendedGroupElement
+	var publicKeyBytes [32]byte
+	copy(publicKeyBytes[:], publicKey)
+	if !A.FromBytes(&publicKeyBytes) {
+		return false
+	}
+	edwards25519.FeNeg(&A.X, &A.X)
+	edwards25519.FeNeg(&A.T, &A.T)
+
+	h := sha512.New()
+	h.Write(ed25519phDom)
+	h.Write(sig[:32])
+	h.Write(publicKey[:])
+	h.Write(digest)
+	var hram [64]byte
+	h.Sum(hram[:0])
+
+	var hReduced [32]byte
+	edwards25519.ScReduce(&hReduced, &hram)
+
+	var R edwards25519.ProjectiveGroupElement
+	var s [32]byte
+	copy(s[:], sig[32:])
+
+	// The scalar must be in the range [0, order) to prevent signature
+	// malleability
+	if !edwards25519.ScMinimal(&s) {
+		return false
+	}
+
+	edwards25519.GeDoubleScalarMultVartime(&R, &hReduced, &A, &s)
+
+	var checkR [32]byte
+	R.ToBytes(&checkR)
+	return bytes.Equal(sig[:32], checkR[:])
+}
@@ -0,0 +1,43 @@
+package keysutil
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestEd25519ph(t *testing.T) {
+	// Test vector of section 7.3 of RFC 8032
+	seed, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42")
+	expectedSig, _ := hex.DecodeString("98a70222f0b8121aa9d30f813d683f809e462b469c7ff87639499bb94e6dae4131f85042463c2a355a2003d062adf5aaa10b8c61e636062aaad11c2a26083406")
+	digest := sha512.Sum512([]byte("abc"))
+
+	key := ed25519.NewKeyFromSeed(seed)
+	pub := key.Public().(ed25519.PublicKey)
+	if hex.EncodeToString(pub) != "ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf" {
+		t.Fatalf("unexpected public key %x", pub)
+	}
+
+	sig := signEd25519ph(key, digest[:])
+	if hex.EncodeToString(sig) != hex.EncodeToString(expectedSig) {
+		t.Fatalf("expected signature %x, got %x", expectedSig, sig)
+	}
+	if !verifyEd25519ph(pub, digest[:], sig) {
+		t.Fatal("expected the signature to verify")
+	}
+
+	// Ed25519ph signatures are not Ed25519 signatures of the digest
+	if ed25519.Verify(pub, digest[:], sig) {
+		t.Fatal("expected the Ed25519 verification to fail")
+	}
+	otherDigest := sha512.Sum512([]byte("abd"))
+	if verifyEd25519ph(pub, otherDigest[:], sig) {
+		t.Fatal("expected the signature of another digest to fail")
+	}
+	sig[63] |= 0x80
+	if verifyEd25519ph(pub, digest[:], sig) {
+		t.Fatal("expected a malleated signature to fail")
+	}
+}
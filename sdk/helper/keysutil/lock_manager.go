@@ -47,6 +47,9 @@ type PolicyRequest struct {
 	// Whether to allow export
 	Exportable bool
 
+	// Whether to allow export when wrapped by another key
+	ExportableWrapped bool
+
 	// Whether to upsert
 	Upsert bool
 
@@ -363,6 +366,7 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 			Type:                 req.KeyType,
 			Derived:              req.Derived,
 			Exportable:           req.Exportable,
+			ExportableWrapped:    req.ExportableWrapped,
 			AllowPlaintextBackup: req.AllowPlaintextBackup,
 		}
 
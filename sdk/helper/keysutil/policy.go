@@ -12,6 +12,7 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/asn1"
 	"encoding/base64"
@@ -85,6 +86,20 @@ type SigningResult struct {
 	PublicKey []byte
 }
 
+// SigningOptions holds the parameters of a signing or verification
+// operation.
+type SigningOptions struct {
+	HashAlgorithm HashType
+	Marshaling    MarshalingType
+	SigAlgorithm  string
+
+	// Prehashed indicates that the input to an ed25519 key is the SHA-512
+	// digest of the message rather than the message itself, and selects the
+	// Ed25519ph signature scheme. The input to the other key types is always
+	// a digest.
+	Prehashed bool
+}
+
 type ecdsaSignature struct {
 	R, S *big.Int
 }
@@ -241,6 +256,9 @@ type PolicyConfig struct {
 	// Whether the key is exportable
 	Exportable bool
 
+	// Whether the key is exportable when wrapped by another key
+	ExportableWrapped bool
+
 	// Whether the key is allowed to be deleted
 	DeletionAllowed bool
 
@@ -268,6 +286,7 @@ func NewPolicy(config PolicyConfig) *Policy {
 		ConvergentEncryption: config.ConvergentEncryption,
 		ConvergentVersion:    -1,
 		Exportable:           config.Exportable,
+		ExportableWrapped:    config.ExportableWrapped,
 		DeletionAllowed:      config.DeletionAllowed,
 		AllowPlaintextBackup: config.AllowPlaintextBackup,
 		VersionTemplate:      config.VersionTemplate,
@@ -325,6 +344,10 @@ type Policy struct {
 	// Whether the key is exportable
 	Exportable bool `json:"exportable"`
 
+	// Whether the key is exportable when wrapped by another key, so that
+	// its plaintext is never revealed
+	ExportableWrapped bool `json:"exportable_wrapped"`
+
 	// The minimum version of the key allowed to be used for decryption
 	MinDecryptionVersion int `json:"min_decryption_version"`
 
@@ -1066,6 +1089,18 @@ func (p *Policy) HMACKey(version int) ([]byte, error) {
 }
 
 func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm HashType, sigAlgorithm string, marshaling MarshalingType) (*SigningResult, error) {
+	return p.SignWithOptions(ver, context, input, &SigningOptions{
+		HashAlgorithm: hashAlgorithm,
+		Marshaling:    marshaling,
+		SigAlgorithm:  sigAlgorithm,
+	})
+}
+
+func (p *Policy) SignWithOptions(ver int, context, input []byte, options *SigningOptions) (*SigningResult, error) {
+	hashAlgorithm := options.HashAlgorithm
+	marshaling := options.Marshaling
+	sigAlgorithm := options.SigAlgorithm
+
 	if !p.Type.SigningSupported() {
 		return nil, fmt.Errorf("message signing not supported for key type %v", p.Type)
 	}
@@ -1165,6 +1200,17 @@ func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm HashType, si
 			key = ed25519.PrivateKey(p.Keys[strconv.Itoa(ver)].Key)
 		}
 
+		if options.Prehashed {
+			if hashAlgorithm != HashTypeSHA2512 {
+				return nil, errutil.UserError{Err: "prehashed ed25519 signatures require the sha2-512 hash algorithm"}
+			}
+			if len(input) != sha512.Size {
+				return nil, errutil.UserError{Err: "prehashed input must be a sha2-512 digest"}
+			}
+			sig = signEd25519ph(key, input)
+			break
+		}
+
 		// Per docs, do not pre-hash ed25519; it does two passes and performs
 		// its own hashing
 		sig, err = key.Sign(rand.Reader, input, crypto.Hash(0))
@@ -1231,6 +1277,18 @@ func (p *Policy) Sign(ver int, context, input []byte, hashAlgorithm HashType, si
 }
 
 func (p *Policy) VerifySignature(context, input []byte, hashAlgorithm HashType, sigAlgorithm string, marshaling MarshalingType, sig string) (bool, error) {
+	return p.VerifySignatureWithOptions(context, input, sig, &SigningOptions{
+		HashAlgorithm: hashAlgorithm,
+		Marshaling:    marshaling,
+		SigAlgorithm:  sigAlgorithm,
+	})
+}
+
+func (p *Policy) VerifySignatureWithOptions(context, input []byte, sig string, options *SigningOptions) (bool, error) {
+	hashAlgorithm := options.HashAlgorithm
+	marshaling := options.Marshaling
+	sigAlgorithm := options.SigAlgorithm
+
 	if !p.Type.SigningSupported() {
 		return false, errutil.UserError{Err: fmt.Sprintf("message verification not supported for key type %v", p.Type)}
 	}
@@ -1333,6 +1391,16 @@ func (p *Policy) VerifySignature(context, input []byte, hashAlgorithm HashType,
 			key = ed25519.PrivateKey(p.Keys[strconv.Itoa(ver)].Key)
 		}
 
+		if options.Prehashed {
+			if hashAlgorithm != HashTypeSHA2512 {
+				return false, errutil.UserError{Err: "prehashed ed25519 signatures require the sha2-512 hash algorithm"}
+			}
+			if len(input) != sha512.Size {
+				return false, errutil.UserError{Err: "prehashed input must be a sha2-512 digest"}
+			}
+			return verifyEd25519ph(key.Public().(ed25519.PublicKey), input, sigBytes), nil
+		}
+
 		return ed25519.Verify(key.Public().(ed25519.PublicKey), input, sigBytes), nil
 
 	case KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
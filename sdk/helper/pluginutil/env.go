@@ -24,6 +24,10 @@ var (
 	// plugin.
 	PluginUnwrapTokenEnv = "VAULT_UNWRAP_TOKEN"
 
+	// PluginUnwrapAddrEnv is the ENV name used to pass the address the plugin
+	// unwraps its token at, when it cannot reach the address of the token.
+	PluginUnwrapAddrEnv = "VAULT_PLUGIN_UNWRAP_ADDR"
+
 	// PluginCACertPEMEnv is an ENV name used for holding a CA PEM-encoded
 	// string. Used for testing.
 	PluginCACertPEMEnv = "VAULT_TESTING_PLUGIN_CA_PEM"
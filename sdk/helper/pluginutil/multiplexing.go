@@ -0,0 +1,116 @@
+package pluginutil
+
+import (
+	"context"
+
+	log "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MultiplexingCtxKey is the gRPC metadata key carrying the ID of the mount a
+// request to a multiplexed plugin process is made for.
+const MultiplexingCtxKey string = "multiplex_id"
+
+// PluginClientConfig defines a client of a plugin process.
+type PluginClientConfig struct {
+	PluginSets      map[int]plugin.PluginSet
+	HandshakeConfig plugin.HandshakeConfig
+	Logger          log.Logger
+
+	// MultiplexingVersion is the lowest protocol version of the plugin sets
+	// which serves multiplexed plugins. A process negotiating a lower version
+	// is dedicated to its client.
+	MultiplexingVersion int
+}
+
+// PluginClient is a client of a plugin process, which may be shared with the
+// clients of other mounts.
+type PluginClient interface {
+	// Dispense returns a new client of the named plugin, and Close releases
+	// the process, which is killed once no client uses it anymore.
+	plugin.ClientProtocol
+
+	// MultiplexingID returns the ID identifying the client in a shared plugin
+	// process, or an empty string if the process is dedicated to the client.
+	MultiplexingID() string
+}
+
+// PluginClientRunner starts clients of plugin processes. Clients of the same
+// plugin share its process if the plugin supports multiplexing.
+// logical.SystemView implementations of Vault satisfy this interface.
+type PluginClientRunner interface {
+	NewPluginClient(ctx context.Context, runner *PluginRunner, config PluginClientConfig) (PluginClient, error)
+}
+
+// GetMultiplexIDFromContext returns the multiplexing ID of an incoming gRPC
+// request, or an empty string if the client of the request does not share the
+// plugin process.
+func GetMultiplexIDFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	ids := md.Get(MultiplexingCtxKey)
+	if len(ids) != 1 {
+		return ""
+	}
+	return ids[0]
+}
+
+// NewMultiplexedClientConn returns a gRPC connection sending the given
+// multiplexing ID along with every request made over conn.
+func NewMultiplexedClientConn(conn grpc.ClientConnInterface, id string) grpc.ClientConnInterface {
+	return &multiplexedClientConn{
+		ClientConnInterface: conn,
+		id:                  id,
+	}
+}
+
+type multiplexedClientConn struct {
+	grpc.ClientConnInterface
+	id string
+}
+
+func (c *multiplexedClientConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, MultiplexingCtxKey, c.id)
+	return c.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (c *multiplexedClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, MultiplexingCtxKey, c.id)
+	return c.ClientConnInterface.NewStream(ctx, desc, method, opts...)
+}
+
+// dedicatedPluginClient is a PluginClient of a process started for a single
+// client.
+type dedicatedPluginClient struct {
+	plugin.ClientProtocol
+	client *plugin.Client
+}
+
+// NewDedicatedPluginClient returns a PluginClient of a process dedicated to
+// the client, which is killed on Close.
+func NewDedicatedPluginClient(client *plugin.Client) (PluginClient, error) {
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	return &dedicatedPluginClient{
+		ClientProtocol: rpcClient,
+		client:         client,
+	}, nil
+}
+
+func (c *dedicatedPluginClient) Close() error {
+	c.client.Kill()
+	return nil
+}
+
+func (c *dedicatedPluginClient) MultiplexingID() string {
+	return ""
+}
@@ -21,6 +21,13 @@ type Looker interface {
 	LookupPlugin(context.Context, string, consts.PluginType) (*PluginRunner, error)
 }
 
+// VersionedLooker defines the plugin Lookup function that looks into the plugin
+// catalog for a plugin registered with the given version. An empty version
+// looks up the plugin as Looker does.
+type VersionedLooker interface {
+	LookupPluginVersion(ctx context.Context, name string, pluginType consts.PluginType, version string) (*PluginRunner, error)
+}
+
 // RunnerUtil interface defines the functions needed by the runner to wrap the
 // metadata needed to run a plugin process. This includes looking up Mlock
 // configuration and wrapping data in a response wrapped token.
@@ -40,6 +47,7 @@ type LookRunnerUtil interface {
 // go-plugin.
 type PluginRunner struct {
 	Name           string                      `json:"name" structs:"name"`
+	Version        string                      `json:"version,omitempty" structs:"version"`
 	Type           consts.PluginType           `json:"type" structs:"type"`
 	Command        string                      `json:"command" structs:"command"`
 	Args           []string                    `json:"args" structs:"args"`
@@ -47,6 +55,8 @@ type PluginRunner struct {
 	Sha256         []byte                      `json:"sha256" structs:"sha256"`
 	Builtin        bool                        `json:"builtin" structs:"builtin"`
 	BuiltinFactory func() (interface{}, error) `json:"-" structs:"-"`
+	Runtime        *PluginRuntimeConfig        `json:"runtime,omitempty" structs:"runtime"`
+	RuntimeWrapper RuntimeWrapper              `json:"-" structs:"-"`
 }
 
 // Run takes a wrapper RunnerUtil instance along with the go-plugin parameters and
@@ -114,6 +124,23 @@ func (r *PluginRunner) runCommon(ctx context.Context, wrapper RunnerUtil, plugin
 		Hash:     sha256.New(),
 	}
 
+	if r.RuntimeWrapper != nil {
+		// The command started by go-plugin is the runtime's, so the checksum of
+		// the plugin is verified before wrapping it
+		if ok, err := secureConfig.Check(cmd.Path); err != nil {
+			return nil, fmt.Errorf("error verifying checksum: %s", err)
+		} else if !ok {
+			return nil, plugin.ErrChecksumsDoNotMatch
+		}
+		secureConfig = nil
+
+		var err error
+		cmd, err = r.RuntimeWrapper.WrapCommand(cmd, pluginEnvNames(cmd.Env, hs.MagicCookieKey))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	clientConfig := &plugin.ClientConfig{
 		HandshakeConfig:  hs,
 		VersionedPlugins: pluginSets,
@@ -0,0 +1,60 @@
+package pluginutil
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// PluginRuntimeConfig defines the isolated runtime an external plugin is run
+// in, rather than as a process of the host.
+type PluginRuntimeConfig struct {
+	// Type is the kind of runtime, such as a container or a gVisor sandbox
+	Type string `json:"type" structs:"type" mapstructure:"type"`
+
+	// Image is the container image the plugin binary is run in
+	Image string `json:"image" structs:"image" mapstructure:"image"`
+
+	// CPUs and MemoryBytes limit the resources of the plugin, if set
+	CPUs        float64 `json:"cpus,omitempty" structs:"cpus" mapstructure:"cpus"`
+	MemoryBytes int64   `json:"memory_bytes,omitempty" structs:"memory_bytes" mapstructure:"memory_bytes"`
+}
+
+// RuntimeWrapper launches the command of a plugin in an isolated runtime.
+type RuntimeWrapper interface {
+	// WrapCommand returns the command launching cmd in the runtime. The
+	// environment of the returned command is extended by go-plugin, so the
+	// runtime is given the names of the variables to pass to the plugin.
+	WrapCommand(cmd *exec.Cmd, envNames []string) (*exec.Cmd, error)
+}
+
+// goPluginEnvNames are the ENV names set by go-plugin when launching the
+// plugin process.
+var goPluginEnvNames = []string{
+	"PLUGIN_MIN_PORT",
+	"PLUGIN_MAX_PORT",
+	"PLUGIN_PROTOCOL_VERSIONS",
+	"PLUGIN_CLIENT_CERT",
+}
+
+// pluginEnvNames returns the names of the ENV variables of the plugin
+// process.
+func pluginEnvNames(env []string, magicCookieKey string) []string {
+	seen := make(map[string]struct{})
+	var names []string
+	add := func(name string) {
+		if _, ok := seen[name]; ok || name == "" {
+			return
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+
+	for _, kv := range env {
+		add(strings.SplitN(kv, "=", 2)[0])
+	}
+	add(magicCookieKey)
+	for _, name := range goPluginEnvNames {
+		add(name)
+	}
+	return names
+}
@@ -34,4 +34,13 @@ type ResponseWrapInfo struct {
 
 	// Controls seal wrapping behavior downstream for specific use cases
 	SealWrap bool `json:"seal_wrap" structs:"seal_wrap" mapstructure:"seal_wrap" sentinel:""`
+
+	// NumUses is the number of times the wrapped response can be unwrapped.
+	// Zero means a single use.
+	NumUses int `json:"num_uses" structs:"num_uses" mapstructure:"num_uses" sentinel:""`
+
+	// AllowedEntityIDs and AllowedGroupIDs restrict unwrapping to callers
+	// with one of the given entities, or members of one of the given groups.
+	AllowedEntityIDs []string `json:"allowed_entity_ids" structs:"allowed_entity_ids" mapstructure:"allowed_entity_ids" sentinel:""`
+	AllowedGroupIDs  []string `json:"allowed_group_ids" structs:"allowed_group_ids" mapstructure:"allowed_group_ids" sentinel:""`
 }
@@ -260,6 +260,19 @@ func RenewAuthRequest(path string, auth *Auth, data map[string]interface{}) *Req
 	}
 }
 
+// GroupSyncRequest creates the structure of the request re-resolving the
+// external groups of an alias.
+func GroupSyncRequest(path string, alias *Alias) *Request {
+	return &Request{
+		Operation: GroupSyncOperation,
+		Path:      path,
+		Data:      make(map[string]interface{}),
+		Auth: &Auth{
+			Alias: alias,
+		},
+	}
+}
+
 // RevokeRequest creates the structure of the revoke request.
 func RevokeRequest(path string, secret *Secret, data map[string]interface{}) *Request {
 	return &Request{
@@ -294,9 +307,10 @@ const (
 	AliasLookaheadOperation           = "alias-lookahead"
 
 	// The operations below are called globally, the path is less relevant.
-	RevokeOperation   Operation = "revoke"
-	RenewOperation              = "renew"
-	RollbackOperation           = "rollback"
+	RevokeOperation    Operation = "revoke"
+	RenewOperation               = "renew"
+	RollbackOperation            = "rollback"
+	GroupSyncOperation           = "group-sync"
 )
 
 type MFACreds map[string][]string
@@ -1,6 +1,9 @@
 package logical
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Secret represents the secret part of a response.
 type Secret struct {
@@ -15,6 +18,11 @@ type Secret struct {
 	// This is generated by Vault core. Any set value will be ignored.
 	// For requests, this will always be blank.
 	LeaseID string `sentinel:""`
+
+	// Tags are key-value pairs attached to the lease of the secret, such as
+	// the application the secret was issued for. Leases can be looked up and
+	// revoked by their tags.
+	Tags map[string]string `json:"tags,omitempty" sentinel:""`
 }
 
 func (s *Secret) Validate() error {
@@ -22,6 +30,12 @@ func (s *Secret) Validate() error {
 		return fmt.Errorf("ttl duration must not be less than zero")
 	}
 
+	for k := range s.Tags {
+		if k == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("invalid lease tag key %q", k)
+		}
+	}
+
 	return nil
 }
 
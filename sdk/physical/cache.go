@@ -5,7 +5,6 @@ import (
 	"sync/atomic"
 
 	log "github.com/hashicorp/go-hclog"
-	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/pathmanager"
 )
@@ -14,6 +13,10 @@ const (
 	// DefaultCacheSize is used if no cache size is specified for NewCache
 	DefaultCacheSize = 128 * 1024
 
+	// DefaultCacheMaxBytes is the size in bytes of the cached entries used if
+	// none is specified
+	DefaultCacheMaxBytes = 256 * 1024 * 1024
+
 	// refreshCacheCtxKey is a ctx value that denotes the cache should be
 	// refreshed during a Get call.
 	refreshCacheCtxKey = "refresh_cache"
@@ -30,6 +33,68 @@ var cacheExceptionsPaths = []string{
 	"core/raft/tls",
 }
 
+// DefaultCachePinnedPrefixes are the prefixes of the keys read by most
+// requests, such as the mount tables and the policies, whose entries are
+// never evicted from the cache by default.
+var DefaultCachePinnedPrefixes = []string{
+	"core/mounts",
+	"core/local-mounts",
+	"core/auth",
+	"core/local-auth",
+	"sys/policy/",
+}
+
+// CacheConfig holds the bounds of a Cache, and the prefixes of the keys whose
+// entries are never evicted. Pinned entries do not count against the bounds.
+type CacheConfig struct {
+	MaxEntries     int
+	MaxBytes       int64
+	PinnedPrefixes []string
+}
+
+// withDefaults returns the config with the default values set for the bounds
+// which are not positive, and the default pinned prefixes if they are nil.
+func (c CacheConfig) withDefaults() CacheConfig {
+	c = c.clone()
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = DefaultCacheSize
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = DefaultCacheMaxBytes
+	}
+	if c.PinnedPrefixes == nil {
+		c.PinnedPrefixes = append([]string{}, DefaultCachePinnedPrefixes...)
+	}
+	return c
+}
+
+func (c CacheConfig) clone() CacheConfig {
+	if c.PinnedPrefixes != nil {
+		c.PinnedPrefixes = append([]string{}, c.PinnedPrefixes...)
+	}
+	return c
+}
+
+// CacheStats describes the usage of a Cache. Hits, misses and evictions are
+// counted since the creation of the cache.
+type CacheStats struct {
+	Entries       int
+	Bytes         int64
+	PinnedEntries int
+	PinnedBytes   int64
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+}
+
+// TunableCache is implemented by caches whose bounds can be changed while
+// they are in use.
+type TunableCache interface {
+	CacheConfig() CacheConfig
+	SetCacheConfig(CacheConfig)
+	CacheStats() CacheStats
+}
+
 // CacheRefreshContext returns a context with an added value denoting if the
 // cache should attempt a refresh.
 func CacheRefreshContext(ctx context.Context, r bool) context.Context {
@@ -52,7 +117,7 @@ func cacheRefreshFromContext(ctx context.Context) bool {
 // by using a simple write-through cache.
 type Cache struct {
 	backend         Backend
-	lru             *lru.TwoQueueCache
+	lru             *CacheLRU
 	locks           []*locksutil.LockEntry
 	logger          log.Logger
 	enabled         *uint32
@@ -70,24 +135,23 @@ var _ ToggleablePurgemonster = (*Cache)(nil)
 var _ ToggleablePurgemonster = (*TransactionalCache)(nil)
 var _ Backend = (*Cache)(nil)
 var _ Transactional = (*TransactionalCache)(nil)
+var _ TunableCache = (*Cache)(nil)
 
-// NewCache returns a physical cache of the given size.
-// If no size is provided, the default size is used.
+// NewCache returns a physical cache of the given number of entries.
+// If no size is provided, the default size is used. The size in bytes of the
+// entries is bounded by DefaultCacheMaxBytes until changed with
+// SetCacheConfig.
 func NewCache(b Backend, size int, logger log.Logger) *Cache {
 	if logger.IsDebug() {
 		logger.Debug("creating LRU cache", "size", size)
 	}
-	if size <= 0 {
-		size = DefaultCacheSize
-	}
 
 	pm := pathmanager.New()
 	pm.AddPaths(cacheExceptionsPaths)
 
-	cache, _ := lru.New2Q(size)
 	c := &Cache{
 		backend: b,
-		lru:     cache,
+		lru:     newCacheLRU(CacheConfig{MaxEntries: size}),
 		locks:   locksutil.CreateLocks(),
 		logger:  logger,
 		// This fails safe.
@@ -123,6 +187,26 @@ func (c *Cache) SetEnabled(enabled bool) {
 	atomic.StoreUint32(c.enabled, 0)
 }
 
+// CacheConfig returns the bounds and pinned prefixes of the cache.
+func (c *Cache) CacheConfig() CacheConfig {
+	return c.lru.Config()
+}
+
+// SetCacheConfig changes the bounds and pinned prefixes of the cache. Bounds
+// which are not positive are set to their default value, and the default
+// pinned prefixes are used if the prefixes are nil.
+func (c *Cache) SetCacheConfig(config CacheConfig) {
+	if c.logger.IsDebug() {
+		c.logger.Debug("configuring LRU cache", "max_entries", config.MaxEntries, "max_bytes", config.MaxBytes, "pinned_prefixes", config.PinnedPrefixes)
+	}
+	c.lru.SetConfig(config)
+}
+
+// CacheStats returns the usage of the cache.
+func (c *Cache) CacheStats() CacheStats {
+	return c.lru.Stats()
+}
+
 // Purge is used to clear the cache
 func (c *Cache) Purge(ctx context.Context) {
 	// Lock the world
@@ -161,11 +245,8 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, error) {
 
 	// Check the LRU first
 	if !cacheRefreshFromContext(ctx) {
-		if raw, ok := c.lru.Get(key); ok {
-			if raw == nil {
-				return nil, nil
-			}
-			return raw.(*Entry), nil
+		if ent, ok := c.lru.Get(key); ok {
+			return ent, nil
 		}
 	}
 
@@ -208,7 +289,7 @@ func (c *TransactionalCache) Locks() []*locksutil.LockEntry {
 	return c.locks
 }
 
-func (c *TransactionalCache) LRU() *lru.TwoQueueCache {
+func (c *TransactionalCache) LRU() *CacheLRU {
 	return c.lru
 }
 
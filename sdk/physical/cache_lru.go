@@ -0,0 +1,227 @@
+package physical
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// cacheEntryOverhead approximates the memory used by a cached entry besides
+// its key and value.
+const cacheEntryOverhead = 64
+
+// cacheItem is an entry of a CacheLRU. A nil entry caches the absence of the
+// key in the backend.
+type cacheItem struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+func newCacheItem(key string, entry *Entry) *cacheItem {
+	size := int64(len(key) + cacheEntryOverhead)
+	if entry != nil {
+		size += int64(len(entry.Key) + len(entry.Value) + len(entry.ValueHash))
+	}
+	return &cacheItem{
+		key:   key,
+		entry: entry,
+		size:  size,
+	}
+}
+
+// CacheLRU is a least recently used cache bounded by both its number of
+// entries and their size in bytes. Entries whose key starts with a pinned
+// prefix are never evicted, and do not count against the bounds. The absence
+// of a key is never pinned, so that negative lookups are always bounded.
+type CacheLRU struct {
+	l sync.Mutex
+
+	config CacheConfig
+
+	// items holds the entries which can be evicted, ordered from the most to
+	// the least recently used, and pinned the others
+	items  map[string]*list.Element
+	ll     *list.List
+	bytes  int64
+	pinned map[string]*cacheItem
+
+	pinnedBytes int64
+	hits        uint64
+	misses      uint64
+	evictions   uint64
+}
+
+func newCacheLRU(config CacheConfig) *CacheLRU {
+	c := &CacheLRU{
+		items:  make(map[string]*list.Element),
+		ll:     list.New(),
+		pinned: make(map[string]*cacheItem),
+	}
+	c.config = config.withDefaults()
+	return c
+}
+
+// isPinned returns whether the item should be pinned, which is the case if it
+// caches an entry whose key starts with a pinned prefix.
+func (c *CacheLRU) isPinned(item *cacheItem) bool {
+	if item.entry == nil {
+		return false
+	}
+	for _, prefix := range c.config.PinnedPrefixes {
+		if strings.HasPrefix(item.key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the cached entry of the key, and whether the key is cached.
+func (c *CacheLRU) Get(key string) (*Entry, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if item, ok := c.pinned[key]; ok {
+		c.hits++
+		return item.entry, true
+	}
+
+	if elem, ok := c.items[key]; ok {
+		c.hits++
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*cacheItem).entry, true
+	}
+
+	c.misses++
+	return nil, false
+}
+
+// Add caches the entry of the key, evicting the least recently used entries
+// if the cache is over its bounds.
+func (c *CacheLRU) Add(key string, entry *Entry) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.removeLocked(key)
+
+	item := newCacheItem(key, entry)
+	if c.isPinned(item) {
+		c.pinned[key] = item
+		c.pinnedBytes += item.size
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(item)
+	c.bytes += item.size
+	c.evictLocked()
+}
+
+// Remove removes the key from the cache.
+func (c *CacheLRU) Remove(key string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.removeLocked(key)
+}
+
+func (c *CacheLRU) removeLocked(key string) {
+	if item, ok := c.pinned[key]; ok {
+		delete(c.pinned, key)
+		c.pinnedBytes -= item.size
+		return
+	}
+
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		c.bytes -= elem.Value.(*cacheItem).size
+	}
+}
+
+// evictLocked evicts the least recently used entries until the cache is
+// within its bounds.
+func (c *CacheLRU) evictLocked() {
+	for c.ll.Len() > c.config.MaxEntries || (c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes) {
+		elem := c.ll.Back()
+		if elem == nil {
+			return
+		}
+
+		item := elem.Value.(*cacheItem)
+		c.ll.Remove(elem)
+		delete(c.items, item.key)
+		c.bytes -= item.size
+		c.evictions++
+	}
+}
+
+// Purge removes all the entries of the cache.
+func (c *CacheLRU) Purge() {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.ll.Init()
+	c.bytes = 0
+	c.pinned = make(map[string]*cacheItem)
+	c.pinnedBytes = 0
+}
+
+// Config returns the bounds and pinned prefixes of the cache.
+func (c *CacheLRU) Config() CacheConfig {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.config.clone()
+}
+
+// SetConfig changes the bounds and pinned prefixes of the cache. Entries are
+// pinned or unpinned according to the new prefixes, and evicted if the cache
+// is over its new bounds.
+func (c *CacheLRU) SetConfig(config CacheConfig) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.config = config.withDefaults()
+
+	for key, item := range c.pinned {
+		if c.isPinned(item) {
+			continue
+		}
+		delete(c.pinned, key)
+		c.pinnedBytes -= item.size
+		c.items[key] = c.ll.PushBack(item)
+		c.bytes += item.size
+	}
+
+	for elem := c.ll.Front(); elem != nil; {
+		next := elem.Next()
+		item := elem.Value.(*cacheItem)
+		if c.isPinned(item) {
+			c.ll.Remove(elem)
+			delete(c.items, item.key)
+			c.bytes -= item.size
+			c.pinned[item.key] = item
+			c.pinnedBytes += item.size
+		}
+		elem = next
+	}
+
+	c.evictLocked()
+}
+
+// Stats returns the usage of the cache.
+func (c *CacheLRU) Stats() CacheStats {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return CacheStats{
+		Entries:       len(c.items),
+		Bytes:         c.bytes,
+		PinnedEntries: len(c.pinned),
+		PinnedBytes:   c.pinnedBytes,
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Evictions:     c.evictions,
+	}
+}
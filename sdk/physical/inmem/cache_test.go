@@ -327,3 +327,134 @@ func TestCache_Refresh(t *testing.T) {
 	}
 
 }
+
+func TestCache_Bounds(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := physical.NewCache(inm, 0, logger)
+	cache.SetEnabled(true)
+	cache.SetCacheConfig(physical.CacheConfig{
+		MaxEntries:     3,
+		MaxBytes:       4096,
+		PinnedPrefixes: []string{"pinned/"},
+	})
+
+	put := func(key string, size int) {
+		t.Helper()
+		ent := &physical.Entry{
+			Key:   key,
+			Value: make([]byte, size),
+		}
+		if err := cache.Put(context.Background(), ent); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A read of a cached key counts as a hit. Note that the read of a key
+	// which is not cached adds it to the cache.
+	cached := func(key string) bool {
+		t.Helper()
+		hits := cache.CacheStats().Hits
+		if _, err := cache.Get(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+		return cache.CacheStats().Hits > hits
+	}
+
+	put("pinned/a", 1024)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		put(key, 16)
+	}
+
+	stats := cache.CacheStats()
+	if stats.Entries != 3 || stats.PinnedEntries != 1 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+	if !cached("pinned/a") || !cached("d") {
+		t.Fatal("expected keys to be cached")
+	}
+	if cached("a") {
+		t.Fatal("expected least recently used key to be evicted")
+	}
+
+	// Entries are evicted once over the size in bytes
+	put("e", 3072)
+	put("f", 1024)
+	if !cached("f") || !cached("pinned/a") {
+		t.Fatal("expected keys to be cached")
+	}
+	if cached("e") {
+		t.Fatal("expected key to be evicted by size")
+	}
+
+	stats = cache.CacheStats()
+	if stats.Misses != 2 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+
+	// Unpinned entries count against the bounds
+	cache.SetCacheConfig(physical.CacheConfig{
+		MaxEntries:     3,
+		MaxBytes:       4096,
+		PinnedPrefixes: []string{},
+	})
+	stats = cache.CacheStats()
+	if stats.PinnedEntries != 0 || stats.Entries > 3 || stats.Bytes > 4096 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+
+	config := cache.CacheConfig()
+	if config.MaxEntries != 3 || config.MaxBytes != 4096 || len(config.PinnedPrefixes) != 0 {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+	cache.SetCacheConfig(physical.CacheConfig{})
+	config = cache.CacheConfig()
+	if config.MaxEntries != physical.DefaultCacheSize || config.MaxBytes != physical.DefaultCacheMaxBytes || len(config.PinnedPrefixes) != len(physical.DefaultCachePinnedPrefixes) {
+		t.Fatalf("unexpected config: %#v", config)
+	}
+}
+
+func TestCache_PinnedMissingKeys(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+
+	inm, err := NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := physical.NewCache(inm, 0, logger)
+	cache.SetEnabled(true)
+	cache.SetCacheConfig(physical.CacheConfig{
+		MaxEntries:     2,
+		PinnedPrefixes: []string{"pinned/"},
+	})
+
+	// The absence of keys under a pinned prefix is cached, but not pinned,
+	// so that reads of missing keys cannot grow the cache without bounds
+	for _, key := range []string{"pinned/a", "pinned/b", "pinned/c"} {
+		ent, err := cache.Get(context.Background(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ent != nil {
+			t.Fatalf("unexpected entry: %#v", ent)
+		}
+	}
+
+	stats := cache.CacheStats()
+	if stats.PinnedEntries != 0 || stats.Entries != 2 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+
+	// Once the key exists, its entry is pinned
+	if err := cache.Put(context.Background(), &physical.Entry{Key: "pinned/c", Value: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+	stats = cache.CacheStats()
+	if stats.PinnedEntries != 1 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+}
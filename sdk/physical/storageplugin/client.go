@@ -0,0 +1,239 @@
+package storageplugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"os/exec"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/physical"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_ physical.Backend   = (*Backend)(nil)
+	_ physical.HABackend = (*Backend)(nil)
+	_ physical.Lock      = (*lock)(nil)
+)
+
+// Backend is the client of a storage plugin
+type Backend struct {
+	client    StorageClient
+	pc        *plugin.Client
+	haEnabled bool
+}
+
+// NewBackend runs the storage plugin started by the command and sets it up
+// with the configuration. If checksum is set, it must be the SHA256 sum of the
+// plugin executable.
+func NewBackend(ctx context.Context, cmd *exec.Cmd, checksum []byte, conf map[string]string, logger log.Logger) (*Backend, error) {
+	config := &plugin.ClientConfig{
+		HandshakeConfig: handshakeConfig,
+		Plugins: plugin.PluginSet{
+			PluginName: &GRPCStoragePlugin{},
+		},
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger:           logger,
+		AutoMTLS:         true,
+		Managed:          true,
+	}
+	if len(checksum) > 0 {
+		config.SecureConfig = &plugin.SecureConfig{
+			Checksum: checksum,
+			Hash:     sha256.New(),
+		}
+	}
+
+	pc := plugin.NewClient(config)
+	rpcClient, err := pc.Client()
+	if err != nil {
+		pc.Kill()
+		return nil, err
+	}
+	raw, err := rpcClient.Dispense(PluginName)
+	if err != nil {
+		pc.Kill()
+		return nil, err
+	}
+
+	b := raw.(*Backend)
+	b.pc = pc
+	if err := b.setup(ctx, conf); err != nil {
+		pc.Kill()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Backend) setup(ctx context.Context, conf map[string]string) error {
+	resp, err := b.client.Setup(ctx, &SetupRequest{
+		Config: conf,
+	})
+	if err != nil {
+		return fromGRPCError(err)
+	}
+	b.haEnabled = resp.HaEnabled
+	return nil
+}
+
+// Close stops the plugin
+func (b *Backend) Close() {
+	if b.pc != nil {
+		b.pc.Kill()
+	}
+}
+
+func (b *Backend) Put(ctx context.Context, entry *physical.Entry) error {
+	_, err := b.client.Put(ctx, &PutRequest{
+		Entry: &Entry{
+			Key:      entry.Key,
+			Value:    entry.Value,
+			SealWrap: entry.SealWrap,
+		},
+	})
+	return fromGRPCError(err)
+}
+
+func (b *Backend) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	resp, err := b.client.Get(ctx, &GetRequest{
+		Key: key,
+	})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	if resp.Entry == nil {
+		return nil, nil
+	}
+	return &physical.Entry{
+		Key:      resp.Entry.Key,
+		Value:    resp.Entry.Value,
+		SealWrap: resp.Entry.SealWrap,
+	}, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.Delete(ctx, &DeleteRequest{
+		Key: key,
+	})
+	return fromGRPCError(err)
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]string, error) {
+	resp, err := b.client.List(ctx, &ListRequest{
+		Prefix: prefix,
+	})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return resp.Keys, nil
+}
+
+func (b *Backend) HAEnabled() bool {
+	return b.haEnabled
+}
+
+func (b *Backend) LockWith(key, value string) (physical.Lock, error) {
+	resp, err := b.client.LockWith(context.Background(), &LockWithRequest{
+		Key:   key,
+		Value: value,
+	})
+	if err != nil {
+		return nil, fromGRPCError(err)
+	}
+	return &lock{
+		client: b.client,
+		id:     resp.LockID,
+	}, nil
+}
+
+// lock is a lock of the plugin. It is held for as long as its lock stream
+// is open.
+type lock struct {
+	client StorageClient
+	id     string
+
+	l      sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (l *lock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := l.client.Lock(ctx, &LockRequest{
+		LockID: l.id,
+	})
+	if err != nil {
+		cancel()
+		return nil, fromGRPCError(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		acquired <- err
+	}()
+
+	select {
+	case err := <-acquired:
+		if err != nil {
+			cancel()
+			return nil, fromGRPCError(err)
+		}
+	case <-stopCh:
+		cancel()
+		return nil, nil
+	}
+
+	l.l.Lock()
+	l.cancel = cancel
+	l.l.Unlock()
+
+	// The stream ends once the lock is lost, or the plugin is gone
+	leaderCh := make(chan struct{})
+	go func() {
+		stream.Recv()
+		close(leaderCh)
+	}()
+	return leaderCh, nil
+}
+
+func (l *lock) Unlock() error {
+	_, err := l.client.Unlock(context.Background(), &UnlockRequest{
+		LockID: l.id,
+	})
+
+	l.l.Lock()
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
+	}
+	l.l.Unlock()
+
+	return fromGRPCError(err)
+}
+
+func (l *lock) Value() (bool, string, error) {
+	resp, err := l.client.Value(context.Background(), &ValueRequest{
+		LockID: l.id,
+	})
+	if err != nil {
+		return false, "", fromGRPCError(err)
+	}
+	return resp.Held, resp.Value, nil
+}
+
+// fromGRPCError returns the errors of the backend of the plugin as they were
+// returned by it
+func fromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Unknown {
+		return errors.New(s.Message())
+	}
+	return err
+}
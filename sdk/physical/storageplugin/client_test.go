@@ -0,0 +1,132 @@
+package storageplugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	gplugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
+)
+
+func TestGRPCStoragePlugin_impl(t *testing.T) {
+	var _ gplugin.Plugin = new(GRPCStoragePlugin)
+	var _ physical.HABackend = new(Backend)
+}
+
+func TestGRPCStoragePlugin(t *testing.T) {
+	ha, err := inmem.NewInmemHA(nil, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, cleanup := testGRPCStorage(t, ha)
+	defer cleanup()
+	b2, cleanup2 := testGRPCStorage(t, ha)
+	defer cleanup2()
+
+	if !b.HAEnabled() {
+		t.Fatal("expected HA to be enabled")
+	}
+
+	physical.ExerciseBackend(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+	physical.ExerciseHABackend(t, b, b2)
+}
+
+func TestGRPCStoragePlugin_NotHA(t *testing.T) {
+	backend, err := inmem.NewInmem(nil, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, cleanup := testGRPCStorage(t, backend)
+	defer cleanup()
+
+	if b.HAEnabled() {
+		t.Fatal("expected HA to be disabled")
+	}
+	if _, err := b.LockWith("foo", "bar"); err == nil {
+		t.Fatal("expected an error locking without HA")
+	}
+}
+
+// The lock of a client which went away is released for another to take it
+func TestGRPCStoragePlugin_LockReleasedWithClient(t *testing.T) {
+	ha, err := inmem.NewInmemHA(nil, logging.NewVaultLogger(log.Debug))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, cleanup := testGRPCStorage(t, ha)
+	b2, cleanup2 := testGRPCStorage(t, ha)
+	defer cleanup2()
+
+	lock, err := b.LockWith("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaderCh, err := lock.Lock(nil)
+	if err != nil || leaderCh == nil {
+		t.Fatalf("failed to lock: %v", err)
+	}
+
+	lock2, err := b2.LockWith("foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, value, err := lock2.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !held || value != "bar" {
+		t.Fatalf("bad: held %v value %q", held, value)
+	}
+
+	cleanup()
+	select {
+	case <-leaderCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader channel should be closed once the connection is gone")
+	}
+
+	stopCh := make(chan struct{})
+	time.AfterFunc(5*time.Second, func() { close(stopCh) })
+	leaderCh2, err := lock2.Lock(stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaderCh2 == nil {
+		t.Fatal("lock of the closed client was not released")
+	}
+	lock2.Unlock()
+}
+
+func testGRPCStorage(t *testing.T, backend physical.Backend) (*Backend, func()) {
+	factory := func(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+		return backend, nil
+	}
+	pluginMap := map[string]gplugin.Plugin{
+		PluginName: &GRPCStoragePlugin{
+			Factory: factory,
+			Logger:  logging.NewVaultLogger(log.Debug),
+		},
+	}
+	client, _ := gplugin.TestPluginGRPCConn(t, pluginMap)
+	cleanup := func() {
+		client.Close()
+	}
+
+	raw, err := client.Dispense(PluginName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := raw.(*Backend)
+	if err := b.setup(context.Background(), map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	return b, cleanup
+}
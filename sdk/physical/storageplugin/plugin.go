@@ -0,0 +1,54 @@
+// Package storageplugin runs storage backends out of process, as plugins
+// speaking gRPC to Vault, so that backends can be maintained outside of the
+// Vault repository.
+package storageplugin
+
+import (
+	"context"
+
+	log "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/physical"
+	"google.golang.org/grpc"
+)
+
+// PluginName is the name of the plugin that can be dispensed from the plugin
+// server.
+const PluginName = "storage"
+
+// handshakeConfig is used to just do a basic handshake between a plugin and
+// host. If the handshake fails, a user friendly error is shown. This prevents
+// users from executing bad plugins or executing a plugin directory. It is a UX
+// feature, not a security feature.
+var handshakeConfig = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "VAULT_STORAGE_PLUGIN",
+	MagicCookieValue: "1c5e1e8f-3c6e-4b47-9c1f-5b8a0d7f2e64",
+}
+
+// GRPCStoragePlugin is the go-plugin implementation of storage plugins. The
+// factory is only used on the plugin side.
+type GRPCStoragePlugin struct {
+	Factory physical.Factory
+	Logger  log.Logger
+
+	// Embeding this will disable the netRPC protocol
+	plugin.NetRPCUnsupportedPlugin
+}
+
+var _ plugin.GRPCPlugin = (*GRPCStoragePlugin)(nil)
+
+func (p *GRPCStoragePlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	RegisterStorageServer(s, &gRPCServer{
+		factory: p.Factory,
+		logger:  p.Logger,
+		locks:   make(map[string]*serverLock),
+	})
+	return nil
+}
+
+func (p *GRPCStoragePlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &Backend{
+		client: NewStorageClient(c),
+	}, nil
+}
@@ -0,0 +1,57 @@
+package storageplugin
+
+import (
+	"math"
+	"os"
+
+	log "github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+	"github.com/hashicorp/vault/sdk/physical"
+	"google.golang.org/grpc"
+)
+
+type ServeOpts struct {
+	// Factory creates the backend from the configuration of the storage
+	// stanza, without the keys configuring the plugin itself
+	Factory physical.Factory
+	Logger  log.Logger
+}
+
+// Serve is a helper function used to serve a storage plugin. This should be
+// ran on the plugin's main process.
+func Serve(opts *ServeOpts) error {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.New(&log.LoggerOptions{
+			Level:      log.Trace,
+			Output:     os.Stderr,
+			JSONFormat: true,
+		})
+	}
+
+	err := pluginutil.OptionallyEnableMlock()
+	if err != nil {
+		return err
+	}
+
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: handshakeConfig,
+		Plugins: plugin.PluginSet{
+			PluginName: &GRPCStoragePlugin{
+				Factory: opts.Factory,
+				Logger:  logger,
+			},
+		},
+		Logger: logger,
+
+		// A non-nil value here enables gRPC serving for this plugin...
+		GRPCServer: func(opts []grpc.ServerOption) *grpc.Server {
+			opts = append(opts, grpc.MaxRecvMsgSize(math.MaxInt32))
+			opts = append(opts, grpc.MaxSendMsgSize(math.MaxInt32))
+			return plugin.DefaultGRPCServer(opts)
+		},
+	})
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package storageplugin
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/physical"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errNotSetup   = status.Error(codes.FailedPrecondition, "storage plugin is not set up")
+	errNoSuchLock = status.Error(codes.NotFound, "no such lock")
+)
+
+var _ StorageServer = (*gRPCServer)(nil)
+
+// gRPCServer serves the backend created by the factory of the plugin
+type gRPCServer struct {
+	factory physical.Factory
+	logger  log.Logger
+
+	l       sync.RWMutex
+	backend physical.Backend
+	locks   map[string]*serverLock
+}
+
+// serverLock is a lock created by the client. Once held, it is released either
+// by the client or when its lock stream ends.
+type serverLock struct {
+	lock physical.Lock
+
+	l        sync.Mutex
+	held     bool
+	released chan struct{}
+}
+
+// release must be called with the lock held
+func (l *serverLock) release() error {
+	if !l.held {
+		return l.lock.Unlock()
+	}
+	l.held = false
+	close(l.released)
+	return l.lock.Unlock()
+}
+
+func (s *gRPCServer) Setup(ctx context.Context, req *SetupRequest) (*SetupResponse, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.backend != nil {
+		return nil, status.Error(codes.FailedPrecondition, "storage plugin is already set up")
+	}
+
+	backend, err := s.factory(req.Config, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	s.backend = backend
+
+	ha, ok := backend.(physical.HABackend)
+	return &SetupResponse{
+		HaEnabled: ok && ha.HAEnabled(),
+	}, nil
+}
+
+func (s *gRPCServer) getBackend() (physical.Backend, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	if s.backend == nil {
+		return nil, errNotSetup
+	}
+	return s.backend, nil
+}
+
+func (s *gRPCServer) Put(ctx context.Context, req *PutRequest) (*Empty, error) {
+	backend, err := s.getBackend()
+	if err != nil {
+		return nil, err
+	}
+	if req.Entry == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing entry")
+	}
+
+	err = backend.Put(ctx, &physical.Entry{
+		Key:      req.Entry.Key,
+		Value:    req.Entry.Value,
+		SealWrap: req.Entry.SealWrap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *gRPCServer) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	backend, err := s.getBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := backend.Get(ctx, req.Key)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return &GetResponse{}, nil
+	}
+	return &GetResponse{
+		Entry: &Entry{
+			Key:      entry.Key,
+			Value:    entry.Value,
+			SealWrap: entry.SealWrap,
+		},
+	}, nil
+}
+
+func (s *gRPCServer) Delete(ctx context.Context, req *DeleteRequest) (*Empty, error) {
+	backend, err := s.getBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := backend.Delete(ctx, req.Key); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *gRPCServer) List(ctx context.Context, req *ListRequest) (*ListResponse, error) {
+	backend, err := s.getBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := backend.List(ctx, req.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &ListResponse{
+		Keys: keys,
+	}, nil
+}
+
+func (s *gRPCServer) LockWith(ctx context.Context, req *LockWithRequest) (*LockWithResponse, error) {
+	backend, err := s.getBackend()
+	if err != nil {
+		return nil, err
+	}
+	ha, ok := backend.(physical.HABackend)
+	if !ok || !ha.HAEnabled() {
+		return nil, status.Error(codes.Unimplemented, "storage plugin does not support HA")
+	}
+
+	lock, err := ha.LockWith(req.Key, req.Value)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	s.l.Lock()
+	s.locks[id] = &serverLock{
+		lock: lock,
+	}
+	s.l.Unlock()
+
+	return &LockWithResponse{
+		LockID: id,
+	}, nil
+}
+
+func (s *gRPCServer) getLock(id string) (*serverLock, error) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	lock, ok := s.locks[id]
+	if !ok {
+		return nil, errNoSuchLock
+	}
+	return lock, nil
+}
+
+// Lock acquires the lock, then holds it until either the lock is lost, the
+// client releases it, or the stream ends. The latter means the client is gone,
+// in which case the lock is released so that another node can take over.
+func (s *gRPCServer) Lock(req *LockRequest, stream Storage_LockServer) error {
+	lock, err := s.getLock(req.LockID)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	stopCh := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(stopCh)
+	}()
+
+	leaderCh, err := lock.lock.Lock(stopCh)
+	if err != nil {
+		return err
+	}
+	if leaderCh == nil {
+		return status.Error(codes.Canceled, "lock attempt stopped")
+	}
+
+	lock.l.Lock()
+	lock.held = true
+	lock.released = make(chan struct{})
+	released := lock.released
+	lock.l.Unlock()
+
+	if err := stream.Send(&LockResponse{Held: true}); err != nil {
+		lock.l.Lock()
+		if lock.held {
+			lock.release()
+		}
+		lock.l.Unlock()
+		return err
+	}
+
+	select {
+	case <-leaderCh:
+		return nil
+	case <-released:
+		return nil
+	case <-ctx.Done():
+		lock.l.Lock()
+		defer lock.l.Unlock()
+		if lock.held {
+			s.logger.Warn("releasing lock of a client which went away")
+			if err := lock.release(); err != nil {
+				s.logger.Error("failed to release lock", "error", err)
+			}
+		}
+		return nil
+	}
+}
+
+func (s *gRPCServer) Unlock(ctx context.Context, req *UnlockRequest) (*Empty, error) {
+	lock, err := s.getLock(req.LockID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.l.Lock()
+	delete(s.locks, req.LockID)
+	s.l.Unlock()
+
+	lock.l.Lock()
+	defer lock.l.Unlock()
+	if err := lock.release(); err != nil {
+		return nil, err
+	}
+	return &Empty{}, nil
+}
+
+func (s *gRPCServer) Value(ctx context.Context, req *ValueRequest) (*ValueResponse, error) {
+	lock, err := s.getLock(req.LockID)
+	if err != nil {
+		return nil, err
+	}
+
+	held, value, err := lock.lock.Value()
+	if err != nil {
+		return nil, err
+	}
+	return &ValueResponse{
+		Held:  held,
+		Value: value,
+	}, nil
+}
@@ -44,6 +44,17 @@ type backendGRPCPluginClient struct {
 	// so it can be cleaned up.
 	clientConn *grpc.ClientConn
 	doneCtx    context.Context
+
+	// multiplexingID identifies the backend in a plugin process shared with
+	// other mounts, whose connection is left open on Cleanup.
+	multiplexingID string
+}
+
+// setMultiplexingID makes the client send its requests to the backend of the
+// given ID in a shared plugin process.
+func (b *backendGRPCPluginClient) setMultiplexingID(id string) {
+	b.multiplexingID = id
+	b.client = pb.NewBackendClient(pluginutil.NewMultiplexedClientConn(b.clientConn, id))
 }
 
 func (b *backendGRPCPluginClient) Initialize(ctx context.Context, _ *logical.InitializationRequest) error {
@@ -192,7 +203,9 @@ func (b *backendGRPCPluginClient) Cleanup(ctx context.Context) {
 	if server != nil {
 		server.(*grpc.Server).GracefulStop()
 	}
-	b.clientConn.Close()
+	if b.multiplexingID == "" {
+		b.clientConn.Close()
+	}
 }
 
 func (b *backendGRPCPluginClient) InvalidateKey(ctx context.Context, key string) {
@@ -3,6 +3,8 @@ package plugin
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 
 	log "github.com/hashicorp/go-hclog"
 	plugin "github.com/hashicorp/go-plugin"
@@ -14,17 +16,42 @@ import (
 
 var ErrServerInMetadataMode = errors.New("plugin server can not perform action while in metadata mode")
 
+// backendInstance is the backend of a mount served by the plugin process,
+// along with its brokered connection to Vault.
+type backendInstance struct {
+	brokeredClient *grpc.ClientConn
+	backend        logical.Backend
+}
+
+// backendGRPCPluginServer serves the backends of the mounts using the plugin
+// process. A process shared by several mounts serves a backend per mount,
+// identified by the multiplexing ID of the requests.
 type backendGRPCPluginServer struct {
-	broker  *plugin.GRPCBroker
-	backend logical.Backend
+	broker *plugin.GRPCBroker
 
-	factory logical.Factory
+	instances     map[string]backendInstance
+	instancesLock sync.RWMutex
 
-	brokeredClient *grpc.ClientConn
+	factory logical.Factory
 
 	logger log.Logger
 }
 
+// getBackendAndBrokeredClient returns the backend instance of the mount the
+// request is made for.
+func (b *backendGRPCPluginServer) getBackendAndBrokeredClient(ctx context.Context) (logical.Backend, *grpc.ClientConn, error) {
+	id := pluginutil.GetMultiplexIDFromContext(ctx)
+
+	b.instancesLock.RLock()
+	defer b.instancesLock.RUnlock()
+
+	instance, ok := b.instances[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("no backend instance found for multiplexing ID %q", id)
+	}
+	return instance.backend, instance.brokeredClient, nil
+}
+
 // Setup dials into the plugin's broker to get a shimmed storage, logger, and
 // system view of the backend. This method also instantiates the underlying
 // backend through its factory func for the server side of the plugin.
@@ -34,7 +61,6 @@ func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs)
 	if err != nil {
 		return &pb.SetupReply{}, err
 	}
-	b.brokeredClient = brokeredClient
 	storage := newGRPCStorageClient(brokeredClient)
 	sysView := newGRPCSystemView(brokeredClient)
 
@@ -47,14 +73,24 @@ func (b *backendGRPCPluginServer) Setup(ctx context.Context, args *pb.SetupArgs)
 	}
 
 	// Call the underlying backend factory after shims have been created
-	// to set b.backend
+	// to create the backend of the mount
 	backend, err := b.factory(ctx, config)
 	if err != nil {
+		brokeredClient.Close()
 		return &pb.SetupReply{
 			Err: pb.ErrToString(err),
 		}, nil
 	}
-	b.backend = backend
+
+	b.instancesLock.Lock()
+	defer b.instancesLock.Unlock()
+	if b.instances == nil {
+		b.instances = make(map[string]backendInstance)
+	}
+	b.instances[pluginutil.GetMultiplexIDFromContext(ctx)] = backendInstance{
+		brokeredClient: brokeredClient,
+		backend:        backend,
+	}
 
 	return &pb.SetupReply{}, nil
 }
@@ -69,9 +105,14 @@ func (b *backendGRPCPluginServer) HandleRequest(ctx context.Context, args *pb.Ha
 		return &pb.HandleRequestReply{}, err
 	}
 
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.HandleRequestReply{}, err
+	}
+
+	logicalReq.Storage = newGRPCStorageClient(brokeredClient)
 
-	resp, respErr := b.backend.HandleRequest(ctx, logicalReq)
+	resp, respErr := backend.HandleRequest(ctx, logicalReq)
 
 	pbResp, err := pb.LogicalResponseToProtoResponse(resp)
 	if err != nil {
@@ -89,11 +130,16 @@ func (b *backendGRPCPluginServer) Initialize(ctx context.Context, _ *pb.Initiali
 		return &pb.InitializeReply{}, ErrServerInMetadataMode
 	}
 
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.InitializeReply{}, err
+	}
+
 	req := &logical.InitializationRequest{
-		Storage: newGRPCStorageClient(b.brokeredClient),
+		Storage: newGRPCStorageClient(brokeredClient),
 	}
 
-	respErr := b.backend.Initialize(ctx, req)
+	respErr := backend.Initialize(ctx, req)
 
 	return &pb.InitializeReply{
 		Err: pb.ErrToProtoErr(respErr),
@@ -101,7 +147,12 @@ func (b *backendGRPCPluginServer) Initialize(ctx context.Context, _ *pb.Initiali
 }
 
 func (b *backendGRPCPluginServer) SpecialPaths(ctx context.Context, args *pb.Empty) (*pb.SpecialPathsReply, error) {
-	paths := b.backend.SpecialPaths()
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.SpecialPathsReply{}, err
+	}
+
+	paths := backend.SpecialPaths()
 	if paths == nil {
 		return &pb.SpecialPathsReply{
 			Paths: nil,
@@ -127,9 +178,14 @@ func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args
 	if err != nil {
 		return &pb.HandleExistenceCheckReply{}, err
 	}
-	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.HandleExistenceCheckReply{}, err
+	}
+
+	logicalReq.Storage = newGRPCStorageClient(brokeredClient)
 
-	checkFound, exists, err := b.backend.HandleExistenceCheck(ctx, logicalReq)
+	checkFound, exists, err := backend.HandleExistenceCheck(ctx, logicalReq)
 	return &pb.HandleExistenceCheckReply{
 		CheckFound: checkFound,
 		Exists:     exists,
@@ -138,10 +194,20 @@ func (b *backendGRPCPluginServer) HandleExistenceCheck(ctx context.Context, args
 }
 
 func (b *backendGRPCPluginServer) Cleanup(ctx context.Context, _ *pb.Empty) (*pb.Empty, error) {
-	b.backend.Cleanup(ctx)
+	backend, brokeredClient, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.Empty{}, err
+	}
+
+	backend.Cleanup(ctx)
 
 	// Close rpc clients
-	b.brokeredClient.Close()
+	brokeredClient.Close()
+
+	b.instancesLock.Lock()
+	delete(b.instances, pluginutil.GetMultiplexIDFromContext(ctx))
+	b.instancesLock.Unlock()
+
 	return &pb.Empty{}, nil
 }
 
@@ -150,12 +216,22 @@ func (b *backendGRPCPluginServer) InvalidateKey(ctx context.Context, args *pb.In
 		return &pb.Empty{}, ErrServerInMetadataMode
 	}
 
-	b.backend.InvalidateKey(ctx, args.Key)
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.Empty{}, err
+	}
+
+	backend.InvalidateKey(ctx, args.Key)
 	return &pb.Empty{}, nil
 }
 
 func (b *backendGRPCPluginServer) Type(ctx context.Context, _ *pb.Empty) (*pb.TypeReply, error) {
+	backend, _, err := b.getBackendAndBrokeredClient(ctx)
+	if err != nil {
+		return &pb.TypeReply{}, err
+	}
+
 	return &pb.TypeReply{
-		Type: uint32(b.backend.Type()),
+		Type: uint32(backend.Type()),
 	}, nil
 }
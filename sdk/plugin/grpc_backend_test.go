@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -147,6 +148,86 @@ func TestGRPCBackendPlugin_Initialize(t *testing.T) {
 	}
 }
 
+func TestGRPCBackendPlugin_Multiplexing(t *testing.T) {
+	pluginMap := map[string]gplugin.Plugin{
+		"backend": &GRPCBackendPlugin{
+			Factory: mock.Factory,
+			Logger:  log.NewNullLogger(),
+		},
+	}
+	client, _ := gplugin.TestPluginGRPCConn(t, pluginMap)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	// Two mounts share the plugin process, each with its own backend
+	backends := make([]logical.Backend, 2)
+	for i, id := range []string{"mount-1", "mount-2"} {
+		raw, err := client.Dispense(BackendPluginName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := raw.(*backendGRPCPluginClient)
+		b.setMultiplexingID(id)
+
+		err = b.Setup(ctx, &logical.BackendConfig{
+			Logger:      logging.NewVaultLogger(log.Debug),
+			System:      &logical.StaticSystemView{},
+			StorageView: &logical.InmemStorage{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		backends[i] = b
+	}
+
+	for i, b := range backends {
+		_, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "internal",
+			Data: map[string]interface{}{
+				"value": fmt.Sprintf("value-%d", i),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, b := range backends {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "internal",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data["value"] != fmt.Sprintf("value-%d", i) {
+			t.Fatalf("bad: %#v", resp)
+		}
+	}
+
+	// Cleaning up a backend leaves the connection open for the other one
+	backends[0].Cleanup(ctx)
+
+	if _, err := backends[0].HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "internal",
+	}); err == nil {
+		t.Fatal("expected error after cleanup")
+	}
+	resp, err := backends[1].HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "internal",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["value"] != "value-1" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
+
 func testGRPCBackend(t *testing.T) (logical.Backend, func()) {
 	// Create a mock provider
 	pluginMap := map[string]gplugin.Plugin{
@@ -172,10 +172,18 @@ func ProtoSecretToLogicalSecret(s *Secret) (*logical.Secret, error) {
 		return nil, err
 	}
 
+	var tags map[string]string
+	if s.Tags != "" {
+		if err := json.Unmarshal([]byte(s.Tags), &tags); err != nil {
+			return nil, err
+		}
+	}
+
 	return &logical.Secret{
 		LeaseOptions: lease,
 		InternalData: data,
 		LeaseID:      s.LeaseID,
+		Tags:         tags,
 	}, nil
 }
 
@@ -194,10 +202,20 @@ func LogicalSecretToProtoSecret(s *logical.Secret) (*Secret, error) {
 		return nil, err
 	}
 
+	var tags string
+	if len(s.Tags) > 0 {
+		tagsBuf, err := json.Marshal(s.Tags)
+		if err != nil {
+			return nil, err
+		}
+		tags = string(tagsBuf)
+	}
+
 	return &Secret{
 		LeaseOptions: lease,
 		InternalData: string(buf[:]),
 		LeaseID:      s.LeaseID,
+		Tags:         tags,
 	}, err
 }
 
@@ -226,6 +244,15 @@ func LogicalRequestToProtoRequest(r *logical.Request) (*Request, error) {
 		headers[k] = &Header{Header: v}
 	}
 
+	var mfaCreds string
+	if len(r.MFACreds) > 0 {
+		mfaCredsBuf, err := json.Marshal(r.MFACreds)
+		if err != nil {
+			return nil, err
+		}
+		mfaCreds = string(mfaCredsBuf)
+	}
+
 	return &Request{
 		ID:                       r.ID,
 		ReplicationCluster:       r.ReplicationCluster,
@@ -247,6 +274,7 @@ func LogicalRequestToProtoRequest(r *logical.Request) (*Request, error) {
 		EntityID:                 r.EntityID,
 		PolicyOverride:           r.PolicyOverride,
 		Unauthenticated:          r.Unauthenticated,
+		MFACreds:                 mfaCreds,
 	}, nil
 }
 
@@ -279,6 +307,13 @@ func ProtoRequestToLogicalRequest(r *Request) (*logical.Request, error) {
 		}
 	}
 
+	var mfaCreds logical.MFACreds
+	if r.MFACreds != "" {
+		if err := json.Unmarshal([]byte(r.MFACreds), &mfaCreds); err != nil {
+			return nil, err
+		}
+	}
+
 	return &logical.Request{
 		ID:                       r.ID,
 		ReplicationCluster:       r.ReplicationCluster,
@@ -300,6 +335,7 @@ func ProtoRequestToLogicalRequest(r *Request) (*logical.Request, error) {
 		EntityID:                 r.EntityID,
 		PolicyOverride:           r.PolicyOverride,
 		Unauthenticated:          r.Unauthenticated,
+		MFACreds:                 mfaCreds,
 	}, nil
 }
 
@@ -154,6 +154,9 @@ func TestTranslation_Request(t *testing.T) {
 			EntityID:                 "tester",
 			PolicyOverride:           true,
 			Unauthenticated:          true,
+			MFACreds: logical.MFACreds{
+				"totp": []string{"123456"},
+			},
 		},
 	}
 
@@ -203,6 +206,9 @@ func TestTranslation_Response(t *testing.T) {
 					"role": "test",
 				},
 				LeaseID: "LeaseID",
+				Tags: map[string]string{
+					"app": "checkout",
+				},
 			},
 			Auth: &logical.Auth{
 				LeaseOptions: logical.LeaseOptions{
@@ -15,20 +15,20 @@ import (
 )
 
 // BackendPluginClient is a wrapper around backendPluginClient
-// that also contains its plugin.Client instance. It's primarily
-// used to cleanly kill the client on Cleanup()
+// that also contains its plugin client. It's primarily
+// used to cleanly release the plugin process on Cleanup()
 type BackendPluginClient struct {
-	client *plugin.Client
+	client pluginutil.PluginClient
 	sync.Mutex
 
 	logical.Backend
 }
 
-// Cleanup calls the RPC client's Cleanup() func and also calls
-// the go-plugin's client Kill() func
+// Cleanup calls the RPC client's Cleanup() func and also releases the plugin
+// process, which is killed unless it is shared with other mounts
 func (b *BackendPluginClient) Cleanup(ctx context.Context) {
 	b.Backend.Cleanup(ctx)
-	b.client.Kill()
+	b.client.Close()
 }
 
 // NewBackend will return an instance of an RPC-based client implementation of the backend for
@@ -86,30 +86,56 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 				MetadataMode: isMetadataMode,
 			},
 		},
+		// Version 5 serves the backends of several mounts in a single
+		// process.
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				MetadataMode: isMetadataMode,
+			},
+		},
 	}
 
 	namedLogger := logger.Named(pluginRunner.Name)
 
-	var client *plugin.Client
-	var err error
-	if isMetadataMode {
-		client, err = pluginRunner.RunMetadataMode(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
-	} else {
-		client, err = pluginRunner.Run(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	// Connect via RPC
-	rpcClient, err := client.Client()
-	if err != nil {
-		return nil, err
+	var client pluginutil.PluginClient
+	clientRunner, multiplexed := sys.(pluginutil.PluginClientRunner)
+	switch {
+	case isMetadataMode:
+		// Metadata mode processes are short-lived and never shared
+		rawClient, err := pluginRunner.RunMetadataMode(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
+		if err != nil {
+			return nil, err
+		}
+		client, err = pluginutil.NewDedicatedPluginClient(rawClient)
+		if err != nil {
+			return nil, err
+		}
+	case multiplexed:
+		var err error
+		client, err = clientRunner.NewPluginClient(ctx, pluginRunner, pluginutil.PluginClientConfig{
+			PluginSets:          pluginSet,
+			HandshakeConfig:     handshakeConfig,
+			Logger:              namedLogger,
+			MultiplexingVersion: multiplexingProtocolVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		rawClient, err := pluginRunner.Run(ctx, sys, pluginSet, handshakeConfig, []string{}, namedLogger)
+		if err != nil {
+			return nil, err
+		}
+		client, err = pluginutil.NewDedicatedPluginClient(rawClient)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Request the plugin
-	raw, err := rpcClient.Dispense("backend")
+	raw, err := client.Dispense("backend")
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
@@ -119,9 +145,14 @@ func NewPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, pluginRunne
 	// implementation but is in fact over an RPC connection.
 	switch raw.(type) {
 	case *backendGRPCPluginClient:
-		backend = raw.(*backendGRPCPluginClient)
+		grpcClient := raw.(*backendGRPCPluginClient)
+		if id := client.MultiplexingID(); id != "" {
+			grpcClient.setMultiplexingID(id)
+		}
+		backend = grpcClient
 		transport = "gRPC"
 	default:
+		client.Close()
 		return nil, errors.New("unsupported plugin client type")
 	}
 
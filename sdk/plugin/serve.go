@@ -55,6 +55,14 @@ func Serve(opts *ServeOpts) error {
 				Logger:  logger,
 			},
 		},
+		// Version 5 serves the backends of several mounts in a single
+		// process.
+		multiplexingProtocolVersion: plugin.PluginSet{
+			"backend": &GRPCBackendPlugin{
+				Factory: opts.BackendFactoryFunc,
+				Logger:  logger,
+			},
+		},
 	}
 
 	err := pluginutil.OptionallyEnableMlock()
@@ -81,6 +89,11 @@ func Serve(opts *ServeOpts) error {
 	return nil
 }
 
+// multiplexingProtocolVersion is the lowest protocol version of the plugins
+// serving the backends of several mounts in a single process, identified by
+// the multiplexing ID of the requests.
+const multiplexingProtocolVersion = 5
+
 // handshakeConfigs are used to just do a basic handshake between
 // a plugin and host. If the handshake fails, a user friendly error is shown.
 // This prevents users from executing bad plugins or executing a plugin
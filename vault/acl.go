@@ -6,12 +6,15 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/go-multierror"
+	sockaddr "github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/cidrutil"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/mitchellh/copystructure"
@@ -55,6 +58,10 @@ type ACLResults struct {
 	MFAMethods         []string
 	ControlGroup       *ControlGroup
 	CapabilitiesBitmap uint32
+
+	// MatchedPath is the path of the policy rule matching the request, with a
+	// trailing * for prefix rules. It is empty if no rule matched.
+	MatchedPath string
 }
 
 // NewACL is used to construct a policy based ACL from a set of policies.
@@ -127,6 +134,7 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				if err != nil {
 					return nil, errwrap.Wrapf("error cloning ACL permissions: {{err}}", err)
 				}
+				clonedPerms.ConditionGrants = []*ConditionGrant{newConditionGrant(pc.Permissions)}
 				switch {
 				case pc.HasSegmentWildcards:
 					a.segmentWildcardPaths[pc.Path] = clonedPerms
@@ -150,6 +158,7 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.ConditionGrants = nil
 				goto INSERT
 
 			default:
@@ -260,6 +269,19 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				}
 			}
 
+			// The conditions of each policy are kept with the capabilities it
+			// grants, so that they do not restrict those granted by the other
+			// policies
+			existingPerms.ConditionGrants = append(existingPerms.ConditionGrants, newConditionGrant(pc.Permissions))
+			if len(pc.Permissions.ParameterConstraints) > 0 {
+				if existingPerms.ParameterConstraints == nil {
+					existingPerms.ParameterConstraints = make(map[string][]*ParameterConstraint, len(pc.Permissions.ParameterConstraints))
+				}
+				for key, constraints := range pc.Permissions.ParameterConstraints {
+					existingPerms.ParameterConstraints[key] = append(existingPerms.ParameterConstraints[key], constraints...)
+				}
+			}
+
 		INSERT:
 			switch {
 			case pc.HasSegmentWildcards:
@@ -285,8 +307,12 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 		return []string{RootCapability}
 	}
 
-	capabilities := res.CapabilitiesBitmap
+	return capabilitiesFromBitmap(res.CapabilitiesBitmap)
+}
 
+// capabilitiesFromBitmap returns the names of the capabilities of a bitmap, or
+// only "deny" if it is denied or has no capabilities at all.
+func capabilitiesFromBitmap(capabilities uint32) (pathCapabilities []string) {
 	if capabilities&SudoCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, SudoCapability)
 	}
@@ -305,6 +331,9 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 	if capabilities&CreateCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, CreateCapability)
 	}
+	if capabilities&ProfileCapabilityInt > 0 {
+		pathCapabilities = append(pathCapabilities, ProfileCapability)
+	}
 
 	// If "deny" is explicitly set or if the path has no capabilities at all,
 	// set the path capabilities to "deny"
@@ -357,6 +386,7 @@ func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheck
 	if ok {
 		permissions = raw.(*ACLPermissions)
 		capabilities = permissions.CapabilitiesBitmap
+		ret.MatchedPath = path
 		goto CHECK
 	}
 	if op == logical.ListOperation {
@@ -364,11 +394,12 @@ func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheck
 		if ok {
 			permissions = raw.(*ACLPermissions)
 			capabilities = permissions.CapabilitiesBitmap
+			ret.MatchedPath = strings.TrimSuffix(path, "/")
 			goto CHECK
 		}
 	}
 
-	permissions = a.CheckAllowedFromNonExactPaths(path, false)
+	ret.MatchedPath, permissions = a.checkAllowedFromNonExactPaths(path, false)
 	if permissions != nil {
 		capabilities = permissions.CapabilitiesBitmap
 		goto CHECK
@@ -383,40 +414,46 @@ CHECK:
 	// If "deny" has been explicitly set, only deny will be in the map, so we
 	// only need to check for the existence of other values
 	ret.RootPrivs = capabilities&SudoCapabilityInt > 0
+	ret.CapabilitiesBitmap = capabilities
 
 	// This is after the RootPrivs check so we can gate on it being from sudo
 	// rather than policy root
 	if capCheckOnly {
-		ret.CapabilitiesBitmap = capabilities
 		return ret
 	}
 
 	ret.MFAMethods = permissions.MFAMethods
 	ret.ControlGroup = permissions.ControlGroup
 
-	operationAllowed := false
+	var requiredCapability uint32
 	switch op {
 	case logical.ReadOperation:
-		operationAllowed = capabilities&ReadCapabilityInt > 0
+		// Profiles of the server require their own capability, so that
+		// reading sys/ does not give access to them
+		if strings.HasPrefix(req.Path, monitorProfilesPathPrefix) {
+			requiredCapability = ProfileCapabilityInt
+		} else {
+			requiredCapability = ReadCapabilityInt
+		}
 	case logical.ListOperation:
-		operationAllowed = capabilities&ListCapabilityInt > 0
+		requiredCapability = ListCapabilityInt
 	case logical.UpdateOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+		requiredCapability = UpdateCapabilityInt
 	case logical.DeleteOperation:
-		operationAllowed = capabilities&DeleteCapabilityInt > 0
+		requiredCapability = DeleteCapabilityInt
 	case logical.CreateOperation:
-		operationAllowed = capabilities&CreateCapabilityInt > 0
+		requiredCapability = CreateCapabilityInt
 
 	// These three re-use UpdateCapabilityInt since that's the most appropriate
 	// capability/operation mapping
 	case logical.RevokeOperation, logical.RenewOperation, logical.RollbackOperation:
-		operationAllowed = capabilities&UpdateCapabilityInt > 0
+		requiredCapability = UpdateCapabilityInt
 
 	default:
 		return
 	}
 
-	if !operationAllowed {
+	if capabilities&requiredCapability == 0 {
 		return
 	}
 
@@ -438,6 +475,10 @@ CHECK:
 		return
 	}
 
+	if !conditionGrantsAllow(permissions.ConditionGrants, requiredCapability, req) {
+		return
+	}
+
 	// Only check parameter permissions for operations that can modify
 	// parameters.
 	if op == logical.ReadOperation || op == logical.UpdateOperation || op == logical.CreateOperation {
@@ -447,6 +488,13 @@ CHECK:
 			}
 		}
 
+		for parameter, value := range req.Data {
+			constraints, ok := permissions.ParameterConstraints[strings.ToLower(parameter)]
+			if ok && !valueSatisfiesConstraints(value, constraints) {
+				return
+			}
+		}
+
 		// If there are no data fields, allow
 		if len(req.Data) == 0 {
 			ret.Allowed = true
@@ -518,6 +566,13 @@ type wcPathDescr struct {
 // of permissions from some allowed path underneath the mount (for use in mount
 // access checks), or nil indicating no non-deny permissions were found.
 func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPermissions {
+	_, permissions := a.checkAllowedFromNonExactPaths(path, bareMount)
+	return permissions
+}
+
+// checkAllowedFromNonExactPaths is CheckAllowedFromNonExactPaths, also
+// returning the path of the matching rule.
+func (a *ACL) checkAllowedFromNonExactPaths(path string, bareMount bool) (string, *ACLPermissions) {
 	wcPathDescrs := make([]wcPathDescr, 0, len(a.segmentWildcardPaths)+1)
 
 	less := func(i, j int) bool {
@@ -581,7 +636,7 @@ func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPer
 		prefix, raw, ok := a.prefixRules.LongestPrefix(path)
 		if ok {
 			if len(a.segmentWildcardPaths) == 0 {
-				return raw.(*ACLPermissions)
+				return prefix + "*", raw.(*ACLPermissions)
 			}
 			wcPathDescrs = append(wcPathDescrs, wcPathDescr{
 				firstWCOrGlob: len(prefix),
@@ -593,7 +648,7 @@ func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPer
 	}
 
 	if len(a.segmentWildcardPaths) == 0 {
-		return nil
+		return "", nil
 	}
 
 	pathParts := strings.Split(path, "/")
@@ -649,7 +704,7 @@ SWCPATH:
 				if strings.HasPrefix(joinedPath, path) {
 					permissions := a.segmentWildcardPaths[fullWCPath].(*ACLPermissions)
 					if permissions.CapabilitiesBitmap&DenyCapabilityInt == 0 && permissions.CapabilitiesBitmap > 0 {
-						return permissions
+						return fullWCPath, permissions
 					}
 				}
 				continue SWCPATH
@@ -660,14 +715,18 @@ SWCPATH:
 	}
 
 	if bareMount || len(wcPathDescrs) == 0 {
-		return nil
+		return "", nil
 	}
 
 	// We don't do this in the bare mount check because we don't care about
 	// priority, we only care about any capability at all.
 	sort.Slice(wcPathDescrs, less)
 
-	return wcPathDescrs[len(wcPathDescrs)-1].perms
+	matched := wcPathDescrs[len(wcPathDescrs)-1]
+	if matched.isPrefix {
+		return matched.wcPath + "*", matched.perms
+	}
+	return matched.wcPath, matched.perms
 }
 
 func (c *Core) performPolicyChecks(ctx context.Context, acl *ACL, te *logical.TokenEntry, req *logical.Request, inEntity *identity.Entity, opts *PolicyCheckOpts) *AuthResults {
@@ -698,6 +757,61 @@ func (c *Core) performPolicyChecks(ctx context.Context, acl *ACL, te *logical.To
 	return ret
 }
 
+// ConditionGrant holds the capabilities granted on a path by a policy, along
+// with the conditions of the policy on them.
+type ConditionGrant struct {
+	CapabilitiesBitmap uint32
+	BoundCIDRs         []*sockaddr.SockAddrMarshaler
+	TimeWindows        []*TimeWindow
+}
+
+func newConditionGrant(permissions *ACLPermissions) *ConditionGrant {
+	return &ConditionGrant{
+		CapabilitiesBitmap: permissions.CapabilitiesBitmap,
+		BoundCIDRs:         permissions.BoundCIDRs,
+		TimeWindows:        permissions.TimeWindows,
+	}
+}
+
+// conditionGrantsAllow returns whether one of the policies granting the
+// capability has its conditions met by the request. A policy granting the
+// capability without conditions is therefore enough.
+func conditionGrantsAllow(grants []*ConditionGrant, capability uint32, req *logical.Request) bool {
+	for _, grant := range grants {
+		if grant.CapabilitiesBitmap&capability == 0 {
+			continue
+		}
+		if len(grant.BoundCIDRs) > 0 {
+			if req.Connection == nil || !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, grant.BoundCIDRs) {
+				continue
+			}
+		}
+		if len(grant.TimeWindows) > 0 && !timeWindowsContain(grant.TimeWindows, time.Now()) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func timeWindowsContain(windows []*TimeWindow, t time.Time) bool {
+	for _, window := range windows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func valueSatisfiesConstraints(v interface{}, constraints []*ParameterConstraint) bool {
+	for _, constraint := range constraints {
+		if constraint.Allows(v) {
+			return true
+		}
+	}
+	return false
+}
+
 func valueInParameterList(v interface{}, list []interface{}) bool {
 	// Empty list is equivalent to the item always existing in the list
 	if len(list) == 0 {
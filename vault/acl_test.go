@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -1175,3 +1176,172 @@ path "test/star" {
 	}
 }
 `
+
+func TestACL_ProfileCapability(t *testing.T) {
+	t.Run("root-ns", func(t *testing.T) {
+		t.Parallel()
+		testACLProfileCapability(t, namespace.RootNamespace)
+	})
+}
+
+func testACLProfileCapability(t *testing.T, ns *namespace.Namespace) {
+	policy, err := ParseACLPolicy(ns, `
+path "sys/*" {
+	capabilities = ["read"]
+}
+path "sys/monitor/profiles/heap" {
+	capabilities = ["profile"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+	acl, err := NewACL(ctx, []*Policy{policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tcase struct {
+		path    string
+		allowed bool
+	}
+	tcases := []tcase{
+		{"sys/host-info", true},
+		{"sys/monitor/profiles/heap", true},
+		{"sys/monitor/profiles/cpu", false},
+	}
+	for _, tc := range tcases {
+		request := &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      tc.path,
+		}
+		authResults := acl.AllowOperation(ctx, request, false)
+		if authResults.Allowed != tc.allowed {
+			t.Fatalf("path %q: expected allowed %v, got %v", tc.path, tc.allowed, authResults.Allowed)
+		}
+	}
+
+	capabilities := acl.Capabilities(ctx, "sys/monitor/profiles/heap")
+	if !reflect.DeepEqual(capabilities, []string{ProfileCapability}) {
+		t.Fatalf("bad capabilities: %v", capabilities)
+	}
+}
+
+func TestACL_Conditions(t *testing.T) {
+	t.Run("root-ns", func(t *testing.T) {
+		t.Parallel()
+		testACLConditions(t, namespace.RootNamespace)
+	})
+}
+
+func testACLConditions(t *testing.T, ns *namespace.Namespace) {
+	closedDay := strings.ToLower(time.Now().UTC().AddDate(0, 0, 3).Weekday().String())
+
+	policy1, err := ParseACLPolicy(ns, `
+path "secret/cidr" {
+	capabilities = ["read"]
+	bound_cidrs = ["10.0.0.0/8"]
+}
+path "secret/window" {
+	capabilities = ["read"]
+	time_window "closed" {
+		days = ["`+closedDay+`"]
+	}
+}
+path "secret/params" {
+	capabilities = ["update"]
+	parameter_constraint "ttl" {
+		max = 60
+	}
+}
+path "secret/mixed" {
+	capabilities = ["read"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy2, err := ParseACLPolicy(ns, `
+path "secret/cidr" {
+	capabilities = ["read"]
+	bound_cidrs = ["192.168.0.0/16"]
+}
+path "secret/window" {
+	capabilities = ["read"]
+	time_window "open" {}
+}
+path "secret/params" {
+	capabilities = ["update"]
+	parameter_constraint "ttl" {
+		min = 3600
+	}
+}
+path "secret/mixed" {
+	capabilities = ["delete"]
+	bound_cidrs = ["10.0.0.0/8"]
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+	single, err := NewACL(ctx, []*Policy{policy1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := NewACL(ctx, []*Policy{policy1, policy2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tcase struct {
+		path       string
+		op         logical.Operation
+		remoteAddr string
+		data       map[string]interface{}
+		single     bool
+		merged     bool
+	}
+	tcases := []tcase{
+		{"secret/cidr", logical.ReadOperation, "10.0.0.1", nil, true, true},
+		{"secret/cidr", logical.ReadOperation, "192.168.0.1", nil, false, true},
+		{"secret/cidr", logical.ReadOperation, "172.16.0.1", nil, false, false},
+		{"secret/cidr", logical.ReadOperation, "", nil, false, false},
+		{"secret/window", logical.ReadOperation, "", nil, false, true},
+		{"secret/params", logical.UpdateOperation, "", nil, true, true},
+		{"secret/params", logical.UpdateOperation, "", map[string]interface{}{"ttl": 30}, true, true},
+		{"secret/params", logical.UpdateOperation, "", map[string]interface{}{"TTL": "120"}, false, false},
+		{"secret/params", logical.UpdateOperation, "", map[string]interface{}{"ttl": 7200}, false, true},
+		// The conditions of a policy only restrict the capabilities it grants
+		{"secret/mixed", logical.ReadOperation, "172.16.0.1", nil, true, true},
+		{"secret/mixed", logical.ReadOperation, "", nil, true, true},
+		{"secret/mixed", logical.DeleteOperation, "172.16.0.1", nil, false, false},
+		{"secret/mixed", logical.DeleteOperation, "10.0.0.1", nil, false, true},
+	}
+
+	for _, tc := range tcases {
+		request := &logical.Request{
+			Path:      tc.path,
+			Operation: tc.op,
+			Data:      tc.data,
+		}
+		if tc.remoteAddr != "" {
+			request.Connection = &logical.Connection{RemoteAddr: tc.remoteAddr}
+		}
+
+		if allowed := single.AllowOperation(ctx, request, false).Allowed; allowed != tc.single {
+			t.Errorf("single: bad: case %#v: %v", tc, allowed)
+		}
+		if allowed := merged.AllowOperation(ctx, request, false).Allowed; allowed != tc.merged {
+			t.Errorf("merged: bad: case %#v: %v", tc, allowed)
+		}
+	}
+
+	// Merging does not modify the parsed policies
+	if len(policy1.Paths[0].Permissions.BoundCIDRs) != 1 {
+		t.Fatalf("bad: bound CIDRs of the policy: %v", policy1.Paths[0].Permissions.BoundCIDRs)
+	}
+}
@@ -4,11 +4,43 @@ package vault
 
 import (
 	"context"
+	"errors"
 
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
 func (c *Core) performEntPolicyChecks(ctx context.Context, acl *ACL, te *logical.TokenEntry, req *logical.Request, inEntity *identity.Entity, opts *PolicyCheckOpts, ret *AuthResults) {
 	ret.Allowed = true
+
+	// Requests to paths requiring MFA methods have to carry a valid passcode
+	// of the entity for each of them. Approved control group requests had
+	// theirs validated when they were first made. The requests made for an
+	// envelope request share its passcodes.
+	if ret.ACLResults != nil && len(ret.ACLResults.MFAMethods) > 0 &&
+		(req.ControlGroup == nil || !req.ControlGroup.Approved) {
+		var err error
+		if c.identityStore == nil {
+			err = errors.New("MFA validation requires the identity store")
+		} else {
+			err = c.identityStore.validateMFACreds(inEntity, ret.ACLResults.MFAMethods, req.MFACreds, envelopeFromContext(ctx))
+		}
+		if err != nil {
+			ret.Allowed = false
+			ret.DeniedError = true
+			ret.Error = multierror.Append(ret.Error, err)
+			return
+		}
+	}
+
+	// Requests to paths under a control group are parked until approved;
+	// once they are, they are performed again with the approvals attached
+	if ret.ACLResults != nil && ret.ACLResults.ControlGroup != nil &&
+		(req.ControlGroup == nil || !req.ControlGroup.Approved) {
+		ret.Allowed = false
+		ret.Error = multierror.Append(ret.Error, &controlGroupRequiredError{
+			controlGroup: ret.ACLResults.ControlGroup,
+		})
+	}
 }
@@ -0,0 +1,547 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// activityCountersPath is where the distinct clients seen each month are
+// stored, under a date path in the requestCounterDatePathFormat. The clients
+// of a month are split across numbered segments, so that the size of each
+// storage entry is bounded.
+const activityCountersPath = "sys/counters/activity/"
+
+// activitySegmentMaxClients is the default number of clients written to each
+// segment of a month.
+const activitySegmentMaxClients = 4096
+
+// activityLog tracks the distinct clients using tokens during the months not
+// yet written to storage. A client is either an identity entity, or a token
+// without an entity. Clients are counted in the namespace and auth mount
+// their token was created in.
+type activityLog struct {
+	l sync.Mutex
+
+	// segmentMaxClients is the number of clients written to each segment
+	// of a month.
+	segmentMaxClients int
+
+	// months maps date paths to the clients seen during that month. It
+	// holds the current month, and any past month whose clients have not
+	// been written yet.
+	months map[string]*activityMonthLog
+}
+
+// activityMonth holds the distinct clients seen during a month, by
+// namespace ID and auth mount path.
+type activityMonth struct {
+	Namespaces map[string]map[string]*activityClients `json:"namespaces"`
+}
+
+// activityClients holds the IDs of the entities and the hashes of the
+// non-entity tokens seen for a mount.
+type activityClients struct {
+	Entities        map[string]bool `json:"entities"`
+	NonEntityTokens map[string]bool `json:"non_entity_tokens"`
+}
+
+// activityMonthLog holds the clients seen during a month, and tracks which
+// of them have been written to storage.
+type activityMonthLog struct {
+	// all holds every client seen during the month, so that each one is
+	// only written once.
+	all *activityMonth
+
+	// pending holds the clients not written to storage yet.
+	pending *activityMonth
+
+	// segment is the index of the last segment of the month, and
+	// segmentData its clients. Pending clients are added to it until it is
+	// full, and then to new segments.
+	segment     int
+	segmentData *activityMonth
+}
+
+func newActivityMonth() *activityMonth {
+	return &activityMonth{
+		Namespaces: make(map[string]map[string]*activityClients),
+	}
+}
+
+func newActivityMonthLog() *activityMonthLog {
+	return &activityMonthLog{
+		all:         newActivityMonth(),
+		pending:     newActivityMonth(),
+		segmentData: newActivityMonth(),
+	}
+}
+
+func (m *activityMonth) clients(namespaceID, mountPath string) *activityClients {
+	mounts, ok := m.Namespaces[namespaceID]
+	if !ok {
+		mounts = make(map[string]*activityClients)
+		m.Namespaces[namespaceID] = mounts
+	}
+	clients, ok := mounts[mountPath]
+	if !ok {
+		clients = &activityClients{
+			Entities:        make(map[string]bool),
+			NonEntityTokens: make(map[string]bool),
+		}
+		mounts[mountPath] = clients
+	}
+	return clients
+}
+
+// merge adds the clients of other to m.
+func (m *activityMonth) merge(other *activityMonth) {
+	for nsID, mounts := range other.Namespaces {
+		for mountPath, otherClients := range mounts {
+			clients := m.clients(nsID, mountPath)
+			for id := range otherClients.Entities {
+				clients.Entities[id] = true
+			}
+			for id := range otherClients.NonEntityTokens {
+				clients.NonEntityTokens[id] = true
+			}
+		}
+	}
+}
+
+// size returns the number of clients of the month, counting a client once
+// for each mount it was seen for.
+func (m *activityMonth) size() int {
+	size := 0
+	for _, mounts := range m.Namespaces {
+		for _, clients := range mounts {
+			size += len(clients.Entities) + len(clients.NonEntityTokens)
+		}
+	}
+	return size
+}
+
+// addEntity records an entity seen for the mount, unless it was seen before.
+func (m *activityMonthLog) addEntity(namespaceID, mountPath, entityID string) {
+	clients := m.all.clients(namespaceID, mountPath)
+	if clients.Entities[entityID] {
+		return
+	}
+	clients.Entities[entityID] = true
+	m.pending.clients(namespaceID, mountPath).Entities[entityID] = true
+}
+
+// addNonEntityToken records a token without an entity seen for the mount,
+// unless it was seen before.
+func (m *activityMonthLog) addNonEntityToken(namespaceID, mountPath, tokenHash string) {
+	clients := m.all.clients(namespaceID, mountPath)
+	if clients.NonEntityTokens[tokenHash] {
+		return
+	}
+	clients.NonEntityTokens[tokenHash] = true
+	m.pending.clients(namespaceID, mountPath).NonEntityTokens[tokenHash] = true
+}
+
+// flush writes the pending clients of the month at datepath to its last
+// segment, and to new segments once it holds maxClients clients.
+func (m *activityMonthLog) flush(ctx context.Context, view *BarrierView, datepath string, maxClients int) error {
+	if m.pending.size() == 0 {
+		return nil
+	}
+
+	writeSegment := func() error {
+		entry, err := logical.StorageEntryJSON(activitySegmentPath(datepath, m.segment), m.segmentData)
+		if err != nil {
+			return errwrap.Wrapf("failed to create activity log entry: {{err}}", err)
+		}
+		if err := view.Put(ctx, entry); err != nil {
+			return errwrap.Wrapf("failed to save activity log: {{err}}", err)
+		}
+		return nil
+	}
+
+	size := m.segmentData.size()
+	add := func(nsID, mountPath, id string, entity bool) error {
+		if size >= maxClients {
+			if err := writeSegment(); err != nil {
+				return err
+			}
+			m.segment++
+			m.segmentData = newActivityMonth()
+			size = 0
+		}
+
+		clients := m.segmentData.clients(nsID, mountPath)
+		if entity {
+			clients.Entities[id] = true
+		} else {
+			clients.NonEntityTokens[id] = true
+		}
+		size++
+		return nil
+	}
+
+	for nsID, mounts := range m.pending.Namespaces {
+		for mountPath, pending := range mounts {
+			for id := range pending.Entities {
+				if err := add(nsID, mountPath, id, true); err != nil {
+					return err
+				}
+			}
+			for id := range pending.NonEntityTokens {
+				if err := add(nsID, mountPath, id, false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := writeSegment(); err != nil {
+		return err
+	}
+	m.pending = newActivityMonth()
+	return nil
+}
+
+func activitySegmentPath(datepath string, segment int) string {
+	return datepath + "/" + strconv.Itoa(segment)
+}
+
+func newActivityLog() *activityLog {
+	return &activityLog{
+		segmentMaxClients: activitySegmentMaxClients,
+		months:            make(map[string]*activityMonthLog),
+	}
+}
+
+// recordActivity counts the client of a token towards the activity of the
+// current month. Only the active node keeps an activity log.
+func (c *Core) recordActivity(ctx context.Context, te *logical.TokenEntry, now time.Time) {
+	if te == nil || c.perfStandby {
+		return
+	}
+
+	mountPath := "auth/token/"
+	tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+	if err == nil && tokenNS != nil {
+		if mount := c.router.MatchingMount(namespace.ContextWithNamespace(ctx, tokenNS), te.Path); mount != "" {
+			mountPath = strings.TrimPrefix(mount, tokenNS.Path)
+		}
+	}
+
+	a := c.activityLog
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	datepath := now.Format(requestCounterDatePathFormat)
+	month, ok := a.months[datepath]
+	if !ok {
+		month = newActivityMonthLog()
+		a.months[datepath] = month
+	}
+
+	if te.EntityID != "" {
+		month.addEntity(te.NamespaceID, mountPath, te.EntityID)
+		return
+	}
+	// Tokens are not kept in storage, only enough to tell them apart
+	sum := sha256.Sum256([]byte(te.ID))
+	month.addNonEntityToken(te.NamespaceID, mountPath, base64.RawStdEncoding.EncodeToString(sum[:]))
+}
+
+// loadCurrentActivity reads the clients of the current month out of
+// storage, so that the in-memory activity log picks up where it was.
+func (c *Core) loadCurrentActivity(ctx context.Context, now time.Time) error {
+	datepath := now.Format(requestCounterDatePathFormat)
+	segments, indexes, err := c.loadActivitySegments(ctx, datepath)
+	if err != nil {
+		return err
+	}
+
+	month := newActivityMonthLog()
+	for i, segment := range segments {
+		month.all.merge(segment)
+		month.segment = indexes[i]
+		month.segmentData = segment
+	}
+
+	a := c.activityLog
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	a.months = make(map[string]*activityMonthLog)
+	if len(segments) > 0 {
+		a.months[datepath] = month
+	}
+	return nil
+}
+
+// loadActivityMonth reads the clients of the month at datepath out of
+// storage. If nothing is found, nil is returned.
+func (c *Core) loadActivityMonth(ctx context.Context, datepath string) (*activityMonth, error) {
+	segments, _, err := c.loadActivitySegments(ctx, datepath)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	month := newActivityMonth()
+	for _, segment := range segments {
+		month.merge(segment)
+	}
+	return month, nil
+}
+
+// loadActivitySegments reads the segments of the month at datepath out of
+// storage, along with their indexes, in the order they were written.
+func (c *Core) loadActivitySegments(ctx context.Context, datepath string) ([]*activityMonth, []int, error) {
+	view := NewBarrierView(c.barrier, activityCountersPath)
+
+	keys, err := view.List(ctx, datepath+"/")
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("failed to list activity log segments: {{err}}", err)
+	}
+
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		index, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	segments := make([]*activityMonth, 0, len(indexes))
+	loaded := make([]int, 0, len(indexes))
+	for _, index := range indexes {
+		out, err := view.Get(ctx, activitySegmentPath(datepath, index))
+		if err != nil {
+			return nil, nil, errwrap.Wrapf("failed to read activity log: {{err}}", err)
+		}
+		if out == nil {
+			continue
+		}
+
+		segment := newActivityMonth()
+		if err := out.DecodeJSON(segment); err != nil {
+			return nil, nil, err
+		}
+		segments = append(segments, segment)
+		loaded = append(loaded, index)
+	}
+	return segments, loaded, nil
+}
+
+// saveCurrentActivity writes the clients seen since the last save to
+// storage. Past months are dropped from memory once written.
+// now should be the current time; it is a parameter to facilitate testing.
+func (c *Core) saveCurrentActivity(ctx context.Context, now time.Time) error {
+	view := NewBarrierView(c.barrier, activityCountersPath)
+	curDatePath := now.Format(requestCounterDatePathFormat)
+
+	a := c.activityLog
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for datepath, month := range a.months {
+		if err := month.flush(ctx, view, datepath, a.segmentMaxClients); err != nil {
+			return err
+		}
+		if datepath != curDatePath {
+			delete(a.months, datepath)
+		}
+	}
+	return nil
+}
+
+// ActivityCounts holds the number of distinct clients seen over a period.
+type ActivityCounts struct {
+	DistinctEntities int `json:"distinct_entities"`
+	NonEntityTokens  int `json:"non_entity_tokens"`
+	Clients          int `json:"clients"`
+}
+
+// MountActivity holds the clients of an auth mount.
+type MountActivity struct {
+	Path   string         `json:"path"`
+	Counts ActivityCounts `json:"counts"`
+}
+
+// NamespaceActivity holds the clients of a namespace, and of each of its
+// auth mounts.
+type NamespaceActivity struct {
+	NamespaceID   string           `json:"namespace_id"`
+	NamespacePath string           `json:"namespace_path"`
+	Counts        ActivityCounts   `json:"counts"`
+	Mounts        []*MountActivity `json:"mounts"`
+}
+
+// MonthlyActivity holds the clients seen during a month.
+type MonthlyActivity struct {
+	StartTime time.Time      `json:"start_time"`
+	Counts    ActivityCounts `json:"counts"`
+}
+
+// ActivityReport holds the distinct clients seen during the months from
+// StartTime to EndTime. A client seen in several months, or with tokens
+// from several mounts of a namespace, is only counted once.
+type ActivityReport struct {
+	StartTime   time.Time            `json:"start_time"`
+	EndTime     time.Time            `json:"end_time"`
+	Total       ActivityCounts       `json:"total"`
+	ByNamespace []*NamespaceActivity `json:"by_namespace"`
+	Months      []*MonthlyActivity   `json:"months"`
+}
+
+// activityReport returns the distinct clients seen during the months
+// overlapping start and end.
+func (c *Core) activityReport(ctx context.Context, start, end time.Time) (*ActivityReport, error) {
+	start, _ = time.Parse(requestCounterDatePathFormat, start.UTC().Format(requestCounterDatePathFormat))
+
+	c.activityLog.l.Lock()
+	unsaved := make(map[string]*activityMonth, len(c.activityLog.months))
+	for datepath, month := range c.activityLog.months {
+		copied := newActivityMonth()
+		copied.merge(month.pending)
+		unsaved[datepath] = copied
+	}
+	c.activityLog.l.Unlock()
+
+	report := &ActivityReport{
+		StartTime:   start,
+		EndTime:     end,
+		ByNamespace: []*NamespaceActivity{},
+		Months:      []*MonthlyActivity{},
+	}
+	all := newActivityMonth()
+	for t := start; !t.After(end); t = t.AddDate(0, 1, 0) {
+		datepath := t.Format(requestCounterDatePathFormat)
+		month, err := c.loadActivityMonth(ctx, datepath)
+		if err != nil {
+			return nil, err
+		}
+		if month == nil {
+			month = newActivityMonth()
+		}
+		if pending, ok := unsaved[datepath]; ok {
+			month.merge(pending)
+		}
+
+		report.Months = append(report.Months, &MonthlyActivity{
+			StartTime: t,
+			Counts:    month.counts(),
+		})
+		all.merge(month)
+	}
+
+	nsIDs := make([]string, 0, len(all.Namespaces))
+	for nsID := range all.Namespaces {
+		nsIDs = append(nsIDs, nsID)
+	}
+	sort.Strings(nsIDs)
+	for _, nsID := range nsIDs {
+		nsActivity := &NamespaceActivity{
+			NamespaceID: nsID,
+			Counts:      all.namespaceCounts(nsID),
+			Mounts:      []*MountActivity{},
+		}
+		if ns, err := NamespaceByID(ctx, nsID, c); err == nil && ns != nil {
+			nsActivity.NamespacePath = ns.Path
+		}
+
+		mounts := all.Namespaces[nsID]
+		mountPaths := make([]string, 0, len(mounts))
+		for mountPath := range mounts {
+			mountPaths = append(mountPaths, mountPath)
+		}
+		sort.Strings(mountPaths)
+		for _, mountPath := range mountPaths {
+			clients := mounts[mountPath]
+			nsActivity.Mounts = append(nsActivity.Mounts, &MountActivity{
+				Path:   mountPath,
+				Counts: newActivityCounts(len(clients.Entities), len(clients.NonEntityTokens)),
+			})
+		}
+		report.ByNamespace = append(report.ByNamespace, nsActivity)
+	}
+	report.Total = all.counts()
+
+	return report, nil
+}
+
+func newActivityCounts(entities, nonEntityTokens int) ActivityCounts {
+	return ActivityCounts{
+		DistinctEntities: entities,
+		NonEntityTokens:  nonEntityTokens,
+		Clients:          entities + nonEntityTokens,
+	}
+}
+
+// namespaceCounts counts the clients of a namespace, across its mounts.
+func (m *activityMonth) namespaceCounts(namespaceID string) ActivityCounts {
+	entities := make(map[string]bool)
+	tokens := make(map[string]bool)
+	for _, clients := range m.Namespaces[namespaceID] {
+		for id := range clients.Entities {
+			entities[id] = true
+		}
+		for id := range clients.NonEntityTokens {
+			tokens[id] = true
+		}
+	}
+	return newActivityCounts(len(entities), len(tokens))
+}
+
+// counts counts the clients across all namespaces. Entities are counted
+// once even if they used tokens from several namespaces.
+func (m *activityMonth) counts() ActivityCounts {
+	entities := make(map[string]bool)
+	tokens := 0
+	for nsID := range m.Namespaces {
+		for _, clients := range m.Namespaces[nsID] {
+			for id := range clients.Entities {
+				entities[id] = true
+			}
+		}
+		tokens += m.namespaceCounts(nsID).NonEntityTokens
+	}
+	return newActivityCounts(len(entities), tokens)
+}
+
+// csv renders the report with one row per auth mount.
+func (r *ActivityReport) csv() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"namespace_id", "namespace_path", "mount_path", "distinct_entities", "non_entity_tokens", "clients"})
+	for _, ns := range r.ByNamespace {
+		for _, mount := range ns.Mounts {
+			w.Write([]string{
+				ns.NamespaceID,
+				ns.NamespacePath,
+				mount.Path,
+				strconv.Itoa(mount.Counts.DistinctEntities),
+				strconv.Itoa(mount.Counts.NonEntityTokens),
+				strconv.Itoa(mount.Counts.Clients),
+			})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
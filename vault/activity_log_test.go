@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestActivityLog_Report(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	december2018 := testParseTime(t, time.RFC3339, "2018-12-05T09:44:12Z")
+	january2019 := testParseTime(t, time.RFC3339, "2019-01-02T08:21:11Z")
+
+	alice := &logical.TokenEntry{ID: "alice-token", Path: "auth/token/create", EntityID: "alice", NamespaceID: namespace.RootNamespaceID}
+	aliceAgain := &logical.TokenEntry{ID: "alice-token-2", Path: "auth/token/create", EntityID: "alice", NamespaceID: namespace.RootNamespaceID}
+	anonymous := &logical.TokenEntry{ID: "anonymous-token", Path: "auth/token/create", NamespaceID: namespace.RootNamespaceID}
+
+	c.recordActivity(ctx, alice, december2018)
+	c.recordActivity(ctx, anonymous, december2018)
+	c.recordActivity(ctx, anonymous, december2018)
+	if err := c.saveCurrentActivity(context.Background(), january2019); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.activityLog.months[december2018.Format(requestCounterDatePathFormat)]; ok {
+		t.Fatal("expected past month to be dropped from memory once saved")
+	}
+
+	// The January clients are reported before they are saved
+	c.recordActivity(ctx, aliceAgain, january2019)
+
+	report, err := c.activityReport(ctx, december2018, january2019)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ActivityCounts{DistinctEntities: 1, NonEntityTokens: 1, Clients: 2}
+	if report.Total != expected {
+		t.Fatalf("expected %#v, got %#v", expected, report.Total)
+	}
+	if len(report.Months) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(report.Months))
+	}
+	if january := report.Months[1].Counts; january != (ActivityCounts{DistinctEntities: 1, Clients: 1}) {
+		t.Fatalf("bad January counts: %#v", january)
+	}
+	if len(report.ByNamespace) != 1 || len(report.ByNamespace[0].Mounts) != 1 ||
+		report.ByNamespace[0].Mounts[0].Path != "auth/token/" {
+		t.Fatalf("bad namespaces: %#v", report.ByNamespace)
+	}
+
+	resp, err := c.HandleRequest(ctx, &logical.Request{
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+		Path:        "sys/internal/counters/activity",
+		Data: map[string]interface{}{
+			"start_time": december2018.Format(time.RFC3339),
+			"end_time":   january2019.Format(time.RFC3339),
+			"format":     "csv",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\n err: %v", resp, err)
+	}
+	body := string(resp.Data[logical.HTTPRawBody].([]byte))
+	if !strings.Contains(body, "root,,auth/token/,1,1,2") {
+		t.Fatalf("unexpected CSV:\n%s", body)
+	}
+}
+
+func TestActivityLog_Segments(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	c.activityLog.segmentMaxClients = 2
+
+	january2019 := testParseTime(t, time.RFC3339, "2019-01-02T08:21:11Z")
+	datepath := january2019.Format(requestCounterDatePathFormat)
+
+	record := func(ids ...string) {
+		t.Helper()
+		for _, id := range ids {
+			c.recordActivity(ctx, &logical.TokenEntry{ID: id, Path: "auth/token/create", NamespaceID: namespace.RootNamespaceID}, january2019)
+		}
+		if err := c.saveCurrentActivity(context.Background(), january2019); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segments := func() []string {
+		t.Helper()
+		keys, err := NewBarrierView(c.barrier, activityCountersPath).List(context.Background(), datepath+"/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+
+	// Clients are split across segments, and the last segment is filled
+	// before new ones are written
+	record("a", "b", "c", "a")
+	if keys := segments(); len(keys) != 2 {
+		t.Fatalf("expected 2 segments, got %v", keys)
+	}
+	record("d")
+	if keys := segments(); len(keys) != 2 {
+		t.Fatalf("expected 2 segments, got %v", keys)
+	}
+	record("b", "e")
+	if keys := segments(); len(keys) != 3 {
+		t.Fatalf("expected 3 segments, got %v", keys)
+	}
+
+	// Clients already in storage are not written again after a restart
+	if err := c.loadCurrentActivity(context.Background(), january2019); err != nil {
+		t.Fatal(err)
+	}
+	record("a", "f")
+	if keys := segments(); len(keys) != 3 {
+		t.Fatalf("expected 3 segments, got %v", keys)
+	}
+
+	month, err := c.loadActivityMonth(context.Background(), datepath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts := month.counts(); counts != (ActivityCounts{NonEntityTokens: 6, Clients: 6}) {
+		t.Fatalf("bad counts: %#v", counts)
+	}
+}
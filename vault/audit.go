@@ -93,6 +93,11 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	view.setReadOnlyErr(logical.ErrSetupReadOnly)
 	defer view.setReadOnlyErr(origViewReadOnlyErr)
 
+	opts, err := parseAuditDeviceOptions(entry.Options)
+	if err != nil {
+		return err
+	}
+
 	// Lookup the new backend
 	backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 	if err != nil {
@@ -121,7 +126,7 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(entry.Path, backend, view, entry.Local, opts)
 	if c.logger.IsInfo() {
 		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -372,6 +377,12 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			view.setReadOnlyErr(origViewReadOnlyErr)
 		})
 
+		opts, err := parseAuditDeviceOptions(entry.Options)
+		if err != nil {
+			c.logger.Error("failed to parse audit options", "path", entry.Path, "error", err)
+			continue
+		}
+
 		// Initialize the backend
 		backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 		if err != nil {
@@ -384,7 +395,7 @@ func (c *Core) setupAudits(ctx context.Context) error {
 		}
 
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(entry.Path, backend, view, entry.Local, opts)
 
 		successCount++
 	}
@@ -394,6 +405,9 @@ func (c *Core) setupAudits(ctx context.Context) error {
 	}
 
 	c.auditBroker = broker
+
+	go c.runAuditHMACKeyRotation(ctx)
+
 	return nil
 }
 
@@ -407,6 +421,9 @@ func (c *Core) teardownAudits() error {
 		for _, entry := range c.audit.Entries {
 			c.removeAuditReloadFunc(entry)
 			removeAuditPathChecker(c, entry)
+			if c.auditBroker != nil {
+				c.auditBroker.Deregister(entry.Path)
+			}
 		}
 	}
 
@@ -3,13 +3,17 @@ package vault
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
 	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -17,6 +21,67 @@ type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
 	local   bool
+
+	// filter selects the entries logged by the backend; a nil filter logs
+	// every entry
+	filter *audit.Filter
+
+	// nonHMACReqDataKeys and nonHMACRespDataKeys are the data keys logged
+	// in the clear by the backend, in addition to those of the mount
+	nonHMACReqDataKeys  []string
+	nonHMACRespDataKeys []string
+
+	// hmacKeyRotationPeriod is how often the backend's HMAC key is rotated;
+	// zero disables scheduled rotation
+	hmacKeyRotationPeriod time.Duration
+}
+
+// auditDeviceOptions are the options of an audit device which are applied
+// by the broker rather than by the backend.
+type auditDeviceOptions struct {
+	filter                *audit.Filter
+	nonHMACReqDataKeys    []string
+	nonHMACRespDataKeys   []string
+	hmacKeyRotationPeriod time.Duration
+}
+
+// parseAuditDeviceOptions parses the broker options out of the options of
+// an audit device.
+func parseAuditDeviceOptions(options map[string]string) (*auditDeviceOptions, error) {
+	filter, err := audit.NewFilter(options["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &auditDeviceOptions{
+		filter:              filter,
+		nonHMACReqDataKeys:  strutil.ParseStringSlice(options["non_hmac_request_keys"], ","),
+		nonHMACRespDataKeys: strutil.ParseStringSlice(options["non_hmac_response_keys"], ","),
+	}
+
+	if raw, ok := options["hmac_key_rotation_period"]; ok && raw != "" {
+		opts.hmacKeyRotationPeriod, err = parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return nil, errwrap.Wrapf("invalid hmac_key_rotation_period: {{err}}", err)
+		}
+		if opts.hmacKeyRotationPeriod < time.Minute {
+			return nil, fmt.Errorf("hmac_key_rotation_period must be at least one minute")
+		}
+	}
+
+	return opts, nil
+}
+
+// logInput returns the input to log with the backend, exempting the
+// backend's data keys from HMACing.
+func (be backendEntry) logInput(in *logical.LogInput) *logical.LogInput {
+	if len(be.nonHMACReqDataKeys) == 0 && len(be.nonHMACRespDataKeys) == 0 {
+		return in
+	}
+	beIn := *in
+	beIn.NonHMACReqDataKeys = strutil.MergeSlices(in.NonHMACReqDataKeys, be.nonHMACReqDataKeys)
+	beIn.NonHMACRespDataKeys = strutil.MergeSlices(in.NonHMACRespDataKeys, be.nonHMACRespDataKeys)
+	return &beIn
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -37,20 +102,35 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 }
 
 // Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool) {
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool, opts *auditDeviceOptions) {
 	a.Lock()
 	defer a.Unlock()
-	a.backends[name] = backendEntry{
+	be := backendEntry{
 		backend: b,
 		view:    v,
 		local:   local,
 	}
+	if opts != nil {
+		be.filter = opts.filter
+		be.nonHMACReqDataKeys = opts.nonHMACReqDataKeys
+		be.nonHMACRespDataKeys = opts.nonHMACRespDataKeys
+		be.hmacKeyRotationPeriod = opts.hmacKeyRotationPeriod
+	}
+	a.backends[name] = be
 }
 
-// Deregister is used to remove an audit backend from the broker
+// Deregister is used to remove an audit backend from the broker. Backends
+// holding resources beyond a request, such as connections, are closed.
 func (a *AuditBroker) Deregister(name string) {
 	a.Lock()
 	defer a.Unlock()
+	if be, ok := a.backends[name]; ok {
+		if closer, ok := be.backend.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				a.logger.Error("failed to close audit backend", "path", name, "error", err)
+			}
+		}
+	}
 	delete(a.backends, name)
 }
 
@@ -120,9 +200,14 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
-	anyLogged := false
+	// Ensure at least one backend logs, among those whose filter matches
+	anyLogged, anyMatched := false, false
 	for name, be := range a.backends {
+		if !be.filter.Matches(ctx, in) {
+			continue
+		}
+		anyMatched = true
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -132,7 +217,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = transHeaders
 
 		start := time.Now()
-		lrErr := be.backend.LogRequest(ctx, in)
+		lrErr := be.backend.LogRequest(ctx, be.logInput(in))
 		metrics.MeasureSince([]string{"audit", name, "log_request"}, start)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log request", "backend", name, "error", lrErr)
@@ -140,7 +225,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyMatched {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
@@ -176,9 +261,14 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
-	anyLogged := false
+	// Ensure at least one backend logs, among those whose filter matches
+	anyLogged, anyMatched := false, false
 	for name, be := range a.backends {
+		if !be.filter.Matches(ctx, in) {
+			continue
+		}
+		anyMatched = true
+
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -188,7 +278,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = transHeaders
 
 		start := time.Now()
-		lrErr := be.backend.LogResponse(ctx, in)
+		lrErr := be.backend.LogResponse(ctx, be.logInput(in))
 		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
 		if lrErr != nil {
 			a.logger.Error("backend failed to log response", "backend", name, "error", lrErr)
@@ -196,7 +286,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyMatched {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
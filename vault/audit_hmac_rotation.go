@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// auditHMACKeyRotationPath is the path in an audit device's view at
+	// which the time of the last rotation of its HMAC key is stored.
+	auditHMACKeyRotationPath = "salt-rotation"
+)
+
+// auditHMACKeyRotationCheckInterval is how often the audit devices are
+// checked for HMAC keys due for rotation.
+var auditHMACKeyRotationCheckInterval = time.Minute
+
+type auditHMACKeyRotation struct {
+	LastRotation time.Time `json:"last_rotation"`
+}
+
+// RotateHMACKey replaces the key an audit backend uses to HMAC sensitive
+// values. Values logged before the rotation can no longer be correlated
+// with those logged after it.
+func (a *AuditBroker) RotateHMACKey(ctx context.Context, name string, now time.Time) error {
+	a.RLock()
+	defer a.RUnlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return fmt.Errorf("unknown audit backend %q", name)
+	}
+	return be.rotateHMACKey(ctx, now)
+}
+
+// rotateDueHMACKeys rotates the HMAC keys of the backends with a rotation
+// period which has elapsed since their last rotation.
+func (a *AuditBroker) rotateDueHMACKeys(ctx context.Context, now time.Time) {
+	a.RLock()
+	defer a.RUnlock()
+	for name, be := range a.backends {
+		if be.hmacKeyRotationPeriod == 0 || be.view == nil {
+			continue
+		}
+
+		var rotation auditHMACKeyRotation
+		entry, err := be.view.Get(ctx, auditHMACKeyRotationPath)
+		if err != nil {
+			a.logger.Error("failed to read audit HMAC key rotation", "backend", name, "error", err)
+			continue
+		}
+		if entry == nil {
+			// The key was created before the period was set, so the period
+			// runs from now
+			if err := be.putHMACKeyRotation(ctx, now); err != nil {
+				a.logger.Error("failed to persist audit HMAC key rotation", "backend", name, "error", err)
+			}
+			continue
+		}
+		if err := entry.DecodeJSON(&rotation); err != nil {
+			a.logger.Error("failed to decode audit HMAC key rotation", "backend", name, "error", err)
+			continue
+		}
+		if now.Sub(rotation.LastRotation) < be.hmacKeyRotationPeriod {
+			continue
+		}
+
+		if err := be.rotateHMACKey(ctx, now); err != nil {
+			a.logger.Error("failed to rotate audit HMAC key", "backend", name, "error", err)
+			continue
+		}
+		a.logger.Info("rotated audit HMAC key", "backend", name)
+	}
+}
+
+func (be backendEntry) rotateHMACKey(ctx context.Context, now time.Time) error {
+	if be.view == nil {
+		return fmt.Errorf("audit backend has no storage")
+	}
+
+	key, err := uuid.GenerateUUID()
+	if err != nil {
+		return errwrap.Wrapf("failed to generate HMAC key: {{err}}", err)
+	}
+	if err := be.view.Put(ctx, &logical.StorageEntry{
+		Key:   salt.DefaultLocation,
+		Value: []byte(key),
+	}); err != nil {
+		return errwrap.Wrapf("failed to persist HMAC key: {{err}}", err)
+	}
+	if err := be.putHMACKeyRotation(ctx, now); err != nil {
+		return err
+	}
+
+	// Drop the cached salt so that the backend loads the new key
+	be.backend.Invalidate(ctx)
+	return nil
+}
+
+func (be backendEntry) putHMACKeyRotation(ctx context.Context, now time.Time) error {
+	entry, err := logical.StorageEntryJSON(auditHMACKeyRotationPath, &auditHMACKeyRotation{
+		LastRotation: now.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return be.view.Put(ctx, entry)
+}
+
+// runAuditHMACKeyRotation periodically rotates the HMAC keys of the audit
+// devices configured with a rotation period, until ctx is done.
+func (c *Core) runAuditHMACKeyRotation(ctx context.Context) {
+	ticker := time.NewTicker(auditHMACKeyRotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.perfStandby {
+				continue
+			}
+			c.auditLock.RLock()
+			broker := c.auditBroker
+			c.auditLock.RUnlock()
+			if broker != nil {
+				broker.rotateDueHMACKeys(ctx, time.Now())
+			}
+		}
+	}
+}
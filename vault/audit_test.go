@@ -352,13 +352,61 @@ func verifyDefaultAuditTable(t *testing.T, table *MountTable) {
 	}
 }
 
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	all := &NoopAudit{}
+	deletes := &NoopAudit{}
+	filter, err := audit.NewFilter(`operation == "delete"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Register("all", all, nil, false, nil)
+	b.Register("deletes", deletes, nil, false, &auditDeviceOptions{filter: filter})
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	for _, op := range []logical.Operation{logical.ReadOperation, logical.DeleteOperation} {
+		logInput := &logical.LogInput{
+			Request: &logical.Request{Operation: op, Path: "secret/foo"},
+		}
+		if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.LogResponse(context.Background(), logInput, headersConf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(all.Req) != 2 || len(all.Resp) != 2 {
+		t.Fatalf("expected every entry to be logged, got %d requests and %d responses", len(all.Req), len(all.Resp))
+	}
+	if len(deletes.Req) != 1 || deletes.Req[0].Operation != logical.DeleteOperation || len(deletes.Resp) != 1 {
+		t.Fatalf("expected only the delete to be logged, got %#v", deletes.Req)
+	}
+
+	// A failing backend is not needed to log entries its filter skips
+	all.ReqErr = errors.New("failed")
+	logInput := &logical.LogInput{
+		Request: &logical.Request{Operation: logical.ReadOperation, Path: "secret/foo"},
+	}
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err == nil {
+		t.Fatal("expected error when no matching backend logs the entry")
+	}
+	b.Deregister("all")
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("expected no error when every backend skips the entry, got %v", err)
+	}
+}
+
 func TestAuditBroker_LogRequest(t *testing.T) {
 	l := logging.NewVaultLogger(log.Trace)
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -443,8 +491,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -549,8 +597,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
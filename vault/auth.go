@@ -105,8 +105,9 @@ func (c *Core) enableCredentialInternal(ctx context.Context, entry *MountEntry,
 		}
 	}
 
-	// Ensure the token backend is a singleton
-	if entry.Type == "token" {
+	// Ensure the token backend is a singleton, other than the token/ mount
+	// every namespace has
+	if entry.Type == "token" && (ns.ID == namespace.RootNamespaceID || entry.Path != "token/") {
 		return fmt.Errorf("token credential backend cannot be instantiated")
 	}
 
@@ -714,7 +715,7 @@ func (c *Core) setupCredentials(ctx context.Context) error {
 		}
 
 		// Check if this is the token store
-		if entry.Type == "token" {
+		if entry.Type == "token" && entry.NamespaceID == namespace.RootNamespaceID {
 			c.tokenStore = backend.(*TokenStore)
 
 			// At some point when this isn't beta we may persist this but for
@@ -788,8 +789,14 @@ func (c *Core) newCredentialBackend(ctx context.Context, entry *MountEntry, sysV
 		t = alias
 	}
 
+	if b, err := c.sharedNamespaceBackend(entry); b != nil || err != nil {
+		return b, err
+	}
+
+	// Mounts pinned to a plugin version run the plugin registered in the
+	// catalog with that version
 	f, ok := c.credentialBackends[t]
-	if !ok {
+	if !ok || entry.Config.PluginVersion != "" {
 		f = plugin.Factory
 	}
 
@@ -20,8 +20,17 @@ type BarrierView struct {
 	readOnlyErr     error
 	readOnlyErrLock sync.RWMutex
 	iCheck          interface{}
+
+	// writeHook, if set, performs the writes through the view, such as to
+	// record them for mirroring
+	writeHook     barrierViewWriteHook
+	writeHookLock sync.RWMutex
 }
 
+// barrierViewWriteHook is called with the expanded key and the entry of a
+// write, nil for a delete, and must call write to perform it.
+type barrierViewWriteHook func(ctx context.Context, key string, entry *logical.StorageEntry, write func() error) error
+
 // NewBarrierView takes an underlying security barrier and returns
 // a view of it that can only operate with the given prefix.
 func NewBarrierView(barrier logical.Storage, prefix string) *BarrierView {
@@ -46,6 +55,18 @@ func (v *BarrierView) getReadOnlyErr() error {
 	return v.readOnlyErr
 }
 
+func (v *BarrierView) setWriteHook(hook barrierViewWriteHook) {
+	v.writeHookLock.Lock()
+	defer v.writeHookLock.Unlock()
+	v.writeHook = hook
+}
+
+func (v *BarrierView) getWriteHook() barrierViewWriteHook {
+	v.writeHookLock.RLock()
+	defer v.writeHookLock.RUnlock()
+	return v.writeHook
+}
+
 func (v *BarrierView) Prefix() string {
 	return v.storage.Prefix()
 }
@@ -73,6 +94,12 @@ func (v *BarrierView) Put(ctx context.Context, entry *logical.StorageEntry) erro
 		}
 	}
 
+	if hook := v.getWriteHook(); hook != nil {
+		return hook(ctx, expandedKey, entry, func() error {
+			return v.storage.Put(ctx, entry)
+		})
+	}
+
 	return v.storage.Put(ctx, entry)
 }
 
@@ -87,6 +114,12 @@ func (v *BarrierView) Delete(ctx context.Context, key string) error {
 		}
 	}
 
+	if hook := v.getWriteHook(); hook != nil {
+		return hook(ctx, expandedKey, nil, func() error {
+			return v.storage.Delete(ctx, key)
+		})
+	}
+
 	return v.storage.Delete(ctx, key)
 }
 
@@ -96,5 +129,6 @@ func (v *BarrierView) SubView(prefix string) *BarrierView {
 		storage:     v.storage.SubView(prefix),
 		readOnlyErr: v.getReadOnlyErr(),
 		iCheck:      v.iCheck,
+		writeHook:   v.getWriteHook(),
 	}
 }
@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"context"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+// CacheConfigEntry stores the bounds of the physical cache set through
+// sys/config/cache. Zero values keep the bounds of the server configuration,
+// and nil pinned prefixes keep the default ones.
+type CacheConfigEntry struct {
+	MaxEntries     int      `json:"max_entries,omitempty"`
+	MaxBytes       int64    `json:"max_bytes,omitempty"`
+	PinnedPrefixes []string `json:"pinned_prefixes"`
+}
+
+// tunableCache returns the physical cache if its bounds can be changed.
+func (c *Core) tunableCache() (physical.TunableCache, bool) {
+	cache, ok := c.physicalCache.(physical.TunableCache)
+	return cache, ok
+}
+
+// applyCacheConfig sets the bounds of the physical cache from the server
+// configuration and the overrides of sys/config/cache.
+func (c *Core) applyCacheConfig() {
+	cache, ok := c.tunableCache()
+	if !ok {
+		return
+	}
+
+	c.physicalCacheLock.Lock()
+	defer c.physicalCacheLock.Unlock()
+
+	config := c.physicalCacheConfig
+	if overrides := c.physicalCacheOverrides; overrides != nil {
+		if overrides.MaxEntries > 0 {
+			config.MaxEntries = overrides.MaxEntries
+		}
+		if overrides.MaxBytes > 0 {
+			config.MaxBytes = overrides.MaxBytes
+		}
+		config.PinnedPrefixes = overrides.PinnedPrefixes
+	}
+	cache.SetCacheConfig(config)
+}
+
+// saveCacheConfig persists the overrides of the bounds of the physical cache
+// and applies them. A nil entry removes the overrides.
+func (c *Core) saveCacheConfig(ctx context.Context, entry *CacheConfigEntry) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	if entry == nil {
+		if err := view.Delete(ctx, "cache"); err != nil {
+			return errwrap.Wrapf("failed to delete cache config: {{err}}", err)
+		}
+	} else {
+		storageEntry, err := logical.StorageEntryJSON("cache", entry)
+		if err != nil {
+			return errwrap.Wrapf("failed to create cache config entry: {{err}}", err)
+		}
+		if err := view.Put(ctx, storageEntry); err != nil {
+			return errwrap.Wrapf("failed to save cache config: {{err}}", err)
+		}
+	}
+
+	c.physicalCacheLock.Lock()
+	c.physicalCacheOverrides = entry
+	c.physicalCacheLock.Unlock()
+
+	c.applyCacheConfig()
+	return nil
+}
+
+// loadCacheConfig loads the overrides of the bounds of the physical cache and
+// applies them. This should only be called with the core state lock held for
+// writing.
+func (c *Core) loadCacheConfig(ctx context.Context) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	out, err := view.Get(ctx, "cache")
+	if err != nil {
+		return errwrap.Wrapf("failed to read cache config: {{err}}", err)
+	}
+
+	var entry *CacheConfigEntry
+	if out != nil {
+		entry = new(CacheConfigEntry)
+		if err := out.DecodeJSON(entry); err != nil {
+			return err
+		}
+	}
+
+	c.physicalCacheLock.Lock()
+	c.physicalCacheOverrides = entry
+	c.physicalCacheLock.Unlock()
+
+	c.applyCacheConfig()
+	return nil
+}
@@ -0,0 +1,423 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/helper/wrapping"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// controlGroupRequestPath is where a control group token stores the
+	// request waiting for approval, in its cubbyhole
+	controlGroupRequestPath = "cubbyhole/control-group"
+
+	// defaultControlGroupTTL is how long a request waits for approval when
+	// its control group sets no TTL
+	defaultControlGroupTTL = 24 * time.Hour
+)
+
+// controlGroupRequiredError is returned by the policy checks when a request
+// must be approved through a control group before being performed
+type controlGroupRequiredError struct {
+	controlGroup *ControlGroup
+}
+
+func (e *controlGroupRequiredError) Error() string {
+	return "request requires control group approval"
+}
+
+// controlGroupFromErr returns the control group a request must be approved
+// through, if the error is the one of the policy checks asking for it
+func controlGroupFromErr(err error) *ControlGroup {
+	switch t := err.(type) {
+	case *controlGroupRequiredError:
+		return t.controlGroup
+	case *multierror.Error:
+		for _, e := range t.Errors {
+			if cg := controlGroupFromErr(e); cg != nil {
+				return cg
+			}
+		}
+	}
+	return nil
+}
+
+// controlGroupRequest is a request waiting for the approval of its control
+// group, along with the approvals given so far
+type controlGroupRequest struct {
+	Path           string                       `json:"path"`
+	Operation      logical.Operation            `json:"operation"`
+	Data           map[string]interface{}       `json:"data,omitempty"`
+	ClientToken    string                       `json:"client_token"`
+	WrapInfo       *logical.RequestWrapInfo     `json:"wrap_info,omitempty"`
+	EntityID       string                       `json:"entity_id"`
+	NamespaceID    string                       `json:"namespace_id"`
+	RequestTime    time.Time                    `json:"request_time"`
+	Factors        []*ControlGroupFactor        `json:"factors"`
+	Authorizations []*controlGroupAuthorization `json:"authorizations"`
+	Approved       bool                         `json:"approved"`
+}
+
+type controlGroupAuthorization struct {
+	EntityID          string    `json:"entity_id"`
+	TokenAccessor     string    `json:"token_accessor"`
+	AuthorizationTime time.Time `json:"authorization_time"`
+}
+
+// createControlGroupToken parks a request which requires the approval of a
+// control group: the request is stored in the cubbyhole of a new control
+// group token, which is returned to the caller as a wrapping token. Once the
+// request is approved, unwrapping the token performs it.
+func (c *Core) createControlGroupToken(ctx context.Context, req *logical.Request, auth *logical.Auth, cg *ControlGroup) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := cg.TTL
+	if ttl == 0 {
+		ttl = defaultControlGroupTTL
+	}
+
+	creationTime := time.Now()
+	te := logical.TokenEntry{
+		Path:           req.Path,
+		Policies:       []string{controlGroupPolicyName},
+		CreationTime:   creationTime.Unix(),
+		TTL:            ttl,
+		NumUses:        1,
+		ExplicitMaxTTL: ttl,
+		NamespaceID:    ns.ID,
+	}
+	if err := c.tokenStore.create(ctx, &te); err != nil {
+		return nil, errwrap.Wrapf("failed to create control group token: {{err}}", err)
+	}
+
+	cgReq := &controlGroupRequest{
+		Path:        req.Path,
+		Operation:   req.Operation,
+		Data:        req.Data,
+		ClientToken: req.ClientToken,
+		WrapInfo:    req.WrapInfo,
+		NamespaceID: ns.ID,
+		RequestTime: creationTime.UTC(),
+		Factors:     cg.Factors,
+	}
+	if auth != nil {
+		cgReq.EntityID = auth.EntityID
+	}
+	if err := c.storeControlGroupRequest(ctx, &te, cgReq); err != nil {
+		c.tokenStore.revokeOrphan(ctx, te.ID)
+		return nil, err
+	}
+
+	// Store info for lookup through sys/wrapping/lookup
+	cubbyReq := &logical.Request{
+		Operation:   logical.CreateOperation,
+		Path:        "cubbyhole/wrapinfo",
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"creation_ttl":  ttl,
+			"creation_time": creationTime,
+			"creation_path": req.Path,
+		},
+	}
+	cubbyReq.SetTokenEntry(&te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err == nil && cubbyResp != nil && cubbyResp.IsError() {
+		err = cubbyResp.Error()
+	}
+	if err != nil {
+		c.tokenStore.revokeOrphan(ctx, te.ID)
+		return nil, errwrap.Wrapf("failed to store control group token information: {{err}}", err)
+	}
+
+	cgAuth := &logical.Auth{
+		ClientToken: te.ID,
+		Policies:    []string{controlGroupPolicyName},
+		LeaseOptions: logical.LeaseOptions{
+			TTL:       te.TTL,
+			Renewable: false,
+		},
+	}
+	if err := c.expiration.RegisterAuth(ctx, &te, cgAuth); err != nil {
+		c.tokenStore.revokeOrphan(ctx, te.ID)
+		return nil, errwrap.Wrapf("failed to register control group token lease: {{err}}", err)
+	}
+
+	return &logical.Response{
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			Token:           te.ID,
+			Accessor:        te.Accessor,
+			TTL:             ttl,
+			CreationTime:    creationTime,
+			CreationPath:    req.Path,
+			WrappedEntityID: cgReq.EntityID,
+		},
+	}, nil
+}
+
+func (c *Core) storeControlGroupRequest(ctx context.Context, te *logical.TokenEntry, cgReq *controlGroupRequest) error {
+	marshaled, err := json.Marshal(cgReq)
+	if err != nil {
+		return errwrap.Wrapf("failed to marshal control group request: {{err}}", err)
+	}
+
+	cubbyReq := &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        controlGroupRequestPath,
+		ClientToken: te.ID,
+		Data: map[string]interface{}{
+			"request": string(marshaled),
+		},
+	}
+	cubbyReq.SetTokenEntry(te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err == nil && cubbyResp != nil && cubbyResp.IsError() {
+		err = cubbyResp.Error()
+	}
+	if err != nil {
+		return errwrap.Wrapf("failed to store control group request: {{err}}", err)
+	}
+	return nil
+}
+
+func (c *Core) loadControlGroupRequest(ctx context.Context, te *logical.TokenEntry) (*controlGroupRequest, error) {
+	cubbyReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        controlGroupRequestPath,
+		ClientToken: te.ID,
+	}
+	cubbyReq.SetTokenEntry(te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err == nil && cubbyResp != nil && cubbyResp.IsError() {
+		err = cubbyResp.Error()
+	}
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read control group request: {{err}}", err)
+	}
+	if cubbyResp == nil || cubbyResp.Data == nil {
+		return nil, nil
+	}
+
+	raw, ok := cubbyResp.Data["request"].(string)
+	if !ok {
+		return nil, errors.New("could not decode control group request")
+	}
+	var cgReq controlGroupRequest
+	if err := jsonutil.DecodeJSON([]byte(raw), &cgReq); err != nil {
+		return nil, errwrap.Wrapf("failed to decode control group request: {{err}}", err)
+	}
+	return &cgReq, nil
+}
+
+// controlGroupTokenByAccessor returns the control group token of the given
+// accessor, and the context of its namespace
+func (c *Core) controlGroupTokenByAccessor(ctx context.Context, accessor string) (*logical.TokenEntry, context.Context, error) {
+	aEntry, err := c.tokenStore.lookupByAccessor(ctx, accessor, false, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	te, err := c.tokenStore.Lookup(ctx, aEntry.TokenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if te == nil || len(te.Policies) != 1 || te.Policies[0] != controlGroupPolicyName {
+		return nil, nil, nil
+	}
+
+	tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tokenNS == nil {
+		return nil, nil, namespace.ErrNoNamespace
+	}
+	return te, namespace.ContextWithNamespace(ctx, tokenNS), nil
+}
+
+// authorizeControlGroupRequest records the approval of a control group
+// request by an entity, which must be a member of the groups of one of the
+// factors of the control group. Requesters cannot approve their own requests.
+// The request is approved once every factor has gathered the approvals it
+// requires.
+func (c *Core) authorizeControlGroupRequest(ctx context.Context, te *logical.TokenEntry, entity *identity.Entity, tokenAccessor string) (*controlGroupRequest, error) {
+	c.controlGroupLock.Lock()
+	defer c.controlGroupLock.Unlock()
+
+	cgReq, err := c.loadControlGroupRequest(ctx, te)
+	if err != nil {
+		return nil, err
+	}
+	if cgReq == nil {
+		return nil, &logical.StatusBadRequest{Err: "no request found for the control group token"}
+	}
+	if cgReq.EntityID != "" && cgReq.EntityID == entity.ID {
+		return nil, &logical.StatusBadRequest{Err: "requesters cannot authorize their own requests"}
+	}
+
+	factors, err := c.controlGroupFactorsOf(cgReq, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(factors) == 0 {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	for _, authz := range cgReq.Authorizations {
+		if authz.EntityID == entity.ID {
+			return cgReq, nil
+		}
+	}
+	cgReq.Authorizations = append(cgReq.Authorizations, &controlGroupAuthorization{
+		EntityID:          entity.ID,
+		TokenAccessor:     tokenAccessor,
+		AuthorizationTime: time.Now().UTC(),
+	})
+
+	approved, err := c.controlGroupApproved(cgReq)
+	if err != nil {
+		return nil, err
+	}
+	cgReq.Approved = approved
+
+	if err := c.storeControlGroupRequest(ctx, te, cgReq); err != nil {
+		return nil, err
+	}
+	return cgReq, nil
+}
+
+// controlGroupFactorsOf returns the names of the factors of the request the
+// entity can approve it for
+func (c *Core) controlGroupFactorsOf(cgReq *controlGroupRequest, entityID string) ([]string, error) {
+	direct, inherited, err := c.identityStore.groupsByEntityID(entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var factors []string
+	for _, factor := range cgReq.Factors {
+		if factor.Identity == nil {
+			continue
+		}
+		for _, group := range append(direct, inherited...) {
+			if strutil.StrListContains(factor.Identity.GroupIDs, group.ID) ||
+				strutil.StrListContains(factor.Identity.GroupNames, group.Name) {
+				factors = append(factors, factor.Name)
+				break
+			}
+		}
+	}
+	return factors, nil
+}
+
+func (c *Core) controlGroupApproved(cgReq *controlGroupRequest) (bool, error) {
+	approvals := make(map[string]int, len(cgReq.Factors))
+	for _, authz := range cgReq.Authorizations {
+		factors, err := c.controlGroupFactorsOf(cgReq, authz.EntityID)
+		if err != nil {
+			return false, err
+		}
+		for _, factor := range factors {
+			approvals[factor]++
+		}
+	}
+
+	for _, factor := range cgReq.Factors {
+		if factor.Identity == nil || approvals[factor.Name] < factor.Identity.ApprovalsRequired {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// controlGroupUnwrap performs the request parked with a control group token
+// once it has been approved, then revokes the token. The response is
+// returned marshaled, the way wrapped responses are.
+func (c *Core) controlGroupUnwrap(ctx context.Context, token string) (string, error) {
+	te, err := c.tokenStore.Lookup(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if te == nil {
+		return "", logical.ErrPermissionDenied
+	}
+
+	cgReq, err := c.loadControlGroupRequest(ctx, te)
+	if err != nil {
+		return "", err
+	}
+	if cgReq == nil {
+		return "no request found for the control group token", ErrInternalError
+	}
+	if !cgReq.Approved {
+		return "request needs further approval", logical.ErrInvalidRequest
+	}
+
+	reqNS, err := NamespaceByID(ctx, cgReq.NamespaceID, c)
+	if err != nil {
+		return "", err
+	}
+	if reqNS == nil {
+		return "", namespace.ErrNoNamespace
+	}
+
+	reqID, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", err
+	}
+	req := &logical.Request{
+		ID:          reqID,
+		Operation:   cgReq.Operation,
+		Path:        cgReq.Path,
+		Data:        cgReq.Data,
+		ClientToken: cgReq.ClientToken,
+		WrapInfo:    cgReq.WrapInfo,
+		ControlGroup: &logical.ControlGroup{
+			RequestTime: cgReq.RequestTime,
+			Approved:    true,
+			NamespaceID: cgReq.NamespaceID,
+		},
+	}
+	for _, authz := range cgReq.Authorizations {
+		req.ControlGroup.Authorizations = append(req.ControlGroup.Authorizations, &logical.Authz{
+			Token:             authz.TokenAccessor,
+			AuthorizationTime: authz.AuthorizationTime,
+		})
+	}
+
+	resp, err := c.handleCancelableRequest(namespace.ContextWithNamespace(ctx, reqNS), reqNS, req)
+	if err != nil {
+		if resp != nil && resp.IsError() {
+			return resp.Error().Error(), err
+		}
+		return "", err
+	}
+
+	// The request was performed, so the token cannot be used again
+	if err := c.tokenStore.revokeOrphan(ctx, te.ID); err != nil {
+		c.logger.Error("failed to revoke control group token", "error", err)
+	}
+
+	if resp == nil {
+		return "", nil
+	}
+	httpResp := logical.LogicalResponseToHTTPResponse(resp)
+	httpResp.RequestID = req.ID
+	marshaled, err := json.Marshal(httpResp)
+	if err != nil {
+		return "", errwrap.Wrapf("failed to marshal control group response: {{err}}", err)
+	}
+	return string(marshaled), nil
+}
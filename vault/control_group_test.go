@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestControlGroup_AuthorizeAndUnwrap(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	handle := func(req *logical.Request) *logical.Response {
+		t.Helper()
+		resp, err := c.HandleRequest(ctx, req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v, resp: %#v", err, resp)
+		}
+		return resp
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/policy/cg")
+	req.ClientToken = root
+	req.Data["policy"] = `
+path "secret/foo" {
+	capabilities = ["read"]
+	control_group = {
+		factor "ops" {
+			identity {
+				group_names = ["approvers"]
+				approvals = 1
+			}
+		}
+	}
+}`
+	handle(req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/policy/authorizer")
+	req.ClientToken = root
+	req.Data["policy"] = `
+path "sys/control-group/authorize" {
+	capabilities = ["update"]
+}`
+	handle(req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.ClientToken = root
+	req.Data["zip"] = "zap"
+	handle(req)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["name"] = "requester"
+	requesterID := handle(req).Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/entity")
+	req.ClientToken = root
+	req.Data["name"] = "approver"
+	approverID := handle(req).Data["id"].(string)
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "identity/group")
+	req.ClientToken = root
+	req.Data["name"] = "approvers"
+	req.Data["member_entity_ids"] = []string{approverID}
+	handle(req)
+
+	requester := &logical.TokenEntry{
+		Path:     "test",
+		Policies: []string{"default", "cg", "authorizer"},
+		EntityID: requesterID,
+	}
+	testMakeTokenDirectly(t, c.tokenStore, requester)
+	approver := &logical.TokenEntry{
+		Path:     "test",
+		Policies: []string{"default", "authorizer"},
+		EntityID: approverID,
+	}
+	testMakeTokenDirectly(t, c.tokenStore, approver)
+
+	// The read is parked behind a wrapping token
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = requester.ID
+	resp := handle(req)
+	if resp == nil || resp.WrapInfo == nil {
+		t.Fatalf("expected wrap info, got %#v", resp)
+	}
+	if resp.Data != nil {
+		t.Fatalf("expected no data, got %#v", resp.Data)
+	}
+	wrapToken, accessor := resp.WrapInfo.Token, resp.WrapInfo.Accessor
+
+	// Unwrapping before the approval fails
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	resp, err := c.HandleRequest(ctx, req)
+	if err == nil {
+		t.Fatalf("expected error unwrapping unapproved request, got %#v", resp)
+	}
+
+	// Requesters cannot approve their own requests
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+	req.ClientToken = requester.ID
+	req.Data["accessor"] = accessor
+	resp, err = c.HandleRequest(ctx, req)
+	if err == nil {
+		t.Fatalf("expected error authorizing own request, got %#v", resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/authorize")
+	req.ClientToken = approver.ID
+	req.Data["accessor"] = accessor
+	resp = handle(req)
+	if !resp.Data["approved"].(bool) {
+		t.Fatalf("expected request to be approved, got %#v", resp.Data)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/control-group/request")
+	req.ClientToken = requester.ID
+	req.Data["accessor"] = accessor
+	resp = handle(req)
+	if !resp.Data["approved"].(bool) || resp.Data["request_path"].(string) != "secret/foo" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	authorizations := resp.Data["authorizations"].([]map[string]interface{})
+	if len(authorizations) != 1 || authorizations[0]["entity_name"] != "approver" {
+		t.Fatalf("bad authorizations: %#v", authorizations)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	resp = handle(req)
+	if resp.Data["zip"] != "zap" {
+		t.Fatalf("expected the secret, got %#v", resp.Data)
+	}
+
+	// The token cannot be used twice
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/wrapping/unwrap")
+	req.ClientToken = wrapToken
+	if resp, err = c.HandleRequest(ctx, req); err == nil {
+		t.Fatalf("expected error unwrapping twice, got %#v", resp)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/helper/loggers"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/internalshared/reloadutil"
@@ -44,6 +45,7 @@ import (
 	sr "github.com/hashicorp/vault/serviceregistration"
 	"github.com/hashicorp/vault/shamir"
 	"github.com/hashicorp/vault/vault/cluster"
+	"github.com/hashicorp/vault/vault/quotas"
 	vaultseal "github.com/hashicorp/vault/vault/seal"
 	"github.com/patrickmn/go-cache"
 	"google.golang.org/grpc"
@@ -236,6 +238,23 @@ type Core struct {
 	migrationInfo *migrationInformation
 	sealMigrated  *uint32
 
+	// onlineSealMigration has migrations between auto-seals performed with
+	// sys/seal-migrate rather than by unsealing with the migrate flag
+	onlineSealMigration bool
+	// sealMigration is the online seal migration the node was started for;
+	// it is only set when creating the core
+	sealMigration *onlineSealMigration
+
+	// drainGracePeriod is how long new requests are held while the node
+	// steps down or seals, rather than failed right away
+	drainGracePeriod time.Duration
+	drainLock        sync.RWMutex
+	drain            *drain
+
+	// sealHealth tracks the health of a single auto-seal; the health of
+	// multiple seals is tracked by their wrapper
+	sealHealth *vaultseal.WrapperHealth
+
 	// unwrapSeal is the seal to use on Enterprise to unwrap values wrapped
 	// with the previous seal.
 	unwrapSeal Seal
@@ -318,6 +337,17 @@ type Core struct {
 	// can be output in the audit logs
 	auditedHeaders *AuditedHeadersConfig
 
+	// quotaManager holds the configured quotas, and the lease counts
+	// they are enforced against
+	quotaManager *quotas.Manager
+
+	// controlGroupLock serializes the approvals of control group requests
+	controlGroupLock sync.Mutex
+
+	// events delivers notifications of changes to their subscribers. It
+	// outlives seals so that subscribers learn when the core is unsealed.
+	events *EventBus
+
 	// systemBackend is the backend which is used to manage internal operations
 	systemBackend *SystemBackend
 
@@ -334,6 +364,12 @@ type Core struct {
 	// rollback manager is used to run rollbacks periodically
 	rollback *RollbackManager
 
+	// namespaceStore holds the namespaces other than the root namespace
+	namespaceStore *namespaceStore
+
+	// mirror runs the mounts mirrored from and to other clusters
+	mirror *mirrorManager
+
 	// policy store is used to manage named ACL policies
 	policyStore *PolicyStore
 
@@ -368,6 +404,13 @@ type Core struct {
 	// disabled
 	physicalCache physical.ToggleablePurgemonster
 
+	// physicalCacheConfig holds the bounds of the cache from the server
+	// configuration, and physicalCacheOverrides the ones set through
+	// sys/config/cache, if any
+	physicalCacheLock      sync.Mutex
+	physicalCacheConfig    physical.CacheConfig
+	physicalCacheOverrides *CacheConfigEntry
+
 	// reloadFuncs is a map containing reload functions
 	reloadFuncs map[string][]reloadutil.ReloadFunc
 
@@ -480,6 +523,10 @@ type Core struct {
 	allLoggers     []log.Logger
 	allLoggersLock sync.RWMutex
 
+	// logRouter writes the server logs at the level of each subsystem,
+	// which is set through sys/loggers
+	logRouter *loggers.Router
+
 	// Can be toggled atomically to cause the core to never try to become
 	// active, or give up active as soon as it gets it
 	neverBecomeActive *uint32
@@ -497,12 +544,19 @@ type Core struct {
 	// Stores request counters
 	counters counters
 
+	// activityLog tracks the distinct clients using tokens each month
+	activityLog *activityLog
+
 	// Stores the raft applied index for standby nodes
 	raftFollowerStates *raftFollowerStates
 	// Stop channel for raft TLS rotations
 	raftTLSRotationStopCh chan struct{}
 	// Stores the pending peers we are waiting to give answers
 	pendingRaftPeers *sync.Map
+	// Manages the servers of the raft cluster on the active node
+	raftAutopilot *raftAutopilot
+	// Takes the configured snapshots of the raft storage on the active node
+	raftAutoSnapshots *raftAutoSnapshots
 
 	// rawConfig stores the config as-is from the provided server configuration.
 	rawConfig *atomic.Value
@@ -514,6 +568,8 @@ type Core struct {
 
 	recoveryMode bool
 
+	disableAutopilot bool
+
 	clusterNetworkLayer cluster.NetworkLayer
 
 	// PR1103disabled is used to test upgrade workflows: when set to true,
@@ -562,6 +618,10 @@ type CoreConfig struct {
 	// Custom cache size for the LRU cache on the physical backend, or zero for default
 	CacheSize int
 
+	// Custom size in bytes of the entries of the LRU cache on the physical
+	// backend, or zero for default
+	CacheMaxBytes int
+
 	// Set as the leader address for HA
 	RedirectAddr string
 
@@ -601,6 +661,10 @@ type CoreConfig struct {
 
 	AllLoggers []log.Logger
 
+	// LogRouter, if set, writes the server logs at the level of each
+	// subsystem and enables the sys/loggers endpoints
+	LogRouter *loggers.Router
+
 	// Telemetry objects
 	MetricsHelper *metricsutil.MetricsHelper
 	MetricSink    *metricsutil.ClusterMetricSink
@@ -610,6 +674,19 @@ type CoreConfig struct {
 	RecoveryMode bool
 
 	ClusterNetworkLayer cluster.NetworkLayer
+
+	// DisableAutopilot adds servers joining the raft cluster as voters right
+	// away, rather than once autopilot finds them stable
+	DisableAutopilot bool
+
+	// OnlineSealMigration has migrations between auto-seals performed with
+	// sys/seal-migrate while the cluster keeps serving, rather than by
+	// unsealing each node with the migrate flag
+	OnlineSealMigration bool
+
+	// DrainGracePeriod is how long new requests are held while the node
+	// steps down or seals, until it can serve or forward them again
+	DrainGracePeriod time.Duration
 }
 
 func (c *CoreConfig) Clone() *CoreConfig {
@@ -626,6 +703,7 @@ func (c *CoreConfig) Clone() *CoreConfig {
 		DisableCache:              c.DisableCache,
 		DisableMlock:              c.DisableMlock,
 		CacheSize:                 c.CacheSize,
+		CacheMaxBytes:             c.CacheMaxBytes,
 		StorageType:               c.StorageType,
 		RedirectAddr:              c.RedirectAddr,
 		ClusterAddr:               c.ClusterAddr,
@@ -644,8 +722,12 @@ func (c *CoreConfig) Clone() *CoreConfig {
 		DisablePerformanceStandby: c.DisablePerformanceStandby,
 		DisableIndexing:           c.DisableIndexing,
 		AllLoggers:                c.AllLoggers,
+		LogRouter:                 c.LogRouter,
 		CounterSyncInterval:       c.CounterSyncInterval,
 		ClusterNetworkLayer:       c.ClusterNetworkLayer,
+		DisableAutopilot:          c.DisableAutopilot,
+		OnlineSealMigration:       c.OnlineSealMigration,
+		DrainGracePeriod:          c.DrainGracePeriod,
 		entCoreConfig:             c.entCoreConfig.Clone(),
 	}
 }
@@ -765,6 +847,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		disablePerfStandby:           true,
 		activeContextCancelFunc:      new(atomic.Value),
 		allLoggers:                   conf.AllLoggers,
+		logRouter:                    conf.LogRouter,
 		builtinRegistry:              conf.BuiltinRegistry,
 		neverBecomeActive:            new(uint32),
 		clusterLeaderParams:          new(atomic.Value),
@@ -776,9 +859,15 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 			requests:     new(uint64),
 			syncInterval: syncInterval,
 		},
-		recoveryMode:      conf.RecoveryMode,
-		postUnsealStarted: new(uint32),
-		raftJoinDoneCh:    make(chan struct{}),
+		activityLog:         newActivityLog(),
+		recoveryMode:        conf.RecoveryMode,
+		disableAutopilot:    conf.DisableAutopilot,
+		onlineSealMigration: conf.OnlineSealMigration,
+		drainGracePeriod:    conf.DrainGracePeriod,
+		namespaceStore:      newNamespaceStore(),
+		sealHealth:          vaultseal.NewWrapperHealth(),
+		postUnsealStarted:   new(uint32),
+		raftJoinDoneCh:      make(chan struct{}),
 	}
 
 	c.rawConfig.Store(conf.RawConfig)
@@ -791,6 +880,18 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 	c.router.logger = c.logger.Named("router")
 	c.allLoggers = append(c.allLoggers, c.router.logger)
 
+	quotasLogger := c.baseLogger.Named("quotas")
+	c.allLoggers = append(c.allLoggers, quotasLogger)
+	c.quotaManager = quotas.NewManager(quotasLogger, c.metricSink)
+
+	mirrorLogger := c.baseLogger.Named("mirror")
+	c.allLoggers = append(c.allLoggers, mirrorLogger)
+	c.mirror = newMirrorManager(c, mirrorLogger)
+
+	eventsLogger := c.baseLogger.Named("events")
+	c.allLoggers = append(c.allLoggers, eventsLogger)
+	c.events = NewEventBus(eventsLogger)
+
 	atomic.StoreUint32(c.replicationState, uint32(consts.ReplicationDRDisabled|consts.ReplicationPerformanceDisabled))
 	c.localClusterCert.Store(([]byte)(nil))
 	c.localClusterParsedCert.Store((*x509.Certificate)(nil))
@@ -844,6 +945,12 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		return nil, err
 	}
 
+	c.physicalCacheConfig = physical.CacheConfig{
+		MaxEntries: conf.CacheSize,
+		MaxBytes:   int64(conf.CacheMaxBytes),
+	}
+	c.applyCacheConfig()
+
 	if !conf.DisableMlock {
 		// Ensure our memory usage is locked into physical RAM
 		if err := mlock.LockMemory(); err != nil {
@@ -1315,6 +1422,12 @@ func (c *Core) unsealPart(ctx context.Context, seal Seal, key []byte, useRecover
 }
 
 func (c *Core) migrateSeal(ctx context.Context) error {
+	if c.sealMigration != nil {
+		// Online migrations are performed on request; only pick up one
+		// performed while this node was a standby
+		return c.adoptMigratedSeal(ctx)
+	}
+
 	if c.migrationInfo == nil {
 		return nil
 	}
@@ -1510,6 +1623,7 @@ func (c *Core) unsealInternal(ctx context.Context, masterKey []byte) (bool, erro
 	if c.logger.IsInfo() {
 		c.logger.Info("vault is unsealed")
 	}
+	c.publishSealEvent(false)
 
 	if c.serviceRegistration != nil {
 		if err := c.serviceRegistration.NotifySealedStateChange(false); err != nil {
@@ -1685,6 +1799,10 @@ func (c *Core) sealInitCommon(ctx context.Context, req *logical.Request) (retErr
 		}
 	}
 
+	// Hold new requests while sealing, in case the node is unsealed again
+	// before the grace period runs out
+	c.beginDrain(DrainReasonSeal, c.sealReadyFunc())
+
 	// Unlock; sealing will grab the lock when needed
 	unlocked = true
 	c.stateLock.RUnlock()
@@ -1722,6 +1840,7 @@ func (c *Core) sealInternalWithOptions(grabStateLock, keepHALock, shutdownRaft b
 	c.metricSink.SetGaugeWithLabels([]string{"core", "unsealed"}, 0, nil)
 
 	c.logger.Info("marked as sealed")
+	c.publishSealEvent(true)
 
 	// Clear forwarding clients
 	c.requestForwardingConnectionLock.Lock()
@@ -1866,6 +1985,9 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.setupPluginCatalog(ctx); err != nil {
 		return err
 	}
+	if err := c.loadNamespaces(ctx); err != nil {
+		return err
+	}
 	if err := c.loadMounts(ctx); err != nil {
 		return err
 	}
@@ -1881,9 +2003,15 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.loadCORSConfig(ctx); err != nil {
 		return err
 	}
+	if err := c.loadCacheConfig(ctx); err != nil {
+		return err
+	}
 	if err := c.loadCurrentRequestCounters(ctx, time.Now()); err != nil {
 		return err
 	}
+	if err := c.loadCurrentActivity(ctx, time.Now()); err != nil {
+		return err
+	}
 	if err := c.loadCredentials(ctx); err != nil {
 		return err
 	}
@@ -1893,6 +2021,9 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 	if err := c.setupCredentials(ctx); err != nil {
 		return err
 	}
+	if err := c.setupQuotas(ctx); err != nil {
+		return err
+	}
 	if !c.IsDRSecondary() {
 		if err := c.startRollback(); err != nil {
 			return err
@@ -1909,6 +2040,7 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 		if err := c.loadIdentityStoreArtifacts(ctx); err != nil {
 			return err
 		}
+		go c.runExternalGroupSync(ctx)
 		if err := loadMFAConfigs(ctx, c); err != nil {
 			return err
 		}
@@ -1930,6 +2062,10 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 
 	}
 
+	if err := c.setupMirror(ctx); err != nil {
+		return err
+	}
+
 	c.clusterParamsLock.Lock()
 	defer c.clusterParamsLock.Unlock()
 	if err := startReplication(c); err != nil {
@@ -2033,6 +2169,8 @@ func (c *Core) preSeal() error {
 
 	c.stopRaftActiveNode()
 
+	c.teardownMirror()
+
 	c.clusterParamsLock.Lock()
 	if err := stopReplication(c); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping replication: {{err}}", err))
@@ -2045,6 +2183,7 @@ func (c *Core) preSeal() error {
 	if err := c.stopExpiration(); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error stopping expiration: {{err}}", err))
 	}
+	c.teardownQuotas()
 	if err := c.teardownCredentials(context.Background()); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error tearing down credentials: {{err}}", err))
 	}
@@ -2057,6 +2196,13 @@ func (c *Core) preSeal() error {
 	if err := c.unloadMounts(context.Background()); err != nil {
 		result = multierror.Append(result, errwrap.Wrapf("error unloading mounts: {{err}}", err))
 	}
+	c.teardownNamespaces()
+	if c.pluginCatalog != nil {
+		c.pluginCatalog.cleanupExternalPlugins()
+		if err := c.pluginCatalog.runtimes.Stop(); err != nil {
+			result = multierror.Append(result, errwrap.Wrapf("error stopping plugin runtimes: {{err}}", err))
+		}
+	}
 	if err := enterprisePreSeal(c); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -2093,6 +2239,12 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 	writeTimer := time.Tick(c.counters.syncInterval)
 	identityCountTimer := time.Tick(time.Minute * 10)
 
+	// The gauge collection processes are started and stopped here
+	// along with the rest of the metrics, so that there is only one set.
+	for _, proc := range c.startGaugeCollectionProcesses() {
+		defer proc.Stop()
+	}
+
 	for {
 		select {
 		case <-emitTimer:
@@ -2100,6 +2252,7 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 			if c.expiration != nil {
 				c.expiration.emitMetrics()
 			}
+			c.quotaManager.EmitMetrics()
 			//Refresh the sealed gauge
 			if c.Sealed() {
 				c.metricSink.SetGaugeWithLabels([]string{"core", "unsealed"}, 0, nil)
@@ -2122,6 +2275,9 @@ func (c *Core) emitMetrics(stopCh chan struct{}) {
 				if err != nil {
 					c.logger.Error("writing request counters to barrier", "err", err)
 				}
+				if err := c.saveCurrentActivity(context.Background(), time.Now()); err != nil {
+					c.logger.Error("writing activity log to barrier", "err", err)
+				}
 			}
 			c.stateLock.RUnlock()
 		case <-identityCountTimer:
@@ -2313,6 +2469,14 @@ func (c *Core) adjustForSealMigration(unwrapSeal Seal) error {
 		return nil
 	}
 
+	if c.onlineSealMigration {
+		if migrationSeal != nil && migrationSeal.RecoveryKeySupported() && newSeal.RecoveryKeySupported() {
+			c.setSealsForOnlineMigration(migrationSeal, newSeal)
+			return nil
+		}
+		c.logger.Warn("online seal migration is only supported between auto-seals; unseal with the migrate flag instead", "from_barrier_type", existBarrierSealConfig.Type, "to_barrier_type", newSeal.BarrierType())
+	}
+
 	// Set the appropriate barrier and recovery configs.
 	switch {
 	case migrationSeal != nil && newSeal != nil && migrationSeal.RecoveryKeySupported() && newSeal.RecoveryKeySupported():
@@ -2446,10 +2610,16 @@ func (c *Core) AddLogger(logger log.Logger) {
 
 func (c *Core) SetLogLevel(level log.Level) {
 	c.allLoggersLock.RLock()
-	defer c.allLoggersLock.RUnlock()
 	for _, logger := range c.allLoggers {
 		logger.SetLevel(level)
 	}
+	c.allLoggersLock.RUnlock()
+
+	// The router sets the level of the loggers it writes for last, as it
+	// may need to be more verbose for the levels set for subsystems
+	if c.logRouter != nil {
+		c.logRouter.SetConfiguredLevel(level)
+	}
 }
 
 // SetConfig sets core's config object to the newly provided config.
@@ -2,7 +2,9 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/helper/metricsutil"
@@ -12,6 +14,109 @@ import (
 
 // TODO: move emitMetrics into this file.
 
+// measureRequestLatency reports the time since start both as a sample,
+// and, if enabled, in a histogram.
+func (c *Core) measureRequestLatency(key []string, start time.Time) {
+	metrics.MeasureSince(key, start)
+	c.metricSink.MeasureSinceHistogramWithLabels(key, start, nil)
+}
+
+// startGaugeCollectionProcesses starts the background collection of
+// usage gauges, unless disabled by a zero usage gauge period.
+// The caller is responsible for stopping the returned processes.
+func (c *Core) startGaugeCollectionProcesses() []*metricsutil.GaugeCollectionProcess {
+	if c.metricSink.GaugeInterval == 0 {
+		return nil
+	}
+
+	// The token and kv collectors walk storage, so they are scheduled as expensive
+	// and run one at a time; token counts take precedence.
+	metricsInit := []struct {
+		MetricName    []string
+		MetadataLabel []metrics.Label
+		CollectorFunc metricsutil.GaugeCollector
+		Options       metricsutil.GaugeCollectionOptions
+	}{
+		{
+			[]string{"token", "count"},
+			[]metrics.Label{{"gauge", "token_by_namespace"}},
+			c.tokenGaugeCollector,
+			metricsutil.GaugeCollectionOptions{Expensive: true, Priority: 1},
+		},
+		{
+			[]string{"secret", "kv", "count"},
+			[]metrics.Label{{"gauge", "kv_secrets_by_mountpoint"}},
+			c.kvSecretGaugeCollector,
+			metricsutil.GaugeCollectionOptions{Expensive: true},
+		},
+		{
+			[]string{"cache", "physical"},
+			[]metrics.Label{{"gauge", "physical_cache_stats"}},
+			c.physicalCacheGaugeCollector,
+			metricsutil.GaugeCollectionOptions{},
+		},
+	}
+
+	processes := make([]*metricsutil.GaugeCollectionProcess, 0, len(metricsInit))
+	for _, init := range metricsInit {
+		proc, err := c.metricSink.NewGaugeCollectionProcessWithOptions(
+			init.MetricName,
+			init.MetadataLabel,
+			init.CollectorFunc,
+			c.logger,
+			init.Options,
+		)
+		if err != nil {
+			c.logger.Error("failed to start collector", "metric", init.MetricName, "error", err)
+			continue
+		}
+		go proc.Run()
+		processes = append(processes, proc)
+	}
+	return processes
+}
+
+// tokenGaugeCollector defers to the token store, which may not
+// be set up yet.
+func (c *Core) tokenGaugeCollector(ctx context.Context) ([]metricsutil.GaugeLabelValues, error) {
+	if c.tokenStore == nil {
+		return []metricsutil.GaugeLabelValues{}, errors.New("nil token store")
+	}
+	return c.tokenStore.gaugeCollector(ctx)
+}
+
+// physicalCacheGaugeCollector reports the usage of the physical cache, with
+// a "stat" label per statistic.
+func (c *Core) physicalCacheGaugeCollector(ctx context.Context) ([]metricsutil.GaugeLabelValues, error) {
+	cache, ok := c.tunableCache()
+	if !ok {
+		return []metricsutil.GaugeLabelValues{}, nil
+	}
+
+	stats := cache.CacheStats()
+	values := []struct {
+		stat  string
+		value float32
+	}{
+		{"entries", float32(stats.Entries)},
+		{"bytes", float32(stats.Bytes)},
+		{"pinned_entries", float32(stats.PinnedEntries)},
+		{"pinned_bytes", float32(stats.PinnedBytes)},
+		{"hits", float32(stats.Hits)},
+		{"misses", float32(stats.Misses)},
+		{"evictions", float32(stats.Evictions)},
+	}
+
+	gauges := make([]metricsutil.GaugeLabelValues, 0, len(values))
+	for _, v := range values {
+		gauges = append(gauges, metricsutil.GaugeLabelValues{
+			Labels: []metrics.Label{{"stat", v.stat}},
+			Value:  v.value,
+		})
+	}
+	return gauges, nil
+}
+
 type kvMount struct {
 	Namespace  *namespace.Namespace
 	MountPoint string
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/quotas"
+)
+
+// quotasSubPath is the sub-path used for the quota configuration. This is
+// nested under the system view.
+const quotasSubPath = "quotas/"
+
+// setupQuotas loads the configured quotas
+func (c *Core) setupQuotas(ctx context.Context) error {
+	return c.quotaManager.Setup(ctx, c.systemBarrierView.SubView(quotasSubPath))
+}
+
+// teardownQuotas unloads the configured quotas on seal
+func (c *Core) teardownQuotas() {
+	c.quotaManager.Reset()
+}
+
+// applyLeaseCountQuota returns a 429 error if the request, which may
+// create a lease, is for a mount or namespace whose lease count quota
+// has been reached.
+func (c *Core) applyLeaseCountQuota(ctx context.Context, req *logical.Request) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	mount := c.router.MatchingMount(ctx, req.Path)
+	if mount == "" {
+		return nil
+	}
+	mount = strings.TrimPrefix(mount, ns.Path)
+
+	err = c.quotaManager.ApplyLeaseCountQuota(&quotas.Request{
+		NamespacePath: ns.Path,
+		MountPath:     mount,
+	})
+	if err != nil {
+		c.metricSink.IncrCounterWithLabels([]string{"quota", "lease_count", "violation"}, 1,
+			[]metrics.Label{{"mount_point", mount}})
+		return logical.CodedError(http.StatusTooManyRequests, err.Error())
+	}
+	return nil
+}
+
+// applyRateLimitQuota returns a 429 error if the request exceeds the rate
+// limit quota of its path. Requests to manage quotas are never limited, so
+// that a misconfigured quota can always be fixed.
+func (c *Core) applyRateLimitQuota(ctx context.Context, req *logical.Request) error {
+	if strings.HasPrefix(req.Path, "sys/"+quotasSubPath) {
+		return nil
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	qReq := &quotas.Request{
+		NamespacePath: ns.Path,
+		Path:          req.Path,
+		EntityID:      req.EntityID,
+	}
+	if req.Connection != nil {
+		qReq.ClientAddress = req.Connection.RemoteAddr
+	}
+
+	retryAfter, err := c.quotaManager.ApplyRateLimitQuota(qReq)
+	if err != nil {
+		return logical.CodedError(http.StatusTooManyRequests,
+			fmt.Sprintf("%s; retry in %s", err, retryAfter.Round(time.Millisecond)))
+	}
+	return nil
+}
@@ -5,7 +5,6 @@ package vault
 import (
 	"context"
 
-	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/helper/license"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/physical"
@@ -87,8 +86,6 @@ func loadMFAConfigs(context.Context, *Core) error { return nil }
 
 func shouldStartClusterListener(*Core) bool { return true }
 
-func hasNamespaces(*Core) bool { return false }
-
 func (c *Core) Features() license.Features {
 	return license.FeatureNone
 }
@@ -97,16 +94,6 @@ func (c *Core) HasFeature(license.Features) bool {
 	return false
 }
 
-func (c *Core) collectNamespaces() []*namespace.Namespace {
-	return []*namespace.Namespace{
-		namespace.RootNamespace,
-	}
-}
-
-func (c *Core) namepaceByPath(string) *namespace.Namespace {
-	return namespace.RootNamespace
-}
-
 func (c *Core) setupReplicatedClusterPrimary(*replication.Cluster) error { return nil }
 
 func (c *Core) perfStandbyCount() int { return 0 }
@@ -0,0 +1,180 @@
+package vault
+
+import (
+	"context"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+)
+
+const (
+	// Reasons for a node to drain requests
+	DrainReasonStepDown = "step-down"
+	DrainReasonSeal     = "seal"
+
+	// drainPollInterval is how often a draining node checks whether it can
+	// serve requests again
+	drainPollInterval = 100 * time.Millisecond
+)
+
+// drain is a window during which new requests to a node which is stepping
+// down or sealing are held, rather than failed, until the node can serve or
+// forward them again.
+type drain struct {
+	reason   string
+	deadline time.Time
+	doneCh   chan struct{}
+
+	// ready is set before doneCh is closed if the node can serve requests
+	// again, rather than the grace period having run out
+	ready bool
+}
+
+// DrainStatus reports whether the node is draining requests
+type DrainStatus struct {
+	Draining bool
+	Reason   string
+	Deadline time.Time
+}
+
+// DrainStatus returns whether the node is currently draining requests, and
+// until when.
+func (c *Core) DrainStatus() DrainStatus {
+	c.drainLock.RLock()
+	defer c.drainLock.RUnlock()
+
+	if c.drain == nil {
+		return DrainStatus{}
+	}
+	return DrainStatus{
+		Draining: true,
+		Reason:   c.drain.reason,
+		Deadline: c.drain.deadline,
+	}
+}
+
+// WaitForDrain holds a request while the node is draining. It returns true
+// right away if the node is not draining, or once it can serve requests
+// again. Otherwise it returns false once the grace period runs out or the
+// context is canceled, along with how long the client should wait before
+// retrying.
+func (c *Core) WaitForDrain(ctx context.Context) (bool, time.Duration) {
+	c.drainLock.RLock()
+	d := c.drain
+	c.drainLock.RUnlock()
+
+	if d == nil {
+		return true, 0
+	}
+
+	defer metrics.MeasureSince([]string{"core", "drain", "wait"}, time.Now())
+
+	timer := time.NewTimer(time.Until(d.deadline))
+	defer timer.Stop()
+
+	select {
+	case <-d.doneCh:
+		if d.ready {
+			return true, 0
+		}
+		return false, c.drainGracePeriod
+	case <-timer.C:
+		return false, c.drainGracePeriod
+	case <-ctx.Done():
+		return false, time.Until(d.deadline)
+	}
+}
+
+// beginDrain starts holding new requests for up to the drain grace period,
+// until ready returns true. It does nothing if no grace period is configured
+// or if the node is already draining.
+func (c *Core) beginDrain(reason string, ready func() bool) {
+	if c.drainGracePeriod <= 0 {
+		return
+	}
+
+	c.drainLock.Lock()
+	defer c.drainLock.Unlock()
+
+	if c.drain != nil {
+		return
+	}
+
+	d := &drain{
+		reason:   reason,
+		deadline: time.Now().Add(c.drainGracePeriod),
+		doneCh:   make(chan struct{}),
+	}
+	c.drain = d
+
+	c.logger.Info("draining requests", "reason", reason, "grace_period", c.drainGracePeriod)
+	metrics.IncrCounterWithLabels([]string{"core", "drain", "start"}, 1, []metrics.Label{{"reason", reason}})
+
+	go c.runDrain(d, ready)
+}
+
+// runDrain ends the drain once the node is ready or the grace period has
+// run out.
+func (c *Core) runDrain(d *drain, ready func() bool) {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Until(d.deadline))
+	defer timer.Stop()
+
+LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			if ready() {
+				d.ready = true
+				break LOOP
+			}
+		case <-timer.C:
+			break LOOP
+		}
+	}
+
+	c.drainLock.Lock()
+	c.drain = nil
+	close(d.doneCh)
+	c.drainLock.Unlock()
+
+	if d.ready {
+		c.logger.Info("finished draining requests", "reason", d.reason)
+	} else {
+		c.logger.Warn("drain grace period expired, failing held requests", "reason", d.reason)
+	}
+}
+
+// stepDownReadyFunc returns whether a node which was the active node with
+// the given leader UUID can serve requests again after stepping down: either
+// by forwarding them to a new active node, or by having been elected again.
+func (c *Core) stepDownReadyFunc(leaderUUID string) func() bool {
+	return func() bool {
+		isLeader, leaderAddr, _, err := c.Leader()
+		if err != nil {
+			return false
+		}
+		if !isLeader {
+			return leaderAddr != ""
+		}
+
+		c.stateLock.RLock()
+		defer c.stateLock.RUnlock()
+		return c.leaderUUID != leaderUUID
+	}
+}
+
+// sealReadyFunc returns whether a node which is sealing can serve requests
+// again, by having been unsealed. The node is only considered unsealed once
+// it has been seen sealed, since the drain starts before the node is.
+func (c *Core) sealReadyFunc() func() bool {
+	var sealed bool
+	return func() bool {
+		if c.Sealed() {
+			sealed = true
+			return false
+		}
+		return sealed
+	}
+}
@@ -205,25 +205,55 @@ func (d dynamicSystemView) ResponseWrapData(ctx context.Context, data map[string
 }
 
 // LookupPlugin looks for a plugin with the given name in the plugin catalog. It
-// returns a PluginRunner or an error if no plugin was found.
+// returns a PluginRunner or an error if no plugin was found. The plugin of the
+// mount is looked up in the version the mount is pinned to, if any.
 func (d dynamicSystemView) LookupPlugin(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
+	var version string
+	if d.mountEntry != nil && d.mountEntry.pluginName() == name && d.mountEntry.pluginType() == pluginType {
+		version = d.mountEntry.Config.PluginVersion
+	}
+
+	return d.LookupPluginVersion(ctx, name, pluginType, version)
+}
+
+// LookupPluginVersion looks for a plugin with the given name and version in
+// the plugin catalog. It returns a PluginRunner or an error if no plugin was
+// found.
+func (d dynamicSystemView) LookupPluginVersion(ctx context.Context, name string, pluginType consts.PluginType, version string) (*pluginutil.PluginRunner, error) {
 	if d.core == nil {
 		return nil, fmt.Errorf("system view core is nil")
 	}
 	if d.core.pluginCatalog == nil {
 		return nil, fmt.Errorf("system view core plugin catalog is nil")
 	}
-	r, err := d.core.pluginCatalog.Get(ctx, name, pluginType)
+	r, err := d.core.pluginCatalog.Get(ctx, name, pluginType, version)
 	if err != nil {
 		return nil, err
 	}
 	if r == nil {
+		if version != "" {
+			return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s version %s", name, version), ErrPluginNotFound)
+		}
 		return nil, errwrap.Wrapf(fmt.Sprintf("{{err}}: %s", name), ErrPluginNotFound)
 	}
 
 	return r, nil
 }
 
+// NewPluginClient starts a client of the given external plugin. The plugin
+// process is shared with the other mounts using the plugin if it supports
+// multiplexing.
+func (d dynamicSystemView) NewPluginClient(ctx context.Context, runner *pluginutil.PluginRunner, config pluginutil.PluginClientConfig) (pluginutil.PluginClient, error) {
+	if d.core == nil {
+		return nil, fmt.Errorf("system view core is nil")
+	}
+	if d.core.pluginCatalog == nil {
+		return nil, fmt.Errorf("system view core plugin catalog is nil")
+	}
+
+	return d.core.pluginCatalog.newPluginClient(ctx, d, runner, config)
+}
+
 // MlockEnabled returns the configuration setting for enabling mlock on plugins.
 func (d dynamicSystemView) MlockEnabled() bool {
 	return d.core.enableMlock
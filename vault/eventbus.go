@@ -0,0 +1,372 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// Topics of the events published by the core
+	EventTopicKVWrite      = "kv/write"
+	EventTopicKVDelete     = "kv/delete"
+	EventTopicLeaseExpire  = "lease/expire"
+	EventTopicPolicyWrite  = "policy/write"
+	EventTopicPolicyDelete = "policy/delete"
+	EventTopicSealSealed   = "seal/sealed"
+	EventTopicSealUnsealed = "seal/unsealed"
+
+	// DefaultEventBufferSize is how many unacknowledged events are held for
+	// a subscriber unless it asks otherwise
+	DefaultEventBufferSize = 1024
+
+	// MaxEventBufferSize is the most unacknowledged events a subscriber can
+	// ask to be held
+	MaxEventBufferSize = 65536
+
+	// eventSubscriberRetention is how long a disconnected subscriber is kept
+	// for its client to resume it
+	eventSubscriberRetention = 5 * time.Minute
+)
+
+var (
+	// ErrEventBufferFull is the reason subscribers whose client fell too far
+	// behind are closed for
+	ErrEventBufferFull = errors.New("too many unacknowledged events")
+
+	errEventSubscriberAttached = errors.New("subscriber already has a connection")
+)
+
+// Event is a notification of a change within Vault
+type Event struct {
+	ID        uint64                 `json:"id"`
+	Topic     string                 `json:"topic"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+
+	// namespace is the namespace the event happened in, or nil for events
+	// of the node itself
+	namespace *namespace.Namespace
+}
+
+// EventBus delivers the events published on this node to the subscribers of
+// their topic within the namespace of the event or one of its parents. Each
+// subscriber buffers events until its client acknowledges them, so that they
+// are delivered at least once, including over reconnects.
+//
+// Events are per node: they are neither replicated nor forwarded, so only
+// the subscribers of the node where a change happens are notified of it.
+type EventBus struct {
+	l           sync.Mutex
+	logger      log.Logger
+	lastID      uint64
+	subscribers map[string]*EventSubscriber
+}
+
+// NewEventBus returns an event bus without subscribers
+func NewEventBus(logger log.Logger) *EventBus {
+	return &EventBus{
+		logger:      logger,
+		subscribers: make(map[string]*EventSubscriber),
+	}
+}
+
+// Publish delivers an event that happened in the given namespace to the
+// subscribers of the topic in that namespace or one of its parents. Events
+// of the node itself, such as seal status changes, have no namespace and are
+// only delivered to subscribers in the root namespace.
+func (b *EventBus) Publish(topic string, ns *namespace.Namespace, data map[string]interface{}) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.lastID++
+	event := &Event{
+		ID:        b.lastID,
+		Topic:     topic,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+		namespace: ns,
+	}
+
+	now := time.Now()
+	for id, sub := range b.subscribers {
+		if sub.expired(now) {
+			delete(b.subscribers, id)
+			continue
+		}
+		if !EventTopicMatches(sub.Topic, topic) || !sub.visible(event) {
+			continue
+		}
+		if !sub.deliver(event) {
+			b.logger.Warn("closing event subscriber falling behind", "subscriber_id", id, "topic", sub.Topic)
+			delete(b.subscribers, id)
+		}
+	}
+}
+
+// Subscribe creates a subscriber to a topic in a namespace on behalf of the
+// token with the given accessor
+func (b *EventBus) Subscribe(topic, accessor string, ns *namespace.Namespace, bufferSize int) (*EventSubscriber, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	if bufferSize <= 0 {
+		bufferSize = DefaultEventBufferSize
+	}
+
+	sub := &EventSubscriber{
+		ID:         id,
+		Topic:      topic,
+		accessor:   accessor,
+		namespace:  ns,
+		bufferSize: bufferSize,
+		detachedAt: time.Now(),
+		notify:     make(chan struct{}, 1),
+	}
+
+	b.l.Lock()
+	b.subscribers[id] = sub
+	b.l.Unlock()
+
+	return sub, nil
+}
+
+// Subscriber returns the subscriber with the given ID, or nil if there is no
+// such subscriber
+func (b *EventBus) Subscriber(id string) *EventSubscriber {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	sub, ok := b.subscribers[id]
+	if !ok {
+		return nil
+	}
+	if sub.expired(time.Now()) {
+		delete(b.subscribers, id)
+		return nil
+	}
+	return sub
+}
+
+// Resume returns the subscriber with the given ID, or nil if there is no
+// such subscriber or it was created on behalf of another token or for
+// another topic or namespace
+func (b *EventBus) Resume(id, topic, accessor string, ns *namespace.Namespace) *EventSubscriber {
+	sub := b.Subscriber(id)
+	if sub == nil || sub.Topic != topic || sub.accessor != accessor || sub.namespace.ID != ns.ID {
+		return nil
+	}
+	return sub
+}
+
+// Unsubscribe removes the subscriber with the given ID
+func (b *EventBus) Unsubscribe(id string) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if sub, ok := b.subscribers[id]; ok {
+		sub.close(nil)
+		delete(b.subscribers, id)
+	}
+}
+
+// EventTopicMatches returns whether a subscription to the given topic
+// receives events of another; topics ending in '*' match by prefix.
+func EventTopicMatches(subscription, topic string) bool {
+	if strings.HasSuffix(subscription, "*") {
+		return strings.HasPrefix(topic, strings.TrimSuffix(subscription, "*"))
+	}
+	return subscription == topic
+}
+
+// EventSubscriber holds the events of a topic its client did not
+// acknowledge yet. A subscriber is served by at most one connection at a
+// time; events not acknowledged over a connection are sent again over the
+// next one.
+type EventSubscriber struct {
+	ID    string
+	Topic string
+
+	accessor   string
+	namespace  *namespace.Namespace
+	bufferSize int
+
+	l          sync.Mutex
+	pending    []*Event
+	sent       int
+	attached   bool
+	detachedAt time.Time
+	closed     bool
+	err        error
+	notify     chan struct{}
+}
+
+// Attach marks the subscriber as served by a new connection, over which all
+// pending events will be sent again
+func (s *EventSubscriber) Attach() error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.attached {
+		return errEventSubscriberAttached
+	}
+	s.attached = true
+	s.sent = 0
+	s.signal()
+	return nil
+}
+
+// Detach marks the connection of the subscriber as gone. The subscriber is
+// kept for a while for its client to resume it.
+func (s *EventSubscriber) Detach() {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.attached = false
+	s.detachedAt = time.Now()
+}
+
+// Ack acknowledges all the events up to the given ID
+func (s *EventSubscriber) Ack(id uint64) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	n := 0
+	for n < len(s.pending) && s.pending[n].ID <= id {
+		n++
+	}
+	s.pending = s.pending[n:]
+	s.sent -= n
+	if s.sent < 0 {
+		s.sent = 0
+	}
+}
+
+// Next returns the events not yet sent over the current connection, along
+// with whether the subscriber was closed and why. Notify signals when there
+// may be more.
+func (s *EventSubscriber) Next() ([]*Event, bool, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	events := s.pending[s.sent:]
+	s.sent = len(s.pending)
+	return events, s.closed, s.err
+}
+
+// Notify returns a channel signalled when events are delivered to the
+// subscriber or it is closed
+func (s *EventSubscriber) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// visible returns whether the event happened within the namespace of the
+// subscriber
+func (s *EventSubscriber) visible(event *Event) bool {
+	if event.namespace == nil {
+		return s.namespace.ID == namespace.RootNamespaceID
+	}
+	return event.namespace.ID == s.namespace.ID || event.namespace.HasParent(s.namespace)
+}
+
+// deliver queues an event for the subscriber, closing it and returning false
+// if its buffer is full
+func (s *EventSubscriber) deliver(event *Event) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.closed {
+		return false
+	}
+	if len(s.pending) >= s.bufferSize {
+		s.closed = true
+		s.err = ErrEventBufferFull
+		s.signal()
+		return false
+	}
+
+	s.pending = append(s.pending, event)
+	s.signal()
+	return true
+}
+
+func (s *EventSubscriber) close(err error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.closed = true
+	s.err = err
+	s.signal()
+}
+
+// expired returns whether the subscriber was left without a connection for
+// too long
+func (s *EventSubscriber) expired(now time.Time) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	return !s.attached && now.Sub(s.detachedAt) > eventSubscriberRetention
+}
+
+// signal must be called with the lock held
+func (s *EventSubscriber) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Events returns the event bus of the core
+func (c *Core) Events() *EventBus {
+	return c.events
+}
+
+// publishRequestEvent publishes the event corresponding to a successful
+// request, if any
+func (c *Core) publishRequestEvent(ctx context.Context, entry *MountEntry, req *logical.Request) {
+	if entry == nil || entry.Type != "kv" {
+		return
+	}
+
+	var topic string
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation:
+		topic = EventTopicKVWrite
+	case logical.DeleteOperation:
+		topic = EventTopicKVDelete
+	default:
+		return
+	}
+
+	data := map[string]interface{}{
+		"mount":     entry.Path,
+		"path":      strings.TrimPrefix(req.Path, entry.Path),
+		"operation": string(req.Operation),
+	}
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return
+	}
+	data["namespace"] = ns.Path
+	c.events.Publish(topic, ns, data)
+}
+
+// publishSealEvent publishes the seal status of the core
+func (c *Core) publishSealEvent(sealed bool) {
+	topic := EventTopicSealUnsealed
+	if sealed {
+		topic = EventTopicSealSealed
+	}
+	c.events.Publish(topic, nil, map[string]interface{}{
+		"sealed":       sealed,
+		"cluster_addr": c.ClusterAddr(),
+	})
+}
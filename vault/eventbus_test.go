@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+)
+
+func TestEventTopicMatches(t *testing.T) {
+	cases := []struct {
+		subscription string
+		topic        string
+		expected     bool
+	}{
+		{"kv/write", "kv/write", true},
+		{"kv/write", "kv/delete", false},
+		{"kv/*", "kv/write", true},
+		{"kv/*", "policy/write", false},
+		{"*", "seal/sealed", true},
+	}
+
+	for _, c := range cases {
+		if actual := EventTopicMatches(c.subscription, c.topic); actual != c.expected {
+			t.Fatalf("%q matching %q: expected %t, got %t", c.subscription, c.topic, c.expected, actual)
+		}
+	}
+}
+
+func TestEventBus_AckResume(t *testing.T) {
+	bus := NewEventBus(log.NewNullLogger())
+
+	sub, err := bus.Subscribe("kv/*", "accessor", namespace.RootNamespace, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Attach(); err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish(EventTopicKVWrite, namespace.RootNamespace, map[string]interface{}{"path": "foo"})
+	bus.Publish(EventTopicPolicyWrite, namespace.RootNamespace, map[string]interface{}{"name": "foo"})
+	bus.Publish(EventTopicKVDelete, namespace.RootNamespace, map[string]interface{}{"path": "foo"})
+
+	events, closed, err := sub.Next()
+	if closed || err != nil {
+		t.Fatalf("unexpected close: %v", err)
+	}
+	if len(events) != 2 || events[0].Topic != EventTopicKVWrite || events[1].Topic != EventTopicKVDelete {
+		t.Fatalf("bad events: %#v", events)
+	}
+	if events, _, _ := sub.Next(); len(events) != 0 {
+		t.Fatalf("expected no events, got %#v", events)
+	}
+
+	// Only one connection may serve a subscriber at a time
+	if err := sub.Attach(); err == nil {
+		t.Fatal("expected error attaching twice")
+	}
+
+	// Events not acknowledged are sent again over the next connection
+	sub.Ack(events[0].ID)
+	sub.Detach()
+
+	if bus.Resume(sub.ID, "kv/*", "other", namespace.RootNamespace) != nil {
+		t.Fatal("expected no subscriber for another token")
+	}
+	if bus.Resume(sub.ID, "kv/write", "accessor", namespace.RootNamespace) != nil {
+		t.Fatal("expected no subscriber for another topic")
+	}
+	resumed := bus.Resume(sub.ID, "kv/*", "accessor", namespace.RootNamespace)
+	if resumed != sub {
+		t.Fatal("expected subscriber to be resumed")
+	}
+	if err := resumed.Attach(); err != nil {
+		t.Fatal(err)
+	}
+	resent, _, _ := resumed.Next()
+	if len(resent) != 1 || resent[0].ID != events[1].ID {
+		t.Fatalf("bad resent events: %#v", resent)
+	}
+
+	bus.Unsubscribe(sub.ID)
+	if bus.Subscriber(sub.ID) != nil {
+		t.Fatal("expected subscriber to be removed")
+	}
+	if _, closed, _ := sub.Next(); !closed {
+		t.Fatal("expected subscriber to be closed")
+	}
+}
+
+func TestEventBus_BufferFull(t *testing.T) {
+	bus := NewEventBus(log.NewNullLogger())
+
+	sub, err := bus.Subscribe(EventTopicKVWrite, "accessor", namespace.RootNamespace, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(EventTopicKVWrite, namespace.RootNamespace, nil)
+	}
+
+	events, closed, err := sub.Next()
+	if !closed || err != ErrEventBufferFull {
+		t.Fatalf("expected subscriber to be closed with a full buffer, got %t, %v", closed, err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the buffered events, got %#v", events)
+	}
+	if bus.Subscriber(sub.ID) != nil {
+		t.Fatal("expected subscriber to be removed")
+	}
+}
+
+func TestEventBus_Namespaces(t *testing.T) {
+	bus := NewEventBus(log.NewNullLogger())
+
+	ns1 := &namespace.Namespace{ID: "ns1", Path: "ns1/"}
+	ns1Child := &namespace.Namespace{ID: "ns1child", Path: "ns1/child/"}
+	ns2 := &namespace.Namespace{ID: "ns2", Path: "ns2/"}
+
+	root, err := bus.Subscribe("*", "accessor", namespace.RootNamespace, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := bus.Subscribe("*", "accessor", ns1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish(EventTopicKVWrite, ns1, nil)
+	bus.Publish(EventTopicKVWrite, ns1Child, nil)
+	bus.Publish(EventTopicKVWrite, ns2, nil)
+	bus.Publish(EventTopicKVWrite, namespace.RootNamespace, nil)
+	bus.Publish(EventTopicSealSealed, nil, nil)
+
+	// Subscribers receive the events of their namespace and its children,
+	// and only those in the root namespace receive events of the node
+	if events, _, _ := root.Next(); len(events) != 5 {
+		t.Fatalf("expected all events in the root namespace, got %#v", events)
+	}
+	events, _, _ := sub.Next()
+	if len(events) != 2 || events[0].namespace != ns1 || events[1].namespace != ns1Child {
+		t.Fatalf("bad events: %#v", events)
+	}
+
+	if bus.Resume(sub.ID, "*", "accessor", ns2) != nil {
+		t.Fatal("expected no subscriber for another namespace")
+	}
+	if bus.Resume(sub.ID, "*", "accessor", ns1) != sub {
+		t.Fatal("expected subscriber to be resumed")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,6 +18,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/fairshare"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/base62"
@@ -37,6 +40,9 @@ const (
 	// tokenViewPrefix is the prefix used for the token based lookup of leases.
 	tokenViewPrefix = "token/"
 
+	// tagViewPrefix is the prefix used for the tag based lookup of leases.
+	tagViewPrefix = "tag/"
+
 	// maxRevokeAttempts limits how many revoke attempts are made
 	maxRevokeAttempts = 6
 
@@ -51,12 +57,25 @@ const (
 
 	// maxLeaseThreshold is the maximum lease count before generating log warning
 	maxLeaseThreshold = 256000
+
+	// defaultRevocationWorkers is the default number of leases revoked at the
+	// same time on expiry
+	defaultRevocationWorkers = 200
+
+	// defaultRevocationMountConcurrency is the default number of leases of a
+	// mount revoked at the same time on expiry
+	defaultRevocationMountConcurrency = 50
 )
 
 type pendingInfo struct {
 	// A subset of the lease entry, cached in memory
 	cachedLeaseInfo *leaseEntry
 	timer           *time.Timer
+
+	// The mount, if any, that the lease counts against for lease
+	// count quotas
+	quotaNamespace string
+	quotaMount     string
 }
 
 // ExpirationManager is used by the Core to manage leases. Secrets
@@ -68,6 +87,7 @@ type ExpirationManager struct {
 	router     *Router
 	idView     *BarrierView
 	tokenView  *BarrierView
+	tagView    *BarrierView
 	tokenStore *TokenStore
 	logger     log.Logger
 
@@ -104,6 +124,10 @@ type ExpirationManager struct {
 	logLeaseExpirations bool
 	expireFunc          ExpireLeaseStrategy
 
+	// revocationJobs runs the revocations of expired leases, taking turns at
+	// the mounts of the leases
+	revocationJobs *fairshare.JobManager
+
 	// testRegisterAuthFailure, if set to true, triggers an explicit failure on
 	// RegisterAuth to simulate a partial failure during a token creation
 	// request. This value should only be set by tests.
@@ -144,6 +168,13 @@ func expireLeaseStrategyRevoke(ctx context.Context, m *ExpirationManager, le *le
 		m.coreStateLock.RUnlock()
 		cancel()
 		if err == nil {
+			if m.core != nil && m.core.events != nil {
+				m.core.events.Publish(EventTopicLeaseExpire, le.namespace, map[string]interface{}{
+					"lease_id":  le.LeaseID,
+					"path":      le.Path,
+					"namespace": le.namespace.Path,
+				})
+			}
 			return
 		}
 
@@ -161,6 +192,7 @@ func NewExpirationManager(c *Core, view *BarrierView, e ExpireLeaseStrategy, log
 		router:      c.router,
 		idView:      view.SubView(leaseViewPrefix),
 		tokenView:   view.SubView(tokenViewPrefix),
+		tagView:     view.SubView(tagViewPrefix),
 		tokenStore:  c.tokenStore,
 		logger:      logger,
 		pending:     sync.Map{},
@@ -191,11 +223,33 @@ func NewExpirationManager(c *Core, view *BarrierView, e ExpireLeaseStrategy, log
 		exp.logger = log.New(&opts)
 	}
 
+	exp.revocationJobs = fairshare.NewJobManager(
+		exp.envInt("VAULT_LEASE_REVOCATION_WORKERS", defaultRevocationWorkers),
+		exp.envInt("VAULT_LEASE_REVOCATION_MOUNT_CONCURRENCY", defaultRevocationMountConcurrency),
+	)
+	exp.revocationJobs.Start()
+
 	go exp.uniquePoliciesGc()
 
 	return exp
 }
 
+// envInt returns the positive integer value of the given environment
+// variable, or the default value if it is unset or invalid
+func (m *ExpirationManager) envInt(name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		m.logger.Warn("ignoring invalid value of environment variable", "name", name, "value", raw)
+		return def
+	}
+	return value
+}
+
 // setupExpiration is invoked after we've loaded the mount table to
 // initialize the expiration manager
 func (c *Core) setupExpiration(e ExpireLeaseStrategy) error {
@@ -266,7 +320,7 @@ func (m *ExpirationManager) invalidate(key string) {
 			pending := info.(pendingInfo)
 			pending.timer.Stop()
 			m.pending.Delete(leaseID)
-			m.leaseCount--
+			m.uncountLease(pending)
 		}
 
 		// If in the nonexpiring map, remove there.
@@ -581,6 +635,10 @@ func (m *ExpirationManager) Stop() error {
 	// expiring timers
 	close(m.quitCh)
 
+	// Revocations in flight are canceled through quitCh, and pending ones are
+	// picked up again from storage by the next restore
+	m.revocationJobs.Stop()
+
 	m.pendingLock.Lock()
 	// Replacing the entire map would cause a race with
 	// a simultaneous WalkTokens, which doesn't hold pendingLock.
@@ -588,7 +646,7 @@ func (m *ExpirationManager) Stop() error {
 		info := value.(pendingInfo)
 		info.timer.Stop()
 		m.pending.Delete(key)
-		m.leaseCount--
+		m.uncountLease(info)
 		return true
 	})
 	m.nonexpiring.Range(func(key, value interface{}) bool {
@@ -690,6 +748,9 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 		if err := m.removeIndexByToken(ctx, le); err != nil {
 			return err
 		}
+		if err := m.removeIndexByTags(ctx, le); err != nil {
+			return err
+		}
 	}
 
 	// Clear the expiration handler (or remove from the list of non-expiring tokens.)
@@ -698,7 +759,7 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 		pending := info.(pendingInfo)
 		pending.timer.Stop()
 		m.pending.Delete(leaseID)
-		m.leaseCount--
+		m.uncountLease(pending)
 	}
 	m.nonexpiring.Delete(leaseID)
 	m.pendingLock.Unlock()
@@ -835,18 +896,18 @@ func (m *ExpirationManager) revokePrefixCommon(ctx context.Context, prefix strin
 		return errwrap.Wrapf("failed to scan for leases: {{err}}", err)
 	}
 
+	leaseIDs := make([]string, len(existing))
+	for i, suffix := range existing {
+		leaseIDs[i] = prefix + suffix
+	}
+
 	// Revoke all the keys
-	for idx, suffix := range existing {
-		leaseID := prefix + suffix
-		switch {
-		case sync:
-			if err := m.revokeCommon(ctx, leaseID, force, false); err != nil {
-				return errwrap.Wrapf(fmt.Sprintf("failed to revoke %q (%d / %d): {{err}}", leaseID, idx+1, len(existing)), err)
-			}
-		default:
-			if err := m.LazyRevoke(ctx, leaseID); err != nil {
-				return errwrap.Wrapf(fmt.Sprintf("failed to revoke %q (%d / %d): {{err}}", leaseID, idx+1, len(existing)), err)
-			}
+	if sync {
+		return m.revokeWithJobs(ctx, leaseIDs, force)
+	}
+	for idx, leaseID := range leaseIDs {
+		if err := m.LazyRevoke(ctx, leaseID); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to revoke %q (%d / %d): {{err}}", leaseID, idx+1, len(leaseIDs)), err)
 		}
 	}
 
@@ -919,6 +980,12 @@ func (m *ExpirationManager) Renew(ctx context.Context, leaseID string, increment
 	// Attach the LeaseID
 	resp.Secret.LeaseID = leaseID
 
+	// Keep the tags of the lease, which are indexed when it is registered
+	// and are not returned by the backend
+	if le.Secret != nil {
+		resp.Secret.Tags = le.Secret.Tags
+	}
+
 	// Update the lease entry
 	le.Data = resp.Data
 	le.Secret = resp.Secret
@@ -1148,6 +1215,10 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 			if err := m.removeIndexByToken(ctx, le); err != nil {
 				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease indexes associated with the newly-generated secret: {{err}}", err))
 			}
+
+			if err := m.removeIndexByTags(ctx, le); err != nil {
+				retErr = multierror.Append(retErr, errwrap.Wrapf("an additional error was encountered removing lease tag indexes associated with the newly-generated secret: {{err}}", err))
+			}
 		}
 	}()
 
@@ -1182,6 +1253,11 @@ func (m *ExpirationManager) Register(ctx context.Context, req *logical.Request,
 		}
 	}
 
+	// Maintain secondary index by tag
+	if err := m.createIndexByTags(ctx, le); err != nil {
+		return "", err
+	}
+
 	// Setup revocation timer if there is a lease
 	m.updatePending(le, resp.Secret.LeaseTotal())
 
@@ -1338,6 +1414,7 @@ func (m *ExpirationManager) leaseTimesForExport(le *leaseEntry) *leaseEntry {
 		ret.Secret = &logical.Secret{}
 		ret.Secret.Renewable = le.Secret.Renewable
 		ret.Secret.TTL = le.Secret.TTL
+		ret.Secret.Tags = le.Secret.Tags
 	}
 	if le.Auth != nil {
 		ret.Auth = &logical.Auth{}
@@ -1419,7 +1496,7 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry, leaseTotal tim
 		if ok {
 			info.(pendingInfo).timer.Stop()
 			m.pending.Delete(le.LeaseID)
-			m.leaseCount--
+			m.uncountLease(info.(pendingInfo))
 		}
 		return
 	}
@@ -1432,13 +1509,13 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry, leaseTotal tim
 	} else {
 		// Extend the timer by the lease total
 		timer := time.AfterFunc(leaseTotal, func() {
-			m.expireFunc(m.quitContext, m, le)
+			m.queueRevocation(le)
 		})
 		pending = pendingInfo{
 			timer: timer,
 		}
 		// new lease
-		m.leaseCount++
+		m.countLease(&pending, le)
 	}
 
 	// Retain some information in-memory
@@ -1447,6 +1524,113 @@ func (m *ExpirationManager) updatePendingInternal(le *leaseEntry, leaseTotal tim
 	m.pending.Store(le.LeaseID, pending)
 }
 
+// queueRevocation adds the revocation of an expired lease to the queue of its
+// mount, so that a mount with many leases expiring at once does not take all
+// the revocation workers from the other mounts.
+func (m *ExpirationManager) queueRevocation(le *leaseEntry) {
+	mount := m.revocationQueueID(le.namespace, le.Path)
+
+	labels := []metrics.Label{{"mount", mount}}
+	queued := time.Now()
+	m.revocationJobs.AddJob(mount, func() {
+		m.core.metricSink.MeasureSinceWithLabels([]string{"expire", "revocation", "wait_time"}, queued, labels)
+
+		start := time.Now()
+		m.expireFunc(m.quitContext, m, le)
+		m.core.metricSink.MeasureSinceWithLabels([]string{"expire", "revocation", "duration"}, start, labels)
+		m.core.metricSink.IncrCounterWithLabels([]string{"expire", "revocation", "processed"}, 1, labels)
+	})
+}
+
+// revocationQueueID returns the mount of a lease in the namespace, whose
+// revocations share a queue of the revocation workers.
+func (m *ExpirationManager) revocationQueueID(ns *namespace.Namespace, leasePath string) string {
+	if ns == nil {
+		ns = namespace.RootNamespace
+	}
+	mount := m.router.MatchingMount(namespace.ContextWithNamespace(context.Background(), ns), leasePath)
+	if mount == "" {
+		mount = ns.Path
+	}
+	return mount
+}
+
+// revokeWithJobs revokes the leases of the namespace of the context with the
+// revocation workers, so that revoking many leases at once takes turns with
+// the revocations of the other mounts, and waits for them to finish.
+func (m *ExpirationManager) revokeWithJobs(ctx context.Context, leaseIDs []string, force bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var retErr *multierror.Error
+	for idx, leaseID := range leaseIDs {
+		idx, leaseID := idx, leaseID
+		wg.Add(1)
+		m.revocationJobs.AddJob(m.revocationQueueID(ns, leaseID), func() {
+			defer wg.Done()
+			if err := m.revokeCommon(ctx, leaseID, force, false); err != nil {
+				errLock.Lock()
+				retErr = multierror.Append(retErr, errwrap.Wrapf(fmt.Sprintf("failed to revoke %q (%d / %d): {{err}}", leaseID, idx+1, len(leaseIDs)), err))
+				errLock.Unlock()
+			}
+		})
+	}
+
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	// Pending jobs are dropped when the expiration manager stops
+	select {
+	case <-doneCh:
+		return retErr.ErrorOrNil()
+	case <-m.quitCh:
+		return errors.New("expiration manager stopped before the leases were revoked")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countLease records a new pending lease in the lease count, and
+// against its auth mount for lease count quotas. Only leases for
+// logins count towards quotas. This method is called with pendingLock
+// held.
+func (m *ExpirationManager) countLease(pending *pendingInfo, le *leaseEntry) {
+	m.leaseCount++
+
+	if le.Auth == nil {
+		return
+	}
+
+	ns := le.namespace
+	if ns == nil {
+		ns = namespace.RootNamespace
+	}
+	mount := m.router.MatchingMount(namespace.ContextWithNamespace(context.Background(), ns), le.Path)
+	if mount == "" {
+		return
+	}
+	pending.quotaNamespace = ns.Path
+	pending.quotaMount = strings.TrimPrefix(mount, ns.Path)
+	m.core.quotaManager.LeaseCreated(pending.quotaNamespace, pending.quotaMount)
+}
+
+// uncountLease reverses countLease for a lease that is no longer
+// pending. This method is called with pendingLock held.
+func (m *ExpirationManager) uncountLease(pending pendingInfo) {
+	m.leaseCount--
+
+	if pending.quotaMount != "" {
+		m.core.quotaManager.LeaseDeleted(pending.quotaNamespace, pending.quotaMount)
+	}
+}
+
 // revokeEntry is used to attempt revocation of an internal entry
 func (m *ExpirationManager) revokeEntry(ctx context.Context, le *leaseEntry) error {
 	// Revocation of login tokens is special since we can by-pass the
@@ -1877,6 +2061,164 @@ func (m *ExpirationManager) lookupLeasesByToken(ctx context.Context, te *logical
 	return leaseIDs, nil
 }
 
+// tagIndexKeyPrefix returns the prefix of the keys of the secondary index
+// from a tag to the leases of the namespace carrying it
+func (m *ExpirationManager) tagIndexKeyPrefix(ctx context.Context, ns *namespace.Namespace, key, value string) (string, error) {
+	saltCtx := namespace.ContextWithNamespace(ctx, ns)
+	saltedTag, err := m.tokenStore.SaltID(saltCtx, key+"="+value)
+	if err != nil {
+		return "", err
+	}
+	return saltedTag + "/", nil
+}
+
+// createIndexByTags creates a secondary index from each tag of the secret to
+// the lease entry
+func (m *ExpirationManager) createIndexByTags(ctx context.Context, le *leaseEntry) error {
+	if le.Secret == nil || len(le.Secret.Tags) == 0 {
+		return nil
+	}
+
+	saltCtx := namespace.ContextWithNamespace(ctx, le.namespace)
+	leaseSaltedID, err := m.tokenStore.SaltID(saltCtx, le.LeaseID)
+	if err != nil {
+		return err
+	}
+
+	tagView := m.tagIndexView(le.namespace)
+	for key, value := range le.Secret.Tags {
+		prefix, err := m.tagIndexKeyPrefix(ctx, le.namespace, key, value)
+		if err != nil {
+			return err
+		}
+
+		ent := logical.StorageEntry{
+			Key:   prefix + leaseSaltedID,
+			Value: []byte(le.LeaseID),
+		}
+		if err := tagView.Put(ctx, &ent); err != nil {
+			return errwrap.Wrapf("failed to persist lease tag index entry: {{err}}", err)
+		}
+	}
+	return nil
+}
+
+// removeIndexByTags removes the secondary index from each tag of the secret
+// to the lease entry
+func (m *ExpirationManager) removeIndexByTags(ctx context.Context, le *leaseEntry) error {
+	if le.Secret == nil || len(le.Secret.Tags) == 0 {
+		return nil
+	}
+
+	saltCtx := namespace.ContextWithNamespace(ctx, le.namespace)
+	leaseSaltedID, err := m.tokenStore.SaltID(saltCtx, le.LeaseID)
+	if err != nil {
+		return err
+	}
+
+	tagView := m.tagIndexView(le.namespace)
+	for key, value := range le.Secret.Tags {
+		prefix, err := m.tagIndexKeyPrefix(ctx, le.namespace, key, value)
+		if err != nil {
+			return err
+		}
+		if err := tagView.Delete(ctx, prefix+leaseSaltedID); err != nil {
+			return errwrap.Wrapf("failed to delete lease tag index entry: {{err}}", err)
+		}
+	}
+	return nil
+}
+
+// LookupLeasesByTags returns the IDs of the leases of the namespace carrying
+// all the given tags, sorted
+func (m *ExpirationManager) LookupLeasesByTags(ctx context.Context, tags map[string]string) ([]string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("no tags given")
+	}
+
+	tagView := m.tagIndexView(ns)
+
+	// Intersect the leases indexed by each tag, keyed by their salted ID
+	var matches map[string]string
+	for key, value := range tags {
+		prefix, err := m.tagIndexKeyPrefix(ctx, ns, key, value)
+		if err != nil {
+			return nil, err
+		}
+
+		subKeys, err := tagView.List(ctx, prefix)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to list leases by tag: {{err}}", err)
+		}
+
+		tagMatches := make(map[string]string, len(subKeys))
+		for _, sub := range subKeys {
+			if matches != nil {
+				leaseID, ok := matches[sub]
+				if ok {
+					tagMatches[sub] = leaseID
+				}
+				continue
+			}
+
+			out, err := tagView.Get(ctx, prefix+sub)
+			if err != nil {
+				return nil, errwrap.Wrapf("failed to read lease tag index: {{err}}", err)
+			}
+			if out == nil {
+				continue
+			}
+			tagMatches[sub] = string(out.Value)
+		}
+		matches = tagMatches
+
+		if len(matches) == 0 {
+			break
+		}
+	}
+
+	leaseIDs := make([]string, 0, len(matches))
+	for _, leaseID := range matches {
+		leaseIDs = append(leaseIDs, leaseID)
+	}
+	sort.Strings(leaseIDs)
+	return leaseIDs, nil
+}
+
+// RevokeByTags revokes all the leases of the namespace carrying all the given
+// tags, and returns their IDs
+func (m *ExpirationManager) RevokeByTags(ctx context.Context, tags map[string]string, sync bool) ([]string, error) {
+	defer metrics.MeasureSince([]string{"expire", "revoke-by-tags"}, time.Now())
+
+	if m.inRestoreMode() {
+		m.restoreRequestLock.Lock()
+		defer m.restoreRequestLock.Unlock()
+	}
+
+	leaseIDs, err := m.LookupLeasesByTags(ctx, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if sync {
+		if err := m.revokeWithJobs(ctx, leaseIDs, false); err != nil {
+			return nil, err
+		}
+		return leaseIDs, nil
+	}
+	for idx, leaseID := range leaseIDs {
+		if err := m.LazyRevoke(ctx, leaseID); err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("failed to revoke %q (%d / %d): {{err}}", leaseID, idx+1, len(leaseIDs)), err)
+		}
+	}
+
+	return leaseIDs, nil
+}
+
 // emitMetrics is invoked periodically to emit statistics
 func (m *ExpirationManager) emitMetrics() {
 	// All updates of this value are with the pendingLock held.
@@ -1885,6 +2227,13 @@ func (m *ExpirationManager) emitMetrics() {
 	m.pendingLock.RUnlock()
 
 	metrics.SetGauge([]string{"expire", "num_leases"}, float32(num))
+
+	// Report the progress of the revocations of expired leases of each mount
+	for mount, stats := range m.revocationJobs.Stats() {
+		labels := []metrics.Label{{"mount", mount}}
+		m.core.metricSink.SetGaugeWithLabels([]string{"expire", "revocation", "pending"}, float32(stats.Pending), labels)
+		m.core.metricSink.SetGaugeWithLabels([]string{"expire", "revocation", "in_flight"}, float32(stats.InFlight), labels)
+	}
 	// Check if lease count is greater than the threshold
 	if num > maxLeaseThreshold {
 		if atomic.LoadUint32(m.leaseCheckCounter) > 59 {
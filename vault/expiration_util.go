@@ -3,27 +3,44 @@
 package vault
 
 import (
+	"fmt"
+
 	"github.com/hashicorp/errwrap"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
-func (m *ExpirationManager) leaseView(*namespace.Namespace) *BarrierView {
-	return m.idView
+func (m *ExpirationManager) leaseView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return m.idView
+	}
+	return m.core.namespaceSystemView(ns).SubView(expirationSubPath).SubView(leaseViewPrefix)
+}
+
+func (m *ExpirationManager) tokenIndexView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return m.tokenView
+	}
+	return m.core.namespaceSystemView(ns).SubView(expirationSubPath).SubView(tokenViewPrefix)
 }
 
-func (m *ExpirationManager) tokenIndexView(*namespace.Namespace) *BarrierView {
-	return m.tokenView
+func (m *ExpirationManager) tagIndexView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return m.tagView
+	}
+	return m.core.namespaceSystemView(ns).SubView(expirationSubPath).SubView(tagViewPrefix)
 }
 
 func (m *ExpirationManager) collectLeases() (map[*namespace.Namespace][]string, int, error) {
 	leaseCount := 0
 	existing := make(map[*namespace.Namespace][]string)
-	keys, err := logical.CollectKeys(m.quitContext, m.leaseView(namespace.RootNamespace))
-	if err != nil {
-		return nil, 0, errwrap.Wrapf("failed to scan for leases: {{err}}", err)
+	for _, ns := range m.core.collectNamespaces() {
+		keys, err := logical.CollectKeys(m.quitContext, m.leaseView(ns))
+		if err != nil {
+			return nil, 0, errwrap.Wrapf(fmt.Sprintf("failed to scan for leases in namespace %q: {{err}}", ns.Path), err)
+		}
+		existing[ns] = keys
+		leaseCount += len(keys)
 	}
-	existing[namespace.RootNamespace] = keys
-	leaseCount += len(keys)
 	return existing, leaseCount, nil
 }
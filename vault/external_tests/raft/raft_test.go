@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -279,6 +282,187 @@ func TestRaft_Configuration(t *testing.T) {
 	}
 }
 
+func TestRaft_Autopilot(t *testing.T) {
+	cluster := raftCluster(t)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	// The joined servers are promoted to voters once they are stable
+	var state map[string]interface{}
+	deadline := time.Now().Add(60 * time.Second)
+	for {
+		secret, err := client.Logical().Read("sys/storage/raft/autopilot/state")
+		if err != nil {
+			t.Fatal(err)
+		}
+		state = secret.Data
+		if len(state["voters"].([]interface{})) == 3 && state["healthy"].(bool) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("servers were not promoted to voters: %#v", state)
+		}
+		time.Sleep(time.Second)
+	}
+
+	if state["leader"] != "core-0" {
+		t.Fatalf("bad: leader: %#v", state["leader"])
+	}
+	if state["failure_tolerance"] != json.Number("1") {
+		t.Fatalf("bad: failure_tolerance: %#v", state["failure_tolerance"])
+	}
+	servers := state["servers"].(map[string]interface{})
+	for id, expected := range map[string]string{"core-0": "leader", "core-1": "voter", "core-2": "voter"} {
+		server := servers[id].(map[string]interface{})
+		if server["status"] != expected || server["node_status"] != "alive" || !server["healthy"].(bool) {
+			t.Fatalf("bad: server %s: %#v", id, server)
+		}
+	}
+
+	// Configuration
+	secret, err := client.Logical().Read("sys/storage/raft/autopilot/configuration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["cleanup_dead_servers"].(bool) || secret.Data["server_stabilization_time"] != "10s" {
+		t.Fatalf("bad: default configuration: %#v", secret.Data)
+	}
+
+	_, err = client.Logical().Write("sys/storage/raft/autopilot/configuration", map[string]interface{}{
+		"cleanup_dead_servers": true,
+		"min_quorum":           1,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a min_quorum below 3")
+	}
+
+	_, err = client.Logical().Write("sys/storage/raft/autopilot/configuration", map[string]interface{}{
+		"cleanup_dead_servers":               true,
+		"dead_server_last_contact_threshold": "1h",
+		"server_stabilization_time":          "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Logical().Read("sys/storage/raft/autopilot/configuration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !secret.Data["cleanup_dead_servers"].(bool) ||
+		secret.Data["dead_server_last_contact_threshold"] != "1h0m0s" ||
+		secret.Data["server_stabilization_time"] != "30s" ||
+		secret.Data["min_quorum"] != json.Number("3") {
+		t.Fatalf("bad: configuration: %#v", secret.Data)
+	}
+}
+
+func TestRaft_AutoSnapshot(t *testing.T) {
+	cluster := raftCluster(t)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	dir, err := ioutil.TempDir("", "raft-autosnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/hourly", map[string]interface{}{
+		"interval":     "1s",
+		"retain":       2,
+		"storage_type": "nfs",
+		"path_prefix":  dir,
+	})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported storage type")
+	}
+
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/hourly", map[string]interface{}{
+		"interval":     "1s",
+		"retain":       2,
+		"storage_type": "local",
+		"path_prefix":  dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := client.Logical().List("sys/storage/raft/snapshot-auto/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := secret.Data["keys"].([]interface{}); len(keys) != 1 || keys[0] != "hourly" {
+		t.Fatalf("bad: keys: %#v", secret.Data["keys"])
+	}
+
+	secret, err = client.Logical().Read("sys/storage/raft/snapshot-auto/config/hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Data["interval"] != json.Number("1") || secret.Data["file_prefix"] != "vault-snapshot" || secret.Data["path_prefix"] != dir {
+		t.Fatalf("bad: config: %#v", secret.Data)
+	}
+
+	// Snapshots are taken until only the last two are retained
+	var names []string
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		secret, err = client.Logical().Read("sys/storage/raft/snapshot-auto/status/hourly")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret.Data["last_error"] != "" {
+			t.Fatalf("bad: status: %#v", secret.Data)
+		}
+
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = names[:0]
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		if len(names) == 2 && secret.Data["last_snapshot_name"] != names[0] && secret.Data["last_snapshot_name"] != "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshots were not taken and retained: %v, status: %#v", names, secret.Data)
+		}
+		time.Sleep(time.Second)
+	}
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "vault-snapshot-") || !strings.HasSuffix(name, ".snap") {
+			t.Fatalf("bad: snapshot name: %q", name)
+		}
+	}
+
+	// The stored snapshots can be restored
+	snap, err := ioutil.ReadFile(filepath.Join(dir, names[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Sys().RaftSnapshotRestore(bytes.NewReader(snap), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Logical().Delete("sys/storage/raft/snapshot-auto/config/hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err = client.Logical().Read("sys/storage/raft/snapshot-auto/status/hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected no status once deleted: %#v", secret.Data)
+	}
+}
+
 func TestRaft_ShamirUnseal(t *testing.T) {
 	cluster := raftCluster(t)
 	defer cluster.Cleanup()
@@ -136,6 +136,7 @@ func migrateFromShamirToTransit_Pre14(
 	var conf = vault.CoreConfig{
 		Logger:                    logger.Named("migrateFromShamirToTransit"),
 		DisablePerformanceStandby: true,
+		DisableAutopilot:          true,
 	}
 	var opts = vault.TestClusterOptions{
 		HandlerFunc:           vaulthttp.Handler,
@@ -554,6 +555,7 @@ func initializeShamir(
 	var conf = vault.CoreConfig{
 		Logger:                    logger.Named("initializeShamir"),
 		DisablePerformanceStandby: true,
+		DisableAutopilot:          true,
 	}
 	var opts = vault.TestClusterOptions{
 		HandlerFunc:           vaulthttp.Handler,
@@ -602,6 +604,7 @@ func runShamir(
 	var conf = vault.CoreConfig{
 		Logger:                    logger.Named("runShamir"),
 		DisablePerformanceStandby: true,
+		DisableAutopilot:          true,
 	}
 	var opts = vault.TestClusterOptions{
 		HandlerFunc:           vaulthttp.Handler,
@@ -666,6 +669,7 @@ func initializeTransit(
 	var conf = vault.CoreConfig{
 		Logger:                    logger.Named("initializeTransit"),
 		DisablePerformanceStandby: true,
+		DisableAutopilot:          true,
 	}
 	var opts = vault.TestClusterOptions{
 		HandlerFunc:           vaulthttp.Handler,
@@ -716,6 +720,7 @@ func runTransit(
 	var conf = vault.CoreConfig{
 		Logger:                    logger.Named("runTransit"),
 		DisablePerformanceStandby: true,
+		DisableAutopilot:          true,
 		Seal:                      transitSeal,
 	}
 	var opts = vault.TestClusterOptions{
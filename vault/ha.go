@@ -315,6 +315,9 @@ func (c *Core) StepDown(httpCtx context.Context, req *logical.Request) (retErr e
 		}
 	}
 
+	// Hold new requests until they can be forwarded to the new active node
+	c.beginDrain(DrainReasonStepDown, c.stepDownReadyFunc(c.leaderUUID))
+
 	select {
 	case c.manualStepDownCh <- struct{}{}:
 	default:
@@ -755,6 +758,10 @@ func (c *Core) periodicCheckKeyUpgrades(ctx context.Context, stopCh chan struct{
 					c.logger.Error("raft tls periodic upgrade check failed", "error", err)
 				}
 
+				if err := c.checkSealMigration(ctx); err != nil {
+					c.logger.Error("seal migration periodic check failed", "error", err)
+				}
+
 				atomic.AddInt32(lopCount, -1)
 				return
 			}()
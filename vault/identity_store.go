@@ -19,6 +19,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/patrickmn/go-cache"
 )
 
 const (
@@ -83,6 +84,8 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 		PathsSpecial: &logical.Paths{
 			Unauthenticated: []string{
 				"oidc/.well-known/*",
+				"oidc/provider/device",
+				"oidc/provider/token",
 			},
 		},
 		PeriodicFunc: func(ctx context.Context, req *logical.Request) error {
@@ -93,6 +96,8 @@ func NewIdentityStore(ctx context.Context, core *Core, config *logical.BackendCo
 	}
 
 	iStore.oidcCache = newOIDCCache()
+	iStore.oidcGrants = cache.New(cache.NoExpiration, time.Minute)
+	iStore.mfaUsedCodes = cache.New(0, 30*time.Second)
 
 	err = iStore.Setup(ctx, config)
 	if err != nil {
@@ -111,6 +116,8 @@ func (i *IdentityStore) paths() []*framework.Path {
 		lookupPaths(i),
 		upgradePaths(i),
 		oidcPaths(i),
+		oidcProviderPaths(i),
+		mfaPaths(i),
 	)
 }
 
@@ -578,7 +585,8 @@ func (i *IdentityStore) CreateOrFetchEntity(ctx context.Context, alias *logical.
 		} else {
 			nsLabel = metricsutil.NamespaceLabel(ns)
 		}
-		i.core.MetricSink().IncrCounterWithLabels(
+		i.core.MetricSink().IncrCounterWithLabelsContext(
+			ctx,
 			[]string{"identity", "entity", "creation"},
 			1,
 			[]metrics.Label{
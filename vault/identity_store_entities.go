@@ -145,6 +145,48 @@ func entityPaths(i *IdentityStore) []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-merge-id"][0]),
 			HelpDescription: strings.TrimSpace(entityHelp["entity-merge-id"][1]),
 		},
+		{
+			Pattern: "entity/deduplicate/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"dry_run": {
+					Type:        framework.TypeBool,
+					Description: "If set, the duplicate entities are reported without being merged.",
+				},
+				"mount_accessors": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Accessors of the mounts whose aliases are compared. If not set, aliases of all mounts are compared.",
+				},
+				"case_insensitive": {
+					Type:        framework.TypeBool,
+					Description: "If set, alias names differing only in case are considered the same.",
+				},
+				"target": {
+					Type:        framework.TypeString,
+					Default:     dedupeTargetOldest,
+					Description: "How the entity the duplicates are merged into is chosen. Either 'oldest' or 'most_aliases'.",
+				},
+				"metadata_conflict": {
+					Type:        framework.TypeString,
+					Default:     dedupeMetadataKeepTarget,
+					Description: "How metadata keys with different values are resolved. 'keep_target' keeps the value of the entity merged into, 'overwrite' takes the value of the newest merged entity, and 'skip' leaves the duplicates unmerged.",
+				},
+				"policies": {
+					Type:        framework.TypeString,
+					Default:     dedupePoliciesUnion,
+					Description: "How the policies of the duplicates are merged. 'union' gives the entity merged into the policies of all duplicates, and 'keep_target' keeps only its own.",
+				},
+				"force": {
+					Type:        framework.TypeBool,
+					Description: "Setting this will follow the 'mine' strategy for merging MFA secrets. If not set, duplicates with conflicting MFA secrets are left unmerged.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathEntityDeduplicate(),
+			},
+
+			HelpSynopsis:    strings.TrimSpace(entityHelp["entity-deduplicate"][0]),
+			HelpDescription: strings.TrimSpace(entityHelp["entity-deduplicate"][1]),
+		},
 	}
 }
 
@@ -865,6 +907,14 @@ var entityHelp = map[string][2]string{
 		"Merge two or more entities together",
 		"",
 	},
+	"entity-deduplicate": {
+		"Merge entities which share alias names across mounts",
+		`
+Entities holding aliases of the same name on different mounts are grouped
+together, and each group is merged into a single entity. Use 'dry_run' to
+preview the groups and the conflicts found in them before merging.
+		`,
+	},
 	"batch-delete": {
 		"Delete all of the entities provided",
 		"",
@@ -0,0 +1,345 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// dedupeTargetOldest merges duplicates into the entity created first.
+	dedupeTargetOldest = "oldest"
+
+	// dedupeTargetMostAliases merges duplicates into the entity with the
+	// most aliases, falling back to the oldest on a tie.
+	dedupeTargetMostAliases = "most_aliases"
+)
+
+const (
+	// dedupeMetadataKeepTarget keeps the target's value of conflicting
+	// metadata keys.
+	dedupeMetadataKeepTarget = "keep_target"
+
+	// dedupeMetadataOverwrite takes the value of conflicting metadata keys
+	// from the merged entities, the most recently created one winning.
+	dedupeMetadataOverwrite = "overwrite"
+
+	// dedupeMetadataSkip leaves groups with conflicting metadata unmerged.
+	dedupeMetadataSkip = "skip"
+)
+
+const (
+	// dedupePoliciesUnion gives the target the policies of all the merged
+	// entities.
+	dedupePoliciesUnion = "union"
+
+	// dedupePoliciesKeepTarget keeps only the target's policies.
+	dedupePoliciesKeepTarget = "keep_target"
+)
+
+// dedupeGroup is a set of entities which share alias names across mounts,
+// and the entity they are merged into.
+type dedupeGroup struct {
+	target     *identity.Entity
+	from       []*identity.Entity
+	aliasNames []string
+	conflicts  []string
+	skipped    bool
+}
+
+// pathEntityDeduplicate finds entities with aliases of the same name on
+// different mounts, and merges each set of them into a single entity.
+func (i *IdentityStore) pathEntityDeduplicate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		dryRun := d.Get("dry_run").(bool)
+		caseInsensitive := d.Get("case_insensitive").(bool)
+		force := d.Get("force").(bool)
+
+		target := d.Get("target").(string)
+		switch target {
+		case dedupeTargetOldest, dedupeTargetMostAliases:
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("unknown target %q", target)), nil
+		}
+
+		metadataConflict := d.Get("metadata_conflict").(string)
+		switch metadataConflict {
+		case dedupeMetadataKeepTarget, dedupeMetadataOverwrite, dedupeMetadataSkip:
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("unknown metadata_conflict %q", metadataConflict)), nil
+		}
+
+		policies := d.Get("policies").(string)
+		switch policies {
+		case dedupePoliciesUnion, dedupePoliciesKeepTarget:
+		default:
+			return logical.ErrorResponse(fmt.Sprintf("unknown policies %q", policies)), nil
+		}
+
+		var mountAccessors map[string]bool
+		if raw := d.Get("mount_accessors").([]string); len(raw) > 0 {
+			mountAccessors = make(map[string]bool, len(raw))
+			for _, accessor := range raw {
+				if i.core.router.validateMountByAccessor(accessor) == nil {
+					return logical.ErrorResponse(fmt.Sprintf("invalid mount accessor %q", accessor)), nil
+				}
+				mountAccessors[accessor] = true
+			}
+		}
+
+		i.lock.Lock()
+		defer i.lock.Unlock()
+
+		groups, err := i.findDuplicateEntities(ctx, mountAccessors, caseInsensitive, target)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := 0
+		respGroups := make([]map[string]interface{}, 0, len(groups))
+		for _, group := range groups {
+			group.resolve(metadataConflict, force)
+
+			if !dryRun && !group.skipped {
+				userErr, intErr := i.mergeDuplicateEntities(ctx, group, policies == dedupePoliciesUnion, force)
+				if userErr != nil {
+					group.conflicts = append(group.conflicts, userErr.Error())
+					group.skipped = true
+				}
+				if intErr != nil {
+					return nil, intErr
+				}
+				if !group.skipped {
+					merged += len(group.from)
+				}
+			}
+
+			fromIDs := make([]string, 0, len(group.from))
+			for _, from := range group.from {
+				fromIDs = append(fromIDs, from.ID)
+			}
+			respGroups = append(respGroups, map[string]interface{}{
+				"to_entity_id":    group.target.ID,
+				"to_entity_name":  group.target.Name,
+				"from_entity_ids": fromIDs,
+				"alias_names":     group.aliasNames,
+				"conflicts":       group.conflicts,
+				"skipped":         group.skipped,
+			})
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"dry_run":         dryRun,
+				"groups":          respGroups,
+				"merged_entities": merged,
+			},
+		}, nil
+	}
+}
+
+// findDuplicateEntities groups the entities of the request's namespace that
+// are linked by aliases of the same name, and picks the target of each
+// group. Entities are grouped transitively: if A shares an alias name with
+// B, and B with C, all three are merged together.
+func (i *IdentityStore) findDuplicateEntities(ctx context.Context, mountAccessors map[string]bool, caseInsensitive bool, target string) ([]*dedupeGroup, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	txn := i.db.Txn(false)
+	iter, err := txn.Get(entityAliasesTable, "namespace_id", ns.ID)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to fetch iterator for aliases in memdb: {{err}}", err)
+	}
+
+	// Collect the entities holding each alias name
+	byName := make(map[string][]string)
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		alias := raw.(*identity.Alias)
+		if mountAccessors != nil && !mountAccessors[alias.MountAccessor] {
+			continue
+		}
+		name := alias.Name
+		if caseInsensitive {
+			name = strings.ToLower(name)
+		}
+		byName[name] = strutil.AppendIfMissing(byName[name], alias.CanonicalID)
+	}
+
+	// Union the entities sharing a name
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			parent[id] = id
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+
+	var names []string
+	for name, entityIDs := range byName {
+		if len(entityIDs) < 2 {
+			continue
+		}
+		names = append(names, name)
+		root := find(entityIDs[0])
+		for _, entityID := range entityIDs[1:] {
+			if other := find(entityID); other != root {
+				parent[other] = root
+			}
+		}
+	}
+	sort.Strings(names)
+
+	members := make(map[string][]*identity.Entity)
+	aliasNames := make(map[string][]string)
+	for entityID := range parent {
+		entity, err := i.MemDBEntityByIDInTxn(txn, entityID, true)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			continue
+		}
+		root := find(entityID)
+		members[root] = append(members[root], entity)
+	}
+	for _, name := range names {
+		root := find(byName[name][0])
+		aliasNames[root] = append(aliasNames[root], name)
+	}
+
+	groups := make([]*dedupeGroup, 0, len(members))
+	for root, entities := range members {
+		if len(entities) < 2 {
+			continue
+		}
+
+		// Order by creation, so that the oldest entity comes first and
+		// the newest overwrites metadata last
+		sort.Slice(entities, func(a, b int) bool {
+			return entityCreatedBefore(entities[a], entities[b])
+		})
+
+		targetIdx := 0
+		if target == dedupeTargetMostAliases {
+			for idx, entity := range entities {
+				if len(entity.Aliases) > len(entities[targetIdx].Aliases) {
+					targetIdx = idx
+				}
+			}
+		}
+
+		group := &dedupeGroup{
+			target:     entities[targetIdx],
+			aliasNames: aliasNames[root],
+		}
+		for idx, entity := range entities {
+			if idx != targetIdx {
+				group.from = append(group.from, entity)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(a, b int) bool {
+		return groups[a].target.ID < groups[b].target.ID
+	})
+	return groups, nil
+}
+
+// entityCreatedBefore orders entities by creation time, then by ID.
+func entityCreatedBefore(a, b *identity.Entity) bool {
+	aTime, aErr := ptypes.Timestamp(a.CreationTime)
+	bTime, bErr := ptypes.Timestamp(b.CreationTime)
+	if aErr == nil && bErr == nil && !aTime.Equal(bTime) {
+		return aTime.Before(bTime)
+	}
+	return a.ID < b.ID
+}
+
+// resolve merges the metadata of the group into its target according to
+// the conflict rule, and records the conflicts found.
+func (g *dedupeGroup) resolve(metadataConflict string, force bool) {
+	metadata := make(map[string]string, len(g.target.Metadata))
+	for k, v := range g.target.Metadata {
+		metadata[k] = v
+	}
+
+	for _, from := range g.from {
+		var keys []string
+		for k := range from.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v := from.Metadata[k]
+			existing, ok := metadata[k]
+			if !ok {
+				metadata[k] = v
+				continue
+			}
+			if existing == v {
+				continue
+			}
+			g.conflicts = append(g.conflicts, fmt.Sprintf("metadata key %q is %q in entity ID %q and %q in entity ID %q", k, existing, g.target.ID, v, from.ID))
+			if metadataConflict == dedupeMetadataOverwrite {
+				metadata[k] = v
+			}
+		}
+
+		for configID := range from.MFASecrets {
+			if _, ok := g.target.MFASecrets[configID]; ok {
+				g.conflicts = append(g.conflicts, fmt.Sprintf("conflicting MFA config ID %q in entity ID %q", configID, from.ID))
+				if !force {
+					g.skipped = true
+				}
+			}
+		}
+	}
+
+	if metadataConflict == dedupeMetadataSkip && len(g.conflicts) > 0 {
+		g.skipped = true
+	}
+	if len(metadata) > 0 {
+		g.target.Metadata = metadata
+	}
+}
+
+// mergeDuplicateEntities merges the entities of the group into its target.
+// The caller must hold the identity store lock.
+func (i *IdentityStore) mergeDuplicateEntities(ctx context.Context, group *dedupeGroup, mergePolicies, force bool) (error, error) {
+	txn := i.db.Txn(true)
+	defer txn.Abort()
+
+	fromIDs := make([]string, 0, len(group.from))
+	for _, from := range group.from {
+		fromIDs = append(fromIDs, from.ID)
+	}
+
+	userErr, intErr := i.mergeEntity(ctx, txn, group.target, fromIDs, force, false, mergePolicies, true)
+	if userErr != nil || intErr != nil {
+		return userErr, intErr
+	}
+
+	// Committing the transaction *after* successfully performing storage
+	// persistence
+	txn.Commit()
+	return nil, nil
+}
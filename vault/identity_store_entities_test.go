@@ -1128,3 +1128,121 @@ func TestIdentityStore_MergeEntitiesByID(t *testing.T) {
 		}
 	}
 }
+
+func TestIdentityStore_DeduplicateEntities(t *testing.T) {
+	ctx := namespace.RootContext(nil)
+	is, githubAccessor, c := testIdentityStoreWithGithubAuth(ctx, t)
+
+	meGH2 := &MountEntry{
+		Table:       credentialTableType,
+		Path:        "github2/",
+		Type:        "github",
+		Description: "second github auth",
+	}
+	if err := c.enableCredential(ctx, meGH2); err != nil {
+		t.Fatal(err)
+	}
+
+	createEntity := func(name, accessor, aliasName string, metadata, policies []string) string {
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity",
+			Data: map[string]interface{}{
+				"name":     name,
+				"metadata": metadata,
+				"policies": policies,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		entityID := resp.Data["id"].(string)
+
+		resp, err = is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity-alias",
+			Data: map[string]interface{}{
+				"name":           aliasName,
+				"mount_accessor": accessor,
+				"canonical_id":   entityID,
+			},
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return entityID
+	}
+
+	entityID1 := createEntity("entity1", githubAccessor, "alice", []string{"team=vault"}, []string{"policy1"})
+	entityID2 := createEntity("entity2", meGH2.Accessor, "Alice", []string{"team=consul", "site=dc1"}, []string{"policy2"})
+	entityID3 := createEntity("entity3", meGH2.Accessor, "bob", nil, nil)
+
+	dedupe := func(data map[string]interface{}) map[string]interface{} {
+		resp, err := is.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "entity/deduplicate",
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp.Data
+	}
+
+	// Alias names are case sensitive by default
+	data := dedupe(map[string]interface{}{"dry_run": true})
+	if groups := data["groups"].([]map[string]interface{}); len(groups) != 0 {
+		t.Fatalf("expected no duplicates, got %#v", groups)
+	}
+
+	// Conflicting metadata is reported, and skips the group when asked to
+	data = dedupe(map[string]interface{}{
+		"case_insensitive":  true,
+		"metadata_conflict": "skip",
+	})
+	groups := data["groups"].([]map[string]interface{})
+	if len(groups) != 1 || !groups[0]["skipped"].(bool) || len(groups[0]["conflicts"].([]string)) != 1 {
+		t.Fatalf("bad: groups: %#v", groups)
+	}
+	if data["merged_entities"].(int) != 0 {
+		t.Fatalf("bad: merged entities: %#v", data)
+	}
+
+	data = dedupe(map[string]interface{}{
+		"case_insensitive": true,
+	})
+	groups = data["groups"].([]map[string]interface{})
+	if len(groups) != 1 || groups[0]["to_entity_id"] != entityID1 || data["merged_entities"].(int) != 1 {
+		t.Fatalf("bad: groups: %#v", groups)
+	}
+	if !reflect.DeepEqual(groups[0]["from_entity_ids"], []string{entityID2}) {
+		t.Fatalf("bad: from entity IDs: %#v", groups[0]["from_entity_ids"])
+	}
+
+	entity, err := is.MemDBEntityByID(entityID1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entity.Aliases) != 2 {
+		t.Fatalf("bad: number of aliases in entity; expected: 2, actual: %d", len(entity.Aliases))
+	}
+	expectedMetadata := map[string]string{"team": "vault", "site": "dc1"}
+	if !reflect.DeepEqual(entity.Metadata, expectedMetadata) {
+		t.Fatalf("bad: metadata; expected: %#v, actual: %#v", expectedMetadata, entity.Metadata)
+	}
+	policies := append([]string(nil), entity.Policies...)
+	sort.Strings(policies)
+	if !reflect.DeepEqual(policies, []string{"policy1", "policy2"}) {
+		t.Fatalf("bad: policies: %#v", policies)
+	}
+
+	for _, entityID := range []string{entityID2, entityID3} {
+		entity, err := is.MemDBEntityByID(entityID, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (entity == nil) != (entityID == entityID2) {
+			t.Fatalf("bad: entity %q: %#v", entityID, entity)
+		}
+	}
+}
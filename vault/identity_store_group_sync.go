@@ -0,0 +1,179 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// minGroupSyncInterval is the shortest interval at which the external group
+// memberships of an auth mount can be synced.
+const minGroupSyncInterval = time.Minute
+
+// groupSyncCheckInterval is how often the auth mounts are checked for
+// external group memberships due to be synced.
+var groupSyncCheckInterval = time.Minute
+
+func parseGroupSyncInterval(raw string) (time.Duration, error) {
+	interval, err := parseutil.ParseDurationSecond(raw)
+	if err != nil {
+		return 0, errwrap.Wrapf("invalid group_sync_interval: {{err}}", err)
+	}
+	if interval != 0 && interval < minGroupSyncInterval {
+		return 0, fmt.Errorf("group_sync_interval must be 0 or at least %s", minGroupSyncInterval)
+	}
+	return interval, nil
+}
+
+// runExternalGroupSync periodically re-resolves the external group
+// memberships of the entities with aliases on the auth mounts configured
+// with a group sync interval, until ctx is done. This lets removals from a
+// group in the external system take effect without waiting for the tokens
+// of its members to be renewed or to expire.
+func (c *Core) runExternalGroupSync(ctx context.Context) {
+	ticker := time.NewTicker(groupSyncCheckInterval)
+	defer ticker.Stop()
+
+	lastSync := make(map[string]time.Time)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Group memberships are written by the active node of the primary
+		if c.perfStandby || c.ReplicationState().HasState(consts.ReplicationPerformanceSecondary) {
+			continue
+		}
+
+		now := time.Now()
+		for _, me := range c.groupSyncMounts() {
+			if now.Sub(lastSync[me.Accessor]) < me.Config.GroupSyncInterval {
+				continue
+			}
+			lastSync[me.Accessor] = now
+			c.syncExternalGroups(ctx, me)
+		}
+	}
+}
+
+// groupSyncMounts returns the auth mounts configured with a group sync
+// interval. Local mounts are left out, since their aliases are not tracked
+// by the identity store.
+func (c *Core) groupSyncMounts() []*MountEntry {
+	c.authLock.RLock()
+	defer c.authLock.RUnlock()
+
+	if c.auth == nil {
+		return nil
+	}
+
+	var entries []*MountEntry
+	for _, me := range c.auth.Entries {
+		if me.Config.GroupSyncInterval > 0 && !me.Local {
+			entries = append(entries, me)
+		}
+	}
+	return entries
+}
+
+// syncExternalGroups asks the auth method of the mount for the current
+// groups of each alias on the mount, and updates the memberships of the
+// alias' entity in the external groups of the mount to match. Aliases whose
+// groups cannot be resolved keep their memberships.
+func (c *Core) syncExternalGroups(ctx context.Context, me *MountEntry) {
+	defer metrics.MeasureSince([]string{"identity", "group_sync"}, time.Now())
+
+	if c.identityStore == nil {
+		return
+	}
+
+	ns := me.Namespace()
+	if ns == nil {
+		return
+	}
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	aliases, err := c.identityStore.aliasesByMountAccessor(ns, me.Accessor)
+	if err != nil {
+		c.logger.Error("failed to fetch aliases for group sync", "path", me.Path, "error", err)
+		return
+	}
+
+	path := credentialRoutePrefix + me.Path
+	var synced, failed int
+	for _, alias := range aliases {
+		if ctx.Err() != nil {
+			return
+		}
+
+		entity, err := c.identityStore.MemDBEntityByID(alias.CanonicalID, false)
+		if err != nil || entity == nil || entity.Disabled {
+			continue
+		}
+
+		resp, err := c.router.Route(nsCtx, logical.GroupSyncRequest(path, &logical.Alias{
+			MountType:     me.Type,
+			MountAccessor: me.Accessor,
+			Name:          alias.Name,
+			Metadata:      alias.Metadata,
+		}))
+		if err == logical.ErrUnsupportedOperation {
+			c.logger.Warn("auth method does not support group sync", "path", me.Path, "type", me.Type)
+			return
+		}
+		if err == nil && resp != nil && resp.IsError() {
+			err = resp.Error()
+		}
+		if err != nil {
+			failed++
+			c.logger.Debug("failed to resolve groups of alias", "path", me.Path, "alias", alias.Name, "error", err)
+			continue
+		}
+		if resp == nil || resp.Auth == nil {
+			continue
+		}
+
+		if _, err := c.identityStore.refreshExternalGroupMembershipsByEntityIDAndMount(nsCtx, entity.ID, me.Accessor, resp.Auth.GroupAliases); err != nil {
+			failed++
+			c.logger.Error("failed to refresh external group memberships", "path", me.Path, "entity_id", entity.ID, "error", err)
+			continue
+		}
+		synced++
+	}
+
+	metrics.IncrCounter([]string{"identity", "group_sync", "synced"}, float32(synced))
+	if failed > 0 {
+		metrics.IncrCounter([]string{"identity", "group_sync", "failed"}, float32(failed))
+		c.logger.Warn("failed to sync the external groups of some aliases", "path", me.Path, "failed", failed, "synced", synced)
+	}
+}
+
+// aliasesByMountAccessor returns the entity aliases of the namespace which
+// belong to the mount.
+func (i *IdentityStore) aliasesByMountAccessor(ns *namespace.Namespace, mountAccessor string) ([]*identity.Alias, error) {
+	txn := i.db.Txn(false)
+
+	iter, err := txn.Get(entityAliasesTable, "namespace_id", ns.ID)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to fetch iterator for aliases in memdb: {{err}}", err)
+	}
+
+	var aliases []*identity.Alias
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		alias := raw.(*identity.Alias)
+		if alias.MountAccessor == mountAccessor {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases, nil
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestIdentityStore_ExternalGroupSync(t *testing.T) {
+	// The groups of each alias name, as the external system reports them
+	var l sync.Mutex
+	groups := map[string][]string{
+		"alice": []string{"admins"},
+	}
+	err := AddTestCredentialBackend("groupsync", func(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
+		b := &framework.Backend{
+			BackendType: logical.TypeCredential,
+			GroupSync: func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+				l.Lock()
+				defer l.Unlock()
+				auth := &logical.Auth{}
+				for _, name := range groups[req.Auth.Alias.Name] {
+					auth.GroupAliases = append(auth.GroupAliases, &logical.Alias{Name: name})
+				}
+				return &logical.Response{Auth: auth}, nil
+			},
+		}
+		if err := b.Setup(ctx, conf); err != nil {
+			return nil, err
+		}
+		return b, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	is := c.identityStore
+
+	me := &MountEntry{
+		Table: credentialTableType,
+		Path:  "groupsync/",
+		Type:  "groupsync",
+	}
+	if err := c.enableCredential(ctx, me); err != nil {
+		t.Fatal(err)
+	}
+
+	// Intervals shorter than the minimum are refused
+	resp, err := c.systemBackend.HandleRequest(ctx, &logical.Request{
+		Path:      "auth/groupsync/tune",
+		Operation: logical.UpdateOperation,
+		Data:      map[string]interface{}{"group_sync_interval": "30s"},
+	})
+	if err == nil {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+	resp, err = c.systemBackend.HandleRequest(ctx, &logical.Request{
+		Path:      "auth/groupsync/tune",
+		Operation: logical.UpdateOperation,
+		Data:      map[string]interface{}{"group_sync_interval": "1h"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	mounts := c.groupSyncMounts()
+	if len(mounts) != 1 || mounts[0].Config.GroupSyncInterval.Hours() != 1 {
+		t.Fatalf("bad: group sync mounts: %#v", mounts)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "group",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"type": "external",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	groupID := resp.Data["id"].(string)
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "group-alias",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"mount_accessor": me.Accessor,
+			"canonical_id":   groupID,
+			"name":           "admins",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = is.HandleRequest(ctx, &logical.Request{
+		Path:      "entity-alias",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"mount_accessor": me.Accessor,
+			"name":           "alice",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	entityID := resp.Data["canonical_id"].(string)
+
+	checkMembers := func(expected int) {
+		t.Helper()
+		group, err := is.MemDBGroupByID(groupID, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(group.MemberEntityIDs) != expected {
+			t.Fatalf("bad: member entity IDs: %#v", group.MemberEntityIDs)
+		}
+		if expected > 0 && group.MemberEntityIDs[0] != entityID {
+			t.Fatalf("bad: member entity IDs: %#v", group.MemberEntityIDs)
+		}
+	}
+
+	c.syncExternalGroups(ctx, mounts[0])
+	checkMembers(1)
+
+	// Removal from the group in the external system is picked up
+	l.Lock()
+	groups["alice"] = nil
+	l.Unlock()
+	c.syncExternalGroups(ctx, mounts[0])
+	checkMembers(0)
+}
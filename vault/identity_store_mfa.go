@@ -0,0 +1,329 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/identity/mfa"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	otplib "github.com/pquerna/otp"
+	totplib "github.com/pquerna/otp/totp"
+)
+
+// mfaPaths returns the API endpoints enrolling entities in the TOTP MFA
+// methods required by the mfa_methods of ACL policies.
+func mfaPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mfa/method/totp/admin-generate$",
+			Fields: map[string]*framework.FieldSchema{
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity to enroll in the MFA method.",
+				},
+				"method_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method, as referenced by the mfa_methods of policies.",
+				},
+				"issuer": {
+					Type:        framework.TypeString,
+					Default:     "Vault",
+					Description: "Name of the issuer of the TOTP key.",
+				},
+				"period": {
+					Type:        framework.TypeDurationSecond,
+					Default:     30,
+					Description: "Length of time during which a passcode is valid.",
+				},
+				"algorithm": {
+					Type:        framework.TypeString,
+					Default:     "SHA1",
+					Description: "Hashing algorithm of the passcodes. Options include SHA1, SHA256 and SHA512.",
+				},
+				"digits": {
+					Type:        framework.TypeInt,
+					Default:     6,
+					Description: "Number of digits of the passcodes. This value can either be 6 or 8.",
+				},
+				"skew": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "Number of periods before and after the current one during which passcodes are accepted. This value can either be 0 or 1.",
+				},
+				"key_size": {
+					Type:        framework.TypeInt,
+					Default:     20,
+					Description: "Size in bytes of the generated key.",
+				},
+				"qr_size": {
+					Type:        framework.TypeInt,
+					Default:     200,
+					Description: "Pixel size of the square QR code of the key. If set to 0, no QR code is returned.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFATOTPAdminGenerate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-generate"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-generate"][1]),
+		},
+		{
+			Pattern: "mfa/method/totp/admin-destroy$",
+			Fields: map[string]*framework.FieldSchema{
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity to remove from the MFA method.",
+				},
+				"method_name": {
+					Type:        framework.TypeString,
+					Description: "Name of the MFA method.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathMFATOTPAdminDestroy,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(mfaHelp["totp-admin-destroy"][0]),
+			HelpDescription: strings.TrimSpace(mfaHelp["totp-admin-destroy"][1]),
+		},
+	}
+}
+
+// mfaEntity returns a clone of the entity of the request's namespace with the
+// given ID, or an error response if there is none.
+func (i *IdentityStore) mfaEntity(ctx context.Context, d *framework.FieldData) (*identity.Entity, *logical.Response, error) {
+	entityID := d.Get("entity_id").(string)
+	if entityID == "" {
+		return nil, logical.ErrorResponse("missing entity_id"), nil
+	}
+	if d.Get("method_name").(string) == "" {
+		return nil, logical.ErrorResponse("missing method_name"), nil
+	}
+
+	entity, err := i.MemDBEntityByID(entityID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entity == nil || entity.NamespaceID != ns.ID {
+		return nil, logical.ErrorResponse("entity not found from id"), nil
+	}
+
+	return entity, nil, nil
+}
+
+func (i *IdentityStore) pathMFATOTPAdminGenerate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	var algorithm otplib.Algorithm
+	switch d.Get("algorithm").(string) {
+	case "SHA1":
+		algorithm = otplib.AlgorithmSHA1
+	case "SHA256":
+		algorithm = otplib.AlgorithmSHA256
+	case "SHA512":
+		algorithm = otplib.AlgorithmSHA512
+	default:
+		return logical.ErrorResponse("the algorithm value is not valid"), nil
+	}
+
+	var digits otplib.Digits
+	switch d.Get("digits").(int) {
+	case 6:
+		digits = otplib.DigitsSix
+	case 8:
+		digits = otplib.DigitsEight
+	default:
+		return logical.ErrorResponse("the digits value can only be 6 or 8"), nil
+	}
+
+	period := d.Get("period").(int)
+	skew := d.Get("skew").(int)
+	keySize := d.Get("key_size").(int)
+	qrSize := d.Get("qr_size").(int)
+	switch {
+	case period <= 0:
+		return logical.ErrorResponse("the period value must be greater than zero"), nil
+	case skew != 0 && skew != 1:
+		return logical.ErrorResponse("the skew value must be 0 or 1"), nil
+	case keySize <= 0:
+		return logical.ErrorResponse("the key_size value must be greater than zero"), nil
+	case qrSize < 0:
+		return logical.ErrorResponse("the qr_size value must be greater than or equal to zero"), nil
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, resp, err := i.mfaEntity(ctx, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	methodName := d.Get("method_name").(string)
+	if _, ok := entity.MFASecrets[methodName]; ok {
+		return logical.ErrorResponse("entity is already enrolled in the MFA method; destroy its secret first"), nil
+	}
+
+	accountName := entity.Name
+	if accountName == "" {
+		accountName = entity.ID
+	}
+	key, err := totplib.Generate(totplib.GenerateOpts{
+		Issuer:      d.Get("issuer").(string),
+		AccountName: accountName,
+		Period:      uint(period),
+		Digits:      digits,
+		Algorithm:   algorithm,
+		SecretSize:  uint(keySize),
+		Rand:        i.GetRandomReader(),
+	})
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to generate TOTP key: {{err}}", err)
+	}
+
+	if entity.MFASecrets == nil {
+		entity.MFASecrets = make(map[string]*mfa.Secret)
+	}
+	entity.MFASecrets[methodName] = &mfa.Secret{
+		MethodName: methodName,
+		Value: &mfa.Secret_TOTPSecret{
+			TOTPSecret: &mfa.TOTPSecret{
+				Issuer:      key.Issuer(),
+				Period:      uint32(period),
+				Algorithm:   int32(algorithm),
+				Digits:      int32(digits),
+				Skew:        uint32(skew),
+				KeySize:     uint32(keySize),
+				AccountName: accountName,
+				Key:         key.Secret(),
+			},
+		},
+	}
+
+	if err := i.upsertEntity(ctx, entity, nil, true); err != nil {
+		return nil, err
+	}
+
+	resp = &logical.Response{
+		Data: map[string]interface{}{
+			"url": key.String(),
+		},
+	}
+	if qrSize > 0 {
+		barcode, err := key.Image(qrSize, qrSize)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to generate QR code image: {{err}}", err)
+		}
+
+		var buff bytes.Buffer
+		if err := png.Encode(&buff, barcode); err != nil {
+			return nil, errwrap.Wrapf("failed to encode QR code image: {{err}}", err)
+		}
+		resp.Data["barcode"] = base64.StdEncoding.EncodeToString(buff.Bytes())
+	}
+
+	return resp, nil
+}
+
+func (i *IdentityStore) pathMFATOTPAdminDestroy(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	entity, resp, err := i.mfaEntity(ctx, d)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	methodName := d.Get("method_name").(string)
+	if _, ok := entity.MFASecrets[methodName]; !ok {
+		return nil, nil
+	}
+	delete(entity.MFASecrets, methodName)
+
+	return nil, i.upsertEntity(ctx, entity, nil, true)
+}
+
+// validateMFACreds checks that the MFA credentials of a request hold a valid
+// passcode for each of the given methods, which the entity has to be enrolled
+// in. Passcodes are accepted again for the requests made for the envelope
+// request with the given ID, if any.
+func (i *IdentityStore) validateMFACreds(entity *identity.Entity, methods []string, creds logical.MFACreds, envelopeID string) error {
+	if entity == nil {
+		return errors.New("MFA validation requires a token tied to an entity")
+	}
+
+	var retErr *multierror.Error
+	for _, method := range methods {
+		if err := i.validateTOTPPasscode(entity, method, creds[method], envelopeID); err != nil {
+			retErr = multierror.Append(retErr, err)
+		}
+	}
+	return retErr.ErrorOrNil()
+}
+
+func (i *IdentityStore) validateTOTPPasscode(entity *identity.Entity, method string, passcodes []string, envelopeID string) error {
+	secret := entity.MFASecrets[method].GetTOTPSecret()
+	if secret == nil {
+		return fmt.Errorf("entity is not enrolled in MFA method %q", method)
+	}
+	if len(passcodes) != 1 || passcodes[0] == "" {
+		return fmt.Errorf("MFA method %q requires a passcode", method)
+	}
+	passcode := passcodes[0]
+
+	// A passcode is only accepted once, so that an intercepted request cannot
+	// be replayed, except by the other requests of the same envelope request
+	usedName := fmt.Sprintf("%s_%s_%s", entity.ID, method, passcode)
+	if usedBy, ok := i.mfaUsedCodes.Get(usedName); ok {
+		if envelopeID != "" && usedBy == envelopeID {
+			return nil
+		}
+		return fmt.Errorf("passcode for MFA method %q already used; wait until the next time period", method)
+	}
+
+	valid, err := totplib.ValidateCustom(passcode, secret.Key, time.Now(), totplib.ValidateOpts{
+		Period:    uint(secret.Period),
+		Skew:      uint(secret.Skew),
+		Digits:    otplib.Digits(secret.Digits),
+		Algorithm: otplib.Algorithm(secret.Algorithm),
+	})
+	if err != nil && err != otplib.ErrValidateInputInvalidLength {
+		return errwrap.Wrapf(fmt.Sprintf("failed to validate passcode for MFA method %q: {{err}}", method), err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid passcode for MFA method %q", method)
+	}
+
+	// Cover the whole time during which the passcode is valid, given the skew
+	i.mfaUsedCodes.Set(usedName, envelopeID, time.Duration(secret.Period*(2+secret.Skew))*time.Second)
+
+	return nil
+}
+
+var mfaHelp = map[string][2]string{
+	"totp-admin-generate": {
+		"Enroll an entity in a TOTP MFA method.",
+		`Generates a TOTP key for the entity, whose passcodes are required by policies
+listing the method in their mfa_methods. The key is returned as an otpauth URL
+and a QR code to be imported into an authenticator application.`,
+	},
+	"totp-admin-destroy": {
+		"Remove an entity from a TOTP MFA method.",
+		"",
+	},
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/helper/identitytpl"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -55,10 +56,11 @@ type namedKey struct {
 }
 
 type role struct {
-	TokenTTL time.Duration `json:"token_ttl"`
-	Key      string        `json:"key"`
-	Template string        `json:"template"`
-	ClientID string        `json:"client_id"`
+	TokenTTL     time.Duration `json:"token_ttl"`
+	Key          string        `json:"key"`
+	Template     string        `json:"template"`
+	ClientID     string        `json:"client_id"`
+	RedirectURIs []string      `json:"redirect_uris"`
 }
 
 // idToken contains the required OIDC fields.
@@ -67,12 +69,13 @@ type role struct {
 // include top-level keys, but those keys may not overwrite any of the
 // required OIDC fields.
 type idToken struct {
-	Issuer    string `json:"iss"`       // api_addr or custom Issuer
-	Namespace string `json:"namespace"` // Namespace of issuer
-	Subject   string `json:"sub"`       // Entity ID
-	Audience  string `json:"aud"`       // role ID will be used here.
-	Expiry    int64  `json:"exp"`       // Expiration, as determined by the role.
-	IssuedAt  int64  `json:"iat"`       // Time of token creation
+	Issuer    string `json:"iss"`             // api_addr or custom Issuer
+	Namespace string `json:"namespace"`       // Namespace of issuer
+	Subject   string `json:"sub"`             // Entity ID
+	Audience  string `json:"aud"`             // role ID will be used here.
+	Expiry    int64  `json:"exp"`             // Expiration, as determined by the role.
+	IssuedAt  int64  `json:"iat"`             // Time of token creation
+	Nonce     string `json:"nonce,omitempty"` // Nonce of the authorization request, if any
 }
 
 // discovery contains a subset of the required elements of OIDC discovery needed
@@ -85,6 +88,13 @@ type discovery struct {
 	ResponseTypes []string `json:"response_types_supported"`
 	Subjects      []string `json:"subject_types_supported"`
 	IDTokenAlgs   []string `json:"id_token_signing_alg_values_supported"`
+
+	// Endpoints of the authorization code and device authorization grants.
+	// The authorization endpoint is per role, so it is not listed.
+	TokenEndpoint        string   `json:"token_endpoint"`
+	DeviceEndpoint       string   `json:"device_authorization_endpoint"`
+	GrantTypes           []string `json:"grant_types_supported"`
+	CodeChallengeMethods []string `json:"code_challenge_methods_supported"`
 }
 
 // oidcCache is a thin wrapper around go-cache to partition by namespace
@@ -255,6 +265,10 @@ func oidcPaths(i *IdentityStore) []*framework.Path {
 					Type:        framework.TypeString,
 					Description: "Optional client_id",
 				},
+				"redirect_uris": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Comma separated string or array of the URIs the authorization code flow may redirect to. The flow is disabled for the role if empty.",
+				},
 			},
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: i.pathOIDCCreateUpdateRole,
@@ -689,11 +703,6 @@ func (i *IdentityStore) pathOIDCKeyExistenceCheck(ctx context.Context, req *logi
 
 // handleOIDCGenerateSignToken generates and signs an OIDC token
 func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
-	ns, err := namespace.FromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	roleName := d.Get("name").(string)
 
 	role, err := i.getOIDCRole(ctx, req.Storage, roleName)
@@ -704,65 +713,93 @@ func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.
 		return logical.ErrorResponse("role %q not found", roleName), nil
 	}
 
+	// generate an OIDC token from entity data
+	if req.EntityID == "" {
+		return logical.ErrorResponse("no entity associated with the request's token"), nil
+	}
+
+	signedIdToken, err := i.generateIDToken(ctx, req.Storage, roleName, role, req.EntityID, "")
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return logical.ErrorResponse(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"token":     signedIdToken,
+			"client_id": role.ClientID,
+			"ttl":       int64(role.TokenTTL.Seconds()),
+		},
+	}, nil
+}
+
+// generateIDToken generates and signs an OIDC token for the entity against
+// the role. Errors due to the configuration of the role or its key are
+// returned as user errors.
+func (i *IdentityStore) generateIDToken(ctx context.Context, s logical.Storage, roleName string, role *role, entityID, nonce string) (string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
 	var key *namedKey
 
 	keyRaw, found, err := i.oidcCache.Get(ns, "namedKeys/"+role.Key)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	if found {
 		key = keyRaw.(*namedKey)
 	} else {
-		entry, _ := req.Storage.Get(ctx, namedKeyConfigPath+role.Key)
+		entry, _ := s.Get(ctx, namedKeyConfigPath+role.Key)
 		if entry == nil {
-			return logical.ErrorResponse("key %q not found", role.Key), nil
+			return "", errutil.UserError{Err: fmt.Sprintf("key %q not found", role.Key)}
 		}
 
 		if err := entry.DecodeJSON(&key); err != nil {
-			return nil, err
+			return "", err
 		}
 
 		if err := i.oidcCache.SetDefault(ns, "namedKeys/"+role.Key, key); err != nil {
-			return nil, err
+			return "", err
 		}
 	}
 	// Validate that the role is allowed to sign with its key (the key could have been updated)
 	if !strutil.StrListContains(key.AllowedClientIDs, "*") && !strutil.StrListContains(key.AllowedClientIDs, role.ClientID) {
-		return logical.ErrorResponse("the key %q does not list the client ID of the role %q as an allowed client ID", role.Key, roleName), nil
-	}
-
-	// generate an OIDC token from entity data
-	if req.EntityID == "" {
-		return logical.ErrorResponse("no entity associated with the request's token"), nil
+		return "", errutil.UserError{Err: fmt.Sprintf("the key %q does not list the client ID of the role %q as an allowed client ID", role.Key, roleName)}
 	}
 
-	config, err := i.getOIDCConfig(ctx, req.Storage)
+	config, err := i.getOIDCConfig(ctx, s)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	now := time.Now()
 	idToken := idToken{
 		Issuer:    config.effectiveIssuer,
 		Namespace: ns.ID,
-		Subject:   req.EntityID,
+		Subject:   entityID,
 		Audience:  role.ClientID,
 		Expiry:    now.Add(role.TokenTTL).Unix(),
 		IssuedAt:  now.Unix(),
+		Nonce:     nonce,
 	}
 
-	e, err := i.MemDBEntityByID(req.EntityID, true)
+	e, err := i.MemDBEntityByID(entityID, true)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	if e == nil {
-		return nil, fmt.Errorf("error loading entity ID %q", req.EntityID)
+		return "", fmt.Errorf("error loading entity ID %q", entityID)
 	}
 
 	groups, inheritedGroups, err := i.groupsByEntityID(e.ID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	groups = append(groups, inheritedGroups...)
@@ -774,16 +811,10 @@ func (i *IdentityStore) pathOIDCGenerateToken(ctx context.Context, req *logical.
 
 	signedIdToken, err := key.signPayload(payload)
 	if err != nil {
-		return nil, errwrap.Wrapf("error signing OIDC token: {{err}}", err)
+		return "", errwrap.Wrapf("error signing OIDC token: {{err}}", err)
 	}
 
-	return &logical.Response{
-		Data: map[string]interface{}{
-			"token":     signedIdToken,
-			"client_id": role.ClientID,
-			"ttl":       int64(role.TokenTTL.Seconds()),
-		},
-	}, nil
+	return signedIdToken, nil
 }
 
 func (tok *idToken) generatePayload(logger hclog.Logger, template string, entity *identity.Entity, groups []*identity.Group) ([]byte, error) {
@@ -826,6 +857,12 @@ func (tok *idToken) generatePayload(logger hclog.Logger, template string, entity
 		}
 	}
 
+	// The nonce binds the token to the authorization request, so it is set
+	// after the template to keep the template from overriding it
+	if tok.Nonce != "" {
+		output["nonce"] = tok.Nonce
+	}
+
 	payload, err := json.Marshal(output)
 	if err != nil {
 		return nil, err
@@ -939,6 +976,15 @@ func (i *IdentityStore) pathOIDCCreateUpdateRole(ctx context.Context, req *logic
 		role.ClientID = clientID.(string)
 	}
 
+	if redirectURIs, ok := d.GetOk("redirect_uris"); ok {
+		role.RedirectURIs = redirectURIs.([]string)
+	}
+	for _, redirectURI := range role.RedirectURIs {
+		if u, err := url.Parse(redirectURI); err != nil || u.Scheme == "" || u.Fragment != "" {
+			return logical.ErrorResponse("invalid redirect URI %q: must be absolute and have no fragment", redirectURI), nil
+		}
+	}
+
 	// create role path
 	if role.ClientID == "" {
 		clientID, err := base62.Random(26)
@@ -976,12 +1022,18 @@ func (i *IdentityStore) pathOIDCReadRole(ctx context.Context, req *logical.Reque
 		return nil, nil
 	}
 
+	redirectURIs := role.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"client_id": role.ClientID,
-			"key":       role.Key,
-			"template":  role.Template,
-			"ttl":       int64(role.TokenTTL.Seconds()),
+			"client_id":     role.ClientID,
+			"key":           role.Key,
+			"template":      role.Template,
+			"ttl":           int64(role.TokenTTL.Seconds()),
+			"redirect_uris": redirectURIs,
 		},
 	}, nil
 }
@@ -1047,9 +1099,14 @@ func (i *IdentityStore) pathOIDCDiscovery(ctx context.Context, req *logical.Requ
 		disc := discovery{
 			Issuer:        c.effectiveIssuer,
 			Keys:          c.effectiveIssuer + "/.well-known/keys",
-			ResponseTypes: []string{"id_token"},
+			ResponseTypes: []string{"id_token", "code"},
 			Subjects:      []string{"public"},
 			IDTokenAlgs:   supportedAlgs,
+
+			TokenEndpoint:        c.effectiveIssuer + "/provider/token",
+			DeviceEndpoint:       c.effectiveIssuer + "/provider/device",
+			GrantTypes:           []string{grantTypeAuthorizationCode, grantTypeDeviceCode},
+			CodeChallengeMethods: []string{codeChallengeMethodS256, codeChallengeMethodPlain},
 		}
 
 		data, err = json.Marshal(disc)
@@ -0,0 +1,556 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+
+	codeChallengeMethodS256  = "S256"
+	codeChallengeMethodPlain = "plain"
+
+	// oidcAuthCodeTTL is how long an authorization code can be redeemed
+	oidcAuthCodeTTL = time.Minute
+
+	// oidcDeviceCodeTTL is how long a device grant can be approved and
+	// redeemed
+	oidcDeviceCodeTTL = 10 * time.Minute
+
+	// oidcDevicePollInterval is the minimum time between two polls of the
+	// token endpoint for a device grant, extended each time a client polls
+	// too fast
+	oidcDevicePollInterval = 5 * time.Second
+
+	// userCodeCharset is the alphabet of user codes, without vowels to
+	// avoid forming words and with letters that are hard to mistake
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+// codeVerifierRegex is the syntax of PKCE code verifiers, RFC 7636 section 4.1
+var codeVerifierRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// oidcAuthCode is an authorization code issued to a client, waiting to be
+// exchanged for an ID token.
+type oidcAuthCode struct {
+	roleName            string
+	clientID            string
+	redirectURI         string
+	entityID            string
+	nonce               string
+	codeChallenge       string
+	codeChallengeMethod string
+}
+
+// oidcDeviceGrant is a device authorization request, waiting for a user to
+// approve it and for the device to redeem it.
+type oidcDeviceGrant struct {
+	roleName  string
+	clientID  string
+	userCode  string
+	expiresAt time.Time
+	interval  time.Duration
+	lastPoll  time.Time
+
+	// entityID is set once a user approves the grant
+	entityID string
+	denied   bool
+}
+
+func oidcProviderPaths(i *IdentityStore) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "oidc/provider/authorize/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role",
+				},
+				"client_id": {
+					Type:        framework.TypeString,
+					Description: "Client ID of the role.",
+				},
+				"redirect_uri": {
+					Type:        framework.TypeString,
+					Description: "URI to redirect to with the code. It must be one of the redirect URIs of the role.",
+				},
+				"response_type": {
+					Type:        framework.TypeString,
+					Description: `Must be "code".`,
+				},
+				"scope": {
+					Type:        framework.TypeString,
+					Description: `Space separated scopes of the request, which must include "openid".`,
+				},
+				"state": {
+					Type:        framework.TypeString,
+					Description: "Opaque value returned with the code.",
+				},
+				"nonce": {
+					Type:        framework.TypeString,
+					Description: "Value to include in the ID token as the nonce claim.",
+				},
+				"code_challenge": {
+					Type:        framework.TypeString,
+					Description: "PKCE code challenge derived from the code verifier of the client.",
+				},
+				"code_challenge_method": {
+					Type:        framework.TypeString,
+					Description: `Method used to derive the code challenge, "S256" or "plain".`,
+					Default:     codeChallengeMethodPlain,
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation:   i.pathOIDCProviderAuthorize,
+				logical.UpdateOperation: i.pathOIDCProviderAuthorize,
+			},
+			HelpSynopsis:    "Issue an OIDC authorization code",
+			HelpDescription: "Issue an authorization code for the entity of the request's token, to be exchanged by the client at the token endpoint for an ID token generated against the role. The code challenge of PKCE is required.",
+		},
+		{
+			Pattern: "oidc/provider/device/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"client_id": {
+					Type:        framework.TypeString,
+					Description: "Client ID of the role.",
+				},
+				"scope": {
+					Type:        framework.TypeString,
+					Description: "Space separated scopes of the request.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCProviderDeviceAuthorization,
+			},
+			HelpSynopsis:    "Start an OIDC device authorization grant",
+			HelpDescription: "Start an RFC 8628 device authorization grant, returning the code for the device to poll the token endpoint with and the code for the user to approve at the verification URI.",
+		},
+		{
+			Pattern: "oidc/provider/verify/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the role",
+				},
+				"user_code": {
+					Type:        framework.TypeString,
+					Description: "User code displayed by the device.",
+				},
+				"deny": {
+					Type:        framework.TypeBool,
+					Description: "Deny the device instead of approving it.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCProviderVerify,
+			},
+			HelpSynopsis:    "Approve an OIDC device authorization grant",
+			HelpDescription: "Approve the device authorization grant of the given user code for the entity of the request's token, or deny it.",
+		},
+		{
+			Pattern: "oidc/provider/token/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"grant_type": {
+					Type:        framework.TypeString,
+					Description: `Either "authorization_code" or "urn:ietf:params:oauth:grant-type:device_code".`,
+				},
+				"client_id": {
+					Type:        framework.TypeString,
+					Description: "Client ID of the role.",
+				},
+				"code": {
+					Type:        framework.TypeString,
+					Description: "Authorization code, for the authorization code grant.",
+				},
+				"redirect_uri": {
+					Type:        framework.TypeString,
+					Description: "Redirect URI of the authorization request, for the authorization code grant.",
+				},
+				"code_verifier": {
+					Type:        framework.TypeString,
+					Description: "PKCE code verifier, for the authorization code grant.",
+				},
+				"device_code": {
+					Type:        framework.TypeString,
+					Description: "Device code, for the device authorization grant.",
+				},
+			},
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: i.pathOIDCProviderToken,
+			},
+			HelpSynopsis:    "Exchange an OIDC grant for an ID token",
+			HelpDescription: "Exchange an authorization code or an approved device code for an ID token. Errors are returned as defined by RFC 6749 and RFC 8628.",
+		},
+	}
+}
+
+func (i *IdentityStore) pathOIDCProviderAuthorize(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roleName := d.Get("name").(string)
+	role, err := i.getOIDCRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return logical.ErrorResponse("role %q not found", roleName), nil
+	}
+
+	if clientID := d.Get("client_id").(string); clientID != role.ClientID {
+		return logical.ErrorResponse("client ID %q does not match role %q", clientID, roleName), nil
+	}
+	redirectURI := d.Get("redirect_uri").(string)
+	if !strutil.StrListContains(role.RedirectURIs, redirectURI) {
+		return logical.ErrorResponse("redirect URI %q is not allowed by role %q", redirectURI, roleName), nil
+	}
+	if responseType := d.Get("response_type").(string); responseType != "code" {
+		return logical.ErrorResponse("unsupported response type %q", responseType), nil
+	}
+	if !strutil.StrListContains(strings.Fields(d.Get("scope").(string)), "openid") {
+		return logical.ErrorResponse(`scope must include "openid"`), nil
+	}
+
+	codeChallenge := d.Get("code_challenge").(string)
+	if codeChallenge == "" {
+		return logical.ErrorResponse("code_challenge is required"), nil
+	}
+	codeChallengeMethod := d.Get("code_challenge_method").(string)
+	switch codeChallengeMethod {
+	case codeChallengeMethodS256, codeChallengeMethodPlain:
+	default:
+		return logical.ErrorResponse("unsupported code challenge method %q", codeChallengeMethod), nil
+	}
+
+	if req.EntityID == "" {
+		return logical.ErrorResponse("no entity associated with the request's token"), nil
+	}
+
+	code, err := base62.Random(32)
+	if err != nil {
+		return nil, err
+	}
+	i.oidcGrants.Set(oidcGrantKey(ns, "code", code), &oidcAuthCode{
+		roleName:            roleName,
+		clientID:            role.ClientID,
+		redirectURI:         redirectURI,
+		entityID:            req.EntityID,
+		nonce:               d.Get("nonce").(string),
+		codeChallenge:       codeChallenge,
+		codeChallengeMethod: codeChallengeMethod,
+	}, oidcAuthCodeTTL)
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"code":  code,
+			"state": d.Get("state").(string),
+		},
+	}, nil
+}
+
+func (i *IdentityStore) pathOIDCProviderDeviceAuthorization(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := d.Get("client_id").(string)
+	roleName, role, err := i.getOIDCRoleByClientID(ctx, req.Storage, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return oidcProviderErrorResponse("invalid_client", "unknown client ID"), nil
+	}
+
+	config, err := i.getOIDCConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode, err := base62.Random(32)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &oidcDeviceGrant{
+		roleName:  roleName,
+		clientID:  clientID,
+		userCode:  userCode,
+		expiresAt: time.Now().Add(oidcDeviceCodeTTL),
+		interval:  oidcDevicePollInterval,
+	}
+	i.oidcGrantsLock.Lock()
+	i.oidcGrants.Set(oidcGrantKey(ns, "device", deviceCode), grant, oidcDeviceCodeTTL)
+	i.oidcGrants.Set(oidcGrantKey(ns, "user_code", userCode), deviceCode, oidcDeviceCodeTTL)
+	i.oidcGrantsLock.Unlock()
+
+	return oidcProviderRawResponse(http.StatusOK, map[string]interface{}{
+		"device_code":      deviceCode,
+		"user_code":        userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:],
+		"verification_uri": config.effectiveIssuer + "/provider/verify/" + roleName,
+		"expires_in":       int64(oidcDeviceCodeTTL.Seconds()),
+		"interval":         int64(oidcDevicePollInterval.Seconds()),
+	})
+}
+
+func (i *IdentityStore) pathOIDCProviderVerify(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.EntityID == "" {
+		return logical.ErrorResponse("no entity associated with the request's token"), nil
+	}
+
+	// User codes are compared regardless of case and separators
+	userCode := strings.ToUpper(d.Get("user_code").(string))
+	userCode = strings.NewReplacer("-", "", " ", "").Replace(userCode)
+
+	i.oidcGrantsLock.Lock()
+	defer i.oidcGrantsLock.Unlock()
+
+	var grant *oidcDeviceGrant
+	if deviceCode, ok := i.oidcGrants.Get(oidcGrantKey(ns, "user_code", userCode)); ok {
+		if raw, ok := i.oidcGrants.Get(oidcGrantKey(ns, "device", deviceCode.(string))); ok {
+			grant = raw.(*oidcDeviceGrant)
+		}
+	}
+	if grant == nil || grant.roleName != d.Get("name").(string) {
+		return logical.ErrorResponse("invalid or expired user code"), nil
+	}
+	if grant.entityID != "" || grant.denied {
+		return logical.ErrorResponse("user code has already been used"), nil
+	}
+
+	if d.Get("deny").(bool) {
+		grant.denied = true
+	} else {
+		grant.entityID = req.EntityID
+	}
+	return nil, nil
+}
+
+func (i *IdentityStore) pathOIDCProviderToken(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := d.Get("client_id").(string)
+
+	var roleName, entityID, nonce string
+	switch grantType := d.Get("grant_type").(string); grantType {
+	case grantTypeAuthorizationCode:
+		// Codes are single use, so they are removed whether or not the
+		// exchange succeeds
+		key := oidcGrantKey(ns, "code", d.Get("code").(string))
+		i.oidcGrantsLock.Lock()
+		raw, ok := i.oidcGrants.Get(key)
+		i.oidcGrants.Delete(key)
+		i.oidcGrantsLock.Unlock()
+		if !ok {
+			return oidcProviderErrorResponse("invalid_grant", "invalid or expired authorization code"), nil
+		}
+		code := raw.(*oidcAuthCode)
+
+		if code.clientID != clientID {
+			return oidcProviderErrorResponse("invalid_grant", "authorization code was issued to another client"), nil
+		}
+		if code.redirectURI != d.Get("redirect_uri").(string) {
+			return oidcProviderErrorResponse("invalid_grant", "redirect URI does not match the authorization request"), nil
+		}
+		if !verifyCodeChallenge(d.Get("code_verifier").(string), code.codeChallenge, code.codeChallengeMethod) {
+			return oidcProviderErrorResponse("invalid_grant", "code verifier does not match the code challenge"), nil
+		}
+		roleName, entityID, nonce = code.roleName, code.entityID, code.nonce
+
+	case grantTypeDeviceCode:
+		resp, grant := i.pollDeviceGrant(ns, d.Get("device_code").(string), clientID)
+		if resp != nil {
+			return resp, nil
+		}
+		roleName, entityID = grant.roleName, grant.entityID
+
+	default:
+		return oidcProviderErrorResponse("unsupported_grant_type", fmt.Sprintf("unsupported grant type %q", grantType)), nil
+	}
+
+	// The role may have changed since the grant was issued
+	role, err := i.getOIDCRole(ctx, req.Storage, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil || role.ClientID != clientID {
+		return oidcProviderErrorResponse("invalid_client", "client no longer exists"), nil
+	}
+
+	idToken, err := i.generateIDToken(ctx, req.Storage, roleName, role, entityID, nonce)
+	switch err.(type) {
+	case nil:
+	case errutil.UserError:
+		return oidcProviderErrorResponse("invalid_request", err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	return oidcProviderRawResponse(http.StatusOK, map[string]interface{}{
+		"access_token": idToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(role.TokenTTL.Seconds()),
+	})
+}
+
+// pollDeviceGrant returns the approved device grant of the device code, or
+// the error response telling the client why it cannot be redeemed yet.
+func (i *IdentityStore) pollDeviceGrant(ns *namespace.Namespace, deviceCode, clientID string) (*logical.Response, *oidcDeviceGrant) {
+	i.oidcGrantsLock.Lock()
+	defer i.oidcGrantsLock.Unlock()
+
+	key := oidcGrantKey(ns, "device", deviceCode)
+	raw, ok := i.oidcGrants.Get(key)
+	if !ok {
+		return oidcProviderErrorResponse("expired_token", "invalid or expired device code"), nil
+	}
+	grant := raw.(*oidcDeviceGrant)
+
+	now := time.Now()
+	switch {
+	case grant.clientID != clientID:
+		return oidcProviderErrorResponse("invalid_grant", "device code was issued to another client"), nil
+	case now.After(grant.expiresAt):
+		i.deleteDeviceGrant(ns, deviceCode, grant)
+		return oidcProviderErrorResponse("expired_token", "invalid or expired device code"), nil
+	case grant.denied:
+		i.deleteDeviceGrant(ns, deviceCode, grant)
+		return oidcProviderErrorResponse("access_denied", "the user denied the request"), nil
+	case grant.entityID != "":
+		i.deleteDeviceGrant(ns, deviceCode, grant)
+		return nil, grant
+	case now.Sub(grant.lastPoll) < grant.interval:
+		grant.lastPoll = now
+		grant.interval += oidcDevicePollInterval
+		return oidcProviderErrorResponse("slow_down", "polling too frequently"), nil
+	default:
+		grant.lastPoll = now
+		return oidcProviderErrorResponse("authorization_pending", "the user has not approved the request yet"), nil
+	}
+}
+
+// deleteDeviceGrant removes a device grant and its user code. The grants
+// lock must be held.
+func (i *IdentityStore) deleteDeviceGrant(ns *namespace.Namespace, deviceCode string, grant *oidcDeviceGrant) {
+	i.oidcGrants.Delete(oidcGrantKey(ns, "device", deviceCode))
+	i.oidcGrants.Delete(oidcGrantKey(ns, "user_code", grant.userCode))
+}
+
+// getOIDCRoleByClientID returns the role with the given client ID and its
+// name, or a nil role if there is none.
+func (i *IdentityStore) getOIDCRoleByClientID(ctx context.Context, s logical.Storage, clientID string) (string, *role, error) {
+	if clientID == "" {
+		return "", nil, nil
+	}
+
+	roleNames, err := s.List(ctx, roleConfigPath)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, roleName := range roleNames {
+		role, err := i.getOIDCRole(ctx, s, roleName)
+		if err != nil {
+			return "", nil, err
+		}
+		if role != nil && role.ClientID == clientID {
+			return roleName, role, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against the challenge of
+// the authorization request, per RFC 7636 section 4.6.
+func verifyCodeChallenge(verifier, challenge, method string) bool {
+	if !codeVerifierRegex.MatchString(verifier) {
+		return false
+	}
+	if method == codeChallengeMethodS256 {
+		sum := sha256.Sum256([]byte(verifier))
+		verifier = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(verifier), []byte(challenge)) == 1
+}
+
+func generateUserCode() (string, error) {
+	// Bytes past the largest multiple of the charset size are skipped, so
+	// that every character is equally likely
+	limit := 256 - 256%len(userCodeCharset)
+	code := make([]byte, 0, userCodeLength)
+	buf := make([]byte, userCodeLength)
+	for len(code) < userCodeLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < userCodeLength {
+				code = append(code, userCodeCharset[int(b)%len(userCodeCharset)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+func oidcGrantKey(ns *namespace.Namespace, kind, id string) string {
+	return fmt.Sprintf("%s:%s:%s", ns.ID, kind, id)
+}
+
+// oidcProviderErrorResponse returns an OAuth 2.0 error response, RFC 6749
+// section 5.2.
+func oidcProviderErrorResponse(code, description string) *logical.Response {
+	resp, _ := oidcProviderRawResponse(http.StatusBadRequest, map[string]interface{}{
+		"error":             code,
+		"error_description": description,
+	})
+	return resp
+}
+
+func oidcProviderRawResponse(status int, body map[string]interface{}) (*logical.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:      status,
+			logical.HTTPRawBody:         data,
+			logical.HTTPContentType:     "application/json",
+			logical.HTTPRawCacheControl: "no-store",
+		},
+	}, nil
+}
@@ -0,0 +1,221 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+func testOIDCProviderSetup(t *testing.T) (*Core, logical.Storage, string) {
+	c, _, _ := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+	storage := &logical.InmemStorage{}
+
+	testEntity := &identity.Entity{
+		Name:      "test-entity-name",
+		ID:        "test-entity-id",
+		BucketKey: "test-entity-bucket-key",
+	}
+	txn := c.identityStore.db.Txn(true)
+	defer txn.Abort()
+	if err := c.identityStore.upsertEntityInTxn(ctx, txn, testEntity, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	txn.Commit()
+
+	c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/key/test-key",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"allowed_client_ids": "*",
+		},
+		Storage: storage,
+	})
+	resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/role/test-role",
+		Operation: logical.CreateOperation,
+		Data: map[string]interface{}{
+			"key":           "test-key",
+			"client_id":     "test-client",
+			"redirect_uris": "https://app.example.com/callback",
+		},
+		Storage: storage,
+	})
+	expectSuccess(t, resp, err)
+
+	return c, storage, "test-entity-id"
+}
+
+func testOIDCProviderBody(t *testing.T, resp *logical.Response) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestOIDC_Provider_AuthorizationCode(t *testing.T) {
+	c, storage, entityID := testOIDCProviderSetup(t)
+	ctx := namespace.RootContext(nil)
+
+	verifier := strings.Repeat("v", 43)
+	sum := sha256.Sum256([]byte(verifier))
+	authorize := func(data map[string]interface{}) (*logical.Response, error) {
+		req := map[string]interface{}{
+			"client_id":             "test-client",
+			"redirect_uri":          "https://app.example.com/callback",
+			"response_type":         "code",
+			"scope":                 "openid",
+			"state":                 "test-state",
+			"nonce":                 "test-nonce",
+			"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+			"code_challenge_method": "S256",
+		}
+		for k, v := range data {
+			req[k] = v
+		}
+		return c.identityStore.HandleRequest(ctx, &logical.Request{
+			Path:      "oidc/provider/authorize/test-role",
+			Operation: logical.UpdateOperation,
+			Data:      req,
+			Storage:   storage,
+			EntityID:  entityID,
+		})
+	}
+
+	// Redirect URIs not listed by the role are refused
+	resp, err := authorize(map[string]interface{}{"redirect_uri": "https://evil.example.com/callback"})
+	expectError(t, resp, err)
+
+	// The code challenge is required
+	resp, err = authorize(map[string]interface{}{"code_challenge": ""})
+	expectError(t, resp, err)
+
+	exchange := func(code, codeVerifier string) map[string]interface{} {
+		resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+			Path:      "oidc/provider/token",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"grant_type":    "authorization_code",
+				"client_id":     "test-client",
+				"redirect_uri":  "https://app.example.com/callback",
+				"code":          code,
+				"code_verifier": codeVerifier,
+			},
+			Storage: storage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return testOIDCProviderBody(t, resp)
+	}
+
+	// A wrong verifier fails, and uses up the code
+	resp, err = authorize(nil)
+	expectSuccess(t, resp, err)
+	if resp.Data["state"] != "test-state" {
+		t.Fatalf("bad state: %#v", resp.Data)
+	}
+	code := resp.Data["code"].(string)
+	if body := exchange(code, strings.Repeat("w", 43)); body["error"] != "invalid_grant" {
+		t.Fatalf("expected invalid_grant, got %#v", body)
+	}
+	if body := exchange(code, verifier); body["error"] != "invalid_grant" {
+		t.Fatalf("expected code to be single use, got %#v", body)
+	}
+
+	resp, err = authorize(nil)
+	expectSuccess(t, resp, err)
+	body := exchange(resp.Data["code"].(string), verifier)
+	if body["error"] != nil {
+		t.Fatalf("unexpected error: %#v", body)
+	}
+
+	parsed, err := jwt.ParseSigned(body["id_token"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{}
+	if err := parsed.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["nonce"] != "test-nonce" || claims["sub"] != entityID || claims["aud"] != "test-client" {
+		t.Fatalf("bad claims: %#v", claims)
+	}
+}
+
+func TestOIDC_Provider_DeviceGrant(t *testing.T) {
+	c, storage, entityID := testOIDCProviderSetup(t)
+	ctx := namespace.RootContext(nil)
+
+	resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/provider/device",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"client_id": "test-client",
+		},
+		Storage: storage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := testOIDCProviderBody(t, resp)
+	deviceCode := device["device_code"].(string)
+	userCode := device["user_code"].(string)
+	if !strings.HasSuffix(device["verification_uri"].(string), "/provider/verify/test-role") {
+		t.Fatalf("bad verification URI: %#v", device)
+	}
+
+	poll := func() map[string]interface{} {
+		resp, err := c.identityStore.HandleRequest(ctx, &logical.Request{
+			Path:      "oidc/provider/token",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+				"client_id":   "test-client",
+				"device_code": deviceCode,
+			},
+			Storage: storage,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return testOIDCProviderBody(t, resp)
+	}
+
+	if body := poll(); body["error"] != "authorization_pending" {
+		t.Fatalf("expected authorization_pending, got %#v", body)
+	}
+	if body := poll(); body["error"] != "slow_down" {
+		t.Fatalf("expected slow_down, got %#v", body)
+	}
+
+	// User codes are accepted regardless of case and separators
+	resp, err = c.identityStore.HandleRequest(ctx, &logical.Request{
+		Path:      "oidc/provider/verify/test-role",
+		Operation: logical.UpdateOperation,
+		Data: map[string]interface{}{
+			"user_code": strings.ToLower(strings.Replace(userCode, "-", "", 1)),
+		},
+		Storage:  storage,
+		EntityID: entityID,
+	})
+	expectSuccess(t, resp, err)
+
+	// Once approved, the token is returned regardless of the polling interval
+	body := poll()
+	if body["error"] != nil || body["id_token"] == nil {
+		t.Fatalf("expected ID token, got %#v", body)
+	}
+	if body := poll(); body["error"] != "expired_token" {
+		t.Fatalf("expected device code to be single use, got %#v", body)
+	}
+}
@@ -51,10 +51,11 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected := map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(86400),
-		"template":  "",
-		"client_id": resp.Data["client_id"],
+		"key":           "test-key",
+		"ttl":           int64(86400),
+		"template":      "",
+		"client_id":     resp.Data["client_id"],
+		"redirect_uris": []string{},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
@@ -65,9 +66,10 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 		Path:      "oidc/role/test-role1",
 		Operation: logical.UpdateOperation,
 		Data: map[string]interface{}{
-			"template":  "{\"some-key\":\"some-value\"}",
-			"ttl":       "2h",
-			"client_id": "my_custom_id",
+			"template":      "{\"some-key\":\"some-value\"}",
+			"ttl":           "2h",
+			"client_id":     "my_custom_id",
+			"redirect_uris": "https://app.example.com/callback",
 		},
 		Storage: storage,
 	})
@@ -81,10 +83,11 @@ func TestOIDC_Path_OIDCRoleRole(t *testing.T) {
 	})
 	expectSuccess(t, resp, err)
 	expected = map[string]interface{}{
-		"key":       "test-key",
-		"ttl":       int64(7200),
-		"template":  "{\"some-key\":\"some-value\"}",
-		"client_id": "my_custom_id",
+		"key":           "test-key",
+		"ttl":           int64(7200),
+		"template":      "{\"some-key\":\"some-value\"}",
+		"client_id":     "my_custom_id",
+		"redirect_uris": []string{"https://app.example.com/callback"},
 	}
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
 		t.Fatal(diff)
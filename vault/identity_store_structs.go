@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/vault/helper/storagepacker"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/patrickmn/go-cache"
 )
 
 const (
@@ -63,6 +64,17 @@ type IdentityStore struct {
 	// will invalidate the cache.
 	oidcCache *oidcCache
 
+	// oidcGrants holds the pending authorization codes and device grants
+	// of the OIDC provider until they are redeemed or expire, guarded by
+	// oidcGrantsLock
+	oidcGrants     *cache.Cache
+	oidcGrantsLock sync.Mutex
+
+	// mfaUsedCodes holds the TOTP passcodes of MFA methods which were already
+	// accepted, with the ID of the envelope request they were accepted for,
+	// until they expire
+	mfaUsedCodes *cache.Cache
+
 	// logger is the server logger copied over from core
 	logger log.Logger
 
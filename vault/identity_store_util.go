@@ -1887,6 +1887,19 @@ func (i *IdentityStore) MemDBGroupByAliasID(aliasID string, clone bool) (*identi
 }
 
 func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(ctx context.Context, entityID string, groupAliases []*logical.Alias) ([]*logical.Alias, error) {
+	mountAccessor := ""
+	if len(groupAliases) != 0 {
+		mountAccessor = groupAliases[0].MountAccessor
+	}
+
+	return i.refreshExternalGroupMembershipsByEntityIDAndMount(ctx, entityID, mountAccessor, groupAliases)
+}
+
+// refreshExternalGroupMembershipsByEntityIDAndMount makes the entity a
+// member of the external groups of the group aliases, and removes it from
+// the other external groups of the mount. If the mount accessor is empty,
+// the entity is removed from the other external groups of all mounts.
+func (i *IdentityStore) refreshExternalGroupMembershipsByEntityIDAndMount(ctx context.Context, entityID, mountAccessor string, groupAliases []*logical.Alias) ([]*logical.Alias, error) {
 	defer metrics.MeasureSince([]string{"identity", "refresh_external_groups"}, time.Now())
 
 	if entityID == "" {
@@ -1908,11 +1921,6 @@ func (i *IdentityStore) refreshExternalGroupMembershipsByEntityID(ctx context.Co
 			return false, nil, err
 		}
 
-		mountAccessor := ""
-		if len(groupAliases) != 0 {
-			mountAccessor = groupAliases[0].MountAccessor
-		}
-
 		var newGroups []*identity.Group
 		var validAliases []*logical.Alias
 		for _, alias := range groupAliases {
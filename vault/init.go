@@ -431,6 +431,12 @@ func (c *Core) UnsealWithStoredKeys(ctx context.Context) error {
 		return nil
 	}
 
+	// Pick up an online seal migration performed since the last attempt, as
+	// the stored keys are then wrapped by the new seal
+	if err := c.checkSealMigration(ctx); err != nil {
+		return NewNonFatalError(errwrap.Wrapf("checking for seal migration failed: {{err}}", err))
+	}
+
 	c.Logger().Info("stored unseal keys supported, attempting fetch")
 	keys, err := c.seal.GetStoredKeys(ctx)
 	if err != nil {
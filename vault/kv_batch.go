@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// kvBatchPath is the path, relative to a KV version 2 mount, of the
+	// batch read endpoint. It is served by core rather than the backend, so
+	// that each item is checked against the caller's ACL.
+	kvBatchPath = "batch"
+
+	// kvBatchDeletePath is the path, relative to a KV version 2 mount, of
+	// the recursive delete endpoint. Like the batch read endpoint, it is
+	// served by core and each secret it touches is a separate request.
+	kvBatchDeletePath = "batch-delete"
+
+	// kvDiffPathPrefix is the prefix, relative to a KV version 2 mount, of
+	// the diff endpoint of each secret. Like the batch endpoints, it is
+	// served by core and each version it compares is a separate request.
+	kvDiffPathPrefix = "diff/"
+)
+
+// KVv2BatchMount returns the path of the KV version 2 mount whose batch
+// endpoint the given path refers to, or an empty string if the path is not
+// a batch endpoint.
+func (c *Core) KVv2BatchMount(ctx context.Context, path string) string {
+	return c.kvv2EndpointMount(ctx, path, kvBatchPath)
+}
+
+// KVv2BatchDeleteMount returns the path of the KV version 2 mount whose
+// recursive delete endpoint the given path refers to, or an empty string if
+// the path is not a recursive delete endpoint.
+func (c *Core) KVv2BatchDeleteMount(ctx context.Context, path string) string {
+	return c.kvv2EndpointMount(ctx, path, kvBatchDeletePath)
+}
+
+// KVv2DiffMount returns the path of the KV version 2 mount whose diff
+// endpoint the given path refers to, or an empty string if the path is not
+// under the diff endpoint.
+func (c *Core) KVv2DiffMount(ctx context.Context, path string) string {
+	entry := c.kvv2MountEntry(ctx, path)
+	if entry == nil || !strings.HasPrefix(path, entry.Path+kvDiffPathPrefix) {
+		return ""
+	}
+	return entry.Path
+}
+
+func (c *Core) kvv2EndpointMount(ctx context.Context, path, endpoint string) string {
+	entry := c.kvv2MountEntry(ctx, path)
+	if entry == nil || path != entry.Path+endpoint {
+		return ""
+	}
+	return entry.Path
+}
+
+// kvv2MountEntry returns the entry of the KV version 2 mount the given path
+// belongs to, or nil if it does not belong to one.
+func (c *Core) kvv2MountEntry(ctx context.Context, path string) *MountEntry {
+	entry := c.router.MatchingMountEntry(ctx, path)
+	if entry == nil || entry.Type != "kv" || entry.Options["version"] != "2" {
+		return nil
+	}
+	return entry
+}
@@ -0,0 +1,18 @@
+package vault
+
+import (
+	"context"
+)
+
+// KVMount returns the path and version of the KV mount the given path is
+// in, or an empty path if it is not in a KV mount.
+func (c *Core) KVMount(ctx context.Context, path string) (string, int) {
+	entry := c.router.MatchingMountEntry(ctx, path)
+	if entry == nil || entry.Type != "kv" {
+		return "", 0
+	}
+	if entry.Options["version"] == "2" {
+		return entry.Path, 2
+	}
+	return entry.Path, 1
+}
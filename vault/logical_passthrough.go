@@ -160,6 +160,14 @@ func (b *PassthroughBackend) handleRead(ctx context.Context, req *logical.Reques
 
 	resp.Secret.TTL = ttlDuration
 
+	// Check if there is a lease_tags key, tagging the leases of the secret
+	if tagsRaw, ok := rawData["lease_tags"].(map[string]interface{}); ok && b.generateLeases {
+		resp.Secret.Tags = make(map[string]string, len(tagsRaw))
+		for key, value := range tagsRaw {
+			resp.Secret.Tags[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
 	return resp, nil
 }
 
@@ -249,4 +257,7 @@ can be used as a hint from the writer of a secret to the consumer of a secret
 that the consumer should re-read the value before the TTL has expired.
 However, any revocation must be handled by the user of this backend; the lease
 duration does not affect the provided data in any way.
+
+Leases can be tagged by writing a map of tags in the "lease_tags" field, so
+that they can be looked up and revoked by tag.
 `
@@ -23,6 +23,7 @@ import (
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-multierror"
 	uuid "github.com/hashicorp/go-uuid"
+	goversion "github.com/hashicorp/go-version"
 	"github.com/hashicorp/vault/helper/hostutil"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/metricsutil"
@@ -34,9 +35,11 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/pluginruntime"
 	"github.com/mitchellh/mapstructure"
 )
 
@@ -82,6 +85,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"remount",
 				"audit",
 				"audit/*",
+				"audit-hash/rotate",
 				"raw",
 				"raw/*",
 				"replication/primary/secondary-token",
@@ -91,7 +95,9 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"replication/dr/reindex",
 				"replication/performance/reindex",
 				"rotate",
+				"seal-migrate",
 				"config/cors",
+				"config/cache",
 				"config/auditing/*",
 				"config/ui/headers/*",
 				"plugins/catalog/*",
@@ -99,7 +105,11 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"revoke-force/*",
 				"leases/revoke-prefix/*",
 				"leases/revoke-force/*",
+				"leases/revoke-by-tag",
 				"leases/lookup/*",
+				"config/wrapping",
+				"loggers",
+				"loggers/*",
 			},
 
 			Unauthenticated: []string{
@@ -159,8 +169,17 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.pprofPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.remountPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.metricsPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.metricsCollectorsPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.metricsStreamPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.eventsSubscribePath())
 	b.Backend.Paths = append(b.Backend.Paths, b.monitorPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.hostInfoPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.monitorProfilesPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.loggersPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.namespacesPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.mirrorPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.controlGroupPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, b.rawPaths()...)
@@ -245,6 +264,75 @@ func (b *SystemBackend) handleCORSDelete(ctx context.Context, req *logical.Reque
 	return nil, b.Core.corsConfig.Disable(ctx)
 }
 
+// handleCacheConfigRead returns the bounds and usage of the physical cache
+func (b *SystemBackend) handleCacheConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	cache, ok := b.Core.tunableCache()
+	if !ok {
+		return logical.ErrorResponse("the storage cache cannot be configured"), nil
+	}
+
+	config := cache.CacheConfig()
+	stats := cache.CacheStats()
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":         !b.Core.cachingDisabled,
+			"max_entries":     config.MaxEntries,
+			"max_bytes":       config.MaxBytes,
+			"pinned_prefixes": config.PinnedPrefixes,
+			"entries":         stats.Entries,
+			"bytes":           stats.Bytes,
+			"pinned_entries":  stats.PinnedEntries,
+			"pinned_bytes":    stats.PinnedBytes,
+			"hits":            stats.Hits,
+			"misses":          stats.Misses,
+			"evictions":       stats.Evictions,
+		},
+	}, nil
+}
+
+// handleCacheConfigUpdate overrides the bounds of the physical cache of the
+// server configuration. Parameters which are not given keep their current
+// override.
+func (b *SystemBackend) handleCacheConfigUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if _, ok := b.Core.tunableCache(); !ok {
+		return logical.ErrorResponse("the storage cache cannot be configured"), nil
+	}
+
+	entry := &CacheConfigEntry{}
+	b.Core.physicalCacheLock.Lock()
+	if current := b.Core.physicalCacheOverrides; current != nil {
+		*entry = *current
+	}
+	b.Core.physicalCacheLock.Unlock()
+
+	if maxEntriesRaw, ok := d.GetOk("max_entries"); ok {
+		entry.MaxEntries = maxEntriesRaw.(int)
+	}
+	if maxBytesRaw, ok := d.GetOk("max_bytes"); ok {
+		entry.MaxBytes = int64(maxBytesRaw.(int))
+	}
+	if entry.MaxEntries < 0 || entry.MaxBytes < 0 {
+		return logical.ErrorResponse("max_entries and max_bytes cannot be negative"), logical.ErrInvalidRequest
+	}
+
+	if pinnedRaw, ok := d.GetOk("pinned_prefixes"); ok {
+		entry.PinnedPrefixes = []string{}
+		for _, prefix := range pinnedRaw.([]string) {
+			if prefix != "" {
+				entry.PinnedPrefixes = append(entry.PinnedPrefixes, prefix)
+			}
+		}
+	}
+
+	return nil, b.Core.saveCacheConfig(ctx, entry)
+}
+
+// handleCacheConfigDelete removes the overrides of the bounds of the physical
+// cache
+func (b *SystemBackend) handleCacheConfigDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	return nil, b.Core.saveCacheConfig(ctx, nil)
+}
+
 func (b *SystemBackend) handleTidyLeases(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
@@ -339,12 +427,36 @@ func (b *SystemBackend) handlePluginCatalogUpdate(ctx context.Context, req *logi
 
 	env := d.Get("env").([]string)
 
+	version := d.Get("version").(string)
+	if version != "" {
+		if pluginType == consts.PluginTypeUnknown {
+			return logical.ErrorResponse("type must be provided to register a plugin version"), nil
+		}
+		if _, err := goversion.NewSemver(version); err != nil {
+			return logical.ErrorResponse(ErrPluginBadVersion.Error()), nil
+		}
+	}
+
+	var runtime *pluginutil.PluginRuntimeConfig
+	if runtimeRaw, ok := d.GetOk("runtime"); ok {
+		runtime = new(pluginutil.PluginRuntimeConfig)
+		if err := mapstructure.WeakDecode(runtimeRaw, runtime); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid runtime: %s", err)), nil
+		}
+		if err := pluginruntime.Validate(runtime); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		if pluginType != consts.PluginTypeCredential && pluginType != consts.PluginTypeSecrets {
+			return logical.ErrorResponse(ErrPluginRuntimeBadType.Error()), nil
+		}
+	}
+
 	sha256Bytes, err := hex.DecodeString(sha256)
 	if err != nil {
 		return logical.ErrorResponse("Could not decode SHA-256 value from Hex"), err
 	}
 
-	err = b.Core.pluginCatalog.Set(ctx, pluginName, pluginType, parts[0], args, env, sha256Bytes)
+	err = b.Core.pluginCatalog.Set(ctx, pluginName, pluginType, version, parts[0], args, env, sha256Bytes, runtime)
 	if err != nil {
 		return nil, err
 	}
@@ -373,7 +485,7 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		return nil, err
 	}
 
-	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginType)
+	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginType, d.Get("version").(string))
 	if err != nil {
 		return nil, err
 	}
@@ -381,6 +493,11 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		return nil, nil
 	}
 
+	versions, err := b.Core.pluginCatalog.ListVersions(ctx, pluginName, pluginType)
+	if err != nil {
+		return nil, err
+	}
+
 	command := ""
 	if !plugin.Builtin {
 		command, err = filepath.Rel(b.Core.pluginCatalog.directory, plugin.Command)
@@ -396,6 +513,20 @@ func (b *SystemBackend) handlePluginCatalogRead(ctx context.Context, req *logica
 		"sha256":  hex.EncodeToString(plugin.Sha256),
 		"builtin": plugin.Builtin,
 	}
+	if plugin.Version != "" {
+		data["version"] = plugin.Version
+	}
+	if len(versions) > 0 {
+		data["versions"] = versions
+	}
+	if plugin.Runtime != nil {
+		data["runtime"] = map[string]interface{}{
+			"type":         plugin.Runtime.Type,
+			"image":        plugin.Runtime.Image,
+			"cpus":         plugin.Runtime.CPUs,
+			"memory_bytes": plugin.Runtime.MemoryBytes,
+		}
+	}
 
 	return &logical.Response{
 		Data: data,
@@ -424,13 +555,31 @@ func (b *SystemBackend) handlePluginCatalogDelete(ctx context.Context, req *logi
 	if err != nil {
 		return nil, err
 	}
-	if err := b.Core.pluginCatalog.Delete(ctx, pluginName, pluginType); err != nil {
+
+	version := d.Get("version").(string)
+	if version != "" && pluginType == consts.PluginTypeUnknown {
+		return logical.ErrorResponse("type must be provided to deregister a plugin version"), nil
+	}
+	if err := b.Core.pluginCatalog.Delete(ctx, pluginName, pluginType, version); err != nil {
 		return nil, err
 	}
 
 	return resp, nil
 }
 
+// checkPluginVersion verifies a version of the plugin of a mount is
+// registered in the catalog.
+func (b *SystemBackend) checkPluginVersion(ctx context.Context, pluginName string, pluginType consts.PluginType, version string) error {
+	plugin, err := b.Core.pluginCatalog.Get(ctx, pluginName, pluginType, version)
+	if err != nil {
+		return err
+	}
+	if plugin == nil {
+		return fmt.Errorf("plugin %q version %q not found in the catalog", pluginName, version)
+	}
+	return nil
+}
+
 func (b *SystemBackend) handlePluginReloadUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	pluginName := d.Get("plugin").(string)
 	pluginMounts := d.Get("mounts").([]string)
@@ -687,6 +836,9 @@ func mountInfo(entry *MountEntry) map[string]interface{} {
 	if len(entry.Config.ListingVisibility) > 0 {
 		entryConfig["listing_visibility"] = entry.Config.ListingVisibility
 	}
+	if entry.Config.GroupSyncInterval > 0 {
+		entryConfig["group_sync_interval"] = int64(entry.Config.GroupSyncInterval.Seconds())
+	}
 	if rawVal, ok := entry.synthesizedConfigCache.Load("passthrough_request_headers"); ok {
 		entryConfig["passthrough_request_headers"] = rawVal.([]string)
 	}
@@ -696,6 +848,9 @@ func mountInfo(entry *MountEntry) map[string]interface{} {
 	if entry.Table == credentialTableType {
 		entryConfig["token_type"] = entry.Config.TokenType.String()
 	}
+	if entry.Config.PluginVersion != "" {
+		entryConfig["plugin_version"] = entry.Config.PluginVersion
+	}
 
 	info["config"] = entryConfig
 
@@ -880,6 +1035,12 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 	if len(apiConfig.AllowedResponseHeaders) > 0 {
 		config.AllowedResponseHeaders = apiConfig.AllowedResponseHeaders
 	}
+	if apiConfig.PluginVersion != "" {
+		if err := b.checkPluginVersion(ctx, logicalType, consts.PluginTypeSecrets, apiConfig.PluginVersion); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.PluginVersion = apiConfig.PluginVersion
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -1089,8 +1250,15 @@ func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (
 		resp.Data["external_entropy_access"] = true
 	}
 
+	if mountEntry.Config.PluginVersion != "" {
+		resp.Data["plugin_version"] = mountEntry.Config.PluginVersion
+	}
+
 	if mountEntry.Table == credentialTableType {
 		resp.Data["token_type"] = mountEntry.Config.TokenType.String()
+		if mountEntry.Config.GroupSyncInterval > 0 {
+			resp.Data["group_sync_interval"] = int64(mountEntry.Config.GroupSyncInterval.Seconds())
+		}
 	}
 
 	if rawVal, ok := mountEntry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
@@ -1370,6 +1538,30 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("group_sync_interval"); ok {
+		if !strings.HasPrefix(path, "auth/") {
+			return logical.ErrorResponse(fmt.Sprintf("'group_sync_interval' can only be modified on auth mounts")), logical.ErrInvalidRequest
+		}
+
+		interval, err := parseGroupSyncInterval(rawVal.(string))
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.GroupSyncInterval
+		mountEntry.Config.GroupSyncInterval = interval
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.GroupSyncInterval = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of group_sync_interval successful", "path", path, "group_sync_interval", interval)
+		}
+	}
+
 	if rawVal, ok := data.GetOk("passthrough_request_headers"); ok {
 		headers := rawVal.([]string)
 
@@ -1422,6 +1614,42 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("plugin_version"); ok {
+		version := rawVal.(string)
+		if version != "" {
+			if err := b.checkPluginVersion(ctx, mountEntry.pluginName(), mountEntry.pluginType(), version); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		oldVal := mountEntry.Config.PluginVersion
+		mountEntry.Config.PluginVersion = version
+
+		// Update the mount table
+		var err error
+		switch {
+		case strings.HasPrefix(path, "auth/"):
+			err = b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local)
+		default:
+			err = b.Core.persistMounts(ctx, b.Core.mounts, &mountEntry.Local)
+		}
+		if err != nil {
+			mountEntry.Config.PluginVersion = oldVal
+			return handleError(err)
+		}
+
+		// Restart the backend with the plugin of the new version
+		if oldVal != version {
+			if err := b.Core.reloadBackendCommon(ctx, mountEntry, strings.HasPrefix(path, credentialRoutePrefix)); err != nil {
+				return handleError(err)
+			}
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of plugin_version successful", "path", path, "plugin_version", version)
+		}
+	}
+
 	var err error
 	var resp *logical.Response
 	var options map[string]string
@@ -1536,6 +1764,9 @@ func (b *SystemBackend) handleLeaseLookup(ctx context.Context, req *logical.Requ
 			"ttl":          int64(0),
 		},
 	}
+	if leaseTimes.Secret != nil && len(leaseTimes.Secret.Tags) > 0 {
+		resp.Data["tags"] = leaseTimes.Secret.Tags
+	}
 	renewable, _ := leaseTimes.renewable()
 	resp.Data["renewable"] = renewable
 
@@ -1668,6 +1899,49 @@ func (b *SystemBackend) handleRevokePrefixCommon(ctx context.Context,
 	return logical.RespondWithStatusCode(nil, nil, http.StatusAccepted)
 }
 
+// handleLeaseLookupByTag is used to list the leases carrying the given tags
+func (b *SystemBackend) handleLeaseLookupByTag(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	tags := data.Get("tags").(map[string]string)
+	if len(tags) == 0 {
+		return logical.ErrorResponse("tags must be specified"), logical.ErrInvalidRequest
+	}
+
+	leaseIDs, err := b.Core.expiration.LookupLeasesByTags(ctx, tags)
+	if err != nil {
+		b.Backend.Logger().Error("error looking up leases by tag", "error", err)
+		return handleErrorNoReadOnlyForward(err)
+	}
+
+	return logical.ListResponse(leaseIDs), nil
+}
+
+// handleRevokeByTag is used to revoke all the leases carrying the given tags
+func (b *SystemBackend) handleRevokeByTag(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	tags := data.Get("tags").(map[string]string)
+	if len(tags) == 0 {
+		return logical.ErrorResponse("tags must be specified"), logical.ErrInvalidRequest
+	}
+	sync := data.Get("sync").(bool)
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invoke the expiration manager directly
+	revokeCtx := namespace.ContextWithNamespace(b.Core.activeContext, ns)
+	if _, err := b.Core.expiration.RevokeByTags(revokeCtx, tags, sync); err != nil {
+		b.Backend.Logger().Error("revoke by tag failed", "error", err)
+		return handleErrorNoReadOnlyForward(err)
+	}
+
+	if sync {
+		return nil, nil
+	}
+
+	return logical.RespondWithStatusCode(nil, nil, http.StatusAccepted)
+}
+
 // handleAuthTable handles the "auth" endpoint to provide the auth table
 func (b *SystemBackend) handleAuthTable(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	ns, err := namespace.FromContext(ctx)
@@ -1833,6 +2107,19 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 	if len(apiConfig.AllowedResponseHeaders) > 0 {
 		config.AllowedResponseHeaders = apiConfig.AllowedResponseHeaders
 	}
+	if apiConfig.GroupSyncInterval != "" {
+		interval, err := parseGroupSyncInterval(apiConfig.GroupSyncInterval)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.GroupSyncInterval = interval
+	}
+	if apiConfig.PluginVersion != "" {
+		if err := b.checkPluginVersion(ctx, logicalType, consts.PluginTypeCredential, apiConfig.PluginVersion); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.PluginVersion = apiConfig.PluginVersion
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -2066,6 +2353,146 @@ func (b *SystemBackend) handlePoliciesDelete(policyType PolicyType) framework.Op
 	}
 }
 
+// handlePoliciesTest handles the "/sys/policies/acl/<name>/test" endpoint to
+// evaluate an ACL policy against a hypothetical request
+func (b *SystemBackend) handlePoliciesTest(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := data.Get("name").(string)
+	path := strings.TrimPrefix(data.Get("path").(string), "/")
+	if path == "" {
+		return logical.ErrorResponse("missing path"), logical.ErrInvalidRequest
+	}
+
+	op := logical.Operation(strings.ToLower(data.Get("operation").(string)))
+	switch op {
+	case logical.CreateOperation, logical.ReadOperation, logical.UpdateOperation,
+		logical.DeleteOperation, logical.ListOperation:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported operation %q", op)), logical.ErrInvalidRequest
+	}
+
+	policy, err := b.Core.policyStore.GetPolicy(ctx, name, PolicyTypeACL)
+	if err != nil {
+		return handleError(err)
+	}
+	if policy == nil {
+		return logical.ErrorResponse(fmt.Sprintf("policy %q does not exist", name)), logical.ErrInvalidRequest
+	}
+
+	templated := policy.Templated
+	if templated {
+		entity, groups, err := b.policyTestIdentity(ctx, ns, data)
+		if err != nil {
+			return nil, err
+		}
+		p, err := parseACLPolicyWithTemplating(policy.namespace, policy.Raw, true, entity, groups)
+		if err != nil {
+			return handleError(err)
+		}
+		p.Name = policy.Name
+		policy = p
+	}
+
+	acl, err := NewACL(ctx, []*Policy{policy})
+	if err != nil {
+		return handleError(err)
+	}
+
+	testReq := &logical.Request{
+		Path:      path,
+		Operation: op,
+		Data:      data.Get("parameters").(map[string]interface{}),
+	}
+	if remoteAddr := data.Get("remote_address").(string); remoteAddr != "" {
+		testReq.Connection = &logical.Connection{
+			RemoteAddr: remoteAddr,
+		}
+	}
+	results := acl.AllowOperation(ctx, testReq, false)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"allowed":   results.Allowed,
+			"templated": templated,
+		},
+	}
+	switch {
+	case results.IsRoot:
+		resp.Data["capabilities"] = []string{RootCapability}
+	case results.MatchedPath != "":
+		resp.Data["matched_path"] = strings.TrimPrefix(results.MatchedPath, ns.Path)
+		resp.Data["capabilities"] = capabilitiesFromBitmap(results.CapabilitiesBitmap)
+	}
+	if len(results.MFAMethods) > 0 {
+		resp.Data["mfa_methods"] = results.MFAMethods
+	}
+	return resp, nil
+}
+
+// policyTestIdentity returns the entity and groups the templating of a policy
+// test is performed with. The entity is the existing entity of the given ID,
+// if any, along with its groups, with the given name, metadata and groups
+// added. Groups which do not exist are tested with their name as their ID.
+func (b *SystemBackend) policyTestIdentity(ctx context.Context, ns *namespace.Namespace, data *framework.FieldData) (*identity.Entity, []*identity.Group, error) {
+	entityID := data.Get("entity_id").(string)
+
+	var entity *identity.Entity
+	var groups []*identity.Group
+	if entityID != "" {
+		var err error
+		entity, err = b.Core.identityStore.MemDBEntityByID(entityID, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		if entity != nil {
+			directGroups, inheritedGroups, err := b.Core.identityStore.groupsByEntityID(entity.ID)
+			if err != nil {
+				return nil, nil, errwrap.Wrapf("failed to fetch group memberships: {{err}}", err)
+			}
+			groups = append(directGroups, inheritedGroups...)
+		}
+	}
+	if entity == nil {
+		entity = &identity.Entity{
+			ID:          entityID,
+			NamespaceID: ns.ID,
+		}
+	}
+
+	if entityName := data.Get("entity_name").(string); entityName != "" {
+		entity.Name = entityName
+	}
+	if metadata := data.Get("entity_metadata").(map[string]string); len(metadata) > 0 {
+		if entity.Metadata == nil {
+			entity.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			entity.Metadata[k] = v
+		}
+	}
+
+	for _, groupName := range data.Get("groups").([]string) {
+		group, err := b.Core.identityStore.MemDBGroupByName(ctx, groupName, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if group == nil {
+			group = &identity.Group{
+				ID:          groupName,
+				Name:        groupName,
+				NamespaceID: ns.ID,
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	return entity, groups, nil
+}
+
 type passwordPolicyConfig struct {
 	HCLPolicy string `json:"policy"`
 }
@@ -2287,6 +2714,31 @@ func (b *SystemBackend) handleAuditHash(ctx context.Context, req *logical.Reques
 	}, nil
 }
 
+// handleAuditHashRotate is used to rotate the HMAC key of the given audit
+// backend, or of all of them if no path is given
+func (b *SystemBackend) handleAuditHashRotate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	var paths []string
+	if path := data.Get("path").(string); path != "" {
+		paths = append(paths, sanitizeMountPath(path))
+	} else {
+		b.Core.auditLock.RLock()
+		for _, entry := range b.Core.audit.Entries {
+			paths = append(paths, entry.Path)
+		}
+		b.Core.auditLock.RUnlock()
+	}
+
+	now := time.Now()
+	for _, path := range paths {
+		if err := b.Core.auditBroker.RotateHMACKey(ctx, path, now); err != nil {
+			return handleError(err)
+		}
+		b.Core.logger.Info("rotated audit HMAC key", "path", path)
+	}
+
+	return nil, nil
+}
+
 // handleEnableAudit is used to enable a new audit backend
 func (b *SystemBackend) handleEnableAudit(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.ReplicationState()
@@ -2495,6 +2947,25 @@ func (b *SystemBackend) handleRotate(ctx context.Context, req *logical.Request,
 	return nil, nil
 }
 
+// handleSealMigrate is used to start the pending online seal migration
+func (b *SystemBackend) handleSealMigrate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	status, err := b.Core.startSealMigration()
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"from":       status.From,
+			"to":         status.To,
+			"state":      status.State,
+			"progress":   status.Progress,
+			"total":      status.Total,
+			"start_time": status.StartTime,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) handleWrappingPubkey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	x, _ := b.Core.wrappingJWTKey.X.MarshalText()
 	y, _ := b.Core.wrappingJWTKey.Y.MarshalText()
@@ -2512,6 +2983,61 @@ func (b *SystemBackend) handleWrappingWrap(ctx context.Context, req *logical.Req
 		return logical.ErrorResponse("endpoint requires response wrapping to be used"), logical.ErrInvalidRequest
 	}
 
+	config, err := b.Core.loadWrappingConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The wrapping options are not part of the wrapped data
+	payload := make(map[string]interface{}, len(data.Raw))
+	for k, v := range data.Raw {
+		if _, ok := data.Schema[k]; !ok {
+			payload[k] = v
+		}
+	}
+
+	numUses := data.Get("wrap_num_uses").(int)
+	switch {
+	case numUses < 1:
+		return logical.ErrorResponse("\"wrap_num_uses\" must be at least 1"), logical.ErrInvalidRequest
+	case config.MaxNumUses > 0 && numUses > config.MaxNumUses:
+		return logical.ErrorResponse(fmt.Sprintf("\"wrap_num_uses\" cannot be greater than %d", config.MaxNumUses)), logical.ErrInvalidRequest
+	}
+
+	allowedEntityIDs := data.Get("wrap_allowed_entity_ids").([]string)
+	allowedGroupIDs := data.Get("wrap_allowed_group_ids").([]string)
+	if config.RequireUnwrapConstraints && len(allowedEntityIDs) == 0 && len(allowedGroupIDs) == 0 {
+		return logical.ErrorResponse("\"wrap_allowed_entity_ids\" or \"wrap_allowed_group_ids\" is required"), logical.ErrInvalidRequest
+	}
+	for _, entityID := range allowedEntityIDs {
+		entity, err := b.Core.identityStore.MemDBEntityByID(entityID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown entity ID %q", entityID)), logical.ErrInvalidRequest
+		}
+	}
+	for _, groupID := range allowedGroupIDs {
+		group, err := b.Core.identityStore.MemDBGroupByID(groupID, false)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown group ID %q", groupID)), logical.ErrInvalidRequest
+		}
+	}
+
+	if config.MaxPayloadSize > 0 {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		if len(encoded) > config.MaxPayloadSize {
+			return logical.ErrorResponse(fmt.Sprintf("payload of %d bytes exceeds the maximum of %d bytes", len(encoded), config.MaxPayloadSize)), logical.ErrInvalidRequest
+		}
+	}
+
 	// N.B.: Do *NOT* allow JWT wrapping tokens to be created through this
 	// endpoint. JWTs are signed so if we don't allow users to create wrapping
 	// tokens using them we can ensure that an operator can't spoof a legit JWT
@@ -2520,10 +3046,59 @@ func (b *SystemBackend) handleWrappingWrap(ctx context.Context, req *logical.Req
 	req.WrapInfo.Format = "uuid"
 
 	return &logical.Response{
-		Data: data.Raw,
+		Data: payload,
+		WrapInfo: &wrapping.ResponseWrapInfo{
+			NumUses:          numUses,
+			AllowedEntityIDs: allowedEntityIDs,
+			AllowedGroupIDs:  allowedGroupIDs,
+		},
 	}, nil
 }
 
+// handleWrappingConfigRead returns the policies enforced on data wrapped
+// through sys/wrapping/wrap.
+func (b *SystemBackend) handleWrappingConfigRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadWrappingConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"max_payload_size":           config.MaxPayloadSize,
+			"max_num_uses":               config.MaxNumUses,
+			"require_unwrap_constraints": config.RequireUnwrapConstraints,
+		},
+	}, nil
+}
+
+// handleWrappingConfigUpdate sets the policies enforced on data wrapped
+// through sys/wrapping/wrap.
+func (b *SystemBackend) handleWrappingConfigUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := b.Core.loadWrappingConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxPayloadSizeRaw, ok := data.GetOk("max_payload_size"); ok {
+		config.MaxPayloadSize = maxPayloadSizeRaw.(int)
+	}
+	if maxNumUsesRaw, ok := data.GetOk("max_num_uses"); ok {
+		config.MaxNumUses = maxNumUsesRaw.(int)
+	}
+	if requireRaw, ok := data.GetOk("require_unwrap_constraints"); ok {
+		config.RequireUnwrapConstraints = requireRaw.(bool)
+	}
+	if config.MaxPayloadSize < 0 {
+		return logical.ErrorResponse("\"max_payload_size\" cannot be negative"), nil
+	}
+	if config.MaxNumUses < 0 {
+		return logical.ErrorResponse("\"max_num_uses\" cannot be negative"), nil
+	}
+
+	return nil, b.Core.saveWrappingConfig(ctx, config)
+}
+
 // handleWrappingUnwrap will unwrap a response wrapping token or complete a
 // request that required a control group.
 func (b *SystemBackend) handleWrappingUnwrap(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
@@ -2568,7 +3143,7 @@ func (b *SystemBackend) handleWrappingUnwrap(ctx context.Context, req *logical.R
 	case controlGroupPolicyName:
 		response, err = controlGroupUnwrap(unwrapCtx, b, token, thirdParty)
 	case responseWrappingPolicyName:
-		response, err = b.responseWrappingUnwrap(unwrapCtx, te, thirdParty)
+		response, err = b.responseWrappingUnwrap(unwrapCtx, te, thirdParty, req.EntityID)
 	}
 	if err != nil {
 		var respErr *logical.Response
@@ -2642,21 +3217,40 @@ func (b *SystemBackend) handleWrappingUnwrap(ctx context.Context, req *logical.R
 
 // responseWrappingUnwrap will read the stored response in the cubbyhole and
 // return the raw HTTP response.
-func (b *SystemBackend) responseWrappingUnwrap(ctx context.Context, te *logical.TokenEntry, thirdParty bool) (string, error) {
+func (b *SystemBackend) responseWrappingUnwrap(ctx context.Context, te *logical.TokenEntry, thirdParty bool, entityID string) (string, error) {
 	tokenID := te.ID
+
+	// Check the restrictions before using the token, so that callers which
+	// are not allowed to unwrap it can't use it up either
+	restrictions, err := b.Core.wrappingRestrictions(ctx, te)
+	if err != nil {
+		return "", err
+	}
+	if restrictions.restricted() {
+		if !thirdParty {
+			return "wrapping token can only be unwrapped by passing it in the \"token\" parameter", logical.ErrPermissionDenied
+		}
+		if err := b.Core.checkUnwrapRestrictions(restrictions, entityID); err != nil {
+			return "", err
+		}
+	}
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
-		_, err := b.Core.tokenStore.UseTokenByID(ctx, tokenID)
+		usedTE, err := b.Core.tokenStore.UseTokenByID(ctx, tokenID)
 		if err != nil {
 			return "", errwrap.Wrapf("error decrementing wrapping token's use-count: {{err}}", err)
 		}
 
-		defer b.Core.tokenStore.revokeOrphan(ctx, tokenID)
+		// Tokens allowing several uses are only revoked on their last one
+		if usedTE.NumUses == tokenRevocationPending {
+			defer b.Core.tokenStore.revokeOrphan(ctx, tokenID)
+		}
 	}
 
 	cubbyReq := &logical.Request{
 		Operation:   logical.ReadOperation,
-		Path:        "cubbyhole/response",
+		Path:        restrictions.responsePath(),
 		ClientToken: tokenID,
 	}
 	cubbyReq.SetTokenEntry(te)
@@ -2694,6 +3288,110 @@ func (b *SystemBackend) handleMetrics(ctx context.Context, req *logical.Request,
 	return b.Core.metricsHelper.ResponseForFormat(format), nil
 }
 
+// handleMetricsStream validates a request to stream metrics. The stream
+// itself is served by the HTTP layer, which upgrades the connection to a
+// WebSocket once this request succeeds.
+func (b *SystemBackend) handleMetricsStream(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	interval := data.Get("interval").(int)
+	if interval < 1 {
+		return logical.ErrorResponse("interval must be at least one second"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"interval": interval,
+		},
+	}, nil
+}
+
+// handleEventsSubscribe validates a request to subscribe to events, and
+// creates or resumes the subscriber. The HTTP layer then upgrades the
+// connection to a WebSocket over which the events of the subscriber are
+// sent. As the request is made to the topic's path, policies control which
+// topics a token may subscribe to. Subscribers only receive the events of
+// their namespace and its children.
+func (b *SystemBackend) handleEventsSubscribe(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	topic := data.Get("topic").(string)
+	if i := strings.Index(topic, "*"); i != -1 && i != len(topic)-1 {
+		return logical.ErrorResponse("topic may only contain '*' as its last character"), logical.ErrInvalidRequest
+	}
+
+	bufferSize := data.Get("buffer_size").(int)
+	if bufferSize < 1 || bufferSize > MaxEventBufferSize {
+		return logical.ErrorResponse(fmt.Sprintf("buffer_size must be between 1 and %d", MaxEventBufferSize)), logical.ErrInvalidRequest
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sub *EventSubscriber
+	if id := data.Get("subscriber_id").(string); id != "" {
+		sub = b.Core.events.Resume(id, topic, req.ClientTokenAccessor, ns)
+		if sub == nil {
+			return logical.ErrorResponse(fmt.Sprintf("unknown subscriber %q for topic %q", id, topic)), logical.ErrInvalidRequest
+		}
+	} else {
+		sub, err = b.Core.events.Subscribe(topic, req.ClientTokenAccessor, ns, bufferSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"subscriber_id": sub.ID,
+		},
+	}, nil
+}
+
+// handleMetricsCollectors reports the status of the running gauge
+// collection processes.
+func (b *SystemBackend) handleMetricsCollectors(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	statuses := b.Core.metricSink.GaugeCollectionStatus()
+
+	collectors := make([]map[string]interface{}, 0, len(statuses))
+	for _, s := range statuses {
+		labels := make(map[string]string, len(s.Labels))
+		for _, l := range s.Labels {
+			labels[l.Name] = l.Value
+		}
+
+		entry := map[string]interface{}{
+			"key":                strings.Join(s.Key, "."),
+			"labels":             labels,
+			"original_interval":  int64(s.OriginalInterval.Seconds()),
+			"current_interval":   int64(s.CurrentInterval.Seconds()),
+			"backoff_multiplier": float64(s.CurrentInterval) / float64(s.OriginalInterval),
+			"error_count":        s.ErrorCount,
+			"last_run":           "",
+			"last_duration_ms":   s.LastDuration.Milliseconds(),
+			"last_status":        "pending",
+			"last_error":         "",
+			"expensive":          s.Expensive,
+			"priority":           s.Priority,
+		}
+		switch {
+		case s.LastRun.IsZero():
+		case s.LastError != nil:
+			entry["last_run"] = s.LastRun.Format(time.RFC3339Nano)
+			entry["last_status"] = "error"
+			entry["last_error"] = s.LastError.Error()
+		default:
+			entry["last_run"] = s.LastRun.Format(time.RFC3339Nano)
+			entry["last_status"] = "ok"
+		}
+		collectors = append(collectors, entry)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"collectors": collectors,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	ll := data.Get("log_level").(string)
 	w := req.ResponseWriter
@@ -2764,6 +3462,12 @@ func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request,
 // system information, cpu, disk, and memory usage. Any capture-related errors
 // returned by the collection method will be returned as response warnings.
 func (b *SystemBackend) handleHostInfo(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	return hostInfoResponse(ctx)
+}
+
+// hostInfoResponse returns the host information of the server, with the
+// capture-related errors as warnings.
+func hostInfoResponse(ctx context.Context) (*logical.Response, error) {
 	resp := &logical.Response{}
 	info, err := hostutil.CollectHostInfo(ctx)
 	if err != nil {
@@ -2876,6 +3580,20 @@ func (b *SystemBackend) handleWrappingLookup(ctx context.Context, req *logical.R
 	if creationPath != nil {
 		resp.Data["creation_path"] = cubbyResp.Data["creation_path"]
 	}
+	if numUses := cubbyResp.Data["num_uses"]; numUses != nil {
+		resp.Data["num_uses"] = numUses
+		remainingUses := te.NumUses
+		if remainingUses < 0 {
+			remainingUses = 0
+		}
+		resp.Data["remaining_uses"] = remainingUses
+	}
+	if allowedEntityIDs := cubbyResp.Data["allowed_entity_ids"]; allowedEntityIDs != nil {
+		resp.Data["allowed_entity_ids"] = allowedEntityIDs
+	}
+	if allowedGroupIDs := cubbyResp.Data["allowed_group_ids"]; allowedGroupIDs != nil {
+		resp.Data["allowed_group_ids"] = allowedGroupIDs
+	}
 
 	return resp, nil
 }
@@ -2906,6 +3624,24 @@ func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.R
 		return nil, errors.New("token is not a valid unwrap token")
 	}
 
+	// The new token carries over the restrictions of the original one, so
+	// rewrapping is held to them too
+	restrictions, err := b.Core.wrappingRestrictions(ctx, te)
+	if err != nil {
+		return nil, err
+	}
+	if restrictions.restricted() {
+		if !thirdParty {
+			return logical.ErrorResponse("wrapping token can only be rewrapped by passing it in the \"token\" parameter"), logical.ErrPermissionDenied
+		}
+		if err := b.Core.checkUnwrapRestrictions(restrictions, req.EntityID); err != nil {
+			return nil, err
+		}
+	}
+
+	// The new token gets the uses the original one has left
+	numUses := te.NumUses
+
 	if thirdParty {
 		// Use the token to decrement the use count to avoid a second operation on the token.
 		_, err := b.Core.tokenStore.UseTokenByID(ctx, token)
@@ -2956,7 +3692,7 @@ func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.R
 	// Fetch the original response and return it as the data for the new response
 	cubbyReq = &logical.Request{
 		Operation:   logical.ReadOperation,
-		Path:        "cubbyhole/response",
+		Path:        restrictions.responsePath(),
 		ClientToken: token,
 	}
 	cubbyReq.SetTokenEntry(te)
@@ -2986,8 +3722,11 @@ func (b *SystemBackend) handleWrappingRewrap(ctx context.Context, req *logical.R
 			"response": response,
 		},
 		WrapInfo: &wrapping.ResponseWrapInfo{
-			TTL:          time.Duration(creationTTL),
-			CreationPath: creationPath,
+			TTL:              time.Duration(creationTTL),
+			CreationPath:     creationPath,
+			NumUses:          numUses,
+			AllowedEntityIDs: restrictions.AllowedEntityIDs,
+			AllowedGroupIDs:  restrictions.AllowedGroupIDs,
 		},
 	}, nil
 }
@@ -3359,6 +4098,61 @@ func (b *SystemBackend) pathInternalCountersEntities(ctx context.Context, req *l
 	return resp, nil
 }
 
+func (b *SystemBackend) pathInternalCountersActivity(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	end := time.Now().UTC()
+	if raw := d.Get("end_time").(string); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return logical.ErrorResponse("invalid end_time: %s", err), logical.ErrInvalidRequest
+		}
+		end = t.UTC()
+	}
+	// By default, report on the current month
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if raw := d.Get("start_time").(string); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return logical.ErrorResponse("invalid start_time: %s", err), logical.ErrInvalidRequest
+		}
+		start = t.UTC()
+	}
+	if start.After(end) {
+		return logical.ErrorResponse("start_time is after end_time"), logical.ErrInvalidRequest
+	}
+
+	format := d.Get("format").(string)
+	switch format {
+	case "json", "csv":
+	default:
+		return logical.ErrorResponse("unsupported format %q", format), logical.ErrInvalidRequest
+	}
+
+	report, err := b.Core.activityReport(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "csv" {
+		buf, err := report.csv()
+		if err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPStatusCode:  200,
+				logical.HTTPRawBody:     buf,
+				logical.HTTPContentType: "text/csv",
+			},
+		}, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"counters": report,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) pathInternalUIResultantACL(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 	if req.ClientToken == "" {
 		// 204 -- no ACL
@@ -3410,6 +4204,9 @@ func (b *SystemBackend) pathInternalUIResultantACL(ctx context.Context, req *log
 		if perms.CapabilitiesBitmap&ListCapabilityInt > 0 {
 			capabilities = append(capabilities, ListCapability)
 		}
+		if perms.CapabilitiesBitmap&ProfileCapabilityInt > 0 {
+			capabilities = append(capabilities, ProfileCapability)
+		}
 		if perms.CapabilitiesBitmap&ReadCapabilityInt > 0 {
 			capabilities = append(capabilities, ReadCapability)
 		}
@@ -3621,6 +4418,24 @@ The path responds to the following HTTP methods.
         Sets the license for the server
 	`,
 	},
+	"config/cache": {
+		"Configures or returns the bounds and usage of the storage cache.",
+		`
+This path responds to the following HTTP methods.
+
+    GET /
+        Returns the bounds of the storage cache, along with its numbers of
+        entries, hits, misses and evictions.
+
+    POST /
+        Overrides the bounds and pinned prefixes of the storage cache of the
+        server configuration.
+
+    DELETE /
+        Removes the overrides, restoring the bounds of the server configuration.
+		`,
+	},
+
 	"config/cors": {
 		"Configures or returns the current configuration of CORS settings.",
 		`
@@ -3890,6 +4705,28 @@ all matching leases.
 		`,
 	},
 
+	"lookup-by-tag": {
+		"List the leases carrying the given tags",
+		`
+Lists the IDs of the leases of the namespace which carry all the given tags,
+attached to the leases by the secrets engines issuing them.
+		`,
+	},
+
+	"revoke-by-tag": {
+		"Revoke all the leases carrying the given tags",
+		`
+Revokes all the leases of the namespace which carry all the given tags. As an
+example, revoking the leases tagged with "app=checkout" revokes all the
+credentials issued for the checkout application once it is decommissioned.
+		`,
+	},
+
+	"lease-tags": {
+		`Tags the leases must all carry, as a map or a list of "key=value" strings.`,
+		"",
+	},
+
 	"revoke-prefix-path": {
 		`The path to revoke keys under. Example: "prod/aws/ops"`,
 		"",
@@ -3994,6 +4831,17 @@ or delete a policy.
 		`,
 	},
 
+	"policy-test": {
+		`Test an ACL policy against a hypothetical request.`,
+		`
+Evaluate the named ACL policy against a request to a path with an operation
+and parameters, and return whether the policy would allow it along with the
+path of the rule matching the request. Templated policies are evaluated with
+the identity given in the request, and bound CIDRs with its remote address.
+MFA methods required by the matching rule are returned, but not validated.
+		`,
+	},
+
 	"policy-name": {
 		`The name of the policy. Example: "ops"`,
 		"",
@@ -4024,6 +4872,17 @@ or delete a policy.
 		"",
 	},
 
+	"audit-hash-rotate": {
+		"Rotate the HMAC key of audit backends.",
+		`
+Replaces the key used to HMAC sensitive values in the logs of the audit
+backend at the given path, or of every audit backend if no path is given.
+Values logged before the rotation cannot be correlated with those logged
+after it, nor hashed with the audit-hash endpoint. To rotate keys on a
+schedule, enable the backend with the hmac_key_rotation_period option.
+		`,
+	},
+
 	"audit-table": {
 		"List the currently enabled audit backends.",
 		`
@@ -4083,6 +4942,16 @@ Enable a new audit backend or disable an existing backend.
 		`,
 	},
 
+	"seal-migrate": {
+		"Starts the online migration to the new auto-seal.",
+		`
+		Rewraps the recovery key and the stored barrier keys under the new
+		auto-seal on the active node, in the background. Standbys keep serving
+		and switch to the new seal once the migration completed. The progress
+		is reported by sys/seal-status.
+		`,
+	},
+
 	"rekey_backup": {
 		"Allows fetching or deleting the backup of the rotated unseal keys.",
 		"",
@@ -4117,7 +4986,11 @@ it.`,
 
 	"wrap": {
 		"Response-wraps an arbitrary JSON object.",
-		`Round trips the given input data into a response-wrapped token.`,
+		`Round trips the given input data into a response-wrapped token.
+
+		The "wrap_num_uses", "wrap_allowed_entity_ids" and "wrap_allowed_group_ids"
+		parameters are not wrapped; they set the number of times the token can be
+		unwrapped, and restrict unwrapping to the given entities and group members.`,
 	},
 
 	"wrappubkey": {
@@ -4134,7 +5007,16 @@ it.`,
 
 	"wraplookup": {
 		"Looks up the properties of a response-wrapped token.",
-		`Returns the creation TTL and creation time of a response-wrapped token.`,
+		`Returns the creation TTL and creation time of a response-wrapped token,
+		along with its remaining uses and the entities and groups allowed to
+		unwrap it when set.`,
+	},
+
+	"wrapping-config": {
+		"Configures the policies enforced on wrapped data.",
+		`Sets the maximum payload size and number of uses of data wrapped through
+		sys/wrapping/wrap, and whether it must be restricted to specific
+		entities or groups.`,
 	},
 
 	"rewrap": {
@@ -4214,6 +5096,18 @@ plugin directory.`,
 Each entry is of the form "key=value".`,
 		"",
 	},
+	"plugin-catalog_version": {
+		`The semantic version of the plugin. Versions are registered alongside the
+plugin registered without a version, and are run by the mounts pinned to them
+with plugin_version.`,
+		"",
+	},
+	"plugin-catalog_runtime": {
+		`The isolated runtime to run the plugin in, with the keys "type"
+("container" or "gvisor"), "image", and optionally "cpus" and "memory_bytes".
+Only supported for auth and secret plugins.`,
+		"",
+	},
 	"leases": {
 		`View or list lease metadata.`,
 		`
@@ -4262,6 +5156,12 @@ This path responds to the following HTTP methods.
 		"A list of headers to whitelist and pass from the request to the plugin.",
 		"",
 	},
+	"plugin_version": {
+		`The version of the external plugin of the mount registered in the
+plugin catalog to run. An empty value runs the plugin registered without a
+version.`,
+		"",
+	},
 	"allowed_response_headers": {
 		"A list of headers to whitelist and allow a plugin to set on responses.",
 		"",
@@ -4270,6 +5170,10 @@ This path responds to the following HTTP methods.
 		"The type of token to issue (service or batch).",
 		"",
 	},
+	"group_sync_interval": {
+		"How often the external group memberships of the entities with aliases on the auth mount are re-resolved. Set to 0 to only resolve them on login and renewal.",
+		"",
+	},
 	"raw": {
 		"Write, Read, and Delete data directly in the Storage backend.",
 		"",
@@ -4292,6 +5196,28 @@ This path responds to the following HTTP methods.
 		"Export the metrics aggregated for telemetry purpose.",
 		"",
 	},
+	"metrics-stream": {
+		"Stream the metrics aggregated for telemetry purpose over a WebSocket.",
+		`
+Upgrades the connection to a WebSocket, and sends the in-memory metrics, in the
+same JSON format as sys/metrics, as a text message at the requested interval.
+		`,
+	},
+	"events-subscribe": {
+		"Subscribe to the events of a topic over a WebSocket.",
+		`
+Upgrades the connection to a WebSocket, and sends the events published on the
+topic as JSON text messages. Events are sent again over later connections
+resuming the subscriber until they are acknowledged by sending {"ack": <id>}.
+		`,
+	},
+	"metrics-collectors": {
+		"Status of the background processes that collect usage gauges.",
+		`
+Lists each active gauge collection process, with its configured and current
+collection intervals, and the outcome of its most recent collection.
+		`,
+	},
 	"internal-counters-requests": {
 		"Count of requests seen by this Vault cluster over time.",
 		"Count of requests seen by this Vault cluster over time. Not included in count: health checks, UI asset requests, requests forwarded from another cluster.",
@@ -4304,10 +5230,41 @@ This path responds to the following HTTP methods.
 		"Count of active entities in this Vault cluster.",
 		"Count of active entities in this Vault cluster.",
 	},
+	"internal-counters-activity": {
+		"Distinct clients seen by this Vault cluster over a period.",
+		`Distinct identity entities and tokens without an entity that made
+		requests during the months from start_time to end_time, in total, by
+		namespace and auth mount, and by month. Use format=csv to export the
+		clients of each auth mount as CSV.`,
+	},
 	"host-info": {
 		"Information about the host instance that this Vault server is running on.",
 		`Information about the host instance that this Vault server is running on.
 		The information that gets collected includes host hardware information, and CPU,
 		disk, and memory utilization`,
 	},
+	"monitor-profiles": {
+		"Profiles of the Vault server, for troubleshooting.",
+		`Profiles of the Vault server: pprof-formatted CPU, heap and goroutine
+		profiles, the memory and garbage collection statistics of the Go runtime,
+		and the host information along with the file descriptor limits and the
+		NUMA nodes. The profile capability is required on the path rather than
+		the read capability. Requests sent to a standby node return the profile
+		of the active node, unless the local parameter is set.`,
+	},
+	"loggers": {
+		"Log levels of the server.",
+		`Read or set the log level of the server without a restart. Deleting
+		restores the level of the configuration and removes the levels set for
+		subsystems. The changes only apply to the node receiving the request
+		and are lost when it restarts.`,
+	},
+	"loggers-name": {
+		"Log level of a subsystem of the server.",
+		`Read, set or remove the log level of a subsystem of the server, such as
+		"expiration", "raft" or "plugin-runtime". The name is matched against
+		the dot-separated segments of the names of the loggers, and the most
+		specific subsystem a logger belongs to sets its level. The changes only
+		apply to the node receiving the request and are lost when it restarts.`,
+	},
 }
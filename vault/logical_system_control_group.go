@@ -0,0 +1,177 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// controlGroupPaths returns the paths used to approve the requests parked by
+// control groups.
+func (b *SystemBackend) controlGroupPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "control-group/authorize$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group token of the request.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupAuthorize(),
+					Summary:  "Approves a request parked by a control group.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysControlGroupHelp["authorize"][0]),
+			HelpDescription: strings.TrimSpace(sysControlGroupHelp["authorize"][1]),
+		},
+		{
+			Pattern: "control-group/request$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the control group token of the request.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleControlGroupRequest(),
+					Summary:  "Reads the status of a request parked by a control group.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysControlGroupHelp["request"][0]),
+			HelpDescription: strings.TrimSpace(sysControlGroupHelp["request"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleControlGroupAuthorize() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		accessor := d.Get("accessor").(string)
+		if accessor == "" {
+			return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+		}
+		if req.EntityID == "" {
+			return logical.ErrorResponse("authorizing a control group request requires a token with an identity entity"), logical.ErrPermissionDenied
+		}
+		entity, err := b.Core.identityStore.MemDBEntityByID(req.EntityID, false)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil || entity.Disabled {
+			return nil, logical.ErrPermissionDenied
+		}
+
+		te, tokenCtx, err := b.Core.controlGroupTokenByAccessor(ctx, accessor)
+		if err != nil {
+			return nil, err
+		}
+		if te == nil {
+			return logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
+		}
+
+		cgReq, err := b.Core.authorizeControlGroupRequest(tokenCtx, te, entity, req.ClientTokenAccessor)
+		if err != nil {
+			if badRequest, ok := err.(*logical.StatusBadRequest); ok {
+				return logical.ErrorResponse(badRequest.Err), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"approved": cgReq.Approved,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleControlGroupRequest() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		accessor := d.Get("accessor").(string)
+		if accessor == "" {
+			return logical.ErrorResponse("missing accessor"), logical.ErrInvalidRequest
+		}
+
+		te, tokenCtx, err := b.Core.controlGroupTokenByAccessor(ctx, accessor)
+		if err != nil {
+			return nil, err
+		}
+		if te == nil {
+			return logical.ErrorResponse("invalid accessor"), logical.ErrInvalidRequest
+		}
+		cgReq, err := b.Core.loadControlGroupRequest(tokenCtx, te)
+		if err != nil {
+			return nil, err
+		}
+		if cgReq == nil {
+			return logical.ErrorResponse("no request found for the control group token"), logical.ErrInvalidRequest
+		}
+
+		authorizations := make([]map[string]interface{}, 0, len(cgReq.Authorizations))
+		for _, authz := range cgReq.Authorizations {
+			authorizations = append(authorizations, map[string]interface{}{
+				"entity_id":          authz.EntityID,
+				"entity_name":        b.controlGroupEntityName(authz.EntityID),
+				"authorization_time": authz.AuthorizationTime.Format(time.RFC3339Nano),
+			})
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"approved":     cgReq.Approved,
+				"request_path": cgReq.Path,
+				"request_time": cgReq.RequestTime.Format(time.RFC3339Nano),
+				"request_entity": map[string]interface{}{
+					"id":   cgReq.EntityID,
+					"name": b.controlGroupEntityName(cgReq.EntityID),
+				},
+				"authorizations": authorizations,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) controlGroupEntityName(entityID string) string {
+	if entityID == "" {
+		return ""
+	}
+	entity, err := b.Core.identityStore.MemDBEntityByID(entityID, false)
+	if err != nil || entity == nil {
+		return ""
+	}
+	return entity.Name
+}
+
+var sysControlGroupHelp = map[string][2]string{
+	"authorize": {
+		"Approves a request parked by a control group.",
+		`
+Requests to paths whose policy sets a control group are not performed right
+away: the caller gets back a wrapping token instead. Members of the identity
+groups of the control group approve the request with the accessor of that
+token. Once every factor of the control group has gathered the approvals it
+requires, unwrapping the token performs the request and returns its response.
+Requesters cannot approve their own requests.
+		`,
+	},
+	"request": {
+		"Reads the status of a request parked by a control group.",
+		`
+Returns the path and the requester of the request parked with the control
+group token of the given accessor, the approvals given so far, and whether
+the request is approved.
+		`,
+	},
+}
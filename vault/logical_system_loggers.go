@@ -0,0 +1,175 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const errLoggersNotConfigurable = "the log levels of this server cannot be changed at runtime"
+
+func (b *SystemBackend) loggersPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "loggers$",
+			Fields: map[string]*framework.FieldSchema{
+				"level": {
+					Type:        framework.TypeString,
+					Description: "Log level of the server. Supported values are \"trace\", \"debug\", \"info\", \"warn\" and \"error\".",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLoggersRead,
+					Summary:  "Read the log level of the server and the levels set for subsystems.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLoggersWrite,
+					Summary:  "Set the log level of the server.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleLoggersDelete,
+					Summary:  "Restore the configured log level and remove the levels set for subsystems.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["loggers"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["loggers"][1]),
+		},
+		{
+			Pattern: "loggers/" + framework.GenericNameRegex("name"),
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the subsystem, such as \"expiration\", \"raft\" or \"plugin-runtime\". It is matched against the dot-separated segments of the names of the loggers.",
+				},
+				"level": {
+					Type:        framework.TypeString,
+					Description: "Log level of the subsystem. Supported values are \"trace\", \"debug\", \"info\", \"warn\" and \"error\".",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLoggerRead,
+					Summary:  "Read the log level of a subsystem.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLoggerWrite,
+					Summary:  "Set the log level of a subsystem.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleLoggerDelete,
+					Summary:  "Remove the log level set for a subsystem.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["loggers-name"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["loggers-name"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleLoggersRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	level, subsystems := b.Core.logRouter.Levels()
+	loggers := make(map[string]interface{}, len(subsystems))
+	for subsystem, subsystemLevel := range subsystems {
+		loggers[subsystem] = subsystemLevel.String()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"level":   level.String(),
+			"loggers": loggers,
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleLoggersWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	level, errResp := parseLoggerLevel(d)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	b.Core.logRouter.SetLevel(level)
+	b.Core.logger.Info("log level of the server changed", "level", level.String())
+	return nil, nil
+}
+
+func (b *SystemBackend) handleLoggersDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	b.Core.logRouter.Reset()
+	b.Core.logger.Info("log levels reset to the configured level")
+	return nil, nil
+}
+
+func (b *SystemBackend) handleLoggerRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	name := d.Get("name").(string)
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":  name,
+			"level": b.Core.logRouter.LevelFor(name).String(),
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleLoggerWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	level, errResp := parseLoggerLevel(d)
+	if errResp != nil {
+		return errResp, nil
+	}
+
+	name := d.Get("name").(string)
+	b.Core.logRouter.SetSubsystemLevel(name, level)
+	b.Core.logger.Info("log level of subsystem changed", "subsystem", name, "level", level.String())
+	return nil, nil
+}
+
+func (b *SystemBackend) handleLoggerDelete(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if b.Core.logRouter == nil {
+		return logical.ErrorResponse(errLoggersNotConfigurable), nil
+	}
+
+	name := d.Get("name").(string)
+	b.Core.logRouter.ResetSubsystem(name)
+	b.Core.logger.Info("log level of subsystem reset", "subsystem", name)
+	return nil, nil
+}
+
+// parseLoggerLevel returns the level of the request, or an error response if
+// it is missing or unknown.
+func parseLoggerLevel(d *framework.FieldData) (log.Level, *logical.Response) {
+	levelRaw := d.Get("level").(string)
+	if levelRaw == "" {
+		return log.NoLevel, logical.ErrorResponse("level is required")
+	}
+
+	level := log.LevelFromString(levelRaw)
+	if level == log.NoLevel {
+		return log.NoLevel, logical.ErrorResponse("unknown log level: " + levelRaw)
+	}
+	return level, nil
+}
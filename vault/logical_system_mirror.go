@@ -0,0 +1,287 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// mirrorPaths returns the paths used to mirror KV mounts to and from other
+// clusters.
+func (b *SystemBackend) mirrorPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "mirror/sources/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleMirrorList(coreMirrorSourcesPath),
+					Summary:  "Lists the names of the mirror sources.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysMirrorHelp["sources-list"][0]),
+			HelpDescription: strings.TrimSpace(sysMirrorHelp["sources-list"][1]),
+		},
+		{
+			Pattern: "mirror/sources/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the mirror source.",
+				},
+				"mount": {
+					Type:        framework.TypeString,
+					Description: "Path of the KV mount to mirror, such as secret/.",
+				},
+				"activation_token": {
+					Type:        framework.TypeString,
+					Description: "Activation token returned by the target cluster when the mirror target was created.",
+				},
+				"address": {
+					Type:        framework.TypeString,
+					Description: "Cluster address of the target cluster. Defaults to the address in the activation token.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleMirrorSourceCreate(),
+					Summary:  "Starts mirroring a KV mount to another cluster.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleMirrorSourceRead(),
+					Summary:  "Reads a mirror source and its status.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleMirrorSourceDelete(),
+					Summary:  "Stops mirroring a KV mount.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysMirrorHelp["sources"][0]),
+			HelpDescription: strings.TrimSpace(sysMirrorHelp["sources"][1]),
+		},
+		{
+			Pattern: "mirror/targets/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleMirrorList(coreMirrorTargetsPath),
+					Summary:  "Lists the names of the mirror targets.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysMirrorHelp["targets-list"][0]),
+			HelpDescription: strings.TrimSpace(sysMirrorHelp["targets-list"][1]),
+		},
+		{
+			Pattern: "mirror/targets/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the mirror target.",
+				},
+				"mount": {
+					Type:        framework.TypeString,
+					Description: "Path of the KV mount receiving the mirrored data, such as secret/. The mount becomes read-only.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleMirrorTargetCreate(),
+					Summary:  "Makes a KV mount the target of a mirror, and returns the activation token of its source.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleMirrorTargetRead(),
+					Summary:  "Reads a mirror target and its status.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleMirrorTargetDelete(),
+					Summary:  "Stops mirroring to a KV mount, which becomes writable again.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysMirrorHelp["targets"][0]),
+			HelpDescription: strings.TrimSpace(sysMirrorHelp["targets"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleMirrorList(prefix string) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		keys, err := b.Core.barrier.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(keys), nil
+	}
+}
+
+func mirrorMountPath(d *framework.FieldData) string {
+	mount := strings.TrimPrefix(d.Get("mount").(string), "/")
+	if mount != "" && !strings.HasSuffix(mount, "/") {
+		mount += "/"
+	}
+	return mount
+}
+
+func (b *SystemBackend) handleMirrorSourceCreate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		mount := mirrorMountPath(d)
+		if mount == "" {
+			return logical.ErrorResponse("mount is required"), nil
+		}
+		token := d.Get("activation_token").(string)
+		if token == "" {
+			return logical.ErrorResponse("activation_token is required"), nil
+		}
+
+		err := b.Core.mirror.createSource(ctx, d.Get("name").(string), mount, token, d.Get("address").(string))
+		if err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleMirrorSourceRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		config := new(mirrorSourceConfig)
+		if err := b.Core.mirror.readJSON(ctx, coreMirrorSourcesPath+name, config); err != nil {
+			return nil, err
+		}
+		if config.Name == "" {
+			return nil, nil
+		}
+
+		data := map[string]interface{}{
+			"name":        config.Name,
+			"mount":       config.Mount,
+			"address":     config.Address,
+			"target_name": config.TargetName,
+		}
+
+		b.Core.mirror.l.RLock()
+		source := b.Core.mirror.sources[name]
+		b.Core.mirror.l.RUnlock()
+		if source != nil {
+			for k, v := range source.status() {
+				data[k] = v
+			}
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleMirrorSourceDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.mirror.deleteSource(ctx, d.Get("name").(string)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleMirrorTargetCreate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		mount := mirrorMountPath(d)
+		if mount == "" {
+			return logical.ErrorResponse("mount is required"), nil
+		}
+
+		token, err := b.Core.mirror.createTarget(ctx, d.Get("name").(string), mount)
+		if err != nil {
+			return handleError(err)
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"activation_token": token,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleMirrorTargetRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		config := new(mirrorTargetConfig)
+		if err := b.Core.mirror.readJSON(ctx, coreMirrorTargetsPath+name, config); err != nil {
+			return nil, err
+		}
+		if config.Name == "" {
+			return nil, nil
+		}
+
+		data := map[string]interface{}{
+			"name":  config.Name,
+			"mount": config.Mount,
+		}
+
+		b.Core.mirror.l.RLock()
+		target := b.Core.mirror.targets[name]
+		b.Core.mirror.l.RUnlock()
+		if target != nil {
+			for k, v := range target.status() {
+				data[k] = v
+			}
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleMirrorTargetDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.mirror.deleteTarget(ctx, d.Get("name").(string)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+var sysMirrorHelp = map[string][2]string{
+	"sources-list": {
+		"Lists the names of the mirror sources.",
+		"",
+	},
+	"sources": {
+		"Mirrors a KV mount of this cluster to another cluster.",
+		`
+A mirror source streams the changes to a KV mount to a mirror target on
+another cluster, over the cluster port. It is created with the activation
+token returned when creating the target. Changes are recorded until the target
+acknowledges them, so the mirror resumes where it stopped after a
+disconnection, and the whole mount is sent again when the target is too far
+behind.
+		`,
+	},
+	"targets-list": {
+		"Lists the names of the mirror targets.",
+		"",
+	},
+	"targets": {
+		"Mirrors a KV mount of another cluster to a mount of this cluster.",
+		`
+A mirror target applies the changes streamed by its source to a KV mount of
+this cluster, which is read-only while it is mirrored. Creating a target
+returns the activation token to create the source with on the other cluster.
+
+A change to a key which does not have the value the source expects is counted
+as a conflict, and the value of the source is applied.
+		`,
+	},
+}
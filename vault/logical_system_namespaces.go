@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// namespacesPaths returns the paths used to manage the child namespaces of
+// the request's namespace.
+func (b *SystemBackend) namespacesPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "namespaces/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesList(),
+					Summary:  "Lists the child namespaces of the namespace.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysNamespacesHelp["namespaces-list"][0]),
+			HelpDescription: strings.TrimSpace(sysNamespacesHelp["namespaces-list"][1]),
+		},
+		{
+			Pattern: "namespaces/" + framework.GenericNameRegex("path"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Name of the child namespace.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesCreate(),
+					Summary:  "Creates a child namespace.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesRead(),
+					Summary:  "Reads a child namespace.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleNamespacesDelete(),
+					Summary:  "Deletes a child namespace, along with all of its data.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysNamespacesHelp["namespaces"][0]),
+			HelpDescription: strings.TrimSpace(sysNamespacesHelp["namespaces"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleNamespacesList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var keys []string
+		keyInfo := make(map[string]interface{})
+		for _, child := range b.Core.namespaceStore.children(ns, false) {
+			key := strings.TrimPrefix(child.Path, ns.Path)
+			keys = append(keys, key)
+			keyInfo[key] = namespaceResponseData(child)
+		}
+		return logical.ListResponseWithInfo(keys, keyInfo), nil
+	}
+}
+
+// lookupChildNamespace returns the named child namespace of the request's
+// namespace.
+func (b *SystemBackend) lookupChildNamespace(ctx context.Context, name string) (*namespace.Namespace, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range b.Core.namespaceStore.children(ns, false) {
+		if child.Path == ns.Path+name+"/" {
+			return child, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleNamespacesCreate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := b.Core.createNamespace(ctx, d.Get("path").(string))
+		if err != nil {
+			return handleError(err)
+		}
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleNamespacesRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := b.lookupChildNamespace(ctx, d.Get("path").(string))
+		if err != nil {
+			return nil, err
+		}
+		if ns == nil {
+			return nil, nil
+		}
+		return &logical.Response{
+			Data: namespaceResponseData(ns),
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleNamespacesDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if err := b.Core.deleteNamespace(ctx, d.Get("path").(string)); err != nil {
+			return handleError(err)
+		}
+		return nil, nil
+	}
+}
+
+func namespaceResponseData(ns *namespace.Namespace) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   ns.ID,
+		"path": ns.Path,
+	}
+}
+
+var sysNamespacesHelp = map[string][2]string{
+	"namespaces-list": {
+		"Lists the child namespaces of the namespace.",
+		"",
+	},
+	"namespaces": {
+		"Creates, reads and deletes child namespaces.",
+		`
+A namespace is an isolated environment with its own secrets engines, auth
+methods, policies, tokens and identities. Requests are made in a namespace by
+prefixing their path with the path of the namespace, or by passing it in the
+X-Vault-Namespace header.
+
+Deleting a namespace revokes its leases and tokens and removes all of its
+data. A namespace which has child namespaces cannot be deleted.
+		`,
+	},
+}
@@ -48,6 +48,43 @@ func (b *SystemBackend) configPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["config/cors"][1]),
 		},
 
+		{
+			Pattern: "config/cache$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"max_entries": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of entries of the cache, besides the pinned ones. If 0, the cache_size of the server configuration is used.",
+				},
+				"max_bytes": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum size in bytes of the entries of the cache, besides the pinned ones. If 0, the cache_max_bytes of the server configuration is used.",
+				},
+				"pinned_prefixes": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "A comma-separated string or array of strings of the prefixes of the storage keys whose entries are never evicted from the cache.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleCacheConfigRead,
+					Summary:  "Return the bounds and usage of the storage cache.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleCacheConfigUpdate,
+					Summary:  "Configure the bounds of the storage cache.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleCacheConfigDelete,
+					Summary:  "Restore the bounds of the storage cache from the server configuration.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["config/cache"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["config/cache"][1]),
+		},
+
 		{
 			Pattern: "config/state/sanitized$",
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -454,6 +491,27 @@ func (b *SystemBackend) rekeyPaths() []*framework.Path {
 
 func (b *SystemBackend) auditPaths() []*framework.Path {
 	return []*framework.Path{
+		{
+			Pattern: "audit-hash/rotate$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The path of the audit backend whose key to rotate. All keys are rotated if empty.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleAuditHashRotate,
+					Summary:  "Rotate the HMAC key of audit backends.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["audit-hash-rotate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["audit-hash-rotate"][1]),
+		},
+
 		{
 			Pattern: "audit-hash/(?P<path>.+)",
 
@@ -602,6 +660,17 @@ func (b *SystemBackend) sealPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
 		},
+
+		{
+			Pattern: "seal-migrate$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleSealMigrate,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["seal-migrate"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["seal-migrate"][1]),
+		},
 	}
 }
 
@@ -638,6 +707,14 @@ func (b *SystemBackend) pluginsCatalogCRUDPath() *framework.Path {
 				Type:        framework.TypeStringSlice,
 				Description: strings.TrimSpace(sysHelp["plugin-catalog_env"][0]),
 			},
+			"runtime": &framework.FieldSchema{
+				Type:        framework.TypeMap,
+				Description: strings.TrimSpace(sysHelp["plugin-catalog_runtime"][0]),
+			},
+			"version": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: strings.TrimSpace(sysHelp["plugin-catalog_version"][0]),
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -904,6 +981,35 @@ func (b *SystemBackend) internalPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-entities"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-entities"][1]),
 		},
+		{
+			Pattern: "internal/counters/activity",
+			Fields: map[string]*framework.FieldSchema{
+				"start_time": {
+					Type:        framework.TypeString,
+					Description: "Start of the period to report on, in RFC3339 format. Defaults to the beginning of the current month.",
+					Query:       true,
+				},
+				"end_time": {
+					Type:        framework.TypeString,
+					Description: "End of the period to report on, in RFC3339 format. Defaults to now.",
+					Query:       true,
+				},
+				"format": {
+					Type:        framework.TypeString,
+					Default:     "json",
+					Description: `Format of the report, "json" or "csv".`,
+					Query:       true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathInternalCountersActivity,
+					Summary:  "Report the distinct clients seen over a period.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(sysHelp["internal-counters-activity"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["internal-counters-activity"][1]),
+		},
 	}
 }
 
@@ -1143,6 +1249,53 @@ func (b *SystemBackend) leasePaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["revoke-prefix"][1]),
 		},
 
+		{
+			Pattern: "leases/lookup-by-tag$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"tags": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: strings.TrimSpace(sysHelp["lease-tags"][0]),
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLeaseLookupByTag,
+					Summary:  "Returns a list of the ids of the leases carrying all the given tags.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["lookup-by-tag"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["lookup-by-tag"][1]),
+		},
+
+		{
+			Pattern: "leases/revoke-by-tag$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"tags": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: strings.TrimSpace(sysHelp["lease-tags"][0]),
+				},
+				"sync": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Default:     true,
+					Description: strings.TrimSpace(sysHelp["revoke-sync"][0]),
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleRevokeByTag,
+					Summary:  "Revokes all the leases carrying all the given tags immediately.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["revoke-by-tag"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["revoke-by-tag"][1]),
+		},
+
 		{
 			Pattern: "leases/tidy$",
 
@@ -1199,6 +1352,64 @@ func (b *SystemBackend) metricsPath() *framework.Path {
 
 }
 
+func (b *SystemBackend) metricsStreamPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "metrics/stream$",
+		Fields: map[string]*framework.FieldSchema{
+			"interval": &framework.FieldSchema{
+				Type:        framework.TypeDurationSecond,
+				Description: "Time between metric snapshots. Must be at least one second.",
+				Default:     10,
+				Query:       true,
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.handleMetricsStream,
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["metrics-stream"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["metrics-stream"][1]),
+	}
+}
+
+func (b *SystemBackend) eventsSubscribePath() *framework.Path {
+	return &framework.Path{
+		Pattern: "events/subscribe/(?P<topic>.+)",
+		Fields: map[string]*framework.FieldSchema{
+			"topic": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The topic to subscribe to, e.g. kv/write. A trailing '*' matches all topics with the preceding prefix.",
+			},
+			"subscriber_id": &framework.FieldSchema{
+				Type:        framework.TypeString,
+				Description: "The ID of a subscriber to resume, receiving again the events it did not acknowledge.",
+				Query:       true,
+			},
+			"buffer_size": &framework.FieldSchema{
+				Type:        framework.TypeInt,
+				Description: "How many unacknowledged events are held before the subscriber is closed.",
+				Default:     DefaultEventBufferSize,
+				Query:       true,
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.handleEventsSubscribe,
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["events-subscribe"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["events-subscribe"][1]),
+	}
+}
+
+func (b *SystemBackend) metricsCollectorsPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "metrics/collectors$",
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.handleMetricsCollectors,
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["metrics-collectors"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["metrics-collectors"][1]),
+	}
+}
+
 func (b *SystemBackend) monitorPath() *framework.Path {
 	return &framework.Path{
 		Pattern: "monitor",
@@ -1286,10 +1497,18 @@ func (b *SystemBackend) authPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: strings.TrimSpace(sysHelp["allowed_response_headers"][0]),
 				},
+				"plugin_version": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin_version"][0]),
+				},
 				"token_type": &framework.FieldSchema{
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["token_type"][0]),
 				},
+				"group_sync_interval": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["group_sync_interval"][0]),
+				},
 			},
 			Operations: map[logical.Operation]framework.OperationHandler{
 				logical.ReadOperation: &framework.PathOperation{
@@ -1431,6 +1650,61 @@ func (b *SystemBackend) policyPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["policy-list"][1]),
 		},
 
+		{
+			Pattern: "policies/acl/(?P<name>.+)/test$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["policy-name"][0]),
+				},
+				"path": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The path of the request to test the policy against.",
+				},
+				"operation": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Default:     "read",
+					Description: `The operation of the request: "create", "read", "update", "delete" or "list".`,
+				},
+				"parameters": &framework.FieldSchema{
+					Type:        framework.TypeMap,
+					Description: "The parameters of the request, checked against the parameter constraints of the policy.",
+				},
+				"entity_id": &framework.FieldSchema{
+					Type: framework.TypeString,
+					Description: "The ID of the entity to evaluate the templating of the policy with. " +
+						"If the entity exists, its name, metadata and groups are used too.",
+				},
+				"entity_name": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The name of the entity to evaluate the templating of the policy with.",
+				},
+				"entity_metadata": &framework.FieldSchema{
+					Type:        framework.TypeKVPairs,
+					Description: "The metadata of the entity to evaluate the templating of the policy with.",
+				},
+				"groups": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "The names of the groups of the entity to evaluate the templating of the policy with.",
+				},
+				"remote_address": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: "The IP address the request comes from, checked against the bound CIDRs of the policy.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesTest,
+					Summary:  "Test the named ACL policy against a hypothetical request.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-test"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-test"][1]),
+		},
+
 		{
 			Pattern: "policies/acl/(?P<name>.+)",
 
@@ -1526,6 +1800,22 @@ func (b *SystemBackend) wrappingPaths() []*framework.Path {
 		{
 			Pattern: "wrapping/wrap$",
 
+			Fields: map[string]*framework.FieldSchema{
+				"wrap_num_uses": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "Number of times the wrapping token can be unwrapped.",
+				},
+				"wrap_allowed_entity_ids": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Entity IDs allowed to unwrap the wrapping token.",
+				},
+				"wrap_allowed_group_ids": &framework.FieldSchema{
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Group IDs whose members are allowed to unwrap the wrapping token.",
+				},
+			},
+
 			Callbacks: map[logical.Operation]framework.OperationFunc{
 				logical.UpdateOperation: b.handleWrappingWrap,
 			},
@@ -1591,6 +1881,39 @@ func (b *SystemBackend) wrappingPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["rewrap"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["rewrap"][1]),
 		},
+
+		{
+			Pattern: "config/wrapping$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"max_payload_size": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum size, in bytes, of the data wrapped through sys/wrapping/wrap. Zero means no limit.",
+				},
+				"max_num_uses": &framework.FieldSchema{
+					Type:        framework.TypeInt,
+					Description: "Maximum number of uses of the tokens wrapping data through sys/wrapping/wrap. Zero means no limit.",
+				},
+				"require_unwrap_constraints": &framework.FieldSchema{
+					Type:        framework.TypeBool,
+					Description: "Refuse to wrap data through sys/wrapping/wrap unless the entities or groups allowed to unwrap it are given.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleWrappingConfigRead,
+					Summary:  "Return the policies enforced on wrapped data.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleWrappingConfigUpdate,
+					Summary:  "Configure the policies enforced on wrapped data.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["wrapping-config"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["wrapping-config"][1]),
+		},
 	}
 }
 
@@ -1640,10 +1963,18 @@ func (b *SystemBackend) mountPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: strings.TrimSpace(sysHelp["allowed_response_headers"][0]),
 				},
+				"plugin_version": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["plugin_version"][0]),
+				},
 				"token_type": &framework.FieldSchema{
 					Type:        framework.TypeString,
 					Description: strings.TrimSpace(sysHelp["token_type"][0]),
 				},
+				"group_sync_interval": &framework.FieldSchema{
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["group_sync_interval"][0]),
+				},
 			},
 
 			Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -0,0 +1,198 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/hostutil"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// monitorProfilesPathPrefix is the prefix of the profile paths, which
+	// require the profile capability rather than the read capability
+	monitorProfilesPathPrefix = "sys/monitor/profiles/"
+
+	MonitorProfileCPU       = "cpu"
+	MonitorProfileHeap      = "heap"
+	MonitorProfileGoroutine = "goroutine"
+	MonitorProfileRuntime   = "runtime"
+	MonitorProfileHost      = "host"
+)
+
+func (b *SystemBackend) monitorProfilesPath() *framework.Path {
+	return &framework.Path{
+		Pattern: "monitor/profiles/(?P<name>" + strings.Join([]string{
+			MonitorProfileCPU,
+			MonitorProfileHeap,
+			MonitorProfileGoroutine,
+			MonitorProfileRuntime,
+			MonitorProfileHost,
+		}, "|") + ")",
+
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: `The profile to return: "cpu", "heap" or "goroutine" for a pprof-formatted profile, "runtime" for the memory and garbage collection statistics of the Go runtime, or "host" for the information on the host.`,
+			},
+			"seconds": {
+				Type:        framework.TypeInt,
+				Description: "The duration of the CPU profile in seconds. Defaults to 30 seconds.",
+				Query:       true,
+			},
+			"debug": {
+				Type:        framework.TypeInt,
+				Description: "If set to a non-zero value, the heap and goroutine profiles are returned in a text format rather than in the pprof format.",
+				Query:       true,
+			},
+			"local": {
+				Type:        framework.TypeBool,
+				Description: "If set on a request sent to a standby node, the profile of the standby node is returned rather than the one of the active node.",
+				Query:       true,
+			},
+			"authorize_only": {
+				Type:        framework.TypeBool,
+				Description: "If set, the request is only authorized and no profile is returned. Used by standby nodes to have the requests for their own profiles authorized by the active node.",
+				Query:       true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:    b.handleMonitorProfile,
+				Summary:     strings.TrimSpace(sysHelp["monitor-profiles"][0]),
+				Description: strings.TrimSpace(sysHelp["monitor-profiles"][1]),
+			},
+		},
+
+		HelpSynopsis:    strings.TrimSpace(sysHelp["monitor-profiles"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["monitor-profiles"][1]),
+	}
+}
+
+func (b *SystemBackend) handleMonitorProfile(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	if d.Get("authorize_only").(bool) {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"authorized": true,
+			},
+		}, nil
+	}
+
+	if err := checkRequestHandlerParams(req); err != nil {
+		return nil, err
+	}
+
+	return MonitorProfile(ctx, d.Get("name").(string), req.ResponseWriter, req.HTTPRequest)
+}
+
+// MonitorProfile returns the named profile of this node. The pprof-formatted
+// profiles are written to the response writer and a nil response is returned,
+// while the runtime and host profiles are returned as the data of the
+// response. The request is not authorized, which is up to the caller.
+func MonitorProfile(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (*logical.Response, error) {
+	switch name {
+	case MonitorProfileCPU:
+		// Return an error if seconds exceeds max request duration, as for
+		// sys/pprof/profile
+		if secQueryVal := r.FormValue("seconds"); secQueryVal != "" {
+			maxDur := int64(DefaultMaxRequestDuration.Seconds())
+			sec, _ := strconv.ParseInt(secQueryVal, 10, 64)
+			if sec > maxDur {
+				return logical.ErrorResponse(fmt.Sprintf("seconds %d exceeds max request duration of %d", sec, maxDur)), nil
+			}
+		}
+		pprof.Profile(w, r)
+
+	case MonitorProfileHeap, MonitorProfileGoroutine:
+		pprof.Handler(name).ServeHTTP(w, r)
+
+	case MonitorProfileRuntime:
+		return runtimeProfileResponse(), nil
+
+	case MonitorProfileHost:
+		return hostProfileResponse(ctx)
+
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unknown profile: %s", name)), nil
+	}
+
+	return nil, nil
+}
+
+// runtimeProfileResponse returns the statistics of the Go runtime, which
+// include the memory allocations and the garbage collections.
+func runtimeProfileResponse() *logical.Response {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	var lastGC time.Time
+	if stats.LastGC > 0 {
+		lastGC = time.Unix(0, int64(stats.LastGC)).UTC()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"timestamp":     time.Now().UTC(),
+			"go_version":    runtime.Version(),
+			"num_cpu":       runtime.NumCPU(),
+			"gomaxprocs":    runtime.GOMAXPROCS(0),
+			"num_goroutine": runtime.NumGoroutine(),
+			"num_cgo_call":  runtime.NumCgoCall(),
+			"memory": map[string]interface{}{
+				"alloc":         stats.Alloc,
+				"total_alloc":   stats.TotalAlloc,
+				"sys":           stats.Sys,
+				"mallocs":       stats.Mallocs,
+				"frees":         stats.Frees,
+				"heap_alloc":    stats.HeapAlloc,
+				"heap_sys":      stats.HeapSys,
+				"heap_idle":     stats.HeapIdle,
+				"heap_inuse":    stats.HeapInuse,
+				"heap_released": stats.HeapReleased,
+				"heap_objects":  stats.HeapObjects,
+				"stack_inuse":   stats.StackInuse,
+				"stack_sys":     stats.StackSys,
+			},
+			"gc": map[string]interface{}{
+				"num_gc":          stats.NumGC,
+				"num_forced_gc":   stats.NumForcedGC,
+				"pause_total_ns":  stats.PauseTotalNs,
+				"last_gc":         lastGC,
+				"next_gc":         stats.NextGC,
+				"gc_cpu_fraction": stats.GCCPUFraction,
+			},
+		},
+	}
+}
+
+// hostProfileResponse returns the host information of sys/host-info along
+// with the file descriptor limits of the process and the NUMA nodes of the
+// host. Any capture-related errors are returned as response warnings.
+func hostProfileResponse(ctx context.Context) (*logical.Response, error) {
+	resp, err := hostInfoResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits, err := hostutil.CollectFDLimits(); err != nil {
+		resp.AddWarning(err.Error())
+	} else {
+		resp.Data["fd_limits"] = limits
+	}
+
+	if nodes, err := hostutil.CollectNUMANodes(); err != nil {
+		resp.AddWarning(err.Error())
+	} else {
+		resp.Data["numa_nodes"] = nodes
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,416 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/quotas"
+)
+
+// quotasPaths returns the paths used to manage quotas.
+func (b *SystemBackend) quotasPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "quotas/lease-count/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotasList(),
+					Summary:  "Lists the names of the lease count quotas.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysQuotasHelp["lease-count-list"][0]),
+			HelpDescription: strings.TrimSpace(sysQuotasHelp["lease-count-list"][1]),
+		},
+		{
+			Pattern: "quotas/lease-count/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the quota.",
+				},
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path of the auth mount to apply the quota to, such as auth/userpass/. If empty, the quota applies to all auth mounts of the namespace.",
+				},
+				"max_leases": {
+					Type:        framework.TypeInt,
+					Description: "The maximum number of login leases that may exist at once.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotaUpdate(),
+					Summary:  "Creates or updates a lease count quota.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotaRead(),
+					Summary:  "Reads a lease count quota and its current usage.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleLeaseCountQuotaDelete(),
+					Summary:  "Deletes a lease count quota.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysQuotasHelp["lease-count"][0]),
+			HelpDescription: strings.TrimSpace(sysQuotasHelp["lease-count"][1]),
+		},
+		{
+			Pattern: "quotas/rate-limit/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleRateLimitQuotasList(),
+					Summary:  "Lists the names of the rate limit quotas.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysQuotasHelp["rate-limit-list"][0]),
+			HelpDescription: strings.TrimSpace(sysQuotasHelp["rate-limit-list"][1]),
+		},
+		{
+			Pattern: "quotas/rate-limit/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the quota.",
+				},
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path prefix of the requests to apply the quota to, such as secret/ or auth/userpass/login. If empty, the quota applies to all the requests of the namespace.",
+				},
+				"granularity": {
+					Type:        framework.TypeString,
+					Default:     quotas.GranularityPath,
+					Description: `Whether the rate limit is shared by all clients ("path"), or applies to each identity entity ("entity") or client address ("ip"). Requests without an entity are limited by client address under "entity".`,
+				},
+				"rate": {
+					Type:        framework.TypeFloat,
+					Description: "The number of requests allowed per interval.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Default:     1,
+					Description: "The duration the rate applies to.",
+				},
+				"burst": {
+					Type:        framework.TypeInt,
+					Description: "The number of requests allowed at once. Defaults to the rate rounded up.",
+				},
+				"block_interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "If set, clients exceeding the rate limit block_threshold times in a row have all their requests rejected for this duration.",
+				},
+				"block_threshold": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "The number of consecutive rejected requests after which a client is blocked for block_interval.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleRateLimitQuotaUpdate(),
+					Summary:  "Creates or updates a rate limit quota.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleRateLimitQuotaRead(),
+					Summary:  "Reads a rate limit quota.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleRateLimitQuotaDelete(),
+					Summary:  "Deletes a rate limit quota.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysQuotasHelp["rate-limit"][0]),
+			HelpDescription: strings.TrimSpace(sysQuotasHelp["rate-limit"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotasList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, name := range b.Core.quotaManager.LeaseCountQuotaNames() {
+			if q := b.Core.quotaManager.LeaseCountQuota(name); q != nil && q.NamespacePath == ns.Path {
+				names = append(names, name)
+			}
+		}
+		return logical.ListResponse(names), nil
+	}
+}
+
+// lookupLeaseCountQuota returns the named quota if it belongs to the
+// request's namespace.
+func (b *SystemBackend) lookupLeaseCountQuota(ctx context.Context, name string) (*quotas.LeaseCountQuota, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := b.Core.quotaManager.LeaseCountQuota(name)
+	if q == nil || q.NamespacePath != ns.Path {
+		return nil, nil
+	}
+	return q, nil
+}
+
+func (b *SystemBackend) handleLeaseCountQuotaUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		name := d.Get("name").(string)
+		existing := b.Core.quotaManager.LeaseCountQuota(name)
+		if existing != nil && existing.NamespacePath != ns.Path {
+			return logical.ErrorResponse("quota %q already exists in another namespace", name), logical.ErrInvalidRequest
+		}
+
+		q := &quotas.LeaseCountQuota{
+			Name:          name,
+			NamespacePath: ns.Path,
+		}
+		if existing != nil {
+			q = existing
+		}
+
+		if raw, ok := d.GetOk("path"); ok {
+			q.MountPath = sanitizeMountPath(raw.(string))
+		}
+		if q.MountPath != "" {
+			entry := b.Core.router.MatchingMountEntry(ctx, q.MountPath)
+			if entry == nil || entry.Table != credentialTableType || credentialRoutePrefix+entry.Path != q.MountPath {
+				return logical.ErrorResponse("path %q is not an auth mount", q.MountPath), logical.ErrInvalidRequest
+			}
+		}
+
+		if raw, ok := d.GetOk("max_leases"); ok {
+			q.MaxLeases = raw.(int)
+		}
+		if q.MaxLeases <= 0 {
+			return logical.ErrorResponse("max_leases must be greater than zero"), logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.quotaManager.SetLeaseCountQuota(ctx, q); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotaRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		q, err := b.lookupLeaseCountQuota(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if q == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":        q.Name,
+				"type":        quotas.TypeLeaseCount.String(),
+				"path":        q.MountPath,
+				"max_leases":  q.MaxLeases,
+				"lease_count": b.Core.quotaManager.LeaseCount(q),
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleLeaseCountQuotaDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		q, err := b.lookupLeaseCountQuota(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if q == nil {
+			return nil, nil
+		}
+
+		if err := b.Core.quotaManager.DeleteLeaseCountQuota(ctx, q.Name); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleRateLimitQuotasList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, name := range b.Core.quotaManager.RateLimitQuotaNames() {
+			if q := b.Core.quotaManager.RateLimitQuota(name); q != nil && q.NamespacePath == ns.Path {
+				names = append(names, name)
+			}
+		}
+		return logical.ListResponse(names), nil
+	}
+}
+
+// lookupRateLimitQuota returns the named quota if it belongs to the
+// request's namespace.
+func (b *SystemBackend) lookupRateLimitQuota(ctx context.Context, name string) (*quotas.RateLimitQuota, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := b.Core.quotaManager.RateLimitQuota(name)
+	if q == nil || q.NamespacePath != ns.Path {
+		return nil, nil
+	}
+	return q, nil
+}
+
+func (b *SystemBackend) handleRateLimitQuotaUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		name := d.Get("name").(string)
+		existing := b.Core.quotaManager.RateLimitQuota(name)
+		if existing != nil && existing.NamespacePath != ns.Path {
+			return logical.ErrorResponse("quota %q already exists in another namespace", name), logical.ErrInvalidRequest
+		}
+
+		q := &quotas.RateLimitQuota{
+			Name:           name,
+			NamespacePath:  ns.Path,
+			Granularity:    d.Get("granularity").(string),
+			Interval:       time.Duration(d.Get("interval").(int)) * time.Second,
+			BlockThreshold: d.Get("block_threshold").(int),
+		}
+		if existing != nil {
+			q = existing
+		}
+
+		if raw, ok := d.GetOk("path"); ok {
+			q.Path = strings.TrimPrefix(raw.(string), "/")
+		}
+		if raw, ok := d.GetOk("granularity"); ok {
+			q.Granularity = raw.(string)
+		}
+		if raw, ok := d.GetOk("rate"); ok {
+			q.Rate = raw.(float64)
+		}
+		if raw, ok := d.GetOk("interval"); ok {
+			q.Interval = time.Duration(raw.(int)) * time.Second
+		}
+		if raw, ok := d.GetOk("burst"); ok {
+			q.Burst = raw.(int)
+		}
+		if raw, ok := d.GetOk("block_interval"); ok {
+			q.BlockInterval = time.Duration(raw.(int)) * time.Second
+		}
+		if raw, ok := d.GetOk("block_threshold"); ok {
+			q.BlockThreshold = raw.(int)
+		}
+
+		if err := b.Core.quotaManager.SetRateLimitQuota(ctx, q); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleRateLimitQuotaRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		q, err := b.lookupRateLimitQuota(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if q == nil {
+			return nil, nil
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"name":            q.Name,
+				"type":            quotas.TypeRateLimit.String(),
+				"path":            q.Path,
+				"granularity":     q.Granularity,
+				"rate":            q.Rate,
+				"interval":        int64(q.Interval.Seconds()),
+				"burst":           q.Burst,
+				"block_interval":  int64(q.BlockInterval.Seconds()),
+				"block_threshold": q.BlockThreshold,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleRateLimitQuotaDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		q, err := b.lookupRateLimitQuota(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if q == nil {
+			return nil, nil
+		}
+
+		if err := b.Core.quotaManager.DeleteRateLimitQuota(ctx, q.Name); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+var sysQuotasHelp = map[string][2]string{
+	"lease-count-list": {
+		"Lists the names of the lease count quotas.",
+		"",
+	},
+	"lease-count": {
+		"Configures a ceiling on the number of leases created by logins.",
+		`
+A lease count quota limits the number of login leases that may exist at once
+for an auth mount or, if no path is given, for all the auth mounts of the
+namespace. Once the limit is reached, further logins are refused with a 429
+status code until existing leases expire or are revoked.
+		`,
+	},
+	"rate-limit-list": {
+		"Lists the names of the rate limit quotas.",
+		"",
+	},
+	"rate-limit": {
+		"Configures a limit on the rate of requests to a path.",
+		`
+A rate limit quota limits the rate of requests whose path starts with the
+quota's path or, if no path is given, of all the requests of the namespace.
+When several quotas match a request, the one with the longest path applies.
+The limit is shared by all clients, or applies to each identity entity or
+client address depending on the granularity. Requests over the limit are
+refused with a 429 status code, and clients refused block_threshold times in
+a row are blocked for block_interval if it is set. Refused requests are
+counted in the vault.quota.rate_limit.violation metric.
+		`,
+	},
+}
@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"net/http"
+	"testing"
+
+	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestSystemBackend_LeaseCountQuota(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+	ctx := namespace.RootContext(nil)
+
+	write := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		return core.HandleRequest(ctx, &logical.Request{
+			Path:        path,
+			ClientToken: root,
+			Operation:   logical.UpdateOperation,
+			Data:        data,
+			Connection:  &logical.Connection{},
+		})
+	}
+	login := func() (*logical.Response, error) {
+		return core.HandleRequest(ctx, &logical.Request{
+			Path:      "auth/userpass/login/test",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"password": "foo",
+			},
+			Connection: &logical.Connection{},
+		})
+	}
+
+	if _, err := write("sys/auth/userpass", map[string]interface{}{"type": "userpass"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := write("auth/userpass/users/test", map[string]interface{}{"password": "foo", "policies": "default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := write("sys/quotas/lease-count/bad", map[string]interface{}{"path": "secret/", "max_leases": 1})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error for non-auth path, got %#v, %v", resp, err)
+	}
+
+	if _, err := write("sys/quotas/lease-count/userpass", map[string]interface{}{"path": "auth/userpass", "max_leases": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+	token := resp.Auth.ClientToken
+
+	_, err = login()
+	coded, ok := err.(logical.HTTPCodedError)
+	if !ok || coded.Code() != http.StatusTooManyRequests {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+
+	resp, err = core.HandleRequest(ctx, &logical.Request{
+		Path:        "sys/quotas/lease-count/userpass",
+		ClientToken: root,
+		Operation:   logical.ReadOperation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["path"] != "auth/userpass/" || resp.Data["max_leases"] != 1 || resp.Data["lease_count"] != 1 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Revoking the lease allows another login.
+	if _, err := write("auth/token/revoke", map[string]interface{}{"token": token}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+
+	resp, err = core.HandleRequest(ctx, &logical.Request{
+		Path:        "sys/quotas/lease-count",
+		ClientToken: root,
+		Operation:   logical.ListOperation,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := resp.Data["keys"].([]string); len(keys) != 1 || keys[0] != "userpass" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	if _, err := core.HandleRequest(ctx, &logical.Request{
+		Path:        "sys/quotas/lease-count/userpass",
+		ClientToken: root,
+		Operation:   logical.DeleteOperation,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = login()
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("bad: %#v, %v", resp, err)
+	}
+}
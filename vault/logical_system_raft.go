@@ -5,7 +5,10 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	proto "github.com/golang/protobuf/proto"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
@@ -99,6 +102,202 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-configuration"][0]),
 			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-configuration"][1]),
 		},
+		{
+			Pattern: "storage/raft/autopilot/configuration",
+
+			Fields: map[string]*framework.FieldSchema{
+				"cleanup_dead_servers": {
+					Type:        framework.TypeBool,
+					Description: "Controls whether to remove dead servers from the raft cluster.",
+				},
+				"last_contact_threshold": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Limit on the amount of time a server can go without leader contact before being considered unhealthy.",
+				},
+				"dead_server_last_contact_threshold": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Limit on the amount of time a server can go without leader contact before being considered dead and removed, if cleanup_dead_servers is set.",
+				},
+				"max_trailing_logs": {
+					Type:        framework.TypeInt,
+					Description: "Amount of entries in the raft log that a server can be behind the leader before being considered unhealthy.",
+				},
+				"min_quorum": {
+					Type:        framework.TypeInt,
+					Description: "Minimum number of voters the cluster is left with when removing dead servers.",
+				},
+				"server_stabilization_time": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Minimum amount of time a new server must be healthy before being promoted to a voter.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftAutopilotConfigRead(),
+					Summary:  "Returns the configuration of the autopilot subsystem of the raft cluster.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftAutopilotConfigUpdate(),
+					Summary:  "Updates the configuration of the autopilot subsystem of the raft cluster.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][1]),
+		},
+		{
+			Pattern: "storage/raft/autopilot/state",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftAutopilotState(),
+					Summary:  "Returns the state of the raft cluster as seen by autopilot.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-autopilot-state"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-autopilot-state"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigList(),
+					Summary:  "Lists the configurations of automatic snapshots of the raft storage.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the configuration.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Time between snapshots.",
+				},
+				"retain": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "Number of snapshots to keep; the oldest snapshots beyond it are deleted.",
+				},
+				"path_prefix": {
+					Type:        framework.TypeString,
+					Description: "Directory of the local snapshots, or prefix of the names of the snapshots in a bucket or container.",
+				},
+				"file_prefix": {
+					Type:        framework.TypeString,
+					Default:     raftAutoSnapshotDefaultFilePrefix,
+					Description: "Prefix of the names of the snapshot files.",
+				},
+				"storage_type": {
+					Type:        framework.TypeString,
+					Description: "Where the snapshots are stored: local, aws-s3, google-gcs or azure-blob.",
+				},
+				"local_max_space": {
+					Type:        framework.TypeInt,
+					Description: "For local storage, the most bytes the snapshots in path_prefix may use; 0 means unlimited.",
+				},
+				"aws_s3_bucket": {
+					Type:        framework.TypeString,
+					Description: "For aws-s3 storage, the bucket to store the snapshots in.",
+				},
+				"aws_s3_region": {
+					Type:        framework.TypeString,
+					Description: "For aws-s3 storage, the region of the bucket.",
+				},
+				"aws_s3_endpoint": {
+					Type:        framework.TypeString,
+					Description: "For aws-s3 storage, the endpoint of an S3-compatible service.",
+				},
+				"aws_s3_disable_tls": {
+					Type:        framework.TypeBool,
+					Description: "For aws-s3 storage, disables TLS to the endpoint.",
+				},
+				"aws_s3_force_path_style": {
+					Type:        framework.TypeBool,
+					Description: "For aws-s3 storage, uses path-style addressing of the bucket.",
+				},
+				"aws_access_key_id": {
+					Type:        framework.TypeString,
+					Description: "For aws-s3 storage, the access key ID; the default AWS credential chain is used if unset.",
+				},
+				"aws_secret_access_key": {
+					Type:        framework.TypeString,
+					Description: "For aws-s3 storage, the secret access key.",
+				},
+				"google_gcs_bucket": {
+					Type:        framework.TypeString,
+					Description: "For google-gcs storage, the bucket to store the snapshots in.",
+				},
+				"google_service_account_key": {
+					Type:        framework.TypeString,
+					Description: "For google-gcs storage, the JSON key of the service account; the application default credentials are used if unset.",
+				},
+				"azure_container_name": {
+					Type:        framework.TypeString,
+					Description: "For azure-blob storage, the container to store the snapshots in.",
+				},
+				"azure_account_name": {
+					Type:        framework.TypeString,
+					Description: "For azure-blob storage, the name of the storage account.",
+				},
+				"azure_account_key": {
+					Type:        framework.TypeString,
+					Description: "For azure-blob storage, the key of the storage account.",
+				},
+				"azure_blob_environment": {
+					Type:        framework.TypeString,
+					Description: "For azure-blob storage, the Azure cloud environment; defaults to AzurePublicCloud.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigRead(),
+					Summary:  "Returns a configuration of automatic snapshots of the raft storage.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigUpdate(),
+					Summary:  "Creates or updates a configuration of automatic snapshots of the raft storage.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigDelete(),
+					Summary:  "Deletes a configuration of automatic snapshots of the raft storage.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/status/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the configuration.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoStatus(),
+					Summary:  "Returns the status of a configuration of automatic snapshots of the raft storage.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][1]),
+		},
 		{
 			Pattern: "storage/raft/snapshot",
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -174,6 +373,298 @@ func (b *SystemBackend) handleRaftRemovePeerUpdate() framework.OperationFunc {
 	}
 }
 
+func (b *SystemBackend) handleStorageRaftAutopilotConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.Core.raftAutopilotConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"cleanup_dead_servers":               config.CleanupDeadServers,
+				"last_contact_threshold":             config.LastContactThreshold.String(),
+				"dead_server_last_contact_threshold": config.DeadServerLastContactThreshold.String(),
+				"max_trailing_logs":                  config.MaxTrailingLogs,
+				"min_quorum":                         config.MinQuorum,
+				"server_stabilization_time":          config.ServerStabilizationTime.String(),
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftAutopilotConfigUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.Core.raftAutopilotConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if cleanupRaw, ok := d.GetOk("cleanup_dead_servers"); ok {
+			config.CleanupDeadServers = cleanupRaw.(bool)
+		}
+		if thresholdRaw, ok := d.GetOk("last_contact_threshold"); ok {
+			config.LastContactThreshold = time.Duration(thresholdRaw.(int)) * time.Second
+		}
+		if thresholdRaw, ok := d.GetOk("dead_server_last_contact_threshold"); ok {
+			config.DeadServerLastContactThreshold = time.Duration(thresholdRaw.(int)) * time.Second
+		}
+		if maxTrailingLogsRaw, ok := d.GetOk("max_trailing_logs"); ok {
+			maxTrailingLogs := maxTrailingLogsRaw.(int)
+			if maxTrailingLogs < 0 {
+				return logical.ErrorResponse("max_trailing_logs must not be negative"), logical.ErrInvalidRequest
+			}
+			config.MaxTrailingLogs = uint64(maxTrailingLogs)
+		}
+		if minQuorumRaw, ok := d.GetOk("min_quorum"); ok {
+			config.MinQuorum = minQuorumRaw.(int)
+		}
+		if stabilizationRaw, ok := d.GetOk("server_stabilization_time"); ok {
+			config.ServerStabilizationTime = time.Duration(stabilizationRaw.(int)) * time.Second
+		}
+
+		if err := config.validate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if err := b.Core.setRaftAutopilotConfig(ctx, config); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftAutopilotState() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		autopilot := b.Core.raftAutopilot
+		if autopilot == nil {
+			return nil, errors.New("autopilot is not running")
+		}
+
+		config, err := b.Core.raftAutopilotConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		state, err := autopilot.state(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+
+		servers := make(map[string]interface{}, len(state.Servers))
+		for id, server := range state.Servers {
+			servers[id] = server
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"healthy":           state.Healthy,
+				"failure_tolerance": state.FailureTolerance,
+				"leader":            state.Leader,
+				"voters":            state.Voters,
+				"servers":           servers,
+			},
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		names, err := b.Core.barrier.List(ctx, raftAutoSnapshotConfigPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(names), nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.Core.raftAutoSnapshotConfig(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, nil
+		}
+
+		// Credentials are not returned
+		data := map[string]interface{}{
+			"interval":     int64(config.Interval.Seconds()),
+			"retain":       config.Retain,
+			"path_prefix":  config.PathPrefix,
+			"file_prefix":  config.FilePrefix,
+			"storage_type": config.StorageType,
+		}
+		switch config.StorageType {
+		case raftAutoSnapshotStorageLocal:
+			data["local_max_space"] = config.LocalMaxSpace
+		case raftAutoSnapshotStorageS3:
+			data["aws_s3_bucket"] = config.AWSS3Bucket
+			data["aws_s3_region"] = config.AWSS3Region
+			data["aws_s3_endpoint"] = config.AWSS3Endpoint
+			data["aws_s3_disable_tls"] = config.AWSS3DisableTLS
+			data["aws_s3_force_path_style"] = config.AWSS3ForcePathStyle
+			data["aws_access_key_id"] = config.AWSAccessKeyID
+		case raftAutoSnapshotStorageGCS:
+			data["google_gcs_bucket"] = config.GoogleGCSBucket
+		case raftAutoSnapshotStorageAzure:
+			data["azure_container_name"] = config.AzureContainerName
+			data["azure_account_name"] = config.AzureAccountName
+			data["azure_blob_environment"] = config.AzureBlobEnvironment
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		config, err := b.Core.raftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			config = &raftAutoSnapshotConfig{
+				Name:       name,
+				Retain:     d.Get("retain").(int),
+				FilePrefix: d.Get("file_prefix").(string),
+			}
+		}
+
+		if intervalRaw, ok := d.GetOk("interval"); ok {
+			config.Interval = time.Duration(intervalRaw.(int)) * time.Second
+		}
+		if retainRaw, ok := d.GetOk("retain"); ok {
+			config.Retain = retainRaw.(int)
+		}
+		if maxSpaceRaw, ok := d.GetOk("local_max_space"); ok {
+			config.LocalMaxSpace = int64(maxSpaceRaw.(int))
+		}
+		if disableTLSRaw, ok := d.GetOk("aws_s3_disable_tls"); ok {
+			config.AWSS3DisableTLS = disableTLSRaw.(bool)
+		}
+		if forcePathStyleRaw, ok := d.GetOk("aws_s3_force_path_style"); ok {
+			config.AWSS3ForcePathStyle = forcePathStyleRaw.(bool)
+		}
+		for field, value := range map[string]*string{
+			"path_prefix":                &config.PathPrefix,
+			"file_prefix":                &config.FilePrefix,
+			"storage_type":               &config.StorageType,
+			"aws_s3_bucket":              &config.AWSS3Bucket,
+			"aws_s3_region":              &config.AWSS3Region,
+			"aws_s3_endpoint":            &config.AWSS3Endpoint,
+			"aws_access_key_id":          &config.AWSAccessKeyID,
+			"aws_secret_access_key":      &config.AWSSecretAccessKey,
+			"google_gcs_bucket":          &config.GoogleGCSBucket,
+			"google_service_account_key": &config.GoogleServiceAccountKey,
+			"azure_container_name":       &config.AzureContainerName,
+			"azure_account_name":         &config.AzureAccountName,
+			"azure_account_key":          &config.AzureAccountKey,
+			"azure_blob_environment":     &config.AzureBlobEnvironment,
+		} {
+			if raw, ok := d.GetOk(field); ok {
+				*value = raw.(string)
+			}
+		}
+
+		if err := config.validate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		// Catch the errors of the storage configuration now rather than on
+		// the first snapshot
+		store, err := config.store(ctx)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid %s storage configuration: %v", config.StorageType, err)), logical.ErrInvalidRequest
+		}
+		if closer, ok := store.(io.Closer); ok {
+			closer.Close()
+		}
+
+		if err := b.Core.setRaftAutoSnapshotConfig(ctx, config); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		if err := b.Core.deleteRaftAutoSnapshotConfig(ctx, d.Get("name").(string)); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoStatus() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		snapshots := b.Core.raftAutoSnapshots
+		if snapshots == nil {
+			return nil, errors.New("automatic snapshots are not running")
+		}
+
+		status := snapshots.status(d.Get("name").(string))
+		if status == nil {
+			return nil, nil
+		}
+
+		data := map[string]interface{}{
+			"last_snapshot_name": status.LastSnapshotName,
+			"last_error":         status.LastError,
+			"consecutive_errors": status.ConsecutiveErrors,
+			"next_snapshot_time": status.NextSnapshotTime.Format(time.RFC3339Nano),
+		}
+		if !status.LastSnapshotTime.IsZero() {
+			data["last_snapshot_time"] = status.LastSnapshotTime.Format(time.RFC3339Nano)
+		}
+		if !status.LastErrorTime.IsZero() {
+			data["last_error_time"] = status.LastErrorTime.Format(time.RFC3339Nano)
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
 func (b *SystemBackend) handleRaftBootstrapChallengeWrite() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 		serverID := d.Get("server_id").(string)
@@ -269,11 +760,15 @@ func (b *SystemBackend) handleRaftBootstrapAnswerWrite() framework.OperationFunc
 			return nil, errors.New("could not decode raft TLS configuration")
 		}
 
-		switch nonVoter {
-		case true:
+		// Servers joining as voters start as non-voters, which autopilot
+		// promotes once they are stable
+		switch {
+		case nonVoter:
 			err = raftStorage.AddNonVotingPeer(ctx, serverID, clusterAddr)
-		default:
+		case b.Core.disableAutopilot:
 			err = raftStorage.AddPeer(ctx, serverID, clusterAddr)
+		default:
+			err = raftStorage.AddNonVoter(ctx, serverID, clusterAddr)
 		}
 		if err != nil {
 			return nil, err
@@ -449,6 +944,33 @@ var sysRaftHelp = map[string][2]string{
 		"Removes a peer from the raft cluster.",
 		"",
 	},
+	"raft-autopilot-configuration": {
+		"Configures the autopilot subsystem of the raft cluster.",
+		`Autopilot runs on the active node. It promotes new servers to voters once
+they have been healthy for server_stabilization_time, and, if
+cleanup_dead_servers is set, removes the servers which have not contacted the
+active node for dead_server_last_contact_threshold, leaving at least min_quorum
+voters.`,
+	},
+	"raft-autopilot-state": {
+		"Returns the health of the servers of the raft cluster, as seen by autopilot.",
+		"",
+	},
+	"raft-snapshot-auto-config-list": {
+		"Lists the configurations of automatic snapshots of the raft storage.",
+		"",
+	},
+	"raft-snapshot-auto-config": {
+		"Configures snapshots taken automatically of the raft storage.",
+		`The active node takes a snapshot of the raft storage every interval, stores
+it locally or in an AWS S3 bucket, a Google Cloud Storage bucket or an Azure
+Blob Storage container, and deletes the oldest snapshots beyond the number to
+retain. Credentials are not returned when reading a configuration.`,
+	},
+	"raft-snapshot-auto-status": {
+		"Returns how the snapshots of a configuration of automatic snapshots went.",
+		"",
+	},
 	"raft-snapshot": {
 		"Restores and saves snapshots from the raft cluster.",
 		"",
@@ -15,12 +15,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/fatih/structs"
 	"github.com/go-test/deep"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/helper/random"
 	"github.com/hashicorp/vault/sdk/framework"
@@ -38,6 +40,7 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"remount",
 		"audit",
 		"audit/*",
+		"audit-hash/rotate",
 		"raw",
 		"raw/*",
 		"replication/primary/secondary-token",
@@ -47,7 +50,9 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"replication/dr/reindex",
 		"replication/performance/reindex",
 		"rotate",
+		"seal-migrate",
 		"config/cors",
+		"config/cache",
 		"config/auditing/*",
 		"config/ui/headers/*",
 		"plugins/catalog/*",
@@ -55,7 +60,9 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"revoke-force/*",
 		"leases/revoke-prefix/*",
 		"leases/revoke-force/*",
+		"leases/revoke-by-tag",
 		"leases/lookup/*",
+		"config/wrapping",
 	}
 
 	b := testSystemBackend(t)
@@ -1815,6 +1822,62 @@ func TestSystemBackend_auditHash(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_auditHashRotate(t *testing.T) {
+	c, b, _ := testCoreSystemBackend(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "audit/foo")
+	req.Data["type"] = "noop"
+	req.Data["options"] = map[string]interface{}{
+		"non_hmac_response_keys":   "serial_number",
+		"hmac_key_rotation_period": "720h",
+	}
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+
+	hash := func() string {
+		req := logical.TestRequest(t, logical.UpdateOperation, "audit-hash/foo")
+		req.Data["input"] = "bar"
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return resp.Data["hash"].(string)
+	}
+	before := hash()
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit-hash/rotate")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v %v", err, resp)
+	}
+	if after := hash(); after == before {
+		t.Fatalf("expected hash to change after rotation, got %s", after)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit-hash/rotate")
+	req.Data["path"] = "nope"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request rotating unknown backend, got %v %v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "audit/bar")
+	req.Data["type"] = "noop"
+	req.Data["options"] = map[string]interface{}{
+		"hmac_key_rotation_period": "1s",
+	}
+	if _, err := b.HandleRequest(namespace.RootContext(nil), req); err != logical.ErrInvalidRequest {
+		t.Fatalf("expected invalid request for short rotation period, got %v", err)
+	}
+}
+
 func TestSystemBackend_enableAudit_invalid(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.UpdateOperation, "audit/foo")
@@ -2145,6 +2208,42 @@ func TestSystemBackend_PluginCatalog_CRUD(t *testing.T) {
 		t.Fatalf("expected did not match actual, got %#v\n expected %#v\n", actual, expected)
 	}
 
+	// Runtimes are only supported for auth and secret plugins
+	runtime := map[string]interface{}{
+		"type":  "gvisor",
+		"image": "gcr.io/distroless/static",
+		"cpus":  "0.5",
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "plugins/catalog/database/test-plugin")
+	req.Data["sha_256"] = hex.EncodeToString([]byte{'1'})
+	req.Data["command"] = command
+	req.Data["runtime"] = runtime
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp.Error() == nil {
+		t.Fatalf("expected error, got %v %v", err, resp)
+	}
+
+	req.Path = "plugins/catalog/secret/test-plugin"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp.Error() != nil {
+		t.Fatalf("err: %v %v", err, resp.Error())
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "plugins/catalog/secret/test-plugin")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expectedRuntime := map[string]interface{}{
+		"type":         "gvisor",
+		"image":        "gcr.io/distroless/static",
+		"cpus":         0.5,
+		"memory_bytes": int64(0),
+	}
+	if !reflect.DeepEqual(resp.Data["runtime"], expectedRuntime) {
+		t.Fatalf("expected did not match actual, got %#v\n expected %#v\n", resp.Data["runtime"], expectedRuntime)
+	}
+
 	// Delete plugin
 	req = logical.TestRequest(t, logical.DeleteOperation, "plugins/catalog/database/test-plugin")
 	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
@@ -2159,6 +2258,53 @@ func TestSystemBackend_PluginCatalog_CRUD(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_MetricsCollectors(t *testing.T) {
+	inmemSink := metrics.NewInmemSink(1000000*time.Hour, 2000000*time.Hour)
+	metricSink := metricsutil.NewClusterMetricSink("test-cluster", inmemSink)
+	metricSink.GaugeInterval = time.Hour
+	c, _, _ := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		MetricSink: metricSink,
+	})
+	b := c.systemBackend
+
+	// The processes are listed once they start running.
+	var collectors []map[string]interface{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(collectors) < 2 && time.Now().Before(deadline) {
+		req := logical.TestRequest(t, logical.ReadOperation, "metrics/collectors")
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collectors = resp.Data["collectors"].([]map[string]interface{})
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(collectors) != 2 {
+		t.Fatalf("expected two collectors, got %#v", collectors)
+	}
+
+	exp := map[string]interface{}{
+		"key":                "secret.kv.count",
+		"labels":             map[string]string{"gauge": "kv_secrets_by_mountpoint"},
+		"original_interval":  int64(3600),
+		"current_interval":   int64(3600),
+		"backoff_multiplier": float64(1),
+		"error_count":        0,
+		"last_run":           "",
+		"last_duration_ms":   int64(0),
+		"last_status":        "pending",
+		"last_error":         "",
+		"expensive":          true,
+		"priority":           0,
+	}
+	if !reflect.DeepEqual(collectors[0], exp) {
+		t.Fatalf("got: %#v expect: %#v", collectors[0], exp)
+	}
+	if collectors[1]["key"] != "token.count" {
+		t.Fatalf("bad: %#v", collectors[1])
+	}
+}
+
 func TestSystemBackend_ToolsHash(t *testing.T) {
 	b := testSystemBackend(t)
 	req := logical.TestRequest(t, logical.UpdateOperation, "tools/hash")
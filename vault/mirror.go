@@ -0,0 +1,1336 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/loggers"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// coreMirrorSourcesPath holds the configuration of the mounts mirrored
+	// from this cluster, and coreMirrorSourceStatePath the index of the last
+	// change their target acknowledged
+	coreMirrorSourcesPath     = "core/mirror/sources/"
+	coreMirrorSourceStatePath = "core/mirror/source-state/"
+
+	// coreMirrorSourceLogPath holds, per source, the changes to its mount
+	// which the target has not acknowledged yet
+	coreMirrorSourceLogPath = "core/mirror/source-log/"
+
+	// coreMirrorTargetsPath holds the configuration of the mounts mirrored
+	// to this cluster, and coreMirrorTargetStatePath how far they are
+	coreMirrorTargetsPath     = "core/mirror/targets/"
+	coreMirrorTargetStatePath = "core/mirror/target-state/"
+
+	// mirrorRetryInterval is how long a source waits before connecting to
+	// its target again
+	mirrorRetryInterval = 5 * time.Second
+)
+
+// Types of the messages streamed from a source to a target
+const (
+	mirrorChangeHandshake uint32 = iota + 1
+	mirrorChangePut
+	mirrorChangeDelete
+	mirrorChangeSnapshotBegin
+	mirrorChangeSnapshotEnd
+)
+
+var (
+	errMirrorReadOnly = errors.New("cannot write to a read-only mirror")
+
+	// errMirrorResync is returned when a source has to send a snapshot of
+	// its mount, as changes are missing from its log
+	errMirrorResync = errors.New("changes are missing from the mirror log, resynchronizing")
+)
+
+// mirrorSourceConfig is a mount of this cluster mirrored to another
+// cluster.
+type mirrorSourceConfig struct {
+	Name       string `json:"name"`
+	Mount      string `json:"mount"`
+	Address    string `json:"address"`
+	TargetName string `json:"target_name"`
+	ServerName string `json:"server_name"`
+
+	// Certificate and PrivateKey authenticate the source to the target,
+	// which also presents the certificate
+	Certificate []byte `json:"certificate"`
+	PrivateKey  []byte `json:"private_key"`
+}
+
+type mirrorSourceState struct {
+	AckedIndex uint64 `json:"acked_index"`
+}
+
+// mirrorLogEntry is a change to the mount of a source.
+type mirrorLogEntry struct {
+	Index    uint64 `json:"index"`
+	Type     uint32 `json:"type"`
+	Key      string `json:"key"`
+	Value    []byte `json:"value,omitempty"`
+	PrevHash []byte `json:"prev_hash,omitempty"`
+}
+
+// mirrorTargetConfig is a mount of this cluster mirroring a mount of
+// another cluster.
+type mirrorTargetConfig struct {
+	Name        string `json:"name"`
+	Mount       string `json:"mount"`
+	ServerName  string `json:"server_name"`
+	Certificate []byte `json:"certificate"`
+	PrivateKey  []byte `json:"private_key"`
+}
+
+type mirrorTargetState struct {
+	Cursor           uint64    `json:"cursor"`
+	Checkpoint       uint64    `json:"checkpoint"`
+	LastAppliedTime  time.Time `json:"last_applied_time"`
+	Conflicts        uint64    `json:"conflicts"`
+	LastConflictKey  string    `json:"last_conflict_key"`
+	LastConflictTime time.Time `json:"last_conflict_time"`
+}
+
+// mirrorActivationToken is handed from a target to its source, to connect
+// to it.
+type mirrorActivationToken struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	ServerName  string `json:"server_name"`
+	Certificate []byte `json:"certificate"`
+	PrivateKey  []byte `json:"private_key"`
+}
+
+// mirrorManager runs the sources and the targets of mirrors on the active
+// node.
+type mirrorManager struct {
+	core   *Core
+	logger log.Logger
+
+	// configLock serializes the changes to the configuration of mirrors
+	configLock sync.Mutex
+
+	l       sync.RWMutex
+	sources map[string]*mirrorSource
+	targets map[string]*mirrorTarget
+}
+
+func newMirrorManager(c *Core, logger log.Logger) *mirrorManager {
+	return &mirrorManager{
+		core:    c,
+		logger:  logger,
+		sources: make(map[string]*mirrorSource),
+		targets: make(map[string]*mirrorTarget),
+	}
+}
+
+// setupMirror starts the configured sources and serves the configured
+// targets.
+func (c *Core) setupMirror(ctx context.Context) error {
+	m := c.mirror
+	ctx = namespace.RootContext(ctx)
+
+	targetNames, err := c.barrier.List(ctx, coreMirrorTargetsPath)
+	if err != nil {
+		return errwrap.Wrapf("failed to list mirror targets: {{err}}", err)
+	}
+	for _, name := range targetNames {
+		config := new(mirrorTargetConfig)
+		if err := m.readJSON(ctx, coreMirrorTargetsPath+name, config); err != nil {
+			return err
+		}
+		if err := m.startTarget(ctx, config); err != nil {
+			m.logger.Error("failed to start mirror target", "name", name, "error", err)
+		}
+	}
+
+	sourceNames, err := c.barrier.List(ctx, coreMirrorSourcesPath)
+	if err != nil {
+		return errwrap.Wrapf("failed to list mirror sources: {{err}}", err)
+	}
+	for _, name := range sourceNames {
+		config := new(mirrorSourceConfig)
+		if err := m.readJSON(ctx, coreMirrorSourcesPath+name, config); err != nil {
+			return err
+		}
+		if err := m.startSource(ctx, config); err != nil {
+			m.logger.Error("failed to start mirror source", "name", name, "error", err)
+		}
+	}
+
+	if clusterListener := c.getClusterListener(); clusterListener != nil {
+		clusterListener.AddHandler(consts.MirrorALPN, newMirrorHandler(m, clusterListener.Server()))
+	}
+
+	return nil
+}
+
+// teardownMirror stops the sources and the targets of mirrors on seal or
+// step-down.
+func (c *Core) teardownMirror() {
+	if clusterListener := c.getClusterListener(); clusterListener != nil {
+		clusterListener.StopHandler(consts.MirrorALPN)
+	}
+
+	m := c.mirror
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for name, source := range m.sources {
+		source.stop()
+		delete(m.sources, name)
+	}
+	for name, target := range m.targets {
+		target.stop()
+		delete(m.targets, name)
+	}
+}
+
+// checkMirrorReadOnly refuses writes to the mounts mirroring another
+// cluster.
+func (c *Core) checkMirrorReadOnly(ctx context.Context, req *logical.Request) error {
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation, logical.DeleteOperation:
+	default:
+		return nil
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil || entry.NamespaceID != namespace.RootNamespaceID {
+		return nil
+	}
+
+	c.mirror.l.RLock()
+	defer c.mirror.l.RUnlock()
+	for _, target := range c.mirror.targets {
+		if target.entry.Accessor == entry.Accessor {
+			return logical.CodedError(400, errMirrorReadOnly.Error())
+		}
+	}
+	return nil
+}
+
+func (m *mirrorManager) readJSON(ctx context.Context, key string, out interface{}) error {
+	entry, err := m.core.barrier.Get(ctx, key)
+	if err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to read %q: {{err}}", key), err)
+	}
+	if entry == nil {
+		return nil
+	}
+	if err := jsonutil.DecodeJSON(entry.Value, out); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to decode %q: {{err}}", key), err)
+	}
+	return nil
+}
+
+func (m *mirrorManager) writeJSON(ctx context.Context, key string, in interface{}) error {
+	entry, err := logical.StorageEntryJSON(key, in)
+	if err != nil {
+		return err
+	}
+	if err := m.core.barrier.Put(ctx, entry); err != nil {
+		return errwrap.Wrapf(fmt.Sprintf("failed to write %q: {{err}}", key), err)
+	}
+	return nil
+}
+
+// createTarget makes a mount of this cluster the target of a mirror, and
+// returns the activation token its source connects with.
+func (m *mirrorManager) createTarget(ctx context.Context, name, mount string) (string, error) {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+
+	address := m.core.ClusterAddr()
+	if address == "" {
+		return "", logical.CodedError(400, "mirror targets require a cluster address")
+	}
+	if err := m.checkNewMirror(ctx, coreMirrorTargetsPath+name, mount); err != nil {
+		return "", err
+	}
+
+	serverName, certBytes, keyBytes, err := generateMirrorTLS()
+	if err != nil {
+		return "", err
+	}
+	config := &mirrorTargetConfig{
+		Name:        name,
+		Mount:       mount,
+		ServerName:  serverName,
+		Certificate: certBytes,
+		PrivateKey:  keyBytes,
+	}
+	if err := m.writeJSON(ctx, coreMirrorTargetsPath+name, config); err != nil {
+		return "", err
+	}
+	if err := m.startTarget(ctx, config); err != nil {
+		return "", err
+	}
+
+	return encodeMirrorActivationToken(&mirrorActivationToken{
+		Name:        name,
+		Address:     address,
+		ServerName:  serverName,
+		Certificate: certBytes,
+		PrivateKey:  keyBytes,
+	})
+}
+
+// deleteTarget stops mirroring to a mount, which keeps its data and becomes
+// writable again.
+func (m *mirrorManager) deleteTarget(ctx context.Context, name string) error {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+
+	m.l.Lock()
+	target := m.targets[name]
+	delete(m.targets, name)
+	m.l.Unlock()
+	if target != nil {
+		target.stop()
+	}
+
+	if err := m.core.barrier.Delete(ctx, coreMirrorTargetsPath+name); err != nil {
+		return err
+	}
+	return m.core.barrier.Delete(ctx, coreMirrorTargetStatePath+name)
+}
+
+// createSource mirrors a mount of this cluster to the target the activation
+// token was issued by.
+func (m *mirrorManager) createSource(ctx context.Context, name, mount, activationToken, address string) error {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+
+	token, err := decodeMirrorActivationToken(activationToken)
+	if err != nil {
+		return logical.CodedError(400, err.Error())
+	}
+	if err := m.checkNewMirror(ctx, coreMirrorSourcesPath+name, mount); err != nil {
+		return err
+	}
+	if address == "" {
+		address = token.Address
+	}
+
+	config := &mirrorSourceConfig{
+		Name:        name,
+		Mount:       mount,
+		Address:     address,
+		TargetName:  token.Name,
+		ServerName:  token.ServerName,
+		Certificate: token.Certificate,
+		PrivateKey:  token.PrivateKey,
+	}
+	if err := m.writeJSON(ctx, coreMirrorSourcesPath+name, config); err != nil {
+		return err
+	}
+	return m.startSource(ctx, config)
+}
+
+// deleteSource stops mirroring a mount, and removes the changes its target
+// has not acknowledged.
+func (m *mirrorManager) deleteSource(ctx context.Context, name string) error {
+	m.configLock.Lock()
+	defer m.configLock.Unlock()
+
+	m.l.Lock()
+	source := m.sources[name]
+	delete(m.sources, name)
+	m.l.Unlock()
+	if source != nil {
+		source.stop()
+	}
+
+	if err := m.core.barrier.Delete(ctx, coreMirrorSourcesPath+name); err != nil {
+		return err
+	}
+	if err := m.core.barrier.Delete(ctx, coreMirrorSourceStatePath+name); err != nil {
+		return err
+	}
+	keys, err := m.core.barrier.List(ctx, coreMirrorSourceLogPath+name+"/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := m.core.barrier.Delete(ctx, coreMirrorSourceLogPath+name+"/"+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNewMirror verifies that a mirror does not exist yet, and that its
+// mount can be mirrored.
+func (m *mirrorManager) checkNewMirror(ctx context.Context, key, mount string) error {
+	existing, err := m.core.barrier.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return logical.CodedError(400, "mirror already exists")
+	}
+	if _, _, _, err := m.mirrorMount(ctx, mount); err != nil {
+		return logical.CodedError(400, err.Error())
+	}
+	if m.mountInUse(mount) {
+		return logical.CodedError(400, fmt.Sprintf("mount at %q is already mirrored", mount))
+	}
+	return nil
+}
+
+// mirrorMount returns the KV mount at the given path of the root namespace,
+// along with its storage view and its backend.
+func (m *mirrorManager) mirrorMount(ctx context.Context, mount string) (*MountEntry, *BarrierView, logical.Backend, error) {
+	entry := m.core.router.MatchingMountEntry(ctx, mount)
+	if entry == nil || entry.Table != mountTableType || entry.Path != mount {
+		return nil, nil, nil, fmt.Errorf("no mount at %q", mount)
+	}
+	if entry.Type != "kv" {
+		return nil, nil, nil, fmt.Errorf("mount at %q is not a kv mount", mount)
+	}
+
+	view, ok := m.core.router.MatchingStorageByAPIPath(ctx, mount).(*BarrierView)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("no storage for mount at %q", mount)
+	}
+	backend := m.core.router.MatchingBackend(ctx, mount)
+	if backend == nil {
+		return nil, nil, nil, fmt.Errorf("no backend for mount at %q", mount)
+	}
+	return entry, view, backend, nil
+}
+
+// mountInUse returns whether a mount is already the source or the target
+// of a mirror.
+func (m *mirrorManager) mountInUse(mount string) bool {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	for _, source := range m.sources {
+		if source.config.Mount == mount {
+			return true
+		}
+	}
+	for _, target := range m.targets {
+		if target.config.Mount == mount {
+			return true
+		}
+	}
+	return false
+}
+
+// generateMirrorTLS generates the self-signed certificate and the key used
+// by both ends of a mirror.
+func generateMirrorTLS() (serverName string, certBytes, keyBytes []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	serverName = fmt.Sprintf("mirror-%s", id)
+	template := &x509.Certificate{
+		Subject: pkix.Name{
+			CommonName: serverName,
+		},
+		DNSNames: []string{serverName},
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement | x509.KeyUsageCertSign,
+		SerialNumber:          big.NewInt(mathrand.Int63()),
+		NotBefore:             time.Now().Add(-30 * time.Second),
+		NotAfter:              time.Now().Add(262980 * time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	certBytes, err = x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return "", nil, nil, errwrap.Wrapf("unable to generate mirror certificate: {{err}}", err)
+	}
+	keyBytes, err = x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return serverName, certBytes, keyBytes, nil
+}
+
+func mirrorTLSCertificate(certBytes, keyBytes []byte) (*tls.Certificate, *x509.Certificate, error) {
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("failed to parse mirror certificate: {{err}}", err)
+	}
+	key, err := x509.ParseECPrivateKey(keyBytes)
+	if err != nil {
+		return nil, nil, errwrap.Wrapf("failed to parse mirror key: {{err}}", err)
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{certBytes},
+		PrivateKey:  key,
+		Leaf:        cert,
+	}, cert, nil
+}
+
+func mirrorHash(value []byte) []byte {
+	if value == nil {
+		return nil
+	}
+	sum := sha256.Sum256(value)
+	return sum[:]
+}
+
+func mountVersion(entry *MountEntry) string {
+	if entry.Options == nil {
+		return ""
+	}
+	version := entry.Options["version"]
+	if version == "1" {
+		return ""
+	}
+	return version
+}
+
+func encodeMirrorActivationToken(token *mirrorActivationToken) (string, error) {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeMirrorActivationToken(encoded string) (*mirrorActivationToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.New("malformed activation token")
+	}
+	token := new(mirrorActivationToken)
+	if err := jsonutil.DecodeJSON(raw, token); err != nil {
+		return nil, errors.New("malformed activation token")
+	}
+	if token.Name == "" || token.Address == "" || len(token.Certificate) == 0 || len(token.PrivateKey) == 0 {
+		return nil, errors.New("incomplete activation token")
+	}
+	return token, nil
+}
+
+// mirrorSource records the changes to a mount and streams them to the
+// target.
+type mirrorSource struct {
+	manager *mirrorManager
+	config  *mirrorSourceConfig
+	entry   *MountEntry
+	view    *BarrierView
+	logger  log.Logger
+
+	// writeLock serializes the writes to the mount with their log entries,
+	// so that the log is in the order of the writes
+	writeLock sync.Mutex
+	lastIndex uint64
+	resync    bool
+
+	notifyCh chan struct{}
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+
+	statusLock sync.RWMutex
+	connected  bool
+	ackedIndex uint64
+	lastError  string
+}
+
+// startSource starts recording the changes to the mount of the source and
+// streaming them to its target.
+func (m *mirrorManager) startSource(ctx context.Context, config *mirrorSourceConfig) error {
+	entry, view, _, err := m.mirrorMount(ctx, config.Mount)
+	if err != nil {
+		return err
+	}
+
+	state := new(mirrorSourceState)
+	if err := m.readJSON(ctx, coreMirrorSourceStatePath+config.Name, state); err != nil {
+		return err
+	}
+
+	s := &mirrorSource{
+		manager:    m,
+		config:     config,
+		entry:      entry,
+		view:       view,
+		logger:     m.logger.With("source", config.Name),
+		lastIndex:  state.AckedIndex,
+		ackedIndex: state.AckedIndex,
+		notifyCh:   make(chan struct{}, 1),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+
+	keys, err := m.core.barrier.List(ctx, s.logPrefix())
+	if err != nil {
+		return errwrap.Wrapf("failed to list mirror log: {{err}}", err)
+	}
+	for _, key := range keys {
+		index, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		if index > s.lastIndex {
+			s.lastIndex = index
+		}
+	}
+
+	m.l.Lock()
+	m.sources[config.Name] = s
+	m.l.Unlock()
+
+	view.setWriteHook(s.record)
+	go s.run()
+	return nil
+}
+
+func (s *mirrorSource) stop() {
+	s.view.setWriteHook(nil)
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *mirrorSource) logPrefix() string {
+	return coreMirrorSourceLogPath + s.config.Name + "/"
+}
+
+func (s *mirrorSource) logKey(index uint64) string {
+	return fmt.Sprintf("%s%020d", s.logPrefix(), index)
+}
+
+// record is the write hook of the mount, which appends the writes to the
+// log.
+func (s *mirrorSource) record(ctx context.Context, key string, entry *logical.StorageEntry, write func() error) error {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	key = strings.TrimPrefix(key, s.view.Prefix())
+	prev, err := s.view.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	if prev == nil && entry == nil {
+		return nil
+	}
+
+	logEntry := &mirrorLogEntry{
+		Index: s.lastIndex + 1,
+		Type:  mirrorChangeDelete,
+		Key:   key,
+	}
+	if prev != nil {
+		logEntry.PrevHash = mirrorHash(prev.Value)
+	}
+	if entry != nil {
+		logEntry.Type = mirrorChangePut
+		logEntry.Value = entry.Value
+	}
+
+	// The write is done, so failing to log it is not returned to the caller,
+	// but the target has to be resynchronized
+	if err := s.manager.writeJSON(ctx, s.logKey(logEntry.Index), logEntry); err != nil {
+		s.logger.Error("failed to record change, resynchronizing the target", "error", err)
+		s.resync = true
+	}
+	s.lastIndex = logEntry.Index
+
+	select {
+	case s.notifyCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *mirrorSource) setStatus(connected bool, err error) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.connected = connected
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// run streams the changes to the target, connecting again whenever the
+// stream ends.
+func (s *mirrorSource) run() {
+	defer close(s.doneCh)
+
+	for {
+		ctx, cancel := context.WithCancel(namespace.RootContext(nil))
+		go func() {
+			select {
+			case <-s.stopCh:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := s.stream(ctx)
+		cancel()
+		s.setStatus(false, err)
+
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+		if err != nil {
+			s.logger.Warn("mirror stream ended", "error", err)
+		}
+
+		select {
+		case <-s.stopCh:
+			return
+		case <-time.After(mirrorRetryInterval):
+		}
+	}
+}
+
+func (s *mirrorSource) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	cert, caCert, err := mirrorTLSCertificate(s.config.Certificate, s.config.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		RootCAs:      pool,
+		ServerName:   s.config.ServerName,
+		NextProtos:   []string{consts.MirrorALPN},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	addr := s.config.Address
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		addr = u.Host
+	}
+
+	// The TLS connection is set up by the dialer, rather than by gRPC, to
+	// negotiate the protocol on the cluster port
+	return grpc.DialContext(ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithDialer(func(addr string, timeout time.Duration) (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+		}))
+}
+
+// stream sends the changes which the target has not applied, then the new
+// changes as they are made, until the stream fails or the source stops.
+func (s *mirrorSource) stream(ctx context.Context) error {
+	dialCtx, dialCancel := context.WithTimeout(ctx, 30*time.Second)
+	conn, err := s.dial(dialCtx)
+	dialCancel()
+	if err != nil {
+		return errwrap.Wrapf("failed to connect to target: {{err}}", err)
+	}
+	defer conn.Close()
+
+	client, err := NewMirrorClient(conn).Stream(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = client.Send(&MirrorChange{
+		Type:         mirrorChangeHandshake,
+		Name:         s.config.TargetName,
+		MountType:    s.entry.Type,
+		MountVersion: mountVersion(s.entry),
+	})
+	if err != nil {
+		return err
+	}
+	ack, err := client.Recv()
+	if err != nil {
+		return err
+	}
+	cursor := ack.Cursor
+
+	s.setStatus(true, nil)
+	s.logger.Info("connected to mirror target", "cursor", cursor)
+
+	recvErrCh := make(chan error, 1)
+	go func() {
+		for {
+			ack, err := client.Recv()
+			if err != nil {
+				recvErrCh <- err
+				return
+			}
+			s.acknowledge(ctx, ack.Cursor)
+		}
+	}()
+
+	s.writeLock.Lock()
+	lastIndex, resync := s.lastIndex, s.resync
+	s.writeLock.Unlock()
+	if cursor == 0 || cursor > lastIndex || resync || !s.hasLogEntry(ctx, cursor+1, lastIndex) {
+		if cursor, err = s.sendSnapshot(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	for {
+		s.writeLock.Lock()
+		lastIndex, resync = s.lastIndex, s.resync
+		s.writeLock.Unlock()
+		if resync {
+			return errMirrorResync
+		}
+
+		for index := cursor + 1; index <= lastIndex; index++ {
+			logEntry := new(mirrorLogEntry)
+			if err := s.manager.readJSON(ctx, s.logKey(index), logEntry); err != nil {
+				return err
+			}
+			if logEntry.Index != index {
+				s.writeLock.Lock()
+				s.resync = true
+				s.writeLock.Unlock()
+				return errMirrorResync
+			}
+
+			err := client.Send(&MirrorChange{
+				Type:     logEntry.Type,
+				Index:    logEntry.Index,
+				Key:      logEntry.Key,
+				Value:    logEntry.Value,
+				PrevHash: logEntry.PrevHash,
+			})
+			if err != nil {
+				return err
+			}
+			metrics.IncrCounter([]string{"mirror", "source", "changes"}, 1)
+			cursor = index
+		}
+
+		select {
+		case <-s.notifyCh:
+		case err := <-recvErrCh:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// hasLogEntry returns whether the log starts at most at the given index,
+// so that the target can catch up from it.
+func (s *mirrorSource) hasLogEntry(ctx context.Context, index, lastIndex uint64) bool {
+	if index > lastIndex {
+		return true
+	}
+	entry, err := s.manager.core.barrier.Get(ctx, s.logKey(index))
+	return err == nil && entry != nil
+}
+
+// sendSnapshot sends all the keys of the mount, and returns the index of
+// the log the target is at afterwards.
+func (s *mirrorSource) sendSnapshot(ctx context.Context, client Mirror_StreamClient) (uint64, error) {
+	s.writeLock.Lock()
+	start := s.lastIndex
+	s.resync = false
+	s.writeLock.Unlock()
+
+	s.logger.Info("sending snapshot to mirror target", "index", start)
+	if err := client.Send(&MirrorChange{Type: mirrorChangeSnapshotBegin, Index: start}); err != nil {
+		return 0, err
+	}
+
+	var sendErr error
+	err := logical.ScanView(ctx, s.view, func(key string) {
+		if sendErr != nil {
+			return
+		}
+		entry, err := s.view.Get(ctx, key)
+		if err != nil {
+			sendErr = err
+			return
+		}
+		if entry == nil {
+			return
+		}
+		sendErr = client.Send(&MirrorChange{
+			Type:  mirrorChangePut,
+			Key:   key,
+			Value: entry.Value,
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	if sendErr != nil {
+		return 0, sendErr
+	}
+
+	s.writeLock.Lock()
+	checkpoint := s.lastIndex
+	s.writeLock.Unlock()
+
+	err = client.Send(&MirrorChange{
+		Type:       mirrorChangeSnapshotEnd,
+		Index:      start,
+		Checkpoint: checkpoint,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return start, nil
+}
+
+// acknowledge records that the target applied the changes up to the
+// cursor, and removes them from the log.
+func (s *mirrorSource) acknowledge(ctx context.Context, cursor uint64) {
+	s.statusLock.Lock()
+	prev := s.ackedIndex
+	if cursor > prev {
+		s.ackedIndex = cursor
+	}
+	s.statusLock.Unlock()
+
+	if cursor <= prev {
+		return
+	}
+
+	if err := s.manager.writeJSON(ctx, coreMirrorSourceStatePath+s.config.Name, &mirrorSourceState{AckedIndex: cursor}); err != nil {
+		s.logger.Error("failed to record acknowledged index", "error", err)
+		return
+	}
+	for index := prev + 1; index <= cursor; index++ {
+		if err := s.manager.core.barrier.Delete(ctx, s.logKey(index)); err != nil {
+			s.logger.Error("failed to remove acknowledged change", "index", index, "error", err)
+			return
+		}
+	}
+}
+
+func (s *mirrorSource) status() map[string]interface{} {
+	s.writeLock.Lock()
+	lastIndex := s.lastIndex
+	s.writeLock.Unlock()
+
+	s.statusLock.RLock()
+	defer s.statusLock.RUnlock()
+	return map[string]interface{}{
+		"connected":   s.connected,
+		"last_index":  lastIndex,
+		"acked_index": s.ackedIndex,
+		"last_error":  s.lastError,
+	}
+}
+
+// mirrorTarget applies the changes streamed by the source to a mount, which
+// is read-only otherwise.
+type mirrorTarget struct {
+	manager *mirrorManager
+	config  *mirrorTargetConfig
+	entry   *MountEntry
+	backend logical.Backend
+	logger  log.Logger
+
+	// routerView is the view of the mount used by requests and the backend,
+	// which is read-only, and view the one the changes are applied through
+	routerView *BarrierView
+	view       *BarrierView
+
+	cert   *tls.Certificate
+	caCert *x509.Certificate
+
+	l         sync.Mutex
+	state     *mirrorTargetState
+	connected bool
+
+	// snapshotKeys are the keys received since the start of a snapshot
+	snapshotKeys map[string]struct{}
+}
+
+// startTarget makes the mount of the target read-only and accepts the
+// stream of its source.
+func (m *mirrorManager) startTarget(ctx context.Context, config *mirrorTargetConfig) error {
+	entry, routerView, backend, err := m.mirrorMount(ctx, config.Mount)
+	if err != nil {
+		return err
+	}
+	cert, caCert, err := mirrorTLSCertificate(config.Certificate, config.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	state := new(mirrorTargetState)
+	if err := m.readJSON(ctx, coreMirrorTargetStatePath+config.Name, state); err != nil {
+		return err
+	}
+
+	t := &mirrorTarget{
+		manager:    m,
+		config:     config,
+		entry:      entry,
+		backend:    backend,
+		logger:     m.logger.With("target", config.Name),
+		routerView: routerView,
+		view:       NewBarrierView(m.core.barrier, entry.ViewPath()),
+		cert:       cert,
+		caCert:     caCert,
+		state:      state,
+	}
+	routerView.setReadOnlyErr(errMirrorReadOnly)
+
+	m.l.Lock()
+	m.targets[config.Name] = t
+	m.l.Unlock()
+	return nil
+}
+
+func (t *mirrorTarget) stop() {
+	t.routerView.setReadOnlyErr(nil)
+}
+
+// attach marks the target as connected to its source, which can only have
+// one stream at a time.
+func (t *mirrorTarget) attach() (uint64, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	if t.connected {
+		return 0, errors.New("source is already connected")
+	}
+	t.connected = true
+	t.snapshotKeys = nil
+	return t.state.Cursor, nil
+}
+
+func (t *mirrorTarget) detach() {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	t.connected = false
+	t.snapshotKeys = nil
+}
+
+// apply applies a change streamed by the source, and returns whether the
+// source has to be acknowledged.
+func (t *mirrorTarget) apply(ctx context.Context, change *MirrorChange) (bool, error) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	switch change.Type {
+	case mirrorChangeSnapshotBegin:
+		t.snapshotKeys = make(map[string]struct{})
+		return false, nil
+
+	case mirrorChangeSnapshotEnd:
+		if t.snapshotKeys == nil {
+			return false, errors.New("snapshot end without a snapshot")
+		}
+
+		// Keys which are not in the snapshot were deleted on the source
+		keys, err := logical.CollectKeys(ctx, t.view)
+		if err != nil {
+			return false, err
+		}
+		for _, key := range keys {
+			if _, ok := t.snapshotKeys[key]; ok {
+				continue
+			}
+			if err := t.view.Delete(ctx, key); err != nil {
+				return false, err
+			}
+			t.backend.InvalidateKey(ctx, key)
+		}
+
+		t.snapshotKeys = nil
+		t.state.Cursor = change.Index
+		t.state.Checkpoint = change.Checkpoint
+		t.state.LastAppliedTime = time.Now()
+		return true, t.manager.writeJSON(ctx, coreMirrorTargetStatePath+t.config.Name, t.state)
+
+	case mirrorChangePut, mirrorChangeDelete:
+	default:
+		return false, fmt.Errorf("unknown change type %d", change.Type)
+	}
+
+	var value []byte
+	if change.Type == mirrorChangePut {
+		value = change.Value
+	}
+
+	inSnapshot := t.snapshotKeys != nil
+	if !inSnapshot && change.Index > t.state.Checkpoint {
+		current, err := t.view.Get(ctx, change.Key)
+		if err != nil {
+			return false, err
+		}
+		var currentHash []byte
+		if current != nil {
+			currentHash = mirrorHash(current.Value)
+		}
+
+		// The key is expected to have the value it had on the source before
+		// the change, unless the change was applied already
+		if !bytes.Equal(currentHash, change.PrevHash) && !bytes.Equal(currentHash, mirrorHash(value)) {
+			t.logger.Warn("mirror conflict, applying the value of the source", "key", change.Key, "index", change.Index)
+			metrics.IncrCounter([]string{"mirror", "target", "conflicts"}, 1)
+			t.state.Conflicts++
+			t.state.LastConflictKey = change.Key
+			t.state.LastConflictTime = time.Now()
+		}
+	}
+
+	var err error
+	if change.Type == mirrorChangePut {
+		err = t.view.Put(ctx, &logical.StorageEntry{
+			Key:   change.Key,
+			Value: value,
+		})
+	} else {
+		err = t.view.Delete(ctx, change.Key)
+	}
+	if err != nil {
+		return false, err
+	}
+	t.backend.InvalidateKey(ctx, change.Key)
+
+	if inSnapshot {
+		t.snapshotKeys[change.Key] = struct{}{}
+		return false, nil
+	}
+
+	metrics.IncrCounter([]string{"mirror", "target", "changes"}, 1)
+	t.state.Cursor = change.Index
+	t.state.LastAppliedTime = time.Now()
+	return true, t.manager.writeJSON(ctx, coreMirrorTargetStatePath+t.config.Name, t.state)
+}
+
+func (t *mirrorTarget) status() map[string]interface{} {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	status := map[string]interface{}{
+		"connected":          t.connected,
+		"cursor":             t.state.Cursor,
+		"conflicts":          t.state.Conflicts,
+		"last_conflict_key":  t.state.LastConflictKey,
+		"last_applied_time":  "",
+		"last_conflict_time": "",
+	}
+	if !t.state.LastAppliedTime.IsZero() {
+		status["last_applied_time"] = t.state.LastAppliedTime.Format(time.RFC3339Nano)
+	}
+	if !t.state.LastConflictTime.IsZero() {
+		status["last_conflict_time"] = t.state.LastConflictTime.Format(time.RFC3339Nano)
+	}
+	return status
+}
+
+// mirrorRPCServer receives the streams of the sources of the targets.
+type mirrorRPCServer struct {
+	manager *mirrorManager
+}
+
+func (s *mirrorRPCServer) Stream(stream Mirror_StreamServer) error {
+	handshake, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if handshake.Type != mirrorChangeHandshake {
+		return errors.New("expected a handshake")
+	}
+
+	s.manager.l.RLock()
+	target := s.manager.targets[handshake.Name]
+	s.manager.l.RUnlock()
+	if target == nil {
+		return fmt.Errorf("unknown mirror target %q", handshake.Name)
+	}
+
+	// The source authenticates with the certificate of the target
+	p, ok := peer.FromContext(stream.Context())
+	if !ok {
+		return errors.New("missing peer")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 || !bytes.Equal(tlsInfo.State.PeerCertificates[0].Raw, target.config.Certificate) {
+		return errors.New("peer certificate does not match the mirror target")
+	}
+
+	if handshake.MountType != target.entry.Type || handshake.MountVersion != mountVersion(target.entry) {
+		return fmt.Errorf("source mount of type %q version %q does not match the target mount", handshake.MountType, handshake.MountVersion)
+	}
+
+	cursor, err := target.attach()
+	if err != nil {
+		return err
+	}
+	defer target.detach()
+
+	target.logger.Info("mirror source connected", "cursor", cursor)
+	if err := stream.Send(&MirrorAck{Cursor: cursor}); err != nil {
+		return err
+	}
+
+	ctx := namespace.RootContext(stream.Context())
+	for {
+		change, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		ack, err := target.apply(ctx, change)
+		if err != nil {
+			target.logger.Error("failed to apply mirrored change", "key", change.Key, "index", change.Index, "error", err)
+			return err
+		}
+		if ack {
+			if err := stream.Send(&MirrorAck{Cursor: change.Index}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mirrorHandler serves the streams of the sources on the cluster port.
+type mirrorHandler struct {
+	manager   *mirrorManager
+	server    *http2.Server
+	rpcServer *grpc.Server
+	logger    log.Logger
+	stopCh    chan struct{}
+}
+
+func newMirrorHandler(m *mirrorManager, server *http2.Server) *mirrorHandler {
+	rpcServer := grpc.NewServer()
+	RegisterMirrorServer(rpcServer, &mirrorRPCServer{
+		manager: m,
+	})
+
+	return &mirrorHandler{
+		manager:   m,
+		server:    server,
+		rpcServer: rpcServer,
+		logger:    m.logger,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// ServerLookup satisfies the ClusterHandler interface and returns the
+// certificate of the target the source connects to.
+func (h *mirrorHandler) ServerLookup(ctx context.Context, clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	h.manager.l.RLock()
+	defer h.manager.l.RUnlock()
+
+	for _, target := range h.manager.targets {
+		if target.config.ServerName == clientHello.ServerName {
+			return target.cert, nil
+		}
+	}
+	return nil, fmt.Errorf("no mirror target for server name %q", clientHello.ServerName)
+}
+
+// CALookup satisfies the ClusterHandler interface and returns the
+// certificates of the targets, which their sources authenticate with.
+func (h *mirrorHandler) CALookup(ctx context.Context) ([]*x509.Certificate, error) {
+	h.manager.l.RLock()
+	defer h.manager.l.RUnlock()
+
+	var certs []*x509.Certificate
+	for _, target := range h.manager.targets {
+		certs = append(certs, target.caCert)
+	}
+	return certs, nil
+}
+
+// Handoff serves a mirror connection.
+func (h *mirrorHandler) Handoff(ctx context.Context, shutdownWg *sync.WaitGroup, closeCh chan struct{}, tlsConn *tls.Conn) error {
+	h.logger.Debug("got mirror connection")
+
+	shutdownWg.Add(2)
+	// quitCh is used to close the connection and the second
+	// goroutine if the server closes before closeCh.
+	quitCh := make(chan struct{})
+	go func() {
+		select {
+		case <-quitCh:
+		case <-closeCh:
+		case <-h.stopCh:
+		}
+		tlsConn.Close()
+		shutdownWg.Done()
+	}()
+
+	// The source dials without gRPC managing the TLS state, so the requests
+	// are not seen as being over TLS, and the peer certificate would not be
+	// available to the stream otherwise
+	state := tlsConn.ConnectionState()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.TLS = &state
+		h.rpcServer.ServeHTTP(w, r)
+	})
+
+	go func() {
+		h.server.ServeConn(tlsConn, &http2.ServeConnOpts{
+			Handler: handler,
+			BaseConfig: &http.Server{
+				ErrorLog: loggers.StandardLogger(h.logger),
+			},
+		})
+
+		close(quitCh)
+		shutdownWg.Done()
+	}()
+
+	return nil
+}
+
+// Stop stops the mirror server and closes connections.
+func (h *mirrorHandler) Stop() error {
+	close(h.stopCh)
+	h.rpcServer.Stop()
+	return nil
+}
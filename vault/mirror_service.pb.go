@@ -0,0 +1,423 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.24.0
+// 	protoc        v3.11.4
+// source: vault/mirror_service.proto
+
+package vault
+
+import (
+	context "context"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type MirrorChange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Type is the kind of message: a handshake, a change to a key, or the
+	// start or end of a snapshot
+	Type uint32 `protobuf:"varint,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Index is the position of the change in the log of the source
+	Index uint64 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	// Key is the storage key of the mount which changed
+	Key string `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	// Value is the new value of the key, empty for a deletion
+	Value []byte `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// PrevHash is the SHA-256 hash of the value the key had on the source
+	// before the change, empty if the key did not exist
+	PrevHash []byte `protobuf:"bytes,5,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	// Name is the name of the target, sent with the handshake
+	Name string `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	// MountType and MountVersion describe the source mount, sent with the
+	// handshake
+	MountType    string `protobuf:"bytes,7,opt,name=mount_type,json=mountType,proto3" json:"mount_type,omitempty"`
+	MountVersion string `protobuf:"bytes,8,opt,name=mount_version,json=mountVersion,proto3" json:"mount_version,omitempty"`
+	// Checkpoint is the last index of the log when a snapshot ended. Changes
+	// up to it may already be reflected in the snapshot.
+	Checkpoint uint64 `protobuf:"varint,9,opt,name=checkpoint,proto3" json:"checkpoint,omitempty"`
+}
+
+func (x *MirrorChange) Reset() {
+	*x = MirrorChange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vault_mirror_service_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MirrorChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MirrorChange) ProtoMessage() {}
+
+func (x *MirrorChange) ProtoReflect() protoreflect.Message {
+	mi := &file_vault_mirror_service_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MirrorChange.ProtoReflect.Descriptor instead.
+func (*MirrorChange) Descriptor() ([]byte, []int) {
+	return file_vault_mirror_service_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MirrorChange) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *MirrorChange) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *MirrorChange) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MirrorChange) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *MirrorChange) GetPrevHash() []byte {
+	if x != nil {
+		return x.PrevHash
+	}
+	return nil
+}
+
+func (x *MirrorChange) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MirrorChange) GetMountType() string {
+	if x != nil {
+		return x.MountType
+	}
+	return ""
+}
+
+func (x *MirrorChange) GetMountVersion() string {
+	if x != nil {
+		return x.MountVersion
+	}
+	return ""
+}
+
+func (x *MirrorChange) GetCheckpoint() uint64 {
+	if x != nil {
+		return x.Checkpoint
+	}
+	return 0
+}
+
+type MirrorAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Cursor is the index of the last change applied by the target
+	Cursor uint64 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+}
+
+func (x *MirrorAck) Reset() {
+	*x = MirrorAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vault_mirror_service_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MirrorAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MirrorAck) ProtoMessage() {}
+
+func (x *MirrorAck) ProtoReflect() protoreflect.Message {
+	mi := &file_vault_mirror_service_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MirrorAck.ProtoReflect.Descriptor instead.
+func (*MirrorAck) Descriptor() ([]byte, []int) {
+	return file_vault_mirror_service_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MirrorAck) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+var File_vault_mirror_service_proto protoreflect.FileDescriptor
+
+var file_vault_mirror_service_proto_rawDesc = []byte{
+	0x0a, 0x1a, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2f, 0x6d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x76, 0x61,
+	0x75, 0x6c, 0x74, 0x22, 0xf5, 0x01, 0x0a, 0x0c, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x43, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x76, 0x5f, 0x68,
+	0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x72, 0x65, 0x76, 0x48,
+	0x61, 0x73, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x6d, 0x6f, 0x75, 0x6e, 0x74,
+	0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6d, 0x6f, 0x75,
+	0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x5f,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6d,
+	0x6f, 0x75, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1e, 0x0a, 0x0a, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x0a, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x22, 0x23, 0x0a, 0x09, 0x4d,
+	0x69, 0x72, 0x72, 0x6f, 0x72, 0x41, 0x63, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73,
+	0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72,
+	0x32, 0x3f, 0x0a, 0x06, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x35, 0x0a, 0x06, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x12, 0x13, 0x2e, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2e, 0x4d, 0x69, 0x72,
+	0x72, 0x6f, 0x72, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x1a, 0x10, 0x2e, 0x76, 0x61, 0x75, 0x6c,
+	0x74, 0x2e, 0x4d, 0x69, 0x72, 0x72, 0x6f, 0x72, 0x41, 0x63, 0x6b, 0x22, 0x00, 0x28, 0x01, 0x30,
+	0x01, 0x42, 0x22, 0x5a, 0x20, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x68, 0x61, 0x73, 0x68, 0x69, 0x63, 0x6f, 0x72, 0x70, 0x2f, 0x76, 0x61, 0x75, 0x6c, 0x74, 0x2f,
+	0x76, 0x61, 0x75, 0x6c, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_vault_mirror_service_proto_rawDescOnce sync.Once
+	file_vault_mirror_service_proto_rawDescData = file_vault_mirror_service_proto_rawDesc
+)
+
+func file_vault_mirror_service_proto_rawDescGZIP() []byte {
+	file_vault_mirror_service_proto_rawDescOnce.Do(func() {
+		file_vault_mirror_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_vault_mirror_service_proto_rawDescData)
+	})
+	return file_vault_mirror_service_proto_rawDescData
+}
+
+var file_vault_mirror_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_vault_mirror_service_proto_goTypes = []interface{}{
+	(*MirrorChange)(nil), // 0: vault.MirrorChange
+	(*MirrorAck)(nil),    // 1: vault.MirrorAck
+}
+var file_vault_mirror_service_proto_depIdxs = []int32{
+	0, // 0: vault.Mirror.Stream:input_type -> vault.MirrorChange
+	1, // 1: vault.Mirror.Stream:output_type -> vault.MirrorAck
+	1, // [1:2] is the sub-list for method output_type
+	0, // [0:1] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_vault_mirror_service_proto_init() }
+func file_vault_mirror_service_proto_init() {
+	if File_vault_mirror_service_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_vault_mirror_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MirrorChange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vault_mirror_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MirrorAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_vault_mirror_service_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vault_mirror_service_proto_goTypes,
+		DependencyIndexes: file_vault_mirror_service_proto_depIdxs,
+		MessageInfos:      file_vault_mirror_service_proto_msgTypes,
+	}.Build()
+	File_vault_mirror_service_proto = out.File
+	file_vault_mirror_service_proto_rawDesc = nil
+	file_vault_mirror_service_proto_goTypes = nil
+	file_vault_mirror_service_proto_depIdxs = nil
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// MirrorClient is the client API for Mirror service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MirrorClient interface {
+	Stream(ctx context.Context, opts ...grpc.CallOption) (Mirror_StreamClient, error)
+}
+
+type mirrorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMirrorClient(cc grpc.ClientConnInterface) MirrorClient {
+	return &mirrorClient{cc}
+}
+
+func (c *mirrorClient) Stream(ctx context.Context, opts ...grpc.CallOption) (Mirror_StreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Mirror_serviceDesc.Streams[0], "/vault.Mirror/Stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mirrorStreamClient{stream}
+	return x, nil
+}
+
+type Mirror_StreamClient interface {
+	Send(*MirrorChange) error
+	Recv() (*MirrorAck, error)
+	grpc.ClientStream
+}
+
+type mirrorStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *mirrorStreamClient) Send(m *MirrorChange) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *mirrorStreamClient) Recv() (*MirrorAck, error) {
+	m := new(MirrorAck)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MirrorServer is the server API for Mirror service.
+type MirrorServer interface {
+	Stream(Mirror_StreamServer) error
+}
+
+// UnimplementedMirrorServer can be embedded to have forward compatible implementations.
+type UnimplementedMirrorServer struct {
+}
+
+func (*UnimplementedMirrorServer) Stream(Mirror_StreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+
+func RegisterMirrorServer(s *grpc.Server, srv MirrorServer) {
+	s.RegisterService(&_Mirror_serviceDesc, srv)
+}
+
+func _Mirror_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MirrorServer).Stream(&mirrorStreamServer{stream})
+}
+
+type Mirror_StreamServer interface {
+	Send(*MirrorAck) error
+	Recv() (*MirrorChange, error)
+	grpc.ServerStream
+}
+
+type mirrorStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *mirrorStreamServer) Send(m *MirrorAck) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *mirrorStreamServer) Recv() (*MirrorChange, error) {
+	m := new(MirrorChange)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var _Mirror_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "vault.Mirror",
+	HandlerType: (*MirrorServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Mirror_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "vault/mirror_service.proto",
+}
@@ -245,6 +245,11 @@ type MountConfig struct {
 	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
 	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
 	TokenType                 logical.TokenType     `json:"token_type" structs:"token_type" mapstructure:"token_type"`
+	GroupSyncInterval         time.Duration         `json:"group_sync_interval,omitempty" structs:"group_sync_interval" mapstructure:"group_sync_interval"`
+
+	// PluginVersion pins the version of the external plugin of the mount
+	// registered in the catalog.
+	PluginVersion string `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -263,6 +268,8 @@ type APIMountConfig struct {
 	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
 	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
 	TokenType                 string                `json:"token_type" structs:"token_type" mapstructure:"token_type"`
+	GroupSyncInterval         string                `json:"group_sync_interval,omitempty" structs:"group_sync_interval" mapstructure:"group_sync_interval"`
+	PluginVersion             string                `json:"plugin_version,omitempty" structs:"plugin_version,omitempty" mapstructure:"plugin_version"`
 
 	// PluginName is the name of the plugin registered in the catalog.
 	//
@@ -293,6 +300,29 @@ func (e *MountEntry) APIPath() string {
 	return e.namespace.Path + path
 }
 
+// pluginName returns the name of the plugin of the mount in the catalog
+func (e *MountEntry) pluginName() string {
+	if e.Type == "plugin" {
+		return e.Config.PluginName
+	}
+	aliases := mountAliases
+	if e.Table == credentialTableType {
+		aliases = credentialAliases
+	}
+	if alias, ok := aliases[e.Type]; ok {
+		return alias
+	}
+	return e.Type
+}
+
+// pluginType returns the catalog type of the plugin of the mount
+func (e *MountEntry) pluginType() consts.PluginType {
+	if e.Table == credentialTableType {
+		return consts.PluginTypeCredential
+	}
+	return consts.PluginTypeSecrets
+}
+
 // SyncCache syncs tunable configuration values to the cache. In the case of
 // cached values, they should be retrieved via synthesizedConfigCache.Load()
 // instead of accessing them directly through MountConfig.
@@ -1248,8 +1278,14 @@ func (c *Core) newLogicalBackend(ctx context.Context, entry *MountEntry, sysView
 		t = alias
 	}
 
+	if b, err := c.sharedNamespaceBackend(entry); b != nil || err != nil {
+		return b, err
+	}
+
+	// Mounts pinned to a plugin version run the plugin registered in the
+	// catalog with that version
 	f, ok := c.logicalBackends[t]
-	if !ok {
+	if !ok || entry.Config.PluginVersion != "" {
 		f = plugin.Factory
 	}
 
@@ -1443,6 +1479,12 @@ func (c *Core) singletonMountTables() (mounts, auth *MountTable) {
 }
 
 func (c *Core) setCoreBackend(entry *MountEntry, backend logical.Backend, view *BarrierView) {
+	// The system and identity backends of the other namespaces are the ones
+	// of the root namespace
+	if entry.NamespaceID != namespace.RootNamespaceID && entry.Type != cubbyholeMountType {
+		return
+	}
+
 	switch entry.Type {
 	case systemMountType:
 		c.systemBackend = backend.(*SystemBackend)
@@ -1451,7 +1493,9 @@ func (c *Core) setCoreBackend(entry *MountEntry, backend logical.Backend, view *
 		ch := backend.(*CubbyholeBackend)
 		ch.saltUUID = entry.UUID
 		ch.storageView = view
-		c.cubbyholeBackend = ch
+		if entry.NamespaceID == namespace.RootNamespaceID {
+			c.cubbyholeBackend = ch
+		}
 	case identityMountType:
 		c.identityStore = backend.(*IdentityStore)
 	}
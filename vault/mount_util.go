@@ -20,20 +20,26 @@ func clearIgnoredPaths(context.Context, *Core, logical.Backend, string) error {
 func addLicenseCallback(*Core, logical.Backend)                               {}
 func runFilteredPathsEvaluation(context.Context, *Core) error                 { return nil }
 
-// ViewPath returns storage prefix for the view
+// ViewPath returns storage prefix for the view. The storage of mounts in a
+// namespace other than the root namespace is kept under the namespace.
 func (e *MountEntry) ViewPath() string {
+	var prefix string
+	if e.NamespaceID != "" && e.NamespaceID != namespace.RootNamespaceID {
+		prefix = namespaceBarrierPrefix + e.NamespaceID + "/"
+	}
+
 	switch e.Type {
 	case systemMountType:
-		return systemBarrierPrefix
+		return prefix + systemBarrierPrefix
 	case "token":
-		return path.Join(systemBarrierPrefix, tokenSubPath) + "/"
+		return prefix + path.Join(systemBarrierPrefix, tokenSubPath) + "/"
 	}
 
 	switch e.Table {
 	case mountTableType:
-		return backendBarrierPrefix + e.UUID + "/"
+		return prefix + backendBarrierPrefix + e.UUID + "/"
 	case credentialTableType:
-		return credentialBarrierPrefix + e.UUID + "/"
+		return prefix + credentialBarrierPrefix + e.UUID + "/"
 	case auditTableType:
 		return auditBarrierPrefix + e.UUID + "/"
 	}
@@ -41,8 +47,6 @@ func (e *MountEntry) ViewPath() string {
 	panic("invalid mount entry")
 }
 
-func verifyNamespace(*Core, *namespace.Namespace, *MountEntry) error { return nil }
-
 // mountEntrySysView creates a logical.SystemView from global and
 // mount-specific entries; because this should be called when setting
 // up a mountEntry, it doesn't check to ensure that me is not nil
@@ -2,17 +2,552 @@ package vault
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
+	radix "github.com/armon/go-radix"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreNamespacesPath is the storage path of the namespaces other than
+	// the root namespace, keyed by namespace ID
+	coreNamespacesPath = "core/namespaces/"
+
+	// namespaceBarrierPrefix is the prefix under which the storage of each
+	// namespace other than the root namespace is kept, by namespace ID. It
+	// mirrors the layout of the root of the barrier, with the mounts, the
+	// policies, the tokens and the leases of the namespace.
+	namespaceBarrierPrefix = "namespaces/"
+
+	// namespaceIDLength is the length of the generated namespace IDs
+	namespaceIDLength = 5
 )
 
 var (
 	NamespaceByID func(context.Context, string, *Core) (*namespace.Namespace, error) = namespaceByID
+
+	// namespaceNameRegex is the allowed format for the name of a namespace,
+	// which is a single path segment
+	namespaceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	// reservedNamespaceNames cannot be used as namespace names, as they
+	// would shadow the paths of the built-in mounts
+	reservedNamespaceNames = []string{
+		"root",
+		"sys",
+		"audit",
+		"auth",
+		"cubbyhole",
+		"identity",
+	}
+
+	// namespaceRestrictedPaths are the system paths which only make sense
+	// for the whole server, and are only served in the root namespace
+	namespaceRestrictedPaths = []string{
+		"sys/audit",
+		"sys/config/",
+		"sys/generate-root",
+		"sys/host-info",
+		"sys/init",
+		"sys/internal/counters",
+		"sys/key-status",
+		"sys/leader",
+		"sys/loggers",
+		"sys/metrics",
+		"sys/mirror/",
+		"sys/monitor",
+		"sys/plugins/",
+		"sys/pprof",
+		"sys/quotas/",
+		"sys/raw",
+		"sys/rekey",
+		"sys/replication/",
+		"sys/rotate",
+		"sys/seal",
+		"sys/step-down",
+		"sys/storage/",
+		"sys/unseal",
+	}
+
+	errNamespaceHasChildren = errors.New("namespace has child namespaces; delete them first")
 )
 
+// namespaceStore holds the namespaces other than the root namespace, which
+// always exists and is never stored.
+type namespaceStore struct {
+	l      sync.RWMutex
+	byID   map[string]*namespace.Namespace
+	byPath *radix.Tree
+
+	// modifyLock serializes the creation and deletion of namespaces
+	modifyLock sync.Mutex
+}
+
+func newNamespaceStore() *namespaceStore {
+	return &namespaceStore{
+		byID:   make(map[string]*namespace.Namespace),
+		byPath: radix.New(),
+	}
+}
+
+func (s *namespaceStore) insert(ns *namespace.Namespace) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.byID[ns.ID] = ns
+	s.byPath.Insert(ns.Path, ns)
+}
+
+func (s *namespaceStore) remove(ns *namespace.Namespace) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	delete(s.byID, ns.ID)
+	s.byPath.Delete(ns.Path)
+}
+
+func (s *namespaceStore) reset() {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.byID = make(map[string]*namespace.Namespace)
+	s.byPath = radix.New()
+}
+
+func (s *namespaceStore) get(nsID string) *namespace.Namespace {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	return s.byID[nsID]
+}
+
+// children returns the namespaces under the given namespace, either
+// directly under it or at any depth.
+func (s *namespaceStore) children(parent *namespace.Namespace, recursive bool) []*namespace.Namespace {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	var children []*namespace.Namespace
+	s.byPath.WalkPrefix(parent.Path, func(path string, raw interface{}) bool {
+		if path == parent.Path {
+			return false
+		}
+		rest := strings.TrimSuffix(strings.TrimPrefix(path, parent.Path), "/")
+		if recursive || !strings.Contains(rest, "/") {
+			children = append(children, raw.(*namespace.Namespace))
+		}
+		return false
+	})
+	return children
+}
+
+// longestPrefix returns the deepest namespace whose path is a prefix of the
+// given path, or the root namespace.
+func (s *namespaceStore) longestPrefix(path string) *namespace.Namespace {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	// Namespace paths end with a slash, so the match is always on a whole
+	// path segment
+	_, raw, ok := s.byPath.LongestPrefix(path)
+	if !ok {
+		return namespace.RootNamespace
+	}
+	return raw.(*namespace.Namespace)
+}
+
 func namespaceByID(ctx context.Context, nsID string, c *Core) (*namespace.Namespace, error) {
 	if nsID == namespace.RootNamespaceID {
 		return namespace.RootNamespace, nil
 	}
+	if ns := c.namespaceStore.get(nsID); ns != nil {
+		return ns, nil
+	}
 	return nil, namespace.ErrNoNamespace
 }
+
+// NamespaceByPath returns the namespace a request path belongs to, which is
+// the deepest namespace whose path is a prefix of it.
+func (c *Core) NamespaceByPath(path string) *namespace.Namespace {
+	return c.namespaceStore.longestPrefix(path)
+}
+
+// collectNamespaces returns the root namespace and all the other namespaces.
+func (c *Core) collectNamespaces() []*namespace.Namespace {
+	return append([]*namespace.Namespace{namespace.RootNamespace}, c.namespaceStore.children(namespace.RootNamespace, true)...)
+}
+
+// namespaceView returns the storage of a namespace other than the root
+// namespace.
+func (c *Core) namespaceView(ns *namespace.Namespace) *BarrierView {
+	return NewBarrierView(c.barrier, namespaceBarrierPrefix+ns.ID+"/")
+}
+
+// namespaceSystemView returns the system storage of a namespace, which is
+// the system barrier view for the root namespace.
+func (c *Core) namespaceSystemView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return c.systemBarrierView
+	}
+	return c.namespaceView(ns).SubView(systemBarrierPrefix)
+}
+
+// isNamespaceRestrictedPath returns whether a request path is only served in
+// the root namespace.
+func isNamespaceRestrictedPath(path string) bool {
+	for _, prefix := range namespaceRestrictedPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyNamespace ensures that a mount does not shadow a child namespace
+// of the namespace it is mounted in.
+func verifyNamespace(c *Core, ns *namespace.Namespace, entry *MountEntry) error {
+	first := strings.SplitN(entry.Path, "/", 2)[0]
+	for _, child := range c.namespaceStore.children(ns, false) {
+		if child.Path == ns.Path+first+"/" {
+			return logical.CodedError(409, fmt.Sprintf("path is already in use by namespace %s", child.Path))
+		}
+	}
+	return nil
+}
+
+// sharedBackend is a backend of the root namespace which is also mounted in
+// another namespace. It is only set up and cleaned up with the root namespace.
+type sharedBackend struct {
+	logical.Backend
+}
+
+func (b *sharedBackend) Initialize(context.Context, *logical.InitializationRequest) error {
+	return nil
+}
+
+func (b *sharedBackend) Cleanup(context.Context) {}
+
+// sharedNamespaceBackend returns the backend of the root namespace for the
+// system, identity and token mounts of another namespace, which handle the
+// requests of all namespaces. It returns nil for any other mount.
+func (c *Core) sharedNamespaceBackend(entry *MountEntry) (logical.Backend, error) {
+	if entry.NamespaceID == "" || entry.NamespaceID == namespace.RootNamespaceID {
+		return nil, nil
+	}
+
+	var b logical.Backend
+	switch entry.Type {
+	case systemMountType:
+		if c.systemBackend != nil {
+			b = c.systemBackend
+		}
+	case identityMountType:
+		if c.identityStore != nil {
+			b = c.identityStore
+		}
+	case "token":
+		if c.tokenStore != nil {
+			b = c.tokenStore
+		}
+	default:
+		return nil, nil
+	}
+	if b == nil {
+		return nil, fmt.Errorf("%q backend of the root namespace is not set up", entry.Type)
+	}
+	return &sharedBackend{Backend: b}, nil
+}
+
+// loadNamespaces loads the namespaces from storage. It has to run before
+// the mount tables are loaded, as mount entries refer to their namespace.
+func (c *Core) loadNamespaces(ctx context.Context) error {
+	c.namespaceStore.reset()
+
+	keys, err := c.barrier.List(ctx, coreNamespacesPath)
+	if err != nil {
+		return errwrap.Wrapf("failed to list namespaces: {{err}}", err)
+	}
+
+	for _, key := range keys {
+		entry, err := c.barrier.Get(ctx, coreNamespacesPath+key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to read namespace %q: {{err}}", key), err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		ns := new(namespace.Namespace)
+		if err := jsonutil.DecodeJSON(entry.Value, ns); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to decode namespace %q: {{err}}", key), err)
+		}
+		c.namespaceStore.insert(ns)
+	}
+
+	return nil
+}
+
+// teardownNamespaces is used to reverse loadNamespaces when sealing.
+func (c *Core) teardownNamespaces() {
+	c.namespaceStore.reset()
+}
+
+// createNamespace creates a namespace with the given name under the
+// namespace of the context, along with the mounts every namespace has and
+// its default policies. If the namespace already exists, it is returned.
+func (c *Core) createNamespace(ctx context.Context, name string) (*namespace.Namespace, error) {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !namespaceNameRegex.MatchString(name):
+		return nil, logical.CodedError(400, fmt.Sprintf("invalid namespace name %q", name))
+	case strutil.StrListContains(reservedNamespaceNames, strings.ToLower(name)):
+		return nil, logical.CodedError(400, fmt.Sprintf("%q is a reserved name", name))
+	}
+
+	c.namespaceStore.modifyLock.Lock()
+	defer c.namespaceStore.modifyLock.Unlock()
+
+	nsPath := parent.Path + name + "/"
+	for _, child := range c.namespaceStore.children(parent, false) {
+		if child.Path == nsPath {
+			return child, nil
+		}
+	}
+
+	if match := c.router.MountConflict(ctx, name+"/"); match != "" {
+		return nil, logical.CodedError(409, fmt.Sprintf("existing mount at %s", match))
+	}
+
+	var nsID string
+	for nsID == "" || c.namespaceStore.get(nsID) != nil {
+		if nsID, err = base62.Random(namespaceIDLength); err != nil {
+			return nil, err
+		}
+	}
+
+	ns := &namespace.Namespace{
+		ID:   nsID,
+		Path: nsPath,
+	}
+	entry, err := logical.StorageEntryJSON(coreNamespacesPath+ns.ID, ns)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return nil, errwrap.Wrapf("failed to persist namespace: {{err}}", err)
+	}
+	c.namespaceStore.insert(ns)
+
+	if err := c.setupNamespace(namespace.ContextWithNamespace(ctx, ns)); err != nil {
+		c.logger.Error("failed to set up namespace, removing it", "namespace", ns.Path, "error", err)
+		if err := c.removeNamespace(ctx, ns); err != nil {
+			c.logger.Error("failed to remove namespace", "namespace", ns.Path, "error", err)
+		}
+		return nil, err
+	}
+
+	c.logger.Info("created namespace", "namespace", ns.Path, "id", ns.ID)
+	return ns, nil
+}
+
+// setupNamespace creates the mounts and the policies every namespace has in
+// a new namespace.
+func (c *Core) setupNamespace(ctx context.Context) error {
+	for _, entry := range c.requiredMountTable().Entries {
+		if err := c.mountInternal(ctx, entry, MountTableUpdateStorage); err != nil {
+			return err
+		}
+	}
+
+	tokenAccessor, err := c.generateMountAccessor("auth_token")
+	if err != nil {
+		return err
+	}
+	tokenEntry := &MountEntry{
+		Table:       credentialTableType,
+		Path:        "token/",
+		Type:        "token",
+		Description: "token based credentials",
+		Accessor:    tokenAccessor,
+	}
+	if err := c.enableCredentialInternal(ctx, tokenEntry, MountTableUpdateStorage); err != nil {
+		return err
+	}
+
+	if err := c.policyStore.loadACLPolicyInternal(ctx, defaultPolicyName, defaultPolicy); err != nil {
+		return err
+	}
+	return c.policyStore.loadACLPolicyInternal(ctx, responseWrappingPolicyName, responseWrappingPolicy)
+}
+
+// deleteNamespace deletes the namespace with the given name under the
+// namespace of the context. Its leases and tokens are revoked and all of
+// its data is removed.
+func (c *Core) deleteNamespace(ctx context.Context, name string) error {
+	parent, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.namespaceStore.modifyLock.Lock()
+	defer c.namespaceStore.modifyLock.Unlock()
+
+	var ns *namespace.Namespace
+	for _, child := range c.namespaceStore.children(parent, false) {
+		if child.Path == parent.Path+name+"/" {
+			ns = child
+		}
+	}
+	if ns == nil {
+		return nil
+	}
+	if len(c.namespaceStore.children(ns, false)) > 0 {
+		return logical.CodedError(400, errNamespaceHasChildren.Error())
+	}
+
+	if err := c.removeNamespace(ctx, ns); err != nil {
+		return err
+	}
+
+	c.logger.Info("deleted namespace", "namespace", ns.Path, "id", ns.ID)
+	return nil
+}
+
+// removeNamespace unmounts everything in a namespace, which revokes its
+// leases and tokens, then removes its identities and its storage.
+func (c *Core) removeNamespace(ctx context.Context, ns *namespace.Namespace) error {
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+
+	// Secrets engines are unmounted first, so that revoking their leases
+	// can still use the tokens, and the token store last, as revoking
+	// tokens cleans up their cubbyholes
+	var mountPaths, authPaths []string
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		if entry.NamespaceID == ns.ID {
+			mountPaths = append(mountPaths, entry.Path)
+		}
+	}
+	c.mountsLock.RUnlock()
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		if entry.NamespaceID == ns.ID {
+			authPaths = append(authPaths, entry.Path)
+		}
+	}
+	c.authLock.RUnlock()
+
+	required := []string{cubbyholeMountPath, "identity/", "sys/"}
+	sort.SliceStable(mountPaths, func(i, j int) bool {
+		return !strutil.StrListContains(required, mountPaths[i]) && strutil.StrListContains(required, mountPaths[j])
+	})
+	sort.SliceStable(authPaths, func(i, j int) bool {
+		return authPaths[i] != "token/" && authPaths[j] == "token/"
+	})
+
+	for _, path := range mountPaths {
+		if strutil.StrListContains(required, path) {
+			continue
+		}
+		if err := c.unmountInternal(nsCtx, path, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to unmount %q: {{err}}", path), err)
+		}
+	}
+	for _, path := range authPaths {
+		if err := c.disableCredentialInternal(nsCtx, path, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to disable auth mount %q: {{err}}", path), err)
+		}
+	}
+	for _, path := range mountPaths {
+		if !strutil.StrListContains(required, path) {
+			continue
+		}
+		if err := c.unmountInternal(nsCtx, path, MountTableUpdateStorage); err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to unmount %q: {{err}}", path), err)
+		}
+	}
+
+	if c.identityStore != nil {
+		if err := c.identityStore.deleteNamespaceIdentities(nsCtx, ns); err != nil {
+			return errwrap.Wrapf("failed to delete identities: {{err}}", err)
+		}
+	}
+
+	if err := logical.ClearView(ctx, c.namespaceView(ns)); err != nil {
+		return errwrap.Wrapf("failed to clear namespace storage: {{err}}", err)
+	}
+	if err := c.barrier.Delete(ctx, coreNamespacesPath+ns.ID); err != nil {
+		return errwrap.Wrapf("failed to delete namespace: {{err}}", err)
+	}
+
+	c.namespaceStore.remove(ns)
+	if c.policyStore != nil {
+		c.policyStore.invalidateNamespace(ns)
+	}
+	if c.tokenStore != nil {
+		c.tokenStore.invalidateNamespace(ns)
+	}
+
+	return nil
+}
+
+// deleteNamespaceIdentities deletes the entities and the groups of a
+// namespace.
+func (i *IdentityStore) deleteNamespaceIdentities(ctx context.Context, ns *namespace.Namespace) error {
+	var groupIDs []string
+	txn := i.db.Txn(false)
+	iter, err := txn.Get(groupsTable, "namespace_id", ns.ID)
+	if err != nil {
+		return err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		groupIDs = append(groupIDs, raw.(*identity.Group).ID)
+	}
+	for _, groupID := range groupIDs {
+		if _, err := i.handleGroupDeleteCommon(ctx, groupID, true); err != nil {
+			return err
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	txn = i.db.Txn(true)
+	defer txn.Abort()
+
+	var entities []*identity.Entity
+	iter, err = txn.Get(entitiesTable, "namespace_id", ns.ID)
+	if err != nil {
+		return err
+	}
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		entity, err := raw.(*identity.Entity).Clone()
+		if err != nil {
+			return err
+		}
+		entities = append(entities, entity)
+	}
+	for _, entity := range entities {
+		if err := i.handleEntityDeleteCommon(ctx, txn, entity, true); err != nil {
+			return err
+		}
+	}
+
+	txn.Commit()
+	return nil
+}
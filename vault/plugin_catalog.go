@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -12,14 +13,19 @@ import (
 
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	plugin "github.com/hashicorp/go-plugin"
+	uuid "github.com/hashicorp/go-uuid"
+	goversion "github.com/hashicorp/go-version"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/database/dbplugin"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	backendplugin "github.com/hashicorp/vault/sdk/plugin"
+	"github.com/hashicorp/vault/vault/pluginruntime"
 )
 
 var (
@@ -27,8 +33,14 @@ var (
 	ErrDirectoryNotConfigured = errors.New("could not set plugin, plugin directory is not configured")
 	ErrPluginNotFound         = errors.New("plugin not found in the catalog")
 	ErrPluginBadType          = errors.New("unable to determine plugin type")
+	ErrPluginRuntimeBadType   = errors.New("plugin runtimes are only supported for auth and secret plugins")
+	ErrPluginBadVersion       = errors.New("plugin version must be a semantic version")
 )
 
+// pluginVersionsPrefix is the prefix of the catalog entries of the versions of
+// the plugins, stored under versions/<type>/<name>/<version>.
+const pluginVersionsPrefix = "versions/"
+
 // PluginCatalog keeps a record of plugins known to vault. External plugins need
 // to be registered to the catalog before they can be used in backends. Builtin
 // plugins are automatically detected and included in the catalog.
@@ -37,7 +49,23 @@ type PluginCatalog struct {
 	catalogView     *BarrierView
 	directory       string
 
+	// runtimes launches the plugins configured with an isolated runtime
+	runtimes *pluginruntime.Manager
+
 	lock sync.RWMutex
+
+	// externalPlugins are the plugin processes shared by the mounts using
+	// the plugins which support multiplexing, by catalog entry.
+	externalPlugins     map[string]*externalPlugin
+	externalPluginsLock sync.Mutex
+}
+
+// externalPlugin is a plugin process shared by several mounts, each
+// identified by its multiplexing ID.
+type externalPlugin struct {
+	client    *plugin.Client
+	rpcClient plugin.ClientProtocol
+	ids       map[string]struct{}
 }
 
 func (c *Core) setupPluginCatalog(ctx context.Context) error {
@@ -45,6 +73,8 @@ func (c *Core) setupPluginCatalog(ctx context.Context) error {
 		builtinRegistry: c.builtinRegistry,
 		catalogView:     NewBarrierView(c.barrier, pluginCatalogPath),
 		directory:       c.pluginDirectory,
+		runtimes:        pluginruntime.NewManager(c.logger.Named("plugin-runtime"), c.pluginDirectory, c.pluginUnwrapHandler()),
+		externalPlugins: make(map[string]*externalPlugin),
 	}
 
 	// Run upgrade if untyped plugins exist
@@ -158,7 +188,7 @@ func (c *PluginCatalog) UpgradePlugins(ctx context.Context, logger log.Logger) e
 		}
 
 		// Upgrade the storage
-		err = c.setInternal(ctx, pluginName, pluginType, cmdOld, plugin.Args, plugin.Env, plugin.Sha256)
+		err = c.setInternal(ctx, pluginName, pluginType, "", cmdOld, plugin.Args, plugin.Env, plugin.Sha256, nil)
 		if err != nil {
 			retErr = multierror.Append(retErr, fmt.Errorf("could not upgrade plugin %s: %s", pluginName, err))
 			continue
@@ -177,12 +207,50 @@ func (c *PluginCatalog) UpgradePlugins(ctx context.Context, logger log.Logger) e
 
 // Get retrieves a plugin with the specified name from the catalog. It first
 // looks for external plugins with this name and then looks for builtin plugins.
-// It returns a PluginRunner or an error if no plugin was found.
-func (c *PluginCatalog) Get(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
+// If a version is given, only the external plugin registered with that
+// version is looked for. It returns a PluginRunner or an error if no plugin
+// was found.
+func (c *PluginCatalog) Get(ctx context.Context, name string, pluginType consts.PluginType, version string) (*pluginutil.PluginRunner, error) {
 	c.lock.RLock()
-	runner, err := c.get(ctx, name, pluginType)
-	c.lock.RUnlock()
-	return runner, err
+	defer c.lock.RUnlock()
+
+	if version != "" {
+		return c.getVersion(ctx, name, pluginType, version)
+	}
+	return c.get(ctx, name, pluginType)
+}
+
+// getVersion retrieves the external plugin registered with the given version.
+func (c *PluginCatalog) getVersion(ctx context.Context, name string, pluginType consts.PluginType, version string) (*pluginutil.PluginRunner, error) {
+	if c.directory == "" {
+		return nil, nil
+	}
+
+	out, err := c.catalogView.Get(ctx, pluginVersionKey(name, pluginType, version))
+	if err != nil {
+		return nil, errwrap.Wrapf(fmt.Sprintf("failed to retrieve plugin %q version %q: {{err}}", name, version), err)
+	}
+	if out == nil {
+		return nil, nil
+	}
+	return c.decodeEntry(out)
+}
+
+// decodeEntry decodes an external plugin entry of the catalog.
+func (c *PluginCatalog) decodeEntry(out *logical.StorageEntry) (*pluginutil.PluginRunner, error) {
+	entry := new(pluginutil.PluginRunner)
+	if err := jsonutil.DecodeJSON(out.Value, entry); err != nil {
+		return nil, errwrap.Wrapf("failed to decode plugin entry: {{err}}", err)
+	}
+
+	// prepend the plugin directory to the command
+	entry.Command = filepath.Join(c.directory, entry.Command)
+
+	if entry.Runtime != nil {
+		entry.RuntimeWrapper = c.runtimes.Wrapper(entry.Runtime)
+	}
+
+	return entry, nil
 }
 
 func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.PluginType) (*pluginutil.PluginRunner, error) {
@@ -202,17 +270,14 @@ func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.
 			}
 		}
 		if out != nil {
-			entry := new(pluginutil.PluginRunner)
-			if err := jsonutil.DecodeJSON(out.Value, entry); err != nil {
-				return nil, errwrap.Wrapf("failed to decode plugin entry: {{err}}", err)
+			entry, err := c.decodeEntry(out)
+			if err != nil {
+				return nil, err
 			}
 			if entry.Type != pluginType && entry.Type != consts.PluginTypeUnknown {
 				return nil, nil
 			}
 
-			// prepend the plugin directory to the command
-			entry.Command = filepath.Join(c.directory, entry.Command)
-
 			return entry, nil
 		}
 	}
@@ -230,12 +295,33 @@ func (c *PluginCatalog) get(ctx context.Context, name string, pluginType consts.
 }
 
 // Set registers a new external plugin with the catalog, or updates an existing
-// external plugin. It takes the name, command and SHA256 of the plugin.
-func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.PluginType, command string, args []string, env []string, sha256 []byte) error {
+// external plugin. It takes the name, command and SHA256 of the plugin, and
+// the isolated runtime to run it in, if any. Plugins registered with a
+// version are stored alongside the other versions of the plugin, and are only
+// run by the mounts pinned to that version.
+func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.PluginType, version string, command string, args []string, env []string, sha256 []byte, runtime *pluginutil.PluginRuntimeConfig) error {
 	if c.directory == "" {
 		return ErrDirectoryNotConfigured
 	}
 
+	if version != "" {
+		if pluginType == consts.PluginTypeUnknown {
+			return ErrPluginBadType
+		}
+		if _, err := goversion.NewSemver(version); err != nil {
+			return ErrPluginBadVersion
+		}
+	}
+
+	if runtime != nil {
+		if pluginType != consts.PluginTypeCredential && pluginType != consts.PluginTypeSecrets {
+			return ErrPluginRuntimeBadType
+		}
+		if err := pluginruntime.Validate(runtime); err != nil {
+			return err
+		}
+	}
+
 	switch {
 	case strings.Contains(name, ".."):
 		fallthrough
@@ -246,10 +332,10 @@ func (c *PluginCatalog) Set(ctx context.Context, name string, pluginType consts.
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	return c.setInternal(ctx, name, pluginType, command, args, env, sha256)
+	return c.setInternal(ctx, name, pluginType, version, command, args, env, sha256, runtime)
 }
 
-func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType consts.PluginType, command string, args []string, env []string, sha256 []byte) error {
+func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType consts.PluginType, version string, command string, args []string, env []string, sha256 []byte, runtime *pluginutil.PluginRuntimeConfig) error {
 	// Best effort check to make sure the command isn't breaking out of the
 	// configured plugin directory.
 	commandFull := filepath.Join(c.directory, command)
@@ -290,12 +376,14 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 
 	entry := &pluginutil.PluginRunner{
 		Name:    name,
+		Version: version,
 		Type:    pluginType,
 		Command: command,
 		Args:    args,
 		Env:     env,
 		Sha256:  sha256,
 		Builtin: false,
+		Runtime: runtime,
 	}
 
 	buf, err := json.Marshal(entry)
@@ -303,8 +391,13 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 		return errwrap.Wrapf("failed to encode plugin entry: {{err}}", err)
 	}
 
+	key := pluginType.String() + "/" + name
+	if version != "" {
+		key = pluginVersionKey(name, pluginType, version)
+	}
+
 	logicalEntry := logical.StorageEntry{
-		Key:   pluginType.String() + "/" + name,
+		Key:   key,
 		Value: buf,
 	}
 	if err := c.catalogView.Put(ctx, &logicalEntry); err != nil {
@@ -313,12 +406,16 @@ func (c *PluginCatalog) setInternal(ctx context.Context, name string, pluginType
 	return nil
 }
 
-// Delete is used to remove an external plugin from the catalog. Builtin plugins
-// can not be deleted.
-func (c *PluginCatalog) Delete(ctx context.Context, name string, pluginType consts.PluginType) error {
+// Delete is used to remove an external plugin from the catalog, or only one of
+// its versions if a version is given. Builtin plugins can not be deleted.
+func (c *PluginCatalog) Delete(ctx context.Context, name string, pluginType consts.PluginType, version string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	if version != "" {
+		return c.catalogView.Delete(ctx, pluginVersionKey(name, pluginType, version))
+	}
+
 	// Check the name under which the plugin exists, but if it's unfound, don't return any error.
 	pluginKey := pluginType.String() + "/" + name
 	out, err := c.catalogView.Get(ctx, pluginKey)
@@ -350,6 +447,14 @@ func (c *PluginCatalog) List(ctx context.Context, pluginType consts.PluginType)
 	pluginTypePrefix := pluginType.String() + "/"
 
 	for _, plugin := range keys {
+		// Plugins registered with versions only are listed too
+		if strings.HasPrefix(plugin, pluginVersionsPrefix) {
+			parts := strings.SplitN(strings.TrimPrefix(plugin, pluginVersionsPrefix), "/", 3)
+			if len(parts) == 3 && parts[0] == pluginType.String() {
+				mapKeys[parts[1]] = true
+			}
+			continue
+		}
 
 		// Only list user-added plugins if they're of the given type.
 		if entry, err := c.get(ctx, plugin, pluginType); err == nil && entry != nil {
@@ -379,3 +484,189 @@ func (c *PluginCatalog) List(ctx context.Context, pluginType consts.PluginType)
 
 	return retList, nil
 }
+
+// ListVersions returns the versions the external plugin of the given name is
+// registered with, in ascending order.
+func (c *PluginCatalog) ListVersions(ctx context.Context, name string, pluginType consts.PluginType) ([]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	keys, err := c.catalogView.List(ctx, pluginVersionKey(name, pluginType, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*goversion.Version, 0, len(keys))
+	for _, key := range keys {
+		v, err := goversion.NewSemver(key)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(goversion.Collection(versions))
+
+	ret := make([]string, len(versions))
+	for i, v := range versions {
+		ret[i] = v.Original()
+	}
+	return ret, nil
+}
+
+// pluginVersionKey returns the storage key of a version of a plugin.
+func pluginVersionKey(name string, pluginType consts.PluginType, version string) string {
+	return pluginVersionsPrefix + pluginType.String() + "/" + name + "/" + version
+}
+
+// newPluginClient returns a client of the plugin process of the given catalog
+// entry. The process is started for the first client, and shared with the next
+// ones if the plugin supports multiplexing; otherwise each client gets its own
+// process.
+func (c *PluginCatalog) newPluginClient(ctx context.Context, sys pluginutil.RunnerUtil, runner *pluginutil.PluginRunner, config pluginutil.PluginClientConfig) (pluginutil.PluginClient, error) {
+	// Any change of the catalog entry, such as a new SHA256, requires a new
+	// process
+	buf, err := json.Marshal(runner)
+	if err != nil {
+		return nil, err
+	}
+	key := string(buf)
+
+	c.externalPluginsLock.Lock()
+	defer c.externalPluginsLock.Unlock()
+
+	ep, ok := c.externalPlugins[key]
+	if ok && ep.client.Exited() {
+		delete(c.externalPlugins, key)
+		ok = false
+	}
+
+	if !ok {
+		client, err := runner.Run(ctx, sys, config.PluginSets, config.HandshakeConfig, []string{}, config.Logger)
+		if err != nil {
+			return nil, err
+		}
+
+		// Starts the process and negotiates the protocol version
+		rpcClient, err := client.Client()
+		if err != nil {
+			client.Kill()
+			return nil, err
+		}
+
+		// The process of a plugin which does not support multiplexing is
+		// dedicated to the client
+		if config.MultiplexingVersion == 0 || client.NegotiatedVersion() < config.MultiplexingVersion {
+			return pluginutil.NewDedicatedPluginClient(client)
+		}
+
+		ep = &externalPlugin{
+			client:    client,
+			rpcClient: rpcClient,
+			ids:       make(map[string]struct{}),
+		}
+		c.externalPlugins[key] = ep
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	ep.ids[id] = struct{}{}
+
+	return &multiplexedPluginClient{
+		ClientProtocol: ep.rpcClient,
+		catalog:        c,
+		key:            key,
+		id:             id,
+	}, nil
+}
+
+// releasePluginClient removes a client from its shared plugin process, which
+// is killed once it has no more clients.
+func (c *PluginCatalog) releasePluginClient(key, id string) {
+	c.externalPluginsLock.Lock()
+	defer c.externalPluginsLock.Unlock()
+
+	ep, ok := c.externalPlugins[key]
+	if !ok {
+		return
+	}
+
+	delete(ep.ids, id)
+	if len(ep.ids) == 0 {
+		ep.client.Kill()
+		delete(c.externalPlugins, key)
+	}
+}
+
+// cleanupExternalPlugins kills the shared plugin processes left running.
+func (c *PluginCatalog) cleanupExternalPlugins() {
+	c.externalPluginsLock.Lock()
+	defer c.externalPluginsLock.Unlock()
+
+	for key, ep := range c.externalPlugins {
+		ep.client.Kill()
+		delete(c.externalPlugins, key)
+	}
+}
+
+// multiplexedPluginClient is the client of a mount to a shared plugin
+// process.
+type multiplexedPluginClient struct {
+	plugin.ClientProtocol
+
+	catalog *PluginCatalog
+	key     string
+	id      string
+}
+
+func (m *multiplexedPluginClient) Close() error {
+	m.catalog.releasePluginClient(m.key, m.id)
+	return nil
+}
+
+func (m *multiplexedPluginClient) MultiplexingID() string {
+	return m.id
+}
+
+// pluginUnwrapHandler serves the unwrap requests of the plugins run in an
+// isolated runtime, which cannot reach the API of Vault. It only allows
+// unwrapping the token the request is made with.
+func (c *Core) pluginUnwrapHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/sys/wrapping/unwrap" || (r.Method != http.MethodPut && r.Method != http.MethodPost) {
+			http.Error(w, "unsupported request", http.StatusNotFound)
+			return
+		}
+
+		req := &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "sys/wrapping/unwrap",
+			ClientToken: r.Header.Get(consts.AuthHeaderName),
+			Connection: &logical.Connection{
+				RemoteAddr: "plugin-runtime",
+			},
+		}
+		resp, err := c.HandleRequest(namespace.RootContext(r.Context()), req)
+		if statusCode, err := logical.RespondErrorCommon(req, resp, err); err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Plugin TLS configurations are wrapped as raw HTTP responses
+		if rawBody, ok := resp.Data[logical.HTTPRawBody].([]byte); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(rawBody)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&logical.HTTPResponse{
+			Data: resp.Data,
+		})
+	})
+}
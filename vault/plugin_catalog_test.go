@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 )
 
 func TestPluginCatalog_CRUD(t *testing.T) {
@@ -25,7 +26,7 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	core.pluginCatalog.directory = sym
 
 	// Get builtin plugin
-	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err := core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -54,13 +55,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	defer file.Close()
 
 	command := fmt.Sprintf("%s", filepath.Base(file.Name()))
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, command, []string{"--test"}, []string{"FOO=BAR"}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{"FOO=BAR"}, []byte{'1'}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Get the plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -80,13 +81,13 @@ func TestPluginCatalog_CRUD(t *testing.T) {
 	}
 
 	// Delete the plugin
-	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	err = core.pluginCatalog.Delete(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
 
 	// Get builtin plugin
-	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase)
+	p, err = core.pluginCatalog.Get(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "")
 	if err != nil {
 		t.Fatalf("unexpected error %v", err)
 	}
@@ -146,13 +147,13 @@ func TestPluginCatalog_List(t *testing.T) {
 	defer file.Close()
 
 	command := filepath.Base(file.Name())
-	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, command, []string{"--test"}, []string{}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "mysql-database-plugin", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{}, []byte{'1'}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// Set another plugin
-	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", consts.PluginTypeDatabase, command, []string{"--test"}, []string{}, []byte{'1'})
+	err = core.pluginCatalog.Set(context.Background(), "aaaaaaa", consts.PluginTypeDatabase, "", command, []string{"--test"}, []string{}, []byte{'1'}, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -181,3 +182,81 @@ func TestPluginCatalog_List(t *testing.T) {
 	}
 
 }
+
+func TestPluginCatalog_Versions(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+
+	sym, err := filepath.EvalSymlinks(os.TempDir())
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	core.pluginCatalog.directory = sym
+
+	file, err := ioutil.TempFile(os.TempDir(), "temp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	command := filepath.Base(file.Name())
+
+	// Versions require the type of the plugin and a semantic version
+	err = core.pluginCatalog.Set(context.Background(), "my-plugin", consts.PluginTypeUnknown, "1.0.0", command, nil, nil, []byte{'1'}, nil)
+	if err != ErrPluginBadType {
+		t.Fatalf("expected ErrPluginBadType, got %v", err)
+	}
+	err = core.pluginCatalog.Set(context.Background(), "my-plugin", consts.PluginTypeSecrets, "latest", command, nil, nil, []byte{'1'}, nil)
+	if err != ErrPluginBadVersion {
+		t.Fatalf("expected ErrPluginBadVersion, got %v", err)
+	}
+
+	for _, version := range []string{"1.10.0", "1.2.0"} {
+		err = core.pluginCatalog.Set(context.Background(), "my-plugin", consts.PluginTypeSecrets, version, command, nil, nil, []byte{'1'}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The plugin is listed although it has no unversioned entry
+	plugins, err := core.pluginCatalog.List(context.Background(), consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strutil.StrListContains(plugins, "my-plugin") {
+		t.Fatalf("expected my-plugin in %v", plugins)
+	}
+	p, err := core.pluginCatalog.Get(context.Background(), "my-plugin", consts.PluginTypeSecrets, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Fatalf("expected no unversioned plugin, got %#v", p)
+	}
+
+	p, err = core.pluginCatalog.Get(context.Background(), "my-plugin", consts.PluginTypeSecrets, "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Version != "1.2.0" || p.Command != filepath.Join(sym, command) {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	versions, err := core.pluginCatalog.ListVersions(context.Background(), "my-plugin", consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"1.2.0", "1.10.0"}) {
+		t.Fatalf("bad: %v", versions)
+	}
+
+	// Deleting a version leaves the other ones
+	if err := core.pluginCatalog.Delete(context.Background(), "my-plugin", consts.PluginTypeSecrets, "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	versions, err = core.pluginCatalog.ListVersions(context.Background(), "my-plugin", consts.PluginTypeSecrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"1.10.0"}) {
+		t.Fatalf("bad: %v", versions)
+	}
+}
@@ -0,0 +1,220 @@
+package pluginruntime
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/loggers"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+)
+
+const (
+	// TypeContainer runs the plugin in a container of the container engine
+	TypeContainer = "container"
+
+	// TypeGVisor runs the plugin in a container sandboxed by gVisor, which
+	// has to be installed as the runsc runtime of the container engine
+	TypeGVisor = "gvisor"
+
+	// gVisorRuntime is the name of the gVisor runtime of the container engine
+	gVisorRuntime = "runsc"
+
+	unwrapSocketName = "unwrap.sock"
+)
+
+// containerEngine is the CLI of the container engine running the plugins
+var containerEngine = "docker"
+
+// Validate verifies a runtime configuration of the plugin catalog.
+func Validate(config *pluginutil.PluginRuntimeConfig) error {
+	switch config.Type {
+	case TypeContainer, TypeGVisor:
+	default:
+		return fmt.Errorf("unsupported plugin runtime type %q", config.Type)
+	}
+
+	if config.Image == "" {
+		return errors.New("plugin runtime image is required")
+	}
+	if config.CPUs < 0 {
+		return errors.New("plugin runtime cpus cannot be negative")
+	}
+	if config.MemoryBytes < 0 {
+		return errors.New("plugin runtime memory_bytes cannot be negative")
+	}
+	return nil
+}
+
+// Manager launches external plugins in the isolated runtimes configured in
+// the plugin catalog.
+//
+// The plugins have no network access, so the manager serves the endpoint
+// plugins unwrap their TLS configuration at on a unix socket, which is
+// shared with the plugins along with a directory for the socket of each
+// plugin.
+type Manager struct {
+	logger        log.Logger
+	pluginDir     string
+	unwrapHandler http.Handler
+
+	l          sync.Mutex
+	baseDir    string
+	socketPath string
+	server     *http.Server
+}
+
+// NewManager returns a Manager for the plugins of the given directory. The
+// unwrap handler serves the unwrap requests of the plugins.
+func NewManager(logger log.Logger, pluginDir string, unwrapHandler http.Handler) *Manager {
+	return &Manager{
+		logger:        logger,
+		pluginDir:     pluginDir,
+		unwrapHandler: unwrapHandler,
+	}
+}
+
+// Wrapper returns the RuntimeWrapper launching plugins in the runtime of the
+// given configuration.
+func (m *Manager) Wrapper(config *pluginutil.PluginRuntimeConfig) pluginutil.RuntimeWrapper {
+	return &wrapper{
+		manager: m,
+		config:  config,
+	}
+}
+
+// Stop stops serving the unwrap endpoint and removes the directories shared
+// with the plugins.
+func (m *Manager) Stop() error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.server == nil {
+		return nil
+	}
+
+	err := m.server.Close()
+	if rmErr := os.RemoveAll(m.baseDir); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	m.server = nil
+	m.baseDir = ""
+	m.socketPath = ""
+	return err
+}
+
+// start creates the directory shared with the plugins and serves the unwrap
+// endpoint, if it is not already. It returns the directory and the path of
+// the unwrap socket.
+func (m *Manager) start() (string, string, error) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.server != nil {
+		return m.baseDir, m.socketPath, nil
+	}
+
+	baseDir, err := ioutil.TempDir("", "vault-plugin-runtime")
+	if err != nil {
+		return "", "", errwrap.Wrapf("failed to create plugin runtime directory: {{err}}", err)
+	}
+
+	// The socket is alone in its directory, which is mounted read-only in the
+	// sandboxes
+	socketDir := filepath.Join(baseDir, "unwrap")
+	if err := os.Mkdir(socketDir, 0700); err != nil {
+		os.RemoveAll(baseDir)
+		return "", "", err
+	}
+	socketPath := filepath.Join(socketDir, unwrapSocketName)
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		os.RemoveAll(baseDir)
+		return "", "", errwrap.Wrapf("failed to listen on plugin unwrap socket: {{err}}", err)
+	}
+
+	server := &http.Server{
+		Handler:  m.unwrapHandler,
+		ErrorLog: loggers.StandardLogger(m.logger),
+	}
+	go server.Serve(ln)
+
+	m.baseDir = baseDir
+	m.socketPath = socketPath
+	m.server = server
+	return baseDir, socketPath, nil
+}
+
+type wrapper struct {
+	manager *Manager
+	config  *pluginutil.PluginRuntimeConfig
+}
+
+// WrapCommand returns the container engine command running the plugin
+// command in a container without network access, limited to the configured
+// resources.
+func (w *wrapper) WrapCommand(cmd *exec.Cmd, envNames []string) (*exec.Cmd, error) {
+	enginePath, err := exec.LookPath(containerEngine)
+	if err != nil {
+		return nil, errwrap.Wrapf("container engine not found: {{err}}", err)
+	}
+
+	baseDir, unwrapSocket, err := w.manager.start()
+	if err != nil {
+		return nil, err
+	}
+
+	// go-plugin listens on a unix socket created in the temporary directory,
+	// which has to be reachable from the host
+	socketDir, err := ioutil.TempDir(baseDir, "plugin")
+	if err != nil {
+		return nil, err
+	}
+	unwrapDir := filepath.Dir(unwrapSocket)
+
+	args := []string{
+		"run", "--rm",
+		"--network=none",
+		"--read-only",
+		"--cap-drop=ALL",
+		"--security-opt=no-new-privileges",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--volume", fmt.Sprintf("%s:%s:ro", w.manager.pluginDir, w.manager.pluginDir),
+		"--volume", fmt.Sprintf("%s:%s:ro", unwrapDir, unwrapDir),
+		"--volume", fmt.Sprintf("%s:%s", socketDir, socketDir),
+		"--env", "TMPDIR=" + socketDir,
+		"--env", fmt.Sprintf("%s=unix://%s", pluginutil.PluginUnwrapAddrEnv, unwrapSocket),
+	}
+	if w.config.Type == TypeGVisor {
+		args = append(args, "--runtime="+gVisorRuntime)
+	}
+	if w.config.CPUs > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(w.config.CPUs, 'f', -1, 64))
+	}
+	if w.config.MemoryBytes > 0 {
+		args = append(args, "--memory", strconv.FormatInt(w.config.MemoryBytes, 10))
+	}
+
+	// The values are taken from the environment of the engine command
+	for _, name := range envNames {
+		args = append(args, "--env", name)
+	}
+
+	args = append(args, w.config.Image, cmd.Path)
+	args = append(args, cmd.Args[1:]...)
+
+	w.manager.logger.Debug("running plugin in sandbox", "type", w.config.Type, "image", w.config.Image, "command", cmd.Path)
+
+	wrapped := exec.Command(enginePath, args...)
+	wrapped.Env = cmd.Env
+	return wrapped, nil
+}
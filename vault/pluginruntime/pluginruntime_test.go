@@ -0,0 +1,141 @@
+package pluginruntime
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/pluginutil"
+)
+
+func TestValidate(t *testing.T) {
+	cases := map[string]struct {
+		config *pluginutil.PluginRuntimeConfig
+		valid  bool
+	}{
+		"container": {
+			config: &pluginutil.PluginRuntimeConfig{Type: TypeContainer, Image: "alpine"},
+			valid:  true,
+		},
+		"gvisor with limits": {
+			config: &pluginutil.PluginRuntimeConfig{Type: TypeGVisor, Image: "alpine", CPUs: 0.5, MemoryBytes: 1 << 28},
+			valid:  true,
+		},
+		"unknown type": {
+			config: &pluginutil.PluginRuntimeConfig{Type: "vm", Image: "alpine"},
+		},
+		"missing image": {
+			config: &pluginutil.PluginRuntimeConfig{Type: TypeContainer},
+		},
+		"negative cpus": {
+			config: &pluginutil.PluginRuntimeConfig{Type: TypeContainer, Image: "alpine", CPUs: -1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(tc.config)
+			if tc.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestManager_WrapCommand(t *testing.T) {
+	// Any binary of the PATH stands in for the container engine
+	defer func(engine string) {
+		containerEngine = engine
+	}(containerEngine)
+	containerEngine = "true"
+
+	pluginDir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pluginDir)
+
+	unwrapped := make(chan string, 1)
+	m := NewManager(log.NewNullLogger(), pluginDir, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unwrapped <- r.URL.Path
+	}))
+	defer m.Stop()
+
+	wrapper := m.Wrapper(&pluginutil.PluginRuntimeConfig{
+		Type:        TypeGVisor,
+		Image:       "gcr.io/distroless/static",
+		CPUs:        0.5,
+		MemoryBytes: 268435456,
+	})
+
+	cmd := exec.Command(filepath.Join(pluginDir, "my-plugin"), "--tls-skip-verify")
+	cmd.Env = []string{"FOO=bar"}
+	wrapped, err := wrapper.WrapCommand(cmd, []string{"FOO", "PLUGIN_MIN_PORT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(wrapped.Env, cmd.Env) {
+		t.Fatalf("bad: %v", wrapped.Env)
+	}
+	args := strings.Join(wrapped.Args[1:], " ")
+	for _, expected := range []string{
+		"run --rm --network=none",
+		"--runtime=runsc",
+		"--cpus 0.5",
+		"--memory 268435456",
+		"--volume " + pluginDir + ":" + pluginDir + ":ro",
+		"--env FOO --env PLUGIN_MIN_PORT",
+		"gcr.io/distroless/static " + filepath.Join(pluginDir, "my-plugin") + " --tls-skip-verify",
+	} {
+		if !strings.Contains(args, expected) {
+			t.Fatalf("expected %q in %q", expected, args)
+		}
+	}
+
+	// The plugin unwraps its token through the socket passed in its
+	// environment
+	var socket string
+	for _, arg := range wrapped.Args {
+		if strings.HasPrefix(arg, pluginutil.PluginUnwrapAddrEnv+"=") {
+			socket = strings.TrimPrefix(arg, pluginutil.PluginUnwrapAddrEnv+"=unix://")
+		}
+	}
+	if socket == "" {
+		t.Fatalf("no unwrap address in %q", args)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(context.Context, string, string) (net.Conn, error) {
+				return net.Dial("unix", socket)
+			},
+		},
+	}
+	resp, err := client.Post("http://localhost/v1/sys/wrapping/unwrap", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if path := <-unwrapped; path != "/v1/sys/wrapping/unwrap" {
+		t.Fatalf("bad: %s", path)
+	}
+
+	// Stopping the manager removes the shared directories
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("expected socket to be removed: %v", err)
+	}
+}
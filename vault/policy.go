@@ -1,13 +1,18 @@
 package vault
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
+	sockaddr "github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/identity"
@@ -19,14 +24,15 @@ import (
 )
 
 const (
-	DenyCapability   = "deny"
-	CreateCapability = "create"
-	ReadCapability   = "read"
-	UpdateCapability = "update"
-	DeleteCapability = "delete"
-	ListCapability   = "list"
-	SudoCapability   = "sudo"
-	RootCapability   = "root"
+	DenyCapability    = "deny"
+	CreateCapability  = "create"
+	ReadCapability    = "read"
+	UpdateCapability  = "update"
+	DeleteCapability  = "delete"
+	ListCapability    = "list"
+	SudoCapability    = "sudo"
+	ProfileCapability = "profile"
+	RootCapability    = "root"
 
 	// Backwards compatibility
 	OldDenyPathPolicy  = "deny"
@@ -43,6 +49,7 @@ const (
 	DeleteCapabilityInt
 	ListCapabilityInt
 	SudoCapabilityInt
+	ProfileCapabilityInt
 )
 
 type PolicyType uint32
@@ -71,13 +78,14 @@ func (p PolicyType) String() string {
 
 var (
 	cap2Int = map[string]uint32{
-		DenyCapability:   DenyCapabilityInt,
-		CreateCapability: CreateCapabilityInt,
-		ReadCapability:   ReadCapabilityInt,
-		UpdateCapability: UpdateCapabilityInt,
-		DeleteCapability: DeleteCapabilityInt,
-		ListCapability:   ListCapabilityInt,
-		SudoCapability:   SudoCapabilityInt,
+		DenyCapability:    DenyCapabilityInt,
+		CreateCapability:  CreateCapabilityInt,
+		ReadCapability:    ReadCapabilityInt,
+		UpdateCapability:  UpdateCapabilityInt,
+		DeleteCapability:  DeleteCapabilityInt,
+		ListCapability:    ListCapabilityInt,
+		SudoCapability:    SudoCapabilityInt,
+		ProfileCapability: ProfileCapabilityInt,
 	}
 )
 
@@ -129,6 +137,10 @@ type PathRules struct {
 	RequiredParametersHCL []string                 `hcl:"required_parameters"`
 	MFAMethodsHCL         []string                 `hcl:"mfa_methods"`
 	ControlGroupHCL       *ControlGroupHCL         `hcl:"control_group"`
+
+	BoundCIDRsHCL           []string                           `hcl:"bound_cidrs"`
+	TimeWindowsHCL          map[string]*TimeWindowHCL          `hcl:"time_window"`
+	ParameterConstraintsHCL map[string]*ParameterConstraintHCL `hcl:"parameter_constraint"`
 }
 
 type ControlGroupHCL struct {
@@ -152,15 +164,149 @@ type IdentityFactor struct {
 	ApprovalsRequired int      `hcl:"approvals"`
 }
 
+// TimeWindowHCL is a window of time of the week during which requests to a
+// path are allowed. Start and end are given as HH:MM in the time zone of the
+// window, which defaults to UTC.
+type TimeWindowHCL struct {
+	Days     []string `hcl:"days"`
+	Start    string   `hcl:"start"`
+	End      string   `hcl:"end"`
+	Timezone string   `hcl:"timezone"`
+}
+
+// TimeWindow is a parsed TimeWindowHCL. Start and End are offsets from
+// midnight; a window ending before it starts spans midnight, and belongs to
+// the day it starts on.
+type TimeWindow struct {
+	Name     string
+	Days     []time.Weekday
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// Contains returns whether the given time falls within the window.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	day := t.Weekday()
+	switch {
+	case w.Start < w.End:
+		if offset < w.Start || offset >= w.End {
+			return false
+		}
+	case offset >= w.Start:
+	case offset < w.End:
+		// Early hours of a window which started the day before
+		day = (day + 6) % 7
+	default:
+		return false
+	}
+
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ParameterConstraintHCL constrains the values of a request parameter: the
+// value has to match the whole pattern, and numeric values have to be within
+// min and max.
+type ParameterConstraintHCL struct {
+	Pattern string      `hcl:"pattern"`
+	Min     interface{} `hcl:"min"`
+	Max     interface{} `hcl:"max"`
+}
+
+// ParameterConstraint is a parsed ParameterConstraintHCL.
+type ParameterConstraint struct {
+	Pattern *regexp.Regexp
+	Min     *float64
+	Max     *float64
+}
+
+// Allows returns whether the given parameter value satisfies the constraint.
+// Every element of a list value has to satisfy it.
+func (c *ParameterConstraint) Allows(v interface{}) bool {
+	if list, ok := v.([]interface{}); ok {
+		for _, el := range list {
+			if !c.Allows(el) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if c.Pattern != nil {
+		var str string
+		switch t := v.(type) {
+		case string:
+			str = t
+		case json.Number, int, int64, float64, bool:
+			str = fmt.Sprintf("%v", t)
+		default:
+			return false
+		}
+		if !c.Pattern.MatchString(str) {
+			return false
+		}
+	}
+
+	if c.Min != nil || c.Max != nil {
+		num, err := parseConstraintNumber(v)
+		if err != nil {
+			return false
+		}
+		if c.Min != nil && num < *c.Min {
+			return false
+		}
+		if c.Max != nil && num > *c.Max {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseConstraintNumber(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case int:
+		return float64(t), nil
+	case int64:
+		return float64(t), nil
+	case float64:
+		return t, nil
+	case json.Number:
+		return t.Float64()
+	case string:
+		return strconv.ParseFloat(t, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
 type ACLPermissions struct {
-	CapabilitiesBitmap uint32
-	MinWrappingTTL     time.Duration
-	MaxWrappingTTL     time.Duration
-	AllowedParameters  map[string][]interface{}
-	DeniedParameters   map[string][]interface{}
-	RequiredParameters []string
-	MFAMethods         []string
-	ControlGroup       *ControlGroup
+	CapabilitiesBitmap   uint32
+	MinWrappingTTL       time.Duration
+	MaxWrappingTTL       time.Duration
+	AllowedParameters    map[string][]interface{}
+	DeniedParameters     map[string][]interface{}
+	RequiredParameters   []string
+	MFAMethods           []string
+	ControlGroup         *ControlGroup
+	BoundCIDRs           []*sockaddr.SockAddrMarshaler
+	TimeWindows          []*TimeWindow
+	ParameterConstraints map[string][]*ParameterConstraint
+
+	// ConditionGrants are the capabilities granted by each policy merged
+	// into the permissions of an ACL, along with the conditions of that
+	// policy, which only restrict the capabilities it grants
+	ConditionGrants []*ConditionGrant
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -217,6 +363,24 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		ret.ControlGroup = clonedControlGroup.(*ControlGroup)
 	}
 
+	// The parsed conditions are never modified, so only the slices holding
+	// them are copied
+	if p.BoundCIDRs != nil {
+		ret.BoundCIDRs = append([]*sockaddr.SockAddrMarshaler{}, p.BoundCIDRs...)
+	}
+	if p.TimeWindows != nil {
+		ret.TimeWindows = append([]*TimeWindow{}, p.TimeWindows...)
+	}
+	if p.ConditionGrants != nil {
+		ret.ConditionGrants = append([]*ConditionGrant{}, p.ConditionGrants...)
+	}
+	if p.ParameterConstraints != nil {
+		ret.ParameterConstraints = make(map[string][]*ParameterConstraint, len(p.ParameterConstraints))
+		for key, constraints := range p.ParameterConstraints {
+			ret.ParameterConstraints[key] = append([]*ParameterConstraint{}, constraints...)
+		}
+	}
+
 	return ret, nil
 }
 
@@ -318,6 +482,9 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 			"max_wrapping_ttl",
 			"mfa_methods",
 			"control_group",
+			"bound_cidrs",
+			"time_window",
+			"parameter_constraint",
 		}
 		if err := hclutil.CheckHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -385,7 +552,7 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 				pc.Capabilities = []string{DenyCapability}
 				pc.Permissions.CapabilitiesBitmap = DenyCapabilityInt
 				goto PathFinished
-			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability:
+			case CreateCapability, ReadCapability, UpdateCapability, DeleteCapability, ListCapability, SudoCapability, ProfileCapability:
 				pc.Permissions.CapabilitiesBitmap |= cap2Int[cap]
 			default:
 				return fmt.Errorf("path %q: invalid capability %q", key, cap)
@@ -460,6 +627,31 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 			}
 			pc.Permissions.ControlGroup.Factors = factors
 		}
+		if len(pc.BoundCIDRsHCL) > 0 {
+			cidrs, err := parseutil.ParseAddrs(pc.BoundCIDRsHCL)
+			if err != nil {
+				return errwrap.Wrapf("error parsing bound_cidrs: {{err}}", err)
+			}
+			pc.Permissions.BoundCIDRs = cidrs
+		}
+		if pc.TimeWindowsHCL != nil {
+			windows, err := parseTimeWindows(pc.TimeWindowsHCL)
+			if err != nil {
+				return err
+			}
+			pc.Permissions.TimeWindows = windows
+		}
+		if pc.ParameterConstraintsHCL != nil {
+			pc.Permissions.ParameterConstraints = make(map[string][]*ParameterConstraint, len(pc.ParameterConstraintsHCL))
+			for name, constraintHCL := range pc.ParameterConstraintsHCL {
+				constraint, err := parseParameterConstraint(constraintHCL)
+				if err != nil {
+					return errwrap.Wrapf(fmt.Sprintf("error parsing parameter_constraint %q: {{err}}", name), err)
+				}
+				key := strings.ToLower(name)
+				pc.Permissions.ParameterConstraints[key] = append(pc.Permissions.ParameterConstraints[key], constraint)
+			}
+		}
 		if pc.Permissions.MinWrappingTTL != 0 &&
 			pc.Permissions.MaxWrappingTTL != 0 &&
 			pc.Permissions.MaxWrappingTTL < pc.Permissions.MinWrappingTTL {
@@ -476,3 +668,132 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 	result.Paths = paths
 	return nil
 }
+
+// weekdays maps the full and abbreviated names of days to their weekday
+var weekdays = func() map[string]time.Weekday {
+	days := make(map[string]time.Weekday, 14)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		days[name] = d
+		days[name[:3]] = d
+	}
+	return days
+}()
+
+// parseTimeWindows parses the time windows of a path, sorted by name
+func parseTimeWindows(windowsHCL map[string]*TimeWindowHCL) ([]*TimeWindow, error) {
+	names := make([]string, 0, len(windowsHCL))
+	for name := range windowsHCL {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	windows := make([]*TimeWindow, 0, len(names))
+	for _, name := range names {
+		window, err := parseTimeWindow(name, windowsHCL[name])
+		if err != nil {
+			return nil, errwrap.Wrapf(fmt.Sprintf("error parsing time_window %q: {{err}}", name), err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+func parseTimeWindow(name string, windowHCL *TimeWindowHCL) (*TimeWindow, error) {
+	window := &TimeWindow{
+		Name:     name,
+		Location: time.UTC,
+		End:      24 * time.Hour,
+	}
+
+	for _, raw := range windowHCL.Days {
+		day := strings.ToLower(strings.TrimSpace(raw))
+		weekday, ok := weekdays[day]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", raw)
+		}
+		window.Days = append(window.Days, weekday)
+	}
+
+	var err error
+	if windowHCL.Start != "" {
+		if window.Start, err = parseTimeOfDay(windowHCL.Start); err != nil {
+			return nil, errwrap.Wrapf("invalid start: {{err}}", err)
+		}
+	}
+	if windowHCL.End != "" {
+		if window.End, err = parseTimeOfDay(windowHCL.End); err != nil {
+			return nil, errwrap.Wrapf("invalid end: {{err}}", err)
+		}
+	}
+	switch {
+	case window.Start == 24*time.Hour:
+		return nil, errors.New("start cannot be 24:00")
+	case window.Start == window.End:
+		return nil, errors.New("start and end cannot be the same")
+	}
+
+	if windowHCL.Timezone != "" {
+		if window.Location, err = time.LoadLocation(windowHCL.Timezone); err != nil {
+			return nil, errwrap.Wrapf("invalid timezone: {{err}}", err)
+		}
+	}
+
+	return window, nil
+}
+
+// parseTimeOfDay parses a HH:MM time of day, from 00:00 to 24:00, into an
+// offset from midnight
+func parseTimeOfDay(raw string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) != 2 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("%q is not in HH:MM format", raw)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", raw)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", raw)
+	}
+	if hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("%q is not a valid time of day", raw)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func parseParameterConstraint(constraintHCL *ParameterConstraintHCL) (*ParameterConstraint, error) {
+	constraint := new(ParameterConstraint)
+
+	if constraintHCL.Pattern != "" {
+		pattern, err := regexp.Compile("^(?:" + constraintHCL.Pattern + ")$")
+		if err != nil {
+			return nil, errwrap.Wrapf("invalid pattern: {{err}}", err)
+		}
+		constraint.Pattern = pattern
+	}
+	if constraintHCL.Min != nil {
+		min, err := parseConstraintNumber(constraintHCL.Min)
+		if err != nil {
+			return nil, errwrap.Wrapf("invalid min: {{err}}", err)
+		}
+		constraint.Min = &min
+	}
+	if constraintHCL.Max != nil {
+		max, err := parseConstraintNumber(constraintHCL.Max)
+		if err != nil {
+			return nil, errwrap.Wrapf("invalid max: {{err}}", err)
+		}
+		constraint.Max = &max
+	}
+
+	switch {
+	case constraint.Pattern == nil && constraint.Min == nil && constraint.Max == nil:
+		return nil, errors.New("one of pattern, min or max is required")
+	case constraint.Min != nil && constraint.Max != nil && *constraint.Max < *constraint.Min:
+		return nil, errors.New("max cannot be less than min")
+	}
+
+	return constraint, nil
+}
@@ -347,7 +347,26 @@ func (ps *PolicyStore) SetPolicy(ctx context.Context, p *Policy) error {
 		return fmt.Errorf("cannot update %q policy", p.Name)
 	}
 
-	return ps.setPolicyInternal(ctx, p)
+	if err := ps.setPolicyInternal(ctx, p); err != nil {
+		return err
+	}
+	ps.publishPolicyEvent(EventTopicPolicyWrite, p.namespace, p.Name, p.Type)
+	return nil
+}
+
+// publishPolicyEvent notifies subscribers of a change to a policy
+func (ps *PolicyStore) publishPolicyEvent(topic string, ns *namespace.Namespace, name string, policyType PolicyType) {
+	if ps.core == nil || ps.core.events == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"name": name,
+		"type": policyType.String(),
+	}
+	if ns != nil {
+		data["namespace"] = ns.Path
+	}
+	ps.core.events.Publish(topic, ns, data)
 }
 
 func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
@@ -639,7 +658,15 @@ func (ps *PolicyStore) ListPolicies(ctx context.Context, policyType PolicyType)
 
 // DeletePolicy is used to delete the named policy
 func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType PolicyType) error {
-	return ps.switchedDeletePolicy(ctx, name, policyType, true, false)
+	if err := ps.switchedDeletePolicy(ctx, name, policyType, true, false); err != nil {
+		return err
+	}
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	ps.publishPolicyEvent(EventTopicPolicyDelete, ns, ps.sanitizeName(name), policyType)
+	return nil
 }
 
 // deletePolicyForce is used to delete the named policy and force it even if
@@ -4,6 +4,7 @@ package vault
 
 import (
 	"context"
+	"strings"
 
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -14,18 +15,59 @@ type entPolicyStore struct{}
 func (ps *PolicyStore) extraInit() {
 }
 
-func (ps *PolicyStore) loadNamespacePolicies(context.Context, *Core) error { return nil }
+// loadNamespacePolicies records the ACL policies of the namespaces other
+// than the root namespace.
+func (ps *PolicyStore) loadNamespacePolicies(ctx context.Context, c *Core) error {
+	for _, ns := range c.collectNamespaces() {
+		if ns.ID == namespace.RootNamespaceID {
+			continue
+		}
 
-func (ps *PolicyStore) getACLView(*namespace.Namespace) *BarrierView {
-	return ps.aclView
+		keys, err := logical.CollectKeys(namespace.ContextWithNamespace(ctx, ns), ps.getACLView(ns))
+		if err != nil {
+			ps.logger.Error("error collecting acl policy keys", "namespace", ns.Path, "error", err)
+			return err
+		}
+		for _, key := range keys {
+			index := ps.cacheKey(ns, ps.sanitizeName(key))
+			ps.policyTypeMap.Store(index, PolicyTypeACL)
+		}
+	}
+	return nil
+}
+
+// invalidateNamespace drops the cached policies of a deleted namespace.
+func (ps *PolicyStore) invalidateNamespace(ns *namespace.Namespace) {
+	ps.policyTypeMap.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(string), ns.ID+"/") {
+			ps.policyTypeMap.Delete(key)
+		}
+		return true
+	})
+	if ps.tokenPoliciesLRU != nil {
+		ps.tokenPoliciesLRU.Purge()
+	}
+}
+
+func (ps *PolicyStore) getACLView(ns *namespace.Namespace) *BarrierView {
+	if ns.ID == namespace.RootNamespaceID {
+		return ps.aclView
+	}
+	return ps.core.namespaceSystemView(ns).SubView(policyACLSubPath)
 }
 
 func (ps *PolicyStore) getRGPView(ns *namespace.Namespace) *BarrierView {
-	return ps.rgpView
+	if ns.ID == namespace.RootNamespaceID {
+		return ps.rgpView
+	}
+	return ps.core.namespaceSystemView(ns).SubView(policyRGPSubPath)
 }
 
 func (ps *PolicyStore) getEGPView(ns *namespace.Namespace) *BarrierView {
-	return ps.egpView
+	if ns.ID == namespace.RootNamespaceID {
+		return ps.egpView
+	}
+	return ps.core.namespaceSystemView(ns).SubView(policyEGPSubPath)
 }
 
 func (ps *PolicyStore) getBarrierView(ns *namespace.Namespace, _ PolicyType) *BarrierView {
@@ -43,5 +85,10 @@ func (ps *PolicyStore) invalidateEGPTreePath(string) {}
 func (ps *PolicyStore) pathsToEGPPaths(*Policy) ([]*egpPath, error) { return nil, nil }
 
 func (ps *PolicyStore) loadACLPolicyNamespaces(ctx context.Context, policyName, policyText string) error {
-	return ps.loadACLPolicyInternal(namespace.RootContext(ctx), policyName, policyText)
+	for _, ns := range ps.core.collectNamespaces() {
+		if err := ps.loadACLPolicyInternal(namespace.ContextWithNamespace(ctx, ns), policyName, policyText); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,6 +1,9 @@
 package vault
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -429,3 +432,160 @@ path "foo/+*" {
 		t.Errorf("bad error: %s", err)
 	}
 }
+
+func TestPolicy_ParseConditions(t *testing.T) {
+	p, err := ParseACLPolicy(namespace.RootNamespace, strings.TrimSpace(`
+path "secret/foo" {
+	capabilities = ["update"]
+	bound_cidrs = ["10.0.0.0/8", "127.0.0.1"]
+	time_window "office" {
+		days = ["Monday", "fri"]
+		start = "09:00"
+		end = "17:30"
+		timezone = "America/New_York"
+	}
+	time_window "all_day" {}
+	parameter_constraint "TTL" {
+		min = 60
+		max = "3600"
+	}
+	parameter_constraint "name" {
+		pattern = "[a-z]+"
+	}
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	perms := p.Paths[0].Permissions
+	if len(perms.BoundCIDRs) != 2 || perms.BoundCIDRs[0].String() != "10.0.0.0/8" || perms.BoundCIDRs[1].String() != "127.0.0.1" {
+		t.Fatalf("bad: bound CIDRs: %v", perms.BoundCIDRs)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedWindows := []*TimeWindow{
+		{
+			Name:     "all_day",
+			End:      24 * time.Hour,
+			Location: time.UTC,
+		},
+		{
+			Name:     "office",
+			Days:     []time.Weekday{time.Monday, time.Friday},
+			Start:    9 * time.Hour,
+			End:      17*time.Hour + 30*time.Minute,
+			Location: newYork,
+		},
+	}
+	if diff := deep.Equal(perms.TimeWindows, expectedWindows); diff != nil {
+		t.Fatal(diff)
+	}
+
+	ttl := perms.ParameterConstraints["ttl"]
+	if len(ttl) != 1 || ttl[0].Pattern != nil || *ttl[0].Min != 60 || *ttl[0].Max != 3600 {
+		t.Fatalf("bad: ttl constraints: %#v", ttl)
+	}
+	name := perms.ParameterConstraints["name"]
+	if len(name) != 1 || name[0].Pattern.String() != "^(?:[a-z]+)$" || name[0].Min != nil || name[0].Max != nil {
+		t.Fatalf("bad: name constraints: %#v", name)
+	}
+}
+
+func TestPolicy_ParseBadConditions(t *testing.T) {
+	cases := map[string]string{
+		`bound_cidrs = ["nope"]`:                            `error parsing bound_cidrs`,
+		`time_window "w" { days = ["funday"] }`:             `invalid day "funday"`,
+		`time_window "w" { start = "9am" }`:                 `invalid start`,
+		`time_window "w" { end = "24:30" }`:                 `invalid end`,
+		`time_window "w" { start = "24:00" }`:               `start cannot be 24:00`,
+		`time_window "w" { start = "10:00" end = "10:00" }`: `start and end cannot be the same`,
+		`time_window "w" { timezone = "Nowhere/Land" }`:     `invalid timezone`,
+		`parameter_constraint "p" {}`:                       `one of pattern, min or max is required`,
+		`parameter_constraint "p" { pattern = "(" }`:        `invalid pattern`,
+		`parameter_constraint "p" { min = "low" }`:          `invalid min`,
+		`parameter_constraint "p" { min = 10 max = 1 }`:     `max cannot be less than min`,
+	}
+
+	for condition, expected := range cases {
+		_, err := ParseACLPolicy(namespace.RootNamespace, fmt.Sprintf(`
+path "secret/foo" {
+	capabilities = ["read"]
+	%s
+}
+`, condition))
+		if err == nil {
+			t.Fatalf("%s: expected error", condition)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: bad error: %s", condition, err)
+		}
+	}
+}
+
+func TestTimeWindow_Contains(t *testing.T) {
+	window := &TimeWindow{
+		Days:     []time.Weekday{time.Friday},
+		Start:    22 * time.Hour,
+		End:      6 * time.Hour,
+		Location: time.UTC,
+	}
+
+	cases := map[string]bool{
+		// 2020-01-03 is a Friday
+		"2020-01-03T21:59:59Z": false,
+		"2020-01-03T22:00:00Z": true,
+		"2020-01-04T05:59:59Z": true,
+		"2020-01-04T06:00:00Z": false,
+		"2020-01-04T22:30:00Z": false,
+		"2020-01-03T03:00:00Z": false,
+		// The window is in UTC
+		"2020-01-03T23:00:00+02:00": false,
+		"2020-01-04T01:00:00+02:00": true,
+	}
+
+	for raw, expected := range cases {
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := window.Contains(at); actual != expected {
+			t.Errorf("%s: expected %t, got %t", raw, expected, actual)
+		}
+	}
+}
+
+func TestParameterConstraint_Allows(t *testing.T) {
+	min, max := 1.0, 10.0
+	constraint := &ParameterConstraint{
+		Pattern: regexp.MustCompile(`^(?:[0-9]+)$`),
+		Min:     &min,
+		Max:     &max,
+	}
+
+	cases := []struct {
+		value    interface{}
+		expected bool
+	}{
+		{"5", true},
+		{5, true},
+		{json.Number("10"), true},
+		{"11", false},
+		{0, false},
+		{"5.5", false},
+		{"five", false},
+		{[]interface{}{"1", 2}, true},
+		{[]interface{}{"1", 20}, false},
+		{map[string]interface{}{"a": 1}, false},
+		{nil, false},
+	}
+
+	for _, tc := range cases {
+		if actual := constraint.Allows(tc.value); actual != tc.expected {
+			t.Errorf("%#v: expected %t, got %t", tc.value, tc.expected, actual)
+		}
+	}
+}
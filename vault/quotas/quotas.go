@@ -0,0 +1,351 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/armon/go-metrics"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Type represents the kind of a quota.
+type Type string
+
+const (
+	// TypeLeaseCount limits the number of leases held by logins to an
+	// auth mount, or to all the auth mounts of a namespace.
+	TypeLeaseCount Type = "lease-count"
+
+	// TypeRateLimit limits the rate of requests to a path prefix, for all
+	// clients together or for each client entity or address.
+	TypeRateLimit Type = "rate-limit"
+)
+
+func (t Type) String() string {
+	return string(t)
+}
+
+// ErrLeaseCountQuotaExceeded is returned when a request is rejected
+// because a lease count quota has been reached.
+var ErrLeaseCountQuotaExceeded = errors.New("lease count quota exceeded")
+
+// LeaseCountQuota caps the number of leases that can exist at once for a
+// mount. If MountPath is empty, the quota applies to the sum of all leases
+// in the namespace.
+type LeaseCountQuota struct {
+	Name          string `json:"name"`
+	NamespacePath string `json:"namespace_path"`
+	MountPath     string `json:"mount_path"`
+	MaxLeases     int    `json:"max_leases"`
+}
+
+func (q *LeaseCountQuota) validate() error {
+	if q.Name == "" {
+		return errors.New("missing quota name")
+	}
+	if q.MaxLeases <= 0 {
+		return errors.New("max_leases must be greater than zero")
+	}
+	return nil
+}
+
+// Request holds the attributes of a request that quotas are applied to.
+type Request struct {
+	// NamespacePath is the path of the request's namespace, with a
+	// trailing slash, or empty for the root namespace.
+	NamespacePath string
+
+	// MountPath is the path of the mount within the namespace that the
+	// request is routed to, with a trailing slash.
+	MountPath string
+
+	// Path is the path of the request within the namespace.
+	Path string
+
+	// EntityID is the identity entity of the request's token, if any.
+	EntityID string
+
+	// ClientAddress is the network address the request was sent from.
+	ClientAddress string
+}
+
+type leaseCountKey struct {
+	namespacePath string
+	mountPath     string
+}
+
+// Manager holds the configured quotas, and the usage they are
+// measured against.
+type Manager struct {
+	logger     log.Logger
+	metricSink *metricsutil.ClusterMetricSink
+
+	l       sync.RWMutex
+	storage logical.Storage
+
+	leaseCountQuotas map[string]*LeaseCountQuota
+	rateLimitQuotas  map[string]*RateLimitQuota
+	rateLimitStates  map[string]*rateLimitState
+
+	// Lease counts are tracked whether or not a quota applies,
+	// so that quotas can be added at any time.
+	mountLeases     map[leaseCountKey]int
+	namespaceLeases map[string]int
+}
+
+// NewManager creates a Manager with no quotas configured.
+func NewManager(logger log.Logger, metricSink *metricsutil.ClusterMetricSink) *Manager {
+	return &Manager{
+		logger:           logger,
+		metricSink:       metricSink,
+		leaseCountQuotas: make(map[string]*LeaseCountQuota),
+		rateLimitQuotas:  make(map[string]*RateLimitQuota),
+		rateLimitStates:  make(map[string]*rateLimitState),
+		mountLeases:      make(map[leaseCountKey]int),
+		namespaceLeases:  make(map[string]int),
+	}
+}
+
+// Setup loads the quotas persisted in storage, replacing any already
+// configured.
+func (m *Manager) Setup(ctx context.Context, storage logical.Storage) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.storage = storage
+	m.leaseCountQuotas = make(map[string]*LeaseCountQuota)
+
+	prefix := TypeLeaseCount.String() + "/"
+	names, err := storage.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list lease count quotas: %w", err)
+	}
+	for _, name := range names {
+		entry, err := storage.Get(ctx, prefix+name)
+		if err != nil {
+			return fmt.Errorf("failed to read lease count quota %q: %w", name, err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var q LeaseCountQuota
+		if err := entry.DecodeJSON(&q); err != nil {
+			return fmt.Errorf("failed to decode lease count quota %q: %w", name, err)
+		}
+		m.leaseCountQuotas[q.Name] = &q
+	}
+
+	m.rateLimitQuotas = make(map[string]*RateLimitQuota)
+	m.rateLimitStates = make(map[string]*rateLimitState)
+
+	prefix = TypeRateLimit.String() + "/"
+	names, err = storage.List(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to list rate limit quotas: %w", err)
+	}
+	for _, name := range names {
+		entry, err := storage.Get(ctx, prefix+name)
+		if err != nil {
+			return fmt.Errorf("failed to read rate limit quota %q: %w", name, err)
+		}
+		if entry == nil {
+			continue
+		}
+
+		var q RateLimitQuota
+		if err := entry.DecodeJSON(&q); err != nil {
+			return fmt.Errorf("failed to decode rate limit quota %q: %w", name, err)
+		}
+		m.rateLimitQuotas[q.Name] = &q
+		m.rateLimitStates[q.Name] = newRateLimitState()
+	}
+
+	return nil
+}
+
+// Reset removes the configured quotas from memory, as on seal. Lease
+// counts are retained, since they are maintained by the expiration
+// manager as leases are loaded and removed.
+func (m *Manager) Reset() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.storage = nil
+	m.leaseCountQuotas = make(map[string]*LeaseCountQuota)
+	m.rateLimitQuotas = make(map[string]*RateLimitQuota)
+	m.rateLimitStates = make(map[string]*rateLimitState)
+}
+
+// SetLeaseCountQuota creates or replaces a lease count quota.
+func (m *Manager) SetLeaseCountQuota(ctx context.Context, q *LeaseCountQuota) error {
+	if err := q.validate(); err != nil {
+		return err
+	}
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, other := range m.leaseCountQuotas {
+		if other.Name != q.Name && other.NamespacePath == q.NamespacePath && other.MountPath == q.MountPath {
+			return fmt.Errorf("quota %q is already defined for this path", other.Name)
+		}
+	}
+
+	if m.storage != nil {
+		entry, err := logical.StorageEntryJSON(TypeLeaseCount.String()+"/"+q.Name, q)
+		if err != nil {
+			return err
+		}
+		if err := m.storage.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to persist lease count quota: %w", err)
+		}
+	}
+
+	stored := *q
+	m.leaseCountQuotas[q.Name] = &stored
+	return nil
+}
+
+// LeaseCountQuota returns a copy of the named quota, or nil.
+func (m *Manager) LeaseCountQuota(name string) *LeaseCountQuota {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	q, ok := m.leaseCountQuotas[name]
+	if !ok {
+		return nil
+	}
+	ret := *q
+	return &ret
+}
+
+// LeaseCountQuotaNames returns the sorted names of the lease count quotas.
+func (m *Manager) LeaseCountQuotaNames() []string {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	names := make([]string, 0, len(m.leaseCountQuotas))
+	for name := range m.leaseCountQuotas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeleteLeaseCountQuota removes the named quota, if it exists.
+func (m *Manager) DeleteLeaseCountQuota(ctx context.Context, name string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.storage != nil {
+		if err := m.storage.Delete(ctx, TypeLeaseCount.String()+"/"+name); err != nil {
+			return fmt.Errorf("failed to delete lease count quota: %w", err)
+		}
+	}
+	delete(m.leaseCountQuotas, name)
+	return nil
+}
+
+// LeaseCount returns the number of leases currently counted against q.
+func (m *Manager) LeaseCount(q *LeaseCountQuota) int {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.leaseCountLocked(q)
+}
+
+func (m *Manager) leaseCountLocked(q *LeaseCountQuota) int {
+	if q.MountPath == "" {
+		return m.namespaceLeases[q.NamespacePath]
+	}
+	return m.mountLeases[leaseCountKey{q.NamespacePath, q.MountPath}]
+}
+
+// ApplyLeaseCountQuota returns an error wrapping ErrLeaseCountQuotaExceeded
+// if a quota for the request's mount or namespace has been reached, so
+// that a request creating another lease should be rejected. Concurrent
+// requests may exceed a quota by a small amount.
+func (m *Manager) ApplyLeaseCountQuota(req *Request) error {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	for _, q := range m.leaseCountQuotas {
+		if q.NamespacePath != req.NamespacePath {
+			continue
+		}
+		if q.MountPath != "" && q.MountPath != req.MountPath {
+			continue
+		}
+		if m.leaseCountLocked(q) >= q.MaxLeases {
+			scope := "namespace"
+			if q.MountPath != "" {
+				scope = fmt.Sprintf("mount %q", q.MountPath)
+			}
+			return fmt.Errorf("%w: quota %q allows at most %d leases for this %s", ErrLeaseCountQuotaExceeded, q.Name, q.MaxLeases, scope)
+		}
+	}
+	return nil
+}
+
+// LeaseCreated counts a new lease against the given mount.
+func (m *Manager) LeaseCreated(namespacePath, mountPath string) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.mountLeases[leaseCountKey{namespacePath, mountPath}]++
+	m.namespaceLeases[namespacePath]++
+}
+
+// LeaseDeleted removes a lease previously counted by LeaseCreated.
+func (m *Manager) LeaseDeleted(namespacePath, mountPath string) {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	key := leaseCountKey{namespacePath, mountPath}
+	if m.mountLeases[key] <= 1 {
+		delete(m.mountLeases, key)
+	} else {
+		m.mountLeases[key]--
+	}
+	if m.namespaceLeases[namespacePath] <= 1 {
+		delete(m.namespaceLeases, namespacePath)
+	} else {
+		m.namespaceLeases[namespacePath]--
+	}
+}
+
+// EmitMetrics reports the usage of each lease count quota.
+func (m *Manager) EmitMetrics() {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	for _, q := range m.leaseCountQuotas {
+		labels := []metrics.Label{
+			{"name", q.Name},
+			{"namespace", namespaceLabel(q.NamespacePath)},
+			{"mount_point", q.MountPath},
+		}
+		count := m.leaseCountLocked(q)
+		m.metricSink.SetGaugeWithLabels([]string{"quota", "lease_count", "leases"},
+			float32(count), labels)
+		m.metricSink.SetGaugeWithLabels([]string{"quota", "lease_count", "max"},
+			float32(q.MaxLeases), labels)
+		m.metricSink.SetGaugeWithLabels([]string{"quota", "lease_count", "utilization"},
+			float32(count)/float32(q.MaxLeases), labels)
+	}
+}
+
+// namespaceLabel matches the labels used by the other usage gauges.
+func namespaceLabel(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return strings.Trim(path, "/")
+}
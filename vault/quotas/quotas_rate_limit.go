@@ -0,0 +1,306 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// GranularityPath shares a rate limit between all the clients of the
+	// quota's path.
+	GranularityPath = "path"
+
+	// GranularityEntity gives each identity entity its own rate limit.
+	// Requests made without an entity, such as logins, are limited by
+	// their client address instead.
+	GranularityEntity = "entity"
+
+	// GranularityIP gives each client address its own rate limit.
+	GranularityIP = "ip"
+)
+
+// ErrRateLimitQuotaExceeded is returned when a request is rejected
+// because a rate limit quota has been exceeded.
+var ErrRateLimitQuotaExceeded = errors.New("rate limit quota exceeded")
+
+// RateLimitQuota limits the rate of requests to a path prefix within a
+// namespace. If Path is empty, the quota applies to every request in the
+// namespace. When several quotas match a request, only the one with the
+// longest path is applied.
+type RateLimitQuota struct {
+	Name          string `json:"name"`
+	NamespacePath string `json:"namespace_path"`
+	Path          string `json:"path"`
+
+	// Granularity is one of GranularityPath, GranularityEntity and
+	// GranularityIP.
+	Granularity string `json:"granularity"`
+
+	// Rate requests are allowed per Interval, with up to Burst requests
+	// allowed at once.
+	Rate     float64       `json:"rate"`
+	Interval time.Duration `json:"interval"`
+	Burst    int           `json:"burst"`
+
+	// A client whose requests are rejected BlockThreshold times in a row
+	// has all its requests rejected for BlockInterval. Clients are not
+	// blocked if BlockInterval is zero.
+	BlockInterval  time.Duration `json:"block_interval"`
+	BlockThreshold int           `json:"block_threshold"`
+}
+
+func (q *RateLimitQuota) validate() error {
+	if q.Name == "" {
+		return errors.New("missing quota name")
+	}
+	switch q.Granularity {
+	case GranularityPath, GranularityEntity, GranularityIP:
+	default:
+		return fmt.Errorf("invalid granularity %q", q.Granularity)
+	}
+	if q.Rate <= 0 {
+		return errors.New("rate must be greater than zero")
+	}
+	if q.Interval <= 0 {
+		return errors.New("interval must be greater than zero")
+	}
+	if q.Burst < 0 {
+		return errors.New("burst cannot be negative")
+	}
+	if q.BlockInterval < 0 {
+		return errors.New("block_interval cannot be negative")
+	}
+	if q.BlockThreshold < 0 {
+		return errors.New("block_threshold cannot be negative")
+	}
+	return nil
+}
+
+// burst returns the number of requests allowed at once, which defaults
+// to the rate rounded up.
+func (q *RateLimitQuota) burst() int {
+	if q.Burst > 0 {
+		return q.Burst
+	}
+	return int(math.Ceil(q.Rate))
+}
+
+// rateLimitState holds the limiters of the clients of a rate limit quota.
+type rateLimitState struct {
+	l         sync.Mutex
+	clients   map[string]*rateLimitClient
+	lastPurge time.Time
+}
+
+type rateLimitClient struct {
+	limiter      *rate.Limiter
+	violations   int
+	blockedUntil time.Time
+	lastSeen     time.Time
+}
+
+func newRateLimitState() *rateLimitState {
+	return &rateLimitState{
+		clients:   make(map[string]*rateLimitClient),
+		lastPurge: time.Now(),
+	}
+}
+
+// allow reports whether the client may make a request now. If not, it
+// also returns how long the client should wait before retrying.
+func (s *rateLimitState) allow(q *RateLimitQuota, client string, now time.Time) (bool, time.Duration) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	s.purgeLocked(q, now)
+
+	c, ok := s.clients[client]
+	if !ok {
+		c = &rateLimitClient{
+			limiter: rate.NewLimiter(rate.Limit(q.Rate/q.Interval.Seconds()), q.burst()),
+		}
+		s.clients[client] = c
+	}
+	c.lastSeen = now
+
+	if now.Before(c.blockedUntil) {
+		return false, c.blockedUntil.Sub(now)
+	}
+
+	if c.limiter.AllowN(now, 1) {
+		c.violations = 0
+		return true, 0
+	}
+
+	c.violations++
+	threshold := q.BlockThreshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	if q.BlockInterval > 0 && c.violations >= threshold {
+		c.violations = 0
+		c.blockedUntil = now.Add(q.BlockInterval)
+		return false, q.BlockInterval
+	}
+	r := c.limiter.ReserveN(now, 1)
+	delay := r.DelayFrom(now)
+	r.CancelAt(now)
+	return false, delay
+}
+
+// purgeLocked forgets the clients which have been idle long enough for
+// their limiters to be full again and any block to be lifted, so that the
+// state does not grow with every client ever seen.
+func (s *rateLimitState) purgeLocked(q *RateLimitQuota, now time.Time) {
+	idle := q.Interval
+	if q.BlockInterval > idle {
+		idle = q.BlockInterval
+	}
+	if now.Sub(s.lastPurge) < idle {
+		return
+	}
+	s.lastPurge = now
+
+	for client, c := range s.clients {
+		if now.Sub(c.lastSeen) > idle && !now.Before(c.blockedUntil) {
+			delete(s.clients, client)
+		}
+	}
+}
+
+// SetRateLimitQuota creates or replaces a rate limit quota. Replacing a
+// quota resets the limits of its clients.
+func (m *Manager) SetRateLimitQuota(ctx context.Context, q *RateLimitQuota) error {
+	if err := q.validate(); err != nil {
+		return err
+	}
+
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	for _, other := range m.rateLimitQuotas {
+		if other.Name != q.Name && other.NamespacePath == q.NamespacePath && other.Path == q.Path {
+			return fmt.Errorf("quota %q is already defined for this path", other.Name)
+		}
+	}
+
+	if m.storage != nil {
+		entry, err := logical.StorageEntryJSON(TypeRateLimit.String()+"/"+q.Name, q)
+		if err != nil {
+			return err
+		}
+		if err := m.storage.Put(ctx, entry); err != nil {
+			return fmt.Errorf("failed to persist rate limit quota: %w", err)
+		}
+	}
+
+	stored := *q
+	m.rateLimitQuotas[q.Name] = &stored
+	m.rateLimitStates[q.Name] = newRateLimitState()
+	return nil
+}
+
+// RateLimitQuota returns a copy of the named quota, or nil.
+func (m *Manager) RateLimitQuota(name string) *RateLimitQuota {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	q, ok := m.rateLimitQuotas[name]
+	if !ok {
+		return nil
+	}
+	ret := *q
+	return &ret
+}
+
+// RateLimitQuotaNames returns the sorted names of the rate limit quotas.
+func (m *Manager) RateLimitQuotaNames() []string {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	names := make([]string, 0, len(m.rateLimitQuotas))
+	for name := range m.rateLimitQuotas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeleteRateLimitQuota removes the named quota, if it exists.
+func (m *Manager) DeleteRateLimitQuota(ctx context.Context, name string) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	if m.storage != nil {
+		if err := m.storage.Delete(ctx, TypeRateLimit.String()+"/"+name); err != nil {
+			return fmt.Errorf("failed to delete rate limit quota: %w", err)
+		}
+	}
+	delete(m.rateLimitQuotas, name)
+	delete(m.rateLimitStates, name)
+	return nil
+}
+
+// ApplyRateLimitQuota returns an error wrapping ErrRateLimitQuotaExceeded
+// if the request exceeds the most specific rate limit quota matching its
+// path, along with how long the client should wait before retrying.
+// Rejected requests are counted in the quota.rate_limit.violation metric.
+func (m *Manager) ApplyRateLimitQuota(req *Request) (time.Duration, error) {
+	m.l.RLock()
+	var quota *RateLimitQuota
+	for _, q := range m.rateLimitQuotas {
+		if q.NamespacePath != req.NamespacePath || !strings.HasPrefix(req.Path, q.Path) {
+			continue
+		}
+		if quota == nil || len(q.Path) > len(quota.Path) {
+			quota = q
+		}
+	}
+	var state *rateLimitState
+	if quota != nil {
+		state = m.rateLimitStates[quota.Name]
+	}
+	m.l.RUnlock()
+
+	if quota == nil || state == nil {
+		return 0, nil
+	}
+
+	var client string
+	switch quota.Granularity {
+	case GranularityEntity:
+		client = req.EntityID
+		if client == "" {
+			client = req.ClientAddress
+		}
+	case GranularityIP:
+		client = req.ClientAddress
+	}
+
+	allowed, retryAfter := state.allow(quota, client, time.Now())
+	if allowed {
+		return 0, nil
+	}
+
+	labels := []metrics.Label{
+		{"name", quota.Name},
+		{"namespace", namespaceLabel(quota.NamespacePath)},
+		{"path", quota.Path},
+	}
+	if quota.Granularity != GranularityPath {
+		labels = append(labels, metrics.Label{"client", client})
+	}
+	m.metricSink.IncrCounterWithLabels([]string{"quota", "rate_limit", "violation"}, 1, labels)
+
+	return retryAfter, fmt.Errorf("%w: quota %q allows %g requests every %s", ErrRateLimitQuotaExceeded, quota.Name, quota.Rate, quota.Interval)
+}
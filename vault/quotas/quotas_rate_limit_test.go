@@ -0,0 +1,187 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRateLimitQuota_Apply(t *testing.T) {
+	m, _ := testManager(t)
+	ctx := context.Background()
+
+	if err := m.SetRateLimitQuota(ctx, &RateLimitQuota{
+		Name:        "secret",
+		Path:        "secret/",
+		Granularity: GranularityPath,
+		Rate:        2,
+		Interval:    time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := m.ApplyRateLimitQuota(&Request{Path: "secret/foo", ClientAddress: "10.0.0.1"}); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	// The limit is shared by all clients
+	retryAfter, err := m.ApplyRateLimitQuota(&Request{Path: "secret/bar", ClientAddress: "10.0.0.2"})
+	if !errors.Is(err, ErrRateLimitQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if retryAfter <= 0 {
+		t.Fatalf("expected a retry delay, got %s", retryAfter)
+	}
+
+	if _, err := m.ApplyRateLimitQuota(&Request{Path: "kv/foo"}); err != nil {
+		t.Fatalf("other path affected by quota: %v", err)
+	}
+	if _, err := m.ApplyRateLimitQuota(&Request{NamespacePath: "ns1/", Path: "secret/foo"}); err != nil {
+		t.Fatalf("other namespace affected by quota: %v", err)
+	}
+}
+
+func TestRateLimitQuota_Granularity(t *testing.T) {
+	m, _ := testManager(t)
+	ctx := context.Background()
+
+	if err := m.SetRateLimitQuota(ctx, &RateLimitQuota{
+		Name:        "entity",
+		Granularity: GranularityEntity,
+		Rate:        1,
+		Interval:    time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The more specific quota applies to its path only
+	if err := m.SetRateLimitQuota(ctx, &RateLimitQuota{
+		Name:        "login",
+		Path:        "auth/userpass/login",
+		Granularity: GranularityIP,
+		Rate:        1,
+		Interval:    time.Hour,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &Request{Path: "secret/foo", EntityID: "alice", ClientAddress: "10.0.0.1"}
+	bob := &Request{Path: "secret/foo", EntityID: "bob", ClientAddress: "10.0.0.1"}
+	if _, err := m.ApplyRateLimitQuota(alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ApplyRateLimitQuota(alice); !errors.Is(err, ErrRateLimitQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if _, err := m.ApplyRateLimitQuota(bob); err != nil {
+		t.Fatalf("entity limited by another entity's requests: %v", err)
+	}
+
+	login := &Request{Path: "auth/userpass/login/alice", ClientAddress: "10.0.0.1"}
+	if _, err := m.ApplyRateLimitQuota(login); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ApplyRateLimitQuota(login); !errors.Is(err, ErrRateLimitQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if _, err := m.ApplyRateLimitQuota(&Request{Path: "auth/userpass/login/bob", ClientAddress: "10.0.0.2"}); err != nil {
+		t.Fatalf("address limited by another address's requests: %v", err)
+	}
+}
+
+func TestRateLimitQuota_Block(t *testing.T) {
+	q := &RateLimitQuota{
+		Name:           "block",
+		Granularity:    GranularityIP,
+		Rate:           1,
+		Interval:       time.Second,
+		BlockInterval:  time.Minute,
+		BlockThreshold: 2,
+	}
+	s := newRateLimitState()
+	now := time.Now()
+
+	if ok, _ := s.allow(q, "10.0.0.1", now); !ok {
+		t.Fatal("expected first request to be allowed")
+	}
+	if ok, retryAfter := s.allow(q, "10.0.0.1", now); ok || retryAfter > time.Second {
+		t.Fatalf("expected rate limit without block, got %t, %s", ok, retryAfter)
+	}
+	if ok, retryAfter := s.allow(q, "10.0.0.1", now); ok || retryAfter != time.Minute {
+		t.Fatalf("expected block after second violation, got %t, %s", ok, retryAfter)
+	}
+
+	// The client stays blocked even once its limiter has refilled
+	if ok, _ := s.allow(q, "10.0.0.1", now.Add(30*time.Second)); ok {
+		t.Fatal("expected client to still be blocked")
+	}
+	if ok, _ := s.allow(q, "10.0.0.1", now.Add(2*time.Minute)); !ok {
+		t.Fatal("expected client to be unblocked")
+	}
+}
+
+func TestRateLimitQuota_Persistence(t *testing.T) {
+	m, storage := testManager(t)
+	ctx := context.Background()
+
+	q := &RateLimitQuota{
+		Name:          "secret",
+		Path:          "secret/",
+		Granularity:   GranularityEntity,
+		Rate:          100,
+		Interval:      time.Minute,
+		Burst:         10,
+		BlockInterval: time.Hour,
+	}
+	if err := m.SetRateLimitQuota(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetRateLimitQuota(ctx, &RateLimitQuota{
+		Name:        "duplicate",
+		Path:        "secret/",
+		Granularity: GranularityPath,
+		Rate:        1,
+		Interval:    time.Second,
+	}); err == nil {
+		t.Fatal("expected error for a second quota on the same path")
+	}
+
+	m.Reset()
+	if names := m.RateLimitQuotaNames(); len(names) != 0 {
+		t.Fatalf("expected no quotas after reset, got %v", names)
+	}
+
+	if err := m.Setup(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.RateLimitQuota("secret"); !reflect.DeepEqual(got, q) {
+		t.Fatalf("got %#v, expected %#v", got, q)
+	}
+
+	if err := m.DeleteRateLimitQuota(ctx, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Setup(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	if names := m.RateLimitQuotaNames(); len(names) != 0 {
+		t.Fatalf("expected no quotas after delete, got %v", names)
+	}
+}
+
+func TestRateLimitQuota_Validation(t *testing.T) {
+	m, _ := testManager(t)
+	ctx := context.Background()
+
+	for _, q := range []*RateLimitQuota{
+		{Name: "granularity", Granularity: "token", Rate: 1, Interval: time.Second},
+		{Name: "rate", Granularity: GranularityPath, Interval: time.Second},
+		{Name: "interval", Granularity: GranularityPath, Rate: 1},
+	} {
+		if err := m.SetRateLimitQuota(ctx, q); err == nil {
+			t.Fatalf("expected error for invalid %s", q.Name)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/metricsutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func testManager(t *testing.T) (*Manager, logical.Storage) {
+	t.Helper()
+	storage := &logical.InmemStorage{}
+	m := NewManager(log.NewNullLogger(), metricsutil.BlackholeSink())
+	if err := m.Setup(context.Background(), storage); err != nil {
+		t.Fatal(err)
+	}
+	return m, storage
+}
+
+func TestLeaseCountQuota_Apply(t *testing.T) {
+	m, _ := testManager(t)
+	ctx := context.Background()
+
+	if err := m.SetLeaseCountQuota(ctx, &LeaseCountQuota{
+		Name:      "userpass",
+		MountPath: "auth/userpass/",
+		MaxLeases: 2,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	userpass := &Request{MountPath: "auth/userpass/"}
+	approle := &Request{MountPath: "auth/approle/"}
+
+	for i := 0; i < 2; i++ {
+		if err := m.ApplyLeaseCountQuota(userpass); err != nil {
+			t.Fatalf("lease %d: %v", i, err)
+		}
+		m.LeaseCreated("", "auth/userpass/")
+	}
+	if err := m.ApplyLeaseCountQuota(userpass); !errors.Is(err, ErrLeaseCountQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if err := m.ApplyLeaseCountQuota(approle); err != nil {
+		t.Fatalf("other mount affected by quota: %v", err)
+	}
+
+	m.LeaseDeleted("", "auth/userpass/")
+	if err := m.ApplyLeaseCountQuota(userpass); err != nil {
+		t.Fatalf("quota still applied after lease deleted: %v", err)
+	}
+}
+
+func TestLeaseCountQuota_Namespace(t *testing.T) {
+	m, _ := testManager(t)
+	ctx := context.Background()
+
+	if err := m.SetLeaseCountQuota(ctx, &LeaseCountQuota{
+		Name:      "global",
+		MaxLeases: 3,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m.LeaseCreated("", "auth/userpass/")
+	m.LeaseCreated("", "auth/userpass/")
+	m.LeaseCreated("", "auth/approle/")
+	// Leases in other namespaces are not counted.
+	m.LeaseCreated("ns1/", "auth/approle/")
+
+	if count := m.LeaseCount(m.LeaseCountQuota("global")); count != 3 {
+		t.Fatalf("expected 3 leases, got %d", count)
+	}
+	if err := m.ApplyLeaseCountQuota(&Request{MountPath: "auth/ldap/"}); !errors.Is(err, ErrLeaseCountQuotaExceeded) {
+		t.Fatalf("expected quota exceeded, got %v", err)
+	}
+	if err := m.ApplyLeaseCountQuota(&Request{NamespacePath: "ns1/", MountPath: "auth/ldap/"}); err != nil {
+		t.Fatalf("other namespace affected by quota: %v", err)
+	}
+}
+
+func TestLeaseCountQuota_Persistence(t *testing.T) {
+	m, storage := testManager(t)
+	ctx := context.Background()
+
+	q := &LeaseCountQuota{
+		Name:      "userpass",
+		MountPath: "auth/userpass/",
+		MaxLeases: 10,
+	}
+	if err := m.SetLeaseCountQuota(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetLeaseCountQuota(ctx, &LeaseCountQuota{
+		Name:      "duplicate",
+		MountPath: "auth/userpass/",
+		MaxLeases: 5,
+	}); err == nil {
+		t.Fatal("expected error for a second quota on the same path")
+	}
+
+	m.Reset()
+	if names := m.LeaseCountQuotaNames(); len(names) != 0 {
+		t.Fatalf("expected no quotas after reset, got %v", names)
+	}
+
+	if err := m.Setup(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.LeaseCountQuota("userpass"); !reflect.DeepEqual(got, q) {
+		t.Fatalf("got %#v, expected %#v", got, q)
+	}
+
+	if err := m.DeleteLeaseCountQuota(ctx, "userpass"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Setup(ctx, storage); err != nil {
+		t.Fatal(err)
+	}
+	if names := m.LeaseCountQuotaNames(); len(names) != 0 {
+		t.Fatalf("expected no quotas after delete, got %v", names)
+	}
+}
+
+func TestLeaseCountQuota_Validation(t *testing.T) {
+	m, _ := testManager(t)
+
+	if err := m.SetLeaseCountQuota(context.Background(), &LeaseCountQuota{Name: "zero"}); err == nil {
+		t.Fatal("expected error for zero max_leases")
+	}
+}
@@ -35,25 +35,51 @@ var (
 
 type raftFollowerStates struct {
 	l         sync.RWMutex
-	followers map[string]uint64
+	followers map[string]*raftFollowerState
+}
+
+// raftFollowerState is what the active node knows of a follower from its
+// heartbeats
+type raftFollowerState struct {
+	AppliedIndex  uint64
+	LastHeartbeat time.Time
 }
 
 func (s *raftFollowerStates) update(nodeID string, appliedIndex uint64) {
 	s.l.Lock()
-	s.followers[nodeID] = appliedIndex
+	s.followers[nodeID] = &raftFollowerState{
+		AppliedIndex:  appliedIndex,
+		LastHeartbeat: time.Now(),
+	}
 	s.l.Unlock()
 }
 func (s *raftFollowerStates) delete(nodeID string) {
-	s.l.RLock()
+	s.l.Lock()
 	delete(s.followers, nodeID)
-	s.l.RUnlock()
+	s.l.Unlock()
 }
 func (s *raftFollowerStates) get(nodeID string) uint64 {
 	s.l.RLock()
-	index := s.followers[nodeID]
+	var index uint64
+	if state, ok := s.followers[nodeID]; ok {
+		index = state.AppliedIndex
+	}
 	s.l.RUnlock()
 	return index
 }
+
+// state returns a copy of the state of the follower, or nil if it is unknown
+func (s *raftFollowerStates) state(nodeID string) *raftFollowerState {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	state, ok := s.followers[nodeID]
+	if !ok {
+		return nil
+	}
+	copied := *state
+	return &copied
+}
 func (s *raftFollowerStates) minIndex() uint64 {
 	var min uint64 = math.MaxUint64
 	minFunc := func(a, b uint64) uint64 {
@@ -64,8 +90,8 @@ func (s *raftFollowerStates) minIndex() uint64 {
 	}
 
 	s.l.RLock()
-	for _, i := range s.followers {
-		min = minFunc(min, i)
+	for _, state := range s.followers {
+		min = minFunc(min, state.AppliedIndex)
 	}
 	s.l.RUnlock()
 
@@ -172,11 +198,17 @@ func (c *Core) startRaftStorage(ctx context.Context) (retErr error) {
 
 func (c *Core) setupRaftActiveNode(ctx context.Context) error {
 	c.pendingRaftPeers = &sync.Map{}
-	return c.startPeriodicRaftTLSRotate(ctx)
+	if err := c.startPeriodicRaftTLSRotate(ctx); err != nil {
+		return err
+	}
+	c.startRaftAutopilot(ctx)
+	return c.startRaftAutoSnapshots(ctx)
 }
 
 func (c *Core) stopRaftActiveNode() {
 	c.pendingRaftPeers = nil
+	c.stopRaftAutoSnapshots()
+	c.stopRaftAutopilot()
 	c.stopPeriodicRaftTLSRotate()
 }
 
@@ -207,7 +239,7 @@ func (c *Core) startPeriodicRaftTLSRotate(ctx context.Context) error {
 
 	stopCh := make(chan struct{})
 	followerStates := &raftFollowerStates{
-		followers: make(map[string]uint64),
+		followers: make(map[string]*raftFollowerState),
 	}
 
 	// Pre-populate the follower list with the set of peers.
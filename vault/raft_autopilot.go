@@ -0,0 +1,319 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	raftAutopilotConfigPath = "core/raft/autopilot/configuration"
+
+	raftAutopilotStatusLeader   = "leader"
+	raftAutopilotStatusVoter    = "voter"
+	raftAutopilotStatusNonVoter = "non-voter"
+
+	raftAutopilotNodeAlive = "alive"
+	raftAutopilotNodeDead  = "dead"
+)
+
+// raftAutopilotInterval is how often autopilot checks the health of the
+// servers of the raft cluster
+var raftAutopilotInterval = 2 * time.Second
+
+// raftAutopilotConfig configures how autopilot manages the servers of the raft
+// cluster
+type raftAutopilotConfig struct {
+	// CleanupDeadServers enables the removal of servers which have not been
+	// heard from for DeadServerLastContactThreshold
+	CleanupDeadServers             bool          `json:"cleanup_dead_servers"`
+	LastContactThreshold           time.Duration `json:"last_contact_threshold"`
+	DeadServerLastContactThreshold time.Duration `json:"dead_server_last_contact_threshold"`
+	MaxTrailingLogs                uint64        `json:"max_trailing_logs"`
+
+	// MinQuorum is the fewest voters dead server cleanup leaves
+	MinQuorum int `json:"min_quorum"`
+
+	// ServerStabilizationTime is how long new servers must stay healthy
+	// before being promoted to voters
+	ServerStabilizationTime time.Duration `json:"server_stabilization_time"`
+}
+
+func defaultRaftAutopilotConfig() *raftAutopilotConfig {
+	return &raftAutopilotConfig{
+		LastContactThreshold:           10 * time.Second,
+		DeadServerLastContactThreshold: 24 * time.Hour,
+		MaxTrailingLogs:                1000,
+		MinQuorum:                      3,
+		ServerStabilizationTime:        10 * time.Second,
+	}
+}
+
+func (c *raftAutopilotConfig) validate() error {
+	switch {
+	case c.LastContactThreshold <= 0:
+		return errors.New("last_contact_threshold must be positive")
+	case c.DeadServerLastContactThreshold < time.Minute:
+		return errors.New("dead_server_last_contact_threshold must be at least 1 minute")
+	case c.DeadServerLastContactThreshold < c.LastContactThreshold:
+		return errors.New("dead_server_last_contact_threshold must not be less than last_contact_threshold")
+	case c.ServerStabilizationTime < 0:
+		return errors.New("server_stabilization_time must not be negative")
+	case c.CleanupDeadServers && c.MinQuorum < 3:
+		return errors.New("min_quorum must be at least 3 when cleanup_dead_servers is enabled")
+	}
+	return nil
+}
+
+// raftAutopilotServer is the state of a server of the raft cluster, as seen
+// by autopilot
+type raftAutopilotServer struct {
+	ID          string    `json:"id"`
+	Address     string    `json:"address"`
+	Status      string    `json:"status"`
+	NodeStatus  string    `json:"node_status"`
+	Healthy     bool      `json:"healthy"`
+	LastContact string    `json:"last_contact"`
+	LastIndex   uint64    `json:"last_index"`
+	StableSince time.Time `json:"stable_since"`
+
+	lastContact time.Duration
+}
+
+// raftAutopilotState is the state of the raft cluster, as seen by autopilot
+type raftAutopilotState struct {
+	Healthy          bool                            `json:"healthy"`
+	FailureTolerance int                             `json:"failure_tolerance"`
+	Leader           string                          `json:"leader"`
+	Voters           []string                        `json:"voters"`
+	Servers          map[string]*raftAutopilotServer `json:"servers"`
+}
+
+// raftAutopilot runs on the active node. It promotes new servers to voters
+// once they are stable, and removes dead servers when so configured.
+type raftAutopilot struct {
+	core           *Core
+	logger         log.Logger
+	raftStorage    *raft.RaftBackend
+	followerStates *raftFollowerStates
+	stopCh         chan struct{}
+
+	l sync.Mutex
+	// healthySince tracks since when each server has been healthy
+	healthySince map[string]time.Time
+}
+
+// startRaftAutopilot starts autopilot on the active node. It must be called
+// once the follower states are tracked.
+func (c *Core) startRaftAutopilot(ctx context.Context) {
+	raftStorage, ok := c.underlyingPhysical.(*raft.RaftBackend)
+	if !ok || c.raftFollowerStates == nil || c.disableAutopilot {
+		return
+	}
+
+	a := &raftAutopilot{
+		core:           c,
+		logger:         c.logger.Named("raft.autopilot"),
+		raftStorage:    raftStorage,
+		followerStates: c.raftFollowerStates,
+		stopCh:         make(chan struct{}),
+		healthySince:   make(map[string]time.Time),
+	}
+	c.raftAutopilot = a
+
+	go a.run(ctx)
+}
+
+func (c *Core) stopRaftAutopilot() {
+	if c.raftAutopilot != nil {
+		close(c.raftAutopilot.stopCh)
+	}
+	c.raftAutopilot = nil
+}
+
+func (c *Core) raftAutopilotConfig(ctx context.Context) (*raftAutopilotConfig, error) {
+	config := defaultRaftAutopilotConfig()
+
+	entry, err := c.barrier.Get(ctx, raftAutopilotConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Core) setRaftAutopilotConfig(ctx context.Context, config *raftAutopilotConfig) error {
+	entry, err := logical.StorageEntryJSON(raftAutopilotConfigPath, config)
+	if err != nil {
+		return err
+	}
+	return c.barrier.Put(ctx, entry)
+}
+
+func (a *raftAutopilot) run(ctx context.Context) {
+	ticker := time.NewTicker(raftAutopilotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.reconcile(ctx); err != nil {
+				a.logger.Error("failed to reconcile the raft cluster", "error", err)
+			}
+		}
+	}
+}
+
+// reconcile promotes the stable non-voters and removes the dead servers
+func (a *raftAutopilot) reconcile(ctx context.Context) error {
+	config, err := a.core.raftAutopilotConfig(ctx)
+	if err != nil {
+		return err
+	}
+	state, err := a.state(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, server := range state.Servers {
+		if server.Status != raftAutopilotStatusNonVoter || !server.Healthy {
+			continue
+		}
+		if now.Sub(server.StableSince) < config.ServerStabilizationTime {
+			continue
+		}
+		a.logger.Info("promoting stable server to voter", "node_id", server.ID)
+		if err := a.raftStorage.AddPeer(ctx, server.ID, server.Address); err != nil {
+			return err
+		}
+	}
+
+	if !config.CleanupDeadServers {
+		return nil
+	}
+
+	voters := len(state.Voters)
+	for _, server := range state.Servers {
+		if server.NodeStatus != raftAutopilotNodeDead {
+			continue
+		}
+		if server.Status == raftAutopilotStatusVoter {
+			if voters-1 < config.MinQuorum {
+				a.logger.Warn("not removing dead server; doing so would leave fewer voters than min_quorum", "node_id", server.ID, "min_quorum", config.MinQuorum)
+				continue
+			}
+			voters--
+		}
+
+		a.logger.Info("removing dead server", "node_id", server.ID, "last_contact", server.LastContact)
+		if err := a.raftStorage.RemovePeer(ctx, server.ID); err != nil {
+			return err
+		}
+		a.followerStates.delete(server.ID)
+		a.l.Lock()
+		delete(a.healthySince, server.ID)
+		a.l.Unlock()
+	}
+
+	return nil
+}
+
+// state computes the health of the servers of the raft cluster
+func (a *raftAutopilot) state(ctx context.Context, config *raftAutopilotConfig) (*raftAutopilotState, error) {
+	raftConfig, err := a.raftStorage.GetConfiguration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	leaderIndex := a.raftStorage.AppliedIndex()
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	now := time.Now()
+	state := &raftAutopilotState{
+		Servers: make(map[string]*raftAutopilotServer, len(raftConfig.Servers)),
+	}
+	healthyVoters := 0
+	seen := make(map[string]bool, len(raftConfig.Servers))
+	for _, rs := range raftConfig.Servers {
+		seen[rs.NodeID] = true
+
+		server := &raftAutopilotServer{
+			ID:         rs.NodeID,
+			Address:    rs.Address,
+			Status:     raftAutopilotStatusNonVoter,
+			NodeStatus: raftAutopilotNodeAlive,
+		}
+		switch {
+		case rs.Leader:
+			server.Status = raftAutopilotStatusLeader
+			state.Leader = rs.NodeID
+		case rs.Voter:
+			server.Status = raftAutopilotStatusVoter
+		}
+
+		if rs.Leader {
+			server.Healthy = true
+			server.LastIndex = leaderIndex
+		} else if fs := a.followerStates.state(rs.NodeID); fs != nil {
+			server.lastContact = now.Sub(fs.LastHeartbeat)
+			server.LastIndex = fs.AppliedIndex
+			server.Healthy = server.lastContact <= config.LastContactThreshold &&
+				(fs.AppliedIndex >= leaderIndex || leaderIndex-fs.AppliedIndex <= config.MaxTrailingLogs)
+			if server.lastContact > config.DeadServerLastContactThreshold {
+				server.NodeStatus = raftAutopilotNodeDead
+			}
+		}
+		server.LastContact = server.lastContact.String()
+
+		if server.Healthy {
+			if _, ok := a.healthySince[rs.NodeID]; !ok {
+				a.healthySince[rs.NodeID] = now
+			}
+			server.StableSince = a.healthySince[rs.NodeID]
+		} else {
+			delete(a.healthySince, rs.NodeID)
+		}
+
+		if rs.Voter {
+			state.Voters = append(state.Voters, rs.NodeID)
+			if server.Healthy {
+				healthyVoters++
+			}
+		}
+		state.Servers[rs.NodeID] = server
+	}
+
+	for id := range a.healthySince {
+		if !seen[id] {
+			delete(a.healthySince, id)
+		}
+	}
+
+	sort.Strings(state.Voters)
+	state.FailureTolerance = healthyVoters - (len(state.Voters)/2 + 1)
+	state.Healthy = state.FailureTolerance >= 0
+	for _, server := range state.Servers {
+		if !server.Healthy {
+			state.Healthy = false
+		}
+	}
+
+	return state, nil
+}
@@ -0,0 +1,396 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/snapshotstore"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	raftAutoSnapshotConfigPrefix = "core/raft/snapshot-auto/config/"
+
+	raftAutoSnapshotStorageLocal = "local"
+	raftAutoSnapshotStorageS3    = "aws-s3"
+	raftAutoSnapshotStorageGCS   = "google-gcs"
+	raftAutoSnapshotStorageAzure = "azure-blob"
+
+	raftAutoSnapshotDefaultFilePrefix = "vault-snapshot"
+	raftAutoSnapshotSuffix            = ".snap"
+
+	// raftAutoSnapshotTimeFormat sorts lexicographically, so that the
+	// oldest snapshots are the first listed
+	raftAutoSnapshotTimeFormat = "20060102T150405.000Z"
+)
+
+// raftAutoSnapshotConfig configures snapshots taken automatically of the raft
+// storage and where they are stored
+type raftAutoSnapshotConfig struct {
+	Name        string        `json:"name"`
+	Interval    time.Duration `json:"interval"`
+	Retain      int           `json:"retain"`
+	PathPrefix  string        `json:"path_prefix"`
+	FilePrefix  string        `json:"file_prefix"`
+	StorageType string        `json:"storage_type"`
+
+	LocalMaxSpace int64 `json:"local_max_space,omitempty"`
+
+	AWSS3Bucket         string `json:"aws_s3_bucket,omitempty"`
+	AWSS3Region         string `json:"aws_s3_region,omitempty"`
+	AWSS3Endpoint       string `json:"aws_s3_endpoint,omitempty"`
+	AWSS3DisableTLS     bool   `json:"aws_s3_disable_tls,omitempty"`
+	AWSS3ForcePathStyle bool   `json:"aws_s3_force_path_style,omitempty"`
+	AWSAccessKeyID      string `json:"aws_access_key_id,omitempty"`
+	AWSSecretAccessKey  string `json:"aws_secret_access_key,omitempty"`
+
+	GoogleGCSBucket         string `json:"google_gcs_bucket,omitempty"`
+	GoogleServiceAccountKey string `json:"google_service_account_key,omitempty"`
+
+	AzureContainerName   string `json:"azure_container_name,omitempty"`
+	AzureAccountName     string `json:"azure_account_name,omitempty"`
+	AzureAccountKey      string `json:"azure_account_key,omitempty"`
+	AzureBlobEnvironment string `json:"azure_blob_environment,omitempty"`
+}
+
+func (c *raftAutoSnapshotConfig) validate() error {
+	switch {
+	case c.Interval <= 0:
+		return errors.New("interval must be positive")
+	case c.Retain < 1:
+		return errors.New("retain must be at least 1")
+	case c.FilePrefix == "" || strings.Contains(c.FilePrefix, "/"):
+		return errors.New("file_prefix must be set and must not contain '/'")
+	case c.LocalMaxSpace < 0:
+		return errors.New("local_max_space must not be negative")
+	}
+
+	switch c.StorageType {
+	case raftAutoSnapshotStorageLocal, raftAutoSnapshotStorageS3, raftAutoSnapshotStorageGCS, raftAutoSnapshotStorageAzure:
+	case "":
+		return errors.New("storage_type is required")
+	default:
+		return fmt.Errorf("unsupported storage_type %q", c.StorageType)
+	}
+	return nil
+}
+
+// store returns where the snapshots are to be stored
+func (c *raftAutoSnapshotConfig) store(ctx context.Context) (snapshotstore.Store, error) {
+	switch c.StorageType {
+	case raftAutoSnapshotStorageLocal:
+		return snapshotstore.NewLocalStore(c.PathPrefix, c.LocalMaxSpace)
+	case raftAutoSnapshotStorageS3:
+		return snapshotstore.NewS3Store(&snapshotstore.S3Config{
+			Bucket:          c.AWSS3Bucket,
+			Region:          c.AWSS3Region,
+			Endpoint:        c.AWSS3Endpoint,
+			AccessKeyID:     c.AWSAccessKeyID,
+			SecretAccessKey: c.AWSSecretAccessKey,
+			DisableTLS:      c.AWSS3DisableTLS,
+			ForcePathStyle:  c.AWSS3ForcePathStyle,
+		}, c.objectPrefix())
+	case raftAutoSnapshotStorageGCS:
+		return snapshotstore.NewGCSStore(ctx, &snapshotstore.GCSConfig{
+			Bucket:            c.GoogleGCSBucket,
+			ServiceAccountKey: c.GoogleServiceAccountKey,
+		}, c.objectPrefix())
+	case raftAutoSnapshotStorageAzure:
+		return snapshotstore.NewAzureStore(&snapshotstore.AzureConfig{
+			ContainerName: c.AzureContainerName,
+			AccountName:   c.AzureAccountName,
+			AccountKey:    c.AzureAccountKey,
+			Environment:   c.AzureBlobEnvironment,
+		}, c.objectPrefix())
+	}
+	return nil, fmt.Errorf("unsupported storage_type %q", c.StorageType)
+}
+
+// objectPrefix returns the path prefix as a prefix of object names
+func (c *raftAutoSnapshotConfig) objectPrefix() string {
+	prefix := strings.Trim(c.PathPrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
+
+// raftAutoSnapshotStatus reports how the snapshots of a configuration went
+type raftAutoSnapshotStatus struct {
+	LastSnapshotTime  time.Time
+	LastSnapshotName  string
+	LastError         string
+	LastErrorTime     time.Time
+	ConsecutiveErrors int
+	NextSnapshotTime  time.Time
+}
+
+// raftAutoSnapshots runs on the active node. It takes the snapshots of each
+// configuration on its interval, and deletes the oldest ones beyond the
+// number to retain.
+type raftAutoSnapshots struct {
+	core        *Core
+	logger      log.Logger
+	raftStorage *raft.RaftBackend
+	ctx         context.Context
+
+	l        sync.Mutex
+	stopChs  map[string]chan struct{}
+	statuses map[string]*raftAutoSnapshotStatus
+}
+
+// startRaftAutoSnapshots starts taking the configured snapshots on the active
+// node
+func (c *Core) startRaftAutoSnapshots(ctx context.Context) error {
+	raftStorage, ok := c.underlyingPhysical.(*raft.RaftBackend)
+	if !ok {
+		return nil
+	}
+
+	s := &raftAutoSnapshots{
+		core:        c,
+		logger:      c.logger.Named("raft.snapshot-auto"),
+		raftStorage: raftStorage,
+		ctx:         ctx,
+		stopChs:     make(map[string]chan struct{}),
+		statuses:    make(map[string]*raftAutoSnapshotStatus),
+	}
+
+	names, err := c.barrier.List(ctx, raftAutoSnapshotConfigPrefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := s.reload(ctx, name); err != nil {
+			s.stopAll()
+			return err
+		}
+	}
+
+	c.raftAutoSnapshots = s
+	return nil
+}
+
+func (c *Core) stopRaftAutoSnapshots() {
+	if c.raftAutoSnapshots != nil {
+		c.raftAutoSnapshots.stopAll()
+	}
+	c.raftAutoSnapshots = nil
+}
+
+func (c *Core) raftAutoSnapshotConfig(ctx context.Context, name string) (*raftAutoSnapshotConfig, error) {
+	entry, err := c.barrier.Get(ctx, raftAutoSnapshotConfigPrefix+name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config raftAutoSnapshotConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Core) setRaftAutoSnapshotConfig(ctx context.Context, config *raftAutoSnapshotConfig) error {
+	entry, err := logical.StorageEntryJSON(raftAutoSnapshotConfigPrefix+config.Name, config)
+	if err != nil {
+		return err
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return err
+	}
+
+	if c.raftAutoSnapshots != nil {
+		return c.raftAutoSnapshots.reload(ctx, config.Name)
+	}
+	return nil
+}
+
+func (c *Core) deleteRaftAutoSnapshotConfig(ctx context.Context, name string) error {
+	if err := c.barrier.Delete(ctx, raftAutoSnapshotConfigPrefix+name); err != nil {
+		return err
+	}
+
+	if c.raftAutoSnapshots != nil {
+		return c.raftAutoSnapshots.reload(ctx, name)
+	}
+	return nil
+}
+
+// reload stops taking the snapshots of a configuration, and starts again
+// with its stored version, if any
+func (s *raftAutoSnapshots) reload(ctx context.Context, name string) error {
+	config, err := s.core.raftAutoSnapshotConfig(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if stopCh, ok := s.stopChs[name]; ok {
+		close(stopCh)
+		delete(s.stopChs, name)
+	}
+	if config == nil {
+		delete(s.statuses, name)
+		return nil
+	}
+
+	stopCh := make(chan struct{})
+	s.stopChs[name] = stopCh
+	s.statuses[name] = &raftAutoSnapshotStatus{
+		NextSnapshotTime: time.Now().Add(config.Interval),
+	}
+	go s.run(config, stopCh)
+
+	return nil
+}
+
+func (s *raftAutoSnapshots) stopAll() {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for name, stopCh := range s.stopChs {
+		close(stopCh)
+		delete(s.stopChs, name)
+	}
+}
+
+// status returns a copy of the status of a configuration, or nil if it is
+// not running
+func (s *raftAutoSnapshots) status(name string) *raftAutoSnapshotStatus {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	status, ok := s.statuses[name]
+	if !ok {
+		return nil
+	}
+	ret := *status
+	return &ret
+}
+
+func (s *raftAutoSnapshots) run(config *raftAutoSnapshotConfig, stopCh chan struct{}) {
+	logger := s.logger.With("name", config.Name)
+
+	ticker := time.NewTicker(config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			name, err := s.snapshot(config, stopCh)
+
+			s.l.Lock()
+			status, ok := s.statuses[config.Name]
+			if ok && s.stopChs[config.Name] == stopCh {
+				now := time.Now()
+				status.NextSnapshotTime = now.Add(config.Interval)
+				if err != nil {
+					status.LastError = err.Error()
+					status.LastErrorTime = now
+					status.ConsecutiveErrors++
+				} else {
+					status.LastSnapshotTime = now
+					status.LastSnapshotName = name
+					status.ConsecutiveErrors = 0
+				}
+			}
+			s.l.Unlock()
+
+			if err != nil {
+				logger.Error("failed to take automatic snapshot", "error", err)
+			} else {
+				logger.Debug("took automatic snapshot", "snapshot", name)
+			}
+		}
+	}
+}
+
+// snapshot takes a snapshot, stores it and deletes the snapshots beyond the
+// number to retain. It returns the name of the snapshot.
+func (s *raftAutoSnapshots) snapshot(config *raftAutoSnapshotConfig, stopCh chan struct{}) (string, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	store, err := config.store(ctx)
+	if err != nil {
+		return "", err
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	f, err := ioutil.TempFile("", "vault-snapshot")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	if err := s.raftStorage.WriteSnapshot(f, s.core.seal.GetAccess()); err != nil {
+		return "", fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s%s", config.FilePrefix, time.Now().UTC().Format(raftAutoSnapshotTimeFormat), raftAutoSnapshotSuffix)
+	if err := store.Put(ctx, name, f, size); err != nil {
+		return "", fmt.Errorf("failed to store snapshot: %w", err)
+	}
+
+	names, err := store.List(ctx, config.FilePrefix+"-")
+	if err != nil {
+		return name, fmt.Errorf("failed to list snapshots to retain: %w", err)
+	}
+	// Only consider the snapshots of this configuration, not those of another
+	// configuration whose file prefix would start with this one
+	var snapshots []string
+	for _, n := range names {
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(n, config.FilePrefix+"-"), raftAutoSnapshotSuffix)
+		if _, err := time.Parse(raftAutoSnapshotTimeFormat, timestamp); err == nil {
+			snapshots = append(snapshots, n)
+		}
+	}
+	sort.Strings(snapshots)
+	for len(snapshots) > config.Retain {
+		if err := store.Delete(ctx, snapshots[0]); err != nil {
+			return name, fmt.Errorf("failed to delete snapshot %q: %w", snapshots[0], err)
+		}
+		snapshots = snapshots[1:]
+	}
+
+	return name, nil
+}
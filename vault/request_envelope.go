@@ -0,0 +1,140 @@
+package vault
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hashicorp/errwrap"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Envelope requests are served by the HTTP layer with requests of their own
+// made on behalf of the caller, such as the batch endpoint of KV version 2
+// mounts or sys/import. Each of the requests made is checked, audited and
+// uses the caller's token on its own; the envelope request is checked
+// against the ACL of its own path and audited, but does not use the token.
+
+// envelopeContextKey is the key of the ID of the envelope request a request is
+// made for in its context
+type envelopeContextKey struct{}
+
+// ContextWithEnvelope returns the context of the requests made for an
+// envelope request. Within it, the MFA passcodes accepted for one of these
+// requests, or the envelope request itself, are accepted again.
+func ContextWithEnvelope(ctx context.Context, req *logical.Request) context.Context {
+	return context.WithValue(ctx, envelopeContextKey{}, req.ID)
+}
+
+// envelopeFromContext returns the ID of the envelope request a request is
+// made for, if any
+func envelopeFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(envelopeContextKey{}).(string)
+	return id
+}
+
+// CheckEnvelopeRequest checks the token of an envelope request against the
+// ACL of its path, and audits the request. If the request is denied, the
+// error response or error to report is returned. Otherwise the returned auth
+// is passed to AuditEnvelopeResponse once the request is served.
+func (c *Core) CheckEnvelopeRequest(httpCtx context.Context, req *logical.Request) (*logical.Auth, *logical.Response, error) {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	ctx, err := c.envelopeContext(httpCtx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	auth, _, ctErr := c.checkToken(ctx, req, false)
+	if ctErr == logical.ErrPerfStandbyPleaseForward {
+		return nil, nil, ctErr
+	}
+
+	logInput := &logical.LogInput{
+		Auth:               auth,
+		Request:            req,
+		OuterErr:           ctErr,
+		NonHMACReqDataKeys: c.envelopeNonHMACKeys(ctx, req, "audit_non_hmac_request_keys"),
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit request", "path", req.Path, "error", err)
+		return nil, nil, ErrInternalError
+	}
+
+	if ctErr != nil {
+		// As in handleRequest, errors other than internal ones and denials
+		// are reported as invalid requests
+		switch {
+		case errwrap.Contains(ctErr, ErrInternalError.Error()):
+			return nil, nil, ErrInternalError
+		case errwrap.Contains(ctErr, logical.ErrPermissionDenied.Error()):
+			return nil, nil, ctErr
+		}
+		return nil, logical.ErrorResponse(ctErr.Error()), multierror.Append(nil, logical.ErrInvalidRequest)
+	}
+
+	req.DisplayName = auth.DisplayName
+	return auth, nil, nil
+}
+
+// AuditEnvelopeResponse audits the response of an envelope request checked
+// by CheckEnvelopeRequest, or the error it failed with.
+func (c *Core) AuditEnvelopeResponse(httpCtx context.Context, req *logical.Request, auth *logical.Auth, resp *logical.Response, respErr error) error {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+
+	ctx, err := c.envelopeContext(httpCtx, req)
+	if err != nil {
+		return err
+	}
+
+	logInput := &logical.LogInput{
+		Auth:                auth,
+		Request:             req,
+		Response:            resp,
+		OuterErr:            respErr,
+		NonHMACReqDataKeys:  c.envelopeNonHMACKeys(ctx, req, "audit_non_hmac_request_keys"),
+		NonHMACRespDataKeys: c.envelopeNonHMACKeys(ctx, req, "audit_non_hmac_response_keys"),
+	}
+	if err := c.auditBroker.LogResponse(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit response", "request_path", req.Path, "error", err)
+		return ErrInternalError
+	}
+	return nil
+}
+
+// envelopeContext returns the context an envelope request is checked and
+// audited with. The state lock must be held.
+func (c *Core) envelopeContext(httpCtx context.Context, req *logical.Request) (context.Context, error) {
+	if c.Sealed() {
+		return nil, consts.ErrSealed
+	}
+	if c.standby && !c.perfStandby {
+		return nil, consts.ErrStandby
+	}
+	if c.activeContext == nil || c.activeContext.Err() != nil {
+		return nil, errors.New("active context canceled after getting state lock")
+	}
+
+	ns, err := namespace.FromContext(httpCtx)
+	if err != nil {
+		return nil, errwrap.Wrapf("could not parse namespace from http context: {{err}}", err)
+	}
+	return ContextWithEnvelope(namespace.ContextWithNamespace(c.activeContext, ns), req), nil
+}
+
+// envelopeNonHMACKeys returns the keys the mount of an envelope request
+// is configured not to HMAC in audit logs.
+func (c *Core) envelopeNonHMACKeys(ctx context.Context, req *logical.Request, option string) []string {
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return nil
+	}
+	if rawVals, ok := entry.synthesizedConfigCache.Load(option); ok {
+		return rawVals.([]string)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/helper/forwarding"
+	"github.com/hashicorp/vault/helper/loggers"
 
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/vault/cluster"
@@ -168,7 +169,7 @@ func (rf *requestForwardingHandler) Handoff(ctx context.Context, shutdownWg *syn
 		rf.fws.ServeConn(tlsConn, &http2.ServeConnOpts{
 			Handler: rf.fwRPCServer,
 			BaseConfig: &http.Server{
-				ErrorLog: rf.logger.StandardLogger(nil),
+				ErrorLog: loggers.StandardLogger(rf.logger),
 			},
 		})
 
@@ -421,6 +421,9 @@ func (c *Core) switchedLockHandleRequest(httpCtx context.Context, req *logical.R
 		return nil, errwrap.Wrapf("could not parse namespace from http context: {{err}}", err)
 	}
 	ctx = namespace.ContextWithNamespace(ctx, ns)
+	if id := envelopeFromContext(httpCtx); id != "" {
+		ctx = context.WithValue(ctx, envelopeContextKey{}, id)
+	}
 
 	resp, err = c.handleCancelableRequest(ctx, ns, req)
 
@@ -447,8 +450,12 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 		return nil, err
 	}
 
-	if !hasNamespaces(c) && ns.Path != "" {
-		return nil, logical.CodedError(403, "namespaces feature not enabled")
+	if ns.ID != namespace.RootNamespaceID && isNamespaceRestrictedPath(req.Path) {
+		return nil, logical.CodedError(403, "path is only available in the root namespace")
+	}
+
+	if err := c.checkMirrorReadOnly(ctx, req); err != nil {
+		return nil, err
 	}
 
 	var auth *logical.Auth
@@ -577,7 +584,7 @@ func (c *Core) doRouting(ctx context.Context, req *logical.Request) (*logical.Re
 }
 
 func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
-	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
+	defer c.measureRequestLatency([]string{"core", "handle_request"}, time.Now())
 
 	var nonHMACReqDataKeys []string
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
@@ -694,6 +701,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Attach the display name
 	req.DisplayName = auth.DisplayName
 
+	if !isControlGroupRun(req) {
+		c.recordActivity(ctx, te, time.Now())
+	}
+
 	// Create an audit trail of the request
 	if !isControlGroupRun(req) {
 		logInput := &logical.LogInput{
@@ -708,6 +719,13 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		}
 	}
 
+	// Requests over their rate limit quota are rejected once audited, so
+	// that the audit log shows the requests which were refused
+	if err := c.applyRateLimitQuota(ctx, req); err != nil {
+		retErr = multierror.Append(retErr, err)
+		return nil, auth, retErr
+	}
+
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
 	if resp != nil {
@@ -716,6 +734,8 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		var wrapTTL time.Duration
 		var wrapFormat, creationPath string
 		var sealWrap bool
+		var numUses int
+		var allowedEntityIDs, allowedGroupIDs []string
 
 		// Ensure no wrap info information is set other than, possibly, the TTL
 		if resp.WrapInfo != nil {
@@ -725,6 +745,9 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 			wrapFormat = resp.WrapInfo.Format
 			creationPath = resp.WrapInfo.CreationPath
 			sealWrap = resp.WrapInfo.SealWrap
+			numUses = resp.WrapInfo.NumUses
+			allowedEntityIDs = resp.WrapInfo.AllowedEntityIDs
+			allowedGroupIDs = resp.WrapInfo.AllowedGroupIDs
 			resp.WrapInfo = nil
 		}
 
@@ -746,10 +769,13 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 		if wrapTTL > 0 {
 			resp.WrapInfo = &wrapping.ResponseWrapInfo{
-				TTL:          wrapTTL,
-				Format:       wrapFormat,
-				CreationPath: creationPath,
-				SealWrap:     sealWrap,
+				TTL:              wrapTTL,
+				Format:           wrapFormat,
+				CreationPath:     creationPath,
+				SealWrap:         sealWrap,
+				NumUses:          numUses,
+				AllowedEntityIDs: allowedEntityIDs,
+				AllowedGroupIDs:  allowedGroupIDs,
 			}
 		}
 	}
@@ -844,7 +870,8 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 			// Count the lease creation
 			ttl_label := metricsutil.TTLBucket(resp.Secret.TTL)
-			c.MetricSink().IncrCounterWithLabels(
+			c.MetricSink().IncrCounterWithLabelsContext(
+				ctx,
 				[]string{"secret", "lease", "creation"},
 				1,
 				[]metrics.Label{
@@ -937,6 +964,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		resp.AddWarning("Reading from 'cubbyhole/response' is deprecated. Please use sys/wrapping/unwrap to unwrap responses, as it provides additional security checks and other benefits.")
 	}
 
+	if routeErr == nil && (resp == nil || !resp.IsError()) {
+		c.publishRequestEvent(ctx, entry, req)
+	}
+
 	// Return the response and error
 	if routeErr != nil {
 		retErr = multierror.Append(retErr, routeErr)
@@ -948,7 +979,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 // handleLoginRequest is used to handle a login request, which is an
 // unauthenticated request to the backend.
 func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (retResp *logical.Response, retAuth *logical.Auth, retErr error) {
-	defer metrics.MeasureSince([]string{"core", "handle_login_request"}, time.Now())
+	defer c.measureRequestLatency([]string{"core", "handle_login_request"}, time.Now())
 
 	req.Unauthenticated = true
 
@@ -1015,6 +1046,12 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		}
 	}
 
+	// As for other requests, logins over their rate limit quota are rejected
+	// once audited
+	if err := c.applyRateLimitQuota(ctx, req); err != nil {
+		return nil, nil, err
+	}
+
 	// The token store uses authentication even when creating a new token,
 	// so it's handled in handleRequest. It should not be reached here.
 	if strings.HasPrefix(req.Path, "auth/token/") {
@@ -1022,6 +1059,12 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		return nil, nil, ErrInternalError
 	}
 
+	// Logins create leases, so are refused once a lease count quota
+	// for the mount or namespace has been reached.
+	if err := c.applyLeaseCountQuota(ctx, req); err != nil {
+		return nil, nil, err
+	}
+
 	// Route the request
 	resp, routeErr := c.doRouting(ctx, req)
 	if resp != nil {
@@ -1197,7 +1240,8 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 
 		// Count the successful token creation
 		ttl_label := metricsutil.TTLBucket(tokenTTL)
-		c.metricSink.IncrCounterWithLabels(
+		c.metricSink.IncrCounterWithLabelsContext(
+			ctx,
 			[]string{"token", "creation"},
 			1,
 			[]metrics.Label{
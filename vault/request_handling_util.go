@@ -11,12 +11,32 @@ import (
 
 func waitForReplicationState(context.Context, *Core, *logical.Request) error { return nil }
 
-func checkNeedsCG(context.Context, *Core, *logical.Request, *logical.Auth, error, []string) (error, *logical.Response, *logical.Auth, error) {
-	return nil, nil, nil, nil
+func checkNeedsCG(ctx context.Context, c *Core, req *logical.Request, auth *logical.Auth, err error, nonHMACReqDataKeys []string) (error, *logical.Response, *logical.Auth, error) {
+	cg := controlGroupFromErr(err)
+	if cg == nil {
+		return nil, nil, nil, nil
+	}
+
+	logInput := &logical.LogInput{
+		Auth:               auth,
+		Request:            req,
+		NonHMACReqDataKeys: nonHMACReqDataKeys,
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit request", "path", req.Path, "error", err)
+		return nil, nil, auth, ErrInternalError
+	}
+
+	resp, err := c.createControlGroupToken(ctx, req, auth, cg)
+	if err != nil {
+		c.logger.Error("failed to create control group token", "path", req.Path, "error", err)
+		return nil, nil, auth, ErrInternalError
+	}
+	return nil, resp, auth, nil
 }
 
 func checkErrControlGroupTokenNeedsCreated(err error) bool {
-	return false
+	return controlGroupFromErr(err) != nil
 }
 
 func shouldForward(c *Core, resp *logical.Response, err error) bool {
@@ -601,8 +601,12 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 	originalClientTokenRemainingUses := req.ClientTokenRemainingUses
 	req.ClientTokenRemainingUses = 0
 
+	// MFA credentials are only passed to the login paths of the mount, so that
+	// auth methods can verify their own second factor.
 	originalMFACreds := req.MFACreds
-	req.MFACreds = nil
+	if !req.Unauthenticated || !re.isLoginPath(req.Path) {
+		req.MFACreds = nil
+	}
 
 	originalControlGroup := req.ControlGroup
 	req.ControlGroup = nil
@@ -790,9 +794,15 @@ func (r *Router) LoginPath(ctx context.Context, path string) bool {
 	// Trim to get remaining path
 	remain := strings.TrimPrefix(adjustedPath, mount)
 
+	return re.isLoginPath(remain)
+}
+
+// isLoginPath checks if the given path, relative to the mount of the entry, is
+// one of the login paths of its backend
+func (re *routeEntry) isLoginPath(path string) bool {
 	// Check the loginPaths of this backend
 	loginPaths := re.loginPaths.Load().(*radix.Tree)
-	match, raw, ok := loginPaths.LongestPrefix(remain)
+	match, raw, ok := loginPaths.LongestPrefix(path)
 	if !ok {
 		return false
 	}
@@ -800,11 +810,11 @@ func (r *Router) LoginPath(ctx context.Context, path string) bool {
 
 	// Handle the prefix match case
 	if prefixMatch {
-		return strings.HasPrefix(remain, match)
+		return strings.HasPrefix(path, match)
 	}
 
 	// Handle the exact match case
-	return match == remain
+	return match == path
 }
 
 // pathsToRadix converts a list of special paths to a radix tree.
@@ -375,6 +375,57 @@ func TestRouter_LoginPath(t *testing.T) {
 	}
 }
 
+func TestRouter_MFACreds(t *testing.T) {
+	r := NewRouter()
+	_, barrier, _ := mockBarrier(t)
+	view := NewBarrierView(barrier, credentialBarrierPrefix)
+
+	meUUID, err := uuid.GenerateUUID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &NoopBackend{
+		Login: []string{
+			"login",
+		},
+	}
+	err = r.Mount(n, "auth/foo/", &MountEntry{UUID: meUUID, Accessor: "authfooaccessor", NamespaceID: namespace.RootNamespaceID, namespace: namespace.RootNamespace}, view)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		path            string
+		unauthenticated bool
+		expect          bool
+	}
+	tcases := []tcase{
+		{"auth/foo/login", true, true},
+		{"auth/foo/login", false, false},
+		{"auth/foo/bar", true, false},
+		{"auth/foo/bar", false, false},
+	}
+
+	mfaCreds := logical.MFACreds{"totp": []string{"123456"}}
+	for i, tc := range tcases {
+		req := &logical.Request{
+			Path:            tc.path,
+			Unauthenticated: tc.unauthenticated,
+			MFACreds:        mfaCreds,
+		}
+		if _, err := r.Route(namespace.RootContext(nil), req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if got := n.Requests[i].MFACreds != nil; got != tc.expect {
+			t.Fatalf("bad: path: %s unauthenticated: %v expect: %v got %v", tc.path, tc.unauthenticated, tc.expect, got)
+		}
+		if !reflect.DeepEqual(req.MFACreds, mfaCreds) {
+			t.Fatalf("MFA credentials of the request not restored: %v", req.MFACreds)
+		}
+	}
+}
+
 func TestRouter_Taint(t *testing.T) {
 	r := NewRouter()
 	_, barrier, _ := mockBarrier(t)
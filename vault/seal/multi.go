@@ -0,0 +1,376 @@
+package seal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// multiKeyIDPrefix marks the values wrapped by a MultiWrapper
+	multiKeyIDPrefix = "multiseal:"
+
+	// healthCheckInterval is how long the health of a seal found by a check
+	// is reported before checking it again
+	healthCheckInterval = 10 * time.Second
+
+	// healthCheckTimeout bounds how long checking the health of a seal takes
+	healthCheckTimeout = 5 * time.Second
+)
+
+var healthCheckPlaintext = []byte("vault-seal-health-check")
+
+// SealBackendStatus is the health of one of the seals of a node
+type SealBackendStatus struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Priority        int    `json:"priority"`
+	Healthy         bool   `json:"healthy"`
+	LastHealthyTime string `json:"last_healthy_time,omitempty"`
+	LastError       string `json:"last_error,omitempty"`
+	LastErrorTime   string `json:"last_error_time,omitempty"`
+}
+
+// WrapperHealth tracks the health of a wrapper, from the outcome of the
+// operations performed with it and of periodic checks
+type WrapperHealth struct {
+	l               sync.Mutex
+	healthy         bool
+	lastHealthyTime time.Time
+	lastError       string
+	lastErrorTime   time.Time
+	lastChecked     time.Time
+}
+
+// NewWrapperHealth returns the health of a wrapper not used yet, presumed
+// healthy
+func NewWrapperHealth() *WrapperHealth {
+	return &WrapperHealth{healthy: true}
+}
+
+// Record updates the health from the outcome of an operation
+func (h *WrapperHealth) Record(err error) {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	now := time.Now().UTC()
+	if err != nil {
+		h.healthy = false
+		h.lastError = err.Error()
+		h.lastErrorTime = now
+		return
+	}
+	h.healthy = true
+	h.lastHealthyTime = now
+}
+
+// Healthy returns whether the last operation with the wrapper succeeded
+func (h *WrapperHealth) Healthy() bool {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	return h.healthy
+}
+
+// Check encrypts and decrypts a value with the wrapper, unless it was
+// checked recently
+func (h *WrapperHealth) Check(ctx context.Context, w wrapping.Wrapper) {
+	h.l.Lock()
+	if time.Since(h.lastChecked) < healthCheckInterval {
+		h.l.Unlock()
+		return
+	}
+	h.lastChecked = time.Now()
+	h.l.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	h.Record(checkWrapper(ctx, w))
+}
+
+// Status returns the health of the wrapper of the named seal
+func (h *WrapperHealth) Status(name, sealType string, priority int) *SealBackendStatus {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	status := &SealBackendStatus{
+		Name:     name,
+		Type:     sealType,
+		Priority: priority,
+		Healthy:  h.healthy,
+	}
+	if !h.lastHealthyTime.IsZero() {
+		status.LastHealthyTime = h.lastHealthyTime.Format(time.RFC3339Nano)
+	}
+	if !h.lastErrorTime.IsZero() {
+		status.LastError = h.lastError
+		status.LastErrorTime = h.lastErrorTime.Format(time.RFC3339Nano)
+	}
+	return status
+}
+
+func checkWrapper(ctx context.Context, w wrapping.Wrapper) error {
+	blob, err := w.Encrypt(ctx, healthCheckPlaintext, nil)
+	if err != nil {
+		return err
+	}
+	pt, err := w.Decrypt(ctx, blob, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(pt, healthCheckPlaintext) {
+		return errors.New("decrypted value does not match the encrypted one")
+	}
+	return nil
+}
+
+// MultiWrapperSeal is one of the seals a MultiWrapper wraps values with
+type MultiWrapperSeal struct {
+	Name string
+	// Priority orders the seals tried for unwrapping, lowest first
+	Priority int
+	Wrapper  wrapping.Wrapper
+}
+
+type multiWrapperBackend struct {
+	*MultiWrapperSeal
+	health *WrapperHealth
+}
+
+// MultiWrapper wraps values with each of several seals, so that any of them
+// can unwrap them should the others be unreachable. Values are unwrapped
+// with the healthy seal of highest priority.
+type MultiWrapper struct {
+	logger   hclog.Logger
+	backends []*multiWrapperBackend
+}
+
+var _ wrapping.Wrapper = (*MultiWrapper)(nil)
+
+// NewMultiWrapper returns a wrapper using all the given seals. The type of
+// the wrapper is that of the seal of highest priority.
+func NewMultiWrapper(logger hclog.Logger, seals []*MultiWrapperSeal) (*MultiWrapper, error) {
+	if len(seals) == 0 {
+		return nil, errors.New("no seal provided")
+	}
+
+	names := make(map[string]bool, len(seals))
+	backends := make([]*multiWrapperBackend, 0, len(seals))
+	for _, s := range seals {
+		switch {
+		case s.Name == "":
+			return nil, errors.New("seal name is required")
+		case strings.ContainsAny(s.Name, ",="):
+			return nil, fmt.Errorf("seal name %q must not contain ',' or '='", s.Name)
+		case names[s.Name]:
+			return nil, fmt.Errorf("seal name %q is used more than once", s.Name)
+		}
+		names[s.Name] = true
+		backends = append(backends, &multiWrapperBackend{
+			MultiWrapperSeal: s,
+			health:           NewWrapperHealth(),
+		})
+	}
+	sort.SliceStable(backends, func(i, j int) bool {
+		return backends[i].Priority < backends[j].Priority
+	})
+
+	return &MultiWrapper{
+		logger:   logger,
+		backends: backends,
+	}, nil
+}
+
+// Type returns the type of the seal of highest priority
+func (m *MultiWrapper) Type() string {
+	return m.backends[0].Wrapper.Type()
+}
+
+// KeyID identifies the keys of all the seals, so that values wrapped before
+// any of them changed are upgraded
+func (m *MultiWrapper) KeyID() string {
+	ids := make([]string, 0, len(m.backends))
+	for _, b := range m.backends {
+		ids = append(ids, b.keyID())
+	}
+	return multiKeyIDPrefix + strings.Join(ids, ",")
+}
+
+func (m *MultiWrapper) HMACKeyID() string {
+	return ""
+}
+
+// Init initializes the seals. It only fails if none of them can be
+// initialized.
+func (m *MultiWrapper) Init(ctx context.Context) error {
+	var errs error
+	for _, b := range m.backends {
+		if err := b.Wrapper.Init(ctx); err != nil {
+			b.health.Record(err)
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", b.Name, err))
+		}
+	}
+	if errs != nil && len(errs.(*multierror.Error).Errors) == len(m.backends) {
+		return errs
+	}
+	if errs != nil {
+		m.logger.Warn("failed to initialize some seals", "error", errs)
+	}
+	return nil
+}
+
+func (m *MultiWrapper) Finalize(ctx context.Context) error {
+	var errs error
+	for _, b := range m.backends {
+		if err := b.Wrapper.Finalize(ctx); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", b.Name, err))
+		}
+	}
+	return errs
+}
+
+// Encrypt wraps the plaintext with each of the seals. It only fails if none
+// of them wraps it; values missing the wrapping of some seal are wrapped with
+// it again once their keys are upgraded.
+func (m *MultiWrapper) Encrypt(ctx context.Context, plaintext, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	blobs := make(map[string][]byte, len(m.backends))
+	ids := make([]string, 0, len(m.backends))
+	var errs error
+	for _, b := range m.backends {
+		blob, err := b.Wrapper.Encrypt(ctx, plaintext, aad)
+		b.health.Record(err)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", b.Name, err))
+			continue
+		}
+		value, err := proto.Marshal(blob)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed to marshal wrapped value: {{err}}", err)
+		}
+		blobs[b.Name] = value
+		ids = append(ids, b.keyID())
+	}
+	if len(blobs) == 0 {
+		return nil, errwrap.Wrapf("failed to encrypt with any seal: {{err}}", errs)
+	}
+	if errs != nil {
+		m.logger.Warn("failed to encrypt with some seals", "error", errs)
+	}
+
+	ct, err := json.Marshal(blobs)
+	if err != nil {
+		return nil, err
+	}
+	return &wrapping.EncryptedBlobInfo{
+		Ciphertext: ct,
+		KeyInfo: &wrapping.KeyInfo{
+			KeyID: multiKeyIDPrefix + strings.Join(ids, ","),
+		},
+	}, nil
+}
+
+// Decrypt unwraps the value with the healthy seals first, by priority, then
+// with the unhealthy ones. Values wrapped by a single seal, before several
+// were configured, are unwrapped with the seal whose key wrapped them first.
+func (m *MultiWrapper) Decrypt(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if in.KeyInfo == nil || !strings.HasPrefix(in.KeyInfo.KeyID, multiKeyIDPrefix) {
+		return m.decryptSingle(ctx, in, aad)
+	}
+
+	var blobs map[string][]byte
+	if err := json.Unmarshal(in.Ciphertext, &blobs); err != nil {
+		return nil, errwrap.Wrapf("failed to decode wrapped value: {{err}}", err)
+	}
+
+	var errs error
+	for _, b := range m.ordered() {
+		value, ok := blobs[b.Name]
+		if !ok {
+			continue
+		}
+		blob := new(wrapping.EncryptedBlobInfo)
+		if err := proto.Unmarshal(value, blob); err != nil {
+			return nil, errwrap.Wrapf("failed to unmarshal wrapped value: {{err}}", err)
+		}
+		pt, err := b.Wrapper.Decrypt(ctx, blob, aad)
+		b.health.Record(err)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", b.Name, err))
+			continue
+		}
+		return pt, nil
+	}
+	if errs == nil {
+		return nil, errors.New("value is not wrapped by any of the configured seals")
+	}
+	return nil, errwrap.Wrapf("failed to decrypt with any seal: {{err}}", errs)
+}
+
+func (m *MultiWrapper) decryptSingle(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	backends := m.ordered()
+	if in.KeyInfo != nil {
+		sort.SliceStable(backends, func(i, j int) bool {
+			return backends[i].Wrapper.KeyID() == in.KeyInfo.KeyID && backends[j].Wrapper.KeyID() != in.KeyInfo.KeyID
+		})
+	}
+
+	var errs error
+	for _, b := range backends {
+		pt, err := b.Wrapper.Decrypt(ctx, in, aad)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", b.Name, err))
+			continue
+		}
+		b.health.Record(nil)
+		return pt, nil
+	}
+	return nil, errwrap.Wrapf("failed to decrypt with any seal: {{err}}", errs)
+}
+
+// ordered returns the seals by priority, the healthy ones first
+func (m *MultiWrapper) ordered() []*multiWrapperBackend {
+	backends := make([]*multiWrapperBackend, len(m.backends))
+	copy(backends, m.backends)
+	sort.SliceStable(backends, func(i, j int) bool {
+		return backends[i].health.Healthy() && !backends[j].health.Healthy()
+	})
+	return backends
+}
+
+// CheckHealth checks the seals which were not checked recently
+func (m *MultiWrapper) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, b := range m.backends {
+		wg.Add(1)
+		go func(b *multiWrapperBackend) {
+			defer wg.Done()
+			b.health.Check(ctx, b.Wrapper)
+		}(b)
+	}
+	wg.Wait()
+}
+
+// Status returns the health of the seals, by priority
+func (m *MultiWrapper) Status() []*SealBackendStatus {
+	statuses := make([]*SealBackendStatus, 0, len(m.backends))
+	for _, b := range m.backends {
+		statuses = append(statuses, b.health.Status(b.Name, b.Wrapper.Type(), b.Priority))
+	}
+	return statuses
+}
+
+func (b *multiWrapperBackend) keyID() string {
+	return b.Name + "=" + b.Wrapper.KeyID()
+}
@@ -0,0 +1,151 @@
+package seal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+)
+
+// unreachableWrapper fails all its operations while down
+type unreachableWrapper struct {
+	*wrapping.TestWrapper
+	down bool
+}
+
+func (w *unreachableWrapper) Encrypt(ctx context.Context, pt, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	if w.down {
+		return nil, errors.New("unreachable")
+	}
+	return w.TestWrapper.Encrypt(ctx, pt, aad)
+}
+
+func (w *unreachableWrapper) Decrypt(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if w.down {
+		return nil, errors.New("unreachable")
+	}
+	return w.TestWrapper.Decrypt(ctx, in, aad)
+}
+
+func newUnreachableWrapper(secret, keyID string) *unreachableWrapper {
+	w := &unreachableWrapper{TestWrapper: wrapping.NewTestWrapper([]byte(secret))}
+	w.SetKeyID(keyID)
+	return w
+}
+
+func TestMultiWrapper(t *testing.T) {
+	ctx := context.Background()
+	primary := newUnreachableWrapper("primary-secret", "primary-key")
+	secondary := newUnreachableWrapper("secondary-secret", "secondary-key")
+
+	m, err := NewMultiWrapper(hclog.NewNullLogger(), []*MultiWrapperSeal{
+		{Name: "secondary", Priority: 2, Wrapper: secondary},
+		{Name: "primary", Priority: 1, Wrapper: primary},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Type() != wrapping.Test {
+		t.Fatalf("bad: %s", m.Type())
+	}
+	if exp := "multiseal:primary=primary-key,secondary=secondary-key"; m.KeyID() != exp {
+		t.Fatalf("expected %q, got %q", exp, m.KeyID())
+	}
+
+	pt := []byte("barrier keys")
+	blob, err := m.Encrypt(ctx, pt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.KeyInfo.KeyID != m.KeyID() {
+		t.Fatalf("bad: %s", blob.KeyInfo.KeyID)
+	}
+
+	// Unwrapping falls back to the secondary seal
+	primary.down = true
+	out, err := m.Decrypt(ctx, blob, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(pt) {
+		t.Fatalf("bad: %q", out)
+	}
+
+	status := m.Status()
+	if len(status) != 2 || status[0].Name != "primary" || status[0].Healthy || status[0].LastError == "" || !status[1].Healthy {
+		t.Fatalf("bad: %#v %#v", status[0], status[1])
+	}
+
+	// Wrapping with the primary seal down leaves it out of the value
+	blob, err = m.Encrypt(ctx, pt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "multiseal:secondary=secondary-key"; blob.KeyInfo.KeyID != exp {
+		t.Fatalf("expected %q, got %q", exp, blob.KeyInfo.KeyID)
+	}
+
+	// No seal available
+	secondary.down = true
+	if _, err := m.Decrypt(ctx, blob, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := m.Encrypt(ctx, pt, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// The health check notices once the seals are back
+	primary.down = false
+	secondary.down = false
+	m.CheckHealth(ctx)
+	for _, s := range m.Status() {
+		if !s.Healthy || s.LastHealthyTime == "" {
+			t.Fatalf("bad: %#v", s)
+		}
+	}
+}
+
+func TestMultiWrapper_SingleSealValue(t *testing.T) {
+	ctx := context.Background()
+	primary := newUnreachableWrapper("primary-secret", "primary-key")
+	secondary := newUnreachableWrapper("secondary-secret", "secondary-key")
+
+	// A value wrapped before the primary seal was added
+	pt := []byte("barrier keys")
+	blob, err := secondary.Encrypt(ctx, pt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewMultiWrapper(hclog.NewNullLogger(), []*MultiWrapperSeal{
+		{Name: "primary", Priority: 1, Wrapper: primary},
+		{Name: "secondary", Priority: 2, Wrapper: secondary},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := m.Decrypt(ctx, blob, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(pt) {
+		t.Fatalf("bad: %q", out)
+	}
+}
+
+func TestNewMultiWrapper_Errors(t *testing.T) {
+	w := wrapping.NewTestWrapper(nil)
+	cases := map[string][]*MultiWrapperSeal{
+		"none":      nil,
+		"no name":   {{Wrapper: w}},
+		"bad name":  {{Name: "a,b", Wrapper: w}},
+		"duplicate": {{Name: "a", Wrapper: w}, {Name: "a", Wrapper: w}},
+	}
+	for name, seals := range cases {
+		if _, err := NewMultiWrapper(hclog.NewNullLogger(), seals); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"context"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+)
+
+// SealBackendStatus returns the health of the seals of the node, ordered by
+// priority. The seals are checked unless they were checked recently.
+func (c *Core) SealBackendStatus(ctx context.Context) []*vaultseal.SealBackendStatus {
+	c.stateLock.RLock()
+	seal := c.seal
+	c.stateLock.RUnlock()
+
+	if seal == nil {
+		return nil
+	}
+
+	access := seal.GetAccess()
+	if access == nil || access.Wrapper == nil || seal.BarrierType() == wrapping.Shamir {
+		return []*vaultseal.SealBackendStatus{
+			{
+				Name:     seal.BarrierType(),
+				Type:     seal.BarrierType(),
+				Priority: 1,
+				Healthy:  true,
+			},
+		}
+	}
+
+	if multi, ok := access.Wrapper.(*vaultseal.MultiWrapper); ok {
+		multi.CheckHealth(ctx)
+		return multi.Status()
+	}
+
+	c.sealHealth.Check(ctx, access.Wrapper)
+	return []*vaultseal.SealBackendStatus{
+		c.sealHealth.Status(seal.BarrierType(), seal.BarrierType(), 1),
+	}
+}
@@ -0,0 +1,45 @@
+package vault
+
+import (
+	"context"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func TestCore_SealBackendStatus(t *testing.T) {
+	ctx := context.Background()
+
+	c := TestCore(t)
+	status := c.SealBackendStatus(ctx)
+	if len(status) != 1 || status[0].Type != wrapping.Shamir || !status[0].Healthy {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	c = TestCoreWithSeal(t, NewTestSeal(t, nil), false)
+	status = c.SealBackendStatus(ctx)
+	if len(status) != 1 || status[0].Type != wrapping.Test || !status[0].Healthy || status[0].LastHealthyTime == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	multi, err := seal.NewMultiWrapper(logging.NewVaultLogger(log.Trace), []*seal.MultiWrapperSeal{
+		{Name: "secondary", Priority: 2, Wrapper: wrapping.NewTestWrapper([]byte("secondary"))},
+		{Name: "primary", Priority: 1, Wrapper: wrapping.NewTestWrapper([]byte("primary"))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = TestCoreWithSeal(t, NewAutoSeal(&seal.Access{Wrapper: multi}), false)
+	status = c.SealBackendStatus(ctx)
+	if len(status) != 2 || status[0].Name != "primary" || status[1].Name != "secondary" {
+		t.Fatalf("bad: %#v", status)
+	}
+	for _, s := range status {
+		if !s.Healthy || s.LastHealthyTime == "" {
+			t.Fatalf("bad: %#v", s)
+		}
+	}
+}
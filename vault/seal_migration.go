@@ -0,0 +1,266 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+const (
+	// States of an online seal migration
+	SealMigrationStatePending    = "pending"
+	SealMigrationStateInProgress = "in_progress"
+	SealMigrationStateComplete   = "complete"
+	SealMigrationStateFailed     = "failed"
+
+	// sealMigrationSteps is the number of steps of an online seal migration:
+	// rewrapping the recovery key, rewrapping the stored barrier keys and
+	// switching the barrier seal configuration to the new seal
+	sealMigrationSteps = 3
+)
+
+var errNoSealMigration = errors.New("no online seal migration is configured on this node")
+
+// SealMigrationStatus reports the progress of an online seal migration, as
+// seen by the node
+type SealMigrationStatus struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	State          string `json:"state"`
+	Progress       int    `json:"progress"`
+	Total          int    `json:"total"`
+	StartTime      string `json:"start_time,omitempty"`
+	CompletionTime string `json:"completion_time,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// onlineSealMigration is a migration between auto-seals which is performed
+// while the cluster keeps serving requests. Until the stored keys are
+// rewrapped under newSeal, the node keeps using oldSeal.
+type onlineSealMigration struct {
+	oldSeal Seal
+	newSeal Seal
+
+	l      sync.Mutex
+	status SealMigrationStatus
+}
+
+func (m *onlineSealMigration) getStatus() *SealMigrationStatus {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	status := m.status
+	return &status
+}
+
+func (m *onlineSealMigration) completed() bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	return m.status.State == SealMigrationStateComplete
+}
+
+func (m *onlineSealMigration) advance() {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	m.status.Progress++
+}
+
+func (c *Core) setSealsForOnlineMigration(oldSeal, newSeal Seal) {
+	c.seal = oldSeal
+	c.seal.SetCore(c)
+	newSeal.SetCore(c)
+	c.sealMigration = &onlineSealMigration{
+		oldSeal: oldSeal,
+		newSeal: newSeal,
+		status: SealMigrationStatus{
+			From:  oldSeal.BarrierType(),
+			To:    newSeal.BarrierType(),
+			State: SealMigrationStatePending,
+			Total: sealMigrationSteps,
+		},
+	}
+	c.logger.Warn("entering online seal migration mode; Vault will unseal using the old seal until the migration is started with sys/seal-migrate", "from_barrier_type", oldSeal.BarrierType(), "to_barrier_type", newSeal.BarrierType())
+}
+
+// SealMigrationStatus returns the status of the online seal migration the
+// node was started for, or nil if there is none
+func (c *Core) SealMigrationStatus() *SealMigrationStatus {
+	// sealMigration is only set when creating the core
+	if c.sealMigration == nil {
+		return nil
+	}
+	return c.sealMigration.getStatus()
+}
+
+// startSealMigration starts rewrapping the recovery key and the stored
+// barrier keys under the new seal in the background. It must be called on
+// the active node with the state lock held; standbys keep serving with the
+// keyring they hold and switch to the new seal once they see the migration
+// completed.
+func (c *Core) startSealMigration() (*SealMigrationStatus, error) {
+	m := c.sealMigration
+	if m == nil {
+		return nil, errNoSealMigration
+	}
+
+	m.l.Lock()
+	switch m.status.State {
+	case SealMigrationStateInProgress:
+		m.l.Unlock()
+		return nil, errors.New("seal migration already in progress")
+	case SealMigrationStateComplete:
+		m.l.Unlock()
+		return nil, errors.New("seal migration already completed")
+	}
+	m.status.State = SealMigrationStateInProgress
+	m.status.Progress = 0
+	m.status.StartTime = time.Now().UTC().Format(time.RFC3339Nano)
+	m.status.CompletionTime = ""
+	m.status.Error = ""
+	status := m.status
+	m.l.Unlock()
+
+	c.logger.Info("online seal migration started", "from", m.oldSeal.BarrierType(), "to", m.newSeal.BarrierType())
+	go c.runSealMigration(c.activeContext, m)
+
+	return &status, nil
+}
+
+func (c *Core) runSealMigration(ctx context.Context, m *onlineSealMigration) {
+	err := c.rewrapSealKeys(ctx, m)
+	if err == nil {
+		// The migration was performed on storage, so switch to the new seal
+		// even if this node lost active duty in the meantime
+		c.stateLock.Lock()
+		err = c.adoptMigratedSeal(context.Background())
+		c.stateLock.Unlock()
+	}
+	if err == nil {
+		return
+	}
+
+	c.logger.Error("online seal migration failed", "error", err)
+	m.l.Lock()
+	m.status.State = SealMigrationStateFailed
+	m.status.Error = err.Error()
+	m.l.Unlock()
+}
+
+// rewrapSealKeys stores the recovery key and the barrier keys wrapped by the
+// new seal, then marks the barrier seal configuration as of the new seal. The
+// entries are put back as they were if any of this fails, so that the old
+// seal can still be used.
+func (c *Core) rewrapSealKeys(ctx context.Context, m *onlineSealMigration) error {
+	barrierConfig, _, err := c.PhysicalSealConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	if barrierConfig == nil {
+		return errors.New("barrier seal configuration not found")
+	}
+	if barrierConfig.Type != m.oldSeal.BarrierType() {
+		return fmt.Errorf("barrier seal configuration is of type %q, expected %q", barrierConfig.Type, m.oldSeal.BarrierType())
+	}
+
+	var saved []*physical.Entry
+	for _, key := range []string{recoveryKeyPath, StoredBarrierKeysPath} {
+		entry, err := c.physical.Get(ctx, key)
+		if err != nil {
+			return errwrap.Wrapf(fmt.Sprintf("failed to read %q: {{err}}", key), err)
+		}
+		if entry == nil {
+			return fmt.Errorf("no entry found at %q", key)
+		}
+		saved = append(saved, entry)
+	}
+
+	recoveryKey, err := m.oldSeal.RecoveryKey(ctx)
+	if err != nil {
+		return errwrap.Wrapf("error getting recovery key to set on new seal: {{err}}", err)
+	}
+	barrierKeys, err := m.oldSeal.GetStoredKeys(ctx)
+	if err != nil {
+		return errwrap.Wrapf("error getting stored keys to set on new seal: {{err}}", err)
+	}
+
+	err = func() error {
+		if err := m.newSeal.SetRecoveryKey(ctx, recoveryKey); err != nil {
+			return errwrap.Wrapf("error setting new recovery key information during migrate: {{err}}", err)
+		}
+		m.advance()
+
+		if err := m.newSeal.SetStoredKeys(ctx, barrierKeys); err != nil {
+			return errwrap.Wrapf("error setting new barrier key information during migrate: {{err}}", err)
+		}
+		m.advance()
+
+		if err := m.newSeal.SetBarrierConfig(ctx, barrierConfig); err != nil {
+			return errwrap.Wrapf("error storing barrier config during migrate: {{err}}", err)
+		}
+		m.advance()
+		return nil
+	}()
+	if err != nil {
+		for _, entry := range saved {
+			if perr := c.physical.Put(context.Background(), entry); perr != nil {
+				c.logger.Error("failed to restore entry after failed seal migration", "key", entry.Key, "error", perr)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// adoptMigratedSeal switches the node to the new seal of its online seal
+// migration once the barrier seal configuration shows that the migration was
+// performed, possibly by another node. It must be called with the state lock
+// held.
+func (c *Core) adoptMigratedSeal(ctx context.Context) error {
+	m := c.sealMigration
+	if m == nil || c.seal == m.newSeal {
+		return nil
+	}
+
+	barrierConfig, recoveryConfig, err := c.PhysicalSealConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	if barrierConfig == nil || barrierConfig.Type != m.newSeal.BarrierType() {
+		return nil
+	}
+
+	m.newSeal.SetCachedBarrierConfig(barrierConfig)
+	m.newSeal.SetCachedRecoveryConfig(recoveryConfig)
+	c.seal = m.newSeal
+	c.unwrapSeal = m.oldSeal
+
+	m.l.Lock()
+	m.status.State = SealMigrationStateComplete
+	m.status.Progress = m.status.Total
+	m.status.CompletionTime = time.Now().UTC().Format(time.RFC3339Nano)
+	m.status.Error = ""
+	m.l.Unlock()
+
+	c.logger.Info("seal migration complete", "barrier_type", m.newSeal.BarrierType())
+	return nil
+}
+
+// checkSealMigration switches the node to the new seal if its pending online
+// seal migration has been performed
+func (c *Core) checkSealMigration(ctx context.Context) error {
+	if c.sealMigration == nil || c.sealMigration.completed() {
+		return nil
+	}
+
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+	return c.adoptMigratedSeal(ctx)
+}
@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
+	"github.com/hashicorp/vault/vault/seal"
+)
+
+func TestCore_OnlineSealMigration(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewVaultLogger(log.Trace)
+	inm, err := inmem.NewInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldOpts := &seal.TestSealOpts{Name: "test-old", Secret: []byte("old-secret")}
+	newOpts := &seal.TestSealOpts{Name: "test-new", Secret: []byte("new-secret")}
+	newCore := func(barrierSeal, unwrapSeal Seal) *Core {
+		conf := testCoreConfig(t, inm, logger)
+		conf.Seal = barrierSeal
+		conf.UnwrapSeal = unwrapSeal
+		conf.OnlineSealMigration = true
+		c, err := NewCore(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	c := newCore(NewTestSeal(t, oldOpts), nil)
+	if c.SealMigrationStatus() != nil {
+		t.Fatal("expected no seal migration before initialization")
+	}
+	result, err := c.Initialize(ctx, &InitParams{
+		BarrierConfig:  &SealConfig{SecretShares: 1, SecretThreshold: 1, StoredShares: 1},
+		RecoveryConfig: &SealConfig{SecretShares: 1, SecretThreshold: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Seal(result.RootToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both nodes are configured for the migration; c is active and standby
+	// stands for a node which sees the migration performed by another
+	c = newCore(NewTestSeal(t, newOpts), NewTestSeal(t, oldOpts))
+	standby := newCore(NewTestSeal(t, newOpts), NewTestSeal(t, oldOpts))
+
+	status := c.SealMigrationStatus()
+	if status == nil || status.State != SealMigrationStatePending || status.From != "test-old" || status.To != "test-new" {
+		t.Fatalf("bad: %#v", status)
+	}
+	if c.IsInSealMigration() {
+		t.Fatal("online seal migration should not require unsealing with the migrate flag")
+	}
+	if err := c.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Sealed() {
+		t.Fatal("should be unsealed using the old seal")
+	}
+	if c.seal.BarrierType() != "test-old" {
+		t.Fatalf("bad: %s", c.seal.BarrierType())
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/seal-migrate")
+	req.ClientToken = result.RootToken
+	resp, err := c.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %#v", err, resp)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status = c.SealMigrationStatus()
+		if status.State == SealMigrationStateComplete {
+			break
+		}
+		if status.State == SealMigrationStateFailed {
+			t.Fatalf("seal migration failed: %s", status.Error)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("seal migration did not complete: %#v", status)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if status.Progress != status.Total || status.CompletionTime == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	barrierConfig, _, err := c.PhysicalSealConfigs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if barrierConfig.Type != "test-new" {
+		t.Fatalf("bad: %s", barrierConfig.Type)
+	}
+	if c.seal.BarrierType() != "test-new" {
+		t.Fatalf("bad: %s", c.seal.BarrierType())
+	}
+	if err := c.seal.VerifyRecoveryKey(ctx, result.RecoveryShares[0]); err != nil {
+		t.Fatal(err)
+	}
+	if c.Sealed() {
+		t.Fatal("should still be unsealed")
+	}
+
+	resp, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if err == nil || !strings.Contains(resp.Error().Error(), "already completed") {
+		t.Fatalf("expected an error, got %v %#v", err, resp)
+	}
+
+	// The standby picks up the migration when unsealing
+	if err := standby.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if standby.Sealed() || standby.seal.BarrierType() != "test-new" {
+		t.Fatal("standby should be unsealed using the new seal")
+	}
+	if status := standby.SealMigrationStatus(); status.State != SealMigrationStateComplete {
+		t.Fatalf("bad: %#v", status)
+	}
+	if err := standby.Seal(result.RootToken); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Seal(result.RootToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once migrated the old seal is only used for unwrapping
+	c = newCore(NewTestSeal(t, newOpts), NewTestSeal(t, oldOpts))
+	if c.SealMigrationStatus() != nil {
+		t.Fatal("expected no seal migration once migrated")
+	}
+	if err := c.UnsealWithStoredKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Sealed() {
+		t.Fatal("should be unsealed using the new seal")
+	}
+}
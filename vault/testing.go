@@ -157,6 +157,9 @@ func TestCoreWithSealAndUI(t testing.T, opts *CoreConfig) *Core {
 	conf.LicensingConfig = opts.LicensingConfig
 	conf.DisableKeyEncodingChecks = opts.DisableKeyEncodingChecks
 	conf.MetricsHelper = opts.MetricsHelper
+	conf.MetricSink = opts.MetricSink
+	conf.DrainGracePeriod = opts.DrainGracePeriod
+	conf.LogRouter = opts.LogRouter
 
 	if opts.Logger != nil {
 		conf.Logger = opts.Logger
@@ -477,7 +480,7 @@ func TestAddTestPlugin(t testing.T, c *Core, name string, pluginType consts.Plug
 	c.pluginCatalog.directory = fullPath
 
 	args := []string{fmt.Sprintf("--test.run=%s", testFunc)}
-	err = c.pluginCatalog.Set(context.Background(), name, pluginType, fileName, args, env, sum)
+	err = c.pluginCatalog.Set(context.Background(), name, pluginType, "", fileName, args, env, sum, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1366,6 +1369,7 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.DefaultLeaseTTL = base.DefaultLeaseTTL
 		coreConfig.MaxLeaseTTL = base.MaxLeaseTTL
 		coreConfig.CacheSize = base.CacheSize
+		coreConfig.CacheMaxBytes = base.CacheMaxBytes
 		coreConfig.PluginDirectory = base.PluginDirectory
 		coreConfig.Seal = base.Seal
 		coreConfig.UnwrapSeal = base.UnwrapSeal
@@ -1429,6 +1433,7 @@ func NewTestCluster(t testing.T, base *CoreConfig, opts *TestClusterOptions) *Te
 		coreConfig.DevToken = base.DevToken
 		coreConfig.CounterSyncInterval = base.CounterSyncInterval
 		coreConfig.RecoveryMode = base.RecoveryMode
+		coreConfig.DisableAutopilot = base.DisableAutopilot
 	}
 
 	if coreConfig.RawConfig == nil {
@@ -2002,6 +2007,7 @@ func (m *mockBuiltinRegistry) Keys(pluginType consts.PluginType) []string {
 		"elasticsearch-database-plugin",
 		"mssql-database-plugin",
 		"cassandra-database-plugin",
+		"clickhouse-database-plugin",
 		"mongodb-database-plugin",
 		"mongodbatlas-database-plugin",
 		"hana-database-plugin",
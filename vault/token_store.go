@@ -93,6 +93,23 @@ var (
 			}
 			return ts.cubbyholeBackend.revoke(ctx, salt.SaltID(ts.cubbyholeBackend.saltUUID, saltedID, salt.SHA1Hash))
 
+		case te.NamespaceID != namespace.RootNamespaceID:
+			if te.CubbyholeID == "" {
+				return fmt.Errorf("missing cubbyhole ID while destroying")
+			}
+
+			// Tokens of other namespaces have the cubbyhole of their namespace
+			tokenNS, err := NamespaceByID(ctx, te.NamespaceID, ts.core)
+			if err != nil {
+				return err
+			}
+			cubbyhole, ok := ts.core.router.MatchingBackend(namespace.ContextWithNamespace(ctx, tokenNS), cubbyholeMountPath).(*CubbyholeBackend)
+			if !ok {
+				// The namespace is being deleted along with its storage
+				return nil
+			}
+			return cubbyhole.revoke(ctx, te.CubbyholeID)
+
 		default:
 			if te.CubbyholeID == "" {
 				return fmt.Errorf("missing cubbyhole ID while destroying")
@@ -1164,7 +1181,12 @@ func (ts *TokenStore) lookupInternal(ctx context.Context, id string, salted, tai
 		_, nsID := namespace.SplitIDFromString(id)
 		if nsID != "" {
 			tokenNS, err := NamespaceByID(ctx, nsID, ts.core)
-			if err != nil {
+			switch {
+			case err == namespace.ErrNoNamespace:
+				// The namespace of the token has been deleted, along with
+				// its tokens
+				return nil, nil
+			case err != nil:
 				return nil, errwrap.Wrapf("failed to look up namespace from the token: {{err}}", err)
 			}
 			if tokenNS != nil {
@@ -2719,7 +2741,8 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 
 	// Count the successful token creation.
 	ttl_label := metricsutil.TTLBucket(te.TTL)
-	ts.core.metricSink.IncrCounterWithLabels(
+	ts.core.metricSink.IncrCounterWithLabelsContext(
+		ctx,
 		[]string{"token", "creation"},
 		1,
 		[]metrics.Label{
@@ -7,21 +7,44 @@ import (
 )
 
 func (ts *TokenStore) baseView(ns *namespace.Namespace) *BarrierView {
-	return ts.baseBarrierView
+	if ns.ID == namespace.RootNamespaceID {
+		return ts.baseBarrierView
+	}
+	return ts.core.namespaceSystemView(ns).SubView(tokenSubPath)
 }
 
 func (ts *TokenStore) idView(ns *namespace.Namespace) *BarrierView {
-	return ts.idBarrierView
+	if ns.ID == namespace.RootNamespaceID {
+		return ts.idBarrierView
+	}
+	return ts.baseView(ns).SubView(idPrefix)
 }
 
 func (ts *TokenStore) accessorView(ns *namespace.Namespace) *BarrierView {
-	return ts.accessorBarrierView
+	if ns.ID == namespace.RootNamespaceID {
+		return ts.accessorBarrierView
+	}
+	return ts.baseView(ns).SubView(accessorPrefix)
 }
 
 func (ts *TokenStore) parentView(ns *namespace.Namespace) *BarrierView {
-	return ts.parentBarrierView
+	if ns.ID == namespace.RootNamespaceID {
+		return ts.parentBarrierView
+	}
+	return ts.baseView(ns).SubView(parentPrefix)
 }
 
 func (ts *TokenStore) rolesView(ns *namespace.Namespace) *BarrierView {
-	return ts.rolesBarrierView
+	if ns.ID == namespace.RootNamespaceID {
+		return ts.rolesBarrierView
+	}
+	return ts.baseView(ns).SubView(rolesPrefix)
+}
+
+// invalidateNamespace drops the cached salt of a deleted namespace.
+func (ts *TokenStore) invalidateNamespace(ns *namespace.Namespace) {
+	ts.saltLock.Lock()
+	defer ts.saltLock.Unlock()
+
+	delete(ts.salts, ns.ID)
 }
@@ -17,7 +17,9 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
 	"gopkg.in/square/go-jose.v2"
 	squarejwt "gopkg.in/square/go-jose.v2/jwt"
 )
@@ -25,8 +27,138 @@ import (
 const (
 	// The location of the key used to generate response-wrapping JWTs
 	coreWrappingJWTKeyPath = "core/wrapping/jwtkey"
+
+	// The cubbyhole location of wrapped responses that can only be unwrapped
+	// by specific entities or groups. Unlike cubbyhole/response, the
+	// response-wrapping policy does not grant access to it, so it can only
+	// be read through sys/wrapping/unwrap.
+	wrappingRestrictedResponsePath = "cubbyhole/restricted-response"
 )
 
+// WrappingConfig holds the policies enforced on data wrapped through
+// sys/wrapping/wrap.
+type WrappingConfig struct {
+	// MaxPayloadSize is the largest payload, in bytes, that can be wrapped.
+	// Zero means no limit.
+	MaxPayloadSize int `json:"max_payload_size"`
+
+	// MaxNumUses is the largest number of times a wrapping token can be
+	// allowed to be unwrapped. Zero means no limit.
+	MaxNumUses int `json:"max_num_uses"`
+
+	// RequireUnwrapConstraints refuses to wrap data without restricting the
+	// entities or groups that can unwrap it.
+	RequireUnwrapConstraints bool `json:"require_unwrap_constraints"`
+}
+
+// wrappingRestrictions are the unwrapping restrictions stored in the
+// cubbyhole of a wrapping token alongside its creation information.
+type wrappingRestrictions struct {
+	NumUses          int      `mapstructure:"num_uses"`
+	AllowedEntityIDs []string `mapstructure:"allowed_entity_ids"`
+	AllowedGroupIDs  []string `mapstructure:"allowed_group_ids"`
+}
+
+func (r *wrappingRestrictions) restricted() bool {
+	return len(r.AllowedEntityIDs) > 0 || len(r.AllowedGroupIDs) > 0
+}
+
+// responsePath returns the cubbyhole location of the wrapped response.
+func (r *wrappingRestrictions) responsePath() string {
+	if r.restricted() {
+		return wrappingRestrictedResponsePath
+	}
+	return "cubbyhole/response"
+}
+
+// loadWrappingConfig reads the wrapping configuration, returning the
+// defaults if none has been set.
+func (c *Core) loadWrappingConfig(ctx context.Context) (*WrappingConfig, error) {
+	view := c.systemBarrierView.SubView("config/")
+
+	config := new(WrappingConfig)
+	out, err := view.Get(ctx, "wrapping")
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to read wrapping config: {{err}}", err)
+	}
+	if out == nil {
+		return config, nil
+	}
+	if err := out.DecodeJSON(config); err != nil {
+		return nil, errwrap.Wrapf("failed to decode wrapping config: {{err}}", err)
+	}
+
+	return config, nil
+}
+
+func (c *Core) saveWrappingConfig(ctx context.Context, config *WrappingConfig) error {
+	view := c.systemBarrierView.SubView("config/")
+
+	entry, err := logical.StorageEntryJSON("wrapping", config)
+	if err != nil {
+		return errwrap.Wrapf("failed to create wrapping config entry: {{err}}", err)
+	}
+	if err := view.Put(ctx, entry); err != nil {
+		return errwrap.Wrapf("failed to save wrapping config: {{err}}", err)
+	}
+
+	return nil
+}
+
+// wrappingRestrictions reads the unwrapping restrictions of a wrapping
+// token. Tokens created by previous Vault versions have none.
+func (c *Core) wrappingRestrictions(ctx context.Context, te *logical.TokenEntry) (*wrappingRestrictions, error) {
+	cubbyReq := &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "cubbyhole/wrapinfo",
+		ClientToken: te.ID,
+	}
+	cubbyReq.SetTokenEntry(te)
+	cubbyResp, err := c.router.Route(ctx, cubbyReq)
+	if err != nil {
+		return nil, errwrap.Wrapf("error looking up wrapping information: {{err}}", err)
+	}
+
+	restrictions := new(wrappingRestrictions)
+	if cubbyResp == nil || cubbyResp.IsError() || cubbyResp.Data == nil {
+		return restrictions, nil
+	}
+	if err := mapstructure.Decode(cubbyResp.Data, restrictions); err != nil {
+		return nil, errwrap.Wrapf("error decoding wrapping restrictions: {{err}}", err)
+	}
+
+	return restrictions, nil
+}
+
+// checkUnwrapRestrictions returns logical.ErrPermissionDenied if the entity
+// is not allowed to unwrap a response with the given restrictions.
+func (c *Core) checkUnwrapRestrictions(restrictions *wrappingRestrictions, entityID string) error {
+	if !restrictions.restricted() {
+		return nil
+	}
+	if entityID == "" {
+		return logical.ErrPermissionDenied
+	}
+	if strutil.StrListContains(restrictions.AllowedEntityIDs, entityID) {
+		return nil
+	}
+	if len(restrictions.AllowedGroupIDs) == 0 {
+		return logical.ErrPermissionDenied
+	}
+
+	groups, inheritedGroups, err := c.identityStore.groupsByEntityID(entityID)
+	if err != nil {
+		return err
+	}
+	for _, group := range append(groups, inheritedGroups...) {
+		if strutil.StrListContains(restrictions.AllowedGroupIDs, group.ID) {
+			return nil
+		}
+	}
+
+	return logical.ErrPermissionDenied
+}
+
 func (c *Core) ensureWrappingKey(ctx context.Context) error {
 	entry, err := c.barrier.Get(ctx, coreWrappingJWTKeyPath)
 	if err != nil {
@@ -129,13 +261,22 @@ DONELISTHANDLING:
 	// before auditing so that resp.WrapInfo.Token can contain the HMAC'd
 	// wrapping token ID in the audit logs, so that it can be determined from
 	// the audit logs whether the token was ever actually used.
+	restrictions := &wrappingRestrictions{
+		NumUses:          resp.WrapInfo.NumUses,
+		AllowedEntityIDs: resp.WrapInfo.AllowedEntityIDs,
+		AllowedGroupIDs:  resp.WrapInfo.AllowedGroupIDs,
+	}
+	if restrictions.NumUses < 1 {
+		restrictions.NumUses = 1
+	}
+
 	creationTime := time.Now()
 	te := logical.TokenEntry{
 		Path:           req.Path,
 		Policies:       []string{"response-wrapping"},
 		CreationTime:   creationTime.Unix(),
 		TTL:            resp.WrapInfo.TTL,
-		NumUses:        1,
+		NumUses:        restrictions.NumUses,
 		ExplicitMaxTTL: resp.WrapInfo.TTL,
 		NamespaceID:    ns.ID,
 	}
@@ -147,7 +288,8 @@ DONELISTHANDLING:
 
 	// Count the successful token creation
 	ttl_label := metricsutil.TTLBucket(resp.WrapInfo.TTL)
-	c.metricSink.IncrCounterWithLabels(
+	c.metricSink.IncrCounterWithLabelsContext(
+		ctx,
 		[]string{"token", "creation"},
 		1,
 		[]metrics.Label{
@@ -229,7 +371,7 @@ DONELISTHANDLING:
 
 	cubbyReq := &logical.Request{
 		Operation:   logical.CreateOperation,
-		Path:        "cubbyhole/response",
+		Path:        restrictions.responsePath(),
 		ClientToken: te.ID,
 	}
 	if sealWrap {
@@ -295,6 +437,13 @@ DONELISTHANDLING:
 	} else {
 		cubbyReq.Data["creation_path"] = resp.WrapInfo.CreationPath
 	}
+	if restrictions.NumUses > 1 {
+		cubbyReq.Data["num_uses"] = restrictions.NumUses
+	}
+	if restrictions.restricted() {
+		cubbyReq.Data["allowed_entity_ids"] = restrictions.AllowedEntityIDs
+		cubbyReq.Data["allowed_group_ids"] = restrictions.AllowedGroupIDs
+	}
 	cubbyResp, err = c.router.Route(ctx, cubbyReq)
 	if err != nil {
 		// Revoke since it's not yet being tracked for expiration
@@ -23,6 +23,7 @@ export default [
         category: 'databases',
         content: [
           'cassandra',
+          'clickhouse',
           'elasticdb',
           'influxdb',
           'hanadb',
@@ -51,6 +52,7 @@ export default [
           'group-alias',
           'tokens',
           'lookup',
+          'mfa',
         ],
       },
       { category: 'mongodbatlas' },
@@ -59,6 +61,7 @@ export default [
       { category: 'pki' },
       { category: 'rabbitmq' },
       { category: 'ssh' },
+      { category: 'tokenize' },
       { category: 'totp' },
       { category: 'transform' },
       { category: 'transit' },
@@ -97,14 +100,18 @@ export default [
       'capabilities-accessor',
       'capabilities-self',
       'config-auditing',
+      'config-cache',
       'config-control-group',
       'config-cors',
       'config-state',
       'config-ui',
+      'config-wrapping',
       'control-group',
+      'events',
       'generate-root',
       'health',
       'host-info',
+      'import',
       'init',
       'internal-counters',
       'internal-specs-openapi',
@@ -113,11 +120,15 @@ export default [
       'leader',
       'leases',
       'license',
+      'loggers',
       'metrics',
+      'mirror',
       {
         category: 'mfa',
         content: ['duo', 'okta', 'pingid', 'totp'],
       },
+      'monitor',
+      'monitor-profiles',
       'mounts',
       'namespaces',
       'plugins-reload-backend',
@@ -126,6 +137,8 @@ export default [
       'policies',
       'policies-password',
       'pprof',
+      'quotas-lease-count',
+      'quotas-rate-limit',
       'raw',
       'rekey',
       'rekey-recovery-key',
@@ -136,6 +149,8 @@ export default [
       },
       'rotate',
       'seal',
+      'seal-backend-status',
+      'seal-migrate',
       'seal-status',
       'sealwrap-rewrap',
       'step-down',
@@ -78,6 +78,7 @@ export default [
           'mssql',
           'mysql',
           'oci-object-storage',
+          'plugin',
           'postgresql',
           'raft',
           's3',
@@ -108,11 +109,16 @@ export default [
       },
       'debug',
       'delete',
+      {
+        category: 'identity',
+        content: ['dedupe'],
+      },
       {
         category: 'kv',
         content: [
           'delete',
           'destroy',
+          'diff',
           'enable-versioning',
           'get',
           'list',
@@ -133,7 +139,9 @@ export default [
       {
         category: 'operator',
         content: [
+          'diagnose',
           'generate-root',
+          'import',
           'init',
           'key-status',
           'migrate',
@@ -141,6 +149,7 @@ export default [
           'rekey',
           'rotate',
           'seal',
+          'seal-migrate',
           'step-down',
           'unseal',
         ],
@@ -152,7 +161,7 @@ export default [
       },
       {
         category: 'policy',
-        content: ['delete', 'fmt', 'list', 'read', 'write'],
+        content: ['delete', 'fmt', 'list', 'read', 'test', 'write'],
       },
       'read',
       {
@@ -217,6 +226,7 @@ export default [
         category: 'databases',
         content: [
           'cassandra',
+          'clickhouse',
           'elasticdb',
           'hanadb',
           'influxdb',
@@ -251,6 +261,7 @@ export default [
           'dynamic-ssh-keys',
         ],
       },
+      { category: 'tokenize' },
       { category: 'totp' },
       { category: 'transform' },
       { category: 'transit' },
@@ -284,7 +295,7 @@ export default [
   },
   {
     category: 'audit',
-    content: ['file', 'syslog', 'socket'],
+    content: ['file', 'syslog', 'socket', 'kafka'],
   },
   {
     category: 'plugin',